@@ -0,0 +1,113 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ReasonApprovalRequired is used as part of the Event 'reason' when an image cache
+// action exceeds the approval thresholds and waits for approval
+const ReasonApprovalRequired = "ApprovalRequired"
+
+// approvalEnabled returns true if large image cache actions require approval
+func (c *Controller) approvalEnabled() bool {
+	return c.approvalNodeThreshold > 0 || c.approvalBytesThreshold > 0
+}
+
+// approvalExceeded fills the plan with the number of nodes and the estimated bytes of
+// the image pulls, and returns the approval thresholds they exceed
+func (c *Controller) approvalExceeded(workItems []imageWorkItem, plan *v1alpha2.ImageCachePlan) []string {
+	nodes, err := c.nodesLister.List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error listing nodes: %v", err)
+	}
+	imageSize := reportedImageSizes(nodes)
+	plan.Nodes = countNodes(workItems)
+	plan.EstimatedBytes = 0
+	for _, w := range workItems {
+		// OCI artifacts are not reported by the nodes
+		if w.workType == images.ImageCachePurge || w.artifact {
+			continue
+		}
+		plan.EstimatedBytes += imageSize(w.image)
+	}
+	exceeded := []string{}
+	if c.approvalNodeThreshold > 0 && plan.Nodes > c.approvalNodeThreshold {
+		exceeded = append(exceeded, fmt.Sprintf("image pulls to %d nodes, more than the threshold of %d nodes", plan.Nodes, c.approvalNodeThreshold))
+	}
+	if c.approvalBytesThreshold > 0 && plan.EstimatedBytes > c.approvalBytesThreshold {
+		exceeded = append(exceeded, fmt.Sprintf("an estimated size of %s, more than the threshold of %s",
+			resource.NewQuantity(plan.EstimatedBytes, resource.BinarySI), resource.NewQuantity(c.approvalBytesThreshold, resource.BinarySI)))
+	}
+	return exceeded
+}
+
+// awaitApproval sets the status of the image cache to PendingApproval. The action is
+// started once the image cache is annotated for approval.
+func (c *Controller) awaitApproval(imageCache *v1alpha2.ImageCache, workType images.WorkType, status *v1alpha2.ImageCacheStatus, exceeded []string) error {
+	status.Status = v1alpha2.ImageCacheActionStatusPendingApproval
+	status.Reason = actionReason(workType)
+	status.Message = fmt.Sprintf("Image cache action requires approval: %s. Annotate the image cache with %s to approve",
+		strings.Join(exceeded, ", "), imageCacheApproveAnnotationKey)
+	if err := c.updateImageCacheStatus(imageCache, status); err != nil {
+		glog.Errorf("Error updating imagecache status to %s: %v", status.Status, err)
+		return err
+	}
+	glog.Infof("Image cache %s/%s: %s", imageCache.Namespace, imageCache.Name, status.Message)
+	c.recorder.Event(imageCache, corev1.EventTypeWarning, ReasonApprovalRequired, status.Message)
+	return nil
+}
+
+// consumeApproval removes the approval annotation once the approved action started, so
+// that the next large action requires approval again
+func (c *Controller) consumeApproval(namespace, name string) error {
+	imageCache, err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if _, ok := imageCache.Annotations[imageCacheApproveAnnotationKey]; !ok {
+		return nil
+	}
+	return c.removeAnnotation(imageCache, imageCacheApproveAnnotationKey)
+}
+
+// approved returns true if the image cache is annotated for approval
+func approved(imageCache *v1alpha2.ImageCache) bool {
+	_, ok := imageCache.Annotations[imageCacheApproveAnnotationKey]
+	return ok
+}
+
+// approvedWorkType returns the work type of the action pending approval. The images
+// removed by an update pending approval are not known anymore, hence the update is
+// executed as a refresh.
+func approvedWorkType(imageCache *v1alpha2.ImageCache) images.WorkType {
+	if imageCache.Status.Reason == v1alpha2.ImageCacheReasonImageCacheCreate {
+		return images.ImageCacheCreate
+	}
+	return images.ImageCacheRefresh
+}
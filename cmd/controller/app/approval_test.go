@@ -0,0 +1,149 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestApprovalExceeded(t *testing.T) {
+	const gi = int64(1 << 30)
+	node1 := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status: corev1.NodeStatus{
+			Images: []corev1.ContainerImage{{Names: []string{"docker.io/library/pytorch:2.0"}, SizeBytes: 10 * gi}},
+		},
+	}
+	node2 := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2"}}
+	workItems := []imageWorkItem{
+		{image: "pytorch:2.0", node: node1, workType: images.ImageCacheRefresh},
+		{image: "pytorch:2.0", node: node2, workType: images.ImageCacheRefresh},
+		{image: "nginx:1.23", node: node2, workType: images.ImageCacheRefresh},
+		{image: "redis:7", node: node2, workType: images.ImageCachePurge},
+	}
+	tests := []struct {
+		name                   string
+		approvalNodeThreshold  int
+		approvalBytesThreshold int64
+		expectedExceeded       int
+	}{
+		{
+			name:                  "#1: Node threshold not exceeded",
+			approvalNodeThreshold: 2,
+		},
+		{
+			name:                  "#2: Node threshold exceeded",
+			approvalNodeThreshold: 1,
+			expectedExceeded:      1,
+		},
+		{
+			name:                   "#3: Bytes threshold exceeded",
+			approvalBytesThreshold: 15 * gi,
+			expectedExceeded:       1,
+		},
+		{
+			name:                   "#4: Both thresholds exceeded",
+			approvalNodeThreshold:  1,
+			approvalBytesThreshold: 15 * gi,
+			expectedExceeded:       2,
+		},
+		{
+			name:                   "#5: Bytes threshold not exceeded",
+			approvalBytesThreshold: 20 * gi,
+		},
+	}
+	controller, nodeInformer, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	nodeInformer.Informer().GetIndexer().Add(node1)
+	nodeInformer.Informer().GetIndexer().Add(node2)
+	for _, test := range tests {
+		controller.approvalNodeThreshold = test.approvalNodeThreshold
+		controller.approvalBytesThreshold = test.approvalBytesThreshold
+		plan := &kubefledgedv1alpha2.ImageCachePlan{WorkItems: len(workItems)}
+		exceeded := controller.approvalExceeded(workItems, plan)
+		if len(exceeded) != test.expectedExceeded {
+			t.Errorf("Test: %s failed: expected %d exceeded thresholds, actual=%v", test.name, test.expectedExceeded, exceeded)
+		}
+		if plan.Nodes != 2 || plan.EstimatedBytes != 20*gi {
+			t.Errorf("Test: %s failed: expected 2 nodes and %d bytes, actual=%d nodes and %d bytes", test.name, 20*gi, plan.Nodes, plan.EstimatedBytes)
+		}
+	}
+}
+
+func TestEnqueueApprovedImageCache(t *testing.T) {
+	tests := []struct {
+		name             string
+		status           kubefledgedv1alpha2.ImageCacheStatus
+		expectedQueued   bool
+		expectedWorkType images.WorkType
+	}{
+		{
+			name: "#1: Create pending approval",
+			status: kubefledgedv1alpha2.ImageCacheStatus{
+				Status: kubefledgedv1alpha2.ImageCacheActionStatusPendingApproval,
+				Reason: kubefledgedv1alpha2.ImageCacheReasonImageCacheCreate,
+			},
+			expectedQueued:   true,
+			expectedWorkType: images.ImageCacheCreate,
+		},
+		{
+			name: "#2: Update pending approval executed as a refresh",
+			status: kubefledgedv1alpha2.ImageCacheStatus{
+				Status: kubefledgedv1alpha2.ImageCacheActionStatusPendingApproval,
+				Reason: kubefledgedv1alpha2.ImageCacheReasonImageCacheUpdate,
+			},
+			expectedQueued:   true,
+			expectedWorkType: images.ImageCacheRefresh,
+		},
+		{
+			name: "#3: Nothing pending approval",
+			status: kubefledgedv1alpha2.ImageCacheStatus{
+				Status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+				Reason: kubefledgedv1alpha2.ImageCacheReasonImageCacheCreate,
+			},
+		},
+	}
+	for _, test := range tests {
+		controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+		oldImageCache := &kubefledgedv1alpha2.ImageCache{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+			Spec: kubefledgedv1alpha2.ImageCacheSpec{
+				CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{{Images: []string{"foo"}}},
+			},
+			Status: test.status,
+		}
+		newImageCache := oldImageCache.DeepCopy()
+		newImageCache.Annotations = map[string]string{imageCacheApproveAnnotationKey: ""}
+		if queued := controller.enqueueImageCache(images.ImageCacheUpdate, oldImageCache, newImageCache); queued != test.expectedQueued {
+			t.Errorf("Test: %s failed: expected queued=%t, actual=%t", test.name, test.expectedQueued, queued)
+			continue
+		}
+		if !test.expectedQueued {
+			continue
+		}
+		item, _ := controller.workqueue.Get()
+		if wqKey := item.(images.WorkQueueKey); wqKey.WorkType != test.expectedWorkType {
+			t.Errorf("Test: %s failed: expected work type %s, actual=%s", test.name, test.expectedWorkType, wqKey.WorkType)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+)
+
+// AuditRecord is the audit record of an image pull or purge performed on a node
+type AuditRecord struct {
+	Time       time.Time `json:"time"`
+	ImageCache string    `json:"imageCache"`
+	// Trigger is the image cache action that triggered the operation i.e. ImageCacheCreate,
+	// ImageCacheUpdate, ImageCacheRefresh or ImageCachePurge
+	Trigger   string `json:"trigger"`
+	Operation string `json:"operation"`
+	Image     string `json:"image"`
+	Artifact  bool   `json:"artifact,omitempty"`
+	Platform  string `json:"platform,omitempty"`
+	// Source is the fallback reference the image was pulled from, if not the image
+	Source string `json:"source,omitempty"`
+	// Digest is the digest of the image as reported in the node status, if known
+	Digest  string `json:"digest,omitempty"`
+	Node    string `json:"node"`
+	Result  string `json:"result"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// AuditSink is a destination of audit records
+type AuditSink interface {
+	Write(records []AuditRecord) error
+}
+
+// NewAuditSink returns an audit sink appending audit records as JSON lines to the file
+// at path, and/or posting them as JSON lines to webhookURL. It returns nil if neither
+// is specified.
+func NewAuditSink(path string, webhookURL string) (AuditSink, error) {
+	sinks := multiAuditSink{}
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("error opening audit log %s: %v", path, err)
+		}
+		sinks = append(sinks, &fileAuditSink{file: f})
+	}
+	if webhookURL != "" {
+		sinks = append(sinks, &webhookAuditSink{url: webhookURL, client: &http.Client{Timeout: 10 * time.Second}})
+	}
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+	return sinks, nil
+}
+
+type multiAuditSink []AuditSink
+
+func (m multiAuditSink) Write(records []AuditRecord) error {
+	var errs []string
+	for _, sink := range m {
+		if err := sink.Write(records); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// fileAuditSink appends audit records to a file
+type fileAuditSink struct {
+	lock sync.Mutex
+	file *os.File
+}
+
+func (s *fileAuditSink) Write(records []AuditRecord) error {
+	data, err := encodeAuditRecords(records)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, err := s.file.Write(data); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// webhookAuditSink posts audit records to a webhook
+type webhookAuditSink struct {
+	url    string
+	client *http.Client
+}
+
+func (s *webhookAuditSink) Write(records []AuditRecord) error {
+	data, err := encodeAuditRecords(records)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/x-ndjson", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("audit webhook %s returned %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+func encodeAuditRecords(records []AuditRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// auditImageWork writes an audit record for each image pull or purge of a finished
+// image cache action. Failures to write audit records are logged.
+func (c *Controller) auditImageWork(imageCache *v1alpha2.ImageCache, trigger string, results map[string]images.ImageWorkResult) {
+	if c.auditSink == nil {
+		return
+	}
+	now := time.Now()
+	records := []AuditRecord{}
+	for _, v := range results {
+		iwr := v.ImageWorkRequest
+		r := AuditRecord{
+			Time:       now,
+			ImageCache: imageCache.Namespace + "/" + imageCache.Name,
+			Trigger:    trigger,
+			Operation:  "pull",
+			Image:      iwr.Image,
+			Artifact:   iwr.Artifact,
+			Platform:   iwr.Platform,
+			Source:     iwr.Source,
+			Result:     v.Status,
+			Reason:     v.Reason,
+			Message:    v.Message,
+		}
+		if iwr.WorkType == images.ImageCachePurge {
+			r.Operation = "purge"
+		}
+		if iwr.Node != nil {
+			r.Node = iwr.Node.Labels["kubernetes.io/hostname"]
+			if !iwr.Artifact && r.Operation == "pull" {
+				r.Digest = c.imageDigestOnNode(iwr.NodeReference(), iwr.Node.Name)
+			}
+		}
+		records = append(records, r)
+	}
+	if err := c.auditSink.Write(records); err != nil {
+		glog.Errorf("Error writing %d audit records of image cache %s: %v", len(records), imageCache.Name, err)
+	}
+}
+
+// imageDigestOnNode returns the digest of an image from the node status, or an empty
+// string if the node does not report it
+func (c *Controller) imageDigestOnNode(image string, nodeName string) string {
+	node, err := c.nodesLister.Get(nodeName)
+	if err != nil {
+		return ""
+	}
+	return imageDigest(image, node)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestAuditImageWork(t *testing.T) {
+	auditNode := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node1",
+			Labels: map[string]string{"kubernetes.io/hostname": "node1"},
+		},
+		Status: corev1.NodeStatus{
+			Images: []corev1.ContainerImage{
+				{Names: []string{"docker.io/library/foo@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31", "docker.io/library/foo:v1"}},
+			},
+		},
+	}
+	imageCache := &kubefledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: fledgedNameSpace,
+		},
+	}
+	results := map[string]images.ImageWorkResult{
+		"job1": {
+			ImageWorkRequest: images.ImageWorkRequest{Image: "foo:v1", Node: &auditNode, WorkType: images.ImageCacheRefresh},
+			Status:           images.ImageWorkResultStatusSucceeded,
+		},
+		"job2": {
+			ImageWorkRequest: images.ImageWorkRequest{Image: "bar:v1", Node: &auditNode, WorkType: images.ImageCachePurge},
+			Status:           images.ImageWorkResultStatusFailed,
+			Reason:           "Error",
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewAuditSink(path, "")
+	if err != nil {
+		t.Fatalf("NewAuditSink() failed: %v", err)
+	}
+	controller, nodeInformer, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	nodeInformer.Informer().GetIndexer().Add(&auditNode)
+	controller.auditSink = sink
+	controller.auditImageWork(imageCache, kubefledgedv1alpha2.ImageCacheReasonImageCacheRefresh, results)
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("error opening audit log: %v", err)
+	}
+	defer f.Close()
+	records := map[string]AuditRecord{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		r := AuditRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("invalid audit record %q: %v", scanner.Text(), err)
+		}
+		records[r.Image] = r
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 audit records, got %d", len(records))
+	}
+	if r := records["foo:v1"]; r.Operation != "pull" || r.Digest != "sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31" || r.Node != "node1" ||
+		r.Trigger != kubefledgedv1alpha2.ImageCacheReasonImageCacheRefresh || r.ImageCache != "kube-fledged/foo" {
+		t.Errorf("unexpected audit record of pull: %+v", r)
+	}
+	if r := records["bar:v1"]; r.Operation != "purge" || r.Result != images.ImageWorkResultStatusFailed || r.Digest != "" {
+		t.Errorf("unexpected audit record of purge: %+v", r)
+	}
+}
+
+func TestNewAuditSink(t *testing.T) {
+	sink, err := NewAuditSink("", "")
+	if err != nil || sink != nil {
+		t.Errorf("expected no audit sink, got %v, %v", sink, err)
+	}
+	if _, err := NewAuditSink(filepath.Join(t.TempDir(), "missing", "audit.log"), ""); err == nil {
+		t.Errorf("expected error opening audit log in missing directory")
+	}
+}
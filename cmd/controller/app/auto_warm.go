@@ -0,0 +1,294 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/imagelist"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// autoWarmAnnotationKey opts the images of a pod, or of the pods of a workload, into
+	// the image cache managed by auto-warm when set to "true"
+	autoWarmAnnotationKey = "fledged.k8s.io/warm"
+	// autoWarmImageCacheName is the name of the image cache managed by auto-warm, in
+	// the namespace of the controller
+	autoWarmImageCacheName = "kubefledged-auto-warm"
+	// autoWarmLabelKey labels the image cache managed by auto-warm
+	autoWarmLabelKey = "kubefledged.io/auto-warm"
+	// ReasonAutoWarmImagesUpdated is used as part of the Event 'reason' when the images
+	// of the image cache managed by auto-warm are updated
+	ReasonAutoWarmImagesUpdated = "AutoWarmImagesUpdated"
+)
+
+// runAutoWarmWorker collects the images of the pods and workloads annotated with
+// fledged.k8s.io/warm="true" across the cluster, and keeps the image cache managed by
+// auto-warm in line with them. App teams opt their images in without writing an
+// image cache.
+func (c *Controller) runAutoWarmWorker() {
+	podSpecs, err := c.autoWarmPodSpecs()
+	if err != nil {
+		glog.Errorf("Error listing workloads annotated for auto-warm: %v", err)
+		return
+	}
+	if err := c.syncAutoWarmImageCache(autoWarmCacheSpec(podSpecs, c.autoWarmContainers, c.defaultNodeOS)); err != nil {
+		glog.Errorf("Error syncing image cache %s/%s of auto-warm: %v", c.fledgedNameSpace, autoWarmImageCacheName, err)
+	}
+}
+
+// autoWarmPodSpecs returns the pod specs of the pods, Deployments, StatefulSets,
+// DaemonSets and CronJobs annotated for auto-warm, either on the object itself or on
+// its pod template. Pods which terminated are skipped.
+func (c *Controller) autoWarmPodSpecs() ([]corev1.PodSpec, error) {
+	listOptions := metav1.ListOptions{ResourceVersion: "0"}
+	podSpecs := []corev1.PodSpec{}
+	pods, err := c.kubeclientset.CoreV1().Pods("").List(context.TODO(), listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("error listing pods: %v", err)
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed && autoWarmAnnotated(pod.ObjectMeta) {
+			podSpecs = append(podSpecs, pod.Spec)
+		}
+	}
+	deployments, err := c.kubeclientset.AppsV1().Deployments("").List(context.TODO(), listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("error listing deployments: %v", err)
+	}
+	for _, d := range deployments.Items {
+		if autoWarmAnnotated(d.ObjectMeta, d.Spec.Template.ObjectMeta) {
+			podSpecs = append(podSpecs, d.Spec.Template.Spec)
+		}
+	}
+	statefulSets, err := c.kubeclientset.AppsV1().StatefulSets("").List(context.TODO(), listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("error listing statefulsets: %v", err)
+	}
+	for _, s := range statefulSets.Items {
+		if autoWarmAnnotated(s.ObjectMeta, s.Spec.Template.ObjectMeta) {
+			podSpecs = append(podSpecs, s.Spec.Template.Spec)
+		}
+	}
+	daemonSets, err := c.kubeclientset.AppsV1().DaemonSets("").List(context.TODO(), listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("error listing daemonsets: %v", err)
+	}
+	for _, d := range daemonSets.Items {
+		if autoWarmAnnotated(d.ObjectMeta, d.Spec.Template.ObjectMeta) {
+			podSpecs = append(podSpecs, d.Spec.Template.Spec)
+		}
+	}
+	cronJobs, err := c.kubeclientset.BatchV1().CronJobs("").List(context.TODO(), listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("error listing cronjobs: %v", err)
+	}
+	for _, j := range cronJobs.Items {
+		if autoWarmAnnotated(j.ObjectMeta, j.Spec.JobTemplate.ObjectMeta, j.Spec.JobTemplate.Spec.Template.ObjectMeta) {
+			podSpecs = append(podSpecs, j.Spec.JobTemplate.Spec.Template.Spec)
+		}
+	}
+	return podSpecs, nil
+}
+
+// autoWarmAnnotated returns true if one of the object metadata is annotated for
+// auto-warm
+func autoWarmAnnotated(objectMetas ...metav1.ObjectMeta) bool {
+	for _, objectMeta := range objectMetas {
+		if objectMeta.Annotations[autoWarmAnnotationKey] == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// autoWarmCacheSpec returns the cache spec of the image cache managed by auto-warm: the
+// images of the containers of the pod specs, and of their init and ephemeral containers
+// if selected, with one image list per node selector of the pod specs, so that images
+// are pulled only to the nodes the pods can be scheduled on. An image of pod specs whose
+// node selectors may match the same nodes is listed once, in the image list of the
+// labels common to these node selectors, since image lists targeting the same nodes
+// must not list the same image. Node selectors are compared as defaulted with
+// defaultNodeOS. Node affinity and taints are not taken into account. Invalid image
+// references are skipped.
+func autoWarmCacheSpec(podSpecs []corev1.PodSpec, containers imagelist.ContainerSelection, defaultNodeOS string) []v1alpha2.CacheSpecImages {
+	nodeSelectors := map[string][]map[string]string{}
+	for _, podSpec := range podSpecs {
+		for _, image := range imagelist.PodSpecImages(podSpec, containers) {
+			if _, ok := nodeSelectors[image]; !ok {
+				if err := images.ValidateImageReference(image); err != nil {
+					glog.Errorf("Image annotated for auto-warm skipped: %v", err)
+					continue
+				}
+			}
+			nodeSelectors[image] = addNodeSelector(nodeSelectors[image], podSpec.NodeSelector, defaultNodeOS)
+		}
+	}
+	imageLists := map[string]*v1alpha2.CacheSpecImages{}
+	for image, selectors := range nodeSelectors {
+		for _, nodeSelector := range selectors {
+			key := labels.Set(nodeSelector).String()
+			imageList, ok := imageLists[key]
+			if !ok {
+				imageList = &v1alpha2.CacheSpecImages{Images: []string{}}
+				if len(nodeSelector) > 0 {
+					imageList.NodeSelector = nodeSelector
+				}
+				imageLists[key] = imageList
+			}
+			imageList.Images = append(imageList.Images, image)
+		}
+	}
+	keys := []string{}
+	for key := range imageLists {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	cacheSpec := []v1alpha2.CacheSpecImages{}
+	for _, key := range keys {
+		sort.Strings(imageLists[key].Images)
+		cacheSpec = append(cacheSpec, *imageLists[key])
+	}
+	return cacheSpec
+}
+
+// addNodeSelector adds a node selector to the node selectors of an image, which never
+// overlap. The node selectors which may match the same nodes as the added one are
+// replaced, together with it, by the labels common to all of them.
+func addNodeSelector(nodeSelectors []map[string]string, nodeSelector map[string]string, defaultNodeOS string) []map[string]string {
+	merged := map[string]string{}
+	for k, v := range nodeSelector {
+		merged[k] = v
+	}
+	for {
+		overlapped := false
+		rest := []map[string]string{}
+		for _, s := range nodeSelectors {
+			if images.NodeSelectorsOverlap(images.EffectiveNodeSelector(s, defaultNodeOS), images.EffectiveNodeSelector(merged, defaultNodeOS)) {
+				for k, v := range merged {
+					if s[k] != v {
+						delete(merged, k)
+					}
+				}
+				overlapped = true
+				continue
+			}
+			rest = append(rest, s)
+		}
+		nodeSelectors = rest
+		if !overlapped {
+			return append(nodeSelectors, merged)
+		}
+	}
+}
+
+// autoWarmImageCache is the image cache managed by auto-warm
+var autoWarmImageCache = managedImageCache{
+	name:           autoWarmImageCacheName,
+	labelKey:       autoWarmLabelKey,
+	manager:        "auto-warm",
+	updatedReason:  ReasonAutoWarmImagesUpdated,
+	updatedMessage: "Images annotated for auto-warm updated",
+}
+
+// managedImageCache is an image cache which the controller creates, updates and deletes
+// itself, in its namespace
+type managedImageCache struct {
+	name string
+	// labelKey labels the image cache as managed by the controller
+	labelKey string
+	// manager is the feature of the controller managing the image cache
+	manager string
+	// imagePullPolicy overrides the image pull policy of the controller for the images
+	// of the image cache
+	imagePullPolicy corev1.PullPolicy
+	// updatedReason and updatedMessage are the Event reason and message recorded when
+	// the images of the image cache are updated
+	updatedReason  string
+	updatedMessage string
+}
+
+// syncAutoWarmImageCache creates, updates or deletes the image cache managed by
+// auto-warm so that its cache spec is the given one
+func (c *Controller) syncAutoWarmImageCache(cacheSpec []v1alpha2.CacheSpecImages) error {
+	return c.syncManagedImageCache(autoWarmImageCache, cacheSpec)
+}
+
+// syncManagedImageCache creates, updates or deletes a managed image cache so that its
+// cache spec is the given one. The image cache is deleted when there is no image to
+// cache anymore: its images stay on the nodes. An image cache of the same name which is
+// not managed by the controller is left untouched.
+func (c *Controller) syncManagedImageCache(managed managedImageCache, cacheSpec []v1alpha2.CacheSpecImages) error {
+	imageCache, err := c.imageCachesLister.ImageCaches(c.fledgedNameSpace).Get(managed.name)
+	if apierrors.IsNotFound(err) {
+		if len(cacheSpec) == 0 {
+			return nil
+		}
+		imageCache = &v1alpha2.ImageCache{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      managed.name,
+				Namespace: c.fledgedNameSpace,
+				Labels:    map[string]string{managed.labelKey: "true"},
+			},
+			Spec: v1alpha2.ImageCacheSpec{CacheSpec: cacheSpec, ImagePullPolicy: managed.imagePullPolicy},
+		}
+		if _, err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(c.fledgedNameSpace).Create(context.TODO(), imageCache, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+		glog.Infof("Image cache %s/%s of %s created", c.fledgedNameSpace, managed.name, managed.manager)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if imageCache.Labels[managed.labelKey] != "true" {
+		return fmt.Errorf("image cache not managed by %s: label %s missing", managed.manager, managed.labelKey)
+	}
+	if len(cacheSpec) == 0 {
+		err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(c.fledgedNameSpace).Delete(context.TODO(), managed.name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		glog.Infof("Image cache %s/%s of %s deleted: no image to cache", c.fledgedNameSpace, managed.name, managed.manager)
+		return nil
+	}
+	// The controller does not act on updates of an image cache under processing: the
+	// image cache is updated once processed
+	if reflect.DeepEqual(imageCache.Spec.CacheSpec, cacheSpec) || imageCache.Status.Status == "" ||
+		imageCache.Status.Status == v1alpha2.ImageCacheActionStatusProcessing ||
+		imageCache.Status.Status == v1alpha2.ImageCacheActionStatusPendingApproval {
+		return nil
+	}
+	updated := imageCache.DeepCopy()
+	updated.Spec.CacheSpec = cacheSpec
+	if _, err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(c.fledgedNameSpace).Update(context.TODO(), updated, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	glog.Infof("Images of image cache %s/%s of %s updated", c.fledgedNameSpace, managed.name, managed.manager)
+	c.recorder.Event(imageCache, corev1.EventTypeNormal, managed.updatedReason, managed.updatedMessage)
+	return nil
+}
@@ -0,0 +1,279 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	"github.com/senthilrch/kube-fledged/pkg/imagelist"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestAutoWarmPodSpecs(t *testing.T) {
+	warm := map[string]string{autoWarmAnnotationKey: "true"}
+	podSpec := func(image string) corev1.PodSpec {
+		return corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}}
+	}
+	kubeclientset := fakeclientset.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "running", Namespace: "default", Annotations: warm},
+			Spec: podSpec("pod:v1"), Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "completed", Namespace: "default", Annotations: warm},
+			Spec: podSpec("completed:v1"), Status: corev1.PodStatus{Phase: corev1.PodSucceeded}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"}, Spec: podSpec("other:v1")},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "team-a", Annotations: warm},
+			Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpec("deployment:v1")}}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "team-a"},
+			Spec: appsv1.StatefulSetSpec{Template: corev1.PodTemplateSpec{Spec: podSpec("statefulset:v1")}}},
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "team-b"},
+			Spec: appsv1.DaemonSetSpec{Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Annotations: warm}, Spec: podSpec("daemonset:v1")}}},
+		&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "report", Namespace: "team-b"},
+			Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{
+				Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Annotations: warm}, Spec: podSpec("cronjob:v1")}}}}},
+	)
+	controller, _, _ := newTestController(kubeclientset, kubefledgedclientsetfake.NewSimpleClientset())
+	podSpecs, err := controller.autoWarmPodSpecs()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	actual := []string{}
+	for _, podSpec := range podSpecs {
+		actual = append(actual, podSpec.Containers[0].Image)
+	}
+	expected := []string{"pod:v1", "deployment:v1", "daemonset:v1", "cronjob:v1"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected images %v, actual %v", expected, actual)
+	}
+}
+
+func TestAutoWarmCacheSpec(t *testing.T) {
+	gpu := map[string]string{"accelerator": "gpu"}
+	podSpecs := []corev1.PodSpec{
+		{
+			InitContainers: []corev1.Container{{Name: "init", Image: "busybox:1.35"}},
+			Containers:     []corev1.Container{{Name: "app", Image: "nginx:1.23"}},
+		},
+		{
+			NodeSelector: gpu,
+			Containers:   []corev1.Container{{Name: "train", Image: "pytorch:2.0"}, {Name: "sidecar", Image: "nginx:1.23"}},
+		},
+		{
+			Containers: []corev1.Container{{Name: "app", Image: "nginx:1.23"}, {Name: "bad", Image: "Invalid:Image"}},
+		},
+	}
+	expected := []kubefledgedv1alpha2.CacheSpecImages{
+		{Images: []string{"busybox:1.35", "nginx:1.23"}},
+		{Images: []string{"pytorch:2.0"}, NodeSelector: gpu},
+	}
+	if actual := autoWarmCacheSpec(podSpecs, imagelist.ContainerSelection{InitContainers: true}, "linux"); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected cache spec %+v, actual %+v", expected, actual)
+	}
+	expected[0].Images = []string{"nginx:1.23"}
+	if actual := autoWarmCacheSpec(podSpecs, imagelist.ContainerSelection{}, "linux"); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected cache spec without init containers %+v, actual %+v", expected, actual)
+	}
+}
+
+func TestAutoWarmCacheSpecOverlappingNodeSelectors(t *testing.T) {
+	podSpec := func(nodeSelector map[string]string, images ...string) corev1.PodSpec {
+		podSpec := corev1.PodSpec{NodeSelector: nodeSelector}
+		for _, image := range images {
+			podSpec.Containers = append(podSpec.Containers, corev1.Container{Name: "app", Image: image})
+		}
+		return podSpec
+	}
+	tests := []struct {
+		name          string
+		podSpecs      []corev1.PodSpec
+		defaultNodeOS string
+		expected      []kubefledgedv1alpha2.CacheSpecImages
+	}{
+		{
+			name:          "#1: Image of pods with and without node selector",
+			podSpecs:      []corev1.PodSpec{podSpec(nil, "nginx:1.23"), podSpec(map[string]string{"pool": "a"}, "nginx:1.23", "redis:7")},
+			defaultNodeOS: "linux",
+			expected: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx:1.23"}},
+				{Images: []string{"redis:7"}, NodeSelector: map[string]string{"pool": "a"}},
+			},
+		},
+		{
+			name: "#2: Image of pods with nested node selectors",
+			podSpecs: []corev1.PodSpec{
+				podSpec(map[string]string{"pool": "a", "zone": "x"}, "nginx:1.23"), podSpec(map[string]string{"pool": "a"}, "nginx:1.23"),
+			},
+			defaultNodeOS: "linux",
+			expected: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx:1.23"}, NodeSelector: map[string]string{"pool": "a"}},
+			},
+		},
+		{
+			name: "#3: Image of pods with node selectors overlapping through a third one",
+			podSpecs: []corev1.PodSpec{
+				podSpec(map[string]string{"pool": "a"}, "nginx:1.23"), podSpec(map[string]string{"pool": "b"}, "nginx:1.23"),
+				podSpec(map[string]string{"zone": "x"}, "nginx:1.23"),
+			},
+			defaultNodeOS: "linux",
+			expected: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx:1.23"}},
+			},
+		},
+		{
+			name:          "#4: Image of pods of the default node OS and of windows nodes",
+			podSpecs:      []corev1.PodSpec{podSpec(nil, "nginx:1.23"), podSpec(map[string]string{"kubernetes.io/os": "windows"}, "nginx:1.23")},
+			defaultNodeOS: "linux",
+			expected: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx:1.23"}},
+				{Images: []string{"nginx:1.23"}, NodeSelector: map[string]string{"kubernetes.io/os": "windows"}},
+			},
+		},
+		{
+			name:     "#5: Image of pods of all nodes and of windows nodes without a default node OS",
+			podSpecs: []corev1.PodSpec{podSpec(nil, "nginx:1.23"), podSpec(map[string]string{"kubernetes.io/os": "windows"}, "nginx:1.23")},
+			expected: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx:1.23"}},
+			},
+		},
+		{
+			name:          "#6: Image of pods with disjoint node selectors",
+			podSpecs:      []corev1.PodSpec{podSpec(map[string]string{"pool": "a"}, "nginx:1.23"), podSpec(map[string]string{"pool": "b"}, "nginx:1.23")},
+			defaultNodeOS: "linux",
+			expected: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx:1.23"}, NodeSelector: map[string]string{"pool": "a"}},
+				{Images: []string{"nginx:1.23"}, NodeSelector: map[string]string{"pool": "b"}},
+			},
+		},
+	}
+	for _, test := range tests {
+		actual := autoWarmCacheSpec(test.podSpecs, imagelist.ContainerSelection{}, test.defaultNodeOS)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test: %s failed: expected cache spec %+v, actual %+v", test.name, test.expected, actual)
+		}
+		if err := images.ValidateNoDuplicateImages(actual, test.defaultNodeOS); err != nil {
+			t.Errorf("Test: %s failed: cache spec rejected: %v", test.name, err)
+		}
+	}
+}
+
+func TestSyncAutoWarmImageCache(t *testing.T) {
+	cacheSpec := []kubefledgedv1alpha2.CacheSpecImages{{Images: []string{"nginx:1.23"}}}
+	managed := func(status kubefledgedv1alpha2.ImageCacheActionStatus, images ...string) *kubefledgedv1alpha2.ImageCache {
+		return &kubefledgedv1alpha2.ImageCache{
+			ObjectMeta: metav1.ObjectMeta{Name: autoWarmImageCacheName, Namespace: fledgedNameSpace, Labels: map[string]string{autoWarmLabelKey: "true"}},
+			Spec:       kubefledgedv1alpha2.ImageCacheSpec{CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{{Images: images}}},
+			Status:     kubefledgedv1alpha2.ImageCacheStatus{Status: status},
+		}
+	}
+	unmanaged := managed(kubefledgedv1alpha2.ImageCacheActionStatusSucceeded, "redis:7")
+	unmanaged.Labels = nil
+	tests := []struct {
+		name           string
+		imageCache     *kubefledgedv1alpha2.ImageCache
+		cacheSpec      []kubefledgedv1alpha2.CacheSpecImages
+		expectedAction string
+		expectErr      bool
+	}{
+		{
+			name:           "#1: Image cache created",
+			cacheSpec:      cacheSpec,
+			expectedAction: "create",
+		},
+		{
+			name:      "#2: No image annotated and no image cache",
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{},
+		},
+		{
+			name:           "#3: Images of image cache updated",
+			imageCache:     managed(kubefledgedv1alpha2.ImageCacheActionStatusSucceeded, "redis:7"),
+			cacheSpec:      cacheSpec,
+			expectedAction: "update",
+		},
+		{
+			name:       "#4: Image cache unchanged",
+			imageCache: managed(kubefledgedv1alpha2.ImageCacheActionStatusSucceeded, "nginx:1.23"),
+			cacheSpec:  cacheSpec,
+		},
+		{
+			name:       "#5: Image cache under processing not updated",
+			imageCache: managed(kubefledgedv1alpha2.ImageCacheActionStatusProcessing, "redis:7"),
+			cacheSpec:  cacheSpec,
+		},
+		{
+			name:           "#6: Image cache deleted when no image is annotated",
+			imageCache:     managed(kubefledgedv1alpha2.ImageCacheActionStatusSucceeded, "redis:7"),
+			cacheSpec:      []kubefledgedv1alpha2.CacheSpecImages{},
+			expectedAction: "delete",
+		},
+		{
+			name:       "#7: Image cache not managed by auto-warm",
+			imageCache: unmanaged,
+			cacheSpec:  cacheSpec,
+			expectErr:  true,
+		},
+	}
+	for _, test := range tests {
+		objects := []runtime.Object{}
+		if test.imageCache != nil {
+			objects = append(objects, test.imageCache)
+		}
+		fledgedclientset := kubefledgedclientsetfake.NewSimpleClientset(objects...)
+		controller, _, imagecacheInformer := newTestController(fakeclientset.NewSimpleClientset(), fledgedclientset)
+		if test.imageCache != nil {
+			imagecacheInformer.Informer().GetIndexer().Add(test.imageCache)
+		}
+		err := controller.syncAutoWarmImageCache(test.cacheSpec)
+		if (err != nil) != test.expectErr {
+			t.Errorf("Test: %s failed: expectErr=%t, actual error=%v", test.name, test.expectErr, err)
+			continue
+		}
+		actions := []string{}
+		for _, action := range fledgedclientset.Actions() {
+			if action.GetVerb() != "get" && action.GetVerb() != "list" && action.GetVerb() != "watch" {
+				actions = append(actions, action.GetVerb())
+			}
+		}
+		expectedActions := []string{}
+		if test.expectedAction != "" {
+			expectedActions = append(expectedActions, test.expectedAction)
+		}
+		if !reflect.DeepEqual(actions, expectedActions) {
+			t.Errorf("Test: %s failed: expected actions %v, actual %v", test.name, expectedActions, actions)
+			continue
+		}
+		imageCache, err := fledgedclientset.KubefledgedV1alpha2().ImageCaches(fledgedNameSpace).Get(context.TODO(), autoWarmImageCacheName, metav1.GetOptions{})
+		switch test.expectedAction {
+		case "delete":
+			if !apierrors.IsNotFound(err) {
+				t.Errorf("Test: %s failed: expected image cache to be deleted, actual error %v", test.name, err)
+			}
+		case "create", "update":
+			if err != nil || !reflect.DeepEqual(imageCache.Spec.CacheSpec, test.cacheSpec) || imageCache.Labels[autoWarmLabelKey] != "true" {
+				t.Errorf("Test: %s failed: expected managed image cache with cache spec %+v, actual %+v (error %v)", test.name, test.cacheSpec, imageCache, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"path"
+	"reflect"
+
+	"github.com/docker/distribution/reference"
+	"github.com/golang/glog"
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/registry"
+)
+
+// hasCatalogs returns true if an image list of the cache spec has registry catalogs
+func hasCatalogs(cacheSpec []v1alpha2.CacheSpecImages) bool {
+	for _, i := range cacheSpec {
+		if len(i.Catalogs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// syncCatalogs returns the cache spec with the images of each image list updated with
+// the images matching its catalogs, and whether the cache spec changed. A catalog that
+// cannot be listed leaves the images of the image list as is.
+func (c *Controller) syncCatalogs(imageCache *v1alpha2.ImageCache, cacheSpec []v1alpha2.CacheSpecImages) ([]v1alpha2.CacheSpecImages, bool) {
+	// Repositories of a registry and tags of a repository are listed once
+	repositories := map[string][]string{}
+	tags := map[string][]string{}
+	result := []v1alpha2.CacheSpecImages{}
+	changed := false
+	for _, i := range cacheSpec {
+		i = *i.DeepCopy()
+		for _, catalog := range i.Catalogs {
+			matched, err := c.matchCatalog(catalog, c.keychainOf(imageCache), repositories, tags)
+			if err != nil {
+				glog.Errorf("Error listing catalog %s of image cache %s/%s: %v", catalog.Repositories, imageCache.Namespace, imageCache.Name, err)
+				continue
+			}
+			images := catalogImages(i.Images, catalog, matched)
+			if !reflect.DeepEqual(images, i.Images) {
+				i.Images = images
+				changed = true
+			}
+		}
+		result = append(result, i)
+	}
+	return result, changed
+}
+
+// matchCatalog returns the images of the repositories and tags matching the catalog,
+// listed with the keychain. repositories and tags cache the repositories listed per
+// registry and the tags listed per repository.
+func (c *Controller) matchCatalog(catalog v1alpha2.RegistryCatalog, keychain authn.Keychain, repositories, tags map[string][]string) ([]string, error) {
+	registryName, glob, err := registry.SplitRepositoryGlob(catalog.Repositories)
+	if err != nil {
+		return nil, err
+	}
+	repos, ok := repositories[registryName]
+	if !ok {
+		if repos, err = c.catalogLister.ListRepositories(registryName, keychain); err != nil {
+			return nil, err
+		}
+		repositories[registryName] = repos
+	}
+	matchedRepos, err := registry.MatchGlob(repos, glob)
+	if err != nil {
+		return nil, err
+	}
+	images := []string{}
+	for _, repo := range matchedRepos {
+		repository := registryName + "/" + repo
+		repoTags, ok := tags[repository]
+		if !ok {
+			if repoTags, err = c.tagLister.ListTags(repository, keychain); err != nil {
+				return nil, err
+			}
+			tags[repository] = repoTags
+		}
+		matchedTags, err := registry.MatchGlob(repoTags, catalog.Tags)
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range matchedTags {
+			images = append(images, repository+":"+tag)
+		}
+	}
+	return images, nil
+}
+
+// catalogImages adds the matched images not yet listed to the images. If the catalog is
+// pruned, the tagged images of the repositories matching the catalog which were not
+// matched are removed.
+func catalogImages(images []string, catalog v1alpha2.RegistryCatalog, matched []string) []string {
+	registryName, glob, err := registry.SplitRepositoryGlob(catalog.Repositories)
+	if err != nil {
+		return images
+	}
+	result := []string{}
+	for _, image := range images {
+		if catalog.Prune && !containsString(matched, image) && inCatalog(image, registryName, glob) {
+			continue
+		}
+		result = append(result, image)
+	}
+	for _, image := range matched {
+		if !containsString(result, image) {
+			result = append(result, image)
+		}
+	}
+	return result
+}
+
+// inCatalog returns true if the image is a tagged image of the registry whose
+// repository matches the glob
+func inCatalog(image, registryName, glob string) bool {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return false
+	}
+	if _, ok := named.(reference.Tagged); !ok || reference.Domain(named) != registryName {
+		return false
+	}
+	ok, _ := path.Match(glob, reference.Path(named))
+	return ok
+}
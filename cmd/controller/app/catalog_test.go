@@ -0,0 +1,106 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+type fakeCatalogLister map[string][]string
+
+func (f fakeCatalogLister) ListRepositories(registry string, keychain authn.Keychain) ([]string, error) {
+	repositories, ok := f[registry]
+	if !ok {
+		return nil, fmt.Errorf("registry %s not found", registry)
+	}
+	return repositories, nil
+}
+
+func TestSyncCatalogs(t *testing.T) {
+	tests := []struct {
+		name            string
+		images          []string
+		catalog         kubefledgedv1alpha2.RegistryCatalog
+		expectedImages  []string
+		expectedChanged bool
+	}{
+		{
+			name:            "#1: Matching images added",
+			images:          []string{"nginx:1.25"},
+			catalog:         kubefledgedv1alpha2.RegistryCatalog{Repositories: "registry.internal/platform/*", Tags: "v1.*"},
+			expectedImages:  []string{"nginx:1.25", "registry.internal/platform/api:v1.0", "registry.internal/platform/api:v1.1", "registry.internal/platform/worker:v1.0"},
+			expectedChanged: true,
+		},
+		{
+			name:           "#2: Matching images already listed",
+			images:         []string{"registry.internal/platform/api:v1.0", "registry.internal/platform/api:v1.1", "registry.internal/platform/worker:v1.0"},
+			catalog:        kubefledgedv1alpha2.RegistryCatalog{Repositories: "registry.internal/platform/*", Tags: "v1.*"},
+			expectedImages: []string{"registry.internal/platform/api:v1.0", "registry.internal/platform/api:v1.1", "registry.internal/platform/worker:v1.0"},
+		},
+		{
+			name:            "#3: Images no longer matching pruned",
+			images:          []string{"nginx:1.25", "registry.internal/platform/api:v0.9", "registry.internal/platform/removed:v1.0", "registry.internal/apps/web:v0.1"},
+			catalog:         kubefledgedv1alpha2.RegistryCatalog{Repositories: "registry.internal/platform/*", Tags: "v1.1", Prune: true},
+			expectedImages:  []string{"nginx:1.25", "registry.internal/apps/web:v0.1", "registry.internal/platform/api:v1.1"},
+			expectedChanged: true,
+		},
+		{
+			name:            "#4: Images no longer matching kept",
+			images:          []string{"registry.internal/platform/api:v0.9"},
+			catalog:         kubefledgedv1alpha2.RegistryCatalog{Repositories: "registry.internal/platform/*", Tags: "v1.1"},
+			expectedImages:  []string{"registry.internal/platform/api:v0.9", "registry.internal/platform/api:v1.1"},
+			expectedChanged: true,
+		},
+		{
+			name:           "#5: Catalog of unreachable registry",
+			images:         []string{"registry.other/platform/api:v0.9"},
+			catalog:        kubefledgedv1alpha2.RegistryCatalog{Repositories: "registry.other/platform/*", Tags: "v1.*", Prune: true},
+			expectedImages: []string{"registry.other/platform/api:v0.9"},
+		},
+	}
+	controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	controller.catalogLister = fakeCatalogLister{"registry.internal": {"apps/web", "platform/api", "platform/worker", "platform/tools/cli"}}
+	controller.tagLister = fakeTagLister{
+		"registry.internal/platform/api":    {"v0.9", "v1.0", "v1.1", "latest"},
+		"registry.internal/platform/worker": {"v1.0"},
+	}
+	for _, test := range tests {
+		imageCache := &kubefledgedv1alpha2.ImageCache{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+			Spec: kubefledgedv1alpha2.ImageCacheSpec{
+				CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+					{Images: test.images, Catalogs: []kubefledgedv1alpha2.RegistryCatalog{test.catalog}},
+				},
+			},
+		}
+		cacheSpec, changed := controller.syncCatalogs(imageCache, imageCache.Spec.CacheSpec)
+		if changed != test.expectedChanged {
+			t.Errorf("Test: %s failed: expectedChanged=%t, actualChanged=%t", test.name, test.expectedChanged, changed)
+		}
+		if actual := cacheSpec[0].Images; !reflect.DeepEqual(actual, test.expectedImages) {
+			t.Errorf("Test: %s failed: expectedImages=%v, actualImages=%v", test.name, test.expectedImages, actual)
+		}
+	}
+}
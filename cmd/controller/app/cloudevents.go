@@ -0,0 +1,216 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+// Types of the CloudEvents published on image cache state transitions
+const (
+	CloudEventTypeCreated        = "io.kubefledged.imagecache.created"
+	CloudEventTypeProcessing     = "io.kubefledged.imagecache.processing"
+	CloudEventTypeRefreshStarted = "io.kubefledged.imagecache.refresh.started"
+	CloudEventTypeSucceeded      = "io.kubefledged.imagecache.succeeded"
+	CloudEventTypeFailed         = "io.kubefledged.imagecache.failed"
+)
+
+const (
+	// cloudEventSpecVersion is the version of the CloudEvents specification
+	cloudEventSpecVersion = "1.0"
+	// cloudEventQueueSize is the number of events waiting to be published, beyond
+	// which events are dropped
+	cloudEventQueueSize = 1000
+)
+
+// CloudEvent is a CloudEvent published on an image cache state transition
+type CloudEvent struct {
+	ID      string
+	Source  string
+	Type    string
+	Subject string
+	Time    time.Time
+	Data    ImageCacheEventData
+}
+
+// ImageCacheEventData is the data of the CloudEvents published on image cache state
+// transitions
+type ImageCacheEventData struct {
+	Namespace  string `json:"namespace"`
+	ImageCache string `json:"imageCache"`
+	// Action is the image cache action i.e. ImageCacheCreate, ImageCacheUpdate,
+	// ImageCacheRefresh or ImageCachePurge
+	Action         string                          `json:"action"`
+	Status         v1alpha2.ImageCacheActionStatus `json:"status"`
+	Reason         string                          `json:"reason,omitempty"`
+	Message        string                          `json:"message,omitempty"`
+	StartTime      *time.Time                      `json:"startTime,omitempty"`
+	CompletionTime *time.Time                      `json:"completionTime,omitempty"`
+	// Failures is the number of nodes on which image pulls or purges failed
+	Failures int `json:"failures,omitempty"`
+}
+
+// CloudEventSink is a destination of CloudEvents
+type CloudEventSink interface {
+	Send(event CloudEvent)
+}
+
+// NewCloudEventSink returns a sink posting CloudEvents in HTTP binary content mode to
+// url, e.g. a Knative broker or an Argo Events webhook event source. Events are posted
+// in the background in the order they are sent. It returns nil if url is not specified.
+func NewCloudEventSink(url string) CloudEventSink {
+	if url == "" {
+		return nil
+	}
+	s := &httpCloudEventSink{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		events: make(chan CloudEvent, cloudEventQueueSize),
+	}
+	go s.run()
+	return s
+}
+
+// httpCloudEventSink posts CloudEvents to an HTTP endpoint
+type httpCloudEventSink struct {
+	url    string
+	client *http.Client
+	events chan CloudEvent
+}
+
+func (s *httpCloudEventSink) Send(event CloudEvent) {
+	select {
+	case s.events <- event:
+	default:
+		glog.Errorf("CloudEvent %s of %s dropped: too many events waiting to be published", event.Type, event.Subject)
+	}
+}
+
+func (s *httpCloudEventSink) run() {
+	for event := range s.events {
+		if err := s.post(event); err != nil {
+			glog.Errorf("Error publishing CloudEvent %s of %s: %v", event.Type, event.Subject, err)
+		}
+	}
+}
+
+func (s *httpCloudEventSink) post(event CloudEvent) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("ce-specversion", cloudEventSpecVersion)
+	req.Header.Set("ce-id", event.ID)
+	req.Header.Set("ce-source", event.Source)
+	req.Header.Set("ce-type", event.Type)
+	req.Header.Set("ce-subject", event.Subject)
+	req.Header.Set("ce-time", event.Time.UTC().Format(time.RFC3339Nano))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("CloudEvents sink %s returned %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+// publishActionStarted publishes the CloudEvents of an image cache action that started
+// processing: created or refresh started for the creation and refresh of the image cache,
+// followed by processing for all actions
+func (c *Controller) publishActionStarted(imageCache *v1alpha2.ImageCache, workType images.WorkType, status *v1alpha2.ImageCacheStatus) {
+	if c.cloudEventSink == nil {
+		return
+	}
+	switch workType {
+	case images.ImageCacheCreate:
+		c.publish(imageCache, CloudEventTypeCreated, actionReason(workType), status)
+	case images.ImageCacheRefresh:
+		c.publish(imageCache, CloudEventTypeRefreshStarted, actionReason(workType), status)
+	}
+	c.publish(imageCache, CloudEventTypeProcessing, actionReason(workType), status)
+}
+
+// publishActionFinished publishes the succeeded or failed CloudEvent of a finished image
+// cache action
+func (c *Controller) publishActionFinished(imageCache *v1alpha2.ImageCache, action string, status *v1alpha2.ImageCacheStatus) {
+	if c.cloudEventSink == nil {
+		return
+	}
+	eventType := CloudEventTypeFailed
+	if status.Status == v1alpha2.ImageCacheActionStatusSucceeded || status.Status == v1alpha2.ImageCacheActioneNoImagesPulledOrDeleted {
+		eventType = CloudEventTypeSucceeded
+	}
+	c.publish(imageCache, eventType, action, status)
+}
+
+// actionReason returns the status reason of the image cache action of the work type
+func actionReason(workType images.WorkType) string {
+	switch workType {
+	case images.ImageCacheCreate:
+		return v1alpha2.ImageCacheReasonImageCacheCreate
+	case images.ImageCacheUpdate:
+		return v1alpha2.ImageCacheReasonImageCacheUpdate
+	case images.ImageCacheRefresh:
+		return v1alpha2.ImageCacheReasonImageCacheRefresh
+	case images.ImageCachePurge:
+		return v1alpha2.ImageCacheReasonImageCachePurge
+	}
+	return string(workType)
+}
+
+func (c *Controller) publish(imageCache *v1alpha2.ImageCache, eventType, action string, status *v1alpha2.ImageCacheStatus) {
+	now := time.Now()
+	data := ImageCacheEventData{
+		Namespace:  imageCache.Namespace,
+		ImageCache: imageCache.Name,
+		Action:     action,
+		Status:     status.Status,
+		Reason:     status.Reason,
+		Message:    status.Message,
+		Failures:   len(status.Failures),
+	}
+	if status.StartTime != nil {
+		data.StartTime = &status.StartTime.Time
+	}
+	if status.Status != v1alpha2.ImageCacheActionStatusProcessing {
+		data.CompletionTime = &now
+	}
+	c.cloudEventSink.Send(CloudEvent{
+		ID:      string(uuid.NewUUID()),
+		Source:  fmt.Sprintf("/apis/%s/namespaces/%s/imagecaches", v1alpha2.SchemeGroupVersion.String(), imageCache.Namespace),
+		Type:    eventType,
+		Subject: imageCache.Name,
+		Time:    now,
+		Data:    data,
+	})
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeCloudEventSink records the CloudEvents sent to it
+type fakeCloudEventSink struct {
+	events []CloudEvent
+}
+
+func (s *fakeCloudEventSink) Send(event CloudEvent) {
+	s.events = append(s.events, event)
+}
+
+func TestHTTPCloudEventSink(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	var data ImageCacheEventData
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			t.Errorf("Error decoding CloudEvent data: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		received <- r
+	}))
+	defer server.Close()
+
+	if NewCloudEventSink("") != nil {
+		t.Errorf("Test failed: expected no sink without url")
+	}
+	sink := NewCloudEventSink(server.URL)
+	sink.Send(CloudEvent{
+		ID:      "1234",
+		Source:  "/apis/kubefledged.io/v1alpha2/namespaces/kube-fledged/imagecaches",
+		Type:    CloudEventTypeSucceeded,
+		Subject: "foo",
+		Time:    time.Now(),
+		Data:    ImageCacheEventData{Namespace: "kube-fledged", ImageCache: "foo", Status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded},
+	})
+	select {
+	case r := <-received:
+		expected := map[string]string{
+			"Content-Type":   "application/json",
+			"Ce-Specversion": "1.0",
+			"Ce-Id":          "1234",
+			"Ce-Type":        CloudEventTypeSucceeded,
+			"Ce-Subject":     "foo",
+		}
+		for header, value := range expected {
+			if r.Header.Get(header) != value {
+				t.Errorf("Test failed: expected header %s=%s, actual=%s", header, value, r.Header.Get(header))
+			}
+		}
+		if data.ImageCache != "foo" || data.Status != kubefledgedv1alpha2.ImageCacheActionStatusSucceeded {
+			t.Errorf("Test failed: unexpected data %+v", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Test failed: CloudEvent not posted")
+	}
+}
+
+func TestPublishAction(t *testing.T) {
+	tests := []struct {
+		name           string
+		workType       images.WorkType
+		status         kubefledgedv1alpha2.ImageCacheActionStatus
+		expectedTypes  []string
+		expectedAction string
+	}{
+		{
+			name:           "#1: Image cache created",
+			workType:       images.ImageCacheCreate,
+			status:         kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+			expectedTypes:  []string{CloudEventTypeCreated, CloudEventTypeProcessing},
+			expectedAction: kubefledgedv1alpha2.ImageCacheReasonImageCacheCreate,
+		},
+		{
+			name:           "#2: Image cache refresh started",
+			workType:       images.ImageCacheRefresh,
+			status:         kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+			expectedTypes:  []string{CloudEventTypeRefreshStarted, CloudEventTypeProcessing},
+			expectedAction: kubefledgedv1alpha2.ImageCacheReasonImageCacheRefresh,
+		},
+		{
+			name:           "#3: Image cache update started",
+			workType:       images.ImageCacheUpdate,
+			status:         kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+			expectedTypes:  []string{CloudEventTypeProcessing},
+			expectedAction: kubefledgedv1alpha2.ImageCacheReasonImageCacheUpdate,
+		},
+		{
+			name:           "#4: Image cache action succeeded",
+			workType:       images.ImageCachePurge,
+			status:         kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			expectedTypes:  []string{CloudEventTypeSucceeded},
+			expectedAction: kubefledgedv1alpha2.ImageCacheReasonImageCachePurge,
+		},
+		{
+			name:           "#5: No images pulled",
+			workType:       images.ImageCacheUpdate,
+			status:         kubefledgedv1alpha2.ImageCacheActioneNoImagesPulledOrDeleted,
+			expectedTypes:  []string{CloudEventTypeSucceeded},
+			expectedAction: kubefledgedv1alpha2.ImageCacheReasonImageCacheUpdate,
+		},
+		{
+			name:           "#6: Image cache action failed",
+			workType:       images.ImageCacheCreate,
+			status:         kubefledgedv1alpha2.ImageCacheActionStatusFailed,
+			expectedTypes:  []string{CloudEventTypeFailed},
+			expectedAction: kubefledgedv1alpha2.ImageCacheReasonImageCacheCreate,
+		},
+		{
+			name:           "#7: Image cache action aborted",
+			workType:       images.ImageCacheRefresh,
+			status:         kubefledgedv1alpha2.ImageCacheActionStatusAborted,
+			expectedTypes:  []string{CloudEventTypeFailed},
+			expectedAction: kubefledgedv1alpha2.ImageCacheReasonImageCacheRefresh,
+		},
+	}
+	imageCache := &kubefledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+	}
+	controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	for _, test := range tests {
+		sink := &fakeCloudEventSink{}
+		controller.cloudEventSink = sink
+		startTime := metav1.Now()
+		status := &kubefledgedv1alpha2.ImageCacheStatus{Status: test.status, StartTime: &startTime}
+		if test.status == kubefledgedv1alpha2.ImageCacheActionStatusProcessing {
+			controller.publishActionStarted(imageCache, test.workType, status)
+		} else {
+			controller.publishActionFinished(imageCache, actionReason(test.workType), status)
+		}
+		types := []string{}
+		for _, event := range sink.events {
+			types = append(types, event.Type)
+			if event.Subject != "foo" || event.Data.Action != test.expectedAction || event.Data.Status != test.status {
+				t.Errorf("Test: %s failed: unexpected event %+v", test.name, event)
+			}
+			finished := test.status != kubefledgedv1alpha2.ImageCacheActionStatusProcessing
+			if (event.Data.CompletionTime != nil) != finished {
+				t.Errorf("Test: %s failed: expected completion time only for finished actions, actual=%v", test.name, event.Data.CompletionTime)
+			}
+		}
+		if !reflect.DeepEqual(types, test.expectedTypes) {
+			t.Errorf("Test: %s failed: expected=%v, actual=%v", test.name, test.expectedTypes, types)
+		}
+	}
+}
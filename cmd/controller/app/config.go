@@ -0,0 +1,196 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Keys of the settings of the configuration ConfigMap
+const (
+	configKeyRefreshFrequency  = "imageCacheRefreshFrequency"
+	configKeyMaxConcurrentJobs = "maxConcurrentJobs"
+	configKeyAllowedRegistries = "allowedRegistries"
+	configKeyCRIClientImage    = "criClientImage"
+	configKeyBusyboxImage      = "busyboxImage"
+)
+
+const (
+	// ReasonInvalidConfig is used as part of the Event 'reason' when the configuration
+	// ConfigMap cannot be loaded
+	ReasonInvalidConfig = "InvalidConfig"
+	// refreshLoopCheckInterval is the interval at which the refresh loop checks for
+	// changes of the refresh frequency
+	refreshLoopCheckInterval = 10 * time.Second
+)
+
+// tunables are the settings of the controller reloaded from the configuration ConfigMap
+// without restarting the controller
+type tunables struct {
+	refreshFrequency  time.Duration
+	maxConcurrentJobs int
+	allowedRegistries []string
+	criClientImage    string
+	busyboxImage      string
+}
+
+// parseTunables parses the settings of the configuration ConfigMap. The settings
+// missing from the ConfigMap take their default values.
+func parseTunables(data map[string]string, defaults tunables) (tunables, error) {
+	t := defaults
+	if v, ok := data[configKeyRefreshFrequency]; ok {
+		d, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || d < 0 {
+			return t, fmt.Errorf("invalid %s %q: expected a positive duration", configKeyRefreshFrequency, v)
+		}
+		t.refreshFrequency = d
+	}
+	if v, ok := data[configKeyMaxConcurrentJobs]; ok {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 0 {
+			return t, fmt.Errorf("invalid %s %q: expected a positive number", configKeyMaxConcurrentJobs, v)
+		}
+		t.maxConcurrentJobs = n
+	}
+	if v, ok := data[configKeyAllowedRegistries]; ok {
+		t.allowedRegistries = nil
+		for _, r := range strings.Split(v, ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				t.allowedRegistries = append(t.allowedRegistries, r)
+			}
+		}
+	}
+	for key, image := range map[string]*string{configKeyCRIClientImage: &t.criClientImage, configKeyBusyboxImage: &t.busyboxImage} {
+		v, ok := data[key]
+		if !ok {
+			continue
+		}
+		if _, err := reference.ParseNormalizedNamed(strings.TrimSpace(v)); err != nil {
+			return t, fmt.Errorf("invalid %s %q: %v", key, v, err)
+		}
+		*image = strings.TrimSpace(v)
+	}
+	return t, nil
+}
+
+// watchConfigMap sets up the informer of the configuration ConfigMap, whose settings
+// are applied whenever it changes. The settings given by flags are restored when the
+// ConfigMap is deleted.
+func (c *Controller) watchConfigMap(namespace, name string) {
+	c.configMapInformerFactory = kubeinformers.NewSharedInformerFactoryWithOptions(c.kubeclientset, time.Second*30,
+		kubeinformers.WithNamespace(namespace),
+		kubeinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}))
+	informer := c.configMapInformerFactory.Core().V1().ConfigMaps().Informer()
+	c.configMapsSynced = informer.HasSynced
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.applyConfigMap(obj.(*corev1.ConfigMap))
+		},
+		UpdateFunc: func(old, new interface{}) {
+			if old.(*corev1.ConfigMap).ResourceVersion == new.(*corev1.ConfigMap).ResourceVersion {
+				return
+			}
+			c.applyConfigMap(new.(*corev1.ConfigMap))
+		},
+		DeleteFunc: func(obj interface{}) {
+			c.applyConfigMap(nil)
+		},
+	})
+}
+
+// applyConfigMap applies the settings of the configuration ConfigMap, or the settings
+// given by flags if it is nil. An invalid ConfigMap is reported and ignored, keeping
+// the current settings. In-flight image cache actions are not interrupted: the new
+// images and limits apply to the jobs created from now on.
+func (c *Controller) applyConfigMap(configMap *corev1.ConfigMap) {
+	t := c.flagTunables
+	if configMap != nil {
+		var err error
+		if t, err = parseTunables(configMap.Data, c.flagTunables); err != nil {
+			glog.Errorf("Error loading configuration from ConfigMap %s/%s, keeping the current configuration: %v",
+				configMap.Namespace, configMap.Name, err)
+			c.recorder.Event(configMap, corev1.EventTypeWarning, ReasonInvalidConfig, err.Error())
+			return
+		}
+	}
+	c.tunablesLock.Lock()
+	c.imageCacheRefreshFrequency = t.refreshFrequency
+	c.allowedRegistries = t.allowedRegistries
+	c.tunablesLock.Unlock()
+	c.imageManager.SetPullerImages(t.criClientImage, t.busyboxImage)
+	c.imageManager.SetMaxConcurrentJobs(t.maxConcurrentJobs)
+	glog.Infof("Configuration loaded: refresh frequency %s, max concurrent jobs %d, allowed registries %v, cri client image %s, busybox image %s",
+		t.refreshFrequency, t.maxConcurrentJobs, t.allowedRegistries, t.criClientImage, t.busyboxImage)
+}
+
+// refreshFrequency returns the frequency at which image caches are refreshed
+func (c *Controller) refreshFrequency() time.Duration {
+	c.tunablesLock.RLock()
+	defer c.tunablesLock.RUnlock()
+	return c.imageCacheRefreshFrequency
+}
+
+// configPolicy returns the registries allowed by the configuration ConfigMap as a
+// policy, or nil if all registries are allowed
+func (c *Controller) configPolicy() *v1alpha2.FledgedPolicy {
+	c.tunablesLock.RLock()
+	defer c.tunablesLock.RUnlock()
+	if len(c.allowedRegistries) == 0 {
+		return nil
+	}
+	return &v1alpha2.FledgedPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "configmap/" + c.configMapName},
+		Spec:       v1alpha2.FledgedPolicySpec{AllowedRegistries: c.allowedRegistries},
+	}
+}
+
+// runRefreshLoop runs the refresh worker at the refresh frequency, which may change at
+// runtime. A zero frequency disables the refresh.
+func (c *Controller) runRefreshLoop(stopCh <-chan struct{}) {
+	var last time.Time
+	for {
+		delay := refreshLoopCheckInterval
+		if frequency := c.refreshFrequency(); frequency > 0 {
+			if time.Since(last) >= frequency {
+				c.runRefreshWorker()
+				last = time.Now()
+			}
+			if next := time.Until(last.Add(frequency)); next < delay {
+				delay = next
+			}
+		}
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseTunables(t *testing.T) {
+	defaults := tunables{refreshFrequency: time.Minute * 15, criClientImage: "senthilrch/kubefledged-cri-client:latest", busyboxImage: "busybox:1.29.2"}
+	tests := []struct {
+		name             string
+		data             map[string]string
+		expectedTunables tunables
+		expectErr        bool
+	}{
+		{
+			name:             "#1: Empty ConfigMap",
+			data:             map[string]string{},
+			expectedTunables: defaults,
+		},
+		{
+			name: "#2: All settings",
+			data: map[string]string{
+				configKeyRefreshFrequency:  "1h",
+				configKeyMaxConcurrentJobs: "20",
+				configKeyAllowedRegistries: "docker.io, registry.local/team ,",
+				configKeyCRIClientImage:    "registry.local/kubefledged-cri-client:v1",
+				configKeyBusyboxImage:      "registry.local/busybox:1.36",
+			},
+			expectedTunables: tunables{
+				refreshFrequency:  time.Hour,
+				maxConcurrentJobs: 20,
+				allowedRegistries: []string{"docker.io", "registry.local/team"},
+				criClientImage:    "registry.local/kubefledged-cri-client:v1",
+				busyboxImage:      "registry.local/busybox:1.36",
+			},
+		},
+		{
+			name:      "#3: Invalid refresh frequency",
+			data:      map[string]string{configKeyRefreshFrequency: "hourly"},
+			expectErr: true,
+		},
+		{
+			name:      "#4: Negative max concurrent jobs",
+			data:      map[string]string{configKeyMaxConcurrentJobs: "-1"},
+			expectErr: true,
+		},
+		{
+			name:      "#5: Invalid image",
+			data:      map[string]string{configKeyBusyboxImage: "Busybox:latest"},
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		actual, err := parseTunables(test.data, defaults)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("Test: %s failed: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(actual, test.expectedTunables) {
+			t.Errorf("Test: %s failed: expected %+v, actual %+v, err=%v", test.name, test.expectedTunables, actual, err)
+		}
+	}
+}
+
+func TestApplyConfigMap(t *testing.T) {
+	controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	controller.configMapName = "kubefledged-config"
+	controller.flagTunables = tunables{refreshFrequency: time.Minute * 15}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubefledged-config", Namespace: fledgedNameSpace},
+		Data:       map[string]string{configKeyRefreshFrequency: "1h", configKeyAllowedRegistries: "registry.local"},
+	}
+
+	controller.applyConfigMap(configMap)
+	if frequency := controller.refreshFrequency(); frequency != time.Hour {
+		t.Errorf("expected refresh frequency 1h, actual %s", frequency)
+	}
+	policies := controller.listPolicies()
+	if len(policies) != 1 || policies[0].Name != "configmap/kubefledged-config" ||
+		!reflect.DeepEqual(policies[0].Spec.AllowedRegistries, []string{"registry.local"}) {
+		t.Errorf("expected allowed registries of the ConfigMap to be enforced as a policy, actual %+v", policies)
+	}
+
+	// An invalid ConfigMap is ignored
+	configMap.Data[configKeyRefreshFrequency] = "hourly"
+	controller.applyConfigMap(configMap)
+	if frequency := controller.refreshFrequency(); frequency != time.Hour {
+		t.Errorf("expected refresh frequency to be kept, actual %s", frequency)
+	}
+
+	// The settings given by flags are restored when the ConfigMap is deleted
+	controller.applyConfigMap(nil)
+	if frequency := controller.refreshFrequency(); frequency != time.Minute*15 {
+		t.Errorf("expected refresh frequency of the flags to be restored, actual %s", frequency)
+	}
+	if policies := controller.listPolicies(); len(policies) != 0 {
+		t.Errorf("expected no policy, actual %+v", policies)
+	}
+}
@@ -20,25 +20,36 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang/glog"
 	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
 	clientset "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned"
 	fledgedscheme "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/scheme"
-	informers "github.com/senthilrch/kube-fledged/pkg/client/informers/externalversions/kubefledged/v1alpha2"
+	fledgedinformers "github.com/senthilrch/kube-fledged/pkg/client/informers/externalversions"
 	listers "github.com/senthilrch/kube-fledged/pkg/client/listers/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/features"
+	"github.com/senthilrch/kube-fledged/pkg/imagelist"
 	"github.com/senthilrch/kube-fledged/pkg/images"
+	"github.com/senthilrch/kube-fledged/pkg/nodestats"
+	"github.com/senthilrch/kube-fledged/pkg/policy"
+	"github.com/senthilrch/kube-fledged/pkg/registry"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
-	coreinformers "k8s.io/client-go/informers/core/v1"
+	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
@@ -46,8 +57,12 @@ import (
 )
 
 const controllerAgentName = "kubefledged-controller"
-const imageCachePurgeAnnotationKey = "kubefledged.io/purge-imagecache"
-const imageCacheRefreshAnnotationKey = "kubefledged.io/refresh-imagecache"
+const imageCachePurgeAnnotationKey = v1alpha2.ImageCachePurgeAnnotationKey
+const imageCacheRefreshAnnotationKey = v1alpha2.ImageCacheRefreshAnnotationKey
+const imageCacheCancelAnnotationKey = v1alpha2.ImageCacheCancelAnnotationKey
+const imageCacheApproveAnnotationKey = v1alpha2.ImageCacheApproveAnnotationKey
+const imageCacheDryRunAnnotationKey = v1alpha2.ImageCacheDryRunAnnotationKey
+const nodeOSLabelKey = "kubernetes.io/os"
 
 const (
 	// SuccessSynced is used as part of the Event 'reason' when a ImageCache is synced
@@ -55,6 +70,9 @@ const (
 	// MessageResourceSynced is the message used for an Event fired when a ImageCache
 	// is synced successfully
 	MessageResourceSynced = "ImageCache synced successfully"
+	// OverlappingCacheSpecEntries is used as part of the Event 'reason' when cache spec
+	// entries of a ImageCache list the same image for the same nodes
+	OverlappingCacheSpecEntries = "OverlappingCacheSpecEntries"
 )
 
 // Controller is the controller for ImageCache resources
@@ -69,79 +87,317 @@ type Controller struct {
 	nodesSynced       cache.InformerSynced
 	imageCachesLister listers.ImageCacheLister
 	imageCachesSynced cache.InformerSynced
+	policiesLister    listers.FledgedPolicyLister
+	policiesSynced    cache.InformerSynced
+	requestsLister    listers.ImageCacheRequestLister
+	requestsSynced    cache.InformerSynced
+	templatesLister   listers.ImageCacheTemplateLister
+	templatesSynced   cache.InformerSynced
+	imageListsLister  listers.ImageListLister
+	imageListsSynced  cache.InformerSynced
 
 	// workqueue is a rate limited work queue. This is used to queue work to be
 	// processed instead of performing it as soon as a change happens. This
 	// means we can ensure we only process a fixed amount of resources at a
 	// time, and makes it easy to ensure we are never processing the same item
 	// simultaneously in two different workers.
-	workqueue      workqueue.RateLimitingInterface
-	imageworkqueue workqueue.RateLimitingInterface
-	imageManager   *images.ImageManager
+	workqueue       workqueue.RateLimitingInterface
+	imageworkqueues images.ImageWorkQueues
+	imageManager    *images.ImageManager
+	// resolveQueue queues the image caches whose registry catalogs and image list
+	// providers are to be listed by the resolve worker
+	resolveQueue workqueue.RateLimitingInterface
+	// resolutions are the images last resolved for the image lists of each image cache
+	resolutions     map[string]imageListResolution
+	resolutionsLock sync.Mutex
+	// imageMetadataQueue queues the images whose metadata is to be read from their
+	// registries by the metadata worker
+	imageMetadataQueue workqueue.RateLimitingInterface
+	// imageMetadata is the metadata of the images read from their registries
+	imageMetadata     map[imageMetadataKey]imageMetadata
+	imageMetadataLock sync.Mutex
+	// keychains are the credentials of the registry operations for the images of
+	// each image cache, read from its image pull secrets
+	keychains *registry.Keychains
 	// recorder is an event recorder for recording Event resources to the
 	// Kubernetes API.
 	recorder                   record.EventRecorder
 	imageCacheRefreshFrequency time.Duration
+	// defaultNodeOS is added as a kubernetes.io/os node selector to cache spec
+	// entries that do not specify a nodeSelector. Empty disables this behaviour.
+	defaultNodeOS string
+	// history keeps the recently finished image cache actions shown by the dashboard
+	history *syncHistory
+	// auditSink receives an audit record of every image pull and purge. Nil disables auditing.
+	auditSink AuditSink
+	// cloudEventSink receives a CloudEvent on every image cache state transition. Nil
+	// disables CloudEvents.
+	cloudEventSink CloudEventSink
+	// hookAllowedURLs are the URLs the http hooks of image caches may post to
+	hookAllowedURLs []string
+	// hookJobsLister lists the jobs of the exec hooks of image caches, from an informer
+	// of the jobs labelled kubefledged=kubefledged-hook
+	hookJobInformerFactory kubeinformers.SharedInformerFactory
+	hookJobsLister         batchlisters.JobLister
+	hookJobsSynced         cache.InformerSynced
+	// hookJobChange is closed and replaced whenever a hook job changes, to wake up the
+	// exec hooks waiting for their job
+	hookJobChange     chan struct{}
+	hookJobChangeLock sync.Mutex
+	// hookAllowedImages are the images the exec hooks of image caches may run
+	hookAllowedImages []string
+	// pluginAddresses are the addresses of the image list plugins image caches may call
+	pluginAddresses []string
+	// tagPollInterval is the interval at which the tags of tracked repositories are
+	// polled. Zero disables polling.
+	tagPollInterval time.Duration
+	// repositoryTags are the tags last polled for each tracked repository
+	repositoryTags     map[repositoryTagsKey][]string
+	repositoryTagsLock sync.Mutex
+	tagLister          registry.TagLister
+	catalogLister      registry.CatalogLister
+	// usageTrackingInterval is the interval at which the usage of cached images by pods
+	// is tracked. Zero disables usage tracking.
+	usageTrackingInterval time.Duration
+	// autoWarmInterval is the interval at which the images annotated for auto-warm are
+	// collected. Zero disables auto-warm.
+	autoWarmInterval time.Duration
+	// autoWarmContainers selects the init and ephemeral containers whose images are
+	// collected by auto-warm
+	autoWarmContainers imagelist.ContainerSelection
+	// hpaPrewarmInterval is the interval at which the autoscalers annotated for pre-warm
+	// are checked for approaching scale-ups. Zero disables the HPA pre-warm.
+	hpaPrewarmInterval time.Duration
+	// hpaPrewarmThreshold is the percentage of the target value of a metric of an
+	// autoscaler beyond which a scale-up is approaching
+	hpaPrewarmThreshold int
+	// startupTaintKey is the key of the taint of nodes to be warmed before pods are
+	// scheduled on them. Empty disables node warming.
+	startupTaintKey string
+	// deferOfflineNodes defers the image pulls to offline nodes until they reconnect,
+	// instead of failing them
+	deferOfflineNodes bool
+	// registryCircuitBreaker is true if the image pulls from registries whose circuit
+	// is open are deferred by the image manager
+	registryCircuitBreaker bool
+	// loadThrottling is true if the image pulls to loaded nodes are deferred
+	loadThrottling bool
+	// includeVirtualNodes targets virtual-kubelet and Fargate nodes with image pulls,
+	// which are excluded otherwise
+	includeVirtualNodes bool
+	// verifyInterval is the interval at which cached images are verified to be still
+	// present on the nodes. Zero disables verification.
+	verifyInterval time.Duration
+	// imageGCHighThreshold and imageGCLowThreshold are the image garbage collection
+	// thresholds of kubelet, in percent of disk usage. A zero high threshold disables
+	// reporting the nodes close to them.
+	imageGCHighThreshold int
+	imageGCLowThreshold  int
+	// egressAccounting estimates the bytes pulled from the registries by image pulls
+	egressAccounting bool
+	imageSizer       registry.ImageSizer
+	// approvalNodeThreshold and approvalBytesThreshold are the number of nodes and the
+	// estimated bytes of the image pulls of an action beyond which the action waits for
+	// approval. Zero disables the threshold.
+	approvalNodeThreshold  int
+	approvalBytesThreshold int64
+	// egressCostRates are the egress cost rates per GB of the registries, used to
+	// estimate the cost of image cache actions
+	egressCostRates map[string]float64
+	// reportLayerStats reports the bytes of the layers shared by the images of image
+	// caches, read from the image manifests in the registries
+	reportLayerStats bool
+	layerLister      registry.LayerLister
+	// nodeBandwidth is the bandwidth of the nodes in bytes per second, used to estimate
+	// the duration of image pulls. Zero disables estimating the image pulls of actions
+	// other than dry runs.
+	nodeBandwidth int64
+	// imagePullPolicy is the image pull policy of the image caches and namespaces that
+	// do not specify one
+	imagePullPolicy corev1.PullPolicy
+	// recordImageFsUsage records the usage of the image filesystem of the nodes at the
+	// start and at the end of image cache actions
+	recordImageFsUsage bool
+	imageFsReader      nodestats.ImageFsReader
+	// configMapName is the name of the ConfigMap whose settings are reloaded without
+	// restarting the controller. Empty disables reloading.
+	configMapName            string
+	configMapInformerFactory kubeinformers.SharedInformerFactory
+	configMapsSynced         cache.InformerSynced
+	// flagTunables are the settings given by flags, overridden by the ConfigMap
+	flagTunables tunables
+	// tunablesLock guards the settings reloaded from the ConfigMap
+	tunablesLock      sync.RWMutex
+	allowedRegistries []string
 }
 
-// NewController returns a new fledged controller
+// NewController returns a new fledged controller. The informers of the controller are
+// taken from the given informer factories, which the caller starts once the controller
+// is created. They may be shared with other controllers of an operator embedding it.
 func NewController(
 	kubeclientset kubernetes.Interface,
 	kubefledgedclientset clientset.Interface,
-	namespace string,
-	nodeInformer coreinformers.NodeInformer,
-	imageCacheInformer informers.ImageCacheInformer,
-	imageCacheRefreshFrequency time.Duration,
-	imagePullDeadlineDuration time.Duration,
-	criClientImage string,
-	busyboxImage string,
-	imagePullPolicy string,
-	serviceAccountName string,
-	imageDeleteJobHostNetwork bool,
-	jobPriorityClassName string,
-	canDeleteJob bool,
-	criSocketPath string) *Controller {
+	kubeInformerFactory kubeinformers.SharedInformerFactory,
+	imageManagerInformerFactory kubeinformers.SharedInformerFactory,
+	fledgedInformerFactory fledgedinformers.SharedInformerFactory,
+	opts *Options,
+	eventSink record.EventSink,
+	auditSink AuditSink,
+	cloudEventSink CloudEventSink) *Controller {
 
 	runtime.Must(fledgedscheme.AddToScheme(scheme.Scheme))
-	glog.V(4).Info("Creating event broadcaster")
-	eventBroadcaster := record.NewBroadcaster()
-	eventBroadcaster.StartLogging(glog.Infof)
-	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeclientset.CoreV1().Events("")})
-	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controllerAgentName})
+	// Credentials of the registry operations of the controller
+	keychain := registry.NewKeychain(kubeclientset, opts.Namespace, opts.ServiceAccountName, opts.RegistryPullSecrets, time.Minute)
+	keychains := registry.NewKeychains(kubeclientset, keychain, time.Minute)
+	recorder := newEventRecorder(kubeclientset, opts.EventComponentName, opts.EventSinkNamespace, opts.DisableEvents, eventSink)
+
+	nodeInformer := kubeInformerFactory.Core().V1().Nodes()
+	imageCacheInformer := fledgedInformerFactory.Kubefledged().V1alpha2().ImageCaches()
+	policyInformer := fledgedInformerFactory.Kubefledged().V1alpha2().FledgedPolicies()
+	requestInformer := fledgedInformerFactory.Kubefledged().V1alpha2().ImageCacheRequests()
+	templateInformer := fledgedInformerFactory.Kubefledged().V1alpha2().ImageCacheTemplates()
+	imageListInformer := fledgedInformerFactory.Kubefledged().V1alpha2().ImageLists()
 
 	controller := &Controller{
 		kubeclientset:              kubeclientset,
 		kubefledgedclientset:       kubefledgedclientset,
-		fledgedNameSpace:           namespace,
+		fledgedNameSpace:           opts.Namespace,
 		nodesLister:                nodeInformer.Lister(),
 		nodesSynced:                nodeInformer.Informer().HasSynced,
 		imageCachesLister:          imageCacheInformer.Lister(),
 		imageCachesSynced:          imageCacheInformer.Informer().HasSynced,
+		policiesLister:             policyInformer.Lister(),
+		policiesSynced:             policyInformer.Informer().HasSynced,
+		requestsLister:             requestInformer.Lister(),
+		requestsSynced:             requestInformer.Informer().HasSynced,
+		templatesLister:            templateInformer.Lister(),
+		templatesSynced:            templateInformer.Informer().HasSynced,
+		imageListsLister:           imageListInformer.Lister(),
+		imageListsSynced:           imageListInformer.Informer().HasSynced,
 		workqueue:                  workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ImageCaches"),
-		imageworkqueue:             workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ImagePullerStatus"),
+		imageworkqueues:            images.NewImageWorkQueues(),
+		resolveQueue:               workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ImageListResolves"),
+		resolutions:                map[string]imageListResolution{},
+		imageMetadataQueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ImageMetadata"),
+		imageMetadata:              map[imageMetadataKey]imageMetadata{},
+		repositoryTags:             map[repositoryTagsKey][]string{},
+		keychains:                  keychains,
 		recorder:                   recorder,
-		imageCacheRefreshFrequency: imageCacheRefreshFrequency,
+		imageCacheRefreshFrequency: opts.ImageCacheRefreshFrequency,
+		defaultNodeOS:              opts.DefaultNodeOS,
+		history:                    newSyncHistory(syncHistoryLength),
+		auditSink:                  auditSink,
+		cloudEventSink:             cloudEventSink,
+		hookAllowedURLs:            opts.HookAllowedURLs,
+		hookAllowedImages:          opts.HookAllowedImages,
+		pluginAddresses:            opts.ImageListPluginAddresses,
+		tagPollInterval:            opts.TagPollInterval,
+		tagLister:                  registry.NewTagLister(30*time.Second, keychain),
+		catalogLister:              registry.NewCatalogLister(30*time.Second, keychain),
+		usageTrackingInterval:      opts.UsageTrackingInterval,
+		autoWarmInterval:           opts.AutoWarmInterval,
+		autoWarmContainers: imagelist.ContainerSelection{
+			InitContainers:      opts.AutoWarmInitContainers,
+			EphemeralContainers: opts.AutoWarmEphemeralContainers,
+		},
+		hpaPrewarmInterval:     opts.HPAPrewarmInterval,
+		hpaPrewarmThreshold:    opts.HPAPrewarmThreshold,
+		startupTaintKey:        opts.StartupTaintKey,
+		deferOfflineNodes:      opts.DeferOfflineNodes,
+		registryCircuitBreaker: opts.CircuitBreaker.Failures > 0,
+		loadThrottling:         opts.LoadThrottle.Enabled() && features.Enabled(features.LoadThrottling),
+		includeVirtualNodes:    opts.IncludeVirtualNodes,
+		verifyInterval:         opts.VerifyInterval,
+		imageGCHighThreshold:   opts.ImageGCHighThreshold,
+		imageGCLowThreshold:    opts.ImageGCLowThreshold,
+		egressAccounting:       opts.EgressAccounting,
+		approvalNodeThreshold:  opts.ApprovalNodeThreshold,
+		approvalBytesThreshold: opts.ApprovalBytesThreshold,
+		egressCostRates:        opts.EgressCostRates,
+		reportLayerStats:       opts.ReportLayerStats,
+		recordImageFsUsage:     opts.RecordImageFsUsage,
+		nodeBandwidth:          opts.NodeBandwidth,
+		imagePullPolicy:        corev1.PullPolicy(opts.ImagePullPolicy),
+		configMapName:          opts.ConfigMapName,
+		flagTunables: tunables{refreshFrequency: opts.ImageCacheRefreshFrequency, criClientImage: opts.CRIClientImage,
+			busyboxImage: opts.BusyboxImage},
+		layerLister:   registry.NewLayerLister(30*time.Second, keychain),
+		imageSizer:    registry.NewImageSizer(30*time.Second, keychain),
+		imageFsReader: nodestats.NewImageFsReader(kubeclientset.CoreV1().RESTClient(), imageFsUsageTimeout),
 	}
 
-	imageManager, _ := images.NewImageManager(controller.workqueue, controller.imageworkqueue,
-		controller.kubeclientset, controller.fledgedNameSpace, imagePullDeadlineDuration,
-		criClientImage, busyboxImage, imagePullPolicy, serviceAccountName, imageDeleteJobHostNetwork,
-		jobPriorityClassName, canDeleteJob, criSocketPath)
+	var digestResolver registry.DigestResolver
+	if opts.VerifyImageDigests {
+		digestResolver = registry.NewDigestResolver(30*time.Second, keychain)
+	}
+	imageManager, _ := images.NewImageManager(controller.workqueue, controller.imageworkqueues,
+		controller.kubeclientset, imageManagerInformerFactory, images.ImageManagerOptions{
+			Namespace:                 controller.fledgedNameSpace,
+			ImagePullDeadlineDuration: opts.ImagePullDeadlineDuration,
+			CRIClientImage:            opts.CRIClientImage,
+			CRIClientWindowsImage:     opts.CRIClientWindowsImage,
+			BusyboxImage:              opts.BusyboxImage,
+			ImagePullPolicy:           opts.ImagePullPolicy,
+			ServiceAccountName:        opts.ServiceAccountName,
+			ImageDeleteJobHostNetwork: opts.ImageDeleteJobHostNetwork,
+			JobPriorityClassName:      opts.JobPriorityClassName,
+			JobSchedulerName:          opts.JobSchedulerName,
+			JobSecurityProfiles:       opts.JobSecurityProfiles,
+			GuaranteedPulls:           opts.GuaranteedPulls,
+			CanDeleteJob:              opts.CanDeleteJob,
+			CRISocketPath:             opts.CRISocketPath,
+			StatusUpdateInterval:      opts.StatusUpdateInterval,
+			StatusUpdateBatchSize:     opts.StatusUpdateBatchSize,
+			StuckJobThreshold:         opts.StuckJobThreshold,
+			PullRetry:                 opts.PullRetry,
+			CircuitBreaker:            opts.CircuitBreaker,
+			LoadThrottle:              opts.LoadThrottle,
+			LoadReader:                nodestats.NewLoadReader(kubeclientset.CoreV1().RESTClient(), imageFsUsageTimeout, opts.LoadThrottle.DiskIOBytesPerSecond > 0),
+			DigestResolver:            digestResolver,
+			Keychains:                 keychains,
+			ORASImage:                 opts.ORASImage,
+			ArtifactStorePath:         opts.ArtifactStorePath,
+			PullStrategy:              opts.PullStrategy,
+			ContainerdNamespace:       opts.ContainerdNamespace,
+		}, recorder)
 	controller.imageManager = imageManager
+	controller.watchHookJobs()
+	if opts.ConfigMapName != "" {
+		controller.watchConfigMap(opts.Namespace, opts.ConfigMapName)
+	}
 
 	glog.Info("Setting up event handlers")
 	// Set up an event handler for when ImageCache resources change
 	imageCacheInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			controller.enqueueImageCache(images.ImageCacheCreate, nil, obj)
+			controller.enqueueResolve(obj.(*v1alpha2.ImageCache))
 		},
 		UpdateFunc: func(old, new interface{}) {
 			controller.enqueueImageCache(images.ImageCacheUpdate, old, new)
+			if !reflect.DeepEqual(old.(*v1alpha2.ImageCache).Spec.CacheSpec, new.(*v1alpha2.ImageCache).Spec.CacheSpec) {
+				controller.enqueueResolve(new.(*v1alpha2.ImageCache))
+			}
 		},
 		DeleteFunc: func(obj interface{}) {
 			controller.enqueueImageCache(images.ImageCacheDelete, obj, nil)
 		},
 	})
+	// Set up an event handler for when the labels of nodes change
+	nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old, new interface{}) {
+			controller.handleNodeUpdate(old.(*corev1.Node), new.(*corev1.Node))
+		},
+	})
+	// Set up an event handler for when ImageList resources are created or updated
+	imageListInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			controller.handleImageListChange(nil, obj.(*v1alpha2.ImageList))
+		},
+		UpdateFunc: func(old, new interface{}) {
+			controller.handleImageListChange(old.(*v1alpha2.ImageList), new.(*v1alpha2.ImageList))
+		},
+	})
 	return controller
 }
 
@@ -176,7 +432,8 @@ func (c *Controller) danglingJobs() error {
 		return nil
 	}
 	deletePropagation := metav1.DeletePropagationBackground
-	for _, job := range joblist.Items {
+	for i := range joblist.Items {
+		job := &joblist.Items[i]
 		err := c.kubeclientset.BatchV1().Jobs(job.Namespace).
 			Delete(context.TODO(), job.Name, metav1.DeleteOptions{PropagationPolicy: &deletePropagation})
 		if err != nil {
@@ -184,10 +441,30 @@ func (c *Controller) danglingJobs() error {
 			return err
 		}
 		glog.Infof("Dangling Job(%s) deleted", job.Name)
+		c.recordDanglingJobDeleted(job)
 	}
 	return nil
 }
 
+// recordDanglingJobDeleted records an event for a dangling job deleted at startup. The event
+// is recorded on the owning image cache, or on the job if the image cache no longer exists
+func (c *Controller) recordDanglingJobDeleted(job *batchv1.Job) {
+	imageCacheName := job.Labels["imagecache"]
+	if imageCacheName != "" {
+		imageCache, err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(job.Namespace).Get(context.TODO(), imageCacheName, metav1.GetOptions{})
+		if err == nil && imageCache != nil && imageCache.Name != "" {
+			c.recorder.Eventf(imageCache, corev1.EventTypeWarning, images.OrphanedJobDeleted,
+				"Job %s deleted: its work item was lost when the controller restarted", job.Name)
+			return
+		}
+		if err != nil && !apierrors.IsNotFound(err) {
+			glog.Errorf("Error getting imagecache(%s): %v", imageCacheName, err)
+		}
+	}
+	c.recorder.Eventf(job, corev1.EventTypeWarning, images.OrphanedJobDeleted,
+		"Job %s deleted: image cache %q no longer exists", job.Name, imageCacheName)
+}
+
 // danglingImageCaches finds dangling or stuck image cache and marks them as abhorted. Such
 // image caches will get refreshed in the next cycle
 func (c *Controller) danglingImageCaches() error {
@@ -211,6 +488,8 @@ func (c *Controller) danglingImageCaches() error {
 	for _, imagecache := range imagecachelist.Items {
 		if imagecache.Status.Status == v1alpha2.ImageCacheActionStatusProcessing {
 			status.StartTime = imagecache.Status.StartTime
+			status.ProvidedImages = imagecache.Status.ProvidedImages
+			status.ResolvedImages = imagecache.Status.ResolvedImages
 			err := c.updateImageCacheStatus(&imagecache, status)
 			if err != nil {
 				glog.Errorf("Error updating ImageCache(%s) status to '%s': %v", imagecache.Name, v1alpha2.ImageCacheActionStatusAborted, err)
@@ -234,26 +513,96 @@ func (c *Controller) danglingImageCaches() error {
 func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) error {
 	defer runtime.HandleCrash()
 	defer c.workqueue.ShutDown()
-	defer c.imageworkqueue.ShutDown()
+	defer c.imageworkqueues.ShutDown()
+	defer c.resolveQueue.ShutDown()
+	defer c.imageMetadataQueue.ShutDown()
 
 	// Start the informer factories to begin populating the informer caches
 	glog.Info("Starting kubefledged-controller")
 
 	// Wait for the caches to be synced before starting workers
-	if ok := cache.WaitForCacheSync(stopCh, c.nodesSynced, c.imageCachesSynced); !ok {
+	if ok := cache.WaitForCacheSync(stopCh, c.nodesSynced, c.imageCachesSynced, c.policiesSynced, c.requestsSynced, c.templatesSynced, c.imageListsSynced); !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
 	glog.Info("Informer caches synched successfull")
 
+	go c.hookJobInformerFactory.Start(stopCh)
+	if ok := cache.WaitForCacheSync(stopCh, c.hookJobsSynced); !ok {
+		return fmt.Errorf("failed to wait for hook job cache to sync")
+	}
+
+	if c.configMapInformerFactory != nil {
+		go c.configMapInformerFactory.Start(stopCh)
+		if ok := cache.WaitForCacheSync(stopCh, c.configMapsSynced); !ok {
+			return fmt.Errorf("failed to wait for configmap cache to sync")
+		}
+		glog.Infof("Watching configuration ConfigMap %s/%s", c.fledgedNameSpace, c.configMapName)
+	}
+
 	// Launch workers to process ImageCache resources
 	for i := 0; i < threadiness; i++ {
 		go wait.Until(c.runWorker, time.Second, stopCh)
 	}
 	glog.Info("Image cache worker started")
 
-	if c.imageCacheRefreshFrequency.Nanoseconds() != int64(0) {
-		go wait.Until(c.runRefreshWorker, c.imageCacheRefreshFrequency, stopCh)
-		glog.Info("Image cache refresh worker started")
+	go c.runRefreshLoop(stopCh)
+	glog.Info("Image cache refresh worker started")
+
+	go wait.Until(c.runRefreshIntervalWorker, refreshIntervalCheckInterval, stopCh)
+	glog.Info("Refresh interval worker started")
+
+	go wait.Until(c.runExpiryWorker, expiryCheckInterval, stopCh)
+	glog.Info("Image cache expiry worker started")
+
+	go wait.Until(c.runImageCacheRequestWorker, imageCacheRequestCheckInterval, stopCh)
+	glog.Info("Image cache request worker started")
+
+	go wait.Until(c.runImageCacheTemplateWorker, imageCacheTemplateCheckInterval, stopCh)
+	glog.Info("Image cache template worker started")
+
+	go wait.Until(c.runWarmStandbyWorker, warmStandbyCheckInterval, stopCh)
+	glog.Info("Warm standby worker started")
+
+	go wait.Until(c.runResolveWorker, time.Second, stopCh)
+	go wait.Until(c.enqueueResolves, resolveInterval, stopCh)
+	glog.Info("Image list resolve worker started")
+
+	go wait.Until(c.runImageMetadataWorker, time.Second, stopCh)
+	glog.Info("Image metadata worker started")
+
+	if c.tagPollInterval.Nanoseconds() != int64(0) {
+		go wait.Until(c.runTagPollWorker, c.tagPollInterval, stopCh)
+		glog.Info("Tag poll worker started")
+	}
+
+	if c.usageTrackingInterval.Nanoseconds() != int64(0) {
+		go wait.Until(c.runUsageWorker, c.usageTrackingInterval, stopCh)
+		glog.Info("Usage tracking worker started")
+	}
+
+	if c.autoWarmInterval.Nanoseconds() != int64(0) {
+		go wait.Until(c.runAutoWarmWorker, c.autoWarmInterval, stopCh)
+		glog.Info("Auto-warm worker started")
+	}
+
+	if c.hpaPrewarmInterval.Nanoseconds() != int64(0) {
+		go wait.Until(c.runHPAPrewarmWorker, c.hpaPrewarmInterval, stopCh)
+		glog.Info("HPA pre-warm worker started")
+	}
+
+	if c.startupTaintKey != "" {
+		go wait.Until(c.runStartupTaintWorker, startupTaintCheckInterval, stopCh)
+		glog.Info("Startup taint worker started")
+	}
+
+	if c.deferOfflineNodes || c.registryCircuitBreaker || c.loadThrottling {
+		go wait.Until(c.runDeferredWorker, deferredCheckInterval, stopCh)
+		glog.Info("Deferred image pull worker started")
+	}
+
+	if c.verifyInterval.Nanoseconds() != int64(0) {
+		go wait.Until(c.runVerifyWorker, c.verifyInterval, stopCh)
+		glog.Info("Verify worker started")
 	}
 
 	c.imageManager.Run(stopCh)
@@ -291,6 +640,12 @@ func (c *Controller) enqueueImageCache(workType images.WorkType, old, new interf
 		oldImageCache := old.(*v1alpha2.ImageCache)
 		newImageCache := new.(*v1alpha2.ImageCache)
 
+		if _, exists := newImageCache.Annotations[imageCacheCancelAnnotationKey]; exists {
+			if _, exists := oldImageCache.Annotations[imageCacheCancelAnnotationKey]; !exists {
+				workType = images.ImageCacheCancel
+				break
+			}
+		}
 		if oldImageCache.Status.Status == v1alpha2.ImageCacheActionStatusProcessing {
 			if !reflect.DeepEqual(newImageCache.Spec, oldImageCache.Spec) {
 				glog.Warningf("Received image cache update/purge/delete for '%s' while it is under processing, so ignoring.", oldImageCache.Name)
@@ -309,6 +664,18 @@ func (c *Controller) enqueueImageCache(workType images.WorkType, old, new interf
 				break
 			}
 		}
+		if _, exists := newImageCache.Annotations[imageCacheDryRunAnnotationKey]; exists {
+			if _, exists := oldImageCache.Annotations[imageCacheDryRunAnnotationKey]; !exists {
+				workType = images.ImageCacheRefresh
+				wqKey.DryRun = true
+				break
+			}
+		}
+		if approved(newImageCache) && !approved(oldImageCache) &&
+			newImageCache.Status.Status == v1alpha2.ImageCacheActionStatusPendingApproval {
+			workType = approvedWorkType(newImageCache)
+			break
+		}
 		if reflect.DeepEqual(newImageCache.Spec, oldImageCache.Spec) {
 			return false
 		}
@@ -335,6 +702,19 @@ func (c *Controller) enqueueImageCache(workType images.WorkType, old, new interf
 	return true
 }
 
+// enqueueBackgroundRefresh queues the refresh of an image cache started by the
+// controller itself, e.g. the periodic refresh. Its image work is queued behind the
+// actions triggered by users.
+func (c *Controller) enqueueBackgroundRefresh(imageCache *v1alpha2.ImageCache) {
+	key, err := cache.MetaNamespaceKeyFunc(imageCache)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.workqueue.AddRateLimited(images.WorkQueueKey{WorkType: images.ImageCacheRefresh, ObjKey: key, Background: true})
+	glog.V(4).Infof("enqueueBackgroundRefresh::ImageCache resource %s queued for background refresh", key)
+}
+
 // runWorker is a long-running function that will continually call the
 // processNextWorkItem function in order to read and process a message on the
 // workqueue.
@@ -407,27 +787,42 @@ func (c *Controller) runRefreshWorker() {
 		return
 	}
 	for i := range imageCaches {
-		// Do not refresh if status is not yet updated
-		if reflect.DeepEqual(imageCaches[i].Status, v1alpha2.ImageCacheStatus{}) {
+		// Image caches overriding the refresh interval are refreshed by the refresh
+		// interval worker
+		if !canRefresh(imageCaches[i]) || hasRefreshIntervals(imageCaches[i].Spec.CacheSpec) {
 			continue
 		}
-		// Do not refresh if image cache is already under processing
-		if imageCaches[i].Status.Status == v1alpha2.ImageCacheActionStatusProcessing {
-			continue
-		}
-		// Do not refresh image cache if cache spec validation failed
-		if imageCaches[i].Status.Status == v1alpha2.ImageCacheActionStatusFailed &&
-			imageCaches[i].Status.Reason == v1alpha2.ImageCacheReasonCacheSpecValidationFailed {
-			continue
-		}
-		// Do not refresh if image cache has been purged
-		if imageCaches[i].Status.Reason == v1alpha2.ImageCacheReasonImageCachePurge {
-			continue
-		}
-		c.enqueueImageCache(images.ImageCacheRefresh, imageCaches[i], nil)
+		c.enqueueBackgroundRefresh(imageCaches[i])
 	}
 }
 
+// canRefresh returns true if the image cache may be refreshed
+func canRefresh(imageCache *v1alpha2.ImageCache) bool {
+	// Do not refresh if status is not yet updated
+	if reflect.DeepEqual(imageCache.Status, v1alpha2.ImageCacheStatus{}) {
+		return false
+	}
+	// Do not refresh if image cache is already under processing or waiting for approval
+	if imageCache.Status.Status == v1alpha2.ImageCacheActionStatusProcessing ||
+		imageCache.Status.Status == v1alpha2.ImageCacheActionStatusPendingApproval {
+		return false
+	}
+	// Do not refresh image cache if cache spec validation failed
+	if imageCache.Status.Status == v1alpha2.ImageCacheActionStatusFailed &&
+		imageCache.Status.Reason == v1alpha2.ImageCacheReasonCacheSpecValidationFailed {
+		return false
+	}
+	// Do not refresh if image cache has been purged
+	if imageCache.Status.Reason == v1alpha2.ImageCacheReasonImageCachePurge {
+		return false
+	}
+	// Do not refresh one-shot image caches, including those executing an ImageCacheRequest
+	if imageCache.Spec.TTLSecondsAfterFinished != nil || imageCacheRequestOf(imageCache) != "" {
+		return false
+	}
+	return true
+}
+
 // syncHandler compares the actual state with the desired, and attempts to
 // converge the two. It then updates the Status block of the ImageCache resource
 // with the current status of the resource.
@@ -459,6 +854,28 @@ func (c *Controller) syncHandler(wqKey images.WorkQueueKey) error {
 			return err
 		}
 
+		// The actions restricted to relabeled nodes wait for the running action
+		if wqKey.Nodes != nil && !canRefresh(imageCache) {
+			if imageCache.Status.Status == v1alpha2.ImageCacheActionStatusProcessing ||
+				imageCache.Status.Status == v1alpha2.ImageCacheActionStatusPendingApproval {
+				c.workqueue.AddAfter(wqKey, nodeUpdateRetryInterval)
+			}
+			return nil
+		}
+
+		// The next wave of a staged rollout is dropped once another action started. It
+		// waits until the status of the previous wave is observed
+		if wqKey.Wave > 0 {
+			if !rolloutContinues(imageCache, wqKey.Wave) {
+				glog.Infof("Rollout of image cache %s superseded: wave %d dropped", wqKey.ObjKey, wqKey.Wave)
+				return nil
+			}
+			if imageCache.Status.Status == v1alpha2.ImageCacheActionStatusProcessing {
+				c.workqueue.AddAfter(wqKey, nodeUpdateRetryInterval)
+				return nil
+			}
+		}
+
 		if wqKey.WorkType == images.ImageCacheUpdate && wqKey.OldImageCache == nil {
 			status.Status = v1alpha2.ImageCacheActionStatusFailed
 			status.Reason = v1alpha2.ImageCacheReasonOldImageCacheNotFound
@@ -472,9 +889,120 @@ func (c *Controller) syncHandler(wqKey images.WorkQueueKey) error {
 			return fmt.Errorf("%s: %s", v1alpha2.ImageCacheReasonOldImageCacheNotFound, v1alpha2.ImageCacheMessageOldImageCacheNotFound)
 		}
 
-		cacheSpec := imageCache.Spec.CacheSpec
+		// The images of the catalogs and providers are resolved by the resolve worker.
+		// A purge purges the images resolved for the last action.
+		status.ProvidedImages = imageCache.Status.ProvidedImages
+		status.ResolvedImages = imageCache.Status.ResolvedImages
+		if wqKey.WorkType != images.ImageCachePurge {
+			resolution := c.lastResolution(imageCache)
+			status.ResolvedImages, status.ProvidedImages = resolution.resolved, resolution.provided
+			// Images pruned from the catalogs and providers are purged by the refresh,
+			// the same as by an update
+			if wqKey.WorkType == images.ImageCacheRefresh && wqKey.OldImageCache == nil &&
+				!reflect.DeepEqual(resolution.resolved, imageCache.Status.ResolvedImages) {
+				wqKey.OldImageCache = imageCache
+			}
+		}
+		if wqKey.OldImageCache != nil {
+			oldImageCache := wqKey.OldImageCache.DeepCopy()
+			oldImageCache.Spec.CacheSpec = imageListsOf(wqKey.OldImageCache)
+			wqKey.OldImageCache = oldImageCache
+		}
+		cacheSpec := resolvedCacheSpec(imageCache.Spec.CacheSpec, status.ResolvedImages)
 		glog.V(4).Infof("cacheSpec: %+v", cacheSpec)
-		var nodes []*corev1.Node
+		if wqKey.WorkType != images.ImageCachePurge {
+			if err := images.ValidateImageLists(cacheSpec); err != nil {
+				status.Status = v1alpha2.ImageCacheActionStatusFailed
+				status.Reason = v1alpha2.ImageCacheReasonCacheSpecValidationFailed
+				status.Message = err.Error()
+
+				if err := c.updateImageCacheStatus(imageCache, status); err != nil {
+					glog.Errorf("Error updating imagecache status to %s: %v", status.Status, err)
+					return err
+				}
+				c.recorder.Event(imageCache, corev1.EventTypeWarning, status.Reason, status.Message)
+				c.publishActionFinished(imageCache, actionReason(wqKey.WorkType), status)
+				glog.Errorf("%s: %s", v1alpha2.ImageCacheReasonCacheSpecValidationFailed, err.Error())
+				return fmt.Errorf("%s: %s", v1alpha2.ImageCacheReasonCacheSpecValidationFailed, err.Error())
+			}
+		}
+		policies := c.listPolicies()
+		if wqKey.WorkType != images.ImageCachePurge {
+			if err := policy.ValidateImageCache(imageCache, policies); err != nil {
+				return c.failPolicyViolation(imageCache, wqKey.WorkType, status, err)
+			}
+		}
+		workItems, plan, err := c.planImageWork(wqKey, cacheSpec, imageCache.Spec.WarmStandby)
+		if err != nil {
+			return err
+		}
+		if max := policy.MaxNodesPerCache(policies); max > 0 && wqKey.WorkType != images.ImageCachePurge {
+			if nodes := countNodes(workItems); nodes > max {
+				return c.failPolicyViolation(imageCache, wqKey.WorkType, status,
+					fmt.Errorf("Image cache targets %d nodes, more than the maximum of %d nodes per cache allowed by policy", nodes, max))
+			}
+		}
+		if c.deferOfflineNodes && wqKey.WorkType != images.ImageCachePurge {
+			workItems, status.Deferred = deferOfflineWork(workItems, imageCache.Status.Deferred, time.Now())
+			if wqKey.Nodes != nil {
+				status.Deferred = append(deferredOfOtherNodes(imageCache.Status.Deferred, *wqKey.Nodes), status.Deferred...)
+			}
+			plan.WorkItems = len(workItems)
+		}
+		if imageCache.Spec.MaxBytesPerNode != nil && wqKey.WorkType != images.ImageCachePurge {
+			workItems, plan.CappedWorkItems = c.capBytesPerNode(workItems, imageCache.Spec.MaxBytesPerNode)
+			plan.WorkItems = len(workItems)
+		}
+		if wqKey.WorkType != images.ImageCachePurge {
+			sizes := c.egressAccounting || c.nodeBandwidth > 0 || len(c.egressCostRates) > 0
+			layers := c.reportLayerStats && features.Enabled(features.LayerStats)
+			if sizes || layers {
+				c.queueImageMetadata(imageCache, workItems, sizes, layers)
+			}
+		}
+		plan.SpecHash = specHash(imageCache.Spec)
+		status.Plan = plan
+		status.Progress = &v1alpha2.ImageCacheProgress{Total: plan.WorkItems}
+		if hasRefreshIntervals(cacheSpec) && wqKey.WorkType != images.ImageCachePurge {
+			status.EntryRefreshTimes = entryRefreshTimes(imageCache.Status.EntryRefreshTimes, len(cacheSpec), wqKey.Entries, startTime)
+		}
+		if wqKey.DryRun {
+			return c.recordDryRun(imageCache, workItems, plan, policies)
+		}
+		if c.nodeBandwidth > 0 && wqKey.WorkType != images.ImageCachePurge {
+			plan.Estimate = c.estimatePulls(imageCache, workItems, c.pullPolicy(imageCache, policies))
+		}
+		approvalRequired := false
+		// The next waves of a staged rollout were approved with its first wave
+		if wqKey.WorkType != images.ImageCachePurge && wqKey.Wave == 0 {
+			exceeded := []string{}
+			if c.approvalEnabled() {
+				exceeded = c.approvalExceeded(workItems, plan)
+			}
+			if len(c.egressCostRates) > 0 {
+				exceeded = append(exceeded, c.budgetExceeded(imageCache, workItems, plan, policies)...)
+			}
+			if len(exceeded) > 0 {
+				if !approved(imageCache) {
+					return c.awaitApproval(imageCache, wqKey.WorkType, status, exceeded)
+				}
+				approvalRequired = true
+			}
+		}
+
+		if imageCache.Spec.Rollout != nil && wqKey.WorkType != images.ImageCachePurge && wqKey.Nodes == nil {
+			wave := wqKey.Wave
+			if wave == 0 {
+				wave = 1
+			}
+			workItems, status.Rollout = stageRollout(workItems, imageCache.Spec.Rollout, wave)
+			plan.WorkItems = len(workItems)
+			status.Progress.Total = plan.WorkItems
+		}
+
+		if c.recordImageFsUsage {
+			status.ImageFsUsage = c.imageFsUsageBefore(workItems)
+		}
 
 		status.Status = v1alpha2.ImageCacheActionStatusProcessing
 
@@ -503,64 +1031,46 @@ func (c *Controller) syncHandler(wqKey images.WorkQueueKey) error {
 			glog.Errorf("Error getting imagecache(%s) from api server: %v", name, err)
 			return err
 		}
+		if imageCache.Spec.ImagePullPolicy == "" {
+			if pullPolicy := policy.ImagePullPolicy(policies); pullPolicy != "" {
+				// The image cache is only modified for the image manager, its spec
+				// is never written back
+				imageCache.Spec.ImagePullPolicy = pullPolicy
+			}
+		}
+
+		// The image work of an image cache with pre-pull hooks is queued once they succeed
+		hooksPending := hasPrePullHooks(imageCache)
+		processingMessage := status.Message
+		if hooksPending {
+			status.Message = v1alpha2.ImageCacheMessagePrePullHooksPending
+		}
 
 		if err = c.updateImageCacheStatus(imageCache, status); err != nil {
 			glog.Errorf("Error updating imagecache status to %s: %v", status.Status, err)
 			return err
 		}
-
-		for k, i := range cacheSpec {
-			if len(i.NodeSelector) > 0 {
-				if nodes, err = c.nodesLister.List(labels.Set(i.NodeSelector).AsSelector()); err != nil {
-					glog.Errorf("Error listing nodes using nodeselector %+v: %v", i.NodeSelector, err)
-					return err
-				}
-			} else {
-				if nodes, err = c.nodesLister.List(labels.Everything()); err != nil {
-					glog.Errorf("Error listing nodes using nodeselector labels.Everything(): %v", err)
-					return err
-				}
+		c.publishActionStarted(imageCache, wqKey.WorkType, status)
+		if approvalRequired {
+			if err := c.consumeApproval(namespace, name); err != nil {
+				glog.Errorf("Error removing Annotation %s from imagecache(%s): %v", imageCacheApproveAnnotationKey, name, err)
 			}
-			glog.V(4).Infof("No. of nodes in %+v is %d", i.NodeSelector, len(nodes))
+		}
 
-			for _, n := range nodes {
-				for m := range i.Images {
-					ipr := images.ImageWorkRequest{
-						Image:                   i.Images[m],
-						Node:                    n,
-						ContainerRuntimeVersion: n.Status.NodeInfo.ContainerRuntimeVersion,
-						WorkType:                wqKey.WorkType,
-						Imagecache:              imageCache,
-					}
-					c.imageworkqueue.AddRateLimited(ipr)
-				}
-				if wqKey.WorkType == images.ImageCacheUpdate {
-					for _, oldimage := range wqKey.OldImageCache.Spec.CacheSpec[k].Images {
-						matched := false
-						for _, newimage := range i.Images {
-							if oldimage == newimage {
-								matched = true
-								break
-							}
-						}
-						if !matched {
-							ipr := images.ImageWorkRequest{
-								Image:                   oldimage,
-								Node:                    n,
-								ContainerRuntimeVersion: n.Status.NodeInfo.ContainerRuntimeVersion,
-								WorkType:                images.ImageCachePurge,
-								Imagecache:              imageCache,
-							}
-							c.imageworkqueue.AddRateLimited(ipr)
-						}
-					}
-				}
-			}
+		if len(plan.Overlaps) > 0 {
+			c.recorder.Eventf(imageCache, corev1.EventTypeWarning, OverlappingCacheSpecEntries,
+				"%d image(s) are listed in cache spec entries targeting common nodes. %d duplicate work items were merged",
+				len(plan.Overlaps), plan.MergedWorkItems)
 		}
 
-		// We add an empty image pull request to signal the image manager that all
-		// requests for this sync action have been placed in the imageworkqueue
-		c.imageworkqueue.AddRateLimited(images.ImageWorkRequest{WorkType: wqKey.WorkType, Imagecache: imageCache})
+		pullDeadline := policy.ImagePullDeadline(policies)
+		if hooksPending {
+			// Hooks may take minutes: they run off the sync worker so that the other
+			// image caches keep being synced
+			go c.runPrePullHooksAndDispatch(imageCache, wqKey, workItems, *status, processingMessage, pullDeadline)
+			return nil
+		}
+		c.dispatchImageWork(imageCache, wqKey, workItems, pullDeadline)
 
 	case images.ImageCacheStatusUpdate:
 		glog.V(4).Infof("wqKey.Status = %+v", wqKey.Status)
@@ -576,13 +1086,67 @@ func (c *Controller) syncHandler(wqKey images.WorkQueueKey) error {
 		if imageCache.Status.StartTime != nil {
 			status.StartTime = imageCache.Status.StartTime
 		}
+		status.Plan = imageCache.Status.Plan
+		status.Deferred = imageCache.Status.Deferred
+		status.EntryRefreshTimes = imageCache.Status.EntryRefreshTimes
+		status.ProvidedImages = imageCache.Status.ProvidedImages
+		status.ResolvedImages = imageCache.Status.ResolvedImages
+		status.Rollout = imageCache.Status.Rollout
+		if c.recordImageFsUsage && len(imageCache.Status.ImageFsUsage) > 0 {
+			status.ImageFsUsage = c.imageFsUsageAfter(imageCache.Status.ImageFsUsage)
+		}
+		if imageCache.Status.Progress != nil {
+			status.Progress = &v1alpha2.ImageCacheProgress{Total: imageCache.Status.Progress.Total}
+		}
 
 		status.Status = v1alpha2.ImageCacheActioneNoImagesPulledOrDeleted
 		status.Reason = imageCache.Status.Reason
 		status.Message = v1alpha2.ImageCacheMessageNoImagesPulledOrDeleted
 
+		tags := displayTags(imageCache)
 		failures := false
+		failed, aborted := 0, 0
+		abortReason := ""
+		circuitDeferred, undispatchedNodes, openRegistries := 0, map[string]bool{}, []string{}
+		loadDeferred, overloadedNodes := 0, []string{}
 		for _, v := range *wqKey.Status {
+			// Work items cancelled on request or when the failure threshold was
+			// exceeded are neither completed nor failed
+			if v.Status == images.ImageWorkResultStatusAborted {
+				aborted++
+				abortReason = v.Reason
+				continue
+			}
+			// Work items deferred because the circuit of their registry is open are
+			// retried once its cool-down period elapsed, and those deferred because
+			// their node is overloaded once their backoff elapsed
+			if v.Status == images.ImageWorkResultStatusDeferred {
+				node := v.ImageWorkRequest.Node.Name
+				if !deferredNode(status.Deferred, node) {
+					undispatchedNodes[node] = true
+				}
+				status.Deferred = deferImage(status.Deferred, imageCache.Status.Deferred, node, v.ImageWorkRequest.Image, time.Now())
+				if v.Reason == v1alpha2.ImageCacheReasonNodeOverloaded {
+					if hostname := v.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"]; !containsString(overloadedNodes, hostname) {
+						overloadedNodes = append(overloadedNodes, hostname)
+					}
+					loadDeferred++
+					continue
+				}
+				status.Deferred = deferUntil(status.Deferred, node, c.imageManager.RegistryCircuitOpenUntil(v.ImageWorkRequest.PullReference()))
+				if registry := imageRegistry(v.ImageWorkRequest.PullReference()); !containsString(openRegistries, registry) {
+					openRegistries = append(openRegistries, registry)
+				}
+				circuitDeferred++
+				continue
+			}
+			// Work items failed because their node went offline are retried once
+			// the node reconnects
+			if c.deferFailedWork(v) {
+				status.Deferred = deferImage(status.Deferred, imageCache.Status.Deferred,
+					v.ImageWorkRequest.Node.Name, v.ImageWorkRequest.Image, time.Now())
+				continue
+			}
 			if (v.Status == images.ImageWorkResultStatusSucceeded || v.Status == images.ImageWorkResultStatusAlreadyPulled) && !failures {
 				status.Status = v1alpha2.ImageCacheActionStatusSucceeded
 				if v.ImageWorkRequest.WorkType == images.ImageCachePurge {
@@ -600,35 +1164,130 @@ func (c *Controller) syncHandler(wqKey images.WorkQueueKey) error {
 					status.Message = v1alpha2.ImageCacheMessageImagePullFailedForSomeImages
 				}
 			}
+			if v.Status == images.ImageWorkResultStatusSucceeded && v.ImageWorkRequest.Fallbacks != nil {
+				if status.Sources == nil {
+					status.Sources = map[string][]v1alpha2.NodeSource{}
+				}
+				image := displayImage(v.ImageWorkRequest.Image, tags)
+				status.Sources[image] = append(status.Sources[image], v1alpha2.NodeSource{
+					Node:   v.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"],
+					Source: v.ImageWorkRequest.PullReference(),
+				})
+			}
+			if status.Progress != nil {
+				if v.Status == images.ImageWorkResultStatusFailed || v.Status == images.ImageWorkResultStatusUnknown {
+					status.Progress.Failed++
+				} else {
+					status.Progress.Completed++
+				}
+			}
 			if v.Status == images.ImageWorkResultStatusFailed || v.Status == images.ImageWorkResultStatusUnknown {
-				status.Failures[v.ImageWorkRequest.Image] = append(
-					status.Failures[v.ImageWorkRequest.Image], v1alpha2.NodeReasonMessage{
+				failed++
+				image := displayImage(v.ImageWorkRequest.Image, tags)
+				status.Failures[image] = append(
+					status.Failures[image], v1alpha2.NodeReasonMessage{
 						Node:    v.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"],
 						Reason:  v.Reason,
 						Message: v.Message,
+						Log:     v.Log,
 					})
 			}
 		}
 
+		for _, sources := range status.Sources {
+			sort.Slice(sources, func(i, j int) bool { return sources[i].Node < sources[j].Node })
+		}
+
+		if offline := len(status.Deferred) - len(undispatchedNodes); offline > 0 {
+			message := fmt.Sprintf("Image pulls to %d offline nodes deferred until they reconnect", offline)
+			if status.Status == v1alpha2.ImageCacheActioneNoImagesPulledOrDeleted {
+				status.Message = message
+			} else {
+				status.Message = status.Message + ". " + message
+			}
+		}
+		if circuitDeferred > 0 {
+			sort.Strings(openRegistries)
+			message := fmt.Sprintf("%d image pulls deferred while the circuit of registries %s is open",
+				circuitDeferred, strings.Join(openRegistries, ", "))
+			if status.Status == v1alpha2.ImageCacheActioneNoImagesPulledOrDeleted && len(status.Deferred) == len(undispatchedNodes) {
+				status.Message = message
+			} else {
+				status.Message = status.Message + ". " + message
+			}
+		}
+		if loadDeferred > 0 {
+			sort.Strings(overloadedNodes)
+			message := fmt.Sprintf("%d image pulls deferred while the load of nodes %s exceeds the load throttle",
+				loadDeferred, strings.Join(overloadedNodes, ", "))
+			if status.Status == v1alpha2.ImageCacheActioneNoImagesPulledOrDeleted && len(status.Deferred) == len(undispatchedNodes) && circuitDeferred == 0 {
+				status.Message = message
+			} else {
+				status.Message = status.Message + ". " + message
+			}
+		}
+
+		if status.Plan != nil && status.Plan.CappedWorkItems > 0 {
+			status.Message = status.Message + fmt.Sprintf(". %d image pulls skipped as they exceed maxBytesPerNode", status.Plan.CappedWorkItems)
+		}
+
+		if status.Reason != v1alpha2.ImageCacheReasonImageCachePurge {
+			if c.egressAccounting {
+				c.recordEgress(imageCache, *wqKey.Status)
+			}
+			status.GCPressure = c.gcPressure(imageCache)
+			if c.reportLayerStats {
+				status.LayerStats = c.layerStats(imageCache)
+			}
+			if len(status.GCPressure) > 0 {
+				status.Message = status.Message + fmt.Sprintf(". Images on %d nodes exceed the kubelet image GC low threshold: cached images may be removed", len(status.GCPressure))
+				c.recorder.Eventf(imageCache, corev1.EventTypeWarning, ReasonImageGCPressure,
+					"Images on %d nodes exceed the kubelet image GC low threshold of %d%%: cached images may be removed", len(status.GCPressure), c.imageGCLowThreshold)
+			}
+		}
+
+		if aborted > 0 && abortReason == v1alpha2.ImageCacheReasonImageCacheCancel {
+			status.Status = v1alpha2.ImageCacheActionStatusAborted
+			status.Reason = v1alpha2.ImageCacheReasonImageCacheCancel
+			status.Message = fmt.Sprintf("Image cache processing cancelled on request. %d remaining image pulls/deletes were cancelled", aborted)
+		} else if aborted > 0 {
+			status.Status = v1alpha2.ImageCacheActionStatusFailed
+			status.Reason = v1alpha2.ImageCacheReasonFailureThresholdExceeded
+			status.Message = fmt.Sprintf("%d image pulls failed, more than the failure threshold of %s. %d remaining image pulls were cancelled. Please see \"failures\" section",
+				failed, imageCache.Spec.FailureThreshold.String(), aborted)
+		}
+
+		nextWave := continueRollout(status)
+
+		// The reason of the action is overwritten when its failure threshold is exceeded
+		action := imageCache.Status.Reason
+		recordReconciled(status, action)
 		err = c.updateImageCacheStatus(imageCache, status)
 		if err != nil {
 			glog.Errorf("Error updating ImageCache status: %v", err)
 			return err
 		}
+		c.recordSync(imageCache, status)
+		c.auditImageWork(imageCache, action, *wqKey.Status)
+		c.publishActionFinished(imageCache, action, status)
+		go c.runPostPullHooks(imageCache, action, status.Status, *wqKey.Status)
+		if nextWave > 0 {
+			c.workqueue.AddRateLimited(images.WorkQueueKey{WorkType: images.ImageCacheRefresh, ObjKey: wqKey.ObjKey, Wave: nextWave})
+		}
 
-		if imageCache.Status.Reason == v1alpha2.ImageCacheReasonImageCachePurge || imageCache.Status.Reason == v1alpha2.ImageCacheReasonImageCacheRefresh {
+		if action == v1alpha2.ImageCacheReasonImageCachePurge || action == v1alpha2.ImageCacheReasonImageCacheRefresh {
 			imageCache, err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(namespace).Get(context.TODO(), name, metav1.GetOptions{})
 			if err != nil {
 				glog.Errorf("Error getting image cache %s: %v", name, err)
 				return err
 			}
-			if imageCache.Status.Reason == v1alpha2.ImageCacheReasonImageCachePurge {
+			if action == v1alpha2.ImageCacheReasonImageCachePurge {
 				if err := c.removeAnnotation(imageCache, imageCachePurgeAnnotationKey); err != nil {
 					glog.Errorf("Error removing Annotation %s from imagecache(%s): %v", imageCachePurgeAnnotationKey, imageCache.Name, err)
 					return err
 				}
 			}
-			if imageCache.Status.Reason == v1alpha2.ImageCacheReasonImageCacheRefresh {
+			if action == v1alpha2.ImageCacheReasonImageCacheRefresh {
 				if _, ok := imageCache.Annotations[imageCacheRefreshAnnotationKey]; ok {
 					if err := c.removeAnnotation(imageCache, imageCacheRefreshAnnotationKey); err != nil {
 						glog.Errorf("Error removing Annotation %s from imagecache(%s): %v", imageCacheRefreshAnnotationKey, imageCache.Name, err)
@@ -642,15 +1301,383 @@ func (c *Controller) syncHandler(wqKey images.WorkQueueKey) error {
 			c.recorder.Event(imageCache, corev1.EventTypeNormal, status.Reason, status.Message)
 		}
 
-		if status.Status == v1alpha2.ImageCacheActionStatusFailed {
+		if status.Status == v1alpha2.ImageCacheActionStatusFailed || status.Status == v1alpha2.ImageCacheActionStatusAborted {
 			c.recorder.Event(imageCache, corev1.EventTypeWarning, status.Reason, status.Message)
 		}
+
+	case images.ImageCacheCancel:
+		imageCache, err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			glog.Errorf("Error getting image cache %s: %v", name, err)
+			return err
+		}
+		if imageCache.Status.Status == v1alpha2.ImageCacheActionStatusProcessing {
+			if c.imageManager.AbortImageCacheAction(imageCache, v1alpha2.ImageCacheReasonImageCacheCancel, "Image cache processing cancelled on request") {
+				glog.Infof("Image cache %s: processing cancelled on request", wqKey.ObjKey)
+			}
+		} else {
+			glog.Infof("Image cache %s is not under processing: nothing to cancel", wqKey.ObjKey)
+		}
+		if err := c.removeAnnotation(imageCache, imageCacheCancelAnnotationKey); err != nil {
+			glog.Errorf("Error removing Annotation %s from imagecache(%s): %v", imageCacheCancelAnnotationKey, imageCache.Name, err)
+			return err
+		}
+
+	case images.ImageCacheProgressUpdate:
+		glog.V(4).Infof("wqKey.Progress = %+v", wqKey.Progress)
+		imageCache, err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			glog.Errorf("Error getting image cache %s: %v", name, err)
+			return err
+		}
+		// The final status may already have been written, in which case this
+		// progress update is stale
+		if imageCache.Status.Status != v1alpha2.ImageCacheActionStatusProcessing || wqKey.Progress == nil {
+			return nil
+		}
+		status = imageCache.Status.DeepCopy()
+		progress := &v1alpha2.ImageCacheProgress{
+			Completed: wqKey.Progress.Completed,
+			Failed:    wqKey.Progress.Failed,
+			Pulls:     wqKey.Progress.Pulls,
+		}
+		if status.Progress != nil {
+			progress.Total = status.Progress.Total
+			if progress.Pulls == nil {
+				progress.Pulls = status.Progress.Pulls
+			}
+		}
+		status.Progress = progress
+		if err = c.updateImageCacheStatus(imageCache, status); err != nil {
+			glog.Errorf("Error updating ImageCache progress: %v", err)
+			return err
+		}
 	}
 	glog.Infof("Completed sync actions for image cache %s(%s)", name, wqKey.WorkType)
 	return nil
 
 }
 
+// imageWorkItem is an image (or OCI artifact) pull or delete to be performed on a node
+type imageWorkItem struct {
+	image      string
+	artifact   bool
+	platform   string
+	pullPolicy corev1.PullPolicy
+	targetRef  string
+	fallbacks  []string
+	node       *corev1.Node
+	workType   images.WorkType
+}
+
+// imageNodeKey identifies an image (or OCI artifact) on a node
+type imageNodeKey struct {
+	image    string
+	artifact bool
+	node     string
+}
+
+// cacheRef is an image or an OCI artifact listed in a cache spec entry
+type cacheRef struct {
+	name       string
+	artifact   bool
+	platform   string
+	pullPolicy corev1.PullPolicy
+	targetRef  string
+}
+
+// cacheSpecRefs returns the images and OCI artifacts listed in a cache spec entry
+func cacheSpecRefs(cacheSpec v1alpha2.CacheSpecImages) []cacheRef {
+	refs := []cacheRef{}
+	for _, image := range cacheSpec.Images {
+		refs = append(refs, cacheRef{name: image, platform: cacheSpec.Platforms[image], pullPolicy: cacheSpec.ImagePullPolicies[image],
+			targetRef: cacheSpec.TargetRefs[image]})
+	}
+	for _, artifact := range cacheSpec.Artifacts {
+		refs = append(refs, cacheRef{name: artifact, artifact: true})
+	}
+	return refs
+}
+
+// cacheSpecFallbacks returns the fallback references of an image listed in a cache spec
+// entry. OCI artifacts have no fallback references
+func cacheSpecFallbacks(cacheSpec v1alpha2.CacheSpecImages, ref cacheRef) []string {
+	if ref.artifact {
+		return nil
+	}
+	return cacheSpec.Fallbacks[ref.name]
+}
+
+// planImageWork lists the nodes targeted by each cache spec entry and builds the work
+// items of an image cache action. When several entries list the same image for the
+// same node, a single work item is queued and the overlap is reported in the plan.
+func (c *Controller) planImageWork(wqKey images.WorkQueueKey, cacheSpec []v1alpha2.CacheSpecImages, warmStandby *v1alpha2.WarmStandby) ([]imageWorkItem, *v1alpha2.ImageCachePlan, error) {
+	plan := &v1alpha2.ImageCachePlan{}
+	workItems := []imageWorkItem{}
+	purgeItems := []imageWorkItem{}
+	entries := map[imageNodeKey][]int{}
+	planned := map[imageNodeKey]bool{}
+	purges := map[imageNodeKey]bool{}
+	// Images tagged as a target reference are stored as the target reference on the nodes
+	targets := map[imageNodeKey]bool{}
+	// Nodes of restricted node pools are excluded from the image caches of namespaces
+	// that may not target them
+	namespace, _, _ := cache.SplitMetaNamespaceKey(wqKey.ObjKey)
+	policies := c.listPolicies()
+	// Warm standby nodes are targeted by every cache spec entry
+	standby, err := c.warmStandbyNodes(warmStandby)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for k, i := range cacheSpec {
+		nodes, err := c.nodesForCacheSpec(i)
+		if err != nil {
+			return nil, nil, err
+		}
+		glog.V(4).Infof("No. of nodes in %+v is %d", i.NodeSelector, len(nodes))
+		for _, n := range standby {
+			if !containsNode(nodes, n) {
+				nodes = append(nodes, n)
+			}
+		}
+
+		if wqKey.Nodes != nil {
+			nodes = restrictNodes(nodes, *wqKey.Nodes)
+		}
+
+		refs := cacheSpecRefs(i)
+		// The images of entries not due for refresh are not pulled, but still cached
+		due := entryDue(wqKey, k)
+		for _, n := range nodes {
+			if !policy.NodeAllowed(namespace, n, policies) {
+				continue
+			}
+			for _, ref := range refs {
+				key := imageNodeKey{image: ref.name, artifact: ref.artifact, node: n.Name}
+				if due && !planned[key] {
+					workItems = append(workItems, imageWorkItem{image: ref.name, artifact: ref.artifact, platform: ref.platform, pullPolicy: ref.pullPolicy,
+						targetRef: ref.targetRef, fallbacks: cacheSpecFallbacks(i, ref), node: n, workType: wqKey.WorkType})
+					planned[key] = true
+				} else if due {
+					plan.MergedWorkItems++
+				}
+				entries[key] = append(entries[key], k)
+				if ref.targetRef != "" {
+					targets[imageNodeKey{image: ref.targetRef, node: n.Name}] = true
+				}
+			}
+			if wqKey.OldImageCache != nil && k < len(wqKey.OldImageCache.Spec.CacheSpec) {
+				for _, oldref := range cacheSpecRefs(wqKey.OldImageCache.Spec.CacheSpec[k]) {
+					matched := false
+					for _, newref := range refs {
+						if oldref == newref {
+							matched = true
+							break
+						}
+					}
+					key := imageNodeKey{image: oldref.name, artifact: oldref.artifact, node: n.Name}
+					if !matched && !purges[key] {
+						purges[key] = true
+						purgeItems = append(purgeItems, imageWorkItem{image: oldref.name, artifact: oldref.artifact, targetRef: oldref.targetRef,
+							node: n, workType: images.ImageCachePurge})
+					}
+				}
+			}
+		}
+		for _, n := range c.unmatchedNodes(wqKey, k, nodes) {
+			for _, ref := range refs {
+				key := imageNodeKey{image: ref.name, artifact: ref.artifact, node: n.Name}
+				if !purges[key] {
+					purges[key] = true
+					purgeItems = append(purgeItems, imageWorkItem{image: ref.name, artifact: ref.artifact, targetRef: ref.targetRef,
+						node: n, workType: images.ImageCachePurge})
+				}
+			}
+		}
+	}
+
+	// An image removed from one entry is not purged from nodes where
+	// another entry still caches it
+	for _, p := range purgeItems {
+		_, cached := entries[imageNodeKey{image: p.image, artifact: p.artifact, node: p.node.Name}]
+		if p.targetRef != "" {
+			cached = targets[imageNodeKey{image: p.targetRef, node: p.node.Name}]
+		}
+		if !cached {
+			workItems = append(workItems, p)
+		}
+	}
+
+	overlaps := map[string]*v1alpha2.ImageCacheOverlap{}
+	overlappingImages := []string{}
+	for _, w := range workItems {
+		key := imageNodeKey{image: w.image, artifact: w.artifact, node: w.node.Name}
+		if len(entries[key]) < 2 {
+			continue
+		}
+		overlap, ok := overlaps[w.image]
+		if !ok {
+			overlap = &v1alpha2.ImageCacheOverlap{Image: w.image}
+			overlaps[w.image] = overlap
+			overlappingImages = append(overlappingImages, w.image)
+		}
+		overlap.Nodes++
+		for _, e := range entries[key] {
+			if !containsInt(overlap.Entries, e) {
+				overlap.Entries = append(overlap.Entries, e)
+			}
+		}
+	}
+	for _, image := range overlappingImages {
+		sort.Ints(overlaps[image].Entries)
+		plan.Overlaps = append(plan.Overlaps, *overlaps[image])
+	}
+	prioritizeNodes(workItems, standby)
+	plan.WorkItems = len(workItems)
+	return workItems, plan, nil
+}
+
+// listPolicies returns the FledgedPolicies of the cluster
+func (c *Controller) listPolicies() []*v1alpha2.FledgedPolicy {
+	policies, err := c.policiesLister.List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error listing fledged policies: %v", err)
+		return nil
+	}
+	if p := c.configPolicy(); p != nil {
+		policies = append(policies, p)
+	}
+	return policies
+}
+
+// failPolicyViolation fails an image cache action that violates a FledgedPolicy
+func (c *Controller) failPolicyViolation(imageCache *v1alpha2.ImageCache, workType images.WorkType, status *v1alpha2.ImageCacheStatus, violation error) error {
+	status.Status = v1alpha2.ImageCacheActionStatusFailed
+	status.Reason = v1alpha2.ImageCacheReasonPolicyViolation
+	status.Message = violation.Error()
+
+	if err := c.updateImageCacheStatus(imageCache, status); err != nil {
+		glog.Errorf("Error updating imagecache status to %s: %v", status.Status, err)
+		return err
+	}
+	c.recorder.Event(imageCache, corev1.EventTypeWarning, status.Reason, status.Message)
+	c.publishActionFinished(imageCache, actionReason(workType), status)
+	glog.Errorf("%s: %s", v1alpha2.ImageCacheReasonPolicyViolation, violation.Error())
+	return fmt.Errorf("%s: %s", v1alpha2.ImageCacheReasonPolicyViolation, violation.Error())
+}
+
+// countNodes returns the number of nodes targeted by the pulls of an image cache action
+func countNodes(workItems []imageWorkItem) int {
+	nodes := map[string]bool{}
+	for _, w := range workItems {
+		if w.workType != images.ImageCachePurge {
+			nodes[w.node.Name] = true
+		}
+	}
+	return len(nodes)
+}
+
+// recordSync adds a finished image cache action to the history shown by the dashboard
+func (c *Controller) recordSync(imageCache *v1alpha2.ImageCache, status *v1alpha2.ImageCacheStatus) {
+	r := syncRecord{
+		ImageCache:     imageCache.Namespace + "/" + imageCache.Name,
+		Reason:         status.Reason,
+		Status:         status.Status,
+		CompletionTime: time.Now(),
+	}
+	if status.StartTime != nil {
+		r.StartTime = status.StartTime.Time
+	}
+	for _, failures := range status.Failures {
+		r.Failures += len(failures)
+	}
+	c.history.add(r)
+}
+
+func containsInt(list []int, i int) bool {
+	for _, v := range list {
+		if v == i {
+			return true
+		}
+	}
+	return false
+}
+
+// nodesForCacheSpec lists the nodes targeted by a cache spec entry. Entries without
+// a nodeSelector are restricted to nodes running the default node OS, so that images
+// are not pulled onto nodes of an incompatible operating system.
+func (c *Controller) nodesForCacheSpec(cacheSpec v1alpha2.CacheSpecImages) ([]*corev1.Node, error) {
+	nodeSelector := c.entryNodeSelector(cacheSpec)
+	nodes, err := c.nodesLister.List(labels.Set(nodeSelector).AsSelector())
+	if err != nil {
+		glog.Errorf("Error listing nodes using nodeselector %+v: %v", nodeSelector, err)
+		return nil, err
+	}
+	return c.excludeVirtualNodes(nodes), nil
+}
+
+// entryNodeSelector returns the node selector of a cache spec entry, which selects the
+// nodes running the default node OS if the entry has none
+func (c *Controller) entryNodeSelector(cacheSpec v1alpha2.CacheSpecImages) map[string]string {
+	return images.EffectiveNodeSelector(cacheSpec.NodeSelector, c.defaultNodeOS)
+}
+
+// newEventRecorder creates the event recorder of the controller. Events are always
+// logged. Unless disabled, they are also recorded to eventSink, or to the Kubernetes
+// API when no custom sink is given. A non-empty sinkNamespace restricts the sink to
+// events of objects in that namespace: the events of other namespaces are dropped.
+func newEventRecorder(kubeclientset kubernetes.Interface, componentName string,
+	sinkNamespace string, disabled bool, eventSink record.EventSink) record.EventRecorder {
+	if componentName == "" {
+		componentName = controllerAgentName
+	}
+	glog.V(4).Info("Creating event broadcaster")
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartLogging(glog.Infof)
+	if disabled {
+		glog.Info("Event recording is disabled")
+	} else {
+		if eventSink == nil {
+			eventSink = &typedcorev1.EventSinkImpl{Interface: kubeclientset.CoreV1().Events(sinkNamespace)}
+		}
+		if sinkNamespace != "" {
+			eventSink = &namespaceEventSink{EventSink: eventSink, namespace: sinkNamespace}
+		}
+		eventBroadcaster.StartRecordingToSink(eventSink)
+	}
+	return eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: componentName})
+}
+
+// namespaceEventSink records the events of a namespace to the wrapped sink, and drops
+// the events of other namespaces. The events API of a namespace rejects the events of
+// other namespaces, which would otherwise be logged as errors.
+type namespaceEventSink struct {
+	record.EventSink
+	namespace string
+}
+
+func (s *namespaceEventSink) Create(event *corev1.Event) (*corev1.Event, error) {
+	if event.Namespace != s.namespace {
+		return event, nil
+	}
+	return s.EventSink.Create(event)
+}
+
+func (s *namespaceEventSink) Update(event *corev1.Event) (*corev1.Event, error) {
+	if event.Namespace != s.namespace {
+		return event, nil
+	}
+	return s.EventSink.Update(event)
+}
+
+func (s *namespaceEventSink) Patch(event *corev1.Event, data []byte) (*corev1.Event, error) {
+	if event.Namespace != s.namespace {
+		return event, nil
+	}
+	return s.EventSink.Patch(event, data)
+}
+
 func (c *Controller) updateImageCacheStatus(imageCache *v1alpha2.ImageCache, status *v1alpha2.ImageCacheStatus) error {
 	imageCacheCopy, err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(imageCache.Namespace).Get(context.TODO(), imageCache.Name, metav1.GetOptions{})
 	if err != nil {
@@ -659,7 +1686,23 @@ func (c *Controller) updateImageCacheStatus(imageCache *v1alpha2.ImageCache, sta
 	// NEVER modify objects from the store. It's a read-only, local cache.
 	// You can use DeepCopy() to make a deep copy of original object and modify this copy
 	// Or create a copy manually for better performance
+	usage := imageCacheCopy.Status.Usage
+	dryRun := imageCacheCopy.Status.DryRun
+	controllerVersion, observedSpecHash := imageCacheCopy.Status.ControllerVersion, imageCacheCopy.Status.ObservedSpecHash
 	imageCacheCopy.Status = *status
+	// Usage is reported by the usage worker, independently of image cache actions
+	if imageCacheCopy.Status.Usage == nil {
+		imageCacheCopy.Status.Usage = usage
+	}
+	// The plan of the last dry run is kept until the next dry run
+	if imageCacheCopy.Status.DryRun == nil {
+		imageCacheCopy.Status.DryRun = dryRun
+	}
+	// The last successful reconcile is kept until the next one
+	if imageCacheCopy.Status.ObservedSpecHash == "" {
+		imageCacheCopy.Status.ControllerVersion = controllerVersion
+		imageCacheCopy.Status.ObservedSpecHash = observedSpecHash
+	}
 	if imageCacheCopy.Status.Status != v1alpha2.ImageCacheActionStatusProcessing {
 		completionTime := metav1.Now()
 		imageCacheCopy.Status.CompletionTime = &completionTime
@@ -681,3 +1724,34 @@ func (c *Controller) removeAnnotation(imageCache *v1alpha2.ImageCache, annotatio
 	}
 	return err
 }
+
+// dispatchImageWork queues the image pulls or deletes of the work items of an image
+// cache action in the image work queue
+func (c *Controller) dispatchImageWork(imageCache *v1alpha2.ImageCache, wqKey images.WorkQueueKey, workItems []imageWorkItem, pullDeadline time.Duration) {
+	c.imageManager.BeginImageCacheAction(imageCache)
+	imageworkqueue := c.imageworkqueues.For(wqKey.WorkType, wqKey.Background)
+	for _, w := range workItems {
+		ipr := images.ImageWorkRequest{
+			Image:                   w.image,
+			Artifact:                w.artifact,
+			Platform:                w.platform,
+			PullPolicy:              w.pullPolicy,
+			TargetRef:               w.targetRef,
+			Node:                    w.node,
+			ContainerRuntimeVersion: w.node.Status.NodeInfo.ContainerRuntimeVersion,
+			WorkType:                w.workType,
+			Total:                   len(workItems),
+			Imagecache:              imageCache,
+		}
+		if len(w.fallbacks) > 0 {
+			fallbacks := w.fallbacks
+			ipr.Fallbacks = &fallbacks
+		}
+		imageworkqueue.AddRateLimited(ipr)
+	}
+
+	// We add an empty image pull request to signal the image manager that all
+	// requests for this sync action have been placed in the imageworkqueue
+	imageworkqueue.AddRateLimited(images.ImageWorkRequest{WorkType: wqKey.WorkType, Imagecache: imageCache,
+		PullDeadline: pullDeadline})
+}
@@ -18,6 +18,8 @@ package app
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -33,6 +35,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	kubeinformers "k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
@@ -58,16 +61,28 @@ func newTestController(kubeclientset kubernetes.Interface, fledgedclientset clie
 	fledgedInformerFactory := informers.NewSharedInformerFactory(fledgedclientset, noResyncPeriodFunc())
 	nodeInformer := kubeInformerFactory.Core().V1().Nodes()
 	imagecacheInformer := fledgedInformerFactory.Kubefledged().V1alpha2().ImageCaches()
-	imageCacheRefreshFrequency := time.Second * 0
-	imagePullDeadlineDuration := time.Second * 5
-	criClientImage := "senthilrch/fledged-docker-client:latest"
-	busyboxImage := "busybox:latest"
-	imagePullPolicy := "IfNotPresent"
-	serviceAccountName := "sa-kube-fledged"
-	imageDeleteJobHostNetwork := false
-	jobPriorityClassName := "priority-class-kube-fledged"
-	canDelete := false
-	socketPath := ""
+	opts := &Options{
+		Namespace:                  fledgedNameSpace,
+		ImageCacheRefreshFrequency: time.Second * 0,
+		ImagePullDeadlineDuration:  time.Second * 5,
+		CRIClientImage:             "senthilrch/fledged-docker-client:latest",
+		CRIClientWindowsImage:      "senthilrch/fledged-docker-client-windows:latest",
+		BusyboxImage:               "busybox:latest",
+		ImagePullPolicy:            "IfNotPresent",
+		ServiceAccountName:         "sa-kube-fledged",
+		JobPriorityClassName:       "priority-class-kube-fledged",
+		DefaultNodeOS:              "linux",
+		StatusUpdateInterval:       time.Second * 5,
+		StatusUpdateBatchSize:      100,
+		StuckJobThreshold:          time.Minute * 2,
+		PullRetry:                  images.PullRetry{BaseDelay: time.Second * 10, MaxDelay: time.Minute * 5, Jitter: 0.1},
+		ORASImage:                  "ghcr.io/oras-project/oras:v0.16.0",
+		ArtifactStorePath:          "/var/lib/kubefledged/artifacts",
+		PullStrategy:               images.PullStrategyKubelet,
+		ContainerdNamespace:        images.DefaultContainerdNamespace,
+		EgressCostRates:            map[string]float64{},
+		EventComponentName:         "kubefledged-controller",
+	}
 
 	/* 	startInformers := true
 	   	if startInformers {
@@ -77,13 +92,15 @@ func newTestController(kubeclientset kubernetes.Interface, fledgedclientset clie
 	   		fledgedInformerFactory.Start(stopCh)
 	   	} */
 
-	controller := NewController(kubeclientset,
-		fledgedclientset, fledgedNameSpace, nodeInformer, imagecacheInformer,
-		imageCacheRefreshFrequency, imagePullDeadlineDuration, criClientImage,
-		busyboxImage, imagePullPolicy, serviceAccountName, imageDeleteJobHostNetwork,
-		jobPriorityClassName, canDelete, socketPath)
+	controller := NewController(kubeclientset, fledgedclientset, kubeInformerFactory, images.NewInformerFactory(kubeclientset), fledgedInformerFactory,
+		opts, nil, nil, nil)
 	controller.nodesSynced = func() bool { return true }
 	controller.imageCachesSynced = func() bool { return true }
+	controller.policiesSynced = func() bool { return true }
+	controller.requestsSynced = func() bool { return true }
+	controller.templatesSynced = func() bool { return true }
+	controller.imageListsSynced = func() bool { return true }
+	controller.hookJobsSynced = func() bool { return true }
 	return controller, nodeInformer, imagecacheInformer
 }
 
@@ -468,6 +485,8 @@ func TestSyncHandler(t *testing.T) {
 		},
 	}
 
+	failureThreshold := intstr.FromInt(0)
+
 	tests := []struct {
 		name              string
 		imageCache        kubefledgedv1alpha2.ImageCache
@@ -842,6 +861,329 @@ func TestSyncHandler(t *testing.T) {
 			expectErr:         false,
 			expectedErrString: "",
 		},
+		{
+			name: "#16: Create - Invalid imagecache spec (duplicate images)",
+			imageCache: kubefledgedv1alpha2.ImageCache{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "kube-fledged",
+				},
+				Spec: kubefledgedv1alpha2.ImageCacheSpec{
+					CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+						{
+							Images: []string{"foo", "foo"},
+						},
+					},
+				},
+			},
+			wqKey: images.WorkQueueKey{
+				ObjKey:   "kube-fledged/foo",
+				WorkType: images.ImageCacheCreate,
+			},
+			nodeList: defaultNodeList,
+			expectedActions: []ActionReaction{
+				{action: "get", reaction: ""},
+				{action: "update", reaction: ""},
+			},
+			expectErr:         true,
+			expectedErrString: "CacheSpecValidationFailed: Duplicate image names within image list: foo",
+		},
+		{
+			name: "#17: ProgressUpdate - Successful",
+			imageCache: kubefledgedv1alpha2.ImageCache{
+				ObjectMeta: defaultImageCache.ObjectMeta,
+				Spec:       defaultImageCache.Spec,
+				Status: kubefledgedv1alpha2.ImageCacheStatus{
+					Status:   kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+					Progress: &kubefledgedv1alpha2.ImageCacheProgress{Total: 2},
+				},
+			},
+			wqKey: images.WorkQueueKey{
+				ObjKey:   "kube-fledged/foo",
+				WorkType: images.ImageCacheProgressUpdate,
+				Progress: &kubefledgedv1alpha2.ImageCacheProgress{Completed: 1},
+			},
+			expectedActions: []ActionReaction{
+				{action: "get", reaction: ""},
+				{action: "update", reaction: ""},
+			},
+			expectErr:         false,
+			expectedErrString: "",
+		},
+		{
+			name: "#18: ProgressUpdate - Unable to update imagecache progress",
+			imageCache: kubefledgedv1alpha2.ImageCache{
+				ObjectMeta: defaultImageCache.ObjectMeta,
+				Spec:       defaultImageCache.Spec,
+				Status: kubefledgedv1alpha2.ImageCacheStatus{
+					Status:   kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+					Progress: &kubefledgedv1alpha2.ImageCacheProgress{Total: 2},
+				},
+			},
+			wqKey: images.WorkQueueKey{
+				ObjKey:   "kube-fledged/foo",
+				WorkType: images.ImageCacheProgressUpdate,
+				Progress: &kubefledgedv1alpha2.ImageCacheProgress{Completed: 1},
+			},
+			expectedActions: []ActionReaction{
+				{action: "get", reaction: ""},
+				{action: "update", reaction: "fake error"},
+			},
+			expectErr:         true,
+			expectedErrString: "Internal error occurred: fake error",
+		},
+		{
+			name:       "#19: ProgressUpdate - Stale progress ignored",
+			imageCache: defaultImageCache,
+			wqKey: images.WorkQueueKey{
+				ObjKey:   "kube-fledged/foo",
+				WorkType: images.ImageCacheProgressUpdate,
+				Progress: &kubefledgedv1alpha2.ImageCacheProgress{Completed: 1},
+			},
+			expectedActions: []ActionReaction{
+				{action: "get", reaction: ""},
+				{action: "update", reaction: "fake error"},
+			},
+			expectErr:         false,
+			expectedErrString: "",
+		},
+		{
+			name: "#20: Cancel - Image cache under processing",
+			imageCache: kubefledgedv1alpha2.ImageCache{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "foo",
+					Namespace:   "kube-fledged",
+					Annotations: map[string]string{imageCacheCancelAnnotationKey: ""},
+				},
+				Spec: defaultImageCache.Spec,
+				Status: kubefledgedv1alpha2.ImageCacheStatus{
+					Status: kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+				},
+			},
+			wqKey: images.WorkQueueKey{
+				ObjKey:   "kube-fledged/foo",
+				WorkType: images.ImageCacheCancel,
+			},
+			expectedActions: []ActionReaction{
+				{action: "get", reaction: ""},
+				{action: "update", reaction: ""},
+			},
+			expectErr:         false,
+			expectedErrString: "",
+		},
+		{
+			name:       "#21: StatusUpdate - ImageCacheCancel",
+			imageCache: defaultImageCache,
+			wqKey: images.WorkQueueKey{
+				ObjKey:   "kube-fledged/foo",
+				WorkType: images.ImageCacheStatusUpdate,
+				Status: &map[string]images.ImageWorkResult{
+					"job1": {
+						Status: images.ImageWorkResultStatusAborted,
+						Reason: kubefledgedv1alpha2.ImageCacheReasonImageCacheCancel,
+						ImageWorkRequest: images.ImageWorkRequest{
+							WorkType: images.ImageCacheCreate,
+							Node:     &node,
+						},
+					},
+				},
+			},
+			expectedActions: []ActionReaction{
+				{action: "get", reaction: ""},
+				{action: "update", reaction: ""},
+			},
+			expectErr:         false,
+			expectedErrString: "",
+		},
+		{
+			name: "#22: StatusUpdate - FailureThresholdExceeded",
+			imageCache: kubefledgedv1alpha2.ImageCache{
+				ObjectMeta: defaultImageCache.ObjectMeta,
+				Spec: kubefledgedv1alpha2.ImageCacheSpec{
+					CacheSpec:        defaultImageCache.Spec.CacheSpec,
+					FailureThreshold: &failureThreshold,
+				},
+			},
+			wqKey: images.WorkQueueKey{
+				ObjKey:   "kube-fledged/foo",
+				WorkType: images.ImageCacheStatusUpdate,
+				Status: &map[string]images.ImageWorkResult{
+					"job1": {
+						Status: images.ImageWorkResultStatusFailed,
+						ImageWorkRequest: images.ImageWorkRequest{
+							WorkType: images.ImageCacheCreate,
+							Node:     &node,
+						},
+					},
+					"job2": {
+						Status: images.ImageWorkResultStatusAborted,
+						ImageWorkRequest: images.ImageWorkRequest{
+							WorkType: images.ImageCacheCreate,
+							Node:     &node,
+						},
+					},
+				},
+			},
+			expectedActions: []ActionReaction{
+				{action: "get", reaction: ""},
+				{action: "update", reaction: ""},
+			},
+			expectErr:         false,
+			expectedErrString: "",
+		},
+		{
+			name:       "#23: StatusUpdate - Image pull deferred while the registry circuit is open",
+			imageCache: defaultImageCache,
+			wqKey: images.WorkQueueKey{
+				ObjKey:   "kube-fledged/foo",
+				WorkType: images.ImageCacheStatusUpdate,
+				Status: &map[string]images.ImageWorkResult{
+					"job1": {
+						Status: images.ImageWorkResultStatusDeferred,
+						Reason: kubefledgedv1alpha2.ImageCacheReasonRegistryCircuitOpen,
+						ImageWorkRequest: images.ImageWorkRequest{
+							Image:    "ghcr.io/foo/bar:v1",
+							WorkType: images.ImageCacheRefresh,
+							Node:     &node,
+						},
+					},
+				},
+			},
+			expectedActions: []ActionReaction{
+				{action: "get", reaction: ""},
+				{action: "update", reaction: ""},
+			},
+			expectErr:         false,
+			expectedErrString: "",
+		},
+		{
+			name:       "#24: StatusUpdate - Image pulled from a fallback reference",
+			imageCache: defaultImageCache,
+			wqKey: images.WorkQueueKey{
+				ObjKey:   "kube-fledged/foo",
+				WorkType: images.ImageCacheStatusUpdate,
+				Status: &map[string]images.ImageWorkResult{
+					"job1": {
+						Status: images.ImageWorkResultStatusSucceeded,
+						ImageWorkRequest: images.ImageWorkRequest{
+							Image:     "foo",
+							Fallbacks: &[]string{"mirror.internal/foo"},
+							Source:    "mirror.internal/foo",
+							WorkType:  images.ImageCacheCreate,
+							Node:      &node,
+						},
+					},
+				},
+			},
+			expectedActions: []ActionReaction{
+				{action: "get", reaction: ""},
+				{action: "update", reaction: ""},
+			},
+			expectErr:         false,
+			expectedErrString: "",
+		},
+		{
+			name:       "#25: StatusUpdate - Image pull deferred while the node is overloaded",
+			imageCache: defaultImageCache,
+			wqKey: images.WorkQueueKey{
+				ObjKey:   "kube-fledged/foo",
+				WorkType: images.ImageCacheStatusUpdate,
+				Status: &map[string]images.ImageWorkResult{
+					"job1": {
+						Status: images.ImageWorkResultStatusDeferred,
+						Reason: kubefledgedv1alpha2.ImageCacheReasonNodeOverloaded,
+						ImageWorkRequest: images.ImageWorkRequest{
+							Image:    "foo",
+							WorkType: images.ImageCacheRefresh,
+							Node:     &node,
+						},
+					},
+				},
+			},
+			expectedActions: []ActionReaction{
+				{action: "get", reaction: ""},
+				{action: "update", reaction: ""},
+			},
+			expectErr:         false,
+			expectedErrString: "",
+		},
+		{
+			name:       "#26: Refresh - Wave of a superseded rollout dropped",
+			imageCache: defaultImageCache,
+			wqKey: images.WorkQueueKey{
+				ObjKey:   "kube-fledged/foo",
+				WorkType: images.ImageCacheRefresh,
+				Wave:     2,
+			},
+			expectErr:         false,
+			expectedErrString: "",
+		},
+		{
+			name: "#27: StatusUpdate - Rollout continued with its next wave",
+			imageCache: kubefledgedv1alpha2.ImageCache{
+				ObjectMeta: defaultImageCache.ObjectMeta,
+				Spec: kubefledgedv1alpha2.ImageCacheSpec{
+					CacheSpec: defaultImageCache.Spec.CacheSpec,
+					Rollout:   &kubefledgedv1alpha2.RolloutStrategy{Type: kubefledgedv1alpha2.RolloutTypeTopology},
+				},
+				Status: kubefledgedv1alpha2.ImageCacheStatus{
+					Status:  kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+					Reason:  kubefledgedv1alpha2.ImageCacheReasonImageCacheCreate,
+					Rollout: &kubefledgedv1alpha2.ImageCacheRollout{Wave: 1, Waves: 2},
+				},
+			},
+			wqKey: images.WorkQueueKey{
+				ObjKey:   "kube-fledged/foo",
+				WorkType: images.ImageCacheStatusUpdate,
+				Status: &map[string]images.ImageWorkResult{
+					"job1": {
+						Status: images.ImageWorkResultStatusSucceeded,
+						ImageWorkRequest: images.ImageWorkRequest{
+							Image:    "foo",
+							WorkType: images.ImageCacheCreate,
+							Node:     &node,
+						},
+					},
+				},
+			},
+			expectedActions: []ActionReaction{
+				{action: "get", reaction: ""},
+				{action: "update", reaction: ""},
+			},
+			expectErr:         false,
+			expectedErrString: "",
+		},
+		{
+			name: "#28: Create - Duplicate images across image lists merged",
+			imageCache: kubefledgedv1alpha2.ImageCache{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "kube-fledged",
+				},
+				Spec: kubefledgedv1alpha2.ImageCacheSpec{
+					CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+						{
+							Images: []string{"foo"},
+						},
+						{
+							Images:       []string{"foo"},
+							NodeSelector: map[string]string{"pool": "foo"},
+						},
+					},
+				},
+			},
+			wqKey: images.WorkQueueKey{
+				ObjKey:   "kube-fledged/foo",
+				WorkType: images.ImageCacheCreate,
+			},
+			nodeList: defaultNodeList,
+			expectedActions: []ActionReaction{
+				{action: "get", reaction: ""},
+				{action: "update", reaction: ""},
+			},
+			expectErr:         false,
+			expectedErrString: "",
+		},
 	}
 
 	for _, test := range tests {
@@ -881,6 +1223,338 @@ func TestSyncHandler(t *testing.T) {
 	t.Logf("%d tests passed", len(tests))
 }
 
+func TestNodesForCacheSpec(t *testing.T) {
+	nodeList := []corev1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "linuxnode",
+				Labels: map[string]string{"kubernetes.io/hostname": "linuxnode", "kubernetes.io/os": "linux", "pool": "foo"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "windowsnode",
+				Labels: map[string]string{"kubernetes.io/hostname": "windowsnode", "kubernetes.io/os": "windows", "pool": "foo"},
+			},
+		},
+	}
+	tests := []struct {
+		name          string
+		defaultNodeOS string
+		cacheSpec     kubefledgedv1alpha2.CacheSpecImages
+		expectedNodes []string
+	}{
+		{
+			name:          "#1: No nodeSelector - restricted to default node OS",
+			defaultNodeOS: "linux",
+			cacheSpec:     kubefledgedv1alpha2.CacheSpecImages{Images: []string{"foo"}},
+			expectedNodes: []string{"linuxnode"},
+		},
+		{
+			name:          "#2: No nodeSelector - default node OS disabled",
+			defaultNodeOS: "",
+			cacheSpec:     kubefledgedv1alpha2.CacheSpecImages{Images: []string{"foo"}},
+			expectedNodes: []string{"linuxnode", "windowsnode"},
+		},
+		{
+			name:          "#3: With nodeSelector - default node OS not applied",
+			defaultNodeOS: "linux",
+			cacheSpec: kubefledgedv1alpha2.CacheSpecImages{
+				Images:       []string{"foo"},
+				NodeSelector: map[string]string{"kubernetes.io/os": "windows"},
+			},
+			expectedNodes: []string{"windowsnode"},
+		},
+	}
+
+	for _, test := range tests {
+		fakekubeclientset := &fakeclientset.Clientset{}
+		fakefledgedclientset := &kubefledgedclientsetfake.Clientset{}
+		controller, nodeInformer, _ := newTestController(fakekubeclientset, fakefledgedclientset)
+		controller.defaultNodeOS = test.defaultNodeOS
+		for i := range nodeList {
+			nodeInformer.Informer().GetIndexer().Add(&nodeList[i])
+		}
+		nodes, err := controller.nodesForCacheSpec(test.cacheSpec)
+		if err != nil {
+			t.Errorf("Test: %s failed. expectedError=nil, actualError=%s", test.name, err.Error())
+		}
+		nodeNames := []string{}
+		for _, n := range nodes {
+			nodeNames = append(nodeNames, n.Name)
+		}
+		sort.Strings(nodeNames)
+		if !reflect.DeepEqual(nodeNames, test.expectedNodes) {
+			t.Errorf("Test: %s failed. expectedNodes=%v, actualNodes=%v", test.name, test.expectedNodes, nodeNames)
+		}
+	}
+	t.Logf("%d tests passed", len(tests))
+}
+
+func TestPlanImageWork(t *testing.T) {
+	nodeList := []corev1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "node1",
+				Labels: map[string]string{"kubernetes.io/hostname": "node1", "kubernetes.io/os": "linux", "pool": "foo"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "node2",
+				Labels: map[string]string{"kubernetes.io/hostname": "node2", "kubernetes.io/os": "linux", "pool": "bar"},
+			},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			},
+		},
+	}
+	tests := []struct {
+		name                    string
+		wqKey                   images.WorkQueueKey
+		cacheSpec               []kubefledgedv1alpha2.CacheSpecImages
+		warmStandby             *kubefledgedv1alpha2.WarmStandby
+		expectedWorkItems       int
+		expectedMergedWorkItems int
+		expectedPlatform        string
+		expectedFallbacks       []string
+		expectedTargetRef       string
+		expectedFirstNode       string
+		expectedOverlaps        []kubefledgedv1alpha2.ImageCacheOverlap
+	}{
+		{
+			name:  "#1: Create - No overlapping entries",
+			wqKey: images.WorkQueueKey{WorkType: images.ImageCacheCreate},
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}, NodeSelector: map[string]string{"pool": "foo"}},
+				{Images: []string{"foo"}, NodeSelector: map[string]string{"pool": "bar"}},
+			},
+			expectedWorkItems:       2,
+			expectedMergedWorkItems: 0,
+		},
+		{
+			name:  "#2: Create - Same image in entries with overlapping node selectors",
+			wqKey: images.WorkQueueKey{WorkType: images.ImageCacheCreate},
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo", "bar"}},
+				{Images: []string{"foo"}, NodeSelector: map[string]string{"pool": "bar"}},
+			},
+			expectedWorkItems:       4,
+			expectedMergedWorkItems: 1,
+			expectedOverlaps: []kubefledgedv1alpha2.ImageCacheOverlap{
+				{Image: "foo", Entries: []int{0, 1}, Nodes: 1},
+			},
+		},
+		{
+			name: "#3: Update - Image removed from one entry is still cached by another entry",
+			wqKey: images.WorkQueueKey{
+				WorkType: images.ImageCacheUpdate,
+				OldImageCache: &kubefledgedv1alpha2.ImageCache{
+					Spec: kubefledgedv1alpha2.ImageCacheSpec{
+						CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+							{Images: []string{"foo", "bar"}},
+							{Images: []string{"foo"}, NodeSelector: map[string]string{"pool": "bar"}},
+						},
+					},
+				},
+			},
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"bar"}},
+				{Images: []string{"foo"}, NodeSelector: map[string]string{"pool": "bar"}},
+			},
+			// 2 pulls of bar, 1 pull of foo on node2 and 1 purge of foo on node1
+			expectedWorkItems:       4,
+			expectedMergedWorkItems: 0,
+		},
+		{
+			name:  "#4: Create - Image and artifact with the same reference",
+			wqKey: images.WorkQueueKey{WorkType: images.ImageCacheCreate},
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}, Artifacts: []string{"foo", "bar"}, NodeSelector: map[string]string{"pool": "foo"}},
+			},
+			expectedWorkItems:       3,
+			expectedMergedWorkItems: 0,
+		},
+		{
+			name: "#5: Update - Artifact removed from entry is purged",
+			wqKey: images.WorkQueueKey{
+				WorkType: images.ImageCacheUpdate,
+				OldImageCache: &kubefledgedv1alpha2.ImageCache{
+					Spec: kubefledgedv1alpha2.ImageCacheSpec{
+						CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+							{Images: []string{"foo"}, Artifacts: []string{"bar"}, NodeSelector: map[string]string{"pool": "foo"}},
+						},
+					},
+				},
+			},
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}, NodeSelector: map[string]string{"pool": "foo"}},
+			},
+			// 1 pull of foo and 1 purge of artifact bar on node1
+			expectedWorkItems:       2,
+			expectedMergedWorkItems: 0,
+		},
+		{
+			name: "#6: Update - Image whose platform changed is pulled and not purged",
+			wqKey: images.WorkQueueKey{
+				WorkType: images.ImageCacheUpdate,
+				OldImageCache: &kubefledgedv1alpha2.ImageCache{
+					Spec: kubefledgedv1alpha2.ImageCacheSpec{
+						CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+							{Images: []string{"foo"}, NodeSelector: map[string]string{"pool": "foo"}},
+						},
+					},
+				},
+			},
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}, NodeSelector: map[string]string{"pool": "foo"}, Platforms: map[string]string{"foo": "linux/arm64"}},
+			},
+			expectedWorkItems:       1,
+			expectedPlatform:        "linux/arm64",
+			expectedMergedWorkItems: 0,
+		},
+		{
+			name:  "#7: Create - Warm standby nodes get all images first",
+			wqKey: images.WorkQueueKey{WorkType: images.ImageCacheCreate},
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo", "bar"}, NodeSelector: map[string]string{"pool": "foo"}},
+			},
+			warmStandby:             &kubefledgedv1alpha2.WarmStandby{NodeSelector: map[string]string{"kubernetes.io/os": "linux"}},
+			expectedWorkItems:       4,
+			expectedMergedWorkItems: 0,
+			expectedFirstNode:       "node2",
+		},
+		{
+			name: "#8: Refresh - Image pruned from a catalog is purged",
+			wqKey: images.WorkQueueKey{
+				WorkType: images.ImageCacheRefresh,
+				OldImageCache: &kubefledgedv1alpha2.ImageCache{
+					Spec: kubefledgedv1alpha2.ImageCacheSpec{
+						CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+							{Images: []string{"registry.internal/platform/api:v1.0"}, NodeSelector: map[string]string{"pool": "foo"}},
+						},
+					},
+				},
+			},
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"registry.internal/platform/api:v1.1"}, NodeSelector: map[string]string{"pool": "foo"}},
+			},
+			// 1 pull of v1.1 and 1 purge of v1.0 on node1
+			expectedWorkItems:       2,
+			expectedMergedWorkItems: 0,
+		},
+		{
+			name:  "#9: Refresh - Only the images of due entries are pulled",
+			wqKey: images.WorkQueueKey{WorkType: images.ImageCacheRefresh, Entries: &[]int{1}},
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo", "bar"}},
+				{Images: []string{"foo"}, NodeSelector: map[string]string{"pool": "bar"}},
+			},
+			expectedWorkItems:       1,
+			expectedMergedWorkItems: 0,
+			expectedFirstNode:       "node2",
+			expectedOverlaps: []kubefledgedv1alpha2.ImageCacheOverlap{
+				{Image: "foo", Entries: []int{0, 1}, Nodes: 1},
+			},
+		},
+		{
+			name: "#10: Refresh - Images of an unmatched entry are purged from the relabeled node",
+			wqKey: images.WorkQueueKey{WorkType: images.ImageCacheRefresh, Entries: &[]int{},
+				Nodes: &[]string{"node1"}, UnmatchedEntries: &[]int{0}},
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"bar", "baz"}, NodeSelector: map[string]string{"pool": "bar"}},
+				{Images: []string{"foo", "bar"}, NodeSelector: map[string]string{"pool": "foo"}},
+			},
+			// 1 purge of baz on node1, bar being still cached by the second entry
+			expectedWorkItems:       1,
+			expectedMergedWorkItems: 0,
+			expectedFirstNode:       "node1",
+		},
+		{
+			name: "#11: Refresh - Images of a matched entry are pulled to the relabeled node only",
+			wqKey: images.WorkQueueKey{WorkType: images.ImageCacheRefresh, Entries: &[]int{1},
+				Nodes: &[]string{"node1"}, UnmatchedEntries: &[]int{}},
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"bar"}},
+				{Images: []string{"foo", "baz"}, NodeSelector: map[string]string{"pool": "foo"}},
+			},
+			// 2 pulls of foo and baz on node1
+			expectedWorkItems:       2,
+			expectedMergedWorkItems: 0,
+			expectedFirstNode:       "node1",
+		},
+		{
+			name:  "#12: Create - Fallback references of an image",
+			wqKey: images.WorkQueueKey{WorkType: images.ImageCacheCreate},
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"registry.internal/foo"}, NodeSelector: map[string]string{"pool": "foo"},
+					Fallbacks: map[string][]string{"registry.internal/foo": {"mirror.internal/foo", "foo"}}},
+			},
+			expectedWorkItems:       1,
+			expectedMergedWorkItems: 0,
+			expectedFallbacks:       []string{"mirror.internal/foo", "foo"},
+		},
+		{
+			name: "#13: Update - Image whose target reference changed is pulled and its old target purged",
+			wqKey: images.WorkQueueKey{
+				WorkType: images.ImageCacheUpdate,
+				OldImageCache: &kubefledgedv1alpha2.ImageCache{
+					Spec: kubefledgedv1alpha2.ImageCacheSpec{
+						CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+							{Images: []string{"mirror.internal/foo"}, NodeSelector: map[string]string{"pool": "foo"},
+								TargetRefs: map[string]string{"mirror.internal/foo": "foo"}},
+						},
+					},
+				},
+			},
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"mirror.internal/foo"}, NodeSelector: map[string]string{"pool": "foo"},
+					TargetRefs: map[string]string{"mirror.internal/foo": "docker.io/library/foo"}},
+			},
+			// 1 pull of mirror.internal/foo tagged as docker.io/library/foo and 1 purge of foo on node1
+			expectedWorkItems:       2,
+			expectedMergedWorkItems: 0,
+			expectedTargetRef:       "docker.io/library/foo",
+		},
+	}
+
+	for _, test := range tests {
+		fakekubeclientset := &fakeclientset.Clientset{}
+		fakefledgedclientset := &kubefledgedclientsetfake.Clientset{}
+		controller, nodeInformer, _ := newTestController(fakekubeclientset, fakefledgedclientset)
+		for i := range nodeList {
+			nodeInformer.Informer().GetIndexer().Add(&nodeList[i])
+		}
+		workItems, plan, err := controller.planImageWork(test.wqKey, test.cacheSpec, test.warmStandby)
+		if err != nil {
+			t.Errorf("Test: %s failed. expectedError=nil, actualError=%s", test.name, err.Error())
+			continue
+		}
+		if len(workItems) != test.expectedWorkItems || plan.WorkItems != test.expectedWorkItems {
+			t.Errorf("Test: %s failed. expectedWorkItems=%d, actualWorkItems=%d", test.name, test.expectedWorkItems, len(workItems))
+		}
+		if plan.MergedWorkItems != test.expectedMergedWorkItems {
+			t.Errorf("Test: %s failed. expectedMergedWorkItems=%d, actualMergedWorkItems=%d", test.name, test.expectedMergedWorkItems, plan.MergedWorkItems)
+		}
+		if !reflect.DeepEqual(plan.Overlaps, test.expectedOverlaps) {
+			t.Errorf("Test: %s failed. expectedOverlaps=%+v, actualOverlaps=%+v", test.name, test.expectedOverlaps, plan.Overlaps)
+		}
+		if test.expectedPlatform != "" && workItems[0].platform != test.expectedPlatform {
+			t.Errorf("Test: %s failed. expectedPlatform=%s, actualPlatform=%s", test.name, test.expectedPlatform, workItems[0].platform)
+		}
+		if test.expectedFallbacks != nil && !reflect.DeepEqual(workItems[0].fallbacks, test.expectedFallbacks) {
+			t.Errorf("Test: %s failed. expectedFallbacks=%v, actualFallbacks=%v", test.name, test.expectedFallbacks, workItems[0].fallbacks)
+		}
+		if test.expectedTargetRef != "" && workItems[0].targetRef != test.expectedTargetRef {
+			t.Errorf("Test: %s failed. expectedTargetRef=%s, actualTargetRef=%s", test.name, test.expectedTargetRef, workItems[0].targetRef)
+		}
+		if test.expectedFirstNode != "" && workItems[0].node.Name != test.expectedFirstNode {
+			t.Errorf("Test: %s failed. expectedFirstNode=%s, actualFirstNode=%s", test.name, test.expectedFirstNode, workItems[0].node.Name)
+		}
+	}
+	t.Logf("%d tests passed", len(tests))
+}
+
 func TestEnqueueImageCache(t *testing.T) {
 	//now := metav1.Now()
 	//nowplus5s := metav1.NewTime(time.Now().Add(time.Second * 5))
@@ -1059,6 +1733,43 @@ func TestEnqueueImageCache(t *testing.T) {
 			},
 			expectedResult: true,
 		},
+		{
+			name:     "#11: Update - Imagecache cancel while processing. Successful queueing",
+			workType: images.ImageCacheUpdate,
+			oldImageCache: kubefledgedv1alpha2.ImageCache{
+				ObjectMeta: defaultImageCache.ObjectMeta,
+				Spec:       defaultImageCache.Spec,
+				Status: kubefledgedv1alpha2.ImageCacheStatus{
+					Status: kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+				},
+			},
+			newImageCache: kubefledgedv1alpha2.ImageCache{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "foo",
+					Namespace:   "kube-fledged",
+					Annotations: map[string]string{imageCacheCancelAnnotationKey: ""},
+				},
+				Spec: defaultImageCache.Spec,
+				Status: kubefledgedv1alpha2.ImageCacheStatus{
+					Status: kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+				},
+			},
+			expectedResult: true,
+		},
+		{
+			name:          "#12: Update - Imagecache dry run. Successful queueing",
+			workType:      images.ImageCacheUpdate,
+			oldImageCache: defaultImageCache,
+			newImageCache: kubefledgedv1alpha2.ImageCache{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "foo",
+					Namespace:   "kube-fledged",
+					Annotations: map[string]string{imageCacheDryRunAnnotationKey: ""},
+				},
+				Spec: defaultImageCache.Spec,
+			},
+			expectedResult: true,
+		},
 	}
 
 	for _, test := range tests {
@@ -1188,3 +1899,87 @@ func TestProcessNextWorkItem(t *testing.T) {
 	}
 	t.Logf("%d tests passed", len(tests))
 }
+
+// fakeEventSink sends the events recorded to it on a channel
+type fakeEventSink struct {
+	events chan *corev1.Event
+}
+
+func (s *fakeEventSink) Create(event *corev1.Event) (*corev1.Event, error) {
+	s.events <- event
+	return event, nil
+}
+
+func (s *fakeEventSink) Update(event *corev1.Event) (*corev1.Event, error) {
+	s.events <- event
+	return event, nil
+}
+
+func (s *fakeEventSink) Patch(event *corev1.Event, data []byte) (*corev1.Event, error) {
+	s.events <- event
+	return event, nil
+}
+
+func TestNewEventRecorder(t *testing.T) {
+	tests := []struct {
+		name              string
+		componentName     string
+		sinkNamespace     string
+		disabled          bool
+		expectEvent       bool
+		expectedComponent string
+	}{
+		{
+			name:              "#1: Custom sink - default component name",
+			componentName:     "",
+			expectEvent:       true,
+			expectedComponent: "kubefledged-controller",
+		},
+		{
+			name:              "#2: Custom sink - custom component name",
+			componentName:     "fledged",
+			expectEvent:       true,
+			expectedComponent: "fledged",
+		},
+		{
+			name:          "#3: Event recording disabled",
+			componentName: "",
+			disabled:      true,
+			expectEvent:   false,
+		},
+		{
+			name:              "#4: Event of the sink namespace",
+			sinkNamespace:     fledgedNameSpace,
+			expectEvent:       true,
+			expectedComponent: "kubefledged-controller",
+		},
+		{
+			name:          "#5: Event of another namespace dropped",
+			sinkNamespace: "other",
+			expectEvent:   false,
+		},
+	}
+	imageCache := &kubefledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: fledgedNameSpace,
+		},
+	}
+	for _, test := range tests {
+		sink := &fakeEventSink{events: make(chan *corev1.Event, 1)}
+		recorder := newEventRecorder(&fakeclientset.Clientset{}, test.componentName, test.sinkNamespace, test.disabled, sink)
+		recorder.Event(imageCache, corev1.EventTypeNormal, "fakereason", "fakemessage")
+		select {
+		case event := <-sink.events:
+			if !test.expectEvent {
+				t.Errorf("Test: %s failed: expected no event, actualEvent=%+v", test.name, event)
+			} else if event.Source.Component != test.expectedComponent {
+				t.Errorf("Test: %s failed: expectedComponent=%s, actualComponent=%s", test.name, test.expectedComponent, event.Source.Component)
+			}
+		case <-time.After(time.Millisecond * 500):
+			if test.expectEvent {
+				t.Errorf("Test: %s failed: expected event not recorded", test.name)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	"github.com/senthilrch/kube-fledged/pkg/policy"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// defaultCostRateKey is the key of the cost rate of the registries without a rate
+const defaultCostRateKey = "*"
+
+// ParseCostRates parses egress cost rates per GB, given as comma separated
+// registry=rate pairs e.g. "docker.io=0.09,ghcr.io=0,*=0.05". The rate of "*" applies
+// to the registries without a rate.
+func ParseCostRates(s string) (map[string]float64, error) {
+	rates := map[string]float64{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		registry, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid cost rate %q: expected registry=rate", pair)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("invalid cost rate %q: rate must be a positive number", pair)
+		}
+		rates[strings.TrimSpace(registry)] = rate
+	}
+	return rates, nil
+}
+
+// costRate returns the egress cost rate per GB of the registry of an image
+func (c *Controller) costRate(image string) float64 {
+	if rate, ok := c.egressCostRates[imageRegistry(image)]; ok {
+		return rate
+	}
+	return c.egressCostRates[defaultCostRateKey]
+}
+
+// estimateCost fills the plan with the estimated egress cost of the image pulls of the
+// image cache. Image sizes are taken from the registries, cached by the metadata worker,
+// or else from the nodes holding the images.
+func (c *Controller) estimateCost(imageCache *v1alpha2.ImageCache, workItems []imageWorkItem, plan *v1alpha2.ImageCachePlan) float64 {
+	keychain := c.keychainOf(imageCache)
+	sizes := map[string]int64{}
+	var cost float64
+	for _, w := range workItems {
+		if w.workType == images.ImageCachePurge {
+			continue
+		}
+		platform := nodePlatform(w.platform, w.node)
+		key := w.image + "@" + platform
+		size, ok := sizes[key]
+		if !ok {
+			size = c.cachedImageSize(w.image, platform, w.node.Name, keychain)
+			sizes[key] = size
+		}
+		cost += float64(size) / 1e9 * c.costRate(w.image)
+	}
+	plan.EstimatedCost = strconv.FormatFloat(cost, 'f', 2, 64)
+	return cost
+}
+
+// costBudget returns the lowest of the cost budgets of the image cache and of its
+// namespace, or nil if there is no budget
+func costBudget(imageCache *v1alpha2.ImageCache, policies []*v1alpha2.FledgedPolicy) *resource.Quantity {
+	budget := imageCache.Spec.CostBudget
+	if b := policy.CostBudget(imageCache.Namespace, policies); b != nil && (budget == nil || b.Cmp(*budget) < 0) {
+		budget = b
+	}
+	return budget
+}
+
+// budgetExceeded fills the plan with the estimated egress cost of the image pulls, and
+// returns the cost budget it exceeds, if any
+func (c *Controller) budgetExceeded(imageCache *v1alpha2.ImageCache, workItems []imageWorkItem,
+	plan *v1alpha2.ImageCachePlan, policies []*v1alpha2.FledgedPolicy) []string {
+	cost := c.estimateCost(imageCache, workItems, plan)
+	budget := costBudget(imageCache, policies)
+	if budget == nil || cost <= budget.AsApproximateFloat64() {
+		return nil
+	}
+	return []string{fmt.Sprintf("an estimated cost of %s, more than the budget of %s", plan.EstimatedCost, budget.String())}
+}
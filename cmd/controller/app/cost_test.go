@@ -0,0 +1,131 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseCostRates(t *testing.T) {
+	tests := []struct {
+		name          string
+		rates         string
+		expectedRates map[string]float64
+		expectErr     bool
+	}{
+		{
+			name:          "#1: Rates of registries and default rate",
+			rates:         "docker.io=0.09, ghcr.io=0,*=0.05",
+			expectedRates: map[string]float64{"docker.io": 0.09, "ghcr.io": 0, "*": 0.05},
+		},
+		{
+			name:      "#2: Missing rate",
+			rates:     "docker.io",
+			expectErr: true,
+		},
+		{
+			name:      "#3: Negative rate",
+			rates:     "docker.io=-1",
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		rates, err := ParseCostRates(test.rates)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("Test: %s failed: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(rates, test.expectedRates) {
+			t.Errorf("Test: %s failed: expected=%v, actual=%v, err=%v", test.name, test.expectedRates, rates, err)
+		}
+	}
+}
+
+func TestBudgetExceeded(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	node.Status.NodeInfo = corev1.NodeSystemInfo{OperatingSystem: "linux", Architecture: "amd64"}
+	workItems := []imageWorkItem{
+		{image: "nginx:1.23", node: node, workType: images.ImageCacheCreate},
+		{image: "nginx:1.23", platform: "linux/arm64", node: node, workType: images.ImageCacheCreate},
+		{image: "ghcr.io/foo/bar:v1", node: node, workType: images.ImageCacheCreate},
+		{image: "redis:7", node: node, workType: images.ImageCachePurge},
+	}
+	budget := func(s string) *resource.Quantity {
+		q := resource.MustParse(s)
+		return &q
+	}
+	tests := []struct {
+		name             string
+		costBudget       *resource.Quantity
+		policies         []*kubefledgedv1alpha2.FledgedPolicy
+		expectedExceeded bool
+	}{
+		{
+			name: "#1: No budget",
+		},
+		{
+			name:       "#2: Within the budget of the image cache",
+			costBudget: budget("1"),
+		},
+		{
+			name:             "#3: Beyond the budget of the image cache",
+			costBudget:       budget("0.5"),
+			expectedExceeded: true,
+		},
+		{
+			name:       "#4: Beyond the budget of the namespace",
+			costBudget: budget("1"),
+			policies: []*kubefledgedv1alpha2.FledgedPolicy{{
+				Spec: kubefledgedv1alpha2.FledgedPolicySpec{
+					CostBudgets: []kubefledgedv1alpha2.NamespaceCostBudget{{Namespace: fledgedNameSpace, Budget: resource.MustParse("0.5")}},
+				},
+			}},
+			expectedExceeded: true,
+		},
+	}
+	controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	controller.imageSizer = fakeImageSizer{"nginx:1.23@linux/amd64": 2e9, "nginx:1.23@linux/arm64": 1e9, "ghcr.io/foo/bar:v1@linux/amd64": 5e9}
+	controller.egressCostRates = map[string]float64{"ghcr.io": 0, "*": 0.25}
+	controller.queueImageMetadata(&kubefledgedv1alpha2.ImageCache{}, workItems, true, false)
+	readImageMetadata(controller)
+	for _, test := range tests {
+		imageCache := &kubefledgedv1alpha2.ImageCache{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+			Spec:       kubefledgedv1alpha2.ImageCacheSpec{CostBudget: test.costBudget},
+		}
+		plan := &kubefledgedv1alpha2.ImageCachePlan{}
+		exceeded := controller.budgetExceeded(imageCache, workItems, plan, test.policies)
+		if (len(exceeded) > 0) != test.expectedExceeded {
+			t.Errorf("Test: %s failed: expected exceeded=%t, actual=%v", test.name, test.expectedExceeded, exceeded)
+		}
+		// 3 GB pulled from docker.io at 0.25 per GB, 5 GB pulled from ghcr.io for free
+		if plan.EstimatedCost != "0.75" {
+			t.Errorf("Test: %s failed: expected estimated cost 0.75, actual=%s", test.name, plan.EstimatedCost)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// syncHistoryLength is the number of finished image cache actions kept for the dashboard
+const syncHistoryLength = 50
+
+// syncRecord is a finished image cache action (create, update, refresh or purge)
+type syncRecord struct {
+	ImageCache     string                          `json:"imageCache"`
+	Reason         string                          `json:"reason"`
+	Status         v1alpha2.ImageCacheActionStatus `json:"status"`
+	StartTime      time.Time                       `json:"startTime"`
+	CompletionTime time.Time                       `json:"completionTime"`
+	Failures       int                             `json:"failures"`
+}
+
+// syncHistory keeps the most recent finished image cache actions, newest first
+type syncHistory struct {
+	lock    sync.RWMutex
+	records []syncRecord
+	length  int
+}
+
+func newSyncHistory(length int) *syncHistory {
+	return &syncHistory{length: length}
+}
+
+func (h *syncHistory) add(r syncRecord) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.records = append([]syncRecord{r}, h.records...)
+	if len(h.records) > h.length {
+		h.records = h.records[:h.length]
+	}
+}
+
+func (h *syncHistory) list() []syncRecord {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return append([]syncRecord{}, h.records...)
+}
+
+// dashboardState is the state of the image caches shown by the dashboard
+type dashboardState struct {
+	ImageCaches []imageCacheState `json:"imageCaches"`
+	Failures    []failureState    `json:"failures"`
+	History     []syncRecord      `json:"history"`
+}
+
+// imageCacheState is the status of an image cache, and the coverage of its images on
+// the nodes targeted by its cache spec
+type imageCacheState struct {
+	Namespace string                          `json:"namespace"`
+	Name      string                          `json:"name"`
+	Status    v1alpha2.ImageCacheActionStatus `json:"status"`
+	Reason    string                          `json:"reason"`
+	Message   string                          `json:"message"`
+	Progress  *v1alpha2.ImageCacheProgress    `json:"progress,omitempty"`
+	Images    []string                        `json:"images"`
+	Nodes     []nodeCoverage                  `json:"nodes"`
+	Cached    int                             `json:"cached"`
+	Targeted  int                             `json:"targeted"`
+}
+
+// nodeCoverage is the state of each image of an image cache on a node
+type nodeCoverage struct {
+	Node   string   `json:"node"`
+	Images []string `json:"images"`
+}
+
+// failureState is a failed image pull or delete reported in the status of an image cache
+type failureState struct {
+	ImageCache string `json:"imageCache"`
+	Image      string `json:"image"`
+	Node       string `json:"node"`
+	Reason     string `json:"reason"`
+	Message    string `json:"message"`
+}
+
+// Coverage states of an image on a node
+const (
+	coverageCached      = "cached"
+	coverageFailed      = "failed"
+	coverageMissing     = "missing"
+	coverageNotTargeted = ""
+)
+
+// StartDashboard serves a read-only web dashboard of the image caches on addr, until
+// stopCh is closed. The dashboard is served at "/" and its data at "/api/state". The
+// cache manifest of the image caches is served at "/api/manifest", their drift from
+// the images held by the nodes at "/api/diff", and the build information of the
+// controller at "/version".
+func (c *Controller) StartDashboard(addr string, stopCh <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", c.serveDashboard)
+	mux.HandleFunc("/api/state", c.serveDashboardState)
+	mux.HandleFunc("/api/manifest", c.serveCacheManifest)
+	mux.HandleFunc("/api/diff", c.serveImageCacheDiff)
+	mux.HandleFunc("/version", serveVersion)
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-stopCh
+		server.Shutdown(context.Background())
+	}()
+	glog.Infof("Dashboard listening on %s", addr)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func (c *Controller) serveDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	state, err := c.dashboardState()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(w, state); err != nil {
+		glog.Errorf("Error rendering dashboard: %v", err)
+	}
+}
+
+func (c *Controller) serveDashboardState(w http.ResponseWriter, r *http.Request) {
+	state, err := c.dashboardState()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// dashboardState builds the state of the image caches from the informer caches
+func (c *Controller) dashboardState() (*dashboardState, error) {
+	imageCaches, err := c.imageCachesLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(imageCaches, func(i, j int) bool {
+		if imageCaches[i].Namespace != imageCaches[j].Namespace {
+			return imageCaches[i].Namespace < imageCaches[j].Namespace
+		}
+		return imageCaches[i].Name < imageCaches[j].Name
+	})
+
+	state := &dashboardState{ImageCaches: []imageCacheState{}, Failures: []failureState{}, History: c.history.list()}
+	for _, imageCache := range imageCaches {
+		ics := imageCacheState{
+			Namespace: imageCache.Namespace,
+			Name:      imageCache.Name,
+			Status:    imageCache.Status.Status,
+			Reason:    imageCache.Status.Reason,
+			Message:   imageCache.Status.Message,
+			Progress:  imageCache.Status.Progress,
+		}
+		// targets maps each node to the images targeted on it
+		targets := map[string]map[string]bool{}
+		nodes := map[string]*corev1.Node{}
+		for _, cacheSpec := range imageListsOf(imageCache) {
+			specNodes, err := c.nodesForCacheSpec(cacheSpec)
+			if err != nil {
+				return nil, err
+			}
+			for _, image := range cacheSpec.Images {
+				if !containsString(ics.Images, image) {
+					ics.Images = append(ics.Images, image)
+				}
+				for _, n := range specNodes {
+					if targets[n.Name] == nil {
+						targets[n.Name] = map[string]bool{}
+						nodes[n.Name] = n
+					}
+					targets[n.Name][image] = true
+				}
+			}
+		}
+		failed := map[string]bool{}
+		for image, failures := range imageCache.Status.Failures {
+			for _, f := range failures {
+				failed[image+"|"+f.Node] = true
+				state.Failures = append(state.Failures, failureState{
+					ImageCache: imageCache.Namespace + "/" + imageCache.Name,
+					Image:      image,
+					Node:       f.Node,
+					Reason:     f.Reason,
+					Message:    f.Message,
+				})
+			}
+		}
+		nodeNames := []string{}
+		for name := range nodes {
+			nodeNames = append(nodeNames, name)
+		}
+		sort.Strings(nodeNames)
+		for _, name := range nodeNames {
+			coverage := nodeCoverage{Node: name}
+			for _, image := range ics.Images {
+				cell := coverageNotTargeted
+				if targets[name][image] {
+					ics.Targeted++
+					switch {
+					case imagePresentOnNode(image, nodes[name]):
+						cell = coverageCached
+						ics.Cached++
+					case failed[image+"|"+nodes[name].Labels["kubernetes.io/hostname"]]:
+						cell = coverageFailed
+					default:
+						cell = coverageMissing
+					}
+				}
+				coverage.Images = append(coverage.Images, cell)
+			}
+			ics.Nodes = append(ics.Nodes, coverage)
+		}
+		state.ImageCaches = append(state.ImageCaches, ics)
+	}
+	return state, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="30">
+<title>kube-fledged</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; font-size: 0.9em; }
+td.cached { background: #4caf50; }
+td.failed { background: #f44336; }
+td.missing { background: #ffc107; }
+</style>
+</head>
+<body>
+<h1>kube-fledged image caches</h1>
+{{range .ImageCaches}}
+<h2>{{.Namespace}}/{{.Name}}</h2>
+<p>Status: <b>{{.Status}}</b> ({{.Reason}}) {{.Message}}<br>
+Cached: {{.Cached}}/{{.Targeted}}{{with .Progress}} &middot; Progress: {{.Completed}} completed, {{.Failed}} failed of {{.Total}}{{end}}</p>
+<table>
+<tr><th>Node</th>{{range .Images}}<th>{{.}}</th>{{end}}</tr>
+{{range .Nodes}}<tr><td>{{.Node}}</td>{{range .Images}}<td class="{{.}}">{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{else}}
+<p>No image caches.</p>
+{{end}}
+<h2>Recent failures</h2>
+<table>
+<tr><th>Image cache</th><th>Image</th><th>Node</th><th>Reason</th><th>Message</th></tr>
+{{range .Failures}}<tr><td>{{.ImageCache}}</td><td>{{.Image}}</td><td>{{.Node}}</td><td>{{.Reason}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>
+<h2>Refresh history</h2>
+<table>
+<tr><th>Image cache</th><th>Action</th><th>Status</th><th>Started</th><th>Completed</th><th>Failures</th></tr>
+{{range .History}}<tr><td>{{.ImageCache}}</td><td>{{.Reason}}</td><td>{{.Status}}</td><td>{{.StartTime.Format "2006-01-02 15:04:05"}}</td><td>{{.CompletionTime.Format "2006-01-02 15:04:05"}}</td><td>{{.Failures}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,117 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDashboardState(t *testing.T) {
+	nodes := []corev1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "node1",
+				Labels: map[string]string{"kubernetes.io/hostname": "node1", "kubernetes.io/os": "linux"},
+			},
+			Status: corev1.NodeStatus{
+				Images: []corev1.ContainerImage{{Names: []string{"docker.io/library/foo:v1"}}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "node2",
+				Labels: map[string]string{"kubernetes.io/hostname": "node2", "kubernetes.io/os": "linux"},
+			},
+		},
+	}
+	imageCache := kubefledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: fledgedNameSpace,
+		},
+		Spec: kubefledgedv1alpha2.ImageCacheSpec{
+			CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo:v1", "bar:v1"}},
+			},
+		},
+		Status: kubefledgedv1alpha2.ImageCacheStatus{
+			Status: kubefledgedv1alpha2.ImageCacheActionStatusFailed,
+			Failures: map[string]kubefledgedv1alpha2.NodeReasonMessageList{
+				"bar:v1": {{Node: "node2", Reason: "ErrImagePull", Message: "not found"}},
+			},
+		},
+	}
+
+	controller, nodeInformer, imagecacheInformer := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	for i := range nodes {
+		nodeInformer.Informer().GetIndexer().Add(&nodes[i])
+	}
+	imagecacheInformer.Informer().GetIndexer().Add(&imageCache)
+	controller.recordSync(&imageCache, &imageCache.Status)
+
+	state, err := controller.dashboardState()
+	if err != nil {
+		t.Fatalf("dashboardState() failed: %v", err)
+	}
+	if len(state.ImageCaches) != 1 {
+		t.Fatalf("expected 1 image cache, got %d", len(state.ImageCaches))
+	}
+	ics := state.ImageCaches[0]
+	expectedNodes := []nodeCoverage{
+		{Node: "node1", Images: []string{coverageCached, coverageMissing}},
+		{Node: "node2", Images: []string{coverageMissing, coverageFailed}},
+	}
+	if !reflect.DeepEqual(ics.Nodes, expectedNodes) {
+		t.Errorf("expected coverage %+v, got %+v", expectedNodes, ics.Nodes)
+	}
+	if ics.Cached != 1 || ics.Targeted != 4 {
+		t.Errorf("expected 1/4 images cached, got %d/%d", ics.Cached, ics.Targeted)
+	}
+	if len(state.Failures) != 1 || state.Failures[0].Node != "node2" {
+		t.Errorf("expected 1 failure on node2, got %+v", state.Failures)
+	}
+	if len(state.History) != 1 || state.History[0].Failures != 1 {
+		t.Errorf("expected 1 history record with 1 failure, got %+v", state.History)
+	}
+
+	rec := httptest.NewRecorder()
+	controller.serveDashboard(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "kube-fledged/foo") {
+		t.Errorf("expected dashboard to list image cache kube-fledged/foo, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestSyncHistory(t *testing.T) {
+	h := newSyncHistory(2)
+	for _, name := range []string{"a", "b", "c"} {
+		h.add(syncRecord{ImageCache: name})
+	}
+	records := h.list()
+	if len(records) != 2 || records[0].ImageCache != "c" || records[1].ImageCache != "b" {
+		t.Errorf("expected the 2 most recent records newest first, got %+v", records)
+	}
+}
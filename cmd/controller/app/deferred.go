@@ -0,0 +1,167 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"time"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// deferredCheckInterval is the interval at which the nodes with deferred image
+	// pulls are checked for reconnection
+	deferredCheckInterval = 30 * time.Second
+	// deferredBackoffBase and deferredBackoffMax bound the exponential backoff between
+	// retries of the deferred image pulls of a node
+	deferredBackoffBase = 30 * time.Second
+	deferredBackoffMax  = 30 * time.Minute
+)
+
+// runDeferredWorker refreshes the image caches having image pulls deferred to nodes
+// that reconnected, or deferred while the circuit of their registry was open or their
+// node was overloaded, once their backoff elapsed
+func (c *Controller) runDeferredWorker() {
+	imageCaches, err := c.imageCachesLister.ImageCaches("").List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error in listing image caches: %v", err)
+		return
+	}
+	now := time.Now()
+	for _, imageCache := range imageCaches {
+		if len(imageCache.Status.Deferred) == 0 || !canRefresh(imageCache) {
+			continue
+		}
+		for _, d := range imageCache.Status.Deferred {
+			node, err := c.nodesLister.Get(d.Node)
+			if err != nil || !nodeOnline(node) || (d.NextAttempt != nil && now.Before(d.NextAttempt.Time)) {
+				continue
+			}
+			glog.Infof("Node %s online: refreshing image cache %s/%s to complete deferred image pulls",
+				d.Node, imageCache.Namespace, imageCache.Name)
+			c.enqueueBackgroundRefresh(imageCache)
+			break
+		}
+	}
+}
+
+// deferOfflineWork separates the work items of offline nodes from the others. The
+// work items of offline nodes are added to the deferred nodes, whose previous
+// attempts are taken from the deferred nodes of the last action.
+func deferOfflineWork(workItems []imageWorkItem, previous []v1alpha2.DeferredNode, now time.Time) ([]imageWorkItem, []v1alpha2.DeferredNode) {
+	online := []imageWorkItem{}
+	var deferred []v1alpha2.DeferredNode
+	for _, w := range workItems {
+		if nodeOnline(w.node) {
+			online = append(online, w)
+			continue
+		}
+		deferred = deferImage(deferred, previous, w.node.Name, w.image, now)
+	}
+	return online, deferred
+}
+
+// deferImage adds an image to be pulled to a node to the deferred nodes. A node
+// deferred for the first time in this action gets one more attempt than in the
+// previous action, and its next attempt is backed off accordingly.
+func deferImage(deferred, previous []v1alpha2.DeferredNode, node, image string, now time.Time) []v1alpha2.DeferredNode {
+	for i := range deferred {
+		if deferred[i].Node == node {
+			if !containsString(deferred[i].Images, image) {
+				deferred[i].Images = append(deferred[i].Images, image)
+			}
+			return deferred
+		}
+	}
+	attempts := 1
+	for _, p := range previous {
+		if p.Node == node {
+			attempts = p.Attempts + 1
+			break
+		}
+	}
+	nextAttempt := metav1.NewTime(now.Add(deferredBackoff(attempts)))
+	return append(deferred, v1alpha2.DeferredNode{Node: node, Images: []string{image}, Attempts: attempts, NextAttempt: &nextAttempt})
+}
+
+// deferredNode returns true if image pulls to the node are deferred
+func deferredNode(deferred []v1alpha2.DeferredNode, node string) bool {
+	for _, d := range deferred {
+		if d.Node == node {
+			return true
+		}
+	}
+	return false
+}
+
+// deferUntil postpones the next attempt of the deferred image pulls of a node to the
+// given time, e.g. the end of the cool-down period of the circuit of a registry, if it
+// is later than the next attempt
+func deferUntil(deferred []v1alpha2.DeferredNode, node string, t time.Time) []v1alpha2.DeferredNode {
+	for i := range deferred {
+		if deferred[i].Node == node && (deferred[i].NextAttempt == nil || deferred[i].NextAttempt.Time.Before(t)) {
+			nextAttempt := metav1.NewTime(t)
+			deferred[i].NextAttempt = &nextAttempt
+		}
+	}
+	return deferred
+}
+
+// deferredBackoff returns the delay before the next attempt of deferred image pulls
+func deferredBackoff(attempts int) time.Duration {
+	backoff := deferredBackoffBase
+	for i := 1; i < attempts && backoff < deferredBackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > deferredBackoffMax {
+		backoff = deferredBackoffMax
+	}
+	return backoff
+}
+
+// nodeOnline returns true if the kubelet of the node reports it ready. Unlike
+// nodeReady, cordoned nodes are online.
+func nodeOnline(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// deferFailedWork returns true if the failed work result should be deferred rather
+// than reported as a failure, because its node went offline
+func (c *Controller) deferFailedWork(result images.ImageWorkResult) bool {
+	if !c.deferOfflineNodes || result.ImageWorkRequest.Node == nil || result.ImageWorkRequest.WorkType == images.ImageCachePurge {
+		return false
+	}
+	if result.Status != images.ImageWorkResultStatusFailed && result.Status != images.ImageWorkResultStatusUnknown {
+		return false
+	}
+	node, err := c.nodesLister.Get(result.ImageWorkRequest.Node.Name)
+	if err != nil {
+		// Removed nodes never reconnect
+		return false
+	}
+	return !nodeOnline(node)
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"testing"
+	"time"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDeferOfflineWork(t *testing.T) {
+	now := time.Now()
+	online := newWarmStandbyTestNode("node1", true)
+	offline := newWarmStandbyTestNode("node2", false)
+	tests := []struct {
+		name             string
+		previous         []kubefledgedv1alpha2.DeferredNode
+		expectedAttempts int
+		expectedBackoff  time.Duration
+	}{
+		{
+			name:             "#1: Node deferred for the first time",
+			expectedAttempts: 1,
+			expectedBackoff:  deferredBackoffBase,
+		},
+		{
+			name:             "#2: Node deferred again",
+			previous:         []kubefledgedv1alpha2.DeferredNode{{Node: "node2", Images: []string{"nginx:1.23"}, Attempts: 2}},
+			expectedAttempts: 3,
+			expectedBackoff:  4 * deferredBackoffBase,
+		},
+		{
+			name:             "#3: Backoff capped",
+			previous:         []kubefledgedv1alpha2.DeferredNode{{Node: "node2", Images: []string{"nginx:1.23"}, Attempts: 20}},
+			expectedAttempts: 21,
+			expectedBackoff:  deferredBackoffMax,
+		},
+	}
+	for _, test := range tests {
+		workItems := []imageWorkItem{
+			{image: "nginx:1.23", node: online},
+			{image: "nginx:1.23", node: offline},
+			{image: "redis:7", node: offline},
+		}
+		actual, deferred := deferOfflineWork(workItems, test.previous, now)
+		if len(actual) != 1 || actual[0].node.Name != "node1" {
+			t.Errorf("Test: %s failed: expected the work item of node1 only, actual=%+v", test.name, actual)
+		}
+		if len(deferred) != 1 {
+			t.Errorf("Test: %s failed: expected 1 deferred node, actual=%d", test.name, len(deferred))
+			continue
+		}
+		d := deferred[0]
+		if d.Node != "node2" || len(d.Images) != 2 || d.Attempts != test.expectedAttempts {
+			t.Errorf("Test: %s failed: expected node2 with 2 images and %d attempts, actual=%+v", test.name, test.expectedAttempts, d)
+		}
+		if d.NextAttempt == nil || !d.NextAttempt.Time.Equal(now.Add(test.expectedBackoff)) {
+			t.Errorf("Test: %s failed: expected next attempt after %v, actual=%v", test.name, test.expectedBackoff, d.NextAttempt)
+		}
+	}
+}
+
+func TestDeferFailedWork(t *testing.T) {
+	tests := []struct {
+		name              string
+		deferOfflineNodes bool
+		node              string
+		workType          images.WorkType
+		status            string
+		expected          bool
+	}{
+		{
+			name:              "#1: Pull to offline node failed",
+			deferOfflineNodes: true,
+			node:              "node2",
+			workType:          images.ImageCacheCreate,
+			status:            images.ImageWorkResultStatusFailed,
+			expected:          true,
+		},
+		{
+			name:     "#2: Deferral disabled",
+			node:     "node2",
+			workType: images.ImageCacheCreate,
+			status:   images.ImageWorkResultStatusFailed,
+		},
+		{
+			name:              "#3: Pull to online node failed",
+			deferOfflineNodes: true,
+			node:              "node1",
+			workType:          images.ImageCacheCreate,
+			status:            images.ImageWorkResultStatusFailed,
+		},
+		{
+			name:              "#4: Purge never deferred",
+			deferOfflineNodes: true,
+			node:              "node2",
+			workType:          images.ImageCachePurge,
+			status:            images.ImageWorkResultStatusFailed,
+		},
+		{
+			name:              "#5: Removed node",
+			deferOfflineNodes: true,
+			node:              "node3",
+			workType:          images.ImageCacheUpdate,
+			status:            images.ImageWorkResultStatusUnknown,
+		},
+		{
+			name:              "#6: Pull succeeded",
+			deferOfflineNodes: true,
+			node:              "node2",
+			workType:          images.ImageCacheRefresh,
+			status:            images.ImageWorkResultStatusSucceeded,
+		},
+	}
+	controller, nodeInformer, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	nodeInformer.Informer().GetIndexer().Add(newWarmStandbyTestNode("node1", true))
+	nodeInformer.Informer().GetIndexer().Add(newWarmStandbyTestNode("node2", false))
+	for _, test := range tests {
+		controller.deferOfflineNodes = test.deferOfflineNodes
+		result := images.ImageWorkResult{
+			ImageWorkRequest: images.ImageWorkRequest{
+				Image:    "nginx:1.23",
+				Node:     &corev1.Node{},
+				WorkType: test.workType,
+			},
+			Status: test.status,
+		}
+		result.ImageWorkRequest.Node.Name = test.node
+		if actual := controller.deferFailedWork(result); actual != test.expected {
+			t.Errorf("Test: %s failed: expected=%t, actual=%t", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestDeferUntil(t *testing.T) {
+	now := time.Now()
+	soon := metav1.NewTime(now.Add(time.Minute))
+	later := metav1.NewTime(now.Add(time.Hour))
+	deferred := []kubefledgedv1alpha2.DeferredNode{
+		{Node: "node1", Images: []string{"nginx:1.23"}, Attempts: 1, NextAttempt: &soon},
+		{Node: "node2", Images: []string{"nginx:1.23"}, Attempts: 1, NextAttempt: &later},
+	}
+	deferred = deferUntil(deferred, "node1", now.Add(time.Minute*10))
+	deferred = deferUntil(deferred, "node2", now.Add(time.Minute*10))
+	if !deferred[0].NextAttempt.Time.Equal(now.Add(time.Minute * 10)) {
+		t.Errorf("expected next attempt of node1 to be postponed, actual %s", deferred[0].NextAttempt)
+	}
+	if !deferred[1].NextAttempt.Time.Equal(later.Time) {
+		t.Errorf("expected next attempt of node2 to be kept, actual %s", deferred[1].NextAttempt)
+	}
+	if !deferredNode(deferred, "node2") || deferredNode(deferred, "node3") {
+		t.Errorf("expected node2 only to be deferred")
+	}
+}
@@ -0,0 +1,321 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/docker/distribution/reference"
+	"github.com/golang/glog"
+	"github.com/opencontainers/go-digest"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// imageCacheDiff is the drift between the images declared by an image cache and the
+// images held by the nodes it targets
+type imageCacheDiff struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// InSync is true if every targeted node holds the declared images, and only them
+	InSync bool `json:"inSync"`
+	// Nodes are the targeted nodes drifting from the image cache
+	Nodes []nodeDiff `json:"nodes"`
+}
+
+// nodeDiff is the drift of a node from an image cache
+type nodeDiff struct {
+	Node string `json:"node"`
+	// Missing are the declared images the node does not hold
+	Missing []string `json:"missing,omitempty"`
+	// Extra are the images held by the node from the repositories of the declared
+	// images, which are not declared, e.g. previous versions
+	Extra []string `json:"extra,omitempty"`
+	// Mismatched are the declared images the node holds with an unexpected digest
+	Mismatched []digestMismatch `json:"mismatched,omitempty"`
+}
+
+// digestMismatch is a declared image held by a node with another digest than expected:
+// the digest the image is pinned to, or else the digest held by most of the nodes
+type digestMismatch struct {
+	Image    string `json:"image"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// serveImageCacheDiff serves the drift of the image caches as JSON, optionally
+// restricted to the namespace and the name given by the query parameters
+func (c *Controller) serveImageCacheDiff(w http.ResponseWriter, r *http.Request) {
+	diffs, err := c.imageCacheDiffs(r.URL.Query().Get("namespace"), r.URL.Query().Get("name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(diffs); err != nil {
+		glog.Errorf("Error writing image cache diff: %v", err)
+	}
+}
+
+// imageCacheDiffs compares the images declared by the image caches of a namespace, or
+// of all namespaces if empty, with the images reported in the status of the nodes. No
+// image is pulled, and the registries are not queried.
+func (c *Controller) imageCacheDiffs(namespace, name string) ([]imageCacheDiff, error) {
+	imageCaches, err := c.imageCachesLister.ImageCaches(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(imageCaches, func(i, j int) bool {
+		if imageCaches[i].Namespace != imageCaches[j].Namespace {
+			return imageCaches[i].Namespace < imageCaches[j].Namespace
+		}
+		return imageCaches[i].Name < imageCaches[j].Name
+	})
+	diffs := []imageCacheDiff{}
+	for _, imageCache := range imageCaches {
+		if name != "" && imageCache.Name != name {
+			continue
+		}
+		diff, err := c.imageCacheDiff(imageCache)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
+
+// imageCacheDiff returns the drift of the nodes targeted by an image cache
+func (c *Controller) imageCacheDiff(imageCache *v1alpha2.ImageCache) (imageCacheDiff, error) {
+	diff := imageCacheDiff{Namespace: imageCache.Namespace, Name: imageCache.Name, Nodes: []nodeDiff{}}
+	// targets maps each node to the images declared for it
+	targets := map[string][]reference.Named{}
+	nodes := map[string]*corev1.Node{}
+	declared := map[string][]*corev1.Node{}
+	for _, cacheSpec := range imageListsOf(imageCache) {
+		specNodes, err := c.nodesForCacheSpec(cacheSpec)
+		if err != nil {
+			return diff, err
+		}
+		for _, image := range cacheSpec.Images {
+			named, err := reference.ParseNormalizedNamed(image)
+			if err != nil {
+				continue
+			}
+			named = reference.TagNameOnly(named)
+			for _, n := range specNodes {
+				nodes[n.Name] = n
+				if !containsNamed(targets[n.Name], named) {
+					targets[n.Name] = append(targets[n.Name], named)
+					declared[named.String()] = append(declared[named.String()], n)
+				}
+			}
+		}
+	}
+	expected := map[string]digest.Digest{}
+	for image, imageNodes := range declared {
+		named, _ := reference.ParseNormalizedNamed(image)
+		expected[image] = expectedDigest(named, imageNodes)
+	}
+
+	nodeNames := []string{}
+	for name := range nodes {
+		nodeNames = append(nodeNames, name)
+	}
+	sort.Strings(nodeNames)
+	for _, name := range nodeNames {
+		nd := nodeDiff{Node: name}
+		repositories := map[string]bool{}
+		for _, named := range targets[name] {
+			repositories[named.Name()] = true
+			present, actual := nodeImage(named, nodes[name])
+			if want := expected[named.String()]; want != "" && actual != "" && actual != want {
+				nd.Mismatched = append(nd.Mismatched, digestMismatch{
+					Image: reference.FamiliarString(named), Expected: want.String(), Actual: actual.String()})
+				continue
+			}
+			if !present {
+				nd.Missing = append(nd.Missing, reference.FamiliarString(named))
+			}
+		}
+		for _, ni := range nodes[name].Status.Images {
+			if extra := extraImage(ni.Names, targets[name], repositories); extra != "" {
+				nd.Extra = append(nd.Extra, extra)
+			}
+		}
+		sort.Strings(nd.Missing)
+		sort.Strings(nd.Extra)
+		if len(nd.Missing) > 0 || len(nd.Extra) > 0 || len(nd.Mismatched) > 0 {
+			diff.Nodes = append(diff.Nodes, nd)
+		}
+	}
+	diff.InSync = len(diff.Nodes) == 0
+	return diff, nil
+}
+
+// expectedDigest returns the digest an image is pinned to, or else the digest held by
+// most of the nodes under the tag of the image
+func expectedDigest(named reference.Named, nodes []*corev1.Node) digest.Digest {
+	if canonical, ok := named.(reference.Canonical); ok {
+		return canonical.Digest()
+	}
+	counts := map[digest.Digest]int{}
+	for _, n := range nodes {
+		if _, d := nodeImage(named, n); d != "" {
+			counts[d]++
+		}
+	}
+	var expected digest.Digest
+	for d, count := range counts {
+		if count > counts[expected] || (count == counts[expected] && d < expected) {
+			expected = d
+		}
+	}
+	return expected
+}
+
+// nodeImage returns true if the node holds the image, and the digest of the image held
+// by the node under the tag of the image, if any
+func nodeImage(named reference.Named, node *corev1.Node) (bool, digest.Digest) {
+	canonical, pinned := named.(reference.Canonical)
+	var tagNamed reference.Named
+	if tagged, ok := named.(reference.Tagged); ok {
+		tagNamed, _ = reference.WithTag(reference.TrimNamed(named), tagged.Tag())
+	}
+	present := false
+	var actual digest.Digest
+	for _, ni := range node.Status.Images {
+		if pinned && holdsDigest(canonical, ni.Names) {
+			present = true
+		}
+		if tagNamed == nil {
+			continue
+		}
+		if d := nodeImageDigest(tagNamed, ni.Names); d != "" {
+			actual = d
+		}
+		if !pinned && holdsName(tagNamed, ni.Names) {
+			present = true
+		}
+	}
+	return present, actual
+}
+
+// nodeImageOf returns the image in the node status held under the image reference, if
+// any. The references are compared once normalized, so that e.g. nginx:1.2 does not
+// match docker.io/library/nginx:1.23.
+func nodeImageOf(image string, node *corev1.Node) (corev1.ContainerImage, bool) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return corev1.ContainerImage{}, false
+	}
+	named = reference.TagNameOnly(named)
+	for _, ni := range node.Status.Images {
+		if canonical, ok := named.(reference.Canonical); ok {
+			if holdsDigest(canonical, ni.Names) {
+				return ni, true
+			}
+		} else if holdsName(named, ni.Names) {
+			return ni, true
+		}
+	}
+	return corev1.ContainerImage{}, false
+}
+
+// imagePresentOnNode returns true if the image is listed in the node status
+func imagePresentOnNode(image string, node *corev1.Node) bool {
+	_, ok := nodeImageOf(image, node)
+	return ok
+}
+
+// imageDigest returns the digest an image is pinned to, or else the digest of the image
+// held by the node under the tag of the image. Empty is returned if the node does not
+// report it.
+func imageDigest(image string, node *corev1.Node) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ""
+	}
+	if canonical, ok := named.(reference.Canonical); ok {
+		return canonical.Digest().String()
+	}
+	_, d := nodeImage(reference.TagNameOnly(named), node)
+	return d.String()
+}
+
+// holdsDigest returns true if the names of a node image include the pinned image
+func holdsDigest(canonical reference.Canonical, names []string) bool {
+	for _, name := range names {
+		nodeNamed, err := reference.ParseNormalizedNamed(name)
+		if err != nil || nodeNamed.Name() != canonical.Name() {
+			continue
+		}
+		if c, ok := nodeNamed.(reference.Canonical); ok && c.Digest() == canonical.Digest() {
+			return true
+		}
+	}
+	return false
+}
+
+// holdsName returns true if the names of a node image include the tagged image
+func holdsName(tagNamed reference.Named, names []string) bool {
+	for _, name := range names {
+		if nodeNamed, err := reference.ParseNormalizedNamed(name); err == nil && nodeNamed.String() == tagNamed.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// extraImage returns the name of a node image from one of the repositories, if none of
+// the declared images is held under its names. Empty is returned otherwise.
+func extraImage(names []string, declared []reference.Named, repositories map[string]bool) string {
+	extra := ""
+	for _, name := range names {
+		nodeNamed, err := reference.ParseNormalizedNamed(name)
+		if err != nil || !repositories[nodeNamed.Name()] {
+			continue
+		}
+		for _, named := range declared {
+			if canonical, ok := named.(reference.Canonical); ok {
+				if holdsDigest(canonical, []string{name}) {
+					return ""
+				}
+			} else if nodeNamed.String() == named.String() {
+				return ""
+			}
+		}
+		// tagged names are preferred to digests
+		if _, ok := nodeNamed.(reference.Tagged); ok || extra == "" {
+			extra = reference.FamiliarString(nodeNamed)
+		}
+	}
+	return extra
+}
+
+// containsNamed returns true if the list includes the image
+func containsNamed(list []reference.Named, named reference.Named) bool {
+	for _, n := range list {
+		if n.String() == named.String() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestImageCacheDiffs(t *testing.T) {
+	newNode := func(name string, images ...[]string) *corev1.Node {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"kubernetes.io/os": "linux"}}}
+		for _, names := range images {
+			node.Status.Images = append(node.Status.Images, corev1.ContainerImage{Names: names})
+		}
+		return node
+	}
+	nodes := []*corev1.Node{
+		newNode("node1",
+			[]string{"docker.io/library/foo@" + fooDigestV2, "docker.io/library/foo:v1"},
+			[]string{"docker.io/library/foo@" + fooDigestV1, "docker.io/library/foo:v0"},
+			[]string{"docker.io/library/bar@" + fooDigestV1},
+			[]string{"docker.io/library/nginx:1.23"}),
+		newNode("node2",
+			[]string{"docker.io/library/foo@" + fooDigestV2, "docker.io/library/foo:v1"}),
+		newNode("node3",
+			[]string{"docker.io/library/foo@" + fooDigestV1, "docker.io/library/foo:v1"}),
+	}
+	imageCaches := []*kubefledgedv1alpha2.ImageCache{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+			Spec: kubefledgedv1alpha2.ImageCacheSpec{
+				CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{{Images: []string{"foo:v1", "bar@" + fooDigestV1}}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: fledgedNameSpace},
+			Spec: kubefledgedv1alpha2.ImageCacheSpec{
+				CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{{Images: []string{"foo"}, NodeSelector: map[string]string{"pool": "none"}}},
+			},
+		},
+	}
+	controller, nodeInformer, imagecacheInformer := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	for _, node := range nodes {
+		nodeInformer.Informer().GetIndexer().Add(node)
+	}
+	for _, imageCache := range imageCaches {
+		imagecacheInformer.Informer().GetIndexer().Add(imageCache)
+	}
+	inSync := imageCacheDiff{Namespace: fledgedNameSpace, Name: "bar", InSync: true, Nodes: []nodeDiff{}}
+	drifting := imageCacheDiff{
+		Namespace: fledgedNameSpace,
+		Name:      "foo",
+		Nodes: []nodeDiff{
+			{Node: "node1", Extra: []string{"foo:v0"}},
+			{Node: "node2", Missing: []string{"bar@" + fooDigestV1}},
+			{
+				Node:       "node3",
+				Missing:    []string{"bar@" + fooDigestV1},
+				Mismatched: []digestMismatch{{Image: "foo:v1", Expected: fooDigestV2, Actual: fooDigestV1}},
+			},
+		},
+	}
+	tests := []struct {
+		name          string
+		namespace     string
+		imageCache    string
+		expectedDiffs []imageCacheDiff
+	}{
+		{
+			name:          "#1: Diff of all image caches",
+			expectedDiffs: []imageCacheDiff{inSync, drifting},
+		},
+		{
+			name:          "#2: Diff of an image cache",
+			namespace:     fledgedNameSpace,
+			imageCache:    "foo",
+			expectedDiffs: []imageCacheDiff{drifting},
+		},
+		{
+			name:          "#3: No image cache in namespace",
+			namespace:     "other",
+			expectedDiffs: []imageCacheDiff{},
+		},
+	}
+	for _, test := range tests {
+		diffs, err := controller.imageCacheDiffs(test.namespace, test.imageCache)
+		if err != nil {
+			t.Errorf("Test: %s failed: unexpected error: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(diffs, test.expectedDiffs) {
+			t.Errorf("Test: %s failed: expected diffs %+v, actual %+v", test.name, test.expectedDiffs, diffs)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	controller.serveImageCacheDiff(rec, httptest.NewRequest(http.MethodGet, "/api/diff?namespace="+fledgedNameSpace+"&name=foo", nil))
+	var served []imageCacheDiff
+	if err := json.NewDecoder(rec.Body).Decode(&served); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("expected diff to be served, got %d: %v", rec.Code, err)
+	}
+	if !reflect.DeepEqual(served, []imageCacheDiff{drifting}) {
+		t.Errorf("expected served diff %+v, actual %+v", drifting, served)
+	}
+}
+
+func TestNodeImageOf(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	node.Status.Images = []corev1.ContainerImage{
+		{
+			Names:     []string{"docker.io/library/nginx@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31", "docker.io/library/nginx:1.23"},
+			SizeBytes: 100,
+		},
+		{Names: []string{"ghcr.io/foo/bar:latest"}, SizeBytes: 200},
+	}
+	tests := []struct {
+		name           string
+		image          string
+		expectedSize   int64
+		expectedFound  bool
+		expectedDigest string
+	}{
+		{"#1: Familiar name held by the node", "nginx:1.23", 100, true, "sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31"},
+		{"#2: Tag prefix of a tag held by the node", "nginx:1.2", 0, false, ""},
+		{"#3: Pinned image held by the node", "nginx@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31", 100, true, "sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31"},
+		{"#4: Untagged image held as latest", "ghcr.io/foo/bar", 200, true, ""},
+		{"#5: Repository prefix of a repository held by the node", "ghcr.io/foo/ba:latest", 0, false, ""},
+		{"#6: Invalid image reference", "Invalid:Image", 0, false, ""},
+	}
+	for _, test := range tests {
+		ni, found := nodeImageOf(test.image, node)
+		if found != test.expectedFound || ni.SizeBytes != test.expectedSize {
+			t.Errorf("Test: %s failed: expected found=%t size=%d, actual found=%t size=%d", test.name, test.expectedFound, test.expectedSize, found, ni.SizeBytes)
+		}
+		if found != imagePresentOnNode(test.image, node) {
+			t.Errorf("Test: %s failed: expected imagePresentOnNode=%t", test.name, found)
+		}
+		if digest := imageDigest(test.image, node); digest != test.expectedDigest {
+			t.Errorf("Test: %s failed: expected digest %q, actual %q", test.name, test.expectedDigest, digest)
+		}
+	}
+}
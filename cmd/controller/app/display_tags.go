@@ -0,0 +1,50 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"strings"
+
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+)
+
+// displayTags returns the display tags of the images of the image lists of an image
+// cache, by image
+func displayTags(imageCache *v1alpha2.ImageCache) map[string]string {
+	tags := map[string]string{}
+	for _, cacheSpec := range imageCache.Spec.CacheSpec {
+		for image, tag := range cacheSpec.DisplayTags {
+			tags[image] = tag
+		}
+	}
+	return tags
+}
+
+// displayImage returns the image with its display tag inserted before its digest, e.g.
+// nginx:1.23@sha256:... for nginx@sha256:..., or the image itself if it has no display
+// tag. Images with a display tag are referenced by digest only.
+func displayImage(image string, tags map[string]string) string {
+	tag, ok := tags[image]
+	if !ok {
+		return image
+	}
+	i := strings.LastIndex(image, "@")
+	if i < 0 {
+		return image
+	}
+	return image[:i] + ":" + tag + image[i:]
+}
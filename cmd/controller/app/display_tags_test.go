@@ -0,0 +1,64 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+)
+
+func TestDisplayImage(t *testing.T) {
+	digest := "sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31"
+	imageCache := &kubefledgedv1alpha2.ImageCache{
+		Spec: kubefledgedv1alpha2.ImageCacheSpec{
+			CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx@" + digest, "redis:7"},
+					DisplayTags: map[string]string{"nginx@" + digest: "1.23"}},
+				{Images: []string{"registry.internal:5000/foo@" + digest},
+					DisplayTags: map[string]string{"registry.internal:5000/foo@" + digest: "v1.2.3"}},
+			},
+		},
+	}
+	tests := []struct {
+		name     string
+		image    string
+		expected string
+	}{
+		{
+			name:     "#1: Image with display tag",
+			image:    "nginx@" + digest,
+			expected: "nginx:1.23@" + digest,
+		},
+		{
+			name:     "#2: Image with display tag in a registry with a port",
+			image:    "registry.internal:5000/foo@" + digest,
+			expected: "registry.internal:5000/foo:v1.2.3@" + digest,
+		},
+		{
+			name:     "#3: Image without display tag",
+			image:    "redis:7",
+			expected: "redis:7",
+		},
+	}
+	tags := displayTags(imageCache)
+	for _, test := range tests {
+		if actual := displayImage(test.image, tags); actual != test.expected {
+			t.Errorf("Test: %s failed: expected=%s, actual=%s", test.name, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"github.com/docker/distribution/reference"
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+)
+
+// recordEgress accounts the bytes pulled from the registries by the image pulls of a
+// finished image cache action. The size of an image is estimated from its manifest in
+// the registry, cached by the metadata worker, or else from the size reported by the
+// node. Image pulls of images already present on the node are not accounted for.
+func (c *Controller) recordEgress(imageCache *v1alpha2.ImageCache, results map[string]images.ImageWorkResult) {
+	keychain := c.keychainOf(imageCache)
+	sizes := map[string]int64{}
+	var total int64
+	for _, r := range results {
+		iwr := r.ImageWorkRequest
+		if r.Status != images.ImageWorkResultStatusSucceeded || iwr.WorkType == images.ImageCachePurge || iwr.Node == nil {
+			continue
+		}
+		platform := nodePlatform(iwr.Platform, iwr.Node)
+		key := iwr.Image + "@" + platform
+		size, ok := sizes[key]
+		if !ok {
+			size = c.cachedImageSize(iwr.Image, platform, iwr.Node.Name, keychain)
+			sizes[key] = size
+		}
+		registryPulledBytes.WithLabelValues(imageRegistry(iwr.Image), imageCache.Namespace, imageCache.Name).Add(float64(size))
+		total += size
+	}
+	imageCacheActionPulledBytes.WithLabelValues(imageCache.Namespace, imageCache.Name).Set(float64(total))
+}
+
+// cachedImageSize returns the bytes pulled from the registry for an image, from the size
+// read with the keychain and cached by the metadata worker, or else from the size
+// reported by the node
+func (c *Controller) cachedImageSize(image string, platform string, nodeName string, keychain authn.Keychain) int64 {
+	if metadata, ok := c.cachedImageMetadata(image, platform, keychain); ok && metadata.err == nil {
+		return metadata.size
+	}
+	return c.nodeImageSize(image, nodeName)
+}
+
+// nodeImageSize returns the size of an image reported by the node
+func (c *Controller) nodeImageSize(image string, nodeName string) int64 {
+	// Nodes report the uncompressed size of images, which overestimates the bytes pulled
+	if node, err := c.nodesLister.Get(nodeName); err == nil {
+		if size, ok := imageSizeOnNode(image, node); ok {
+			return size
+		}
+	}
+	return 0
+}
+
+// imageRegistry returns the registry of an image e.g. "docker.io" or "ghcr.io"
+func imageRegistry(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ""
+	}
+	return reference.Domain(named)
+}
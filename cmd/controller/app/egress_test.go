@@ -0,0 +1,98 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+type fakeImageSizer map[string]int64
+
+func (f fakeImageSizer) ImageSize(image string, platform string, keychain authn.Keychain) (int64, error) {
+	if size, ok := f[image+"@"+platform]; ok {
+		return size, nil
+	}
+	return 0, fmt.Errorf("image %s not found", image)
+}
+
+func TestRecordEgress(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	node.Status.NodeInfo = corev1.NodeSystemInfo{OperatingSystem: "linux", Architecture: "amd64"}
+	node.Status.Images = []corev1.ContainerImage{{Names: []string{"ghcr.io/foo/private:v1"}, SizeBytes: 700}}
+	imageCache := &kubefledgedv1alpha2.ImageCache{ObjectMeta: metav1.ObjectMeta{Name: "egress", Namespace: fledgedNameSpace}}
+	result := func(image string, platform string, status string) images.ImageWorkResult {
+		return images.ImageWorkResult{
+			ImageWorkRequest: images.ImageWorkRequest{Image: image, Platform: platform, Node: node, WorkType: images.ImageCacheRefresh},
+			Status:           status,
+		}
+	}
+	results := map[string]images.ImageWorkResult{
+		"job1": result("nginx:1.23", "", images.ImageWorkResultStatusSucceeded),
+		"job2": result("nginx:1.23", "linux/arm64", images.ImageWorkResultStatusSucceeded),
+		"job3": result("ghcr.io/foo/private:v1", "", images.ImageWorkResultStatusSucceeded),
+		"job4": result("redis:7", "", images.ImageWorkResultStatusAlreadyPulled),
+		"job5": result("redis:7", "", images.ImageWorkResultStatusFailed),
+	}
+	controller, nodeInformer, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	controller.imageSizer = fakeImageSizer{"nginx:1.23@linux/amd64": 100, "nginx:1.23@linux/arm64": 90, "redis:7@linux/amd64": 1000}
+	nodeInformer.Informer().GetIndexer().Add(node)
+	for _, key := range []string{"nginx:1.23@linux/amd64", "nginx:1.23@linux/arm64", "ghcr.io/foo/private:v1@linux/amd64"} {
+		image, platform, _ := strings.Cut(key, "@")
+		controller.cachedImageMetadata(image, platform, controller.keychainOf(imageCache))
+	}
+	readImageMetadata(controller)
+
+	controller.recordEgress(imageCache, results)
+
+	tests := []struct {
+		name     string
+		actual   float64
+		expected float64
+	}{
+		{
+			name:     "#1: Bytes pulled from docker.io",
+			actual:   testutil.ToFloat64(registryPulledBytes.WithLabelValues("docker.io", fledgedNameSpace, "egress")),
+			expected: 190,
+		},
+		{
+			name:     "#2: Bytes pulled from ghcr.io estimated from the node",
+			actual:   testutil.ToFloat64(registryPulledBytes.WithLabelValues("ghcr.io", fledgedNameSpace, "egress")),
+			expected: 700,
+		},
+		{
+			name:     "#3: Bytes pulled by the image cache action",
+			actual:   testutil.ToFloat64(imageCacheActionPulledBytes.WithLabelValues(fledgedNameSpace, "egress")),
+			expected: 890,
+		},
+	}
+	for _, test := range tests {
+		if test.actual != test.expected {
+			t.Errorf("Test: %s failed: expected=%v, actual=%v", test.name, test.expected, test.actual)
+		}
+	}
+}
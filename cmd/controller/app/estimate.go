@@ -0,0 +1,131 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	"github.com/senthilrch/kube-fledged/pkg/policy"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReasonDryRun is used as part of the Event 'reason' when the dry run of an image cache
+// is completed
+const ReasonDryRun = "DryRun"
+
+// pullPolicy returns the image pull policy of an image cache, from its spec, or else
+// from the policies of its namespace, or else from the controller
+func (c *Controller) pullPolicy(imageCache *v1alpha2.ImageCache, policies []*v1alpha2.FledgedPolicy) corev1.PullPolicy {
+	if imageCache.Spec.ImagePullPolicy != "" {
+		return imageCache.Spec.ImagePullPolicy
+	}
+	if pullPolicy := policy.ImagePullPolicy(policies); pullPolicy != "" {
+		return pullPolicy
+	}
+	return c.imagePullPolicy
+}
+
+// estimatePulls estimates the bytes pulled to each node by the image pulls of an action
+// of the image cache, and their duration at the node bandwidth. Image sizes are taken from
+// the registries, cached by the metadata worker, or else from the nodes holding the
+// images. The images present on a
+// node are not pulled again, unless the image pull policy of the image cache, or of the
+// image when it is overridden in the cache spec, is Always.
+func (c *Controller) estimatePulls(imageCache *v1alpha2.ImageCache, workItems []imageWorkItem, pullPolicy corev1.PullPolicy) *v1alpha2.ImageCacheEstimate {
+	keychain := c.keychainOf(imageCache)
+	sizes := map[string]int64{}
+	nodeBytes := map[string]int64{}
+	estimate := &v1alpha2.ImageCacheEstimate{}
+	for _, w := range workItems {
+		if w.workType == images.ImageCachePurge {
+			continue
+		}
+		itemPullPolicy := pullPolicy
+		if w.pullPolicy != "" {
+			itemPullPolicy = w.pullPolicy
+		}
+		if itemPullPolicy != corev1.PullAlways && imagePresentOnNode(w.image, w.node) {
+			continue
+		}
+		platform := nodePlatform(w.platform, w.node)
+		key := w.image + "@" + platform
+		size, ok := sizes[key]
+		if !ok {
+			size = c.cachedImageSize(w.image, platform, w.node.Name, keychain)
+			sizes[key] = size
+		}
+		nodeBytes[w.node.Name] += size
+		estimate.TotalBytes += size
+	}
+	var slowest time.Duration
+	for node, bytes := range nodeBytes {
+		nodeEstimate := v1alpha2.NodeEstimate{Node: node, Bytes: bytes}
+		if c.nodeBandwidth > 0 {
+			duration := time.Duration(float64(bytes) / float64(c.nodeBandwidth) * float64(time.Second)).Round(time.Second)
+			nodeEstimate.Duration = &metav1.Duration{Duration: duration}
+			if duration > slowest {
+				slowest = duration
+			}
+		}
+		estimate.Nodes = append(estimate.Nodes, nodeEstimate)
+	}
+	sort.Slice(estimate.Nodes, func(i, j int) bool { return estimate.Nodes[i].Node < estimate.Nodes[j].Node })
+	if c.nodeBandwidth > 0 {
+		estimate.Duration = &metav1.Duration{Duration: slowest}
+	}
+	return estimate
+}
+
+// recordDryRun records the plan of a dry run of an image cache, with the estimate of its
+// image pulls, in the status of the image cache and removes the dry run annotation. The
+// status of the last action is left unchanged, and no image is pulled.
+func (c *Controller) recordDryRun(imageCache *v1alpha2.ImageCache, workItems []imageWorkItem,
+	plan *v1alpha2.ImageCachePlan, policies []*v1alpha2.FledgedPolicy) error {
+	plan.Nodes = countNodes(workItems)
+	plan.Estimate = c.estimatePulls(imageCache, workItems, c.pullPolicy(imageCache, policies))
+	plan.EstimatedBytes = plan.Estimate.TotalBytes
+	if len(c.egressCostRates) > 0 {
+		c.estimateCost(imageCache, workItems, plan)
+	}
+	imageCacheCopy, err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(imageCache.Namespace).Get(context.TODO(), imageCache.Name, metav1.GetOptions{})
+	if err != nil {
+		glog.Errorf("Error getting imagecache(%s) from api server: %v", imageCache.Name, err)
+		return err
+	}
+	imageCacheCopy.Status.DryRun = &v1alpha2.ImageCacheDryRun{Time: metav1.Now(), Plan: *plan}
+	delete(imageCacheCopy.Annotations, imageCacheDryRunAnnotationKey)
+	if _, err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(imageCache.Namespace).Update(context.TODO(), imageCacheCopy, metav1.UpdateOptions{}); err != nil {
+		glog.Errorf("Error updating imagecache(%s) with the plan of its dry run: %v", imageCache.Name, err)
+		return err
+	}
+	message := fmt.Sprintf("Dry run: %d image pulls to %d nodes, estimated size %s", plan.WorkItems, plan.Nodes,
+		resource.NewQuantity(plan.EstimatedBytes, resource.BinarySI))
+	if plan.Estimate.Duration != nil {
+		message += fmt.Sprintf(", estimated duration %s", plan.Estimate.Duration.Duration)
+	}
+	glog.Infof("Image cache %s/%s: %s", imageCache.Namespace, imageCache.Name, message)
+	c.recorder.Event(imageCache, corev1.EventTypeNormal, ReasonDryRun, message)
+	return nil
+}
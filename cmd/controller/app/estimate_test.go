@@ -0,0 +1,197 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestEstimatePulls(t *testing.T) {
+	imageCache := &kubefledgedv1alpha2.ImageCache{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace}}
+	newNode := func(name string, images ...string) *corev1.Node {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		node.Status.NodeInfo = corev1.NodeSystemInfo{OperatingSystem: "linux", Architecture: "amd64"}
+		for _, image := range images {
+			node.Status.Images = append(node.Status.Images, corev1.ContainerImage{Names: []string{image}, SizeBytes: 500})
+		}
+		return node
+	}
+	node1 := newNode("node1")
+	node2 := newNode("node2", "nginx:1.23")
+	workItems := []imageWorkItem{
+		{image: "nginx:1.23", node: node1, workType: images.ImageCacheCreate},
+		{image: "redis:7", node: node1, workType: images.ImageCacheCreate},
+		{image: "redis:7", platform: "linux/arm64", node: node1, workType: images.ImageCacheCreate},
+		{image: "nginx:1.23", node: node2, workType: images.ImageCacheCreate},
+		{image: "httpd:2.4", node: node2, workType: images.ImageCachePurge},
+	}
+	duration := func(d time.Duration) *metav1.Duration {
+		return &metav1.Duration{Duration: d}
+	}
+	tests := []struct {
+		name       string
+		pullPolicy corev1.PullPolicy
+		bandwidth  int64
+		expected   *kubefledgedv1alpha2.ImageCacheEstimate
+	}{
+		{
+			name:       "#1: Images present on the nodes are not pulled again",
+			pullPolicy: corev1.PullIfNotPresent,
+			bandwidth:  100,
+			expected: &kubefledgedv1alpha2.ImageCacheEstimate{
+				TotalBytes: 1400,
+				Duration:   duration(14 * time.Second),
+				Nodes: []kubefledgedv1alpha2.NodeEstimate{
+					{Node: "node1", Bytes: 1400, Duration: duration(14 * time.Second)},
+				},
+			},
+		},
+		{
+			name:       "#2: Images are always pulled",
+			pullPolicy: corev1.PullAlways,
+			bandwidth:  100,
+			expected: &kubefledgedv1alpha2.ImageCacheEstimate{
+				TotalBytes: 1600,
+				Duration:   duration(14 * time.Second),
+				Nodes: []kubefledgedv1alpha2.NodeEstimate{
+					{Node: "node1", Bytes: 1400, Duration: duration(14 * time.Second)},
+					{Node: "node2", Bytes: 200, Duration: duration(2 * time.Second)},
+				},
+			},
+		},
+		{
+			name:       "#3: No duration without node bandwidth",
+			pullPolicy: corev1.PullAlways,
+			expected: &kubefledgedv1alpha2.ImageCacheEstimate{
+				TotalBytes: 1600,
+				Nodes: []kubefledgedv1alpha2.NodeEstimate{
+					{Node: "node1", Bytes: 1400},
+					{Node: "node2", Bytes: 200},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+		controller.imageSizer = fakeImageSizer{"nginx:1.23@linux/amd64": 200, "redis:7@linux/amd64": 700, "redis:7@linux/arm64": 500}
+		controller.nodeBandwidth = test.bandwidth
+		controller.queueImageMetadata(imageCache, workItems, true, false)
+		readImageMetadata(controller)
+		if actual := controller.estimatePulls(imageCache, workItems, test.pullPolicy); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test: %s failed: expected estimate %+v, actual %+v", test.name, test.expected, actual)
+		}
+	}
+
+	// The pull policy of an image overrides the pull policy of the image cache
+	controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	controller.imageSizer = fakeImageSizer{"nginx:1.23@linux/amd64": 200}
+	expected := &kubefledgedv1alpha2.ImageCacheEstimate{
+		TotalBytes: 200,
+		Nodes:      []kubefledgedv1alpha2.NodeEstimate{{Node: "node2", Bytes: 200}},
+	}
+	alwaysItems := []imageWorkItem{{image: "nginx:1.23", pullPolicy: corev1.PullAlways, node: node2, workType: images.ImageCacheRefresh}}
+	controller.queueImageMetadata(imageCache, alwaysItems, true, false)
+	readImageMetadata(controller)
+	if actual := controller.estimatePulls(imageCache, alwaysItems, corev1.PullIfNotPresent); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected estimate %+v, actual %+v", expected, actual)
+	}
+
+	// Registries are not read while estimating: sizes not cached yet are queued
+	controller, _, _ = newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	sizer := &countingImageSizer{sizes: fakeImageSizer{"nginx:1.23@linux/amd64": 200}}
+	controller.imageSizer = sizer
+	controller.estimatePulls(imageCache, alwaysItems, corev1.PullIfNotPresent)
+	if sizer.reads != 0 || controller.imageMetadataQueue.Len() != 1 {
+		t.Errorf("expected the size of the image queued for the metadata worker, actual reads=%d, queued=%d", sizer.reads, controller.imageMetadataQueue.Len())
+	}
+}
+
+func TestPullPolicy(t *testing.T) {
+	imageCache := &kubefledgedv1alpha2.ImageCache{}
+	fledgedPolicy := &kubefledgedv1alpha2.FledgedPolicy{
+		Spec: kubefledgedv1alpha2.FledgedPolicySpec{
+			Defaults: &kubefledgedv1alpha2.FledgedPolicyDefaults{ImagePullPolicy: corev1.PullAlways},
+		},
+	}
+	controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	if actual := controller.pullPolicy(imageCache, nil); actual != corev1.PullIfNotPresent {
+		t.Errorf("expected pull policy of the controller, actual %s", actual)
+	}
+	if actual := controller.pullPolicy(imageCache, []*kubefledgedv1alpha2.FledgedPolicy{fledgedPolicy}); actual != corev1.PullAlways {
+		t.Errorf("expected pull policy of the policy, actual %s", actual)
+	}
+	imageCache.Spec.ImagePullPolicy = corev1.PullNever
+	if actual := controller.pullPolicy(imageCache, []*kubefledgedv1alpha2.FledgedPolicy{fledgedPolicy}); actual != corev1.PullNever {
+		t.Errorf("expected pull policy of the image cache, actual %s", actual)
+	}
+}
+
+func TestRecordDryRun(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	node.Status.NodeInfo = corev1.NodeSystemInfo{OperatingSystem: "linux", Architecture: "amd64"}
+	imageCache := &kubefledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   fledgedNameSpace,
+			Annotations: map[string]string{imageCacheDryRunAnnotationKey: ""},
+		},
+		Spec: kubefledgedv1alpha2.ImageCacheSpec{
+			CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{{Images: []string{"nginx:1.23"}}},
+		},
+		Status: kubefledgedv1alpha2.ImageCacheStatus{
+			Status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			Reason: kubefledgedv1alpha2.ImageCacheReasonImagesPulledSuccessfully,
+		},
+	}
+	fakefledgedclientset := kubefledgedclientsetfake.NewSimpleClientset(imageCache)
+	controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), fakefledgedclientset)
+	controller.imageSizer = fakeImageSizer{"nginx:1.23@linux/amd64": 3000}
+	controller.nodeBandwidth = 1000
+	workItems := []imageWorkItem{{image: "nginx:1.23", node: node, workType: images.ImageCacheRefresh}}
+	plan := &kubefledgedv1alpha2.ImageCachePlan{WorkItems: 1}
+	controller.queueImageMetadata(imageCache, workItems, true, false)
+	readImageMetadata(controller)
+
+	if err := controller.recordDryRun(imageCache, workItems, plan, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	actual, err := fakefledgedclientset.KubefledgedV1alpha2().ImageCaches(fledgedNameSpace).Get(context.TODO(), "foo", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := actual.Annotations[imageCacheDryRunAnnotationKey]; ok {
+		t.Errorf("expected dry run annotation to be removed")
+	}
+	if actual.Status.Status != kubefledgedv1alpha2.ImageCacheActionStatusSucceeded {
+		t.Errorf("expected status of the last action to be unchanged, actual %s", actual.Status.Status)
+	}
+	dryRun := actual.Status.DryRun
+	if dryRun == nil || dryRun.Plan.Nodes != 1 || dryRun.Plan.EstimatedBytes != 3000 || dryRun.Plan.Estimate == nil ||
+		dryRun.Plan.Estimate.Duration == nil || dryRun.Plan.Estimate.Duration.Duration != 3*time.Second {
+		t.Errorf("expected dry run plan of 3000 bytes in 3s to 1 node, actual %+v", dryRun)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// expiryCheckInterval is the interval at which image caches are checked for expiry
+	expiryCheckInterval = 30 * time.Second
+	// ReasonImageCacheExpired is used as part of the Event 'reason' when the TTL of an
+	// ImageCache expires
+	ReasonImageCacheExpired = "ImageCacheExpired"
+)
+
+// runExpiryWorker purges and deletes the image caches whose TTL expired, and deletes
+// the one-shot image caches finished for longer than their ttlSecondsAfterFinished
+func (c *Controller) runExpiryWorker() {
+	imageCaches, err := c.imageCachesLister.ImageCaches("").List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error in listing image caches: %v", err)
+		return
+	}
+	now := time.Now()
+	for _, imageCache := range imageCaches {
+		if finishedTTLExpired(imageCache, now) {
+			err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(imageCache.Namespace).Delete(context.TODO(), imageCache.Name, metav1.DeleteOptions{})
+			if err != nil {
+				glog.Errorf("Error deleting finished image cache %s/%s: %v", imageCache.Namespace, imageCache.Name, err)
+				continue
+			}
+			glog.Infof("Image cache %s/%s deleted %ds after it finished", imageCache.Namespace, imageCache.Name, *imageCache.Spec.TTLSecondsAfterFinished)
+			continue
+		}
+		if imageCache.Spec.TTL == nil || now.Before(imageCache.CreationTimestamp.Add(imageCache.Spec.TTL.Duration)) {
+			continue
+		}
+		if err := c.expireImageCache(imageCache); err != nil {
+			glog.Errorf("Error expiring image cache %s/%s: %v", imageCache.Namespace, imageCache.Name, err)
+		}
+	}
+}
+
+// expireImageCache purges the images of an expired image cache by annotating it for
+// purge, the same as a user would. Once the purge is complete, the image cache is
+// deleted.
+func (c *Controller) expireImageCache(imageCache *v1alpha2.ImageCache) error {
+	if imageCache.Status.Status == v1alpha2.ImageCacheActionStatusProcessing {
+		return nil
+	}
+	if _, purging := imageCache.Annotations[imageCachePurgeAnnotationKey]; purging {
+		return nil
+	}
+	if imageCache.Status.Reason == v1alpha2.ImageCacheReasonImageCachePurge {
+		err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(imageCache.Namespace).Delete(context.TODO(), imageCache.Name, metav1.DeleteOptions{})
+		if err != nil {
+			return err
+		}
+		glog.Infof("Expired image cache %s/%s deleted", imageCache.Namespace, imageCache.Name)
+		return nil
+	}
+	imageCacheCopy := imageCache.DeepCopy()
+	if imageCacheCopy.Annotations == nil {
+		imageCacheCopy.Annotations = map[string]string{}
+	}
+	imageCacheCopy.Annotations[imageCachePurgeAnnotationKey] = ""
+	_, err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(imageCache.Namespace).Update(context.TODO(), imageCacheCopy, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	message := fmt.Sprintf("Image cache expired after its TTL of %s: purging images", imageCache.Spec.TTL.Duration)
+	glog.Infof("Image cache %s/%s: %s", imageCache.Namespace, imageCache.Name, message)
+	c.recorder.Event(imageCache, corev1.EventTypeNormal, ReasonImageCacheExpired, message)
+	return nil
+}
+
+// finishedTTLExpired returns true if the one-shot image cache finished its last action
+// more than ttlSecondsAfterFinished ago
+func finishedTTLExpired(imageCache *v1alpha2.ImageCache, now time.Time) bool {
+	if imageCache.Spec.TTLSecondsAfterFinished == nil || imageCache.Status.CompletionTime == nil {
+		return false
+	}
+	if imageCache.Status.Status == "" || imageCache.Status.Status == v1alpha2.ImageCacheActionStatusProcessing {
+		return false
+	}
+	ttl := time.Duration(*imageCache.Spec.TTLSecondsAfterFinished) * time.Second
+	return !now.Before(imageCache.Status.CompletionTime.Add(ttl))
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRunExpiryWorker(t *testing.T) {
+	finished := metav1.NewTime(time.Now().Add(-10 * time.Minute))
+	fiveMinutes, oneHour := int32(300), int32(3600)
+	tests := []struct {
+		name            string
+		ttl             *metav1.Duration
+		ttlAfterFinish  *int32
+		annotations     map[string]string
+		status          kubefledgedv1alpha2.ImageCacheStatus
+		expectedPurge   bool
+		expectedDeleted bool
+	}{
+		{
+			name: "#1: No ttl",
+			status: kubefledgedv1alpha2.ImageCacheStatus{
+				Status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			},
+		},
+		{
+			name: "#2: Ttl not expired",
+			ttl:  &metav1.Duration{Duration: 2 * time.Hour},
+			status: kubefledgedv1alpha2.ImageCacheStatus{
+				Status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			},
+		},
+		{
+			name: "#3: Ttl expired - images purged",
+			ttl:  &metav1.Duration{Duration: 30 * time.Minute},
+			status: kubefledgedv1alpha2.ImageCacheStatus{
+				Status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			},
+			expectedPurge: true,
+		},
+		{
+			name: "#4: Ttl expired - image cache under processing",
+			ttl:  &metav1.Duration{Duration: 30 * time.Minute},
+			status: kubefledgedv1alpha2.ImageCacheStatus{
+				Status: kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+			},
+		},
+		{
+			name:        "#5: Ttl expired - purge not complete",
+			ttl:         &metav1.Duration{Duration: 30 * time.Minute},
+			annotations: map[string]string{imageCachePurgeAnnotationKey: ""},
+			status: kubefledgedv1alpha2.ImageCacheStatus{
+				Status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+				Reason: kubefledgedv1alpha2.ImageCacheReasonImageCachePurge,
+			},
+			expectedPurge: true,
+		},
+		{
+			name: "#6: Ttl expired - image cache purged",
+			ttl:  &metav1.Duration{Duration: 30 * time.Minute},
+			status: kubefledgedv1alpha2.ImageCacheStatus{
+				Status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+				Reason: kubefledgedv1alpha2.ImageCacheReasonImageCachePurge,
+			},
+			expectedDeleted: true,
+		},
+		{
+			name:           "#7: Finished - ttlSecondsAfterFinished not expired",
+			ttlAfterFinish: &oneHour,
+			status: kubefledgedv1alpha2.ImageCacheStatus{
+				Status:         kubefledgedv1alpha2.ImageCacheActionStatusFailed,
+				CompletionTime: &finished,
+			},
+		},
+		{
+			name:           "#8: Finished - ttlSecondsAfterFinished expired",
+			ttlAfterFinish: &fiveMinutes,
+			status: kubefledgedv1alpha2.ImageCacheStatus{
+				Status:         kubefledgedv1alpha2.ImageCacheActionStatusFailed,
+				CompletionTime: &finished,
+			},
+			expectedDeleted: true,
+		},
+		{
+			name:           "#9: Processing - ttlSecondsAfterFinished expired since the previous action",
+			ttlAfterFinish: &fiveMinutes,
+			status: kubefledgedv1alpha2.ImageCacheStatus{
+				Status:         kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+				CompletionTime: &finished,
+			},
+		},
+	}
+	for _, test := range tests {
+		imageCache := kubefledgedv1alpha2.ImageCache{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "foo",
+				Namespace:         fledgedNameSpace,
+				Annotations:       test.annotations,
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+			},
+			Spec: kubefledgedv1alpha2.ImageCacheSpec{
+				CacheSpec:               []kubefledgedv1alpha2.CacheSpecImages{{Images: []string{"foo"}}},
+				TTL:                     test.ttl,
+				TTLSecondsAfterFinished: test.ttlAfterFinish,
+			},
+			Status: test.status,
+		}
+		fledgedclientset := kubefledgedclientsetfake.NewSimpleClientset(&imageCache)
+		controller, _, imagecacheInformer := newTestController(fakeclientset.NewSimpleClientset(), fledgedclientset)
+		imagecacheInformer.Informer().GetIndexer().Add(&imageCache)
+
+		controller.runExpiryWorker()
+
+		updated, err := fledgedclientset.KubefledgedV1alpha2().ImageCaches(fledgedNameSpace).Get(context.TODO(), "foo", metav1.GetOptions{})
+		if test.expectedDeleted {
+			if !errors.IsNotFound(err) {
+				t.Errorf("Test: %s failed: expected image cache to be deleted, got error %v", test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test: %s failed: %v", test.name, err)
+		}
+		if _, purge := updated.Annotations[imageCachePurgeAnnotationKey]; purge != test.expectedPurge {
+			t.Errorf("Test: %s failed: expectedPurge=%t, actualPurge=%t", test.name, test.expectedPurge, purge)
+		}
+	}
+}
@@ -0,0 +1,460 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/features"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Phases of the hooks of image cache actions
+const (
+	HookPhasePrePull  = "PrePull"
+	HookPhasePostPull = "PostPull"
+)
+
+const (
+	// defaultHookTimeout is the timeout of the hooks which do not specify one
+	defaultHookTimeout = 5 * time.Minute
+)
+
+// HookContext is the context of a hook, posted as JSON to HTTP hooks and passed in the
+// environment of exec hooks
+type HookContext struct {
+	Phase string `json:"phase"`
+	// Action is the image cache action i.e. ImageCacheCreate, ImageCacheUpdate,
+	// ImageCacheRefresh or ImageCachePurge
+	Action string `json:"action"`
+	// ImageCache is the namespace/name of the image cache
+	ImageCache string `json:"imageCache"`
+	// Node is the node of node scoped hooks
+	Node string `json:"node,omitempty"`
+	// Status is the status of the completed action, passed to post-pull hooks
+	Status v1alpha2.ImageCacheActionStatus `json:"status,omitempty"`
+}
+
+// runPrePullHooks runs the pre-pull hooks of an image cache before the image pulls or
+// deletes of its action are queued. It returns an error if a hook with the Fail
+// failure policy failed, in which case the action must not proceed.
+func (c *Controller) runPrePullHooks(imageCache *v1alpha2.ImageCache, workType images.WorkType, workItems []imageWorkItem) error {
+	if !hasPrePullHooks(imageCache) {
+		return nil
+	}
+	nodes := []*corev1.Node{}
+	seen := map[string]bool{}
+	for _, w := range workItems {
+		if !seen[w.node.Name] {
+			seen[w.node.Name] = true
+			nodes = append(nodes, w.node)
+		}
+	}
+	hctx := HookContext{Phase: HookPhasePrePull, Action: actionReason(workType), ImageCache: imageCache.Namespace + "/" + imageCache.Name}
+	return c.runHooks(imageCache, imageCache.Spec.Hooks.PrePull, hctx, nodes)
+}
+
+// hasPrePullHooks returns true if pre-pull hooks are to be run before the image pulls or
+// deletes of the actions of the image cache
+func hasPrePullHooks(imageCache *v1alpha2.ImageCache) bool {
+	return imageCache.Spec.Hooks != nil && len(imageCache.Spec.Hooks.PrePull) > 0 && features.Enabled(features.PullHooks)
+}
+
+// runPrePullHooksAndDispatch runs the pre-pull hooks of an image cache action, off the
+// sync worker, then queues the image work of the action. If a hook with the Fail failure
+// policy failed, the action fails with reason PrePullHookFailed instead. The status of
+// the action reports the pending hooks until they completed.
+func (c *Controller) runPrePullHooksAndDispatch(imageCache *v1alpha2.ImageCache, wqKey images.WorkQueueKey, workItems []imageWorkItem,
+	status v1alpha2.ImageCacheStatus, processingMessage string, pullDeadline time.Duration) {
+	if err := c.runPrePullHooks(imageCache, wqKey.WorkType, workItems); err != nil {
+		status.Status = v1alpha2.ImageCacheActionStatusFailed
+		status.Reason = v1alpha2.ImageCacheReasonPrePullHookFailed
+		status.Message = err.Error()
+		if err := c.updateImageCacheStatus(imageCache, &status); err != nil {
+			glog.Errorf("Error updating imagecache status to %s: %v", status.Status, err)
+		}
+		c.publishActionFinished(imageCache, actionReason(wqKey.WorkType), &status)
+		return
+	}
+	status.Message = processingMessage
+	if err := c.updateImageCacheStatus(imageCache, &status); err != nil {
+		glog.Errorf("Error updating imagecache status: %v", err)
+	}
+	c.dispatchImageWork(imageCache, wqKey, workItems, pullDeadline)
+}
+
+// runPostPullHooks runs the post-pull hooks of an image cache once the image pulls or
+// deletes of its action completed. Failed hooks are reported, the status of the action
+// is not changed.
+func (c *Controller) runPostPullHooks(imageCache *v1alpha2.ImageCache, action string, status v1alpha2.ImageCacheActionStatus, results map[string]images.ImageWorkResult) {
+	if imageCache.Spec.Hooks == nil || len(imageCache.Spec.Hooks.PostPull) == 0 || !features.Enabled(features.PullHooks) {
+		return
+	}
+	nodes := []*corev1.Node{}
+	seen := map[string]bool{}
+	for _, r := range results {
+		if node := r.ImageWorkRequest.Node; node != nil && !seen[node.Name] {
+			seen[node.Name] = true
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	hctx := HookContext{Phase: HookPhasePostPull, Action: action, ImageCache: imageCache.Namespace + "/" + imageCache.Name, Status: status}
+	c.runHooks(imageCache, imageCache.Spec.Hooks.PostPull, hctx, nodes)
+}
+
+// runHooks runs hooks in order. The runs of a node scoped hook on each of the nodes are
+// concurrent. Failed hooks are reported by a warning event; the first failed pre-pull
+// hook with the Fail failure policy stops the run and is returned.
+func (c *Controller) runHooks(imageCache *v1alpha2.ImageCache, hooks []v1alpha2.ImageCacheHook, hctx HookContext, nodes []*corev1.Node) error {
+	reason := v1alpha2.ImageCacheReasonPostPullHookFailed
+	if hctx.Phase == HookPhasePrePull {
+		reason = v1alpha2.ImageCacheReasonPrePullHookFailed
+	}
+	for _, hook := range hooks {
+		var err error
+		if hook.Scope == v1alpha2.HookScopeNode {
+			err = c.runNodeHook(imageCache, hook, hctx, nodes)
+		} else {
+			err = c.runHook(imageCache, hook, hctx, nil)
+		}
+		if err == nil {
+			glog.Infof("%s hook %s of image cache %s succeeded", hctx.Phase, hook.Name, hctx.ImageCache)
+			continue
+		}
+		err = fmt.Errorf("%s hook %s failed: %v", hctx.Phase, hook.Name, err)
+		glog.Errorf("Image cache %s: %v", hctx.ImageCache, err)
+		c.recorder.Event(imageCache, corev1.EventTypeWarning, reason, err.Error())
+		if hctx.Phase == HookPhasePrePull && hook.FailurePolicy != v1alpha2.HookFailurePolicyIgnore {
+			return err
+		}
+	}
+	return nil
+}
+
+// runNodeHook runs a node scoped hook on all the nodes, and returns the failures
+func (c *Controller) runNodeHook(imageCache *v1alpha2.ImageCache, hook v1alpha2.ImageCacheHook, hctx HookContext, nodes []*corev1.Node) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(nodes))
+	for i := range nodes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = c.runHook(imageCache, hook, hctx, nodes[i])
+		}(i)
+	}
+	wg.Wait()
+	failures := []string{}
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("node %s: %v", nodes[i].Name, err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// runHook runs a hook once, on a node if not nil
+func (c *Controller) runHook(imageCache *v1alpha2.ImageCache, hook v1alpha2.ImageCacheHook, hctx HookContext, node *corev1.Node) error {
+	timeout := defaultHookTimeout
+	if hook.Timeout != nil {
+		timeout = hook.Timeout.Duration
+	}
+	if node != nil {
+		hctx.Node = node.Name
+	}
+	if hook.HTTP != nil {
+		if !hookURLAllowed(hook.HTTP.URL, c.hookAllowedURLs) {
+			return fmt.Errorf("URL %s not allowed by --hook-allowed-urls", hook.HTTP.URL)
+		}
+		return postHook(hook.HTTP.URL, hctx, timeout)
+	}
+	if hook.Exec != nil {
+		if !hookImageAllowed(hook.Exec.Image, c.hookAllowedImages) {
+			return fmt.Errorf("image %s not allowed by --hook-allowed-images", hook.Exec.Image)
+		}
+		return c.execHook(imageCache, hook, hctx, node, timeout)
+	}
+	return fmt.Errorf("neither exec nor http specified")
+}
+
+// validateHookAllowedURL returns an error if a URL allowed for http hooks is not an
+// absolute http or https URL
+func validateHookAllowedURL(allowed string) error {
+	u, err := url.Parse(allowed)
+	if err != nil {
+		return fmt.Errorf("invalid hook URL %q: %v", allowed, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return fmt.Errorf("invalid hook URL %q: expected http(s)://host[:port][/path]", allowed)
+	}
+	return nil
+}
+
+// hookURLAllowed returns true if the URL of an http hook has the scheme and the host of
+// an allowed URL, and its path is the path of the allowed URL or lies under it. Paths
+// are cleaned first, so that "/hooks" allows neither "/hooks-evil" nor
+// "/hooks/../admin". URLs with user information are refused.
+func hookURLAllowed(hookURL string, allowedURLs []string) bool {
+	u, err := url.Parse(hookURL)
+	if err != nil || u.User != nil || u.Host == "" {
+		return false
+	}
+	hookPath := cleanURLPath(u.Path)
+	for _, allowed := range allowedURLs {
+		a, err := url.Parse(allowed)
+		if err != nil || a.Scheme != u.Scheme || !strings.EqualFold(a.Host, u.Host) {
+			continue
+		}
+		allowedPath := cleanURLPath(a.Path)
+		if hookPath == allowedPath || strings.HasPrefix(hookPath, strings.TrimSuffix(allowedPath, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanURLPath returns the shortest path equivalent to the path of a URL, "/" if empty
+func cleanURLPath(p string) string {
+	return path.Clean("/" + p)
+}
+
+// hookImageAllowed returns true if the image of an exec hook is an allowed image, or
+// an image of the repository of an allowed image without a tag or digest
+func hookImageAllowed(image string, allowedImages []string) bool {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range allowedImages {
+		a, err := reference.ParseNormalizedNamed(allowed)
+		if err != nil || a.Name() != named.Name() {
+			continue
+		}
+		if !reference.IsNameOnly(a) {
+			if a.String() != named.String() {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// hookTransport is the transport of the requests of http hooks
+var hookTransport = func() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	return transport
+}()
+
+// newHookClient returns the client of the requests of http hooks. Redirects are not
+// followed, so that a hook cannot be redirected to a URL which is not allowed.
+func newHookClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: hookTransport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// postHook posts the context of a hook to its URL
+func postHook(hookURL string, hctx HookContext, timeout time.Duration) error {
+	data, err := json.Marshal(hctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hookURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := newHookClient(timeout).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", hookURL, resp.Status)
+	}
+	return nil
+}
+
+// execHook runs the command of a hook in a job, and waits for the job to complete. The
+// job is deleted once completed or timed out.
+func (c *Controller) execHook(imageCache *v1alpha2.ImageCache, hook v1alpha2.ImageCacheHook, hctx HookContext, node *corev1.Node, timeout time.Duration) error {
+	job, err := c.kubeclientset.BatchV1().Jobs(imageCache.Namespace).Create(context.TODO(),
+		newHookJob(imageCache, hook, hctx, node, timeout), metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("error creating job: %v", err)
+	}
+	defer func() {
+		deletePropagation := metav1.DeletePropagationBackground
+		if err := c.kubeclientset.BatchV1().Jobs(job.Namespace).Delete(context.TODO(), job.Name,
+			metav1.DeleteOptions{PropagationPolicy: &deletePropagation}); err != nil {
+			glog.Errorf("Error deleting hook job %s: %v", job.Name, err)
+		}
+	}()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		// The channel is taken before reading the job, so that the changes of the job
+		// in the meantime are not missed
+		changed := c.hookJobChangeCh()
+		// The job is not found until the informer receives it
+		if current, err := c.hookJobsLister.Jobs(job.Namespace).Get(job.Name); err == nil {
+			if current.Status.Succeeded > 0 {
+				return nil
+			}
+			if current.Status.Failed > 0 {
+				return fmt.Errorf("job %s failed", job.Name)
+			}
+		}
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return fmt.Errorf("job %s did not complete within %s", job.Name, timeout)
+		}
+	}
+}
+
+// watchHookJobs sets up the informer of the jobs of exec hooks, waking up the exec
+// hooks waiting for their job whenever a hook job changes
+func (c *Controller) watchHookJobs() {
+	c.hookJobChange = make(chan struct{})
+	c.hookJobInformerFactory = kubeinformers.NewSharedInformerFactoryWithOptions(c.kubeclientset, time.Second*30,
+		kubeinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = labels.Set{"app": "kubefledged", "kubefledged": "kubefledged-hook"}.String()
+		}))
+	informer := c.hookJobInformerFactory.Batch().V1().Jobs()
+	c.hookJobsLister = informer.Lister()
+	c.hookJobsSynced = informer.Informer().HasSynced
+	informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.notifyHookJobChange()
+		},
+		UpdateFunc: func(old, new interface{}) {
+			c.notifyHookJobChange()
+		},
+	})
+}
+
+// hookJobChangeCh returns the channel closed on the next change of a hook job
+func (c *Controller) hookJobChangeCh() <-chan struct{} {
+	c.hookJobChangeLock.Lock()
+	defer c.hookJobChangeLock.Unlock()
+	return c.hookJobChange
+}
+
+// notifyHookJobChange wakes up the exec hooks waiting for their job
+func (c *Controller) notifyHookJobChange() {
+	c.hookJobChangeLock.Lock()
+	defer c.hookJobChangeLock.Unlock()
+	close(c.hookJobChange)
+	c.hookJobChange = make(chan struct{})
+}
+
+// newHookJob constructs the job of an exec hook. The job of a node scoped hook runs on
+// its node. Its pods run with the restricted security context of the image pull jobs.
+func newHookJob(imageCache *v1alpha2.ImageCache, hook v1alpha2.ImageCacheHook, hctx HookContext, node *corev1.Node, timeout time.Duration) *batchv1.Job {
+	labels := map[string]string{
+		"app":         "kubefledged",
+		"kubefledged": "kubefledged-hook",
+		"imagecache":  imageCache.Name,
+		"controller":  controllerAgentName,
+	}
+	backoffLimit := int32(0)
+	activeDeadlineSeconds := int64(timeout.Seconds())
+	if activeDeadlineSeconds < 1 {
+		activeDeadlineSeconds = 1
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: imageCache.Name + "-hook-",
+			Namespace:    imageCache.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(imageCache, schema.GroupVersionKind{
+					Group:   v1alpha2.SchemeGroupVersion.Group,
+					Version: v1alpha2.SchemeGroupVersion.Version,
+					Kind:    "ImageCache",
+				}),
+			},
+			Labels: labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoffLimit,
+			ActiveDeadlineSeconds: &activeDeadlineSeconds,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: imageCache.Namespace,
+					Labels:    labels,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:    "hook",
+							Image:   hook.Exec.Image,
+							Command: hook.Exec.Command,
+							Env: []corev1.EnvVar{
+								{Name: "HOOK_PHASE", Value: hctx.Phase},
+								{Name: "HOOK_ACTION", Value: hctx.Action},
+								{Name: "HOOK_IMAGECACHE", Value: hctx.ImageCache},
+								{Name: "HOOK_NODE", Value: hctx.Node},
+								{Name: "HOOK_STATUS", Value: string(hctx.Status)},
+							},
+						},
+					},
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ImagePullSecrets:   imageCache.Spec.ImagePullSecrets,
+					ServiceAccountName: imageCache.Spec.ServiceAccountName,
+				},
+			},
+		},
+	}
+	if node != nil {
+		job.Spec.Template.Spec.NodeSelector = map[string]string{"kubernetes.io/hostname": node.Labels["kubernetes.io/hostname"]}
+	}
+	images.SetRestrictedSecurityContext(&job.Spec.Template.Spec)
+	return job
+}
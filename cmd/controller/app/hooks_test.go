@@ -0,0 +1,426 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	"github.com/senthilrch/kube-fledged/pkg/features"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	core "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/component-base/featuregate"
+)
+
+// enablePullHooks enables the PullHooks feature gate for the duration of a test
+func enablePullHooks(t *testing.T) {
+	enableFeatures(t, features.PullHooks)
+}
+
+// enableFeatures enables alpha feature gates for the duration of a test
+func enableFeatures(t *testing.T, gates ...featuregate.Feature) {
+	enabled, disabled := map[string]bool{}, map[string]bool{}
+	for _, gate := range gates {
+		enabled[string(gate)], disabled[string(gate)] = true, false
+	}
+	if err := features.DefaultMutableFeatureGate.SetFromMap(enabled); err != nil {
+		t.Fatalf("Error enabling feature gates: %v", err)
+	}
+	t.Cleanup(func() {
+		features.DefaultMutableFeatureGate.SetFromMap(disabled)
+	})
+}
+
+func TestRunHooks(t *testing.T) {
+	enablePullHooks(t)
+	var lock sync.Mutex
+	received := []HookContext{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var hctx HookContext
+		if err := json.NewDecoder(r.Body).Decode(&hctx); err != nil {
+			t.Errorf("Error decoding hook context: %v", err)
+		}
+		lock.Lock()
+		received = append(received, hctx)
+		lock.Unlock()
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	nodes := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
+	}
+	workItems := []imageWorkItem{
+		{image: "foo:v1", node: nodes[0]}, {image: "bar:v1", node: nodes[0]}, {image: "foo:v1", node: nodes[1]},
+	}
+	ok := &kubefledgedv1alpha2.HTTPHook{URL: server.URL + "/ok"}
+	fail := &kubefledgedv1alpha2.HTTPHook{URL: server.URL + "/fail"}
+	tests := []struct {
+		name          string
+		hooks         []kubefledgedv1alpha2.ImageCacheHook
+		expectErr     bool
+		expectedNodes []string
+	}{
+		{
+			name:          "#1: Cache scoped hook",
+			hooks:         []kubefledgedv1alpha2.ImageCacheHook{{Name: "notify", HTTP: ok}},
+			expectedNodes: []string{""},
+		},
+		{
+			name:          "#2: Node scoped hook runs once per node",
+			hooks:         []kubefledgedv1alpha2.ImageCacheHook{{Name: "drain", Scope: kubefledgedv1alpha2.HookScopeNode, HTTP: ok}},
+			expectedNodes: []string{"node1", "node2"},
+		},
+		{
+			name:          "#3: Failed hook stops the run",
+			hooks:         []kubefledgedv1alpha2.ImageCacheHook{{Name: "drain", HTTP: fail}, {Name: "notify", HTTP: ok}},
+			expectErr:     true,
+			expectedNodes: []string{""},
+		},
+		{
+			name: "#4: Failed hook ignored",
+			hooks: []kubefledgedv1alpha2.ImageCacheHook{
+				{Name: "drain", HTTP: fail, FailurePolicy: kubefledgedv1alpha2.HookFailurePolicyIgnore}, {Name: "notify", HTTP: ok},
+			},
+			expectedNodes: []string{"", ""},
+		},
+	}
+	for _, test := range tests {
+		received = []HookContext{}
+		controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+		controller.hookAllowedURLs = []string{server.URL + "/"}
+		imageCache := &kubefledgedv1alpha2.ImageCache{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+			Spec:       kubefledgedv1alpha2.ImageCacheSpec{Hooks: &kubefledgedv1alpha2.ImageCacheHooks{PrePull: test.hooks}},
+		}
+		err := controller.runPrePullHooks(imageCache, images.ImageCacheCreate, workItems)
+		if (err != nil) != test.expectErr {
+			t.Errorf("Test: %s failed: expectErr=%t, actual error=%v", test.name, test.expectErr, err)
+		}
+		actualNodes := []string{}
+		for _, hctx := range received {
+			if hctx.Phase != HookPhasePrePull || hctx.Action != kubefledgedv1alpha2.ImageCacheReasonImageCacheCreate ||
+				hctx.ImageCache != fledgedNameSpace+"/foo" {
+				t.Errorf("Test: %s failed: unexpected hook context %+v", test.name, hctx)
+			}
+			actualNodes = append(actualNodes, hctx.Node)
+		}
+		sort.Strings(actualNodes)
+		if !reflect.DeepEqual(actualNodes, test.expectedNodes) {
+			t.Errorf("Test: %s failed: expected hooks run for nodes %v, actual %v", test.name, test.expectedNodes, actualNodes)
+		}
+	}
+
+	// Post-pull hooks never fail the action
+	received = []HookContext{}
+	controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	controller.hookAllowedURLs = []string{server.URL + "/"}
+	imageCache := &kubefledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+		Spec: kubefledgedv1alpha2.ImageCacheSpec{Hooks: &kubefledgedv1alpha2.ImageCacheHooks{
+			PostPull: []kubefledgedv1alpha2.ImageCacheHook{{Name: "drain", HTTP: fail}, {Name: "notify", Scope: kubefledgedv1alpha2.HookScopeNode, HTTP: ok}},
+		}},
+	}
+	results := map[string]images.ImageWorkResult{
+		"job1": {ImageWorkRequest: images.ImageWorkRequest{Node: nodes[1]}},
+		"job2": {ImageWorkRequest: images.ImageWorkRequest{Node: nodes[1]}},
+	}
+	controller.runPostPullHooks(imageCache, kubefledgedv1alpha2.ImageCacheReasonImageCacheRefresh, kubefledgedv1alpha2.ImageCacheActionStatusSucceeded, results)
+	if len(received) != 2 || received[1].Node != "node2" || received[1].Status != kubefledgedv1alpha2.ImageCacheActionStatusSucceeded {
+		t.Errorf("expected post-pull hooks to run, actual hook contexts %+v", received)
+	}
+}
+
+func TestRunHooksFeatureGateDisabled(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+	controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	imageCache := &kubefledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+		Spec: kubefledgedv1alpha2.ImageCacheSpec{Hooks: &kubefledgedv1alpha2.ImageCacheHooks{
+			PrePull: []kubefledgedv1alpha2.ImageCacheHook{{Name: "notify", HTTP: &kubefledgedv1alpha2.HTTPHook{URL: server.URL}}},
+		}},
+	}
+	if err := controller.runPrePullHooks(imageCache, images.ImageCacheCreate, nil); err != nil || called {
+		t.Errorf("expected hooks not to run without the %s feature gate, error=%v", features.PullHooks, err)
+	}
+}
+
+func TestExecHook(t *testing.T) {
+	enablePullHooks(t)
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"kubernetes.io/hostname": "node1"}}}
+	tests := []struct {
+		name          string
+		image         string
+		jobStatus     batchv1.JobStatus
+		completeLater bool
+		expectErr     bool
+		expectCreated bool
+	}{
+		{
+			name:          "#1: Hook job succeeded",
+			image:         "busybox:1.36",
+			jobStatus:     batchv1.JobStatus{Succeeded: 1},
+			expectCreated: true,
+		},
+		{
+			name:          "#2: Hook job failed",
+			image:         "busybox:1.36",
+			jobStatus:     batchv1.JobStatus{Failed: 1},
+			expectErr:     true,
+			expectCreated: true,
+		},
+		{
+			name:          "#3: Hook job succeeded after it was created",
+			image:         "busybox:1.36",
+			jobStatus:     batchv1.JobStatus{Succeeded: 1},
+			completeLater: true,
+			expectCreated: true,
+		},
+		{
+			name:      "#4: Hook image not allowed",
+			image:     "evil.io/miner:latest",
+			jobStatus: batchv1.JobStatus{Succeeded: 1},
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		var created *batchv1.Job
+		deleted := false
+		// The hook jobs are read from the informer of the hook jobs
+		hookJobs := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		fakekubeclientset := &fakeclientset.Clientset{}
+		controller, _, _ := newTestController(fakekubeclientset, kubefledgedclientsetfake.NewSimpleClientset())
+		controller.hookJobsLister = batchlisters.NewJobLister(hookJobs)
+		fakekubeclientset.AddReactor("create", "jobs", func(action core.Action) (bool, runtime.Object, error) {
+			created = action.(core.CreateAction).GetObject().(*batchv1.Job)
+			created.Name = "foo-hook-1"
+			job := created.DeepCopy()
+			if !test.completeLater {
+				job.Status = test.jobStatus
+			}
+			hookJobs.Add(job)
+			if test.completeLater {
+				go func() {
+					time.Sleep(100 * time.Millisecond)
+					job := job.DeepCopy()
+					job.Status = test.jobStatus
+					hookJobs.Update(job)
+					controller.notifyHookJobChange()
+				}()
+			}
+			return true, created, nil
+		})
+		fakekubeclientset.AddReactor("delete", "jobs", func(action core.Action) (bool, runtime.Object, error) {
+			deleted = true
+			return true, nil, nil
+		})
+		controller.hookAllowedImages = []string{"busybox"}
+		imageCache := &kubefledgedv1alpha2.ImageCache{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+			Spec: kubefledgedv1alpha2.ImageCacheSpec{Hooks: &kubefledgedv1alpha2.ImageCacheHooks{
+				PrePull: []kubefledgedv1alpha2.ImageCacheHook{{
+					Name:  "drain",
+					Scope: kubefledgedv1alpha2.HookScopeNode,
+					Exec:  &kubefledgedv1alpha2.ExecHook{Image: test.image, Command: []string{"sh", "-c", "drain-mirror"}},
+				}},
+			}},
+		}
+		err := controller.runPrePullHooks(imageCache, images.ImageCachePurge, []imageWorkItem{{image: "foo:v1", node: node}})
+		if (err != nil) != test.expectErr {
+			t.Errorf("Test: %s failed: expectErr=%t, actual error=%v", test.name, test.expectErr, err)
+		}
+		if (created != nil) != test.expectCreated {
+			t.Errorf("Test: %s failed: expectCreated=%t, actual hook job %+v", test.name, test.expectCreated, created)
+		}
+		if created == nil {
+			continue
+		}
+		podSpec := created.Spec.Template.Spec
+		if podSpec.NodeSelector["kubernetes.io/hostname"] != "node1" || podSpec.Containers[0].Image != "busybox:1.36" ||
+			podSpec.Containers[0].Env[0].Value != HookPhasePrePull || podSpec.Containers[0].Env[3].Value != "node1" {
+			t.Errorf("Test: %s failed: unexpected hook job %+v", test.name, podSpec)
+		}
+		if len(podSpec.Tolerations) != 0 || podSpec.SecurityContext == nil || podSpec.SecurityContext.RunAsNonRoot == nil ||
+			!*podSpec.SecurityContext.RunAsNonRoot || podSpec.Containers[0].SecurityContext == nil {
+			t.Errorf("Test: %s failed: expected restricted hook job without tolerations, actual %+v", test.name, podSpec)
+		}
+		if !deleted {
+			t.Errorf("Test: %s failed: expected hook job to be deleted", test.name)
+		}
+	}
+}
+
+func TestHookImageAllowed(t *testing.T) {
+	allowedImages := []string{"busybox", "registry.local/tools/drain:v1"}
+	tests := []struct {
+		name     string
+		image    string
+		expected bool
+	}{
+		{name: "#1: Tag of an allowed repository", image: "docker.io/library/busybox:1.36", expected: true},
+		{name: "#2: Allowed image", image: "registry.local/tools/drain:v1", expected: true},
+		{name: "#3: Other tag of an allowed image", image: "registry.local/tools/drain:v2"},
+		{name: "#4: Repository with an allowed repository as prefix", image: "busybox-evil:1.36"},
+		{name: "#5: Other repository", image: "evil.io/miner:latest"},
+		{name: "#6: Invalid image", image: "Busybox"},
+	}
+	for _, test := range tests {
+		if allowed := hookImageAllowed(test.image, allowedImages); allowed != test.expected {
+			t.Errorf("Test: %s failed: expected allowed=%t, actual %t", test.name, test.expected, allowed)
+		}
+	}
+	if hookImageAllowed("busybox:1.36", nil) {
+		t.Errorf("expected exec hooks to be refused without allowed images")
+	}
+}
+
+func TestHookURLAllowed(t *testing.T) {
+	allowedURLs := []string{"https://cmdb.local/hooks/", "http://notifier.tools.svc:8080"}
+	tests := []struct {
+		name     string
+		url      string
+		expected bool
+	}{
+		{name: "#1: URL under an allowed path", url: "https://cmdb.local/hooks/kubefledged", expected: true},
+		{name: "#2: URL of an allowed host", url: "http://notifier.tools.svc:8080/notify", expected: true},
+		{name: "#3: URL outside the allowed path", url: "https://cmdb.local/admin"},
+		{name: "#4: Other scheme", url: "http://cmdb.local/hooks/kubefledged"},
+		{name: "#5: Host with an allowed host as prefix", url: "https://cmdb.local.evil.com/hooks/kubefledged"},
+		{name: "#6: Other port", url: "http://notifier.tools.svc:9090/notify"},
+		{name: "#7: Metadata endpoint", url: "http://169.254.169.254/latest/meta-data/"},
+		{name: "#8: URL with user information", url: "https://admin@cmdb.local/hooks/kubefledged"},
+		{name: "#9: Path with an allowed path as prefix", url: "https://cmdb.local/hooks-evil"},
+		{name: "#10: Path escaping the allowed path", url: "https://cmdb.local/hooks/../admin"},
+		{name: "#11: Allowed path", url: "https://cmdb.local/hooks", expected: true},
+		{name: "#12: Path with dot segments under the allowed path", url: "https://cmdb.local/hooks/./a/../kubefledged", expected: true},
+	}
+	for _, test := range tests {
+		if allowed := hookURLAllowed(test.url, allowedURLs); allowed != test.expected {
+			t.Errorf("Test: %s failed: expected allowed=%t, actual %t", test.name, test.expected, allowed)
+		}
+	}
+	if hookURLAllowed("https://cmdb.local/hooks/kubefledged", nil) {
+		t.Errorf("expected http hooks to be refused without allowed URLs")
+	}
+}
+
+func TestPostHookRedirect(t *testing.T) {
+	redirected := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			redirected = true
+			return
+		}
+		http.Redirect(w, r, "/internal", http.StatusFound)
+	}))
+	defer server.Close()
+	if err := postHook(server.URL+"/hook", HookContext{Phase: HookPhasePrePull}, time.Second); err == nil || redirected {
+		t.Errorf("expected redirect to be refused, actual error=%v, redirected=%t", err, redirected)
+	}
+}
+
+func TestRunPrePullHooksAndDispatch(t *testing.T) {
+	enablePullHooks(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	tests := []struct {
+		name              string
+		url               string
+		expectedStatus    kubefledgedv1alpha2.ImageCacheActionStatus
+		expectedReason    string
+		expectedWorkItems int
+	}{
+		{
+			name:              "#1: Image work queued once the hooks succeeded",
+			url:               server.URL + "/ok",
+			expectedStatus:    kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+			expectedReason:    kubefledgedv1alpha2.ImageCacheReasonImageCacheCreate,
+			expectedWorkItems: 2,
+		},
+		{
+			name:           "#2: Action failed by a failed hook",
+			url:            server.URL + "/fail",
+			expectedStatus: kubefledgedv1alpha2.ImageCacheActionStatusFailed,
+			expectedReason: kubefledgedv1alpha2.ImageCacheReasonPrePullHookFailed,
+		},
+	}
+	for _, test := range tests {
+		imageCache := &kubefledgedv1alpha2.ImageCache{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+			Spec: kubefledgedv1alpha2.ImageCacheSpec{Hooks: &kubefledgedv1alpha2.ImageCacheHooks{
+				PrePull: []kubefledgedv1alpha2.ImageCacheHook{{Name: "notify", HTTP: &kubefledgedv1alpha2.HTTPHook{URL: test.url}}},
+			}},
+		}
+		fledgedclientset := kubefledgedclientsetfake.NewSimpleClientset(imageCache)
+		controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), fledgedclientset)
+		controller.hookAllowedURLs = []string{server.URL}
+		status := kubefledgedv1alpha2.ImageCacheStatus{
+			Status:  kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+			Reason:  kubefledgedv1alpha2.ImageCacheReasonImageCacheCreate,
+			Message: kubefledgedv1alpha2.ImageCacheMessagePrePullHooksPending,
+		}
+		wqKey := images.WorkQueueKey{WorkType: images.ImageCacheCreate, ObjKey: fledgedNameSpace + "/foo"}
+		controller.runPrePullHooksAndDispatch(imageCache, wqKey, []imageWorkItem{{image: "foo:v1", node: node, workType: images.ImageCacheCreate}},
+			status, kubefledgedv1alpha2.ImageCacheMessagePullingImages, time.Minute)
+		updated, err := fledgedclientset.KubefledgedV1alpha2().ImageCaches(fledgedNameSpace).Get(context.TODO(), "foo", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Test: %s failed: unexpected error: %v", test.name, err)
+		}
+		if updated.Status.Status != test.expectedStatus || updated.Status.Reason != test.expectedReason ||
+			updated.Status.Message == kubefledgedv1alpha2.ImageCacheMessagePrePullHooksPending {
+			t.Errorf("Test: %s failed: unexpected status %+v", test.name, updated.Status)
+		}
+		// The image work request is followed by the request signalling the end of the work.
+		// The requests are rate limited, i.e. added to the queue after a short delay
+		imageworkqueue := controller.imageworkqueues.For(images.ImageCacheCreate, false)
+		wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+			return imageworkqueue.Len() == test.expectedWorkItems, nil
+		})
+		if queued := imageworkqueue.Len(); queued != test.expectedWorkItems {
+			t.Errorf("Test: %s failed: expected %d image work requests, actual %d", test.name, test.expectedWorkItems, queued)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/senthilrch/kube-fledged/pkg/imagelist"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// hpaPrewarmAnnotationKey opts the scale target of a HorizontalPodAutoscaler into
+	// pre-warming ahead of its scale-ups when set to "true" on the autoscaler
+	hpaPrewarmAnnotationKey = "fledged.k8s.io/prewarm-on-scale-up"
+	// hpaPrewarmImageCacheName is the name of the image cache managed by the HPA
+	// pre-warm, in the namespace of the controller
+	hpaPrewarmImageCacheName = "kubefledged-hpa-prewarm"
+	// hpaPrewarmLabelKey labels the image cache managed by the HPA pre-warm
+	hpaPrewarmLabelKey = "kubefledged.io/hpa-prewarm"
+	// ReasonHPAPrewarmImagesUpdated is used as part of the Event 'reason' when the
+	// images of the image cache managed by the HPA pre-warm are updated
+	ReasonHPAPrewarmImagesUpdated = "HPAPrewarmImagesUpdated"
+)
+
+// hpaPrewarmImageCache is the image cache managed by the HPA pre-warm. Its images are
+// only pulled to the nodes which do not hold them yet
+var hpaPrewarmImageCache = managedImageCache{
+	name:            hpaPrewarmImageCacheName,
+	labelKey:        hpaPrewarmLabelKey,
+	manager:         "HPA pre-warm",
+	imagePullPolicy: corev1.PullIfNotPresent,
+	updatedReason:   ReasonHPAPrewarmImagesUpdated,
+	updatedMessage:  "Images of the workloads approaching an HPA scale-up updated",
+}
+
+// runHPAPrewarmWorker warms the images of the workloads whose HorizontalPodAutoscaler,
+// annotated with fledged.k8s.io/prewarm-on-scale-up="true", approaches a scale-up, on
+// the nodes which lack them, so that the pods added by the scale-up start immediately.
+// The images of a workload stay in the image cache managed by the HPA pre-warm as long
+// as its autoscaler is annotated.
+func (c *Controller) runHPAPrewarmWorker() {
+	podSpecs, err := c.hpaPrewarmPodSpecs()
+	if err != nil {
+		glog.Errorf("Error listing workloads to pre-warm ahead of HPA scale-ups: %v", err)
+		return
+	}
+	cacheSpec := autoWarmCacheSpec(podSpecs, imagelist.ContainerSelection{InitContainers: true}, c.defaultNodeOS)
+	if err := c.syncManagedImageCache(hpaPrewarmImageCache, cacheSpec); err != nil {
+		glog.Errorf("Error syncing image cache %s/%s of HPA pre-warm: %v", c.fledgedNameSpace, hpaPrewarmImageCacheName, err)
+	}
+}
+
+// hpaPrewarmPodSpecs returns the pod templates of the Deployments and StatefulSets scaled
+// by annotated autoscalers which approach a scale-up, or whose images are already in
+// the image cache managed by the HPA pre-warm
+func (c *Controller) hpaPrewarmPodSpecs() ([]corev1.PodSpec, error) {
+	hpas, err := c.kubeclientset.AutoscalingV2().HorizontalPodAutoscalers("").List(context.TODO(), metav1.ListOptions{ResourceVersion: "0"})
+	if err != nil {
+		return nil, fmt.Errorf("error listing horizontalpodautoscalers: %v", err)
+	}
+	warmed := map[string]bool{}
+	if imageCache, err := c.imageCachesLister.ImageCaches(c.fledgedNameSpace).Get(hpaPrewarmImageCacheName); err == nil {
+		for _, i := range imageCache.Spec.CacheSpec {
+			for _, image := range i.Images {
+				warmed[image] = true
+			}
+		}
+	}
+	podSpecs := []corev1.PodSpec{}
+	for i := range hpas.Items {
+		hpa := &hpas.Items[i]
+		if hpa.Annotations[hpaPrewarmAnnotationKey] != "true" {
+			continue
+		}
+		podSpec, err := c.scaleTargetPodSpec(hpa)
+		if err != nil {
+			glog.Errorf("Error getting the scale target of horizontalpodautoscaler %s/%s: %v", hpa.Namespace, hpa.Name, err)
+			continue
+		}
+		if podSpec == nil {
+			continue
+		}
+		if hpaApproachingScaleUp(hpa, c.hpaPrewarmThreshold) {
+			glog.V(4).Infof("Horizontalpodautoscaler %s/%s approaching a scale-up of %s %s", hpa.Namespace, hpa.Name,
+				hpa.Spec.ScaleTargetRef.Kind, hpa.Spec.ScaleTargetRef.Name)
+			podSpecs = append(podSpecs, *podSpec)
+			continue
+		}
+		for _, image := range imagelist.PodSpecImages(*podSpec, imagelist.ContainerSelection{InitContainers: true}) {
+			if warmed[image] {
+				podSpecs = append(podSpecs, *podSpec)
+				break
+			}
+		}
+	}
+	return podSpecs, nil
+}
+
+// scaleTargetPodSpec returns the pod template of the Deployment or StatefulSet scaled by
+// an autoscaler, or nil if the autoscaler scales another kind of resource or its target
+// does not exist
+func (c *Controller) scaleTargetPodSpec(hpa *autoscalingv2.HorizontalPodAutoscaler) (*corev1.PodSpec, error) {
+	target := hpa.Spec.ScaleTargetRef
+	if target.APIVersion != "apps/v1" {
+		return nil, nil
+	}
+	switch target.Kind {
+	case "Deployment":
+		d, err := c.kubeclientset.AppsV1().Deployments(hpa.Namespace).Get(context.TODO(), target.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &d.Spec.Template.Spec, nil
+	case "StatefulSet":
+		s, err := c.kubeclientset.AppsV1().StatefulSets(hpa.Namespace).Get(context.TODO(), target.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &s.Spec.Template.Spec, nil
+	}
+	return nil, nil
+}
+
+// hpaApproachingScaleUp returns true if an autoscaler may still add replicas to its
+// target and is about to: it already wants more replicas than its target has, or one of
+// its metrics reached the threshold, in percent, of its target value
+func hpaApproachingScaleUp(hpa *autoscalingv2.HorizontalPodAutoscaler, threshold int) bool {
+	if hpa.Status.CurrentReplicas >= hpa.Spec.MaxReplicas {
+		return false
+	}
+	if hpa.Status.DesiredReplicas > hpa.Status.CurrentReplicas {
+		return true
+	}
+	// The current metrics are reported in the order of the metrics of the spec
+	for i, metric := range hpa.Spec.Metrics {
+		if i >= len(hpa.Status.CurrentMetrics) || hpa.Status.CurrentMetrics[i].Type != metric.Type {
+			continue
+		}
+		target, current := metricTarget(metric), metricCurrent(hpa.Status.CurrentMetrics[i])
+		if target != nil && current != nil && metricReached(*target, *current, threshold) {
+			return true
+		}
+	}
+	return false
+}
+
+// metricTarget returns the target value of a metric of an autoscaler
+func metricTarget(metric autoscalingv2.MetricSpec) *autoscalingv2.MetricTarget {
+	switch {
+	case metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil:
+		return &metric.Resource.Target
+	case metric.Type == autoscalingv2.ContainerResourceMetricSourceType && metric.ContainerResource != nil:
+		return &metric.ContainerResource.Target
+	case metric.Type == autoscalingv2.PodsMetricSourceType && metric.Pods != nil:
+		return &metric.Pods.Target
+	case metric.Type == autoscalingv2.ObjectMetricSourceType && metric.Object != nil:
+		return &metric.Object.Target
+	case metric.Type == autoscalingv2.ExternalMetricSourceType && metric.External != nil:
+		return &metric.External.Target
+	}
+	return nil
+}
+
+// metricCurrent returns the current value of a metric of an autoscaler
+func metricCurrent(metric autoscalingv2.MetricStatus) *autoscalingv2.MetricValueStatus {
+	switch {
+	case metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil:
+		return &metric.Resource.Current
+	case metric.Type == autoscalingv2.ContainerResourceMetricSourceType && metric.ContainerResource != nil:
+		return &metric.ContainerResource.Current
+	case metric.Type == autoscalingv2.PodsMetricSourceType && metric.Pods != nil:
+		return &metric.Pods.Current
+	case metric.Type == autoscalingv2.ObjectMetricSourceType && metric.Object != nil:
+		return &metric.Object.Current
+	case metric.Type == autoscalingv2.ExternalMetricSourceType && metric.External != nil:
+		return &metric.External.Current
+	}
+	return nil
+}
+
+// metricReached returns true if the current value of a metric reached the threshold,
+// in percent, of its target value
+func metricReached(target autoscalingv2.MetricTarget, current autoscalingv2.MetricValueStatus, threshold int) bool {
+	switch {
+	case target.AverageUtilization != nil && current.AverageUtilization != nil:
+		return int64(*current.AverageUtilization)*100 >= int64(threshold)*int64(*target.AverageUtilization)
+	case target.AverageValue != nil && current.AverageValue != nil:
+		return current.AverageValue.MilliValue()*100 >= int64(threshold)*target.AverageValue.MilliValue()
+	case target.Value != nil && current.Value != nil:
+		return current.Value.MilliValue()*100 >= int64(threshold)*target.Value.MilliValue()
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func cpuUtilizationHPA(current, desired, max, currentUtilization, targetUtilization int32) *autoscalingv2.HorizontalPodAutoscaler {
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			MaxReplicas: max,
+			Metrics: []autoscalingv2.MetricSpec{{
+				Type: autoscalingv2.ResourceMetricSourceType,
+				Resource: &autoscalingv2.ResourceMetricSource{Name: corev1.ResourceCPU,
+					Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &targetUtilization}},
+			}},
+		},
+		Status: autoscalingv2.HorizontalPodAutoscalerStatus{
+			CurrentReplicas: current,
+			DesiredReplicas: desired,
+			CurrentMetrics: []autoscalingv2.MetricStatus{{
+				Type: autoscalingv2.ResourceMetricSourceType,
+				Resource: &autoscalingv2.ResourceMetricStatus{Name: corev1.ResourceCPU,
+					Current: autoscalingv2.MetricValueStatus{AverageUtilization: &currentUtilization}},
+			}},
+		},
+	}
+}
+
+func TestHPAApproachingScaleUp(t *testing.T) {
+	externalHPA := func(current, target string) *autoscalingv2.HorizontalPodAutoscaler {
+		targetValue, currentValue := resource.MustParse(target), resource.MustParse(current)
+		return &autoscalingv2.HorizontalPodAutoscaler{
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				MaxReplicas: 10,
+				Metrics: []autoscalingv2.MetricSpec{{
+					Type: autoscalingv2.ExternalMetricSourceType,
+					External: &autoscalingv2.ExternalMetricSource{Metric: autoscalingv2.MetricIdentifier{Name: "queue_depth"},
+						Target: autoscalingv2.MetricTarget{Type: autoscalingv2.ValueMetricType, Value: &targetValue}},
+				}},
+			},
+			Status: autoscalingv2.HorizontalPodAutoscalerStatus{
+				CurrentReplicas: 2,
+				DesiredReplicas: 2,
+				CurrentMetrics: []autoscalingv2.MetricStatus{{
+					Type: autoscalingv2.ExternalMetricSourceType,
+					External: &autoscalingv2.ExternalMetricStatus{Metric: autoscalingv2.MetricIdentifier{Name: "queue_depth"},
+						Current: autoscalingv2.MetricValueStatus{Value: &currentValue}},
+				}},
+			},
+		}
+	}
+	tests := []struct {
+		name     string
+		hpa      *autoscalingv2.HorizontalPodAutoscaler
+		expected bool
+	}{
+		{
+			name: "#1: Utilization below the threshold",
+			hpa:  cpuUtilizationHPA(2, 2, 10, 50, 80),
+		},
+		{
+			name:     "#2: Utilization reached the threshold",
+			hpa:      cpuUtilizationHPA(2, 2, 10, 70, 80),
+			expected: true,
+		},
+		{
+			name:     "#3: Scale-up already desired",
+			hpa:      cpuUtilizationHPA(2, 4, 10, 50, 80),
+			expected: true,
+		},
+		{
+			name: "#4: Maximum replicas reached",
+			hpa:  cpuUtilizationHPA(10, 10, 10, 95, 80),
+		},
+		{
+			name: "#5: External metric below the threshold",
+			hpa:  externalHPA("50", "100"),
+		},
+		{
+			name:     "#6: External metric reached the threshold",
+			hpa:      externalHPA("85", "100"),
+			expected: true,
+		},
+		{
+			name: "#7: Current metrics not reported yet",
+			hpa: func() *autoscalingv2.HorizontalPodAutoscaler {
+				hpa := cpuUtilizationHPA(2, 2, 10, 95, 80)
+				hpa.Status.CurrentMetrics = nil
+				return hpa
+			}(),
+		},
+	}
+	for _, test := range tests {
+		if actual := hpaApproachingScaleUp(test.hpa, 80); actual != test.expected {
+			t.Errorf("Test: %s failed: expected=%t, actual=%t", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestHPAPrewarmPodSpecs(t *testing.T) {
+	prewarm := map[string]string{hpaPrewarmAnnotationKey: "true"}
+	hpa := func(name, kind, target string, annotations map[string]string, currentUtilization int32) *autoscalingv2.HorizontalPodAutoscaler {
+		hpa := cpuUtilizationHPA(2, 2, 10, currentUtilization, 80)
+		hpa.ObjectMeta = metav1.ObjectMeta{Name: name, Namespace: "team-a", Annotations: annotations}
+		hpa.Spec.ScaleTargetRef = autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: kind, Name: target}
+		return hpa
+	}
+	template := func(image string) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}}}
+	}
+	kubeclientset := fakeclientset.NewSimpleClientset(
+		hpa("web", "Deployment", "web", prewarm, 75),
+		hpa("api", "Deployment", "api", prewarm, 10),
+		hpa("db", "StatefulSet", "db", prewarm, 10),
+		hpa("worker", "Deployment", "worker", nil, 95),
+		hpa("missing", "Deployment", "missing", prewarm, 95),
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "team-a"},
+			Spec: appsv1.DeploymentSpec{Template: template("web:v1")}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "team-a"},
+			Spec: appsv1.DeploymentSpec{Template: template("api:v1")}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "team-a"},
+			Spec: appsv1.StatefulSetSpec{Template: template("db:v1")}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "team-a"},
+			Spec: appsv1.DeploymentSpec{Template: template("worker:v1")}},
+	)
+	controller, _, imagecacheInformer := newTestController(kubeclientset, kubefledgedclientsetfake.NewSimpleClientset())
+	controller.hpaPrewarmThreshold = 80
+	// The images of the statefulset were warmed before
+	imagecacheInformer.Informer().GetIndexer().Add(&kubefledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{Name: hpaPrewarmImageCacheName, Namespace: fledgedNameSpace, Labels: map[string]string{hpaPrewarmLabelKey: "true"}},
+		Spec:       kubefledgedv1alpha2.ImageCacheSpec{CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{{Images: []string{"db:v1"}}}},
+	})
+	podSpecs, err := controller.hpaPrewarmPodSpecs()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	actual := []string{}
+	for _, podSpec := range podSpecs {
+		actual = append(actual, podSpec.Containers[0].Image)
+	}
+	expected := []string{"db:v1", "web:v1"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected images %v, actual %v", expected, actual)
+	}
+}
+
+func TestRunHPAPrewarmWorkerOverlappingNodeSelectors(t *testing.T) {
+	prewarm := map[string]string{hpaPrewarmAnnotationKey: "true"}
+	hpa := func(name string) *autoscalingv2.HorizontalPodAutoscaler {
+		hpa := cpuUtilizationHPA(2, 3, 10, 90, 80)
+		hpa.ObjectMeta = metav1.ObjectMeta{Name: name, Namespace: "team-a", Annotations: prewarm}
+		hpa.Spec.ScaleTargetRef = autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: name}
+		return hpa
+	}
+	deployment := func(name string, nodeSelector map[string]string) *appsv1.Deployment {
+		return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a"},
+			Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				NodeSelector: nodeSelector,
+				Containers:   []corev1.Container{{Name: "app", Image: "nginx:1.23"}, {Name: name, Image: name + ":v1"}},
+			}}}}
+	}
+	kubeclientset := fakeclientset.NewSimpleClientset(
+		hpa("web"), hpa("api"),
+		deployment("web", nil), deployment("api", map[string]string{"pool": "a"}),
+	)
+	fledgedclientset := kubefledgedclientsetfake.NewSimpleClientset()
+	controller, _, _ := newTestController(kubeclientset, fledgedclientset)
+	controller.hpaPrewarmThreshold = 80
+	controller.defaultNodeOS = "linux"
+	controller.runHPAPrewarmWorker()
+	imageCache, err := fledgedclientset.KubefledgedV1alpha2().ImageCaches(fledgedNameSpace).Get(context.TODO(), hpaPrewarmImageCacheName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected image cache %s to be created: %v", hpaPrewarmImageCacheName, err)
+	}
+	expected := []kubefledgedv1alpha2.CacheSpecImages{
+		{Images: []string{"nginx:1.23", "web:v1"}},
+		{Images: []string{"api:v1"}, NodeSelector: map[string]string{"pool": "a"}},
+	}
+	if !reflect.DeepEqual(imageCache.Spec.CacheSpec, expected) {
+		t.Errorf("expected cache spec %+v, actual %+v", expected, imageCache.Spec.CacheSpec)
+	}
+	if err := images.ValidateNoDuplicateImages(imageCache.Spec.CacheSpec, controller.defaultNodeOS); err != nil {
+		t.Errorf("expected cache spec to be accepted, actual error: %v", err)
+	}
+}
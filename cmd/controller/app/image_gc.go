@@ -0,0 +1,158 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"sort"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ReasonImageGCPressure is used as part of the Event 'reason' when nodes targeted by an
+// image cache hold images beyond the kubelet image garbage collection low threshold
+const ReasonImageGCPressure = "ImageGCPressure"
+
+// imageSizeOnNode returns the size of the image reported by the node, if the node
+// holds the image
+func imageSizeOnNode(image string, node *corev1.Node) (int64, bool) {
+	if ni, ok := nodeImageOf(image, node); ok {
+		return ni.SizeBytes, true
+	}
+	return 0, false
+}
+
+// reportedImageSizes returns a function returning the size of an image reported by
+// any of the nodes, or zero if no node holds the image
+func reportedImageSizes(nodes []*corev1.Node) func(image string) int64 {
+	sizes := map[string]int64{}
+	return func(image string) int64 {
+		if size, ok := sizes[image]; ok {
+			return size
+		}
+		for _, n := range nodes {
+			if size, ok := imageSizeOnNode(image, n); ok {
+				sizes[image] = size
+				return size
+			}
+		}
+		sizes[image] = 0
+		return 0
+	}
+}
+
+// nodeImageBytes returns the size of all the images reported by the node
+func nodeImageBytes(node *corev1.Node) int64 {
+	var size int64
+	for _, nodeImage := range node.Status.Images {
+		size += nodeImage.SizeBytes
+	}
+	return size
+}
+
+// gcThresholdBytes returns the image storage usage of the node at the given kubelet
+// image garbage collection threshold percent. The capacity of the image filesystem is
+// not reported by kubelet, hence the ephemeral storage capacity of the node is used.
+func gcThresholdBytes(node *corev1.Node, percent int) (int64, bool) {
+	capacity, ok := node.Status.Capacity[corev1.ResourceEphemeralStorage]
+	if !ok || capacity.IsZero() {
+		return 0, false
+	}
+	return capacity.Value() / 100 * int64(percent), true
+}
+
+// gcPressure returns the nodes targeted by the image cache whose images exceed the
+// kubelet image garbage collection low threshold, sorted by name
+func (c *Controller) gcPressure(imageCache *v1alpha2.ImageCache) []v1alpha2.NodeGCPressure {
+	if c.imageGCHighThreshold == 0 {
+		return nil
+	}
+	nodes := map[string]*corev1.Node{}
+	for _, cacheSpec := range imageListsOf(imageCache) {
+		specNodes, err := c.nodesForCacheSpec(cacheSpec)
+		if err != nil {
+			return nil
+		}
+		for _, n := range specNodes {
+			nodes[n.Name] = n
+		}
+	}
+	pressure := []v1alpha2.NodeGCPressure{}
+	for _, n := range nodes {
+		low, ok := gcThresholdBytes(n, c.imageGCLowThreshold)
+		if !ok {
+			continue
+		}
+		imageBytes := nodeImageBytes(n)
+		if imageBytes <= low {
+			continue
+		}
+		high, _ := gcThresholdBytes(n, c.imageGCHighThreshold)
+		var cachedBytes int64
+		for _, cacheSpec := range imageListsOf(imageCache) {
+			for _, image := range cacheSpec.Images {
+				if size, ok := imageSizeOnNode(image, n); ok {
+					cachedBytes += size
+				}
+			}
+		}
+		pressure = append(pressure, v1alpha2.NodeGCPressure{
+			Node:          n.Name,
+			ImageBytes:    imageBytes,
+			CachedBytes:   cachedBytes,
+			HeadroomBytes: high - imageBytes,
+		})
+	}
+	sort.Slice(pressure, func(i, j int) bool { return pressure[i].Node < pressure[j].Node })
+	return pressure
+}
+
+// capBytesPerNode drops the image pulls that would take the size of the images of the
+// image cache on a node beyond maxBytes. Image sizes are taken from any node reporting
+// the image. Work items keep their order, the first ones being kept. It returns the
+// kept work items and the number of dropped ones.
+func (c *Controller) capBytesPerNode(workItems []imageWorkItem, maxBytes *resource.Quantity) ([]imageWorkItem, int) {
+	if maxBytes == nil {
+		return workItems, 0
+	}
+	nodes, err := c.nodesLister.List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error listing nodes: %v", err)
+		return workItems, 0
+	}
+	imageSize := reportedImageSizes(nodes)
+	kept := []imageWorkItem{}
+	nodeBytes := map[string]int64{}
+	for _, w := range workItems {
+		// OCI artifacts are not reported by the nodes
+		if w.artifact {
+			kept = append(kept, w)
+			continue
+		}
+		size := imageSize(w.image)
+		if nodeBytes[w.node.Name]+size > maxBytes.Value() {
+			glog.Infof("Image %s not pulled to node %s: it exceeds maxBytesPerNode %s", w.image, w.node.Name, maxBytes.String())
+			continue
+		}
+		nodeBytes[w.node.Name] += size
+		kept = append(kept, w)
+	}
+	return kept, len(workItems) - len(kept)
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func newImageGCTestNode(name string, capacity string, images map[string]int64) *corev1.Node {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"kubernetes.io/os": "linux"}},
+	}
+	if capacity != "" {
+		node.Status.Capacity = corev1.ResourceList{corev1.ResourceEphemeralStorage: resource.MustParse(capacity)}
+	}
+	for image, size := range images {
+		node.Status.Images = append(node.Status.Images, corev1.ContainerImage{Names: []string{image}, SizeBytes: size})
+	}
+	return node
+}
+
+func TestGCPressure(t *testing.T) {
+	imageCache := &kubefledgedv1alpha2.ImageCache{
+		Spec: kubefledgedv1alpha2.ImageCacheSpec{
+			CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{{Images: []string{"nginx:1.23"}}},
+		},
+	}
+	tests := []struct {
+		name             string
+		highThreshold    int
+		node             *corev1.Node
+		expectedPressure bool
+		expectedHeadroom int64
+		expectedCached   int64
+	}{
+		{
+			name:          "#1: Node below the low threshold",
+			highThreshold: 85,
+			node:          newImageGCTestNode("node1", "1000", map[string]int64{"docker.io/library/nginx:1.23": 100}),
+		},
+		{
+			name:          "#2: Node beyond the low threshold",
+			highThreshold: 85,
+			node: newImageGCTestNode("node1", "1000", map[string]int64{
+				"docker.io/library/nginx:1.23": 300,
+				"docker.io/library/redis:7":    520,
+			}),
+			expectedPressure: true,
+			expectedHeadroom: 30,
+			expectedCached:   300,
+		},
+		{
+			name:          "#3: Node capacity not reported",
+			highThreshold: 85,
+			node:          newImageGCTestNode("node1", "", map[string]int64{"docker.io/library/nginx:1.23": 900}),
+		},
+		{
+			name: "#4: Reporting disabled",
+			node: newImageGCTestNode("node1", "1000", map[string]int64{"docker.io/library/nginx:1.23": 900}),
+		},
+	}
+	for _, test := range tests {
+		controller, nodeInformer, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+		controller.imageGCHighThreshold = test.highThreshold
+		controller.imageGCLowThreshold = 80
+		nodeInformer.Informer().GetIndexer().Add(test.node)
+
+		pressure := controller.gcPressure(imageCache)
+		if (len(pressure) > 0) != test.expectedPressure {
+			t.Errorf("Test: %s failed: expectedPressure=%t, actualPressure=%+v", test.name, test.expectedPressure, pressure)
+			continue
+		}
+		if !test.expectedPressure {
+			continue
+		}
+		if pressure[0].HeadroomBytes != test.expectedHeadroom || pressure[0].CachedBytes != test.expectedCached {
+			t.Errorf("Test: %s failed: expectedHeadroom=%d, expectedCached=%d, actual=%+v",
+				test.name, test.expectedHeadroom, test.expectedCached, pressure[0])
+		}
+	}
+}
+
+func TestCapBytesPerNode(t *testing.T) {
+	node1 := newImageGCTestNode("node1", "", map[string]int64{"docker.io/library/nginx:1.23": 300})
+	node2 := newImageGCTestNode("node2", "", map[string]int64{"docker.io/library/redis:7": 500})
+	workItems := []imageWorkItem{
+		{image: "nginx:1.23", node: node1},
+		{image: "redis:7", node: node1},
+		{image: "busybox:1.35", node: node1},
+		{image: "ghcr.io/foo/model:v1", artifact: true, node: node1},
+		{image: "nginx:1.23", node: node2},
+		{image: "redis:7", node: node2},
+	}
+	quantity := func(v string) *resource.Quantity { q := resource.MustParse(v); return &q }
+	tests := []struct {
+		name            string
+		maxBytes        *resource.Quantity
+		expectedKept    int
+		expectedDropped int
+	}{
+		{
+			name:         "#1: No cap",
+			expectedKept: 6,
+		},
+		{
+			name:            "#2: Images beyond the cap dropped",
+			maxBytes:        quantity("600"),
+			expectedKept:    4,
+			expectedDropped: 2,
+		},
+		{
+			name:         "#3: All images within the cap",
+			maxBytes:     quantity("800"),
+			expectedKept: 6,
+		},
+	}
+	controller, nodeInformer, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	nodeInformer.Informer().GetIndexer().Add(node1)
+	nodeInformer.Informer().GetIndexer().Add(node2)
+	for _, test := range tests {
+		kept, dropped := controller.capBytesPerNode(workItems, test.maxBytes)
+		if len(kept) != test.expectedKept || dropped != test.expectedDropped {
+			t.Errorf("Test: %s failed: expectedKept=%d, expectedDropped=%d, actualKept=%d, actualDropped=%d",
+				test.name, test.expectedKept, test.expectedDropped, len(kept), dropped)
+		}
+	}
+}
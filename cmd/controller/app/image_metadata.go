@@ -0,0 +1,154 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	"github.com/senthilrch/kube-fledged/pkg/registry"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+)
+
+// imageMetadataTTL is the duration for which the metadata of an image read from its
+// registry is cached, before it is read again
+const imageMetadataTTL = time.Hour
+
+// imageMetadataKey identifies the metadata of an image for a platform: its size, or its
+// layers, read with the keychain of an image cache
+type imageMetadataKey struct {
+	image    string
+	platform string
+	layers   bool
+	keychain authn.Keychain
+}
+
+// imageMetadata is the metadata of an image read from its registry
+type imageMetadata struct {
+	size   int64
+	layers []registry.Layer
+	// err is the error reading the metadata of the image, if any
+	err  error
+	read time.Time
+}
+
+// nodePlatform returns the platform of an image on a node: the platform the image is
+// cached for, or else the platform of the node
+func nodePlatform(platform string, node *corev1.Node) string {
+	if platform != "" {
+		return platform
+	}
+	return node.Status.NodeInfo.OperatingSystem + "/" + node.Status.NodeInfo.Architecture
+}
+
+// keychainOf returns the keychain of the registry operations for the images of the image
+// cache: the credentials of its image pull secrets, then those of the controller
+func (c *Controller) keychainOf(imageCache *v1alpha2.ImageCache) authn.Keychain {
+	return c.keychains.For(imageCache.Namespace, imageCache.Spec.ImagePullSecrets)
+}
+
+// cachedImageMetadata returns the size of an image for the platform, read from its
+// registry with the keychain by the metadata worker, and whether it is cached
+func (c *Controller) cachedImageMetadata(image, platform string, keychain authn.Keychain) (imageMetadata, bool) {
+	return c.cachedMetadata(imageMetadataKey{image: image, platform: platform, keychain: keychain})
+}
+
+// cachedImageLayers returns the layers of an image for the platform, read from its
+// registry with the keychain by the metadata worker, and whether they are cached
+func (c *Controller) cachedImageLayers(image, platform string, keychain authn.Keychain) (imageMetadata, bool) {
+	return c.cachedMetadata(imageMetadataKey{image: image, platform: platform, layers: true, keychain: keychain})
+}
+
+// cachedMetadata returns the metadata of an image read from its registry by the metadata
+// worker, and whether it is cached. The image is queued for the metadata worker if its
+// metadata is not cached or expired: registries may be slow to answer, and are never
+// called by the sync worker.
+func (c *Controller) cachedMetadata(key imageMetadataKey) (imageMetadata, bool) {
+	c.imageMetadataLock.Lock()
+	metadata, ok := c.imageMetadata[key]
+	c.imageMetadataLock.Unlock()
+	if !ok || time.Since(metadata.read) > imageMetadataTTL {
+		c.imageMetadataQueue.Add(key)
+	}
+	return metadata, ok
+}
+
+// queueImageMetadata queues the images pulled by the work items of the image cache for
+// the metadata worker, so that their sizes, or their layers, are cached when the image
+// cache action finishes
+func (c *Controller) queueImageMetadata(imageCache *v1alpha2.ImageCache, workItems []imageWorkItem, sizes, layers bool) {
+	keychain := c.keychainOf(imageCache)
+	for _, w := range workItems {
+		if w.workType == images.ImageCachePurge || w.artifact {
+			continue
+		}
+		platform := nodePlatform(w.platform, w.node)
+		if sizes {
+			c.cachedImageMetadata(w.image, platform, keychain)
+		}
+		if layers {
+			c.cachedImageLayers(w.image, platform, keychain)
+		}
+	}
+}
+
+// runImageMetadataWorker reads the metadata of the images queued on the metadata queue
+// from their registries
+func (c *Controller) runImageMetadataWorker() {
+	for c.processNextImageMetadataItem() {
+	}
+}
+
+// processNextImageMetadataItem reads a single image off the metadata queue and caches
+// its metadata
+func (c *Controller) processNextImageMetadataItem() bool {
+	obj, shutdown := c.imageMetadataQueue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.imageMetadataQueue.Done(obj)
+	c.imageMetadataQueue.Forget(obj)
+	key, ok := obj.(imageMetadataKey)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("unexpected type in image metadata queue: %#v", obj))
+		return true
+	}
+	c.imageMetadataLock.Lock()
+	metadata, ok := c.imageMetadata[key]
+	c.imageMetadataLock.Unlock()
+	if ok && time.Since(metadata.read) <= imageMetadataTTL {
+		return true
+	}
+	metadata = imageMetadata{read: time.Now()}
+	if key.layers {
+		metadata.layers, metadata.err = c.layerLister.ImageLayers(key.image, key.platform, key.keychain)
+	} else {
+		metadata.size, metadata.err = c.imageSizer.ImageSize(key.image, key.platform, key.keychain)
+	}
+	if metadata.err != nil {
+		glog.V(4).Infof("Error getting manifest of image %s from registry: %v", key.image, metadata.err)
+	}
+	c.imageMetadataLock.Lock()
+	c.imageMetadata[key] = metadata
+	c.imageMetadataLock.Unlock()
+	return true
+}
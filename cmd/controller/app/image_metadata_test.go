@@ -0,0 +1,165 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	"github.com/senthilrch/kube-fledged/pkg/registry"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+// readImageMetadata reads the metadata of the images queued for the metadata worker
+func readImageMetadata(controller *Controller) {
+	for controller.imageMetadataQueue.Len() > 0 {
+		controller.processNextImageMetadataItem()
+	}
+}
+
+type countingImageSizer struct {
+	sizes fakeImageSizer
+	reads int
+}
+
+func (c *countingImageSizer) ImageSize(image string, platform string, keychain authn.Keychain) (int64, error) {
+	c.reads++
+	return c.sizes.ImageSize(image, platform, keychain)
+}
+
+func TestCachedImageMetadata(t *testing.T) {
+	controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	sizer := &countingImageSizer{sizes: fakeImageSizer{"nginx:1.23@linux/amd64": 100}}
+	controller.imageSizer = sizer
+
+	if _, ok := controller.cachedImageMetadata("nginx:1.23", "linux/amd64", nil); ok {
+		t.Errorf("expected no metadata cached before the metadata worker ran")
+	}
+	if sizer.reads != 0 {
+		t.Errorf("expected the registry not to be read by the caller, actual reads=%d", sizer.reads)
+	}
+	controller.cachedImageMetadata("nginx:1.23", "linux/amd64", nil)
+	controller.cachedImageMetadata("redis:7", "linux/amd64", nil)
+	readImageMetadata(controller)
+	if sizer.reads != 2 {
+		t.Errorf("expected each queued image read once, actual reads=%d", sizer.reads)
+	}
+
+	tests := []struct {
+		name         string
+		image        string
+		expectedSize int64
+		expectErr    bool
+	}{
+		{name: "#1: Size cached", image: "nginx:1.23", expectedSize: 100},
+		{name: "#2: Error cached", image: "redis:7", expectErr: true},
+	}
+	for _, test := range tests {
+		metadata, ok := controller.cachedImageMetadata(test.image, "linux/amd64", nil)
+		if !ok {
+			t.Errorf("Test: %s failed: expected metadata cached", test.name)
+			continue
+		}
+		if (metadata.err != nil) != test.expectErr || metadata.size != test.expectedSize {
+			t.Errorf("Test: %s failed: expectedSize=%d, expectErr=%t, actual=%+v", test.name, test.expectedSize, test.expectErr, metadata)
+		}
+	}
+	if controller.imageMetadataQueue.Len() != 0 {
+		t.Errorf("expected no image queued while its metadata is cached")
+	}
+
+	key := imageMetadataKey{image: "nginx:1.23", platform: "linux/amd64"}
+	controller.imageMetadata[key] = imageMetadata{size: 90, read: time.Now().Add(-2 * imageMetadataTTL)}
+	if metadata, ok := controller.cachedImageMetadata("nginx:1.23", "linux/amd64", nil); !ok || metadata.size != 90 {
+		t.Errorf("expected expired metadata returned until read again, actual %+v", metadata)
+	}
+	readImageMetadata(controller)
+	if metadata := controller.imageMetadata[key]; metadata.size != 100 {
+		t.Errorf("expected expired metadata read again, actual %+v", metadata)
+	}
+	if sizer.reads != 3 {
+		t.Errorf("expected expired metadata read once, actual reads=%d", sizer.reads)
+	}
+}
+
+// keychainImageSizer records the keychain of the last image size read
+type keychainImageSizer struct {
+	keychain authn.Keychain
+}
+
+func (s *keychainImageSizer) ImageSize(image string, platform string, keychain authn.Keychain) (int64, error) {
+	s.keychain = keychain
+	return 100, nil
+}
+
+func TestImageMetadataWithImagePullSecrets(t *testing.T) {
+	controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	controller.keychains = registry.NewKeychains(fakeclientset.NewSimpleClientset(), nil, time.Minute)
+	sizer := &keychainImageSizer{}
+	controller.imageSizer = sizer
+	imageCache := &kubefledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "team-a"},
+		Spec: kubefledgedv1alpha2.ImageCacheSpec{
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "team"}},
+		},
+	}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	node.Status.NodeInfo = corev1.NodeSystemInfo{OperatingSystem: "linux", Architecture: "amd64"}
+	controller.queueImageMetadata(imageCache, []imageWorkItem{{image: "ghcr.io/team-a/app:v1", node: node}}, true, false)
+	readImageMetadata(controller)
+	keychain := controller.keychainOf(imageCache)
+	if keychain == nil || sizer.keychain != keychain {
+		t.Errorf("expected the image size read with the keychain of the image pull secrets of the image cache")
+	}
+	if _, ok := controller.cachedImageMetadata("ghcr.io/team-a/app:v1", "linux/amd64", controller.keychainOf(&kubefledgedv1alpha2.ImageCache{})); ok {
+		t.Errorf("expected the image size read with the image pull secrets not shared with image caches without them")
+	}
+}
+
+func TestQueueImageMetadata(t *testing.T) {
+	controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	node.Status.NodeInfo = corev1.NodeSystemInfo{OperatingSystem: "linux", Architecture: "arm64"}
+	imageCache := &kubefledgedv1alpha2.ImageCache{}
+	keychain := controller.keychainOf(imageCache)
+	controller.queueImageMetadata(imageCache, []imageWorkItem{
+		{image: "nginx:1.23", node: node},
+		{image: "nginx:1.23", platform: "linux/amd64", node: node},
+		{image: "ghcr.io/foo/chart:1.0", artifact: true, node: node},
+	}, true, true)
+	expected := map[imageMetadataKey]bool{
+		{image: "nginx:1.23", platform: "linux/arm64", keychain: keychain}:               true,
+		{image: "nginx:1.23", platform: "linux/amd64", keychain: keychain}:               true,
+		{image: "nginx:1.23", platform: "linux/arm64", layers: true, keychain: keychain}: true,
+		{image: "nginx:1.23", platform: "linux/amd64", layers: true, keychain: keychain}: true,
+	}
+	if actual := controller.imageMetadataQueue.Len(); actual != len(expected) {
+		t.Fatalf("expected %d images queued, actual %d", len(expected), actual)
+	}
+	for range expected {
+		obj, _ := controller.imageMetadataQueue.Get()
+		if !expected[obj.(imageMetadataKey)] {
+			t.Errorf("unexpected image queued: %v", obj)
+		}
+		controller.imageMetadataQueue.Done(obj)
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// imageCacheRequestCheckInterval is the interval at which image cache requests are
+	// executed, checked for completion and garbage collected
+	imageCacheRequestCheckInterval = 10 * time.Second
+	// defaultImageCacheRequestTTL is the time a finished image cache request is kept
+	// if it does not specify ttlSecondsAfterFinished
+	defaultImageCacheRequestTTL = time.Hour
+	// imageCacheRequestLabelKey labels the image cache executing an image cache request
+	// with the name of the request
+	imageCacheRequestLabelKey = "kubefledged.io/imagecacherequest"
+)
+
+// runImageCacheRequestWorker executes the new image cache requests, reports the
+// outcome of the running ones, and deletes the finished ones whose TTL expired
+func (c *Controller) runImageCacheRequestWorker() {
+	requests, err := c.requestsLister.ImageCacheRequests("").List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error in listing image cache requests: %v", err)
+		return
+	}
+	now := time.Now()
+	for _, request := range requests {
+		if err := c.syncImageCacheRequest(request, now); err != nil {
+			glog.Errorf("Error syncing image cache request %s/%s: %v", request.Namespace, request.Name, err)
+		}
+	}
+}
+
+// syncImageCacheRequest moves the image cache request one step further in its life
+func (c *Controller) syncImageCacheRequest(request *v1alpha2.ImageCacheRequest, now time.Time) error {
+	if request.Status.CompletionTime != nil {
+		if now.Before(request.Status.CompletionTime.Add(imageCacheRequestTTL(request))) {
+			return nil
+		}
+		err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCacheRequests(request.Namespace).Delete(context.TODO(), request.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		glog.Infof("Finished image cache request %s/%s deleted", request.Namespace, request.Name)
+		return nil
+	}
+	if request.Status.ImageCache == "" {
+		return c.startImageCacheRequest(request, now)
+	}
+	imageCache, err := c.imageCachesLister.ImageCaches(request.Namespace).Get(request.Status.ImageCache)
+	if apierrors.IsNotFound(err) {
+		status := request.Status.DeepCopy()
+		status.Status = v1alpha2.ImageCacheActionStatusFailed
+		status.Reason = v1alpha2.ImageCacheRequestReasonImageCacheDeleted
+		status.Message = fmt.Sprintf("Image cache %s was deleted before the request finished", request.Status.ImageCache)
+		return c.finishImageCacheRequest(request, status, now)
+	}
+	if err != nil {
+		return err
+	}
+	if imageCache.Status.Status == "" || imageCache.Status.Status == v1alpha2.ImageCacheActionStatusProcessing {
+		return nil
+	}
+	status := request.Status.DeepCopy()
+	status.Status = imageCache.Status.Status
+	status.Reason = imageCache.Status.Reason
+	status.Message = imageCache.Status.Message
+	status.Failures = imageCache.Status.Failures
+	if err := c.finishImageCacheRequest(request, status, now); err != nil {
+		return err
+	}
+	// The images stay on the nodes: image caches are not purged when deleted
+	err = c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(imageCache.Namespace).Delete(context.TODO(), imageCache.Name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// startImageCacheRequest creates the image cache pulling the images of the request. The
+// image cache is owned by the request, so that it is garbage collected along with the
+// request. An image cache left by an earlier attempt is reused.
+func (c *Controller) startImageCacheRequest(request *v1alpha2.ImageCacheRequest, now time.Time) error {
+	status := request.Status.DeepCopy()
+	imageCache, err := c.imageCacheOfRequest(request)
+	if err != nil {
+		return err
+	}
+	if imageCache == nil {
+		imageCache, err = c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(request.Namespace).Create(context.TODO(), newRequestImageCache(request), metav1.CreateOptions{})
+		if err != nil {
+			// e.g. rejected by the webhook server for violating a policy
+			status.Status = v1alpha2.ImageCacheActionStatusFailed
+			status.Reason = v1alpha2.ImageCacheRequestReasonImageCacheCreateFailed
+			status.Message = err.Error()
+			return c.finishImageCacheRequest(request, status, now)
+		}
+	}
+	startTime := metav1.NewTime(now)
+	status.Status = v1alpha2.ImageCacheActionStatusProcessing
+	status.Message = v1alpha2.ImageCacheMessagePullingImages
+	status.ImageCache = imageCache.Name
+	status.StartTime = &startTime
+	glog.Infof("Image cache request %s/%s started: image cache %s created", request.Namespace, request.Name, imageCache.Name)
+	return c.updateImageCacheRequestStatus(request, status)
+}
+
+// imageCacheOfRequest returns the image cache owned by the request, if any
+func (c *Controller) imageCacheOfRequest(request *v1alpha2.ImageCacheRequest) (*v1alpha2.ImageCache, error) {
+	selector := labels.SelectorFromSet(labels.Set{imageCacheRequestLabelKey: request.Name})
+	imageCaches, err := c.imageCachesLister.ImageCaches(request.Namespace).List(selector)
+	if err != nil {
+		return nil, err
+	}
+	for _, imageCache := range imageCaches {
+		if owner := metav1.GetControllerOf(imageCache); owner != nil && owner.UID == request.UID {
+			return imageCache, nil
+		}
+	}
+	return nil, nil
+}
+
+// finishImageCacheRequest records the terminal status of the request
+func (c *Controller) finishImageCacheRequest(request *v1alpha2.ImageCacheRequest, status *v1alpha2.ImageCacheRequestStatus, now time.Time) error {
+	completionTime := metav1.NewTime(now)
+	status.CompletionTime = &completionTime
+	glog.Infof("Image cache request %s/%s finished: %s", request.Namespace, request.Name, status.Status)
+	return c.updateImageCacheRequestStatus(request, status)
+}
+
+// updateImageCacheRequestStatus updates the status of the request
+func (c *Controller) updateImageCacheRequestStatus(request *v1alpha2.ImageCacheRequest, status *v1alpha2.ImageCacheRequestStatus) error {
+	requestCopy := request.DeepCopy()
+	requestCopy.Status = *status
+	_, err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCacheRequests(request.Namespace).Update(context.TODO(), requestCopy, metav1.UpdateOptions{})
+	return err
+}
+
+// newRequestImageCache returns the image cache executing the request
+func newRequestImageCache(request *v1alpha2.ImageCacheRequest) *v1alpha2.ImageCache {
+	return &v1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: request.Name + "-",
+			Namespace:    request.Namespace,
+			Labels:       map[string]string{imageCacheRequestLabelKey: request.Name},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(request, v1alpha2.SchemeGroupVersion.WithKind("ImageCacheRequest")),
+			},
+		},
+		Spec: v1alpha2.ImageCacheSpec{
+			CacheSpec: []v1alpha2.CacheSpecImages{
+				{
+					Images:       request.Spec.Images,
+					NodeSelector: request.Spec.NodeSelector,
+				},
+			},
+		},
+	}
+}
+
+// imageCacheRequestOf returns the name of the image cache request executed by the image
+// cache, if any
+func imageCacheRequestOf(imageCache *v1alpha2.ImageCache) string {
+	owner := metav1.GetControllerOf(imageCache)
+	if owner == nil || owner.Kind != "ImageCacheRequest" {
+		return ""
+	}
+	return owner.Name
+}
+
+// imageCacheRequestTTL returns the time the finished request is kept
+func imageCacheRequestTTL(request *v1alpha2.ImageCacheRequest) time.Duration {
+	if request.Spec.TTLSecondsAfterFinished == nil {
+		return defaultImageCacheRequestTTL
+	}
+	return time.Duration(*request.Spec.TTLSecondsAfterFinished) * time.Second
+}
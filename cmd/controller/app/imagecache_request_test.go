@@ -0,0 +1,216 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+)
+
+func TestSyncImageCacheRequest(t *testing.T) {
+	now := time.Now()
+	finished := metav1.NewTime(now.Add(-30 * time.Minute))
+	zero := int32(0)
+	request := kubefledgedv1alpha2.ImageCacheRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "build-42",
+			Namespace: fledgedNameSpace,
+			UID:       "uid-42",
+		},
+		Spec: kubefledgedv1alpha2.ImageCacheRequestSpec{
+			Images:       []string{"nginx:1.23"},
+			NodeSelector: map[string]string{"tier": "web"},
+		},
+	}
+	ownedImageCache := func(status kubefledgedv1alpha2.ImageCacheActionStatus) *kubefledgedv1alpha2.ImageCache {
+		imageCache := newRequestImageCache(&request)
+		imageCache.Name = "build-42-abcde"
+		imageCache.Status.Status = status
+		return imageCache
+	}
+	tests := []struct {
+		name               string
+		status             kubefledgedv1alpha2.ImageCacheRequestStatus
+		ttl                *int32
+		imageCache         *kubefledgedv1alpha2.ImageCache
+		createError        error
+		expectedStatus     kubefledgedv1alpha2.ImageCacheActionStatus
+		expectedReason     string
+		expectedImageCache string
+		expectedFinished   bool
+		expectedCreate     bool
+		expectedCacheGone  bool
+		expectedDeleted    bool
+	}{
+		{
+			name:           "#1: New request - image cache created",
+			expectedStatus: kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+			expectedCreate: true,
+		},
+		{
+			name:               "#2: New request - image cache of an earlier attempt reused",
+			imageCache:         ownedImageCache(""),
+			expectedStatus:     kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+			expectedImageCache: "build-42-abcde",
+		},
+		{
+			name:             "#3: New request - image cache rejected",
+			createError:      fmt.Errorf("admission webhook denied the request"),
+			expectedStatus:   kubefledgedv1alpha2.ImageCacheActionStatusFailed,
+			expectedReason:   kubefledgedv1alpha2.ImageCacheRequestReasonImageCacheCreateFailed,
+			expectedFinished: true,
+			expectedCreate:   true,
+		},
+		{
+			name: "#4: Images being pulled",
+			status: kubefledgedv1alpha2.ImageCacheRequestStatus{
+				Status:     kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+				ImageCache: "build-42-abcde",
+			},
+			imageCache:         ownedImageCache(kubefledgedv1alpha2.ImageCacheActionStatusProcessing),
+			expectedStatus:     kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+			expectedImageCache: "build-42-abcde",
+		},
+		{
+			name: "#5: Images pulled - image cache deleted",
+			status: kubefledgedv1alpha2.ImageCacheRequestStatus{
+				Status:     kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+				ImageCache: "build-42-abcde",
+			},
+			imageCache:         ownedImageCache(kubefledgedv1alpha2.ImageCacheActionStatusSucceeded),
+			expectedStatus:     kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			expectedImageCache: "build-42-abcde",
+			expectedFinished:   true,
+			expectedCacheGone:  true,
+		},
+		{
+			name: "#6: Image cache deleted by user",
+			status: kubefledgedv1alpha2.ImageCacheRequestStatus{
+				Status:     kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+				ImageCache: "build-42-abcde",
+			},
+			expectedStatus:     kubefledgedv1alpha2.ImageCacheActionStatusFailed,
+			expectedReason:     kubefledgedv1alpha2.ImageCacheRequestReasonImageCacheDeleted,
+			expectedImageCache: "build-42-abcde",
+			expectedFinished:   true,
+		},
+		{
+			name: "#7: Finished - ttl not expired",
+			status: kubefledgedv1alpha2.ImageCacheRequestStatus{
+				Status:         kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+				ImageCache:     "build-42-abcde",
+				CompletionTime: &finished,
+			},
+			expectedStatus:     kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			expectedImageCache: "build-42-abcde",
+			expectedFinished:   true,
+		},
+		{
+			name: "#8: Finished - ttl expired",
+			status: kubefledgedv1alpha2.ImageCacheRequestStatus{
+				Status:         kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+				ImageCache:     "build-42-abcde",
+				CompletionTime: &finished,
+			},
+			ttl:             &zero,
+			expectedDeleted: true,
+		},
+	}
+	for _, test := range tests {
+		request := request.DeepCopy()
+		request.Spec.TTLSecondsAfterFinished = test.ttl
+		request.Status = test.status
+		objects := []runtime.Object{request}
+		if test.imageCache != nil {
+			objects = append(objects, test.imageCache)
+		}
+		fledgedclientset := kubefledgedclientsetfake.NewSimpleClientset(objects...)
+		created := false
+		fledgedclientset.PrependReactor("create", "imagecaches", func(action core.Action) (bool, runtime.Object, error) {
+			created = true
+			return test.createError != nil, nil, test.createError
+		})
+		controller, _, imagecacheInformer := newTestController(fakeclientset.NewSimpleClientset(), fledgedclientset)
+		if test.imageCache != nil {
+			imagecacheInformer.Informer().GetIndexer().Add(test.imageCache)
+		}
+
+		if err := controller.syncImageCacheRequest(request, now); err != nil {
+			t.Errorf("Test: %s failed: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		if created != test.expectedCreate {
+			t.Errorf("Test: %s failed: expectedCreate=%t, actualCreate=%t", test.name, test.expectedCreate, created)
+		}
+		updated, err := fledgedclientset.KubefledgedV1alpha2().ImageCacheRequests(fledgedNameSpace).Get(context.TODO(), "build-42", metav1.GetOptions{})
+		if test.expectedDeleted {
+			if !errors.IsNotFound(err) {
+				t.Errorf("Test: %s failed: expected request to be deleted, got error %v", test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test: %s failed: %v", test.name, err)
+		}
+		if updated.Status.Status != test.expectedStatus || updated.Status.Reason != test.expectedReason ||
+			(updated.Status.CompletionTime != nil) != test.expectedFinished {
+			t.Errorf("Test: %s failed: unexpected status %+v", test.name, updated.Status)
+		}
+		if test.expectedImageCache != "" && updated.Status.ImageCache != test.expectedImageCache {
+			t.Errorf("Test: %s failed: expected image cache %s, actual %s", test.name, test.expectedImageCache, updated.Status.ImageCache)
+		}
+		if test.imageCache != nil {
+			_, err := fledgedclientset.KubefledgedV1alpha2().ImageCaches(fledgedNameSpace).Get(context.TODO(), test.imageCache.Name, metav1.GetOptions{})
+			if gone := errors.IsNotFound(err); gone != test.expectedCacheGone {
+				t.Errorf("Test: %s failed: expectedCacheGone=%t, actualGone=%t", test.name, test.expectedCacheGone, gone)
+			}
+		}
+	}
+}
+
+func TestCanRefreshOneShotImageCache(t *testing.T) {
+	request := &kubefledgedv1alpha2.ImageCacheRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "build-42", Namespace: fledgedNameSpace},
+		Spec:       kubefledgedv1alpha2.ImageCacheRequestSpec{Images: []string{"nginx:1.23"}},
+	}
+	imageCache := newRequestImageCache(request)
+	imageCache.Status.Status = kubefledgedv1alpha2.ImageCacheActionStatusSucceeded
+	if canRefresh(imageCache) {
+		t.Errorf("Test failed: expected the image cache of a request not to be refreshed")
+	}
+	oneShot := &kubefledgedv1alpha2.ImageCache{
+		Spec:   kubefledgedv1alpha2.ImageCacheSpec{TTLSecondsAfterFinished: new(int32)},
+		Status: imageCache.Status,
+	}
+	if canRefresh(oneShot) {
+		t.Errorf("Test failed: expected an image cache with ttlSecondsAfterFinished not to be refreshed")
+	}
+	if imageCacheRequestOf(imageCache) != "build-42" {
+		t.Errorf("Test failed: expected request build-42, actual %q", imageCacheRequestOf(imageCache))
+	}
+}
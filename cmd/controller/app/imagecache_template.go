@@ -0,0 +1,260 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// imageCacheTemplateCheckInterval is the interval at which the image caches of the
+	// templates are instantiated for the node pools discovered
+	imageCacheTemplateCheckInterval = 30 * time.Second
+	// imageCacheTemplateLabelKey labels the image caches instantiated from a template
+	// with the name of the template
+	imageCacheTemplateLabelKey = "kubefledged.io/imagecachetemplate"
+	// nodePoolLabelKey labels the image caches instantiated from a template with their
+	// node pool
+	nodePoolLabelKey = "kubefledged.io/node-pool"
+)
+
+// invalidNameChars are the characters of a node pool replaced in the name of its image
+// cache
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// runImageCacheTemplateWorker instantiates the image caches of the image cache templates
+// for the node pools discovered, and deletes those of the node pools which are gone
+func (c *Controller) runImageCacheTemplateWorker() {
+	templates, err := c.templatesLister.ImageCacheTemplates("").List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error in listing image cache templates: %v", err)
+		return
+	}
+	if len(templates) == 0 {
+		return
+	}
+	nodes, err := c.nodesLister.List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error in listing nodes: %v", err)
+		return
+	}
+	for _, template := range templates {
+		if err := c.syncImageCacheTemplate(template, nodes); err != nil {
+			glog.Errorf("Error syncing image cache template %s/%s: %v", template.Namespace, template.Name, err)
+		}
+	}
+}
+
+// syncImageCacheTemplate creates an image cache for every node pool of the template which
+// has images to cache, updates those whose spec differs from the template, and deletes
+// those of the node pools without nodes or images. The images stay on the nodes when an
+// image cache is deleted.
+func (c *Controller) syncImageCacheTemplate(template *v1alpha2.ImageCacheTemplate, nodes []*corev1.Node) error {
+	status := v1alpha2.ImageCacheTemplateStatus{ImageCaches: map[string]string{}}
+	failures := []string{}
+	if errs := validation.IsQualifiedName(template.Spec.NodePoolLabelKey); len(errs) > 0 {
+		status.Message = fmt.Sprintf("Invalid nodePoolLabelKey %q: %s", template.Spec.NodePoolLabelKey, strings.Join(errs, "; "))
+		return c.updateImageCacheTemplateStatus(template, status)
+	}
+	existing, err := c.imageCachesOfTemplate(template)
+	if err != nil {
+		return err
+	}
+	for _, pool := range nodePools(nodes, template.Spec.NodePoolLabelKey) {
+		spec := templateImageCacheSpec(template, pool)
+		if len(spec.CacheSpec) == 0 {
+			continue
+		}
+		imageCache, ok := existing[pool]
+		delete(existing, pool)
+		if !ok {
+			imageCache, err = c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(template.Namespace).Create(context.TODO(), newTemplateImageCache(template, pool, spec), metav1.CreateOptions{})
+			if err != nil {
+				// e.g. rejected by the webhook server for violating a policy
+				failures = append(failures, fmt.Sprintf("%s: %v", pool, err))
+				continue
+			}
+			glog.Infof("Image cache %s/%s of node pool %s instantiated from template %s", imageCache.Namespace, imageCache.Name, pool, template.Name)
+			status.ImageCaches[pool] = imageCache.Name
+			continue
+		}
+		status.ImageCaches[pool] = imageCache.Name
+		if err := c.updateTemplateImageCache(imageCache, spec); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", pool, err))
+		}
+	}
+	for pool, imageCache := range existing {
+		err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(imageCache.Namespace).Delete(context.TODO(), imageCache.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			failures = append(failures, fmt.Sprintf("%s: %v", pool, err))
+			continue
+		}
+		glog.Infof("Image cache %s/%s of node pool %s deleted: no nodes or images in the pool", imageCache.Namespace, imageCache.Name, pool)
+	}
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		status.Message = "Image caches of node pools not instantiated: " + strings.Join(failures, "; ")
+	}
+	return c.updateImageCacheTemplateStatus(template, status)
+}
+
+// updateTemplateImageCache updates the spec of an image cache instantiated from a
+// template. Image lists can not be added or removed, nor their node selector changed,
+// by an update: the image cache is deleted instead, and instantiated again by the next
+// sync of the template. The controller does not act on updates of an image cache under
+// processing: the image cache is updated once processed.
+func (c *Controller) updateTemplateImageCache(imageCache *v1alpha2.ImageCache, spec v1alpha2.ImageCacheSpec) error {
+	if reflect.DeepEqual(imageCache.Spec, spec) || imageCache.Status.Status == "" ||
+		imageCache.Status.Status == v1alpha2.ImageCacheActionStatusProcessing ||
+		imageCache.Status.Status == v1alpha2.ImageCacheActionStatusPendingApproval {
+		return nil
+	}
+	if !sameImageLists(imageCache.Spec.CacheSpec, spec.CacheSpec) {
+		err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(imageCache.Namespace).Delete(context.TODO(), imageCache.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		glog.Infof("Image cache %s/%s deleted to be instantiated again: its image lists changed", imageCache.Namespace, imageCache.Name)
+		return nil
+	}
+	updated := imageCache.DeepCopy()
+	updated.Spec = spec
+	if _, err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(imageCache.Namespace).Update(context.TODO(), updated, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	glog.Infof("Image cache %s/%s updated from its template", imageCache.Namespace, imageCache.Name)
+	return nil
+}
+
+// imageCachesOfTemplate returns the image caches owned by the template, by node pool
+func (c *Controller) imageCachesOfTemplate(template *v1alpha2.ImageCacheTemplate) (map[string]*v1alpha2.ImageCache, error) {
+	selector := labels.SelectorFromSet(labels.Set{imageCacheTemplateLabelKey: template.Name})
+	imageCaches, err := c.imageCachesLister.ImageCaches(template.Namespace).List(selector)
+	if err != nil {
+		return nil, err
+	}
+	owned := map[string]*v1alpha2.ImageCache{}
+	for _, imageCache := range imageCaches {
+		if owner := metav1.GetControllerOf(imageCache); owner != nil && owner.UID == template.UID {
+			owned[imageCache.Labels[nodePoolLabelKey]] = imageCache
+		}
+	}
+	return owned, nil
+}
+
+// updateImageCacheTemplateStatus updates the status of the template, if changed
+func (c *Controller) updateImageCacheTemplateStatus(template *v1alpha2.ImageCacheTemplate, status v1alpha2.ImageCacheTemplateStatus) error {
+	if len(status.ImageCaches) == 0 {
+		status.ImageCaches = nil
+	}
+	if reflect.DeepEqual(template.Status, status) {
+		return nil
+	}
+	templateCopy := template.DeepCopy()
+	templateCopy.Status = status
+	_, err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCacheTemplates(template.Namespace).Update(context.TODO(), templateCopy, metav1.UpdateOptions{})
+	return err
+}
+
+// nodePools returns the node pools of the nodes, i.e. the values of their node pool
+// label, sorted. The nodes without the label are in no node pool.
+func nodePools(nodes []*corev1.Node, labelKey string) []string {
+	pools := []string{}
+	for _, node := range nodes {
+		if pool := node.Labels[labelKey]; pool != "" && !containsString(pools, pool) {
+			pools = append(pools, pool)
+		}
+	}
+	sort.Strings(pools)
+	return pools
+}
+
+// templateImageCacheSpec returns the spec of the image cache instantiated from a template
+// for a node pool. The node selectors of the image lists of the template are restricted
+// to the nodes of the pool, and the images of the pool are appended as an image list.
+func templateImageCacheSpec(template *v1alpha2.ImageCacheTemplate, pool string) v1alpha2.ImageCacheSpec {
+	spec := template.Spec.Template.DeepCopy()
+	for i := range spec.CacheSpec {
+		if spec.CacheSpec[i].NodeSelector == nil {
+			spec.CacheSpec[i].NodeSelector = map[string]string{}
+		}
+		spec.CacheSpec[i].NodeSelector[template.Spec.NodePoolLabelKey] = pool
+	}
+	if images := template.Spec.Pools[pool]; len(images) > 0 {
+		spec.CacheSpec = append(spec.CacheSpec, v1alpha2.CacheSpecImages{
+			Images:       append([]string{}, images...),
+			NodeSelector: map[string]string{template.Spec.NodePoolLabelKey: pool},
+		})
+	}
+	return *spec
+}
+
+// newTemplateImageCache returns the image cache instantiated from a template for a node
+// pool. The image cache is owned by the template, so that it is garbage collected along
+// with the template.
+func newTemplateImageCache(template *v1alpha2.ImageCacheTemplate, pool string, spec v1alpha2.ImageCacheSpec) *v1alpha2.ImageCache {
+	return &v1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      templateImageCacheName(template.Name, pool),
+			Namespace: template.Namespace,
+			Labels:    map[string]string{imageCacheTemplateLabelKey: template.Name, nodePoolLabelKey: pool},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(template, v1alpha2.SchemeGroupVersion.WithKind("ImageCacheTemplate")),
+			},
+		},
+		Spec: spec,
+	}
+}
+
+// templateImageCacheName returns the name of the image cache of a node pool: the name of
+// the template suffixed with the node pool, lowercased, whose characters invalid in a name
+// are replaced with dashes
+func templateImageCacheName(template, pool string) string {
+	name := template + "-" + strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(pool), "-"), "-")
+	if len(name) > validation.DNS1123SubdomainMaxLength {
+		name = strings.TrimRight(name[:validation.DNS1123SubdomainMaxLength], "-")
+	}
+	return name
+}
+
+// sameImageLists returns true if two cache specs have the same number of image lists,
+// with the same node selectors
+func sameImageLists(a, b []v1alpha2.CacheSpecImages) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !reflect.DeepEqual(a[i].NodeSelector, b[i].NodeSelector) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestTemplateImageCacheSpec(t *testing.T) {
+	template := &kubefledgedv1alpha2.ImageCacheTemplate{
+		Spec: kubefledgedv1alpha2.ImageCacheTemplateSpec{
+			NodePoolLabelKey: "karpenter.sh/nodepool",
+			Template: kubefledgedv1alpha2.ImageCacheSpec{
+				CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+					{Images: []string{"nginx:1.23"}},
+					{Images: []string{"fluentd:v1"}, NodeSelector: map[string]string{"kubernetes.io/os": "linux"}},
+				},
+			},
+			Pools: map[string][]string{"gpu": {"cuda:12"}},
+		},
+	}
+	tests := []struct {
+		name              string
+		pool              string
+		expectedCacheSpec []kubefledgedv1alpha2.CacheSpecImages
+	}{
+		{
+			name: "#1: Node pool without images of its own",
+			pool: "general",
+			expectedCacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx:1.23"}, NodeSelector: map[string]string{"karpenter.sh/nodepool": "general"}},
+				{Images: []string{"fluentd:v1"}, NodeSelector: map[string]string{"kubernetes.io/os": "linux", "karpenter.sh/nodepool": "general"}},
+			},
+		},
+		{
+			name: "#2: Node pool with images of its own",
+			pool: "gpu",
+			expectedCacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx:1.23"}, NodeSelector: map[string]string{"karpenter.sh/nodepool": "gpu"}},
+				{Images: []string{"fluentd:v1"}, NodeSelector: map[string]string{"kubernetes.io/os": "linux", "karpenter.sh/nodepool": "gpu"}},
+				{Images: []string{"cuda:12"}, NodeSelector: map[string]string{"karpenter.sh/nodepool": "gpu"}},
+			},
+		},
+	}
+	for _, test := range tests {
+		spec := templateImageCacheSpec(template, test.pool)
+		if !reflect.DeepEqual(spec.CacheSpec, test.expectedCacheSpec) {
+			t.Errorf("Test: %s failed: expectedCacheSpec=%+v, actualCacheSpec=%+v", test.name, test.expectedCacheSpec, spec.CacheSpec)
+		}
+	}
+	if template.Spec.Template.CacheSpec[0].NodeSelector != nil {
+		t.Errorf("Test: template modified: %+v", template.Spec.Template.CacheSpec[0])
+	}
+}
+
+func TestTemplateImageCacheName(t *testing.T) {
+	tests := []struct {
+		name         string
+		pool         string
+		expectedName string
+	}{
+		{name: "#1: Valid node pool", pool: "gpu", expectedName: "base-gpu"},
+		{name: "#2: Node pool with invalid characters", pool: "Spot_Pool.A", expectedName: "base-spot-pool-a"},
+	}
+	for _, test := range tests {
+		if name := templateImageCacheName("base", test.pool); name != test.expectedName {
+			t.Errorf("Test: %s failed: expectedName=%s, actualName=%s", test.name, test.expectedName, name)
+		}
+	}
+}
+
+func TestSyncImageCacheTemplate(t *testing.T) {
+	template := &kubefledgedv1alpha2.ImageCacheTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: fledgedNameSpace, UID: "uid-base"},
+		Spec: kubefledgedv1alpha2.ImageCacheTemplateSpec{
+			NodePoolLabelKey: "pool",
+			Template: kubefledgedv1alpha2.ImageCacheSpec{
+				CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{{Images: []string{"nginx:1.23"}}},
+			},
+			Pools: map[string][]string{"gpu": {"cuda:12"}},
+		},
+	}
+	poolNode := func(name, pool string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"pool": pool}}}
+	}
+	instantiated := func(pool string, status kubefledgedv1alpha2.ImageCacheActionStatus, images ...string) *kubefledgedv1alpha2.ImageCache {
+		imageCache := newTemplateImageCache(template, pool, templateImageCacheSpec(template, pool))
+		imageCache.Spec.CacheSpec[0].Images = images
+		imageCache.Status.Status = status
+		return imageCache
+	}
+	withoutPoolImages := instantiated("gpu", kubefledgedv1alpha2.ImageCacheActionStatusSucceeded, "nginx:1.23")
+	withoutPoolImages.Spec.CacheSpec = withoutPoolImages.Spec.CacheSpec[:1]
+	tests := []struct {
+		name               string
+		nodes              []*corev1.Node
+		imageCaches        []*kubefledgedv1alpha2.ImageCache
+		expectedActions    []string
+		expectedCaches     map[string]string
+		expectedMessageSet bool
+	}{
+		{
+			name:            "#1: Image caches instantiated for the node pools discovered",
+			nodes:           []*corev1.Node{poolNode("n1", "general"), poolNode("n2", "gpu"), poolNode("n3", "general"), {}},
+			expectedActions: []string{"create", "create", "update"},
+			expectedCaches:  map[string]string{"general": "base-general", "gpu": "base-gpu"},
+		},
+		{
+			name:            "#2: Image cache in line with the template",
+			nodes:           []*corev1.Node{poolNode("n1", "general")},
+			imageCaches:     []*kubefledgedv1alpha2.ImageCache{instantiated("general", kubefledgedv1alpha2.ImageCacheActionStatusSucceeded, "nginx:1.23")},
+			expectedActions: []string{"update"},
+			expectedCaches:  map[string]string{"general": "base-general"},
+		},
+		{
+			name:            "#3: Images of the template changed",
+			nodes:           []*corev1.Node{poolNode("n1", "general")},
+			imageCaches:     []*kubefledgedv1alpha2.ImageCache{instantiated("general", kubefledgedv1alpha2.ImageCacheActionStatusSucceeded, "nginx:1.22")},
+			expectedActions: []string{"update", "update"},
+			expectedCaches:  map[string]string{"general": "base-general"},
+		},
+		{
+			name:            "#4: Image cache under processing not updated",
+			nodes:           []*corev1.Node{poolNode("n1", "general")},
+			imageCaches:     []*kubefledgedv1alpha2.ImageCache{instantiated("general", kubefledgedv1alpha2.ImageCacheActionStatusProcessing, "nginx:1.22")},
+			expectedActions: []string{"update"},
+			expectedCaches:  map[string]string{"general": "base-general"},
+		},
+		{
+			name:            "#5: Image lists changed - image cache deleted to be instantiated again",
+			nodes:           []*corev1.Node{poolNode("n1", "gpu")},
+			imageCaches:     []*kubefledgedv1alpha2.ImageCache{withoutPoolImages},
+			expectedActions: []string{"delete", "update"},
+			expectedCaches:  map[string]string{"gpu": "base-gpu"},
+		},
+		{
+			name:            "#6: Image cache of a node pool without nodes deleted",
+			nodes:           []*corev1.Node{},
+			imageCaches:     []*kubefledgedv1alpha2.ImageCache{instantiated("general", kubefledgedv1alpha2.ImageCacheActionStatusSucceeded, "nginx:1.23")},
+			expectedActions: []string{"delete"},
+		},
+		{
+			name:               "#7: Image cache of the same name not owned by the template",
+			nodes:              []*corev1.Node{poolNode("n1", "general")},
+			imageCaches:        []*kubefledgedv1alpha2.ImageCache{{ObjectMeta: metav1.ObjectMeta{Name: "base-general", Namespace: fledgedNameSpace}}},
+			expectedActions:    []string{"create", "update"},
+			expectedMessageSet: true,
+		},
+	}
+	for _, test := range tests {
+		objects := []runtime.Object{template.DeepCopy()}
+		for _, imageCache := range test.imageCaches {
+			objects = append(objects, imageCache)
+		}
+		fledgedclientset := kubefledgedclientsetfake.NewSimpleClientset(objects...)
+		controller, _, imagecacheInformer := newTestController(fakeclientset.NewSimpleClientset(), fledgedclientset)
+		for _, imageCache := range test.imageCaches {
+			imagecacheInformer.Informer().GetIndexer().Add(imageCache)
+		}
+		if err := controller.syncImageCacheTemplate(template, test.nodes); err != nil {
+			t.Errorf("Test: %s failed: expectedError=nil, actualError=%v", test.name, err)
+			continue
+		}
+		actions := []string{}
+		for _, action := range fledgedclientset.Actions() {
+			if action.GetVerb() != "get" && action.GetVerb() != "list" && action.GetVerb() != "watch" {
+				actions = append(actions, action.GetVerb())
+			}
+		}
+		if !reflect.DeepEqual(actions, test.expectedActions) {
+			t.Errorf("Test: %s failed: expectedActions=%v, actualActions=%v", test.name, test.expectedActions, actions)
+		}
+		updated, err := fledgedclientset.KubefledgedV1alpha2().ImageCacheTemplates(fledgedNameSpace).Get(context.TODO(), template.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Test: %s failed: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(updated.Status.ImageCaches, test.expectedCaches) {
+			t.Errorf("Test: %s failed: expectedImageCaches=%v, actualImageCaches=%v", test.name, test.expectedCaches, updated.Status.ImageCaches)
+		}
+		if (updated.Status.Message != "") != test.expectedMessageSet {
+			t.Errorf("Test: %s failed: unexpected message %q", test.name, updated.Status.Message)
+		}
+	}
+}
+
+func TestNodePools(t *testing.T) {
+	nodes := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "n1", Labels: map[string]string{"pool": "spot"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "n2", Labels: map[string]string{"pool": "general"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "n3", Labels: map[string]string{"pool": "spot"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "n4"}},
+	}
+	pools := nodePools(nodes, "pool")
+	expected := []string{"general", "spot"}
+	if !sort.StringsAreSorted(pools) || !reflect.DeepEqual(pools, expected) {
+		t.Errorf("expected node pools %v, actual %v", expected, pools)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/nodestats"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// imageFsUsageTimeout is the timeout of the reads of the stats summary of a node
+	imageFsUsageTimeout = 10 * time.Second
+	// imageFsUsageWorkers is the number of nodes whose stats summary is read in parallel
+	imageFsUsageWorkers = 16
+)
+
+// imageFsUsageBefore returns the image filesystem usage of the nodes targeted by the
+// work items of an action, at its start. Nodes whose usage cannot be read are left out.
+func (c *Controller) imageFsUsageBefore(workItems []imageWorkItem) []v1alpha2.NodeImageFsUsage {
+	nodes := []string{}
+	for _, w := range workItems {
+		if !containsString(nodes, w.node.Name) {
+			nodes = append(nodes, w.node.Name)
+		}
+	}
+	sort.Strings(nodes)
+	usage := []v1alpha2.NodeImageFsUsage{}
+	read := c.readImageFsUsage(nodes)
+	for _, node := range nodes {
+		if u, ok := read[node]; ok {
+			usage = append(usage, v1alpha2.NodeImageFsUsage{Node: node, BeforeBytes: u.UsedBytes, CapacityBytes: u.CapacityBytes})
+		}
+	}
+	return usage
+}
+
+// imageFsUsageAfter returns the image filesystem usage recorded at the start of an
+// action, completed with the usage of the nodes at its end and the difference. Nodes
+// whose usage cannot be read at the end are reported without it.
+func (c *Controller) imageFsUsageAfter(before []v1alpha2.NodeImageFsUsage) []v1alpha2.NodeImageFsUsage {
+	nodes := []string{}
+	for _, u := range before {
+		nodes = append(nodes, u.Node)
+	}
+	read := c.readImageFsUsage(nodes)
+	usage := []v1alpha2.NodeImageFsUsage{}
+	for _, u := range before {
+		if after, ok := read[u.Node]; ok {
+			u.AfterBytes = after.UsedBytes
+			u.DeltaBytes = after.UsedBytes - u.BeforeBytes
+			u.CapacityBytes = after.CapacityBytes
+		}
+		usage = append(usage, u)
+	}
+	return usage
+}
+
+// readImageFsUsage reads the image filesystem usage of nodes in parallel
+func (c *Controller) readImageFsUsage(nodes []string) map[string]nodestats.ImageFsUsage {
+	var lock sync.Mutex
+	read := map[string]nodestats.ImageFsUsage{}
+	workqueue.ParallelizeUntil(context.TODO(), imageFsUsageWorkers, len(nodes), func(i int) {
+		usage, err := c.imageFsReader.ImageFsUsage(nodes[i])
+		if err != nil {
+			glog.Errorf("Error reading image filesystem usage: %v", err)
+			return
+		}
+		lock.Lock()
+		read[nodes[i]] = usage
+		lock.Unlock()
+	})
+	return read
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	"github.com/senthilrch/kube-fledged/pkg/nodestats"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+type fakeImageFsReader map[string]nodestats.ImageFsUsage
+
+func (f fakeImageFsReader) ImageFsUsage(node string) (nodestats.ImageFsUsage, error) {
+	if usage, ok := f[node]; ok {
+		return usage, nil
+	}
+	return nodestats.ImageFsUsage{}, fmt.Errorf("node %s not found", node)
+}
+
+func TestImageFsUsage(t *testing.T) {
+	controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	node1 := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	node2 := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2"}}
+	node3 := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node3"}}
+	workItems := []imageWorkItem{
+		{image: "nginx:1.23", node: node2},
+		{image: "nginx:1.23", node: node1},
+		{image: "httpd:2.4", node: node1},
+		{image: "httpd:2.4", node: node3},
+	}
+
+	controller.imageFsReader = fakeImageFsReader{
+		"node1": {UsedBytes: 1000, CapacityBytes: 10000},
+		"node2": {UsedBytes: 2000, CapacityBytes: 10000},
+	}
+	before := controller.imageFsUsageBefore(workItems)
+	expectedBefore := []kubefledgedv1alpha2.NodeImageFsUsage{
+		{Node: "node1", BeforeBytes: 1000, CapacityBytes: 10000},
+		{Node: "node2", BeforeBytes: 2000, CapacityBytes: 10000},
+	}
+	if !reflect.DeepEqual(before, expectedBefore) {
+		t.Fatalf("expected usage before %+v, actual %+v", expectedBefore, before)
+	}
+
+	controller.imageFsReader = fakeImageFsReader{
+		"node1": {UsedBytes: 1500, CapacityBytes: 10000},
+	}
+	after := controller.imageFsUsageAfter(before)
+	expectedAfter := []kubefledgedv1alpha2.NodeImageFsUsage{
+		{Node: "node1", BeforeBytes: 1000, AfterBytes: 1500, DeltaBytes: 500, CapacityBytes: 10000},
+		{Node: "node2", BeforeBytes: 2000, CapacityBytes: 10000},
+	}
+	if !reflect.DeepEqual(after, expectedAfter) {
+		t.Errorf("expected usage after %+v, actual %+v", expectedAfter, after)
+	}
+}
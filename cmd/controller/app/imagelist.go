@@ -0,0 +1,98 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"reflect"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// imageListRef returns the namespace and the name of the ImageList referenced by an
+// image list provider of an image cache. The namespace defaults to the namespace of the
+// image cache.
+func imageListRef(imageCache *v1alpha2.ImageCache, ref *v1alpha2.ImageListReference) (string, string) {
+	if ref.Namespace == "" {
+		return imageCache.Namespace, ref.Name
+	}
+	return ref.Namespace, ref.Name
+}
+
+// imageListEntries returns the indexes of the image lists of the image cache listing the
+// images of an ImageList. If missingOnly is set, only the image lists missing images of
+// the ImageList are returned.
+func imageListEntries(imageCache *v1alpha2.ImageCache, imageList *v1alpha2.ImageList, missingOnly bool) []int {
+	entries := []int{}
+	imageLists := imageListsOf(imageCache)
+	for k, i := range imageCache.Spec.CacheSpec {
+		for _, spec := range i.Providers {
+			if spec.ImageList == nil {
+				continue
+			}
+			namespace, name := imageListRef(imageCache, spec.ImageList)
+			if namespace != imageList.Namespace || name != imageList.Name {
+				continue
+			}
+			if !missingOnly || !containsAll(imageLists[k].Images, imageList.Spec.Images) {
+				entries = append(entries, k)
+			}
+			break
+		}
+	}
+	return entries
+}
+
+// containsAll returns true if all the non-empty strings of b are in a
+func containsAll(a, b []string) bool {
+	for _, s := range b {
+		if s != "" && !containsString(a, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// handleImageListChange queues the image caches listing the images of an ImageList which
+// was created or whose images changed for the resolve worker, which refreshes their image
+// lists. The refresh pulls the added images and, with prune, purges the removed ones.
+// When an ImageList is created, only the image caches missing some of its images are
+// queued, so that the image caches are not refreshed when the controller starts.
+// Deleting an ImageList leaves the image lists unchanged.
+func (c *Controller) handleImageListChange(old, new *v1alpha2.ImageList) {
+	if old != nil && reflect.DeepEqual(old.Spec.Images, new.Spec.Images) {
+		return
+	}
+	imageCaches, err := c.imageCachesLister.ImageCaches("").List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error in listing image caches: %v", err)
+		return
+	}
+	for _, imageCache := range imageCaches {
+		if !canRefresh(imageCache) {
+			continue
+		}
+		entries := imageListEntries(imageCache, new, old == nil)
+		if len(entries) == 0 {
+			continue
+		}
+		glog.Infof("ImageList %s/%s of image lists %v of image cache %s/%s changed, resolving", new.Namespace, new.Name, entries,
+			imageCache.Namespace, imageCache.Name)
+		c.enqueueResolve(imageCache)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestHandleImageListChange(t *testing.T) {
+	newImageList := func(images ...string) *kubefledgedv1alpha2.ImageList {
+		return &kubefledgedv1alpha2.ImageList{
+			ObjectMeta: metav1.ObjectMeta{Name: "base-images", Namespace: "platform"},
+			Spec:       kubefledgedv1alpha2.ImageListSpec{Images: images},
+		}
+	}
+	imageListProvider := func(namespace, name string) []kubefledgedv1alpha2.ImageListProviderSpec {
+		return []kubefledgedv1alpha2.ImageListProviderSpec{{
+			ImageList: &kubefledgedv1alpha2.ImageListReference{Namespace: namespace, Name: name}, Prune: true,
+		}}
+	}
+	cacheSpec := []kubefledgedv1alpha2.CacheSpecImages{
+		{Images: []string{"redis:7"}},
+		{Images: []string{"nginx:1.23"}, Providers: imageListProvider("platform", "base-images"),
+			NodeSelector: map[string]string{"pool": "web"}},
+		{Images: []string{"nginx:1.23"}, Providers: imageListProvider("platform", "ml-images")},
+	}
+	tests := []struct {
+		name            string
+		namespace       string
+		cacheSpec       []kubefledgedv1alpha2.CacheSpecImages
+		status          kubefledgedv1alpha2.ImageCacheActionStatus
+		old             *kubefledgedv1alpha2.ImageList
+		new             *kubefledgedv1alpha2.ImageList
+		expectedEntries []int
+	}{
+		{
+			name:            "#1: Images of the image list updated",
+			namespace:       "team",
+			cacheSpec:       cacheSpec,
+			status:          kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			old:             newImageList("nginx:1.23"),
+			new:             newImageList("nginx:1.25"),
+			expectedEntries: []int{1},
+		},
+		{
+			name:      "#2: Images of the image list unchanged",
+			namespace: "team",
+			cacheSpec: cacheSpec,
+			status:    kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			old:       newImageList("nginx:1.23"),
+			new:       newImageList("nginx:1.23"),
+		},
+		{
+			name:      "#3: Image list created with the images of the image lists",
+			namespace: "team",
+			cacheSpec: cacheSpec,
+			status:    kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			new:       newImageList("nginx:1.23"),
+		},
+		{
+			name:            "#4: Image list created with missing images",
+			namespace:       "team",
+			cacheSpec:       cacheSpec,
+			status:          kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			new:             newImageList("nginx:1.23", "httpd:2.4"),
+			expectedEntries: []int{1},
+		},
+		{
+			name:      "#5: Image list of the namespace of the image cache",
+			namespace: "platform",
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx:1.23"}, Providers: imageListProvider("", "base-images")},
+			},
+			status:          kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			old:             newImageList("nginx:1.23"),
+			new:             newImageList("nginx:1.25"),
+			expectedEntries: []int{0},
+		},
+		{
+			name:      "#6: Image list of another namespace",
+			namespace: "team",
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx:1.23"}, Providers: imageListProvider("", "base-images")},
+			},
+			status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			old:    newImageList("nginx:1.23"),
+			new:    newImageList("nginx:1.25"),
+		},
+		{
+			name:      "#7: Image cache under processing",
+			namespace: "team",
+			cacheSpec: cacheSpec,
+			status:    kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+			old:       newImageList("nginx:1.23"),
+			new:       newImageList("nginx:1.25"),
+		},
+	}
+	for _, test := range tests {
+		controller, _, imagecacheInformer := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+		imagecacheInformer.Informer().GetIndexer().Add(&kubefledgedv1alpha2.ImageCache{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: test.namespace},
+			Spec:       kubefledgedv1alpha2.ImageCacheSpec{CacheSpec: test.cacheSpec},
+			Status:     kubefledgedv1alpha2.ImageCacheStatus{Status: test.status},
+		})
+		controller.handleImageListChange(test.old, test.new)
+		if test.expectedEntries == nil {
+			if controller.resolveQueue.Len() != 0 {
+				t.Errorf("Test: %s failed: expected no image cache to be resolved", test.name)
+			}
+			continue
+		}
+		if key, _ := controller.resolveQueue.Get(); key != test.namespace+"/foo" {
+			t.Errorf("Test: %s failed: unexpected resolve queue key %v", test.name, key)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// BuildConfig returns the configuration of the clients of the controller. The in-cluster
+// configuration is used unless a kubeconfig, a context or a master URL is given, in
+// which case the controller runs out of the cluster. The kubeconfig defaults to the
+// KUBECONFIG environment variable or ~/.kube/config, and its current context and
+// server may be overridden.
+func BuildConfig(kubeconfig, kubeContext, masterURL string) (*rest.Config, error) {
+	if kubeconfig == "" && kubeContext == "" && masterURL == "" {
+		return rest.InClusterConfig()
+	}
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	overrides.ClusterInfo.Server = masterURL
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+}
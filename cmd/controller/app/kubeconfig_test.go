@@ -0,0 +1,103 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: management
+  cluster:
+    server: https://management.example.com:6443
+- name: workload
+  cluster:
+    server: https://workload.example.com:6443
+contexts:
+- name: management
+  context:
+    cluster: management
+    user: admin
+- name: workload
+  context:
+    cluster: workload
+    user: admin
+current-context: management
+users:
+- name: admin
+  user:
+    token: secret
+`
+
+func TestBuildConfig(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := os.WriteFile(kubeconfig, []byte(testKubeconfig), 0600); err != nil {
+		t.Fatalf("Error writing kubeconfig: %v", err)
+	}
+	tests := []struct {
+		name         string
+		kubeconfig   string
+		kubeContext  string
+		masterURL    string
+		expectedHost string
+		expectErr    bool
+	}{
+		{
+			name:         "#1: Current context of the kubeconfig",
+			kubeconfig:   kubeconfig,
+			expectedHost: "https://management.example.com:6443",
+		},
+		{
+			name:         "#2: Context overridden",
+			kubeconfig:   kubeconfig,
+			kubeContext:  "workload",
+			expectedHost: "https://workload.example.com:6443",
+		},
+		{
+			name:         "#3: Master URL overridden",
+			kubeconfig:   kubeconfig,
+			masterURL:    "https://other.example.com:6443",
+			expectedHost: "https://other.example.com:6443",
+		},
+		{
+			name:        "#4: Unknown context",
+			kubeconfig:  kubeconfig,
+			kubeContext: "unknown",
+			expectErr:   true,
+		},
+	}
+	for _, test := range tests {
+		cfg, err := BuildConfig(test.kubeconfig, test.kubeContext, test.masterURL)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("Test: %s failed: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test: %s failed: unexpected error: %v", test.name, err)
+			continue
+		}
+		if cfg.Host != test.expectedHost || cfg.BearerToken != "secret" {
+			t.Errorf("Test: %s failed: expected host %s, actual %s", test.name, test.expectedHost, cfg.Host)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"sort"
+	"strings"
+
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/features"
+	"github.com/senthilrch/kube-fledged/pkg/registry"
+)
+
+// layerStats returns the layer statistics of the images of the image cache on the nodes
+// targeted by its image lists. Nodes caching the same images for the same platform are
+// grouped. The layers of the images are cached by the metadata worker: images whose
+// layers are not cached yet, or cannot be read from the registry, are left out.
+func (c *Controller) layerStats(imageCache *v1alpha2.ImageCache) []v1alpha2.LayerStats {
+	if !features.Enabled(features.LayerStats) {
+		return nil
+	}
+	// Images cached on each node, with the platform they are cached for
+	nodeImages := map[string]map[string]string{}
+	for _, cacheSpec := range imageListsOf(imageCache) {
+		nodes, err := c.nodesForCacheSpec(cacheSpec)
+		if err != nil {
+			return nil
+		}
+		for _, n := range nodes {
+			if nodeImages[n.Name] == nil {
+				nodeImages[n.Name] = map[string]string{}
+			}
+			for _, image := range cacheSpec.Images {
+				nodeImages[n.Name][image] = nodePlatform(cacheSpec.Platforms[image], n)
+			}
+		}
+	}
+	nodeNames := []string{}
+	for name := range nodeImages {
+		nodeNames = append(nodeNames, name)
+	}
+	sort.Strings(nodeNames)
+
+	keychain := c.keychainOf(imageCache)
+	imageLayers := func(image, platform string) []registry.Layer {
+		metadata, ok := c.cachedImageLayers(image, platform, keychain)
+		if !ok || metadata.err != nil {
+			return nil
+		}
+		return metadata.layers
+	}
+
+	groups := map[string]int{}
+	stats := []v1alpha2.LayerStats{}
+	for _, name := range nodeNames {
+		refs := []string{}
+		for image, platform := range nodeImages[name] {
+			refs = append(refs, image+"@"+platform)
+		}
+		sort.Strings(refs)
+		key := strings.Join(refs, ",")
+		if i, ok := groups[key]; ok {
+			stats[i].Nodes = append(stats[i].Nodes, name)
+			continue
+		}
+		images := map[string][]registry.Layer{}
+		platforms := map[string]bool{}
+		for image, platform := range nodeImages[name] {
+			if l := imageLayers(image, platform); l != nil {
+				images[image] = l
+				platforms[platform] = true
+			}
+		}
+		if len(images) == 0 {
+			continue
+		}
+		s := computeLayerStats(images)
+		s.Nodes = []string{name}
+		s.Platform = strings.Join(sortedKeys(platforms), ",")
+		groups[key] = len(stats)
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// computeLayerStats returns the layer statistics of images given their layers
+func computeLayerStats(images map[string][]registry.Layer) v1alpha2.LayerStats {
+	// Images using each distinct layer
+	users := map[string]map[string]bool{}
+	sizes := map[string]int64{}
+	for image, layers := range images {
+		for _, l := range layers {
+			if users[l.Digest] == nil {
+				users[l.Digest] = map[string]bool{}
+			}
+			users[l.Digest][image] = true
+			sizes[l.Digest] = l.Size
+		}
+	}
+	s := v1alpha2.LayerStats{Images: []v1alpha2.ImageLayerStats{}}
+	for digest, size := range sizes {
+		s.DedupBytes += size
+		if len(users[digest]) > 1 {
+			s.SharedBytes += size
+		}
+	}
+	for image, layers := range images {
+		is := v1alpha2.ImageLayerStats{Image: image}
+		counted := map[string]bool{}
+		for _, l := range layers {
+			if counted[l.Digest] {
+				continue
+			}
+			counted[l.Digest] = true
+			is.Bytes += l.Size
+			if len(users[l.Digest]) == 1 {
+				is.UniqueBytes += l.Size
+			}
+		}
+		s.TotalBytes += is.Bytes
+		s.Images = append(s.Images, is)
+	}
+	sort.Slice(s.Images, func(i, j int) bool { return s.Images[i].Image < s.Images[j].Image })
+	return s
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	"github.com/senthilrch/kube-fledged/pkg/features"
+	"github.com/senthilrch/kube-fledged/pkg/registry"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+type fakeLayerLister map[string][]registry.Layer
+
+func (f fakeLayerLister) ImageLayers(image string, platform string, keychain authn.Keychain) ([]registry.Layer, error) {
+	if layers, ok := f[image+"@"+platform]; ok {
+		return layers, nil
+	}
+	return nil, fmt.Errorf("image %s not found", image)
+}
+
+func TestLayerStats(t *testing.T) {
+	newNode := func(name, pool string) *corev1.Node {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"pool": pool}}}
+		node.Status.NodeInfo = corev1.NodeSystemInfo{OperatingSystem: "linux", Architecture: "amd64"}
+		return node
+	}
+	enableFeatures(t, features.LayerStats)
+	controller, nodeInformer, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	for _, node := range []*corev1.Node{newNode("node1", "web"), newNode("node2", "web"), newNode("node3", "batch")} {
+		nodeInformer.Informer().GetIndexer().Add(node)
+	}
+	base := registry.Layer{Digest: "sha256:base", Size: 100}
+	controller.layerLister = fakeLayerLister{
+		"nginx:1.23@linux/amd64":  {{Digest: "sha256:nginx-config", Size: 1}, base, {Digest: "sha256:nginx", Size: 50}},
+		"httpd:2.4@linux/amd64":   {{Digest: "sha256:httpd-config", Size: 1}, base, {Digest: "sha256:httpd", Size: 30}},
+		"python:3.11@linux/amd64": {{Digest: "sha256:python-config", Size: 1}, {Digest: "sha256:python", Size: 500}},
+	}
+	imageCache := &kubefledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+		Spec: kubefledgedv1alpha2.ImageCacheSpec{
+			CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx:1.23", "httpd:2.4"}, NodeSelector: map[string]string{"pool": "web"}},
+				{Images: []string{"python:3.11", "missing:v1"}, NodeSelector: map[string]string{"pool": "batch"}},
+			},
+		},
+	}
+	expected := []kubefledgedv1alpha2.LayerStats{
+		{
+			Nodes:       []string{"node1", "node2"},
+			Platform:    "linux/amd64",
+			TotalBytes:  282,
+			DedupBytes:  182,
+			SharedBytes: 100,
+			Images: []kubefledgedv1alpha2.ImageLayerStats{
+				{Image: "httpd:2.4", Bytes: 131, UniqueBytes: 31},
+				{Image: "nginx:1.23", Bytes: 151, UniqueBytes: 51},
+			},
+		},
+		{
+			Nodes:      []string{"node3"},
+			Platform:   "linux/amd64",
+			TotalBytes: 501,
+			DedupBytes: 501,
+			Images: []kubefledgedv1alpha2.ImageLayerStats{
+				{Image: "python:3.11", Bytes: 501, UniqueBytes: 501},
+			},
+		},
+	}
+	if actual := controller.layerStats(imageCache); len(actual) != 0 {
+		t.Errorf("expected no layer stats before the layers are read from the registries, actual %+v", actual)
+	}
+	readImageMetadata(controller)
+	if actual := controller.layerStats(imageCache); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected layer stats %+v, actual %+v", expected, actual)
+	}
+	features.DefaultMutableFeatureGate.SetFromMap(map[string]bool{string(features.LayerStats): false})
+	if actual := controller.layerStats(imageCache); actual != nil {
+		t.Errorf("expected no layer stats with the %s feature gate disabled, actual %+v", features.LayerStats, actual)
+	}
+}
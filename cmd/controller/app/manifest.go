@@ -0,0 +1,207 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/docker/distribution/reference"
+	"github.com/golang/glog"
+	"github.com/opencontainers/go-digest"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+// lastAppliedConfigAnnotationKey is the annotation of the objects applied with kubectl
+const lastAppliedConfigAnnotationKey = "kubectl.kubernetes.io/last-applied-configuration"
+
+// cacheManifest is a snapshot of the image caches of a cluster, applied with kubectl to
+// re-warm a rebuilt cluster to the same state
+type cacheManifest struct {
+	metav1.TypeMeta `json:",inline"`
+	Items           []manifestImageCache `json:"items"`
+}
+
+// manifestImageCache is an image cache of a cache manifest, without its status
+type manifestImageCache struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              v1alpha2.ImageCacheSpec `json:"spec"`
+}
+
+// serveCacheManifest serves the cache manifest of the image caches as YAML, optionally
+// restricted to the namespace given by the namespace query parameter
+func (c *Controller) serveCacheManifest(w http.ResponseWriter, r *http.Request) {
+	manifest, err := c.cacheManifest(r.URL.Query().Get("namespace"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out, err := yaml.Marshal(manifest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	if _, err := w.Write(out); err != nil {
+		glog.Errorf("Error writing cache manifest: %v", err)
+	}
+}
+
+// cacheManifest returns the cache manifest of the image caches of a namespace, or of all
+// namespaces if empty. The images are pinned to the digest held by the nodes, so that
+// the same images are pulled once the manifest is applied, and the coverage of each
+// image is recorded in an annotation.
+func (c *Controller) cacheManifest(namespace string) (*cacheManifest, error) {
+	imageCaches, err := c.imageCachesLister.ImageCaches(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(imageCaches, func(i, j int) bool {
+		if imageCaches[i].Namespace != imageCaches[j].Namespace {
+			return imageCaches[i].Namespace < imageCaches[j].Namespace
+		}
+		return imageCaches[i].Name < imageCaches[j].Name
+	})
+	manifest := &cacheManifest{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "List"}, Items: []manifestImageCache{}}
+	for _, imageCache := range imageCaches {
+		item := manifestImageCache{
+			TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha2.SchemeGroupVersion.String(), Kind: "ImageCache"},
+			ObjectMeta: metav1.ObjectMeta{Name: imageCache.Name, Namespace: imageCache.Namespace, Labels: imageCache.Labels},
+			Spec:       *imageCache.Spec.DeepCopy(),
+		}
+		annotations := map[string]string{}
+		for key, value := range imageCache.Annotations {
+			switch key {
+			case imageCachePurgeAnnotationKey, imageCacheRefreshAnnotationKey, imageCacheCancelAnnotationKey,
+				imageCacheApproveAnnotationKey, lastAppliedConfigAnnotationKey, v1alpha2.ImageCacheManifestCoverageAnnotationKey:
+				continue
+			}
+			annotations[key] = value
+		}
+		coverage := map[string]string{}
+		for k, cacheSpec := range item.Spec.CacheSpec {
+			nodes, err := c.nodesForCacheSpec(cacheSpec)
+			if err != nil {
+				return nil, err
+			}
+			for m, image := range cacheSpec.Images {
+				cached := 0
+				for _, n := range nodes {
+					if imagePresentOnNode(image, n) {
+						cached++
+					}
+				}
+				pinned := pinImageDigest(image, nodes)
+				coverage[pinned] = fmt.Sprintf("%d/%d", cached, len(nodes))
+				if pinned == image {
+					continue
+				}
+				item.Spec.CacheSpec[k].Images[m] = pinned
+				if platform, ok := cacheSpec.Platforms[image]; ok {
+					delete(item.Spec.CacheSpec[k].Platforms, image)
+					item.Spec.CacheSpec[k].Platforms[pinned] = platform
+				}
+				if pullPolicy, ok := cacheSpec.ImagePullPolicies[image]; ok {
+					delete(item.Spec.CacheSpec[k].ImagePullPolicies, image)
+					item.Spec.CacheSpec[k].ImagePullPolicies[pinned] = pullPolicy
+				}
+				if fallbacks, ok := cacheSpec.Fallbacks[image]; ok {
+					delete(item.Spec.CacheSpec[k].Fallbacks, image)
+					item.Spec.CacheSpec[k].Fallbacks[pinned] = fallbacks
+				}
+				if targetRef, ok := cacheSpec.TargetRefs[image]; ok {
+					delete(item.Spec.CacheSpec[k].TargetRefs, image)
+					item.Spec.CacheSpec[k].TargetRefs[pinned] = targetRef
+				}
+			}
+		}
+		if len(coverage) > 0 {
+			value, _ := json.Marshal(coverage)
+			annotations[v1alpha2.ImageCacheManifestCoverageAnnotationKey] = string(value)
+		}
+		if len(annotations) > 0 {
+			item.Annotations = annotations
+		}
+		manifest.Items = append(manifest.Items, item)
+	}
+	return manifest, nil
+}
+
+// pinImageDigest returns the image pinned to the digest held by most of the nodes, e.g.
+// nginx:1.23@sha256:..., or the image unchanged if it is pinned already or no node
+// reports its digest
+func pinImageDigest(image string, nodes []*corev1.Node) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+	if _, ok := named.(reference.Canonical); ok {
+		return image
+	}
+	named = reference.TagNameOnly(named)
+	counts := map[digest.Digest]int{}
+	for _, n := range nodes {
+		for _, nodeImage := range n.Status.Images {
+			if d := nodeImageDigest(named, nodeImage.Names); d != "" {
+				counts[d]++
+			}
+		}
+	}
+	var pinned digest.Digest
+	for d, count := range counts {
+		if count > counts[pinned] || (count == counts[pinned] && d < pinned) {
+			pinned = d
+		}
+	}
+	if pinned == "" {
+		return image
+	}
+	canonical, err := reference.WithDigest(named, pinned)
+	if err != nil {
+		return image
+	}
+	return reference.FamiliarString(canonical)
+}
+
+// nodeImageDigest returns the digest of an image reported by a node under the given
+// names, if they include the image
+func nodeImageDigest(named reference.Named, names []string) digest.Digest {
+	found := false
+	var d digest.Digest
+	for _, name := range names {
+		nodeNamed, err := reference.ParseNormalizedNamed(name)
+		if err != nil || nodeNamed.Name() != named.Name() {
+			continue
+		}
+		if canonical, ok := nodeNamed.(reference.Canonical); ok {
+			d = canonical.Digest()
+		} else if nodeNamed.String() == named.String() {
+			found = true
+		}
+	}
+	if !found {
+		return ""
+	}
+	return d
+}
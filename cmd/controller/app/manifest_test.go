@@ -0,0 +1,141 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	fooDigestV1 = "sha256:7031c1b283388d2c2e09b57badb803c05ebed362dc88d84b480cc47f72a21097"
+	fooDigestV2 = "sha256:9e9a3dbd1ad4f6b5d1e4bfa2a2e7b4ad25d4da5a1c3f2f0c6f1c4d3e2b1a0f9e"
+)
+
+func TestPinImageDigest(t *testing.T) {
+	newNode := func(names ...string) *corev1.Node {
+		node := &corev1.Node{}
+		node.Status.Images = []corev1.ContainerImage{{Names: names}}
+		return node
+	}
+	nodes := []*corev1.Node{
+		newNode("docker.io/library/foo@"+fooDigestV1, "docker.io/library/foo:v1"),
+		newNode("docker.io/library/foo@"+fooDigestV2, "docker.io/library/foo:v1"),
+		newNode("docker.io/library/foo@"+fooDigestV2, "docker.io/library/foo:v1"),
+		newNode("registry.internal/bar@"+fooDigestV1, "registry.internal/bar:v2"),
+	}
+	tests := []struct {
+		name          string
+		image         string
+		expectedImage string
+	}{
+		{
+			name:          "#1: Image pinned to the digest held by most nodes",
+			image:         "foo:v1",
+			expectedImage: "foo:v1@" + fooDigestV2,
+		},
+		{
+			name:          "#2: Image not held by the nodes",
+			image:         "registry.internal/bar:v1",
+			expectedImage: "registry.internal/bar:v1",
+		},
+		{
+			name:          "#3: Image pinned already",
+			image:         "foo@" + fooDigestV1,
+			expectedImage: "foo@" + fooDigestV1,
+		},
+	}
+	for _, test := range tests {
+		if image := pinImageDigest(test.image, nodes); image != test.expectedImage {
+			t.Errorf("Test: %s failed: expected image %s, actual %s", test.name, test.expectedImage, image)
+		}
+	}
+}
+
+func TestCacheManifest(t *testing.T) {
+	nodes := []corev1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"kubernetes.io/os": "linux"}},
+			Status: corev1.NodeStatus{Images: []corev1.ContainerImage{
+				{Names: []string{"docker.io/library/foo@" + fooDigestV1, "docker.io/library/foo:v1"}},
+			}},
+		},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"kubernetes.io/os": "linux"}}},
+	}
+	imageCache := &kubefledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   fledgedNameSpace,
+			Labels:      map[string]string{"team": "web"},
+			Annotations: map[string]string{imageCacheRefreshAnnotationKey: "", "owner": "web"},
+		},
+		Spec: kubefledgedv1alpha2.ImageCacheSpec{
+			CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo:v1", "bar:v1"}, Platforms: map[string]string{"foo:v1": "linux/arm64"}},
+			},
+		},
+		Status: kubefledgedv1alpha2.ImageCacheStatus{Status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded},
+	}
+	controller, nodeInformer, imagecacheInformer := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	for i := range nodes {
+		nodeInformer.Informer().GetIndexer().Add(&nodes[i])
+	}
+	imagecacheInformer.Informer().GetIndexer().Add(imageCache)
+
+	manifest, err := controller.cacheManifest("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(manifest.Items) != 1 {
+		t.Fatalf("expected 1 image cache, got %d", len(manifest.Items))
+	}
+	item := manifest.Items[0]
+	pinned := "foo:v1@" + fooDigestV1
+	expectedSpec := kubefledgedv1alpha2.ImageCacheSpec{
+		CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+			{Images: []string{pinned, "bar:v1"}, Platforms: map[string]string{pinned: "linux/arm64"}},
+		},
+	}
+	if !reflect.DeepEqual(item.Spec, expectedSpec) {
+		t.Errorf("expected spec %+v, actual %+v", expectedSpec, item.Spec)
+	}
+	expectedAnnotations := map[string]string{
+		"owner": "web",
+		kubefledgedv1alpha2.ImageCacheManifestCoverageAnnotationKey: `{"bar:v1":"0/2","` + pinned + `":"1/2"}`,
+	}
+	if !reflect.DeepEqual(item.Annotations, expectedAnnotations) {
+		t.Errorf("expected annotations %v, actual %v", expectedAnnotations, item.Annotations)
+	}
+	if imageCache.Spec.CacheSpec[0].Images[0] != "foo:v1" {
+		t.Errorf("expected image cache in the informer cache to be left unchanged")
+	}
+
+	rec := httptest.NewRecorder()
+	controller.serveCacheManifest(rec, httptest.NewRequest(http.MethodGet, "/api/manifest?namespace=other", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "kind: List") || strings.Contains(rec.Body.String(), "name: foo") {
+		t.Errorf("expected empty cache manifest for namespace other, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"github.com/prometheus/common/expfmt"
+	"github.com/senthilrch/kube-fledged/pkg/version"
+)
+
+// metricsNamespace is the namespace of the metrics of kubefledged-controller
+const metricsNamespace = "kubefledged"
+
+var (
+	// metricsRegistry is the registry of the metrics of kubefledged-controller
+	metricsRegistry = prometheus.NewRegistry()
+
+	cachedImagePods = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "cached_image_pods",
+		Help:      "Number of running or pending pods using a cached image",
+	}, []string{"namespace", "imagecache", "image", "display_tag"})
+
+	cachedImageLastUsed = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "cached_image_last_used_timestamp_seconds",
+		Help:      "Last time a pod was found using a cached image, in seconds since the epoch",
+	}, []string{"namespace", "imagecache", "image", "display_tag"})
+
+	evictedImages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "evicted_images_total",
+		Help:      "Number of cached images found missing on nodes by verification",
+	}, []string{"namespace", "imagecache"})
+
+	registryPulledBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "registry_pulled_bytes_total",
+		Help:      "Estimated bytes pulled from a registry by the image pulls of an image cache",
+	}, []string{"registry", "namespace", "imagecache"})
+
+	imageCacheActionPulledBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "imagecache_action_pulled_bytes",
+		Help:      "Estimated bytes pulled from the registries by the last action (create, update or refresh) of an image cache",
+	}, []string{"namespace", "imagecache"})
+
+	buildInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "build_info",
+		Help:      "Build information of kubefledged-controller, with a constant value of 1",
+	}, []string{"version", "commit", "build_date", "go_version"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(cachedImagePods, cachedImageLastUsed, evictedImages,
+		registryPulledBytes, imageCacheActionPulledBytes, buildInfo)
+	info := version.Get()
+	buildInfo.WithLabelValues(info.Version, info.Commit, info.BuildDate, info.GoVersion).Set(1)
+}
+
+// StartMetricsServer serves the metrics of kubefledged-controller in the Prometheus
+// exposition format at "/metrics" on addr, and its build information at "/version",
+// until stopCh is closed.
+func StartMetricsServer(addr string, stopCh <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/version", serveVersion)
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-stopCh
+		server.Shutdown(context.Background())
+	}()
+	glog.Infof("Metrics server listening on %s", addr)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// serveVersion serves the build information of kubefledged-controller as JSON
+func serveVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(version.Get()); err != nil {
+		glog.Errorf("Error encoding version: %v", err)
+	}
+}
+
+// ExportMetrics pushes the metrics of kubefledged-controller to the Prometheus
+// Pushgateway at pushgatewayURL, replacing the metrics of the previous runs of the job,
+// and writes them in the OpenMetrics text format to the file at path. Either is skipped
+// if empty. It is called when the controller exits, so that the metrics of short-lived
+// runs, e.g. warming the nodes then exiting, outlive the controller.
+func ExportMetrics(pushgatewayURL, job, path string) error {
+	if pushgatewayURL != "" {
+		if err := push.New(pushgatewayURL, job).Gatherer(metricsRegistry).Push(); err != nil {
+			return fmt.Errorf("error pushing metrics to %s: %v", pushgatewayURL, err)
+		}
+		glog.Infof("Metrics pushed to %s (job %s)", pushgatewayURL, job)
+	}
+	if path != "" {
+		if err := writeOpenMetrics(metricsRegistry, path); err != nil {
+			return fmt.Errorf("error writing metrics to %s: %v", path, err)
+		}
+		glog.Infof("Metrics written to %s", path)
+	}
+	return nil
+}
+
+// writeOpenMetrics writes the metrics of a gatherer in the OpenMetrics text format to
+// the file at path. The file is replaced at once, so that readers never see a partial
+// exposition.
+func writeOpenMetrics(gatherer prometheus.Gatherer, path string) error {
+	families, err := gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	encoder := expfmt.NewEncoder(f, expfmt.FmtOpenMetrics)
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if closer, ok := encoder.(expfmt.Closer); ok {
+		if err := closer.Close(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/senthilrch/kube-fledged/pkg/version"
+)
+
+func TestExportMetrics(t *testing.T) {
+	evictedImages.WithLabelValues("kube-fledged", "exported").Inc()
+	var pushedPath, pushedBody string
+	pushgateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		pushedPath, pushedBody = r.URL.Path, string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer pushgateway.Close()
+	path := filepath.Join(t.TempDir(), "metrics.txt")
+
+	if err := ExportMetrics(pushgateway.URL, "warm-nodes", path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pushedPath != "/metrics/job/warm-nodes" || pushedBody == "" {
+		t.Errorf("expected metrics to be pushed to job warm-nodes, actual path %s", pushedPath)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error reading metrics file: %v", err)
+	}
+	if !strings.Contains(string(content), `kubefledged_build_info{build_date="unknown",commit="unknown",go_version="`) {
+		t.Errorf("expected build info metric, actual %s", content)
+	}
+	if !strings.Contains(string(content), `kubefledged_evicted_images_total{imagecache="exported",namespace="kube-fledged"} 1.0`) ||
+		!strings.HasSuffix(string(content), "# EOF\n") {
+		t.Errorf("expected metrics in the OpenMetrics text format, actual %s", content)
+	}
+
+	// Nothing is exported if neither the Pushgateway nor the file is given
+	if err := ExportMetrics("", "warm-nodes", ""); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ExportMetrics(pushgateway.URL+"/unreachable\x7f", "warm-nodes", ""); err == nil {
+		t.Errorf("expected error pushing metrics to an invalid URL")
+	}
+}
+
+func TestServeVersion(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	serveVersion(recorder, httptest.NewRequest(http.MethodGet, "/version", nil))
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("expected content type application/json, actual %s", contentType)
+	}
+	info := version.Info{}
+	if err := json.NewDecoder(recorder.Body).Decode(&info); err != nil {
+		t.Fatalf("unexpected error decoding version: %v", err)
+	}
+	if info != version.Get() {
+		t.Errorf("expected version %+v, actual %+v", version.Get(), info)
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// nodeUpdateRetryInterval is the interval at which the actions restricted to
+	// relabeled nodes are retried while their image cache is under processing
+	nodeUpdateRetryInterval = 30 * time.Second
+	// ReasonNodeUnmatched is used as part of the Event 'reason' when the images of an
+	// image cache are purged from a node no longer matching its node selectors
+	ReasonNodeUnmatched = "NodeUnmatched"
+	// ReasonNodeMatched is used as part of the Event 'reason' when the images of an
+	// image cache are pulled to a node newly matching its node selectors
+	ReasonNodeMatched = "NodeMatched"
+)
+
+// handleNodeUpdate reconciles the image caches with a node whose labels changed. The
+// images of the cache spec entries whose node selector newly matches the node are
+// pulled to it. The images of the cache spec entries whose node selector no longer
+// matches the node are purged from it, if the image cache sets purgeUnmatchedNodes.
+func (c *Controller) handleNodeUpdate(old, new *corev1.Node) {
+	if reflect.DeepEqual(old.Labels, new.Labels) || len(c.excludeVirtualNodes([]*corev1.Node{new})) == 0 {
+		return
+	}
+	imageCaches, err := c.imageCachesLister.ImageCaches("").List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error in listing image caches: %v", err)
+		return
+	}
+	for _, imageCache := range imageCaches {
+		if imageCache.Status.Reason == v1alpha2.ImageCacheReasonImageCachePurge {
+			continue
+		}
+		matched, unmatched := c.changedEntries(imageCache.Spec.CacheSpec, old, new)
+		if !imageCache.Spec.PurgeUnmatchedNodes {
+			unmatched = []int{}
+		}
+		if len(matched) == 0 && len(unmatched) == 0 {
+			continue
+		}
+		if len(matched) > 0 {
+			glog.Infof("Node %s newly matches the node selector of entries %v of image cache %s/%s: pulling their images to it",
+				new.Name, matched, imageCache.Namespace, imageCache.Name)
+			c.recorder.Eventf(imageCache, corev1.EventTypeNormal, ReasonNodeMatched,
+				"Node %s newly matches the node selector of cache spec entries %v: pulling their images to it", new.Name, matched)
+		}
+		if len(unmatched) > 0 {
+			glog.Infof("Node %s no longer matches the node selector of entries %v of image cache %s/%s: purging their images from it",
+				new.Name, unmatched, imageCache.Namespace, imageCache.Name)
+			c.recorder.Eventf(imageCache, corev1.EventTypeNormal, ReasonNodeUnmatched,
+				"Node %s no longer matches the node selector of cache spec entries %v: purging their images from it", new.Name, unmatched)
+		}
+		c.enqueueNodeRefresh(imageCache, new.Name, matched, unmatched)
+	}
+}
+
+// changedEntries returns the indexes of the cache spec entries whose node selector
+// matches a node since its labels changed, and of those whose node selector matched
+// the node before and no longer matches it
+func (c *Controller) changedEntries(cacheSpec []v1alpha2.CacheSpecImages, old, new *corev1.Node) (matched, unmatched []int) {
+	matched, unmatched = []int{}, []int{}
+	for k, i := range cacheSpec {
+		selector := labels.Set(c.entryNodeSelector(i)).AsSelector()
+		oldMatch, newMatch := selector.Matches(labels.Set(old.Labels)), selector.Matches(labels.Set(new.Labels))
+		if !oldMatch && newMatch {
+			matched = append(matched, k)
+		}
+		if oldMatch && !newMatch {
+			unmatched = append(unmatched, k)
+		}
+	}
+	return matched, unmatched
+}
+
+// enqueueNodeRefresh queues a refresh of an image cache restricted to a node, which
+// pulls the images of the matched entries to the node and purges the images of the
+// unmatched entries from it. The images of the other entries are not pulled.
+func (c *Controller) enqueueNodeRefresh(imageCache *v1alpha2.ImageCache, node string, matched, unmatched []int) {
+	key, err := cache.MetaNamespaceKeyFunc(imageCache)
+	if err != nil {
+		glog.Errorf("Error getting key of image cache %s: %v", imageCache.Name, err)
+		return
+	}
+	c.workqueue.AddRateLimited(images.WorkQueueKey{
+		WorkType:         images.ImageCacheRefresh,
+		ObjKey:           key,
+		Entries:          &matched,
+		Nodes:            &[]string{node},
+		UnmatchedEntries: &unmatched,
+		Background:       true,
+	})
+}
+
+// restrictNodes returns the nodes whose name is listed
+func restrictNodes(nodes []*corev1.Node, names []string) []*corev1.Node {
+	restricted := []*corev1.Node{}
+	for _, n := range nodes {
+		if containsString(names, n.Name) {
+			restricted = append(restricted, n)
+		}
+	}
+	return restricted
+}
+
+// unmatchedNodes returns the nodes of an action whose images of a cache spec entry are
+// to be purged, since the node selector of the entry no longer matches them. The nodes
+// still targeted by the entry, e.g. warm standby nodes, are excluded.
+func (c *Controller) unmatchedNodes(wqKey images.WorkQueueKey, k int, targeted []*corev1.Node) []*corev1.Node {
+	if wqKey.Nodes == nil || wqKey.UnmatchedEntries == nil || !containsEntry(*wqKey.UnmatchedEntries, k) {
+		return nil
+	}
+	unmatched := []*corev1.Node{}
+	for _, name := range *wqKey.Nodes {
+		node, err := c.nodesLister.Get(name)
+		if err != nil {
+			glog.Errorf("Error getting node %s: %v", name, err)
+			continue
+		}
+		if containsNode(targeted, node) {
+			continue
+		}
+		unmatched = append(unmatched, node)
+	}
+	return unmatched
+}
+
+// deferredOfOtherNodes returns the deferred nodes other than the named ones
+func deferredOfOtherNodes(deferred []v1alpha2.DeferredNode, names []string) []v1alpha2.DeferredNode {
+	var others []v1alpha2.DeferredNode
+	for _, d := range deferred {
+		if !containsString(names, d.Node) {
+			others = append(others, d)
+		}
+	}
+	return others
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestHandleNodeUpdate(t *testing.T) {
+	oldNode := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1",
+		Labels: map[string]string{"kubernetes.io/os": "linux", "pool": "gpu"}}}
+	cacheSpec := []kubefledgedv1alpha2.CacheSpecImages{
+		{Images: []string{"cuda:12"}, NodeSelector: map[string]string{"pool": "gpu"}},
+		{Images: []string{"nginx:1.23"}},
+	}
+	tests := []struct {
+		name              string
+		oldLabels         map[string]string
+		newLabels         map[string]string
+		purge             bool
+		expectedMatched   []int
+		expectedUnmatched []int
+	}{
+		{
+			name:              "#1: Node no longer matches an entry",
+			newLabels:         map[string]string{"kubernetes.io/os": "linux", "pool": "web"},
+			purge:             true,
+			expectedMatched:   []int{},
+			expectedUnmatched: []int{0},
+		},
+		{
+			name:      "#2: Image cache does not purge unmatched nodes",
+			newLabels: map[string]string{"kubernetes.io/os": "linux", "pool": "web"},
+		},
+		{
+			name:      "#3: Node still matches all the entries",
+			newLabels: map[string]string{"kubernetes.io/os": "linux", "pool": "gpu", "zone": "a"},
+			purge:     true,
+		},
+		{
+			name:              "#4: Node newly matches an entry",
+			oldLabels:         map[string]string{"pool": "gpu"},
+			newLabels:         map[string]string{"kubernetes.io/os": "linux", "pool": "gpu"},
+			expectedMatched:   []int{1},
+			expectedUnmatched: []int{},
+		},
+		{
+			name:              "#5: Node moved from an entry to another",
+			oldLabels:         map[string]string{"pool": "gpu"},
+			newLabels:         map[string]string{"kubernetes.io/os": "linux", "pool": "web"},
+			purge:             true,
+			expectedMatched:   []int{1},
+			expectedUnmatched: []int{0},
+		},
+	}
+	for _, test := range tests {
+		controller, _, imagecacheInformer := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+		imageCache := &kubefledgedv1alpha2.ImageCache{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+			Spec:       kubefledgedv1alpha2.ImageCacheSpec{CacheSpec: cacheSpec, PurgeUnmatchedNodes: test.purge},
+		}
+		imagecacheInformer.Informer().GetIndexer().Add(imageCache)
+		node := oldNode.DeepCopy()
+		if test.oldLabels != nil {
+			node.Labels = test.oldLabels
+		}
+		newNode := node.DeepCopy()
+		newNode.Labels = test.newLabels
+		controller.handleNodeUpdate(node, newNode)
+		if test.expectedUnmatched == nil {
+			if controller.workqueue.Len() != 0 {
+				t.Errorf("Test: %s failed: expected no refresh to be queued", test.name)
+			}
+			continue
+		}
+		item, _ := controller.workqueue.Get()
+		wqKey := item.(images.WorkQueueKey)
+		if wqKey.WorkType != images.ImageCacheRefresh || wqKey.Nodes == nil || !reflect.DeepEqual(*wqKey.Nodes, []string{"node1"}) ||
+			wqKey.UnmatchedEntries == nil || !reflect.DeepEqual(*wqKey.UnmatchedEntries, test.expectedUnmatched) ||
+			wqKey.Entries == nil || !reflect.DeepEqual(*wqKey.Entries, test.expectedMatched) {
+			t.Errorf("Test: %s failed: unexpected work queue key %+v", test.name, wqKey)
+		}
+	}
+}
+
+func TestDeferredOfOtherNodes(t *testing.T) {
+	deferred := []kubefledgedv1alpha2.DeferredNode{
+		{Node: "node1", Images: []string{"foo"}},
+		{Node: "node2", Images: []string{"bar"}},
+	}
+	expected := []kubefledgedv1alpha2.DeferredNode{{Node: "node2", Images: []string{"bar"}}}
+	if actual := deferredOfOtherNodes(deferred, []string{"node1"}); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected deferred nodes %+v, actual %+v", expected, actual)
+	}
+}
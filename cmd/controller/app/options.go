@@ -0,0 +1,428 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/features"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// containerdNamespaceRegexp matches the valid names of containerd namespaces
+var containerdNamespaceRegexp = regexp.MustCompile(`^[A-Za-z0-9]+(?:[._-][A-Za-z0-9]+)*$`)
+
+// Options holds the settings of the controller. The kubefledged-controller binary sets
+// them from its command line flags; operators embedding the controller may set them
+// directly, starting from the defaults returned by NewOptions.
+type Options struct {
+	// Namespace is the namespace in which the controller runs
+	Namespace                  string
+	ImageCacheRefreshFrequency time.Duration
+	ImagePullDeadlineDuration  time.Duration
+	CRIClientImage             string
+	CRIClientWindowsImage      string
+	BusyboxImage               string
+	ImagePullPolicy            string
+	ServiceAccountName         string
+	ImageDeleteJobHostNetwork  bool
+	JobPriorityClassName       string
+	JobSchedulerName           string
+	JobSecurityProfiles        v1alpha2.SecurityProfiles
+	// CanDeleteJob is false if the finished jobs of the image manager are retained
+	CanDeleteJob                bool
+	CRISocketPath               string
+	DefaultNodeOS               string
+	StatusUpdateInterval        time.Duration
+	StatusUpdateBatchSize       int
+	StuckJobThreshold           time.Duration
+	PullRetry                   images.PullRetry
+	ORASImage                   string
+	ArtifactStorePath           string
+	PullStrategy                string
+	ContainerdNamespace         string
+	DashboardAddress            string
+	RegistryWebhookAddress      string
+	RegistryWebhookToken        string
+	RegistryWebhookInsecure     bool
+	TagPollInterval             time.Duration
+	UsageTrackingInterval       time.Duration
+	AutoWarmInterval            time.Duration
+	AutoWarmInitContainers      bool
+	AutoWarmEphemeralContainers bool
+	HPAPrewarmInterval          time.Duration
+	HPAPrewarmThreshold         int
+	StartupTaintKey             string
+	DeferOfflineNodes           bool
+	CircuitBreaker              images.CircuitBreaker
+	GuaranteedPulls             images.GuaranteedPulls
+	LoadThrottle                images.LoadThrottle
+	RegistryPullSecrets         []string
+	HookAllowedURLs             []string
+	HookAllowedImages           []string
+	ImageListPluginAddresses    []string
+	IncludeVirtualNodes         bool
+	VerifyInterval              time.Duration
+	ImageGCHighThreshold        int
+	ImageGCLowThreshold         int
+	EgressAccounting            bool
+	ApprovalNodeThreshold       int
+	ApprovalBytesThreshold      int64
+	EgressCostRates             map[string]float64
+	ReportLayerStats            bool
+	VerifyImageDigests          bool
+	RecordImageFsUsage          bool
+	NodeBandwidth               int64
+	MetricsAddress              string
+	MetricsPushgatewayURL       string
+	MetricsPushgatewayJob       string
+	MetricsFile                 string
+	AuditLogPath                string
+	AuditWebhookURL             string
+	CloudEventsSinkURL          string
+	EventComponentName          string
+	EventSinkNamespace          string
+	DisableEvents               bool
+	KubeAPIQPS                  float64
+	KubeAPIBurst                int
+	Kubeconfig                  string
+	ConfigMapName               string
+	KubeContext                 string
+	MasterURL                   string
+}
+
+// NewOptions returns the default settings of the controller. The namespace, the images
+// of the jobs and the registry webhook token are read from the environment variables
+// of the controller if set.
+func NewOptions() *Options {
+	o := &Options{
+		Namespace:                  "kube-fledged",
+		ImageCacheRefreshFrequency: time.Minute * 15,
+		ImagePullDeadlineDuration:  time.Minute * 5,
+		CRIClientImage:             "senthilrch/kubefledged-cri-client:latest",
+		CRIClientWindowsImage:      "senthilrch/kubefledged-cri-client-windows:latest",
+		BusyboxImage:               "senthilrch/busybox:1.35.0",
+		ImagePullPolicy:            "IfNotPresent",
+		CanDeleteJob:               true,
+		DefaultNodeOS:              "linux",
+		StatusUpdateInterval:       time.Second * 5,
+		StatusUpdateBatchSize:      100,
+		StuckJobThreshold:          time.Minute * 2,
+		PullRetry:                  images.PullRetry{BaseDelay: time.Second * 10, MaxDelay: time.Minute * 5, Jitter: 0.1},
+		CircuitBreaker:             images.CircuitBreaker{CoolDown: time.Minute * 5},
+		MetricsPushgatewayJob:      "kubefledged-controller",
+		ORASImage:                  "ghcr.io/oras-project/oras:v0.16.0",
+		ArtifactStorePath:          "/var/lib/kubefledged/artifacts",
+		PullStrategy:               images.PullStrategyKubelet,
+		ContainerdNamespace:        images.DefaultContainerdNamespace,
+		TagPollInterval:            time.Minute * 10,
+		AutoWarmInitContainers:     true,
+		HPAPrewarmThreshold:        80,
+		ImageGCHighThreshold:       85,
+		ImageGCLowThreshold:        80,
+		EventComponentName:         "kubefledged-controller",
+		KubeAPIQPS:                 50,
+		KubeAPIBurst:               100,
+		RegistryWebhookToken:       os.Getenv("KUBEFLEDGED_REGISTRY_WEBHOOK_TOKEN"),
+	}
+	for env, value := range map[string]*string{
+		"KUBEFLEDGED_NAMESPACE":                &o.Namespace,
+		"KUBEFLEDGED_CRI_CLIENT_IMAGE":         &o.CRIClientImage,
+		"KUBEFLEDGED_CRI_CLIENT_WINDOWS_IMAGE": &o.CRIClientWindowsImage,
+		"BUSYBOX_IMAGE":                        &o.BusyboxImage,
+		"ORAS_IMAGE":                           &o.ORASImage,
+	} {
+		if v := os.Getenv(env); v != "" {
+			*value = v
+		}
+	}
+	return o
+}
+
+// AddFlags adds the flags of the controller to a flag set. The current values of the
+// options are the defaults of the flags.
+func (o *Options) AddFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&o.ImagePullDeadlineDuration, "image-pull-deadline-duration", o.ImagePullDeadlineDuration, "Maximum duration allowed for pulling an image. After this duration, image pull is considered to have failed")
+	fs.DurationVar(&o.ImageCacheRefreshFrequency, "image-cache-refresh-frequency", o.ImageCacheRefreshFrequency, "The image cache is refreshed periodically to ensure the cache is up to date. Setting this flag to 0s will disable refresh")
+	fs.StringVar(&o.ImagePullPolicy, "image-pull-policy", o.ImagePullPolicy, "Image pull policy for pulling images into the cache. Possible values are 'IfNotPresent' and 'Always'. Default value is 'IfNotPresent'. Images with no or ':latest' tag are always pulled")
+	fs.StringVar(&o.ServiceAccountName, "service-account-name", o.ServiceAccountName, "serviceAccountName used in Jobs created for pulling/deleting images. Optional flag. If not specified the default service account of the namespace is used")
+	fs.BoolVar(&o.ImageDeleteJobHostNetwork, "image-delete-job-host-network", o.ImageDeleteJobHostNetwork, "whether the pod for the image delete job should be run with 'HostNetwork: true'. Default value: false")
+	fs.StringVar(&o.JobPriorityClassName, "job-priority-class-name", o.JobPriorityClassName, "priorityClassName of jobs created by kubefledged-controller")
+	fs.StringVar(&o.JobSchedulerName, "job-scheduler-name", o.JobSchedulerName, "schedulerName of the pods of jobs created by kubefledged-controller, e.g. to schedule them with a secondary scheduler. If not specified the default scheduler is used")
+	fs.BoolVar(&o.GuaranteedPulls.Enabled, "guaranteed-pulls", o.GuaranteedPulls.Enabled, "Bind the pods of the image pull jobs to their node, bypassing the scheduler, with zero cpu and memory requests, so that images are pulled even to nodes without schedulable capacity. The kubelet still rejects the pods on nodes which reached their maximum number of pods")
+	fs.StringVar(&o.GuaranteedPulls.PriorityClassName, "guaranteed-pull-priority-class-name", o.GuaranteedPulls.PriorityClassName, "priorityClassName of the pods of the image pull jobs bound to their node by --guaranteed-pulls, instead of --job-priority-class-name. Requires --guaranteed-pulls")
+	fs.Func("job-seccomp-profile", "seccomp profile of the pods of jobs created by kubefledged-controller. Possible values are 'RuntimeDefault', 'Unconfined' and 'Localhost/<profile>'. Image caches may override it with spec.securityProfiles. If not specified the pods of the image pull jobs of the kubelet pull strategy have the RuntimeDefault profile, and the pods of the other jobs have no seccomp profile",
+		func(val string) error {
+			profile, err := images.ParseSeccompProfile(val)
+			if err != nil {
+				return err
+			}
+			o.JobSecurityProfiles.SeccompProfile = profile
+			return nil
+		})
+	fs.StringVar(&o.JobSecurityProfiles.AppArmorProfile, "job-apparmor-profile", o.JobSecurityProfiles.AppArmorProfile, "AppArmor profile of the containers of the pods of jobs created by kubefledged-controller. Possible values are 'runtime/default', 'unconfined' and 'localhost/<profile>'. Image caches may override it with spec.securityProfiles. If not specified the pods have no AppArmor annotations")
+	fs.Func("job-retention-policy", "sets the retention behavior of finished Image Manager Jobs (default: 'delete')",
+		func(val string) error {
+			const (
+				deletePolicy string = "delete"
+				retainPolicy string = "retain"
+			)
+			switch strings.ToLower(strings.TrimSpace(val)) {
+			case deletePolicy:
+				o.CanDeleteJob = true
+				glog.Infof("Using '%s' Job Retention Policy", deletePolicy)
+				return nil
+			case retainPolicy:
+				o.CanDeleteJob = false
+				glog.Infof("Using '%s' Job Retention Policy", retainPolicy)
+				return nil
+			default:
+				//CanDeleteJob keeps its current value
+				glog.Infof("Failed to set '%s' Job Retention Policy -- invalid input:"+
+					" falling back to '%s' Job Retention Policy", val, deletePolicy)
+				return nil
+			}
+		},
+	)
+	fs.Float64Var(&o.KubeAPIQPS, "kube-api-qps", o.KubeAPIQPS, "QPS of the requests of kubefledged-controller to the Kubernetes API server, after which requests are throttled client side")
+	fs.IntVar(&o.KubeAPIBurst, "kube-api-burst", o.KubeAPIBurst, "Burst of the requests of kubefledged-controller to the Kubernetes API server, i.e. the number of requests allowed above kube-api-qps for short periods")
+	fs.StringVar(&o.CRISocketPath, "cri-socket-path", o.CRISocketPath, "path to the cri socket on the node e.g. /run/containerd/containerd.sock (default: /var/run/docker.sock, /run/containerd/containerd.sock, /var/run/crio/crio.sock)")
+	fs.StringVar(&o.DefaultNodeOS, "default-node-os", o.DefaultNodeOS, "Operating system (kubernetes.io/os label value) of the nodes targeted by cache spec entries that do not specify a nodeSelector. Set to empty string to target nodes of all operating systems")
+	fs.DurationVar(&o.StatusUpdateInterval, "status-update-interval", o.StatusUpdateInterval, "Interval at which the progress of an image cache action under processing is written to the image cache status. Setting this flag to 0s will disable periodic progress updates")
+	fs.IntVar(&o.StatusUpdateBatchSize, "status-update-batch-size", o.StatusUpdateBatchSize, "Number of finished image pulls/deletes after which the progress of an image cache action is written to the image cache status, without waiting for the status update interval. Setting this flag to 0 will disable batch triggered progress updates")
+	fs.DurationVar(&o.StuckJobThreshold, "stuck-job-threshold", o.StuckJobThreshold, "Duration after which a job whose pod cannot be scheduled or whose init containers did not complete is considered stuck. Stuck jobs are deleted and the image pull/delete is marked as failed. Setting this flag to 0s will disable stuck job detection")
+	fs.StringVar(&o.ConfigMapName, "config-map", o.ConfigMapName, "Name of a ConfigMap in the namespace of the controller whose settings (imageCacheRefreshFrequency, maxConcurrentJobs, allowedRegistries, criClientImage, busyboxImage) override the flags and are reloaded whenever it changes, without restarting the controller. Reloading is disabled if not specified")
+	fs.StringVar(&o.Kubeconfig, "kubeconfig", o.Kubeconfig, "Path to a kubeconfig, to run the controller out of the cluster. Defaults to the KUBECONFIG environment variable or ~/.kube/config when --context or --master is given. The in-cluster configuration is used if none of these flags is given")
+	fs.StringVar(&o.KubeContext, "context", o.KubeContext, "Context of the kubeconfig the controller runs against, instead of its current context")
+	fs.StringVar(&o.MasterURL, "master", o.MasterURL, "Address of the Kubernetes API server, overriding the server of the kubeconfig")
+	fs.IntVar(&o.PullRetry.Retries, "pull-retries", o.PullRetry.Retries, "Maximum number of retries of a failed image pull, within the image pull deadline. Failed pulls are retried with exponential backoff by creating a new job, instead of leaving the retries to the backoff of kubelet. Setting this flag to 0 disables retries")
+	fs.DurationVar(&o.PullRetry.BaseDelay, "pull-retry-base-delay", o.PullRetry.BaseDelay, "Delay before the first retry of a failed image pull. The delay doubles with each retry")
+	fs.DurationVar(&o.PullRetry.MaxDelay, "pull-retry-max-delay", o.PullRetry.MaxDelay, "Maximum delay between the retries of a failed image pull")
+	fs.Float64Var(&o.PullRetry.Jitter, "pull-retry-jitter", o.PullRetry.Jitter, "Maximum fraction of the retry delay added to it at random, so that the retries of image pulls failed at the same time are spread")
+	fs.IntVar(&o.CircuitBreaker.Failures, "registry-circuit-breaker-failures", o.CircuitBreaker.Failures, "Number of consecutive failed image pulls from a registry after which its circuit opens: image pulls from the registry are not dispatched during the cool-down period, and are deferred in the image cache status and completed automatically afterwards. Failures specific to an image, e.g. an unknown manifest, are not counted. Setting this flag to 0 disables the circuit breaker")
+	fs.DurationVar(&o.CircuitBreaker.CoolDown, "registry-circuit-breaker-cool-down", o.CircuitBreaker.CoolDown, "Period during which the image pulls from a registry whose circuit opened are not dispatched. A single failed image pull after the cool-down period opens the circuit again")
+	fs.IntVar(&o.LoadThrottle.CPUPercent, "load-throttle-cpu-percent", o.LoadThrottle.CPUPercent, "CPU usage of a node, in percent of its CPU capacity, beyond which image pulls to the node are not dispatched. They are deferred in the image cache status and completed automatically once the load of the node subsides. The CPU usage is read from the summary API of kubelet. Setting this flag to 0 disables the threshold")
+	fs.Func("load-throttle-network-rate", "Network traffic of a node in bytes per second (e.g. 100Mi) beyond which image pulls to the node are deferred until its load subsides. The traffic is read from the summary API of kubelet. The threshold is disabled if not specified",
+		func(val string) error {
+			quantity, err := resource.ParseQuantity(val)
+			if err != nil {
+				return err
+			}
+			o.LoadThrottle.NetworkBytesPerSecond = quantity.Value()
+			return nil
+		})
+	fs.Func("load-throttle-disk-io-rate", "Disk IO of a node in bytes per second (e.g. 200Mi) beyond which image pulls to the node are deferred until its load subsides. The disk IO is read from the cAdvisor metrics of kubelet. The threshold is disabled if not specified",
+		func(val string) error {
+			quantity, err := resource.ParseQuantity(val)
+			if err != nil {
+				return err
+			}
+			o.LoadThrottle.DiskIOBytesPerSecond = quantity.Value()
+			return nil
+		})
+	fs.StringVar(&o.ArtifactStorePath, "artifact-store-path", o.ArtifactStorePath, "Path of the directory on the nodes into which OCI artifacts listed in image caches are pulled. Each artifact is stored in a sub-directory named after the artifact reference")
+	fs.Func("pull-strategy", "strategy used for pulling images into the cache. Possible values are 'kubelet' and 'containerd'. 'containerd' pulls images on containerd nodes using ctr and reports the layer download progress in the image cache status (default: 'kubelet')",
+		func(val string) error {
+			switch strings.ToLower(strings.TrimSpace(val)) {
+			case images.PullStrategyKubelet:
+				o.PullStrategy = images.PullStrategyKubelet
+			case images.PullStrategyContainerd:
+				o.PullStrategy = images.PullStrategyContainerd
+			default:
+				glog.Infof("Failed to set '%s' pull strategy -- invalid input:"+
+					" falling back to '%s' pull strategy", val, images.PullStrategyKubelet)
+				o.PullStrategy = images.PullStrategyKubelet
+			}
+			return nil
+		},
+	)
+	fs.StringVar(&o.ContainerdNamespace, "containerd-namespace", o.ContainerdNamespace, "containerd namespace into which images are pulled with the containerd pull strategy, and from which they are purged. Images pulled into a namespace other than k8s.io are not visible to the kubelet, but can be used by other consumers of containerd on the nodes e.g. nerdctl (default: k8s.io)")
+	fs.DurationVar(&o.TagPollInterval, "tag-poll-interval", o.TagPollInterval, "Interval at which the tags of the tracked repositories of image caches are polled. Matching tags are added to the resolved images of the image caches. Setting this flag to 0s will disable polling")
+	fs.StringVar(&o.StartupTaintKey, "startup-taint-key", o.StartupTaintKey, "Key of the startup taint of newly provisioned nodes (e.g. kubefledged.io/warming). The images of the image caches targeting a node carrying this taint are pulled, and the taint is removed once the node holds all of them. Setting this flag to empty string will disable node warming")
+	fs.BoolVar(&o.DeferOfflineNodes, "defer-offline-nodes", o.DeferOfflineNodes, "Defer the image pulls to offline nodes until they reconnect, instead of failing them. Deferred image pulls are reported in the image cache status and retried with exponential backoff. Useful for edge clusters whose nodes are intermittently connected")
+	fs.BoolVar(&o.IncludeVirtualNodes, "include-virtual-nodes", o.IncludeVirtualNodes, "Target virtual nodes (virtual-kubelet, EKS Fargate) with image pulls. They are excluded by default since they have no image store to cache images in")
+	fs.DurationVar(&o.VerifyInterval, "verify-interval", o.VerifyInterval, "Interval at which the images of image caches are verified to be still present on the nodes, using the images reported in the node status. Image caches whose images were removed (e.g. by kubelet image garbage collection) are refreshed. Setting this flag to 0s will disable verification")
+	fs.IntVar(&o.ImageGCHighThreshold, "image-gc-high-threshold", o.ImageGCHighThreshold, "Image garbage collection high threshold of kubelet, in percent of disk usage. The nodes targeted by an image cache whose images exceed the low threshold are reported in the image cache status. Setting this flag to 0 will disable reporting")
+	fs.IntVar(&o.ImageGCLowThreshold, "image-gc-low-threshold", o.ImageGCLowThreshold, "Image garbage collection low threshold of kubelet, in percent of disk usage")
+	fs.BoolVar(&o.EgressAccounting, "egress-accounting", o.EgressAccounting, "Estimate the bytes pulled from each registry by the image pulls of image caches, from the image manifests in the registries, and export them as metrics")
+	fs.Func("registry-pull-secrets", "Comma separated names of image pull secrets in the namespace of kubefledged-controller, whose credentials are used by the registry operations of the controller (e.g. listing tags and catalogs, reading image sizes and layers), in addition to the image pull secrets of the service account given by --service-account-name and the docker config file of the controller ($DOCKER_CONFIG/config.json)",
+		func(val string) error {
+			o.RegistryPullSecrets = nil
+			for _, secret := range strings.Split(val, ",") {
+				if secret = strings.TrimSpace(secret); secret != "" {
+					o.RegistryPullSecrets = append(o.RegistryPullSecrets, secret)
+				}
+			}
+			return nil
+		})
+	fs.Func("hook-allowed-urls", "Comma separated URLs the http hooks of image caches may post to, e.g. \"https://cmdb.local/hooks/,http://notifier.tools.svc:8080/\". The URL of a hook must have the scheme and the host of one of them, and its path or a path under it. Http hooks are refused if not specified",
+		func(val string) error {
+			o.HookAllowedURLs = nil
+			for _, allowed := range strings.Split(val, ",") {
+				if allowed = strings.TrimSpace(allowed); allowed == "" {
+					continue
+				}
+				if err := validateHookAllowedURL(allowed); err != nil {
+					return err
+				}
+				o.HookAllowedURLs = append(o.HookAllowedURLs, allowed)
+			}
+			return nil
+		})
+	fs.Func("hook-allowed-images", "Comma separated images the exec hooks of image caches may run, e.g. \"busybox:1.36,registry.local/tools/drain\". An image without a tag or digest allows all the tags and digests of its repository. Exec hooks are refused if not specified",
+		func(val string) error {
+			o.HookAllowedImages = nil
+			for _, allowed := range strings.Split(val, ",") {
+				if allowed = strings.TrimSpace(allowed); allowed == "" {
+					continue
+				}
+				if _, err := reference.ParseNormalizedNamed(allowed); err != nil {
+					return fmt.Errorf("invalid hook image %q: %v", allowed, err)
+				}
+				o.HookAllowedImages = append(o.HookAllowedImages, allowed)
+			}
+			return nil
+		})
+	fs.Func("image-list-plugin-addresses", "Comma separated addresses of the image list plugins image caches may call, e.g. \"catalog.tools.svc:9000,unix:///var/run/plugin.sock\". Plugins are called in plaintext: only list addresses of plugins trusted by the operator. Plugin providers are refused if not specified",
+		func(val string) error {
+			o.ImageListPluginAddresses = nil
+			for _, address := range strings.Split(val, ",") {
+				if address = strings.TrimSpace(address); address != "" {
+					o.ImageListPluginAddresses = append(o.ImageListPluginAddresses, address)
+				}
+			}
+			return nil
+		})
+	fs.Func("egress-cost-rates", "Egress cost rates per GB of the registries, as comma separated registry=rate pairs e.g. \"docker.io=0.09,*=0.05\". The rate of \"*\" applies to the other registries. The cost of the image pulls of image cache actions is estimated and reported in their status, and actions beyond the costBudget of the image cache or of its namespace wait for approval. Cost estimation is disabled if not specified",
+		func(val string) error {
+			rates, err := ParseCostRates(val)
+			if err != nil {
+				return err
+			}
+			o.EgressCostRates = rates
+			return nil
+		})
+	fs.BoolVar(&o.ReportLayerStats, "layer-stats", o.ReportLayerStats, "Report in the status of image caches the bytes of the layers shared by their images and unique to each image, per node, read from the image manifests in the registries")
+	fs.BoolVar(&o.VerifyImageDigests, "verify-image-digests", o.VerifyImageDigests, "Verify after each image pull that the digest of the image pulled on the node, as reported by the container runtime, matches the digest the image resolved to in the registry before the pull. Mismatching images are reported as failures in the status of image caches")
+	fs.BoolVar(&o.RecordImageFsUsage, "record-imagefs-usage", o.RecordImageFsUsage, "Record in the status of image caches the used bytes of the image filesystem of the targeted nodes at the start and at the end of each image pull/purge, and the difference, read from the summary API of kubelet")
+	fs.Func("node-bandwidth", "Bandwidth of the nodes for image pulls, in bytes per second (e.g. 100Mi). The bytes and the duration of the image pulls of image cache actions are estimated per node from the sizes of the images in the registries, and reported in the plan of their status. Dry runs requested with the kubefledged.io/dry-run-imagecache annotation estimate the bytes only if not specified",
+		func(val string) error {
+			quantity, err := resource.ParseQuantity(val)
+			if err != nil {
+				return err
+			}
+			o.NodeBandwidth = quantity.Value()
+			return nil
+		})
+	fs.IntVar(&o.ApprovalNodeThreshold, "approval-node-threshold", o.ApprovalNodeThreshold, "Number of nodes beyond which the image pulls of an image cache action wait for approval, given by annotating the image cache with kubefledged.io/approve-imagecache. Setting this flag to 0 disables the threshold")
+	fs.Func("approval-bytes-threshold", "Estimated size of the image pulls of an image cache action (e.g. 500Gi) beyond which the action waits for approval, given by annotating the image cache with kubefledged.io/approve-imagecache. Image sizes are taken from the nodes holding the images. The threshold is disabled if not specified",
+		func(val string) error {
+			quantity, err := resource.ParseQuantity(val)
+			if err != nil {
+				return err
+			}
+			o.ApprovalBytesThreshold = quantity.Value()
+			return nil
+		})
+	fs.DurationVar(&o.UsageTrackingInterval, "usage-tracking-interval", o.UsageTrackingInterval, "Interval at which the images of image caches are cross-referenced with the images of the pods of the cluster. The number of pods using each image and its last use are reported in the image cache status and as metrics. Setting this flag to 0s will disable usage tracking")
+	fs.DurationVar(&o.AutoWarmInterval, "auto-warm-interval", o.AutoWarmInterval, "Interval at which the images of the pods and workloads annotated with fledged.k8s.io/warm=\"true\" are collected into the image cache kubefledged-auto-warm, managed by the controller in its namespace. Setting this flag to 0s will disable auto-warm")
+	fs.BoolVar(&o.AutoWarmInitContainers, "auto-warm-init-containers", o.AutoWarmInitContainers, "Collect the images of the init containers of the pods and workloads annotated for auto-warm")
+	fs.BoolVar(&o.AutoWarmEphemeralContainers, "auto-warm-ephemeral-containers", o.AutoWarmEphemeralContainers, "Collect the images of the ephemeral containers (e.g. debug containers) of the pods annotated for auto-warm")
+	fs.DurationVar(&o.HPAPrewarmInterval, "hpa-prewarm-interval", o.HPAPrewarmInterval, "Interval at which the HorizontalPodAutoscalers annotated with fledged.k8s.io/prewarm-on-scale-up=\"true\" are checked for approaching scale-ups, whose workload images are then warmed on the nodes lacking them. Setting this flag to 0s will disable the HPA pre-warm")
+	fs.IntVar(&o.HPAPrewarmThreshold, "hpa-prewarm-threshold", o.HPAPrewarmThreshold, "Percentage of the target value of a metric of a HorizontalPodAutoscaler beyond which a scale-up is approaching")
+	fs.StringVar(&o.MetricsAddress, "metrics-address", o.MetricsAddress, "Address (e.g. :9090) on which the metrics of kubefledged-controller are served at /metrics in the Prometheus exposition format, and its build information at /version. Metrics are not served if not specified")
+	fs.StringVar(&o.MetricsPushgatewayURL, "metrics-pushgateway-url", o.MetricsPushgatewayURL, "URL of a Prometheus Pushgateway (e.g. http://pushgateway:9091) to which the metrics of kubefledged-controller are pushed when it exits, so that the metrics of short-lived runs are not lost. Metrics are not pushed if not specified")
+	fs.StringVar(&o.MetricsPushgatewayJob, "metrics-pushgateway-job", o.MetricsPushgatewayJob, "Job name under which the metrics are pushed to the Prometheus Pushgateway. The metrics pushed by the previous run under the same job name are replaced")
+	fs.StringVar(&o.MetricsFile, "metrics-file", o.MetricsFile, "Path of a file to which the metrics of kubefledged-controller are written in the OpenMetrics text format when it exits, e.g. on a volume collected after the pod terminated. Metrics are not written if not specified")
+	fs.StringVar(&o.DashboardAddress, "dashboard-address", o.DashboardAddress, "Address (e.g. :8080) on which a read-only web dashboard of the image caches is served. The dashboard is disabled if not specified")
+	fs.StringVar(&o.RegistryWebhookAddress, "registry-webhook-address", o.RegistryWebhookAddress, "Address (e.g. :8081) on which push notifications of Harbor, Docker Hub and Quay registries are accepted at /harbor, /dockerhub/<token> and /quay/<token>, where <token> is the token of the KUBEFLEDGED_REGISTRY_WEBHOOK_TOKEN environment variable. Image caches listing a pushed image are refreshed. Harbor notifications must carry the token as a bearer token in the Authorization header. Disabled if not specified")
+	fs.BoolVar(&o.RegistryWebhookInsecure, "registry-webhook-insecure", o.RegistryWebhookInsecure, "Accept the push notifications of registries without a token if the KUBEFLEDGED_REGISTRY_WEBHOOK_TOKEN environment variable is not set. Anyone able to reach the registry webhook can then refresh image caches")
+	fs.StringVar(&o.AuditLogPath, "audit-log-path", o.AuditLogPath, "Path of a file to which an audit record of every image pull and purge is appended as a JSON line. Auditing to a file is disabled if not specified")
+	fs.StringVar(&o.AuditWebhookURL, "audit-webhook-url", o.AuditWebhookURL, "URL to which the audit records of every image pull and purge are posted as JSON lines. Auditing to a webhook is disabled if not specified")
+	fs.StringVar(&o.CloudEventsSinkURL, "cloudevents-sink-url", o.CloudEventsSinkURL, "URL to which a CloudEvent is posted on every image cache state transition (created, processing, refresh started, succeeded, failed), e.g. a Knative broker or an Argo Events webhook. CloudEvents are disabled if not specified")
+	fs.StringVar(&o.EventComponentName, "event-component-name", o.EventComponentName, "Component name reported as the source of events recorded by kubefledged-controller")
+	fs.StringVar(&o.EventSinkNamespace, "event-sink-namespace", o.EventSinkNamespace, "Namespace to which event recording is restricted. Events of objects in other namespaces are dropped. Default: all namespaces")
+	fs.Func("feature-gates", "A set of key=value pairs that describe feature gates for alpha/experimental features. Options are:\n"+
+		strings.Join(features.DefaultMutableFeatureGate.KnownFeatures(), "\n"), features.DefaultMutableFeatureGate.Set)
+	fs.BoolVar(&o.DisableEvents, "disable-events", o.DisableEvents, "Disable recording of events to the Kubernetes API. Events are still logged. Useful in clusters with strict event quotas")
+}
+
+// Validate returns an error if the options are inconsistent, or use features whose
+// feature gate is disabled
+func (o *Options) Validate() error {
+	if err := o.PullRetry.Validate(); err != nil {
+		return fmt.Errorf("invalid pull retry options: %v", err)
+	}
+	if err := o.CircuitBreaker.Validate(); err != nil {
+		return fmt.Errorf("invalid registry circuit breaker options: %v", err)
+	}
+	if err := o.GuaranteedPulls.Validate(); err != nil {
+		return fmt.Errorf("invalid guaranteed pulls options: %v", err)
+	}
+	if o.GuaranteedPulls.Enabled && o.JobSchedulerName != "" {
+		return fmt.Errorf("--guaranteed-pulls bypasses the scheduler and cannot be used with --job-scheduler-name")
+	}
+	if err := o.LoadThrottle.Validate(); err != nil {
+		return fmt.Errorf("invalid load throttle options: %v", err)
+	}
+	if o.LoadThrottle.Enabled() && !features.Enabled(features.LoadThrottling) {
+		return fmt.Errorf("--load-throttle-* flags require the %s feature gate", features.LoadThrottling)
+	}
+	if o.HPAPrewarmThreshold < 1 || o.HPAPrewarmThreshold > 100 {
+		return fmt.Errorf("invalid --hpa-prewarm-threshold %d: must be between 1 and 100", o.HPAPrewarmThreshold)
+	}
+	if o.MetricsPushgatewayURL != "" {
+		if u, err := url.Parse(o.MetricsPushgatewayURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid --metrics-pushgateway-url %q: expected an http or https URL", o.MetricsPushgatewayURL)
+		}
+		if o.MetricsPushgatewayJob == "" {
+			return fmt.Errorf("--metrics-pushgateway-job must not be empty")
+		}
+	}
+	if o.PullStrategy == images.PullStrategyContainerd && !features.Enabled(features.ContainerdPull) {
+		return fmt.Errorf("--pull-strategy=%s requires the %s feature gate", images.PullStrategyContainerd, features.ContainerdPull)
+	}
+	if !containerdNamespaceRegexp.MatchString(o.ContainerdNamespace) || len(o.ContainerdNamespace) > 76 {
+		return fmt.Errorf("invalid --containerd-namespace %q", o.ContainerdNamespace)
+	}
+	if o.ContainerdNamespace != images.DefaultContainerdNamespace && o.PullStrategy != images.PullStrategyContainerd {
+		return fmt.Errorf("--containerd-namespace requires --pull-strategy=%s", images.PullStrategyContainerd)
+	}
+	if err := images.ValidateSecurityProfiles(&o.JobSecurityProfiles); err != nil {
+		return fmt.Errorf("invalid job security profiles: %v", err)
+	}
+	if o.RegistryWebhookAddress != "" && !features.Enabled(features.RegistryWebhook) {
+		return fmt.Errorf("--registry-webhook-address requires the %s feature gate", features.RegistryWebhook)
+	}
+	if o.RegistryWebhookAddress != "" && o.RegistryWebhookToken == "" && !o.RegistryWebhookInsecure {
+		return fmt.Errorf("--registry-webhook-address requires the KUBEFLEDGED_REGISTRY_WEBHOOK_TOKEN environment variable or --registry-webhook-insecure")
+	}
+	if o.ReportLayerStats && !features.Enabled(features.LayerStats) {
+		return fmt.Errorf("--layer-stats requires the %s feature gate", features.LayerStats)
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/senthilrch/kube-fledged/pkg/features"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+)
+
+func TestNewOptions(t *testing.T) {
+	t.Setenv("KUBEFLEDGED_NAMESPACE", "")
+	t.Setenv("BUSYBOX_IMAGE", "myregistry/busybox:1.36")
+	opts := NewOptions()
+	if opts.Namespace != "kube-fledged" {
+		t.Errorf("expected default namespace kube-fledged, actual %s", opts.Namespace)
+	}
+	if opts.BusyboxImage != "myregistry/busybox:1.36" {
+		t.Errorf("expected busybox image from environment, actual %s", opts.BusyboxImage)
+	}
+	if !opts.CanDeleteJob || opts.PullStrategy != images.PullStrategyKubelet {
+		t.Errorf("expected jobs to be deleted and images pulled by kubelet by default, actual %+v", opts)
+	}
+}
+
+func TestOptionsAddFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected func(*Options) bool
+	}{
+		{
+			name:     "#1: Defaults kept without flags",
+			args:     []string{},
+			expected: func(o *Options) bool { return o.ImageCacheRefreshFrequency == time.Minute*15 && o.CanDeleteJob },
+		},
+		{
+			name: "#2: Flags override defaults",
+			args: []string{"--image-cache-refresh-frequency=1h", "--job-retention-policy=retain", "--pull-retries=3"},
+			expected: func(o *Options) bool {
+				return o.ImageCacheRefreshFrequency == time.Hour && !o.CanDeleteJob && o.PullRetry.Retries == 3
+			},
+		},
+		{
+			name: "#3: Quantity and cost rates parsed",
+			args: []string{"--approval-bytes-threshold=1Gi", "--egress-cost-rates=docker.io=0.09"},
+			expected: func(o *Options) bool {
+				return o.ApprovalBytesThreshold == 1<<30 && o.EgressCostRates["docker.io"] == 0.09
+			},
+		},
+		{
+			name:     "#4: Invalid pull strategy falls back to kubelet",
+			args:     []string{"--pull-strategy=foo"},
+			expected: func(o *Options) bool { return o.PullStrategy == images.PullStrategyKubelet },
+		},
+		{
+			name: "#5: Security profiles of jobs parsed",
+			args: []string{"--job-seccomp-profile=Localhost/profiles/puller.json", "--job-apparmor-profile=runtime/default"},
+			expected: func(o *Options) bool {
+				seccomp := o.JobSecurityProfiles.SeccompProfile
+				return seccomp != nil && seccomp.Type == "Localhost" && *seccomp.LocalhostProfile == "profiles/puller.json" &&
+					o.JobSecurityProfiles.AppArmorProfile == "runtime/default"
+			},
+		},
+		{
+			name: "#6: Registry circuit breaker enabled",
+			args: []string{"--registry-circuit-breaker-failures=20", "--registry-circuit-breaker-cool-down=10m"},
+			expected: func(o *Options) bool {
+				return o.CircuitBreaker.Failures == 20 && o.CircuitBreaker.CoolDown == time.Minute*10
+			},
+		},
+		{
+			name: "#7: Metrics exported on exit",
+			args: []string{"--metrics-pushgateway-url=http://pushgateway:9091", "--metrics-file=/metrics/kubefledged.txt"},
+			expected: func(o *Options) bool {
+				return o.MetricsPushgatewayURL == "http://pushgateway:9091" && o.MetricsPushgatewayJob == "kubefledged-controller" &&
+					o.MetricsFile == "/metrics/kubefledged.txt"
+			},
+		},
+		{
+			name: "#8: Load throttle thresholds parsed",
+			args: []string{"--load-throttle-cpu-percent=80", "--load-throttle-network-rate=100Mi", "--load-throttle-disk-io-rate=200Mi"},
+			expected: func(o *Options) bool {
+				return o.LoadThrottle == images.LoadThrottle{CPUPercent: 80, NetworkBytesPerSecond: 100 << 20, DiskIOBytesPerSecond: 200 << 20}
+			},
+		},
+	}
+	for _, test := range tests {
+		opts := NewOptions()
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		opts.AddFlags(fs)
+		if err := fs.Parse(test.args); err != nil {
+			t.Errorf("Test: %s failed: unexpected error: %v", test.name, err)
+			continue
+		}
+		if !test.expected(opts) {
+			t.Errorf("Test: %s failed: unexpected options %+v", test.name, opts)
+		}
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	enableFeatures(t, features.ContainerdPull, features.RegistryWebhook)
+	tests := []struct {
+		name      string
+		modify    func(*Options)
+		expectErr bool
+	}{
+		{
+			name:   "#1: Default options",
+			modify: func(o *Options) {},
+		},
+		{
+			name:      "#2: Invalid pull retry",
+			modify:    func(o *Options) { o.PullRetry.MaxDelay = time.Second },
+			expectErr: true,
+		},
+		{
+			name:      "#3: Feature gate of layer stats disabled",
+			modify:    func(o *Options) { o.ReportLayerStats = true },
+			expectErr: true,
+		},
+		{
+			name:      "#4: Invalid AppArmor profile of jobs",
+			modify:    func(o *Options) { o.JobSecurityProfiles.AppArmorProfile = "enforce" },
+			expectErr: true,
+		},
+		{
+			name: "#5: Registry circuit breaker without cool-down",
+			modify: func(o *Options) {
+				o.CircuitBreaker = images.CircuitBreaker{Failures: 10}
+			},
+			expectErr: true,
+		},
+		{
+			name:      "#6: Invalid Pushgateway URL",
+			modify:    func(o *Options) { o.MetricsPushgatewayURL = "pushgateway:9091" },
+			expectErr: true,
+		},
+		{
+			name: "#7: Non-default containerd namespace with containerd pull strategy",
+			modify: func(o *Options) {
+				o.PullStrategy = images.PullStrategyContainerd
+				o.ContainerdNamespace = "nerdctl.io"
+			},
+		},
+		{
+			name:      "#8: Non-default containerd namespace with kubelet pull strategy",
+			modify:    func(o *Options) { o.ContainerdNamespace = "default" },
+			expectErr: true,
+		},
+		{
+			name: "#9: Invalid containerd namespace",
+			modify: func(o *Options) {
+				o.PullStrategy = images.PullStrategyContainerd
+				o.ContainerdNamespace = "foo/bar"
+			},
+			expectErr: true,
+		},
+		{
+			name:      "#10: Load throttle with feature gate disabled",
+			modify:    func(o *Options) { o.LoadThrottle.CPUPercent = 80 },
+			expectErr: true,
+		},
+		{
+			name:      "#11: CPU threshold of load throttle out of range",
+			modify:    func(o *Options) { o.LoadThrottle.CPUPercent = 120 },
+			expectErr: true,
+		},
+		{
+			name:      "#12: HPA pre-warm threshold out of range",
+			modify:    func(o *Options) { o.HPAPrewarmThreshold = 0 },
+			expectErr: true,
+		},
+		{
+			name: "#13: Guaranteed pulls with a priority class",
+			modify: func(o *Options) {
+				o.GuaranteedPulls = images.GuaranteedPulls{Enabled: true, PriorityClassName: "kubefledged-guaranteed-pull"}
+			},
+		},
+		{
+			name:      "#14: Priority class of guaranteed pulls without guaranteed pulls",
+			modify:    func(o *Options) { o.GuaranteedPulls.PriorityClassName = "kubefledged-guaranteed-pull" },
+			expectErr: true,
+		},
+		{
+			name: "#15: Guaranteed pulls with a scheduler of the jobs",
+			modify: func(o *Options) {
+				o.GuaranteedPulls.Enabled = true
+				o.JobSchedulerName = "secondary-scheduler"
+			},
+			expectErr: true,
+		},
+		{
+			name:      "#16: Registry webhook without a token",
+			modify:    func(o *Options) { o.RegistryWebhookAddress = ":8081" },
+			expectErr: true,
+		},
+		{
+			name: "#17: Insecure registry webhook without a token",
+			modify: func(o *Options) {
+				o.RegistryWebhookAddress = ":8081"
+				o.RegistryWebhookInsecure = true
+			},
+		},
+		{
+			name: "#18: Registry webhook with a token",
+			modify: func(o *Options) {
+				o.RegistryWebhookAddress = ":8081"
+				o.RegistryWebhookToken = "secret"
+			},
+		},
+	}
+	for _, test := range tests {
+		opts := NewOptions()
+		test.modify(opts)
+		if err := opts.Validate(); (err != nil) != test.expectErr {
+			t.Errorf("Test: %s failed: expectErr=%t, actual error=%v", test.name, test.expectErr, err)
+		}
+	}
+}
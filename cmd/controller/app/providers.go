@@ -0,0 +1,200 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/imagelist"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	"github.com/senthilrch/kube-fledged/pkg/policy"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// pluginTimeout is the timeout of the calls to image list plugins
+const pluginTimeout = 30 * time.Second
+
+// imageStreamTimeout is the timeout of the requests reading OpenShift ImageStreams
+const imageStreamTimeout = 30 * time.Second
+
+// systemNamespace is the namespace of the system components of the cluster
+const systemNamespace = "kube-system"
+
+// hasProviders returns true if an image list of the cache spec has image list providers
+func hasProviders(cacheSpec []v1alpha2.CacheSpecImages) bool {
+	for _, i := range cacheSpec {
+		if len(i.Providers) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// newImageListProvider returns the image list provider of a provider spec of an image
+// cache. ConfigMaps and pods are read in the namespace of the image cache, system
+// components in kube-system, and Helm releases, ImageLists and ImageStreams in the
+// namespace of the image cache unless other namespaces are given. Other namespaces must
+// be shared with the namespace of the image cache by a policy, and plugins must be
+// served at an address allowed by --image-list-plugin-addresses.
+func (c *Controller) newImageListProvider(imageCache *v1alpha2.ImageCache, spec v1alpha2.ImageListProviderSpec) (imagelist.ImageListProvider, error) {
+	if err := policy.ValidateProvider(imageCache.Namespace, spec, c.listPolicies()); err != nil {
+		return nil, err
+	}
+	switch {
+	case spec.ConfigMap != nil:
+		return imagelist.NewConfigMapProvider(c.kubeclientset, imageCache.Namespace, spec.ConfigMap.Name, spec.ConfigMap.Key), nil
+	case spec.Workloads != nil:
+		selector := labels.Everything()
+		if spec.Workloads.Selector != nil {
+			var err error
+			if selector, err = metav1.LabelSelectorAsSelector(spec.Workloads.Selector); err != nil {
+				return nil, err
+			}
+		}
+		containers := imagelist.ContainerSelection{
+			InitContainers:      !spec.Workloads.ExcludeInitContainers,
+			EphemeralContainers: spec.Workloads.IncludeEphemeralContainers,
+		}
+		return imagelist.NewWorkloadProvider(c.kubeclientset, imageCache.Namespace, selector, containers), nil
+	case spec.Plugin != nil:
+		if !containsString(c.pluginAddresses, spec.Plugin.Address) {
+			return nil, fmt.Errorf("image list plugin address %s is not allowed by --image-list-plugin-addresses", spec.Plugin.Address)
+		}
+		return imagelist.NewPluginProvider(spec.Plugin.Address, imageCache.Namespace+"/"+imageCache.Name, spec.Plugin.Parameters, pluginTimeout), nil
+	case spec.System != nil:
+		namespaces := spec.System.Namespaces
+		if len(namespaces) == 0 {
+			namespaces = []string{systemNamespace}
+		}
+		return imagelist.NewSystemProvider(c.kubeclientset, namespaces, spec.System.KubernetesVersion, spec.System.PauseImage), nil
+	case spec.HelmRelease != nil:
+		namespace := spec.HelmRelease.Namespace
+		if namespace == "" {
+			namespace = imageCache.Namespace
+		}
+		return imagelist.NewHelmReleaseProvider(c.kubeclientset, namespace, spec.HelmRelease.Name), nil
+	case spec.ImageList != nil:
+		namespace, name := imageListRef(imageCache, spec.ImageList)
+		return imagelist.NewImageListResourceProvider(c.imageListsLister, namespace, name), nil
+	case spec.ImageStream != nil:
+		namespace := spec.ImageStream.Namespace
+		if namespace == "" {
+			namespace = imageCache.Namespace
+		}
+		return imagelist.NewImageStreamProvider(c.kubeclientset.Discovery().RESTClient(), namespace, spec.ImageStream.Name,
+			spec.ImageStream.Tags, imageStreamTimeout), nil
+	}
+	return nil, fmt.Errorf("no image list provider specified")
+}
+
+// syncProviders returns the cache spec with the images of each image list updated with
+// the images listed by its providers, the images listed by the pruned providers of each
+// image list, and whether the cache spec changed. The images previously listed by a
+// pruned provider which are no longer listed are removed. If a provider of an image list
+// fails, no image of the image list is removed.
+func (c *Controller) syncProviders(imageCache *v1alpha2.ImageCache, cacheSpec []v1alpha2.CacheSpecImages, previouslyProvided [][]string) ([]v1alpha2.CacheSpecImages, [][]string, bool) {
+	result := []v1alpha2.CacheSpecImages{}
+	provided := make([][]string, len(cacheSpec))
+	pruning := false
+	changed := false
+	for k, i := range cacheSpec {
+		i = *i.DeepCopy()
+		var previous []string
+		if k < len(previouslyProvided) {
+			previous = previouslyProvided[k]
+		}
+		listed, pruned := []string{}, []string{}
+		failed, prune := false, false
+		for _, spec := range i.Providers {
+			prune = prune || spec.Prune
+			listedImages, err := c.listProviderImages(imageCache, spec)
+			if err != nil {
+				glog.Errorf("Error listing images of provider of image cache %s/%s: %v", imageCache.Namespace, imageCache.Name, err)
+				failed = true
+				continue
+			}
+			for _, image := range listedImages {
+				if !containsString(listed, image) {
+					listed = append(listed, image)
+				}
+				if spec.Prune && !containsString(pruned, image) {
+					pruned = append(pruned, image)
+				}
+			}
+		}
+		images := []string{}
+		for _, image := range i.Images {
+			if prune && !failed && containsString(previous, image) && !containsString(listed, image) {
+				continue
+			}
+			images = append(images, image)
+		}
+		for _, image := range listed {
+			if !containsString(images, image) {
+				images = append(images, image)
+			}
+		}
+		if prune && failed {
+			// The images of a failed provider are pruned once it lists images again
+			for _, image := range previous {
+				if !containsString(pruned, image) {
+					pruned = append(pruned, image)
+				}
+			}
+		}
+		if len(pruned) > 0 {
+			provided[k] = pruned
+			pruning = true
+		}
+		if (len(images) > 0 || len(i.Images) > 0) && !reflect.DeepEqual(images, i.Images) {
+			i.Images = images
+			changed = true
+		}
+		result = append(result, i)
+	}
+	if !pruning {
+		provided = nil
+	}
+	return result, provided, changed
+}
+
+// listProviderImages returns the valid images listed by a provider. Invalid image
+// references are skipped.
+func (c *Controller) listProviderImages(imageCache *v1alpha2.ImageCache, spec v1alpha2.ImageListProviderSpec) ([]string, error) {
+	provider, err := c.newImageListProvider(imageCache, spec)
+	if err != nil {
+		return nil, err
+	}
+	listed, err := provider.ListImages()
+	if err != nil {
+		return nil, err
+	}
+	valid := []string{}
+	for _, image := range listed {
+		if err := images.ValidateImageReference(image); err != nil {
+			glog.Errorf("Image listed by provider of image cache %s/%s skipped: %v", imageCache.Namespace, imageCache.Name, err)
+			continue
+		}
+		valid = append(valid, image)
+	}
+	return valid, nil
+}
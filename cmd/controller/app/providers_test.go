@@ -0,0 +1,193 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	listers "github.com/senthilrch/kube-fledged/pkg/client/listers/kubefledged/v1alpha2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestSyncProviders(t *testing.T) {
+	release := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "release", Namespace: fledgedNameSpace},
+		Data:       map[string]string{"images": "app:v2\nworker:v2\nnot a valid image\n"},
+	}
+	webPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: fledgedNameSpace, Labels: map[string]string{"app": "web"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx:1.23"}}},
+	}
+	kubeProxy := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "kube-proxy", Namespace: systemNamespace}}
+	kubeProxy.Spec.Template.Spec.Containers = []corev1.Container{{Name: "kube-proxy", Image: "registry.k8s.io/kube-proxy:v1.27.3"}}
+	configMapProvider := kubefledgedv1alpha2.ImageListProviderSpec{
+		ConfigMap: &kubefledgedv1alpha2.ConfigMapImageList{Name: "release", Key: "images"}, Prune: true,
+	}
+	workloadProvider := kubefledgedv1alpha2.ImageListProviderSpec{
+		Workloads: &kubefledgedv1alpha2.WorkloadImageList{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+	}
+	tests := []struct {
+		name             string
+		images           []string
+		providers        []kubefledgedv1alpha2.ImageListProviderSpec
+		previous         [][]string
+		expectedImages   []string
+		expectedProvided [][]string
+		expectedChanged  bool
+	}{
+		{
+			name:             "#1: Listed images added",
+			images:           []string{"redis:7"},
+			providers:        []kubefledgedv1alpha2.ImageListProviderSpec{configMapProvider, workloadProvider},
+			expectedImages:   []string{"redis:7", "app:v2", "worker:v2", "nginx:1.23"},
+			expectedProvided: [][]string{{"app:v2", "worker:v2"}},
+			expectedChanged:  true,
+		},
+		{
+			name:             "#2: Images no longer listed pruned",
+			images:           []string{"redis:7", "app:v1", "app:v2", "worker:v2"},
+			providers:        []kubefledgedv1alpha2.ImageListProviderSpec{configMapProvider},
+			previous:         [][]string{{"app:v1", "app:v2"}},
+			expectedImages:   []string{"redis:7", "app:v2", "worker:v2"},
+			expectedProvided: [][]string{{"app:v2", "worker:v2"}},
+			expectedChanged:  true,
+		},
+		{
+			name:            "#3: Images not pruned without prune",
+			images:          []string{"app:v1", "nginx:1.23"},
+			providers:       []kubefledgedv1alpha2.ImageListProviderSpec{workloadProvider},
+			previous:        [][]string{{"app:v1"}},
+			expectedImages:  []string{"app:v1", "nginx:1.23"},
+			expectedChanged: false,
+		},
+		{
+			name:   "#4: Images not pruned when a provider fails",
+			images: []string{"app:v1"},
+			providers: []kubefledgedv1alpha2.ImageListProviderSpec{configMapProvider, {
+				ConfigMap: &kubefledgedv1alpha2.ConfigMapImageList{Name: "missing", Key: "images"}, Prune: true,
+			}},
+			previous:         [][]string{{"app:v1"}},
+			expectedImages:   []string{"app:v1", "app:v2", "worker:v2"},
+			expectedProvided: [][]string{{"app:v2", "worker:v2", "app:v1"}},
+			expectedChanged:  true,
+		},
+		{
+			name:   "#5: System images of the target version of an upgrade",
+			images: []string{"registry.k8s.io/kube-proxy:v1.28.1"},
+			providers: []kubefledgedv1alpha2.ImageListProviderSpec{{
+				System: &kubefledgedv1alpha2.SystemImageList{KubernetesVersion: "v1.28.2"}, Prune: true,
+			}},
+			previous:         [][]string{{"registry.k8s.io/kube-proxy:v1.28.1"}},
+			expectedImages:   []string{"registry.k8s.io/kube-proxy:v1.28.2"},
+			expectedProvided: [][]string{{"registry.k8s.io/kube-proxy:v1.28.2"}},
+			expectedChanged:  true,
+		},
+	}
+	for _, test := range tests {
+		controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(release, webPod, kubeProxy), kubefledgedclientsetfake.NewSimpleClientset())
+		imageCache := &kubefledgedv1alpha2.ImageCache{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+			Spec: kubefledgedv1alpha2.ImageCacheSpec{
+				CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{{Images: test.images, Providers: test.providers}},
+			},
+		}
+		cacheSpec, provided, changed := controller.syncProviders(imageCache, imageCache.Spec.CacheSpec, test.previous)
+		if !reflect.DeepEqual(cacheSpec[0].Images, test.expectedImages) {
+			t.Errorf("Test: %s failed: expected images %v, actual %v", test.name, test.expectedImages, cacheSpec[0].Images)
+		}
+		if !reflect.DeepEqual(provided, test.expectedProvided) {
+			t.Errorf("Test: %s failed: expected provided images %v, actual %v", test.name, test.expectedProvided, provided)
+		}
+		if changed != test.expectedChanged {
+			t.Errorf("Test: %s failed: expected changed=%t, actual %t", test.name, test.expectedChanged, changed)
+		}
+	}
+}
+
+func TestNewImageListProvider(t *testing.T) {
+	policies := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	policies.Add(&kubefledgedv1alpha2.FledgedPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared"},
+		Spec: kubefledgedv1alpha2.FledgedPolicySpec{
+			ProviderNamespaces: []kubefledgedv1alpha2.ProviderNamespace{{Namespace: "platform", Namespaces: []string{"team-a"}}},
+		},
+	})
+	tests := []struct {
+		name                string
+		namespace           string
+		spec                kubefledgedv1alpha2.ImageListProviderSpec
+		expectedErrorString string
+	}{
+		{
+			name:      "#1: Plugin at an allowed address",
+			namespace: "team-a",
+			spec:      kubefledgedv1alpha2.ImageListProviderSpec{Plugin: &kubefledgedv1alpha2.PluginImageList{Address: "catalog.tools.svc:9000"}},
+		},
+		{
+			name:                "#2: Plugin at another address",
+			namespace:           "team-a",
+			spec:                kubefledgedv1alpha2.ImageListProviderSpec{Plugin: &kubefledgedv1alpha2.PluginImageList{Address: "169.254.169.254:80"}},
+			expectedErrorString: "image list plugin address 169.254.169.254:80 is not allowed",
+		},
+		{
+			name:      "#3: Helm release of the namespace of the image cache",
+			namespace: "team-a",
+			spec:      kubefledgedv1alpha2.ImageListProviderSpec{HelmRelease: &kubefledgedv1alpha2.HelmReleaseImageList{Name: "web"}},
+		},
+		{
+			name:      "#4: ImageList of a namespace shared by a policy",
+			namespace: "team-a",
+			spec:      kubefledgedv1alpha2.ImageListProviderSpec{ImageList: &kubefledgedv1alpha2.ImageListReference{Namespace: "platform", Name: "base-images"}},
+		},
+		{
+			name:                "#5: Helm release of another namespace",
+			namespace:           "team-b",
+			spec:                kubefledgedv1alpha2.ImageListProviderSpec{HelmRelease: &kubefledgedv1alpha2.HelmReleaseImageList{Namespace: "platform", Name: "web"}},
+			expectedErrorString: "Namespace team-b is not allowed by any policy to list the images of namespace platform",
+		},
+		{
+			name:                "#6: ImageStream of another namespace",
+			namespace:           "team-a",
+			spec:                kubefledgedv1alpha2.ImageListProviderSpec{ImageStream: &kubefledgedv1alpha2.ImageStreamImageList{Namespace: "kube-system", Name: "app"}},
+			expectedErrorString: "Namespace team-a is not allowed by any policy to list the images of namespace kube-system",
+		},
+	}
+	controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	controller.policiesLister = listers.NewFledgedPolicyLister(policies)
+	controller.pluginAddresses = []string{"catalog.tools.svc:9000"}
+	for _, test := range tests {
+		imageCache := &kubefledgedv1alpha2.ImageCache{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: test.namespace}}
+		_, err := controller.newImageListProvider(imageCache, test.spec)
+		if test.expectedErrorString == "" {
+			if err != nil {
+				t.Errorf("Test: %s failed: expectedError=nil, actualError=%s", test.name, err.Error())
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.expectedErrorString) {
+			t.Errorf("Test: %s failed: expectedError=%s, actualError=%v", test.name, test.expectedErrorString, err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/version"
+)
+
+// specHash returns the hash of the spec of an image cache, i.e. the SHA-256 of its JSON
+// encoding, or an empty string if it cannot be encoded
+func specHash(spec v1alpha2.ImageCacheSpec) string {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		glog.Errorf("Error encoding image cache spec: %v", err)
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// recordReconciled records the version of the controller and the hash of the spec the
+// action was planned from in the status of a successful image cache action. Purges
+// remove the images of the image cache and do not reconcile its spec.
+func recordReconciled(status *v1alpha2.ImageCacheStatus, action string) {
+	if action == v1alpha2.ImageCacheReasonImageCachePurge || status.Plan == nil || status.Plan.SpecHash == "" {
+		return
+	}
+	if status.Status != v1alpha2.ImageCacheActionStatusSucceeded && status.Status != v1alpha2.ImageCacheActioneNoImagesPulledOrDeleted {
+		return
+	}
+	status.ControllerVersion = version.Version
+	status.ObservedSpecHash = status.Plan.SpecHash
+}
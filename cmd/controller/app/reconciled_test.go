@@ -0,0 +1,120 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"testing"
+
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	"github.com/senthilrch/kube-fledged/pkg/version"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSpecHash(t *testing.T) {
+	spec := v1alpha2.ImageCacheSpec{
+		CacheSpec: []v1alpha2.CacheSpecImages{
+			{Images: []string{"nginx:1.23"}, NodeSelector: map[string]string{"pool": "web", "zone": "a"}},
+		},
+	}
+	hash := specHash(spec)
+	if len(hash) != 64 {
+		t.Errorf("expected a SHA-256 hex digest, actual %q", hash)
+	}
+	same := *spec.DeepCopy()
+	if actual := specHash(same); actual != hash {
+		t.Errorf("expected hash of an identical spec to be %s, actual %s", hash, actual)
+	}
+	changed := *spec.DeepCopy()
+	changed.CacheSpec[0].Images = append(changed.CacheSpec[0].Images, "httpd:2.4")
+	if actual := specHash(changed); actual == hash {
+		t.Errorf("expected hash of a changed spec to differ from %s", hash)
+	}
+}
+
+func TestRecordReconciled(t *testing.T) {
+	plan := &v1alpha2.ImageCachePlan{WorkItems: 1, SpecHash: "abc"}
+	tests := []struct {
+		name         string
+		status       v1alpha2.ImageCacheStatus
+		action       string
+		expectedHash string
+	}{
+		{
+			name:         "#1: Successful refresh",
+			status:       v1alpha2.ImageCacheStatus{Status: v1alpha2.ImageCacheActionStatusSucceeded, Plan: plan},
+			action:       v1alpha2.ImageCacheReasonImageCacheRefresh,
+			expectedHash: "abc",
+		},
+		{
+			name:         "#2: No images pulled",
+			status:       v1alpha2.ImageCacheStatus{Status: v1alpha2.ImageCacheActioneNoImagesPulledOrDeleted, Plan: plan},
+			action:       v1alpha2.ImageCacheReasonImageCacheCreate,
+			expectedHash: "abc",
+		},
+		{
+			name:   "#3: Failed update",
+			status: v1alpha2.ImageCacheStatus{Status: v1alpha2.ImageCacheActionStatusFailed, Plan: plan},
+			action: v1alpha2.ImageCacheReasonImageCacheUpdate,
+		},
+		{
+			name:   "#4: Successful purge",
+			status: v1alpha2.ImageCacheStatus{Status: v1alpha2.ImageCacheActionStatusSucceeded, Plan: plan},
+			action: v1alpha2.ImageCacheReasonImageCachePurge,
+		},
+	}
+	for _, test := range tests {
+		status := test.status
+		recordReconciled(&status, test.action)
+		if status.ObservedSpecHash != test.expectedHash {
+			t.Errorf("Test: %s failed: expected observed spec hash %q, actual %q", test.name, test.expectedHash, status.ObservedSpecHash)
+		}
+		expectedVersion := ""
+		if test.expectedHash != "" {
+			expectedVersion = version.Version
+		}
+		if status.ControllerVersion != expectedVersion {
+			t.Errorf("Test: %s failed: expected controller version %q, actual %q", test.name, expectedVersion, status.ControllerVersion)
+		}
+	}
+}
+
+func TestUpdateImageCacheStatusKeepsReconcile(t *testing.T) {
+	imageCache := &v1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+		Status: v1alpha2.ImageCacheStatus{
+			Status:            v1alpha2.ImageCacheActionStatusSucceeded,
+			ControllerVersion: "v0.10.0",
+			ObservedSpecHash:  "abc",
+		},
+	}
+	fledgedclientset := kubefledgedclientsetfake.NewSimpleClientset(imageCache)
+	controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), fledgedclientset)
+	status := &v1alpha2.ImageCacheStatus{Status: v1alpha2.ImageCacheActionStatusProcessing}
+	if err := controller.updateImageCacheStatus(imageCache, status); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	updated, err := fledgedclientset.KubefledgedV1alpha2().ImageCaches(fledgedNameSpace).Get(context.TODO(), "foo", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.Status.ControllerVersion != "v0.10.0" || updated.Status.ObservedSpecHash != "abc" {
+		t.Errorf("expected last reconcile to be kept, actual status %+v", updated.Status)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"time"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// refreshIntervalCheckInterval is the interval at which the image lists overriding the
+// refresh interval are checked for refresh
+const refreshIntervalCheckInterval = time.Minute
+
+// hasRefreshIntervals returns true if an image list of the cache spec overrides the
+// refresh interval
+func hasRefreshIntervals(cacheSpec []v1alpha2.CacheSpecImages) bool {
+	for _, i := range cacheSpec {
+		if i.RefreshInterval != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// runRefreshIntervalWorker refreshes the image lists of the image caches overriding the
+// refresh interval, once their interval has elapsed since they were last pulled. The
+// image lists not overriding the refresh interval are refreshed at the image cache
+// refresh frequency of the controller. Such image caches are not refreshed by the
+// refresh worker.
+func (c *Controller) runRefreshIntervalWorker() {
+	imageCaches, err := c.imageCachesLister.ImageCaches("").List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error in listing image caches: %v", err)
+		return
+	}
+	now := time.Now()
+	for _, imageCache := range imageCaches {
+		if !hasRefreshIntervals(imageCache.Spec.CacheSpec) || !canRefresh(imageCache) {
+			continue
+		}
+		entries := c.dueEntries(imageCache, now)
+		if len(entries) == 0 {
+			continue
+		}
+		key, err := cache.MetaNamespaceKeyFunc(imageCache)
+		if err != nil {
+			glog.Errorf("Error getting key of image cache %s/%s: %v", imageCache.Namespace, imageCache.Name, err)
+			continue
+		}
+		glog.Infof("Refreshing image lists %v of image cache %s", entries, key)
+		c.workqueue.AddRateLimited(images.WorkQueueKey{WorkType: images.ImageCacheRefresh, ObjKey: key, Entries: &entries, Background: true})
+	}
+}
+
+// dueEntries returns the indexes of the image lists of the image cache whose refresh
+// interval elapsed since they were last pulled
+func (c *Controller) dueEntries(imageCache *v1alpha2.ImageCache, now time.Time) []int {
+	entries := []int{}
+	for k, i := range imageCache.Spec.CacheSpec {
+		interval := c.refreshFrequency()
+		if i.RefreshInterval != nil {
+			interval = i.RefreshInterval.Duration
+		}
+		if interval == 0 {
+			continue
+		}
+		last := imageCache.CreationTimestamp
+		if k < len(imageCache.Status.EntryRefreshTimes) {
+			last = imageCache.Status.EntryRefreshTimes[k]
+		} else if imageCache.Status.StartTime != nil {
+			last = *imageCache.Status.StartTime
+		}
+		if !now.Before(last.Add(interval)) {
+			entries = append(entries, k)
+		}
+	}
+	return entries
+}
+
+// entryRefreshTimes returns the times the image lists of the cache spec were last
+// pulled, once the images of the given entries are pulled at startTime. All the
+// entries are pulled if entries is nil.
+func entryRefreshTimes(previous []metav1.Time, entryCount int, entries *[]int, startTime metav1.Time) []metav1.Time {
+	times := make([]metav1.Time, entryCount)
+	for k := range times {
+		if entries == nil || containsEntry(*entries, k) || k >= len(previous) {
+			times[k] = startTime
+			continue
+		}
+		times[k] = previous[k]
+	}
+	return times
+}
+
+// entryDue returns true if the images of the cache spec entry are to be pulled by the
+// action of the work queue key
+func entryDue(wqKey images.WorkQueueKey, k int) bool {
+	return wqKey.Entries == nil || containsEntry(*wqKey.Entries, k)
+}
+
+func containsEntry(entries []int, k int) bool {
+	for _, e := range entries {
+		if e == k {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDueEntries(t *testing.T) {
+	now := time.Now()
+	created := metav1.NewTime(now.Add(-2 * time.Hour))
+	started := metav1.NewTime(now.Add(-30 * time.Minute))
+	cacheSpec := []kubefledgedv1alpha2.CacheSpecImages{
+		{Images: []string{"alpine:3.18"}, RefreshInterval: &metav1.Duration{Duration: 168 * time.Hour}},
+		{Images: []string{"myapp:latest"}, RefreshInterval: &metav1.Duration{Duration: 15 * time.Minute}},
+		{Images: []string{"nginx:1.23"}},
+		{Images: []string{"redis:7"}, RefreshInterval: &metav1.Duration{}},
+	}
+	tests := []struct {
+		name             string
+		refreshFrequency time.Duration
+		status           kubefledgedv1alpha2.ImageCacheStatus
+		expectedEntries  []int
+	}{
+		{
+			name:             "#1: Entries last pulled by the last action",
+			refreshFrequency: 15 * time.Minute,
+			status:           kubefledgedv1alpha2.ImageCacheStatus{StartTime: &started},
+			expectedEntries:  []int{1, 2},
+		},
+		{
+			name:             "#2: Refresh frequency of the controller disabled",
+			refreshFrequency: 0,
+			status:           kubefledgedv1alpha2.ImageCacheStatus{StartTime: &started},
+			expectedEntries:  []int{1},
+		},
+		{
+			name:             "#3: Entries last pulled at different times",
+			refreshFrequency: time.Hour,
+			status: kubefledgedv1alpha2.ImageCacheStatus{
+				StartTime: &started,
+				EntryRefreshTimes: []metav1.Time{
+					metav1.NewTime(now.Add(-200 * time.Hour)),
+					metav1.NewTime(now.Add(-5 * time.Minute)),
+					metav1.NewTime(now.Add(-90 * time.Minute)),
+					metav1.NewTime(now.Add(-90 * time.Minute)),
+				},
+			},
+			expectedEntries: []int{0, 2},
+		},
+		{
+			name:             "#4: Image cache never pulled",
+			refreshFrequency: 3 * time.Hour,
+			expectedEntries:  []int{1},
+		},
+	}
+	controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	for _, test := range tests {
+		controller.imageCacheRefreshFrequency = test.refreshFrequency
+		imageCache := &kubefledgedv1alpha2.ImageCache{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace, CreationTimestamp: created},
+			Spec:       kubefledgedv1alpha2.ImageCacheSpec{CacheSpec: cacheSpec},
+			Status:     test.status,
+		}
+		if actual := controller.dueEntries(imageCache, now); !reflect.DeepEqual(actual, test.expectedEntries) {
+			t.Errorf("Test: %s failed: expected=%v, actual=%v", test.name, test.expectedEntries, actual)
+		}
+	}
+}
+
+func TestEntryRefreshTimes(t *testing.T) {
+	earlier := metav1.NewTime(time.Now().Add(-time.Hour))
+	now := metav1.Now()
+	tests := []struct {
+		name       string
+		previous   []metav1.Time
+		entryCount int
+		entries    *[]int
+		expected   []metav1.Time
+	}{
+		{
+			name:       "#1: All entries pulled",
+			previous:   []metav1.Time{earlier, earlier},
+			entryCount: 2,
+			expected:   []metav1.Time{now, now},
+		},
+		{
+			name:       "#2: Due entries pulled",
+			previous:   []metav1.Time{earlier, earlier},
+			entryCount: 2,
+			entries:    &[]int{1},
+			expected:   []metav1.Time{earlier, now},
+		},
+		{
+			name:       "#3: Entries added to the cache spec",
+			previous:   []metav1.Time{earlier},
+			entryCount: 3,
+			entries:    &[]int{0},
+			expected:   []metav1.Time{now, now, now},
+		},
+	}
+	for _, test := range tests {
+		if actual := entryRefreshTimes(test.previous, test.entryCount, test.entries, now); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test: %s failed: expected=%v, actual=%v", test.name, test.expected, actual)
+		}
+	}
+}
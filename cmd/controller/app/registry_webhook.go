@@ -0,0 +1,263 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/golang/glog"
+	"github.com/senthilrch/kube-fledged/pkg/features"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// maxRegistryWebhookPayload is the maximum size of a registry push notification
+const maxRegistryWebhookPayload = 1 << 20
+
+// harborPushEvent is the payload of a Harbor PUSH_ARTIFACT webhook
+type harborPushEvent struct {
+	Type      string `json:"type"`
+	EventData struct {
+		Resources []struct {
+			ResourceURL string `json:"resource_url"`
+		} `json:"resources"`
+	} `json:"event_data"`
+}
+
+// dockerHubPushEvent is the payload of a Docker Hub webhook
+type dockerHubPushEvent struct {
+	PushData struct {
+		Tag string `json:"tag"`
+	} `json:"push_data"`
+	Repository struct {
+		RepoName string `json:"repo_name"`
+	} `json:"repository"`
+}
+
+// quayPushEvent is the payload of a Quay repository push notification
+type quayPushEvent struct {
+	DockerURL   string   `json:"docker_url"`
+	UpdatedTags []string `json:"updated_tags"`
+}
+
+// StartRegistryWebhook serves endpoints accepting the push notifications of container
+// registries on addr, until stopCh is closed. Image caches listing a pushed image are
+// refreshed. An empty token is refused unless insecure is set.
+func (c *Controller) StartRegistryWebhook(addr string, token string, insecure bool, stopCh <-chan struct{}) error {
+	if !features.Enabled(features.RegistryWebhook) {
+		return fmt.Errorf("registry webhook requires the %s feature gate", features.RegistryWebhook)
+	}
+	if token == "" && !insecure {
+		return fmt.Errorf("registry webhook requires a token unless it is insecure")
+	}
+	mux := c.registryWebhookMux(token)
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-stopCh
+		server.Shutdown(context.Background())
+	}()
+	glog.Infof("Registry webhook listening on %s", addr)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// registryWebhookMux returns the endpoints of the registry webhook. Harbor notifications
+// are accepted at "/harbor" and must carry token as a bearer token in the Authorization
+// header. Docker Hub and Quay cannot add headers to their notifications: they are
+// accepted at "/dockerhub/<token>" and "/quay/<token>" respectively.
+func (c *Controller) registryWebhookMux(token string) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/harbor", c.registryWebhookHandler(bearerTokenAuthorizer(token), parseHarborPushEvent))
+	mux.HandleFunc("/dockerhub", c.registryWebhookHandler(pathTokenAuthorizer("/dockerhub", token), parseDockerHubPushEvent))
+	mux.HandleFunc("/dockerhub/", c.registryWebhookHandler(pathTokenAuthorizer("/dockerhub", token), parseDockerHubPushEvent))
+	mux.HandleFunc("/quay", c.registryWebhookHandler(pathTokenAuthorizer("/quay", token), parseQuayPushEvent))
+	mux.HandleFunc("/quay/", c.registryWebhookHandler(pathTokenAuthorizer("/quay", token), parseQuayPushEvent))
+	return mux
+}
+
+// registryWebhookHandler returns a handler of push notifications authorized by authorized
+// and parsed by parse into the pushed images
+func (c *Controller) registryWebhookHandler(authorized func(*http.Request) bool, parse func([]byte) ([]string, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !authorized(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxRegistryWebhookPayload))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		pushed, err := parse(body)
+		if err != nil {
+			glog.Errorf("Error parsing registry push notification: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		refreshed, err := c.refreshImageCachesOfImages(pushed)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		glog.Infof("Registry push of %v triggered refresh of image caches %v", pushed, refreshed)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]string{"refreshed": refreshed})
+	}
+}
+
+// bearerTokenAuthorizer authorizes the requests carrying token as a bearer token in
+// their Authorization header. An empty token authorizes all requests.
+func bearerTokenAuthorizer(token string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		if token == "" {
+			return true
+		}
+		authorization := r.Header.Get("Authorization")
+		if !strings.HasPrefix(authorization, "Bearer ") {
+			return false
+		}
+		return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(authorization, "Bearer ")), []byte(token)) == 1
+	}
+}
+
+// pathTokenAuthorizer authorizes the requests whose path is endpoint followed by token as
+// its last segment. An empty token authorizes all requests.
+func pathTokenAuthorizer(endpoint, token string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		if token == "" {
+			return true
+		}
+		if !strings.HasPrefix(r.URL.Path, endpoint+"/") {
+			return false
+		}
+		return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(r.URL.Path, endpoint+"/")), []byte(token)) == 1
+	}
+}
+
+func parseHarborPushEvent(body []byte) ([]string, error) {
+	e := harborPushEvent{}
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, err
+	}
+	if e.Type != "PUSH_ARTIFACT" && e.Type != "pushImage" {
+		return nil, nil
+	}
+	pushed := []string{}
+	for _, r := range e.EventData.Resources {
+		pushed = append(pushed, r.ResourceURL)
+	}
+	return pushed, nil
+}
+
+func parseDockerHubPushEvent(body []byte) ([]string, error) {
+	e := dockerHubPushEvent{}
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, err
+	}
+	if e.Repository.RepoName == "" {
+		return nil, fmt.Errorf("push notification does not name a repository")
+	}
+	return []string{e.Repository.RepoName + ":" + e.PushData.Tag}, nil
+}
+
+func parseQuayPushEvent(body []byte) ([]string, error) {
+	e := quayPushEvent{}
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, err
+	}
+	if e.DockerURL == "" {
+		return nil, fmt.Errorf("push notification does not name a repository")
+	}
+	pushed := []string{}
+	for _, tag := range e.UpdatedTags {
+		pushed = append(pushed, e.DockerURL+":"+tag)
+	}
+	return pushed, nil
+}
+
+// refreshImageCachesOfImages queues a refresh of the image caches listing any of the
+// pushed images, and returns the keys of the refreshed image caches
+func (c *Controller) refreshImageCachesOfImages(pushed []string) ([]string, error) {
+	refreshed := []string{}
+	if len(pushed) == 0 {
+		return refreshed, nil
+	}
+	imageCaches, err := c.imageCachesLister.ImageCaches("").List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error in listing image caches: %v", err)
+		return nil, err
+	}
+	for i := range imageCaches {
+		if !canRefresh(imageCaches[i]) {
+			continue
+		}
+		matched := false
+		for _, cacheSpec := range imageListsOf(imageCaches[i]) {
+			for _, image := range cacheSpec.Images {
+				for _, p := range pushed {
+					if sameImage(image, p) {
+						matched = true
+					}
+				}
+			}
+		}
+		if matched && c.enqueueImageCache(images.ImageCacheRefresh, imageCaches[i], nil) {
+			refreshed = append(refreshed, imageCaches[i].Namespace+"/"+imageCaches[i].Name)
+		}
+	}
+	return refreshed, nil
+}
+
+// sameImage returns true if both image references name the same repository and tag.
+// Images referenced by digest never match a pushed tag.
+func sameImage(image string, pushed string) bool {
+	a, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return false
+	}
+	b, err := reference.ParseNormalizedNamed(pushed)
+	if err != nil {
+		return false
+	}
+	if a.Name() != b.Name() {
+		return false
+	}
+	if _, ok := a.(reference.Digested); ok {
+		return false
+	}
+	at, bt := "latest", "latest"
+	if tagged, ok := a.(reference.Tagged); ok {
+		at = tagged.Tag()
+	}
+	if tagged, ok := b.(reference.Tagged); ok {
+		bt = tagged.Tag()
+	}
+	return at == bt
+}
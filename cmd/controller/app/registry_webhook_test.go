@@ -0,0 +1,173 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	"github.com/senthilrch/kube-fledged/pkg/features"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSameImage(t *testing.T) {
+	tests := []struct {
+		name     string
+		image    string
+		pushed   string
+		expected bool
+	}{
+		{name: "#1: Normalized names match", image: "nginx:1.23", pushed: "docker.io/library/nginx:1.23", expected: true},
+		{name: "#2: No tag matches latest", image: "ghcr.io/foo/bar", pushed: "ghcr.io/foo/bar:latest", expected: true},
+		{name: "#3: Different tag", image: "nginx:1.23", pushed: "nginx:1.24", expected: false},
+		{name: "#4: Different repository", image: "quay.io/foo/bar:v1", pushed: "quay.io/foo/baz:v1", expected: false},
+		{name: "#5: Image referenced by digest", image: "nginx@sha256:0123456789012345678901234567890123456789012345678901234567890123", pushed: "nginx:latest", expected: false},
+	}
+	for _, test := range tests {
+		if actual := sameImage(test.image, test.pushed); actual != test.expected {
+			t.Errorf("Test: %s failed: expected=%t, actual=%t", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestStartRegistryWebhookRefused(t *testing.T) {
+	enableFeatures(t, features.RegistryWebhook)
+	controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	if err := controller.StartRegistryWebhook(":0", "", false, make(chan struct{})); err == nil {
+		t.Errorf("expected an error starting the registry webhook without a token")
+	}
+	features.DefaultMutableFeatureGate.SetFromMap(map[string]bool{string(features.RegistryWebhook): false})
+	if err := controller.StartRegistryWebhook(":0", "secret", false, make(chan struct{})); err == nil {
+		t.Errorf("expected an error starting the registry webhook with the %s feature gate disabled", features.RegistryWebhook)
+	}
+}
+
+func TestRegistryWebhook(t *testing.T) {
+	synced := kubefledgedv1alpha2.ImageCacheStatus{
+		Status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+		Reason: kubefledgedv1alpha2.ImageCacheReasonImagesPulledSuccessfully,
+	}
+	imageCaches := []kubefledgedv1alpha2.ImageCache{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+			Spec: kubefledgedv1alpha2.ImageCacheSpec{
+				CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{{Images: []string{"quay.io/foo/bar:v1"}}},
+			},
+			Status: synced,
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: fledgedNameSpace},
+			Spec: kubefledgedv1alpha2.ImageCacheSpec{
+				CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{{Images: []string{"quay.io/foo/bar:v2"}}},
+			},
+			Status: synced,
+		},
+	}
+	tests := []struct {
+		name           string
+		path           string
+		authorization  string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "#1: Missing token",
+			path:           "/quay",
+			body:           `{"docker_url":"quay.io/foo/bar","updated_tags":["v1"]}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "#2: Quay push of cached tag",
+			path:           "/quay/secret",
+			body:           `{"docker_url":"quay.io/foo/bar","updated_tags":["v1"]}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"refreshed":["kube-fledged/foo"]}`,
+		},
+		{
+			name:           "#3: Harbor push of uncached tag",
+			path:           "/harbor",
+			authorization:  "Bearer secret",
+			body:           `{"type":"PUSH_ARTIFACT","event_data":{"resources":[{"resource_url":"quay.io/foo/bar:v3"}]}}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"refreshed":[]}`,
+		},
+		{
+			name:           "#4: Invalid payload",
+			path:           "/dockerhub/secret",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "#5: Token in query parameter",
+			path:           "/quay?token=secret",
+			body:           `{"docker_url":"quay.io/foo/bar","updated_tags":["v1"]}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "#6: Wrong token",
+			path:           "/quay/public",
+			body:           `{"docker_url":"quay.io/foo/bar","updated_tags":["v1"]}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "#7: Harbor bearer token without Bearer prefix",
+			path:           "/harbor",
+			authorization:  "secret",
+			body:           `{"type":"PUSH_ARTIFACT","event_data":{"resources":[{"resource_url":"quay.io/foo/bar:v3"}]}}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "#8: Docker Hub token in Authorization header",
+			path:           "/dockerhub",
+			authorization:  "Bearer secret",
+			body:           `{}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "#9: Token followed by a path segment",
+			path:           "/quay/secret/foo",
+			body:           `{"docker_url":"quay.io/foo/bar","updated_tags":["v1"]}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, test := range tests {
+		controller, _, imagecacheInformer := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+		for i := range imageCaches {
+			imagecacheInformer.Informer().GetIndexer().Add(&imageCaches[i])
+		}
+		mux := controller.registryWebhookMux("secret")
+
+		req := httptest.NewRequest(http.MethodPost, test.path, strings.NewReader(test.body))
+		if test.authorization != "" {
+			req.Header.Set("Authorization", test.authorization)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != test.expectedStatus {
+			t.Errorf("Test: %s failed: expectedStatus=%d, actualStatus=%d", test.name, test.expectedStatus, rec.Code)
+		}
+		if body := strings.TrimSpace(rec.Body.String()); test.expectedBody != "" && body != test.expectedBody {
+			t.Errorf("Test: %s failed: expectedBody=%s, actualBody=%s", test.name, test.expectedBody, body)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+// resolveInterval is the interval at which the registry catalogs and the image list
+// providers of the image caches are listed again
+const resolveInterval = time.Minute
+
+// imageListResolution holds the images added to the image lists of an image cache by
+// their registry catalogs, image list providers and tracked repositories
+type imageListResolution struct {
+	// resolved are the images added to each image list, in the order of the cache spec
+	resolved [][]string
+	// provided are the images listed by the pruned providers of each image list
+	provided [][]string
+}
+
+// resolvesImages returns true if an image list of the cache spec has registry catalogs,
+// image list providers or tracked repositories
+func resolvesImages(cacheSpec []v1alpha2.CacheSpecImages) bool {
+	return hasCatalogs(cacheSpec) || hasProviders(cacheSpec) || hasTrackedRepositories(cacheSpec)
+}
+
+// resolvedCacheSpec returns the cache spec with the resolved images of each image list
+// having catalogs, providers or tracked repositories added to its images. The cache spec itself is left as
+// is: it is owned by the user.
+func resolvedCacheSpec(cacheSpec []v1alpha2.CacheSpecImages, resolved [][]string) []v1alpha2.CacheSpecImages {
+	if len(resolved) == 0 {
+		return cacheSpec
+	}
+	result := make([]v1alpha2.CacheSpecImages, 0, len(cacheSpec))
+	for k, i := range cacheSpec {
+		if k < len(resolved) && (len(i.Catalogs) > 0 || len(i.Providers) > 0 || len(i.TrackedRepositories) > 0) {
+			i = *i.DeepCopy()
+			for _, image := range resolved[k] {
+				if !containsString(i.Images, image) {
+					i.Images = append(i.Images, image)
+				}
+			}
+		}
+		result = append(result, i)
+	}
+	return result
+}
+
+// imageListsOf returns the image lists of an image cache with the images resolved for
+// its last action
+func imageListsOf(imageCache *v1alpha2.ImageCache) []v1alpha2.CacheSpecImages {
+	return resolvedCacheSpec(imageCache.Spec.CacheSpec, imageCache.Status.ResolvedImages)
+}
+
+// lastResolution returns the images last resolved for the image lists of an image cache:
+// those resolved by the resolve worker if any, else those of its status
+func (c *Controller) lastResolution(imageCache *v1alpha2.ImageCache) imageListResolution {
+	if !resolvesImages(imageCache.Spec.CacheSpec) {
+		return imageListResolution{}
+	}
+	c.resolutionsLock.Lock()
+	defer c.resolutionsLock.Unlock()
+	if resolution, ok := c.resolutions[imageCache.Namespace+"/"+imageCache.Name]; ok {
+		return resolution
+	}
+	return imageListResolution{resolved: imageCache.Status.ResolvedImages, provided: imageCache.Status.ProvidedImages}
+}
+
+// enqueueResolve queues an image cache with registry catalogs or image list providers
+// for the resolve worker
+func (c *Controller) enqueueResolve(imageCache *v1alpha2.ImageCache) {
+	if !resolvesImages(imageCache.Spec.CacheSpec) {
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(imageCache)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.resolveQueue.Add(key)
+}
+
+// enqueueResolves queues all the image caches with registry catalogs or image list
+// providers for the resolve worker
+func (c *Controller) enqueueResolves() {
+	imageCaches, err := c.imageCachesLister.ImageCaches("").List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error in listing image caches: %v", err)
+		return
+	}
+	for _, imageCache := range imageCaches {
+		c.enqueueResolve(imageCache)
+	}
+}
+
+// runResolveWorker lists the registry catalogs and image list providers of the image
+// caches queued on the resolve queue. Registries, plugins and the API server may be
+// slow to answer: they are never called by the sync worker.
+func (c *Controller) runResolveWorker() {
+	for c.processNextResolveItem() {
+	}
+}
+
+// processNextResolveItem reads a single image cache key off the resolve queue and
+// resolves the images of its image lists
+func (c *Controller) processNextResolveItem() bool {
+	obj, shutdown := c.resolveQueue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.resolveQueue.Done(obj)
+	key, ok := obj.(string)
+	if !ok {
+		c.resolveQueue.Forget(obj)
+		runtime.HandleError(fmt.Errorf("unexpected type in resolve queue: %#v", obj))
+		return true
+	}
+	c.resolveImageCache(key)
+	c.resolveQueue.Forget(obj)
+	return true
+}
+
+// resolveImageCache resolves the images of the image lists of an image cache, and
+// refreshes the image lists whose resolved images changed since its last action. The
+// refresh pulls the added images and purges the pruned ones. The refresh of an image
+// cache busy with another action is retried.
+func (c *Controller) resolveImageCache(key string) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	imageCache, err := c.imageCachesLister.ImageCaches(namespace).Get(name)
+	if err != nil || !resolvesImages(imageCache.Spec.CacheSpec) {
+		if err != nil && !errors.IsNotFound(err) {
+			glog.Errorf("Error getting imagecache(%s): %v", key, err)
+			return
+		}
+		c.resolutionsLock.Lock()
+		delete(c.resolutions, key)
+		c.resolutionsLock.Unlock()
+		return
+	}
+	resolution := c.resolveImageLists(imageCache, c.lastResolution(imageCache))
+	c.resolutionsLock.Lock()
+	c.resolutions[key] = resolution
+	c.resolutionsLock.Unlock()
+
+	entries := changedEntries(imageCache.Status.ResolvedImages, resolution.resolved, len(imageCache.Spec.CacheSpec))
+	if len(entries) == 0 {
+		return
+	}
+	if !canRefresh(imageCache) {
+		// The action under way may not have picked up the resolved images
+		if busy(imageCache) {
+			c.resolveQueue.AddAfter(key, nodeUpdateRetryInterval)
+		}
+		return
+	}
+	glog.Infof("Images resolved for image lists %v of image cache %s changed, refreshing", entries, key)
+	c.workqueue.AddRateLimited(images.WorkQueueKey{WorkType: images.ImageCacheRefresh, ObjKey: key, Entries: &entries, Background: true})
+}
+
+// resolveImageLists lists the registry catalogs, image list providers and tracked
+// repositories of the image lists of an image cache, and returns the images they add to
+// each image list. The images previously resolved are kept, unless pruned. The images
+// of the cache spec are never pruned.
+func (c *Controller) resolveImageLists(imageCache *v1alpha2.ImageCache, previous imageListResolution) imageListResolution {
+	cacheSpec := resolvedCacheSpec(imageCache.Spec.CacheSpec, previous.resolved)
+	if hasCatalogs(cacheSpec) {
+		cacheSpec, _ = c.syncCatalogs(imageCache, cacheSpec)
+	}
+	resolution := imageListResolution{}
+	if hasProviders(cacheSpec) {
+		cacheSpec, resolution.provided, _ = c.syncProviders(imageCache, cacheSpec, previous.provided)
+	}
+	if hasTrackedRepositories(cacheSpec) {
+		cacheSpec = c.syncTrackedRepositories(imageCache, cacheSpec)
+	}
+	resolved := make([][]string, len(cacheSpec))
+	found := false
+	for k, i := range cacheSpec {
+		for _, image := range i.Images {
+			if !containsString(imageCache.Spec.CacheSpec[k].Images, image) {
+				resolved[k] = append(resolved[k], image)
+				found = true
+			}
+		}
+	}
+	if found {
+		resolution.resolved = resolved
+	}
+	return resolution
+}
+
+// changedEntries returns the indexes of the image lists whose resolved images differ
+func changedEntries(old, new [][]string, entries int) []int {
+	changed := []int{}
+	for k := 0; k < entries; k++ {
+		var o, n []string
+		if k < len(old) {
+			o = old[k]
+		}
+		if k < len(new) {
+			n = new[k]
+		}
+		if len(o) != len(n) || (len(o) > 0 && !reflect.DeepEqual(o, n)) {
+			changed = append(changed, k)
+		}
+	}
+	return changed
+}
+
+// busy returns true if an image cache is not yet synced, under processing or waiting
+// for approval
+func busy(imageCache *v1alpha2.ImageCache) bool {
+	return imageCache.Status.Status == "" ||
+		imageCache.Status.Status == v1alpha2.ImageCacheActionStatusProcessing ||
+		imageCache.Status.Status == v1alpha2.ImageCacheActionStatusPendingApproval
+}
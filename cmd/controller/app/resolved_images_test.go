@@ -0,0 +1,183 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+	"time"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestResolvedCacheSpec(t *testing.T) {
+	helmReleaseProvider := []kubefledgedv1alpha2.ImageListProviderSpec{{HelmRelease: &kubefledgedv1alpha2.HelmReleaseImageList{Name: "web"}}}
+	tests := []struct {
+		name           string
+		cacheSpec      []kubefledgedv1alpha2.CacheSpecImages
+		resolved       [][]string
+		expectedImages [][]string
+	}{
+		{
+			name: "#1: Resolved images added",
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"redis:7"}},
+				{Images: []string{"web:v1"}, Providers: helmReleaseProvider},
+			},
+			resolved:       [][]string{nil, {"web:v1", "web-init:v1"}},
+			expectedImages: [][]string{{"redis:7"}, {"web:v1", "web-init:v1"}},
+		},
+		{
+			name: "#2: Image list without catalogs and providers",
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"redis:7"}},
+			},
+			resolved:       [][]string{{"web:v1"}},
+			expectedImages: [][]string{{"redis:7"}},
+		},
+		{
+			name: "#3: Image list added to the cache spec",
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"redis:7"}, Providers: helmReleaseProvider},
+				{Providers: helmReleaseProvider},
+			},
+			resolved:       [][]string{{"web:v1"}},
+			expectedImages: [][]string{{"redis:7", "web:v1"}, nil},
+		},
+	}
+	for _, test := range tests {
+		cacheSpec := resolvedCacheSpec(test.cacheSpec, test.resolved)
+		actual := [][]string{}
+		for _, i := range cacheSpec {
+			actual = append(actual, i.Images)
+		}
+		if !reflect.DeepEqual(actual, test.expectedImages) {
+			t.Errorf("Test: %s failed: expected images %v, actual %v", test.name, test.expectedImages, actual)
+		}
+		if len(test.cacheSpec) > 1 && len(test.cacheSpec[1].Images) > 1 {
+			t.Errorf("Test: %s failed: cache spec modified", test.name)
+		}
+	}
+}
+
+func TestResolveImageCache(t *testing.T) {
+	release := `{"manifest":"apiVersion: apps/v1\nkind: Deployment\nspec:\n  template:\n    spec:\n      containers:\n      - name: web\n        image: web:v2\n"}`
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "sh.helm.release.v1.web.v2", Namespace: fledgedNameSpace,
+			Labels: map[string]string{"owner": "helm", "name": "web", "status": "deployed", "version": "2"}},
+		Data: map[string][]byte{"release": []byte(base64.StdEncoding.EncodeToString([]byte(release)))},
+	}
+	helmReleaseProvider := kubefledgedv1alpha2.ImageListProviderSpec{
+		HelmRelease: &kubefledgedv1alpha2.HelmReleaseImageList{Name: "web"}, Prune: true,
+	}
+	tests := []struct {
+		name             string
+		cacheSpec        []kubefledgedv1alpha2.CacheSpecImages
+		status           kubefledgedv1alpha2.ImageCacheActionStatus
+		previous         [][]string
+		expectedResolved [][]string
+		expectedEntries  []int
+	}{
+		{
+			name: "#1: Release upgraded",
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"redis:7"}},
+				{Providers: []kubefledgedv1alpha2.ImageListProviderSpec{helmReleaseProvider}},
+			},
+			status:           kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			previous:         [][]string{nil, {"web:v1"}},
+			expectedResolved: [][]string{nil, {"web:v2"}},
+			expectedEntries:  []int{1},
+		},
+		{
+			name: "#2: Release unchanged",
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Providers: []kubefledgedv1alpha2.ImageListProviderSpec{helmReleaseProvider}},
+			},
+			status:           kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			previous:         [][]string{{"web:v2"}},
+			expectedResolved: [][]string{{"web:v2"}},
+		},
+		{
+			name: "#3: Release not installed",
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Providers: []kubefledgedv1alpha2.ImageListProviderSpec{{
+					HelmRelease: &kubefledgedv1alpha2.HelmReleaseImageList{Name: "db"}, Prune: true,
+				}}},
+			},
+			status:           kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			previous:         [][]string{{"db:v1"}},
+			expectedResolved: [][]string{{"db:v1"}},
+		},
+		{
+			name: "#4: Images listed by the image list",
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"web:v2"}, Providers: []kubefledgedv1alpha2.ImageListProviderSpec{helmReleaseProvider}},
+			},
+			status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+		},
+		{
+			name: "#5: Image cache under processing",
+			cacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Providers: []kubefledgedv1alpha2.ImageListProviderSpec{helmReleaseProvider}},
+			},
+			status:           kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+			previous:         [][]string{{"web:v1"}},
+			expectedResolved: [][]string{{"web:v2"}},
+		},
+	}
+	for _, test := range tests {
+		fledgedclientset := kubefledgedclientsetfake.NewSimpleClientset()
+		controller, _, imagecacheInformer := newTestController(fakeclientset.NewSimpleClientset(secret), fledgedclientset)
+		imagecacheInformer.Informer().GetIndexer().Add(&kubefledgedv1alpha2.ImageCache{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+			Spec:       kubefledgedv1alpha2.ImageCacheSpec{CacheSpec: test.cacheSpec},
+			Status: kubefledgedv1alpha2.ImageCacheStatus{Status: test.status, ResolvedImages: test.previous,
+				ProvidedImages: test.previous},
+		})
+		key := fledgedNameSpace + "/foo"
+		controller.resolveImageCache(key)
+		if resolution := controller.resolutions[key]; !reflect.DeepEqual(resolution.resolved, test.expectedResolved) {
+			t.Errorf("Test: %s failed: expected resolved images %v, actual %v", test.name, test.expectedResolved, resolution.resolved)
+		}
+		if len(fledgedclientset.Actions()) != 0 {
+			t.Errorf("Test: %s failed: image cache written: %v", test.name, fledgedclientset.Actions())
+		}
+		if test.expectedEntries == nil {
+			// Refreshes are queued rate limited
+			if err := wait.Poll(10*time.Millisecond, 100*time.Millisecond, func() (bool, error) {
+				return controller.workqueue.Len() != 0, nil
+			}); err == nil {
+				t.Errorf("Test: %s failed: expected no refresh to be queued", test.name)
+			}
+			continue
+		}
+		item, _ := controller.workqueue.Get()
+		wqKey := item.(images.WorkQueueKey)
+		if wqKey.WorkType != images.ImageCacheRefresh || wqKey.ObjKey != key || !wqKey.Background ||
+			wqKey.Entries == nil || !reflect.DeepEqual(*wqKey.Entries, test.expectedEntries) {
+			t.Errorf("Test: %s failed: unexpected work queue key %+v", test.name, wqKey)
+		}
+	}
+}
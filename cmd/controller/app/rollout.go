@@ -0,0 +1,120 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"sort"
+
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+)
+
+// defaultTopologyKey is the node label of the topology domains of a rollout which does
+// not specify a topology key
+const defaultTopologyKey = "topology.kubernetes.io/zone"
+
+// rolloutWaves returns the names of the nodes of each wave of a topology rollout of the
+// image pulls of work items. The first wave is the first node, by name, of every topology
+// domain. The next waves are the remaining nodes of each domain, in the order of the
+// domains. The nodes without the topology label form a domain of their own.
+func rolloutWaves(workItems []imageWorkItem, topologyKey string) [][]string {
+	domains := map[string][]string{}
+	for _, w := range workItems {
+		if w.workType == images.ImageCachePurge {
+			continue
+		}
+		domain := w.node.Labels[topologyKey]
+		if !containsString(domains[domain], w.node.Name) {
+			domains[domain] = append(domains[domain], w.node.Name)
+		}
+	}
+	keys := []string{}
+	for domain := range domains {
+		keys = append(keys, domain)
+	}
+	sort.Strings(keys)
+	waves := [][]string{}
+	seeds := []string{}
+	for _, domain := range keys {
+		sort.Strings(domains[domain])
+		seeds = append(seeds, domains[domain][0])
+	}
+	if len(seeds) > 0 {
+		waves = append(waves, seeds)
+	}
+	for _, domain := range keys {
+		if len(domains[domain]) > 1 {
+			waves = append(waves, domains[domain][1:])
+		}
+	}
+	return waves
+}
+
+// stageRollout returns the work items of a wave, from 1, of the rollout of the image pulls
+// of an image cache action, and the progress of the rollout. Image deletes are not staged.
+// The work items are not staged if they pull no image.
+func stageRollout(workItems []imageWorkItem, rollout *v1alpha2.RolloutStrategy, wave int) ([]imageWorkItem, *v1alpha2.ImageCacheRollout) {
+	topologyKey := rollout.TopologyKey
+	if topologyKey == "" {
+		topologyKey = defaultTopologyKey
+	}
+	waves := rolloutWaves(workItems, topologyKey)
+	if len(waves) == 0 {
+		return workItems, nil
+	}
+	nodes := []string{}
+	if wave <= len(waves) {
+		nodes = waves[wave-1]
+	}
+	staged := []imageWorkItem{}
+	for _, w := range workItems {
+		if w.workType == images.ImageCachePurge || containsString(nodes, w.node.Name) {
+			staged = append(staged, w)
+		}
+	}
+	progress := &v1alpha2.ImageCacheRollout{Wave: wave, Waves: len(waves)}
+	// The waves shrink if nodes were removed since the rollout started
+	if progress.Waves < wave {
+		progress.Waves = wave
+	}
+	return staged, progress
+}
+
+// continueRollout returns the next wave of the rollout of an image cache once an action
+// finished, or 0 if the rollout completed or halted. The rollout halts once an image
+// pull of the wave failed, or the action was cancelled. The outcome of the wave is
+// appended to the status message.
+func continueRollout(status *v1alpha2.ImageCacheStatus) int {
+	rollout := status.Rollout
+	if rollout == nil || rollout.Wave >= rollout.Waves {
+		return 0
+	}
+	if status.Status == v1alpha2.ImageCacheActionStatusSucceeded || status.Status == v1alpha2.ImageCacheActioneNoImagesPulledOrDeleted {
+		status.Message = status.Message + fmt.Sprintf(". Rollout wave %d of %d completed", rollout.Wave, rollout.Waves)
+		return rollout.Wave + 1
+	}
+	status.Message = status.Message + fmt.Sprintf(". Rollout halted after wave %d of %d", rollout.Wave, rollout.Waves)
+	return 0
+}
+
+// rolloutContinues returns true if the rollout of an image cache continues with a wave,
+// i.e. the previous wave is the last action of the image cache
+func rolloutContinues(imageCache *v1alpha2.ImageCache, wave int) bool {
+	rollout := imageCache.Status.Rollout
+	return rollout != nil && rollout.Wave == wave-1 && imageCache.Status.Status != v1alpha2.ImageCacheActionStatusPendingApproval
+}
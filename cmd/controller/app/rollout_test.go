@@ -0,0 +1,214 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func zoneNode(name, zone string) *corev1.Node {
+	labels := map[string]string{"kubernetes.io/hostname": name}
+	if zone != "" {
+		labels[defaultTopologyKey] = zone
+	}
+	return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func TestRolloutWaves(t *testing.T) {
+	a1, a2, a3 := zoneNode("a1", "zone-a"), zoneNode("a2", "zone-a"), zoneNode("a3", "zone-a")
+	b1, b2, c1 := zoneNode("b1", "zone-b"), zoneNode("b2", "zone-b"), zoneNode("c1", "zone-c")
+	unlabeled := zoneNode("x1", "")
+	tests := []struct {
+		name          string
+		workItems     []imageWorkItem
+		expectedWaves [][]string
+	}{
+		{
+			name:          "#1: No work items",
+			workItems:     []imageWorkItem{},
+			expectedWaves: [][]string{},
+		},
+		{
+			name: "#2: One node per zone seeded first, then the remaining nodes zone by zone",
+			workItems: []imageWorkItem{
+				{image: "foo", node: a3}, {image: "foo", node: b2}, {image: "foo", node: a1},
+				{image: "bar", node: a1}, {image: "foo", node: c1}, {image: "foo", node: b1}, {image: "foo", node: a2},
+			},
+			expectedWaves: [][]string{{"a1", "b1", "c1"}, {"a2", "a3"}, {"b2"}},
+		},
+		{
+			name: "#3: Nodes without the topology label form a domain, image deletes not staged",
+			workItems: []imageWorkItem{
+				{image: "foo", node: unlabeled}, {image: "foo", node: b1},
+				{image: "old", node: b2, workType: images.ImageCachePurge},
+			},
+			expectedWaves: [][]string{{"x1", "b1"}},
+		},
+	}
+	for _, test := range tests {
+		waves := rolloutWaves(test.workItems, defaultTopologyKey)
+		if !reflect.DeepEqual(waves, test.expectedWaves) {
+			t.Errorf("Test: %s failed: expectedWaves=%v, actualWaves=%v", test.name, test.expectedWaves, waves)
+		}
+	}
+}
+
+func TestStageRollout(t *testing.T) {
+	a1, a2, b1 := zoneNode("a1", "zone-a"), zoneNode("a2", "zone-a"), zoneNode("b1", "zone-b")
+	workItems := []imageWorkItem{
+		{image: "foo", node: a1}, {image: "foo", node: a2}, {image: "foo", node: b1},
+		{image: "old", node: a2, workType: images.ImageCachePurge},
+	}
+	rollout := &kubefledgedv1alpha2.RolloutStrategy{Type: kubefledgedv1alpha2.RolloutTypeTopology}
+	tests := []struct {
+		name             string
+		workItems        []imageWorkItem
+		wave             int
+		expectedNodes    []string
+		expectedProgress *kubefledgedv1alpha2.ImageCacheRollout
+	}{
+		{
+			name:             "#1: First wave",
+			workItems:        workItems,
+			wave:             1,
+			expectedNodes:    []string{"a1", "b1", "a2"},
+			expectedProgress: &kubefledgedv1alpha2.ImageCacheRollout{Wave: 1, Waves: 2},
+		},
+		{
+			name:             "#2: Last wave",
+			workItems:        workItems,
+			wave:             2,
+			expectedNodes:    []string{"a2", "a2"},
+			expectedProgress: &kubefledgedv1alpha2.ImageCacheRollout{Wave: 2, Waves: 2},
+		},
+		{
+			name:             "#3: Wave beyond the waves of the removed nodes",
+			workItems:        workItems,
+			wave:             3,
+			expectedNodes:    []string{"a2"},
+			expectedProgress: &kubefledgedv1alpha2.ImageCacheRollout{Wave: 3, Waves: 3},
+		},
+		{
+			name:          "#4: No image pulled",
+			workItems:     workItems[3:],
+			wave:          1,
+			expectedNodes: []string{"a2"},
+		},
+	}
+	for _, test := range tests {
+		staged, progress := stageRollout(test.workItems, rollout, test.wave)
+		nodes := []string{}
+		for _, w := range staged {
+			nodes = append(nodes, w.node.Name)
+		}
+		if !reflect.DeepEqual(nodes, test.expectedNodes) {
+			t.Errorf("Test: %s failed: expectedNodes=%v, actualNodes=%v", test.name, test.expectedNodes, nodes)
+		}
+		if !reflect.DeepEqual(progress, test.expectedProgress) {
+			t.Errorf("Test: %s failed: expectedProgress=%+v, actualProgress=%+v", test.name, test.expectedProgress, progress)
+		}
+	}
+}
+
+func TestContinueRollout(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          kubefledgedv1alpha2.ImageCacheStatus
+		expectedWave    int
+		expectedMessage string
+	}{
+		{
+			name:            "#1: No rollout",
+			status:          kubefledgedv1alpha2.ImageCacheStatus{Status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded, Message: "done"},
+			expectedMessage: "done",
+		},
+		{
+			name: "#2: Wave succeeded",
+			status: kubefledgedv1alpha2.ImageCacheStatus{Status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded, Message: "done",
+				Rollout: &kubefledgedv1alpha2.ImageCacheRollout{Wave: 1, Waves: 3}},
+			expectedWave:    2,
+			expectedMessage: "done. Rollout wave 1 of 3 completed",
+		},
+		{
+			name: "#3: Wave failed",
+			status: kubefledgedv1alpha2.ImageCacheStatus{Status: kubefledgedv1alpha2.ImageCacheActionStatusFailed, Message: "failed",
+				Rollout: &kubefledgedv1alpha2.ImageCacheRollout{Wave: 2, Waves: 3}},
+			expectedMessage: "failed. Rollout halted after wave 2 of 3",
+		},
+		{
+			name: "#4: Last wave succeeded",
+			status: kubefledgedv1alpha2.ImageCacheStatus{Status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded, Message: "done",
+				Rollout: &kubefledgedv1alpha2.ImageCacheRollout{Wave: 3, Waves: 3}},
+			expectedMessage: "done",
+		},
+	}
+	for _, test := range tests {
+		wave := continueRollout(&test.status)
+		if wave != test.expectedWave {
+			t.Errorf("Test: %s failed: expectedWave=%d, actualWave=%d", test.name, test.expectedWave, wave)
+		}
+		if test.status.Message != test.expectedMessage {
+			t.Errorf("Test: %s failed: expectedMessage=%q, actualMessage=%q", test.name, test.expectedMessage, test.status.Message)
+		}
+	}
+}
+
+func TestRolloutContinues(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   kubefledgedv1alpha2.ImageCacheStatus
+		wave     int
+		expected bool
+	}{
+		{
+			name:   "#1: No rollout",
+			status: kubefledgedv1alpha2.ImageCacheStatus{Status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded},
+			wave:   2,
+		},
+		{
+			name: "#2: Previous wave is the last action",
+			status: kubefledgedv1alpha2.ImageCacheStatus{Status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+				Rollout: &kubefledgedv1alpha2.ImageCacheRollout{Wave: 1, Waves: 2}},
+			wave:     2,
+			expected: true,
+		},
+		{
+			name: "#3: Rollout restarted",
+			status: kubefledgedv1alpha2.ImageCacheStatus{Status: kubefledgedv1alpha2.ImageCacheActionStatusProcessing,
+				Rollout: &kubefledgedv1alpha2.ImageCacheRollout{Wave: 1, Waves: 3}},
+			wave: 3,
+		},
+		{
+			name: "#4: Another action waiting for approval",
+			status: kubefledgedv1alpha2.ImageCacheStatus{Status: kubefledgedv1alpha2.ImageCacheActionStatusPendingApproval,
+				Rollout: &kubefledgedv1alpha2.ImageCacheRollout{Wave: 1, Waves: 2}},
+			wave: 2,
+		},
+	}
+	for _, test := range tests {
+		imageCache := &kubefledgedv1alpha2.ImageCache{Status: test.status}
+		if actual := rolloutContinues(imageCache, test.wave); actual != test.expected {
+			t.Errorf("Test: %s failed: expected=%t, actual=%t", test.name, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	clientset "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned"
+	fledgedinformers "github.com/senthilrch/kube-fledged/pkg/client/informers/externalversions"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	"github.com/senthilrch/kube-fledged/pkg/version"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Run builds the clients and informers of the controller from its options, and runs
+// it with its dashboard, metrics server and registry webhook until stopCh is closed.
+// The metrics are then pushed to the Pushgateway and written to the metrics file, if
+// any.
+// Operators sharing clients or informer factories with the controller use
+// NewController instead.
+func Run(opts *Options, stopCh <-chan struct{}) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	glog.Infof("Starting kubefledged-controller %s", version.Get())
+	cfg, err := BuildConfig(opts.Kubeconfig, opts.KubeContext, opts.MasterURL)
+	if err != nil {
+		return fmt.Errorf("error building kubeconfig: %v", err)
+	}
+	cfg.QPS = float32(opts.KubeAPIQPS)
+	cfg.Burst = opts.KubeAPIBurst
+
+	// Core API objects support protobuf, which is cheaper to encode and decode than
+	// JSON, notably for node and job watches on big clusters. Custom resources only
+	// support JSON, hence the fledged clientset keeps using it.
+	kubeCfg := rest.CopyConfig(cfg)
+	kubeCfg.AcceptContentTypes = "application/vnd.kubernetes.protobuf,application/json"
+	kubeCfg.ContentType = "application/vnd.kubernetes.protobuf"
+
+	kubeClient, err := kubernetes.NewForConfig(kubeCfg)
+	if err != nil {
+		return fmt.Errorf("error building kubernetes clientset: %v", err)
+	}
+
+	fledgedClient, err := clientset.NewForConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("error building fledged clientset: %v", err)
+	}
+
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, time.Second*30)
+	imageManagerInformerFactory := images.NewInformerFactory(kubeClient)
+	fledgedInformerFactory := fledgedinformers.NewSharedInformerFactory(fledgedClient, time.Second*30)
+
+	auditSink, err := NewAuditSink(opts.AuditLogPath, opts.AuditWebhookURL)
+	if err != nil {
+		return fmt.Errorf("error building audit sink: %v", err)
+	}
+
+	controller := NewController(kubeClient, fledgedClient, kubeInformerFactory, imageManagerInformerFactory, fledgedInformerFactory,
+		opts, nil, auditSink, NewCloudEventSink(opts.CloudEventsSinkURL))
+
+	glog.Info("Starting pre-flight checks")
+	if err = controller.PreFlightChecks(); err != nil {
+		return fmt.Errorf("error running pre-flight checks: %v", err)
+	}
+	glog.Info("Pre-flight checks completed")
+
+	go kubeInformerFactory.Start(stopCh)
+	go imageManagerInformerFactory.Start(stopCh)
+	go fledgedInformerFactory.Start(stopCh)
+
+	if opts.DashboardAddress != "" {
+		go func() {
+			if err := controller.StartDashboard(opts.DashboardAddress, stopCh); err != nil {
+				glog.Errorf("Error running dashboard: %s", err.Error())
+			}
+		}()
+	}
+
+	if opts.MetricsAddress != "" {
+		go func() {
+			if err := StartMetricsServer(opts.MetricsAddress, stopCh); err != nil {
+				glog.Errorf("Error running metrics server: %s", err.Error())
+			}
+		}()
+	}
+
+	if opts.RegistryWebhookAddress != "" {
+		go func() {
+			if err := controller.StartRegistryWebhook(opts.RegistryWebhookAddress, opts.RegistryWebhookToken, opts.RegistryWebhookInsecure, stopCh); err != nil {
+				glog.Errorf("Error running registry webhook: %s", err.Error())
+			}
+		}()
+	}
+
+	if err = controller.Run(1, stopCh); err != nil {
+		return fmt.Errorf("error running controller: %v", err)
+	}
+	if err = ExportMetrics(opts.MetricsPushgatewayURL, opts.MetricsPushgatewayJob, opts.MetricsFile); err != nil {
+		return fmt.Errorf("error exporting metrics: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/images"
+	"github.com/senthilrch/kube-fledged/pkg/policy"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// startupTaintCheckInterval is the interval at which nodes carrying the startup
+	// taint are checked for missing images
+	startupTaintCheckInterval = 30 * time.Second
+	// ReasonNodeWarmed is used as part of the Event 'reason' when the startup taint is
+	// removed from a node holding all the images of the image caches targeting it
+	ReasonNodeWarmed = "NodeWarmed"
+)
+
+// runStartupTaintWorker warms the nodes carrying the startup taint. The image caches
+// whose images are missing on such a node are refreshed. Once the node holds all the
+// images of the image caches targeting it, the startup taint is removed so that pods
+// can be scheduled on it.
+func (c *Controller) runStartupTaintWorker() {
+	nodes, err := c.nodesLister.List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error listing nodes: %v", err)
+		return
+	}
+	imageCaches, err := c.imageCachesLister.ImageCaches("").List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error in listing image caches: %v", err)
+		return
+	}
+	policies := c.listPolicies()
+	refreshed := map[string]bool{}
+	for _, node := range nodes {
+		if !hasTaint(node, c.startupTaintKey) {
+			continue
+		}
+		warm := true
+		for _, imageCache := range imageCaches {
+			if imageCache.Status.Reason == v1alpha2.ImageCacheReasonImageCachePurge {
+				continue
+			}
+			image := c.missingImageOnNode(imageCache, node, policies)
+			if image == "" {
+				continue
+			}
+			warm = false
+			key := imageCache.Namespace + "/" + imageCache.Name
+			if refreshed[key] || !canRefresh(imageCache) || recentlyFailed(imageCache, warmingRetryInterval) {
+				continue
+			}
+			glog.Infof("Node %s under warming misses image %s of image cache %s: refreshing image cache", node.Name, image, key)
+			c.enqueueImageCache(images.ImageCacheRefresh, imageCache, nil)
+			refreshed[key] = true
+		}
+		if !warm {
+			continue
+		}
+		if err := c.removeStartupTaint(node); err != nil {
+			glog.Errorf("Error removing startup taint %s from node %s: %v", c.startupTaintKey, node.Name, err)
+		}
+	}
+}
+
+// missingImageOnNode returns an image of the image cache entries targeting the node
+// that is missing on the node, or "" if the node holds all of them
+func (c *Controller) missingImageOnNode(imageCache *v1alpha2.ImageCache, node *corev1.Node, policies []*v1alpha2.FledgedPolicy) string {
+	if !policy.NodeAllowed(imageCache.Namespace, node, policies) {
+		return ""
+	}
+	standby := false
+	if imageCache.Spec.WarmStandby != nil {
+		nodes, err := c.warmStandbyNodes(imageCache.Spec.WarmStandby)
+		if err == nil {
+			standby = containsNode(nodes, node)
+		}
+	}
+	for _, cacheSpec := range imageListsOf(imageCache) {
+		if !standby && !c.cacheSpecTargetsNode(cacheSpec, node) {
+			continue
+		}
+		if image := missingImageOfCacheSpec(cacheSpec, node); image != "" {
+			return image
+		}
+	}
+	return ""
+}
+
+// cacheSpecTargetsNode returns true if the node is selected by the cache spec entry,
+// the same as nodesForCacheSpec
+func (c *Controller) cacheSpecTargetsNode(cacheSpec v1alpha2.CacheSpecImages, node *corev1.Node) bool {
+	if !c.includeVirtualNodes && isVirtualNode(node) {
+		return false
+	}
+	nodeSelector := cacheSpec.NodeSelector
+	if len(nodeSelector) == 0 && c.defaultNodeOS != "" {
+		nodeSelector = map[string]string{nodeOSLabelKey: c.defaultNodeOS}
+	}
+	return labels.Set(nodeSelector).AsSelector().Matches(labels.Set(node.Labels))
+}
+
+// removeStartupTaint removes the startup taint from the node
+func (c *Controller) removeStartupTaint(node *corev1.Node) error {
+	nodeCopy, err := c.kubeclientset.CoreV1().Nodes().Get(context.TODO(), node.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	taints := []corev1.Taint{}
+	for _, taint := range nodeCopy.Spec.Taints {
+		if taint.Key != c.startupTaintKey {
+			taints = append(taints, taint)
+		}
+	}
+	nodeCopy.Spec.Taints = taints
+	if _, err := c.kubeclientset.CoreV1().Nodes().Update(context.TODO(), nodeCopy, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	glog.Infof("Node %s warmed: startup taint %s removed", node.Name, c.startupTaintKey)
+	c.recorder.Eventf(nodeCopy, corev1.EventTypeNormal, ReasonNodeWarmed,
+		"All images of the image caches targeting the node are pulled: startup taint %s removed", c.startupTaintKey)
+	return nil
+}
+
+// hasTaint returns true if the node carries a taint with the given key
+func hasTaint(node *corev1.Node, key string) bool {
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// recentlyFailed returns true if the last action of the image cache failed less than
+// interval ago
+func recentlyFailed(imageCache *v1alpha2.ImageCache, interval time.Duration) bool {
+	return imageCache.Status.Status == v1alpha2.ImageCacheActionStatusFailed && imageCache.Status.CompletionTime != nil &&
+		time.Since(imageCache.Status.CompletionTime.Time) < interval
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRunStartupTaintWorker(t *testing.T) {
+	imageCache := &kubefledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+		Spec: kubefledgedv1alpha2.ImageCacheSpec{
+			CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx:1.23"}, NodeSelector: map[string]string{"pool": "web"}},
+				{Images: []string{"redis:7"}, NodeSelector: map[string]string{"pool": "db"}},
+			},
+		},
+		Status: kubefledgedv1alpha2.ImageCacheStatus{Status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded},
+	}
+	tests := []struct {
+		name          string
+		taints        []corev1.Taint
+		nodeImages    []string
+		expectedTaint bool
+	}{
+		{
+			name:          "#1: Node under warming missing an image",
+			taints:        []corev1.Taint{{Key: "kubefledged.io/warming", Effect: corev1.TaintEffectNoSchedule}},
+			expectedTaint: true,
+		},
+		{
+			name:          "#2: Node warmed",
+			taints:        []corev1.Taint{{Key: "kubefledged.io/warming", Effect: corev1.TaintEffectNoSchedule}},
+			nodeImages:    []string{"docker.io/library/nginx:1.23"},
+			expectedTaint: false,
+		},
+	}
+	for _, test := range tests {
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"pool": "web"}},
+			Spec: corev1.NodeSpec{
+				Taints: append(test.taints, corev1.Taint{Key: "dedicated", Effect: corev1.TaintEffectNoSchedule}),
+			},
+		}
+		if len(test.nodeImages) > 0 {
+			node.Status.Images = []corev1.ContainerImage{{Names: test.nodeImages}}
+		}
+		kubeclientset := fakeclientset.NewSimpleClientset(node)
+		controller, nodeInformer, imagecacheInformer := newTestController(kubeclientset, kubefledgedclientsetfake.NewSimpleClientset(imageCache))
+		controller.startupTaintKey = "kubefledged.io/warming"
+		nodeInformer.Informer().GetIndexer().Add(node)
+		imagecacheInformer.Informer().GetIndexer().Add(imageCache)
+
+		controller.runStartupTaintWorker()
+
+		updated, err := kubeclientset.CoreV1().Nodes().Get(context.TODO(), "node1", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Test: %s failed: %v", test.name, err)
+		}
+		if actual := hasTaint(updated, "kubefledged.io/warming"); actual != test.expectedTaint {
+			t.Errorf("Test: %s failed: expectedTaint=%t, actualTaint=%t", test.name, test.expectedTaint, actual)
+		}
+		if !hasTaint(updated, "dedicated") {
+			t.Errorf("Test: %s failed: other taints must be kept", test.name)
+		}
+	}
+}
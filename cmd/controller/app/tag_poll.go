@@ -0,0 +1,188 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"reflect"
+
+	"github.com/docker/distribution/reference"
+	"github.com/golang/glog"
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/registry"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// hasTrackedRepositories returns true if an image list of the cache spec has tracked
+// repositories
+func hasTrackedRepositories(cacheSpec []v1alpha2.CacheSpecImages) bool {
+	for _, i := range cacheSpec {
+		if len(i.TrackedRepositories) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// runTagPollWorker polls the tags of the repositories tracked by the image caches, and
+// queues the image caches tracking a repository whose tags changed for the resolve
+// worker. The matching tags are added to the resolved images of the image caches: the
+// cache spec is owned by the user and left as is.
+func (c *Controller) runTagPollWorker() {
+	imageCaches, err := c.imageCachesLister.ImageCaches("").List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error in listing image caches: %v", err)
+		return
+	}
+	c.repositoryTagsLock.Lock()
+	previous := c.repositoryTags
+	c.repositoryTagsLock.Unlock()
+	// Tags of a repository tracked by several image caches with the same keychain are
+	// listed once
+	listed := map[repositoryTagsKey]bool{}
+	polled := map[repositoryTagsKey][]string{}
+	changed := map[repositoryTagsKey]bool{}
+	for _, imageCache := range imageCaches {
+		keychain := c.keychainOf(imageCache)
+		for _, i := range imageCache.Spec.CacheSpec {
+			for _, t := range i.TrackedRepositories {
+				key := repositoryTagsKey{repository: t.Repository, keychain: keychain}
+				if listed[key] {
+					continue
+				}
+				listed[key] = true
+				repoTags, err := c.tagLister.ListTags(t.Repository, keychain)
+				if err != nil {
+					glog.Errorf("Error polling tags of tracked repository %s: %v", t.Repository, err)
+					// The tags last polled are kept
+					if repoTags, ok := previous[key]; ok {
+						polled[key] = repoTags
+					}
+					continue
+				}
+				if !reflect.DeepEqual(repoTags, previous[key]) {
+					changed[key] = true
+				}
+				polled[key] = repoTags
+			}
+		}
+	}
+	c.repositoryTagsLock.Lock()
+	c.repositoryTags = polled
+	c.repositoryTagsLock.Unlock()
+	for _, imageCache := range imageCaches {
+		if tracksAny(imageCache.Spec.CacheSpec, c.keychainOf(imageCache), changed) {
+			c.enqueueResolve(imageCache)
+		}
+	}
+}
+
+// repositoryTagsKey identifies the tags of a tracked repository listed with the keychain
+// of an image cache
+type repositoryTagsKey struct {
+	repository string
+	keychain   authn.Keychain
+}
+
+// tracksAny returns true if an image list of the cache spec tracks one of the repositories
+// with the keychain
+func tracksAny(cacheSpec []v1alpha2.CacheSpecImages, keychain authn.Keychain, repositories map[repositoryTagsKey]bool) bool {
+	for _, i := range cacheSpec {
+		for _, t := range i.TrackedRepositories {
+			if repositories[repositoryTagsKey{repository: t.Repository, keychain: keychain}] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// repositoryTagsOf returns the tags last polled for a tracked repository with the
+// keychain. The tags of a repository not yet polled, e.g. one of a new image cache, are
+// listed.
+func (c *Controller) repositoryTagsOf(repository string, keychain authn.Keychain) ([]string, error) {
+	key := repositoryTagsKey{repository: repository, keychain: keychain}
+	c.repositoryTagsLock.Lock()
+	repoTags, ok := c.repositoryTags[key]
+	c.repositoryTagsLock.Unlock()
+	if ok {
+		return repoTags, nil
+	}
+	repoTags, err := c.tagLister.ListTags(repository, keychain)
+	if err != nil {
+		return nil, err
+	}
+	c.repositoryTagsLock.Lock()
+	c.repositoryTags[key] = repoTags
+	c.repositoryTagsLock.Unlock()
+	return repoTags, nil
+}
+
+// syncTrackedRepositories returns the cache spec with the images of each image list
+// updated with the matching tags of its tracked repositories. A repository whose tags
+// cannot be listed leaves the images of the image list as is.
+func (c *Controller) syncTrackedRepositories(imageCache *v1alpha2.ImageCache, cacheSpec []v1alpha2.CacheSpecImages) []v1alpha2.CacheSpecImages {
+	keychain := c.keychainOf(imageCache)
+	result := []v1alpha2.CacheSpecImages{}
+	for _, i := range cacheSpec {
+		i = *i.DeepCopy()
+		for _, t := range i.TrackedRepositories {
+			repoTags, err := c.repositoryTagsOf(t.Repository, keychain)
+			if err != nil {
+				glog.Errorf("Error polling tags of tracked repository %s of image cache %s/%s: %v", t.Repository, imageCache.Namespace, imageCache.Name, err)
+				continue
+			}
+			matched, err := registry.MatchTags(repoTags, t.TagConstraint, t.MaxTags)
+			if err != nil {
+				glog.Errorf("Error matching tags of tracked repository %s of image cache %s/%s: %v", t.Repository, imageCache.Namespace, imageCache.Name, err)
+				continue
+			}
+			i.Images = trackedImages(i.Images, t, matched)
+		}
+		result = append(result, i)
+	}
+	return result
+}
+
+// trackedImages adds an image of the tracked repository to the images for each matched
+// tag not yet listed. If the tracked repository is pruned, tagged images of the
+// repository whose tags were not matched are removed.
+func trackedImages(images []string, t v1alpha2.TrackedRepository, matched []string) []string {
+	repository, err := reference.ParseNormalizedNamed(t.Repository)
+	if err != nil {
+		return images
+	}
+	listed := map[string]bool{}
+	result := []string{}
+	for _, image := range images {
+		if named, err := reference.ParseNormalizedNamed(image); err == nil && named.Name() == repository.Name() {
+			if tagged, ok := named.(reference.Tagged); ok {
+				if t.Prune && !containsString(matched, tagged.Tag()) {
+					continue
+				}
+				listed[tagged.Tag()] = true
+			}
+		}
+		result = append(result, image)
+	}
+	for _, tag := range matched {
+		if !listed[tag] {
+			result = append(result, t.Repository+":"+tag)
+		}
+	}
+	return result
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+type fakeTagLister map[string][]string
+
+func (f fakeTagLister) ListTags(repository string, keychain authn.Keychain) ([]string, error) {
+	tags, ok := f[repository]
+	if !ok {
+		return nil, fmt.Errorf("repository %s not found", repository)
+	}
+	return tags, nil
+}
+
+func TestResolveTrackedRepositories(t *testing.T) {
+	tests := []struct {
+		name           string
+		cacheSpec      kubefledgedv1alpha2.CacheSpecImages
+		resolved       [][]string
+		expectedImages []string
+	}{
+		{
+			name: "#1: Matching tags added",
+			cacheSpec: kubefledgedv1alpha2.CacheSpecImages{
+				Images: []string{"busybox:1.35", "nginx:1.25.0"},
+				TrackedRepositories: []kubefledgedv1alpha2.TrackedRepository{
+					{Repository: "nginx", TagConstraint: "1.25.*"},
+				},
+			},
+			expectedImages: []string{"busybox:1.35", "nginx:1.25.0", "nginx:1.25.2", "nginx:1.25.1"},
+		},
+		{
+			name: "#2: Tags resolved no longer matching pruned, tags of the cache spec kept",
+			cacheSpec: kubefledgedv1alpha2.CacheSpecImages{
+				Images: []string{"docker.io/library/nginx:1.24.0"},
+				TrackedRepositories: []kubefledgedv1alpha2.TrackedRepository{
+					{Repository: "nginx", TagConstraint: "1.25.*", MaxTags: 1, Prune: true},
+				},
+			},
+			resolved:       [][]string{{"nginx:1.25.1"}},
+			expectedImages: []string{"docker.io/library/nginx:1.24.0", "nginx:1.25.2"},
+		},
+		{
+			name: "#3: Tags resolved no longer matching kept without prune",
+			cacheSpec: kubefledgedv1alpha2.CacheSpecImages{
+				TrackedRepositories: []kubefledgedv1alpha2.TrackedRepository{
+					{Repository: "nginx", TagConstraint: "1.25.*", MaxTags: 1},
+				},
+			},
+			resolved:       [][]string{{"nginx:1.25.1"}},
+			expectedImages: []string{"nginx:1.25.1", "nginx:1.25.2"},
+		},
+		{
+			name: "#4: Error listing tags",
+			cacheSpec: kubefledgedv1alpha2.CacheSpecImages{
+				Images: []string{"busybox:1.35"},
+				TrackedRepositories: []kubefledgedv1alpha2.TrackedRepository{
+					{Repository: "ghcr.io/foo/missing", TagConstraint: "1.x", Prune: true},
+				},
+			},
+			resolved:       [][]string{{"ghcr.io/foo/missing:1.0.0"}},
+			expectedImages: []string{"busybox:1.35", "ghcr.io/foo/missing:1.0.0"},
+		},
+	}
+	for _, test := range tests {
+		imageCache := kubefledgedv1alpha2.ImageCache{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+			Spec: kubefledgedv1alpha2.ImageCacheSpec{
+				CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{test.cacheSpec},
+			},
+		}
+		controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset(&imageCache))
+		controller.tagLister = fakeTagLister{"nginx": {"1.24.0", "1.25.0", "1.25.1", "1.25.2", "latest"}}
+
+		resolution := controller.resolveImageLists(&imageCache, imageListResolution{resolved: test.resolved})
+		cacheSpec := resolvedCacheSpec(imageCache.Spec.CacheSpec, resolution.resolved)
+		if actual := cacheSpec[0].Images; !reflect.DeepEqual(actual, test.expectedImages) {
+			t.Errorf("Test: %s failed: expectedImages=%v, actualImages=%v", test.name, test.expectedImages, actual)
+		}
+		if !reflect.DeepEqual(imageCache.Spec.CacheSpec[0], test.cacheSpec) {
+			t.Errorf("Test: %s failed: cache spec modified: %+v", test.name, imageCache.Spec.CacheSpec[0])
+		}
+	}
+}
+
+// polledTags returns the tags polled per repository with the keychain
+func polledTags(keychain authn.Keychain, tags map[string][]string) map[repositoryTagsKey][]string {
+	polled := map[repositoryTagsKey][]string{}
+	for repository, repoTags := range tags {
+		polled[repositoryTagsKey{repository: repository, keychain: keychain}] = repoTags
+	}
+	return polled
+}
+
+func TestRunTagPollWorker(t *testing.T) {
+	tracking := func(name, repository string) *kubefledgedv1alpha2.ImageCache {
+		return &kubefledgedv1alpha2.ImageCache{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: fledgedNameSpace},
+			Spec: kubefledgedv1alpha2.ImageCacheSpec{
+				CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{{
+					TrackedRepositories: []kubefledgedv1alpha2.TrackedRepository{
+						{Repository: repository, TagConstraint: "1.*"},
+					},
+				}},
+			},
+		}
+	}
+	tests := []struct {
+		name           string
+		polled         map[string][]string
+		tags           fakeTagLister
+		expectedTags   map[string][]string
+		expectedQueued int
+	}{
+		{
+			name:           "#1: Image caches tracking repositories polled first queued",
+			polled:         map[string][]string{},
+			tags:           fakeTagLister{"nginx": {"1.0.0"}, "redis": {"1.0.0"}},
+			expectedTags:   map[string][]string{"nginx": {"1.0.0"}, "redis": {"1.0.0"}},
+			expectedQueued: 2,
+		},
+		{
+			name:           "#2: Image caches tracking repositories whose tags changed queued",
+			polled:         map[string][]string{"nginx": {"1.0.0"}, "redis": {"1.0.0"}},
+			tags:           fakeTagLister{"nginx": {"1.0.0", "1.1.0"}, "redis": {"1.0.0"}},
+			expectedTags:   map[string][]string{"nginx": {"1.0.0", "1.1.0"}, "redis": {"1.0.0"}},
+			expectedQueued: 1,
+		},
+		{
+			name:           "#3: Tags last polled kept on error",
+			polled:         map[string][]string{"nginx": {"1.0.0"}, "redis": {"1.0.0"}, "busybox": {"1.0.0"}},
+			tags:           fakeTagLister{"redis": {"1.0.0"}},
+			expectedTags:   map[string][]string{"nginx": {"1.0.0"}, "redis": {"1.0.0"}},
+			expectedQueued: 0,
+		},
+	}
+	for _, test := range tests {
+		controller, _, imagecacheInformer := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+		imagecacheInformer.Informer().GetIndexer().Add(tracking("foo", "nginx"))
+		imagecacheInformer.Informer().GetIndexer().Add(tracking("bar", "redis"))
+		keychain := controller.keychainOf(&kubefledgedv1alpha2.ImageCache{})
+		controller.repositoryTags = polledTags(keychain, test.polled)
+		controller.tagLister = test.tags
+
+		controller.runTagPollWorker()
+
+		if !reflect.DeepEqual(controller.repositoryTags, polledTags(keychain, test.expectedTags)) {
+			t.Errorf("Test: %s failed: expectedTags=%v, actualTags=%v", test.name, test.expectedTags, controller.repositoryTags)
+		}
+		if actual := controller.resolveQueue.Len(); actual != test.expectedQueued {
+			t.Errorf("Test: %s failed: expectedQueued=%d, actualQueued=%d", test.name, test.expectedQueued, actual)
+		}
+	}
+}
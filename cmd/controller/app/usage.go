@@ -0,0 +1,232 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// ReasonUnusedImagesPurged is used as part of the Event 'reason' when unused images
+	// are removed from an ImageCache
+	ReasonUnusedImagesPurged = "UnusedImagesPurged"
+)
+
+// runUsageWorker cross-references the images of the image caches with the images used
+// by the pods of the cluster. The usage of each image is reported in the status of the
+// image caches and as metrics.
+func (c *Controller) runUsageWorker() {
+	pods, err := c.kubeclientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{ResourceVersion: "0"})
+	if err != nil {
+		glog.Errorf("Error listing pods: %v", err)
+		return
+	}
+	imageCaches, err := c.imageCachesLister.ImageCaches("").List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error in listing image caches: %v", err)
+		return
+	}
+	podsUsingImage := countPodsUsingImages(pods.Items)
+	now := metav1.Now()
+	cachedImagePods.Reset()
+	cachedImageLastUsed.Reset()
+	for _, imageCache := range imageCaches {
+		usage := imageUsage(imageCache, podsUsingImage, now)
+		for _, u := range usage {
+			cachedImagePods.WithLabelValues(imageCache.Namespace, imageCache.Name, u.Image, u.DisplayTag).Set(float64(u.Pods))
+			if u.LastUsed != nil {
+				cachedImageLastUsed.WithLabelValues(imageCache.Namespace, imageCache.Name, u.Image, u.DisplayTag).Set(float64(u.LastUsed.Unix()))
+			}
+		}
+		if !reflect.DeepEqual(usage, imageCache.Status.Usage) {
+			if err := c.updateImageCacheUsage(imageCache, usage); err != nil {
+				glog.Errorf("Error updating usage of image cache %s/%s: %v", imageCache.Namespace, imageCache.Name, err)
+				continue
+			}
+		}
+		if imageCache.Spec.UnusedImagePurge != nil && canRefresh(imageCache) {
+			if err := c.purgeUnusedImages(imageCache, usage, now.Time); err != nil {
+				glog.Errorf("Error purging unused images of image cache %s/%s: %v", imageCache.Namespace, imageCache.Name, err)
+			}
+		}
+	}
+}
+
+// purgeUnusedImages removes the images of an image cache that have not been used for
+// longer than allowed by its unused image purge policy from its image lists. The update
+// of the image cache purges the removed images from the nodes. An image list keeps at
+// least one image, OCI artifact or tracked repository.
+func (c *Controller) purgeUnusedImages(imageCache *v1alpha2.ImageCache, usage []v1alpha2.ImageUsage, now time.Time) error {
+	purge := imageCache.Spec.UnusedImagePurge
+	unused := map[string]bool{}
+	for _, u := range usage {
+		lastUsed := imageCache.CreationTimestamp.Time
+		if u.LastUsed != nil {
+			lastUsed = u.LastUsed.Time
+		}
+		if u.Pods == 0 && now.Sub(lastUsed) > purge.After.Duration && !containsString(purge.Pinned, u.Image) {
+			unused[u.Image] = true
+		}
+	}
+	if len(unused) == 0 {
+		return nil
+	}
+	imageCacheCopy, err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(imageCache.Namespace).Get(context.TODO(), imageCache.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	purged := []string{}
+	for i := range imageCacheCopy.Spec.CacheSpec {
+		cacheSpec := &imageCacheCopy.Spec.CacheSpec[i]
+		kept := []string{}
+		for _, image := range cacheSpec.Images {
+			if unused[image] {
+				continue
+			}
+			kept = append(kept, image)
+		}
+		if len(kept) == 0 && len(cacheSpec.Artifacts) == 0 && len(cacheSpec.TrackedRepositories) == 0 && len(cacheSpec.Images) > 0 {
+			// Keep the last image of the image list, which may not be empty
+			kept = cacheSpec.Images[len(cacheSpec.Images)-1:]
+		}
+		for _, image := range cacheSpec.Images {
+			if !containsString(kept, image) && !containsString(purged, image) {
+				purged = append(purged, image)
+			}
+		}
+		cacheSpec.Images = kept
+		for image := range cacheSpec.Platforms {
+			if !containsString(kept, image) {
+				delete(cacheSpec.Platforms, image)
+			}
+		}
+		for image := range cacheSpec.ImagePullPolicies {
+			if !containsString(kept, image) {
+				delete(cacheSpec.ImagePullPolicies, image)
+			}
+		}
+		for image := range cacheSpec.Fallbacks {
+			if !containsString(kept, image) {
+				delete(cacheSpec.Fallbacks, image)
+			}
+		}
+		for image := range cacheSpec.TargetRefs {
+			if !containsString(kept, image) {
+				delete(cacheSpec.TargetRefs, image)
+			}
+		}
+		for image := range cacheSpec.DisplayTags {
+			if !containsString(kept, image) {
+				delete(cacheSpec.DisplayTags, image)
+			}
+		}
+	}
+	if len(purged) == 0 {
+		return nil
+	}
+	if _, err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(imageCache.Namespace).Update(context.TODO(), imageCacheCopy, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	message := fmt.Sprintf("Purging images unused for more than %s: %s", purge.After.Duration, strings.Join(purged, ", "))
+	glog.Infof("Image cache %s/%s: %s", imageCache.Namespace, imageCache.Name, message)
+	c.recorder.Event(imageCache, corev1.EventTypeNormal, ReasonUnusedImagesPurged, message)
+	return nil
+}
+
+// imageUsage returns the usage of the images of an image cache, sorted by image. The
+// last use of images not used by any pod is taken from the status of the image cache.
+func imageUsage(imageCache *v1alpha2.ImageCache, podsUsingImage map[string]int, now metav1.Time) []v1alpha2.ImageUsage {
+	lastUsed := map[string]*metav1.Time{}
+	for _, u := range imageCache.Status.Usage {
+		lastUsed[u.Image] = u.LastUsed
+	}
+	usage := []v1alpha2.ImageUsage{}
+	seen := map[string]bool{}
+	for _, cacheSpec := range imageListsOf(imageCache) {
+		for _, image := range cacheSpec.Images {
+			if seen[image] {
+				continue
+			}
+			seen[image] = true
+			u := v1alpha2.ImageUsage{Image: image, DisplayTag: cacheSpec.DisplayTags[image], Pods: podsUsingImage[normalizeImage(image)], LastUsed: lastUsed[image]}
+			if u.Pods > 0 {
+				u.LastUsed = now.DeepCopy()
+			}
+			usage = append(usage, u)
+		}
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Image < usage[j].Image })
+	return usage
+}
+
+// countPodsUsingImages returns the number of running or pending pods using each image,
+// by normalized image reference
+func countPodsUsingImages(pods []corev1.Pod) map[string]int {
+	counts := map[string]int{}
+	for i := range pods {
+		if pods[i].Status.Phase == corev1.PodSucceeded || pods[i].Status.Phase == corev1.PodFailed {
+			continue
+		}
+		podImages := map[string]bool{}
+		for _, container := range pods[i].Spec.InitContainers {
+			podImages[normalizeImage(container.Image)] = true
+		}
+		for _, container := range pods[i].Spec.Containers {
+			podImages[normalizeImage(container.Image)] = true
+		}
+		for _, container := range pods[i].Spec.EphemeralContainers {
+			podImages[normalizeImage(container.Image)] = true
+		}
+		for image := range podImages {
+			counts[image]++
+		}
+	}
+	return counts
+}
+
+// normalizeImage returns the fully qualified reference of an image, with the latest tag
+// if the image has no tag or digest e.g. docker.io/library/nginx:latest for nginx
+func normalizeImage(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+	return reference.TagNameOnly(named).String()
+}
+
+// updateImageCacheUsage writes the usage of the images of an image cache to its status
+func (c *Controller) updateImageCacheUsage(imageCache *v1alpha2.ImageCache, usage []v1alpha2.ImageUsage) error {
+	imageCacheCopy, err := c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(imageCache.Namespace).Get(context.TODO(), imageCache.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	imageCacheCopy.Status.Usage = usage
+	_, err = c.kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(imageCache.Namespace).Update(context.TODO(), imageCacheCopy, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func newUsageTestPod(name string, phase corev1.PodPhase, images ...string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+	for _, image := range images {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: name, Image: image})
+	}
+	return pod
+}
+
+func TestRunUsageWorker(t *testing.T) {
+	lastUsed := metav1.NewTime(time.Now().Add(-48 * time.Hour).Truncate(time.Second))
+	imageCache := kubefledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+		Spec: kubefledgedv1alpha2.ImageCacheSpec{
+			CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx:1.23", "redis"}},
+				{Images: []string{"busybox:1.35", "nginx:1.23"}},
+			},
+		},
+		Status: kubefledgedv1alpha2.ImageCacheStatus{
+			Status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			Usage:  []kubefledgedv1alpha2.ImageUsage{{Image: "busybox:1.35", LastUsed: &lastUsed}},
+		},
+	}
+	kubeclientset := fakeclientset.NewSimpleClientset(
+		newUsageTestPod("pod1", corev1.PodRunning, "docker.io/library/nginx:1.23", "nginx:1.23"),
+		newUsageTestPod("pod2", corev1.PodPending, "redis:latest"),
+		newUsageTestPod("pod3", corev1.PodSucceeded, "busybox:1.35"),
+		newUsageTestPod("pod4", corev1.PodRunning, "nginx:1.23"),
+	)
+	fledgedclientset := kubefledgedclientsetfake.NewSimpleClientset(&imageCache)
+	controller, _, imagecacheInformer := newTestController(kubeclientset, fledgedclientset)
+	imagecacheInformer.Informer().GetIndexer().Add(&imageCache)
+
+	controller.runUsageWorker()
+
+	updated, err := fledgedclientset.KubefledgedV1alpha2().ImageCaches(fledgedNameSpace).Get(context.TODO(), "foo", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting image cache: %v", err)
+	}
+	expectedPods := map[string]int{"busybox:1.35": 0, "nginx:1.23": 2, "redis": 1}
+	if len(updated.Status.Usage) != len(expectedPods) {
+		t.Fatalf("expected usage of %d images, got %+v", len(expectedPods), updated.Status.Usage)
+	}
+	for _, u := range updated.Status.Usage {
+		if u.Pods != expectedPods[u.Image] {
+			t.Errorf("expected %d pods using %s, got %d", expectedPods[u.Image], u.Image, u.Pods)
+		}
+		if u.Pods > 0 && (u.LastUsed == nil || time.Since(u.LastUsed.Time) > time.Minute) {
+			t.Errorf("expected %s to be last used now, got %v", u.Image, u.LastUsed)
+		}
+		if u.Image == "busybox:1.35" && (u.LastUsed == nil || !u.LastUsed.Equal(&lastUsed)) {
+			t.Errorf("expected last use of unused image to be kept, got %v", u.LastUsed)
+		}
+	}
+	if pods := testutil.ToFloat64(cachedImagePods.WithLabelValues(fledgedNameSpace, "foo", "nginx:1.23", "")); pods != 2 {
+		t.Errorf("expected metric of 2 pods using nginx:1.23, got %v", pods)
+	}
+}
+
+func TestPurgeUnusedImages(t *testing.T) {
+	now := time.Now()
+	lastUsed := func(d time.Duration) *metav1.Time {
+		t := metav1.NewTime(now.Add(-d))
+		return &t
+	}
+	imageCache := kubefledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "foo",
+			Namespace:         fledgedNameSpace,
+			CreationTimestamp: metav1.NewTime(now.Add(-100 * time.Hour)),
+		},
+		Spec: kubefledgedv1alpha2.ImageCacheSpec{
+			CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"used", "recent", "stale", "pinned", "never"}},
+				{Images: []string{"stale2"}},
+			},
+			UnusedImagePurge: &kubefledgedv1alpha2.UnusedImagePurge{
+				After:  metav1.Duration{Duration: 24 * time.Hour},
+				Pinned: []string{"pinned"},
+			},
+		},
+	}
+	usage := []kubefledgedv1alpha2.ImageUsage{
+		{Image: "never"},
+		{Image: "pinned", LastUsed: lastUsed(48 * time.Hour)},
+		{Image: "recent", LastUsed: lastUsed(time.Hour)},
+		{Image: "stale", LastUsed: lastUsed(48 * time.Hour)},
+		{Image: "stale2", LastUsed: lastUsed(48 * time.Hour)},
+		{Image: "used", Pods: 1, LastUsed: lastUsed(0)},
+	}
+	fledgedclientset := kubefledgedclientsetfake.NewSimpleClientset(&imageCache)
+	controller, _, _ := newTestController(fakeclientset.NewSimpleClientset(), fledgedclientset)
+
+	if err := controller.purgeUnusedImages(&imageCache, usage, now); err != nil {
+		t.Fatalf("purgeUnusedImages() failed: %v", err)
+	}
+
+	updated, err := fledgedclientset.KubefledgedV1alpha2().ImageCaches(fledgedNameSpace).Get(context.TODO(), "foo", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting image cache: %v", err)
+	}
+	if expected := []string{"used", "recent", "pinned"}; !reflect.DeepEqual(updated.Spec.CacheSpec[0].Images, expected) {
+		t.Errorf("expected images %v, got %v", expected, updated.Spec.CacheSpec[0].Images)
+	}
+	// The last image of an image list is kept
+	if expected := []string{"stale2"}; !reflect.DeepEqual(updated.Spec.CacheSpec[1].Images, expected) {
+		t.Errorf("expected images %v, got %v", expected, updated.Spec.CacheSpec[1].Images)
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// ReasonImagesEvicted is used as part of the Event 'reason' when cached images are
+	// found missing on nodes, e.g. removed by the image garbage collection of kubelet
+	ReasonImagesEvicted = "ImagesEvicted"
+	// kubeletMaxReportedImages is the default maximum number of images reported by
+	// kubelet in the node status (--node-status-max-images). A node reporting as many
+	// images may hold images it does not report.
+	kubeletMaxReportedImages = 50
+)
+
+// runVerifyWorker verifies that the images of the image caches that were successfully
+// pulled are still present on the nodes. The image caches whose images were evicted
+// are refreshed, so that the images are pulled again.
+func (c *Controller) runVerifyWorker() {
+	nodes, err := c.nodesLister.List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error listing nodes: %v", err)
+		return
+	}
+	imageCaches, err := c.imageCachesLister.ImageCaches("").List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error in listing image caches: %v", err)
+		return
+	}
+	nodes = verifiableNodes(c.excludeVirtualNodes(nodes))
+	policies := c.listPolicies()
+	for _, imageCache := range imageCaches {
+		if imageCache.Status.Status != v1alpha2.ImageCacheActionStatusSucceeded || !canRefresh(imageCache) {
+			continue
+		}
+		evicted := 0
+		for _, node := range nodes {
+			if image := c.missingImageOnNode(imageCache, node, policies); image != "" {
+				glog.Infof("Image %s of image cache %s/%s is missing on node %s", image, imageCache.Namespace, imageCache.Name, node.Name)
+				evicted++
+			}
+		}
+		if evicted == 0 {
+			continue
+		}
+		evictedImages.WithLabelValues(imageCache.Namespace, imageCache.Name).Add(float64(evicted))
+		c.recorder.Eventf(imageCache, corev1.EventTypeWarning, ReasonImagesEvicted,
+			"Images of the image cache are missing on %d nodes: refreshing image cache", evicted)
+		c.enqueueBackgroundRefresh(imageCache)
+	}
+}
+
+// verifiableNodes returns the nodes whose status reliably reports the images they
+// hold: online nodes reporting fewer images than kubelet reports at most
+func verifiableNodes(nodes []*corev1.Node) []*corev1.Node {
+	verifiable := []*corev1.Node{}
+	for _, n := range nodes {
+		if !nodeOnline(n) || len(n.Status.Images) >= kubeletMaxReportedImages {
+			continue
+		}
+		verifiable = append(verifiable, n)
+	}
+	return verifiable
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func newVerifyTestNode(name string, ready bool, images ...string) *corev1.Node {
+	node := newWarmStandbyTestNode(name, ready, images...)
+	node.Labels = map[string]string{"kubernetes.io/os": "linux"}
+	return node
+}
+
+func TestRunVerifyWorker(t *testing.T) {
+	truncated := newVerifyTestNode("node4", true)
+	for i := 0; i < kubeletMaxReportedImages; i++ {
+		truncated.Status.Images = append(truncated.Status.Images, corev1.ContainerImage{Names: []string{fmt.Sprintf("docker.io/library/app%d:1", i)}})
+	}
+	tests := []struct {
+		name            string
+		status          kubefledgedv1alpha2.ImageCacheActionStatus
+		nodes           []*corev1.Node
+		expectedEvicted float64
+	}{
+		{
+			name:   "#1: Images present on all nodes",
+			status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			nodes:  []*corev1.Node{newVerifyTestNode("node1", true, "docker.io/library/nginx:1.23")},
+		},
+		{
+			name:   "#2: Image evicted from a node",
+			status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			nodes: []*corev1.Node{
+				newVerifyTestNode("node1", true, "docker.io/library/nginx:1.23"),
+				newVerifyTestNode("node2", true, "docker.io/library/redis:7"),
+			},
+			expectedEvicted: 1,
+		},
+		{
+			name:   "#3: Offline and truncated nodes not verified",
+			status: kubefledgedv1alpha2.ImageCacheActionStatusSucceeded,
+			nodes:  []*corev1.Node{newVerifyTestNode("node3", false), truncated},
+		},
+		{
+			name:   "#4: Failed image cache not verified",
+			status: kubefledgedv1alpha2.ImageCacheActionStatusFailed,
+			nodes:  []*corev1.Node{newVerifyTestNode("node2", true, "docker.io/library/redis:7")},
+		},
+	}
+	for i, test := range tests {
+		imageCache := &kubefledgedv1alpha2.ImageCache{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("verify%d", i), Namespace: fledgedNameSpace},
+			Spec: kubefledgedv1alpha2.ImageCacheSpec{
+				CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{{Images: []string{"nginx:1.23"}}},
+			},
+			Status: kubefledgedv1alpha2.ImageCacheStatus{Status: test.status},
+		}
+		controller, nodeInformer, imagecacheInformer := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset(imageCache))
+		for _, n := range test.nodes {
+			nodeInformer.Informer().GetIndexer().Add(n)
+		}
+		imagecacheInformer.Informer().GetIndexer().Add(imageCache)
+
+		controller.runVerifyWorker()
+
+		if actual := testutil.ToFloat64(evictedImages.WithLabelValues(fledgedNameSpace, imageCache.Name)); actual != test.expectedEvicted {
+			t.Errorf("Test: %s failed: expectedEvicted=%v, actualEvicted=%v", test.name, test.expectedEvicted, actual)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// virtualKubeletTypeLabelKey and virtualKubeletTypeLabelValue are the label set on
+	// the nodes registered by virtual-kubelet providers (e.g. Azure ACI virtual nodes)
+	virtualKubeletTypeLabelKey   = "type"
+	virtualKubeletTypeLabelValue = "virtual-kubelet"
+	// virtualKubeletTaintPrefix is the prefix of the taint set on virtual-kubelet nodes
+	virtualKubeletTaintPrefix = "virtual-kubelet.io/"
+	// eksComputeTypeLabelKey is set to "fargate" on the nodes backing EKS Fargate pods
+	eksComputeTypeLabelKey = "eks.amazonaws.com/compute-type"
+)
+
+// isVirtualNode returns true if the node is backed by a serverless runtime
+// (virtual-kubelet, EKS Fargate) rather than a machine with an image store. Image
+// pull jobs cannot cache anything on such nodes.
+func isVirtualNode(node *corev1.Node) bool {
+	if node.Labels[virtualKubeletTypeLabelKey] == virtualKubeletTypeLabelValue ||
+		node.Labels[eksComputeTypeLabelKey] == "fargate" {
+		return true
+	}
+	for _, taint := range node.Spec.Taints {
+		if strings.HasPrefix(taint.Key, virtualKubeletTaintPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// excludeVirtualNodes removes the virtual nodes from the list, unless the controller
+// was asked to include them
+func (c *Controller) excludeVirtualNodes(nodes []*corev1.Node) []*corev1.Node {
+	if c.includeVirtualNodes {
+		return nodes
+	}
+	filtered := []*corev1.Node{}
+	for _, n := range nodes {
+		if isVirtualNode(n) {
+			continue
+		}
+		filtered = append(filtered, n)
+	}
+	return filtered
+}
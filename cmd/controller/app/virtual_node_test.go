@@ -0,0 +1,76 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNodesForCacheSpecVirtualNodes(t *testing.T) {
+	nodes := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"kubernetes.io/os": "linux"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "aci", Labels: map[string]string{"kubernetes.io/os": "linux", "type": "virtual-kubelet"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "fargate", Labels: map[string]string{"kubernetes.io/os": "linux", "eks.amazonaws.com/compute-type": "fargate"}}},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "vk", Labels: map[string]string{"kubernetes.io/os": "linux"}},
+			Spec: corev1.NodeSpec{
+				Taints: []corev1.Taint{{Key: "virtual-kubelet.io/provider", Value: "mock", Effect: corev1.TaintEffectNoSchedule}},
+			},
+		},
+	}
+	tests := []struct {
+		name                string
+		includeVirtualNodes bool
+		expectedNodes       int
+	}{
+		{
+			name:          "#1: Virtual nodes excluded",
+			expectedNodes: 1,
+		},
+		{
+			name:                "#2: Virtual nodes included",
+			includeVirtualNodes: true,
+			expectedNodes:       4,
+		},
+	}
+	for _, test := range tests {
+		controller, nodeInformer, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+		controller.includeVirtualNodes = test.includeVirtualNodes
+		for _, n := range nodes {
+			nodeInformer.Informer().GetIndexer().Add(n)
+		}
+		actual, err := controller.nodesForCacheSpec(kubefledgedv1alpha2.CacheSpecImages{Images: []string{"nginx:1.23"}})
+		if err != nil {
+			t.Errorf("Test: %s failed: %v", test.name, err)
+			continue
+		}
+		if len(actual) != test.expectedNodes {
+			t.Errorf("Test: %s failed: expectedNodes=%d, actualNodes=%d", test.name, test.expectedNodes, len(actual))
+		}
+		for _, n := range nodes {
+			if controller.cacheSpecTargetsNode(kubefledgedv1alpha2.CacheSpecImages{}, n) != (test.includeVirtualNodes || !isVirtualNode(n)) {
+				t.Errorf("Test: %s failed: unexpected targeting of node %s", test.name, n.Name)
+			}
+		}
+	}
+}
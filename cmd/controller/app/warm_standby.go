@@ -0,0 +1,140 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"sort"
+	"time"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// warmStandbyCheckInterval is the interval at which warm standby nodes are checked
+	// for missing images
+	warmStandbyCheckInterval = time.Minute
+	// warmingRetryInterval is the minimum interval between the refreshes of an image
+	// cache whose last action failed, when nodes being warmed miss its images
+	warmingRetryInterval = 5 * time.Minute
+)
+
+// runWarmStandbyWorker refreshes the image caches whose warm standby nodes miss images,
+// e.g. because they were added to the warm standby pool
+func (c *Controller) runWarmStandbyWorker() {
+	imageCaches, err := c.imageCachesLister.ImageCaches("").List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error in listing image caches: %v", err)
+		return
+	}
+	for _, imageCache := range imageCaches {
+		if imageCache.Spec.WarmStandby == nil || !canRefresh(imageCache) || recentlyFailed(imageCache, warmingRetryInterval) {
+			continue
+		}
+		nodes, err := c.warmStandbyNodes(imageCache.Spec.WarmStandby)
+		if err != nil {
+			continue
+		}
+		if node, image := missingImage(imageCache, nodes); node != "" {
+			glog.Infof("Warm standby node %s misses image %s of image cache %s/%s: refreshing image cache",
+				node, image, imageCache.Namespace, imageCache.Name)
+			c.enqueueBackgroundRefresh(imageCache)
+		}
+	}
+}
+
+// warmStandbyNodes returns the warm standby nodes of an image cache: the first ready
+// nodes by name among those selected
+func (c *Controller) warmStandbyNodes(warmStandby *v1alpha2.WarmStandby) ([]*corev1.Node, error) {
+	if warmStandby == nil {
+		return nil, nil
+	}
+	nodes, err := c.nodesLister.List(labels.Set(warmStandby.NodeSelector).AsSelector())
+	if err != nil {
+		glog.Errorf("Error listing nodes using nodeselector %+v: %v", warmStandby.NodeSelector, err)
+		return nil, err
+	}
+	ready := []*corev1.Node{}
+	for _, n := range c.excludeVirtualNodes(nodes) {
+		if nodeReady(n) {
+			ready = append(ready, n)
+		}
+	}
+	sort.Slice(ready, func(i, j int) bool { return ready[i].Name < ready[j].Name })
+	if warmStandby.Nodes > 0 && len(ready) > warmStandby.Nodes {
+		ready = ready[:warmStandby.Nodes]
+	}
+	return ready, nil
+}
+
+// missingImage returns the first node missing an image of an image cache, and the
+// image. Images pulled for another platform and OCI artifacts are not reported by the
+// nodes, and are not checked.
+func missingImage(imageCache *v1alpha2.ImageCache, nodes []*corev1.Node) (string, string) {
+	for _, n := range nodes {
+		for _, cacheSpec := range imageListsOf(imageCache) {
+			if image := missingImageOfCacheSpec(cacheSpec, n); image != "" {
+				return n.Name, image
+			}
+		}
+	}
+	return "", ""
+}
+
+// missingImageOfCacheSpec returns the first image of a cache spec entry missing on the
+// node, or "" if the node holds all of them. Images pulled for another platform are not
+// checked.
+func missingImageOfCacheSpec(cacheSpec v1alpha2.CacheSpecImages, node *corev1.Node) string {
+	for _, image := range cacheSpec.Images {
+		if _, ok := cacheSpec.Platforms[image]; ok {
+			continue
+		}
+		if !imagePresentOnNode(image, node) {
+			return image
+		}
+	}
+	return ""
+}
+
+// nodeReady returns true if the node is ready and schedulable
+func nodeReady(node *corev1.Node) bool {
+	return !node.Spec.Unschedulable && nodeOnline(node)
+}
+
+// containsNode returns true if the list contains a node of the same name
+func containsNode(nodes []*corev1.Node, node *corev1.Node) bool {
+	for _, n := range nodes {
+		if n.Name == node.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// prioritizeNodes moves the work items of the given nodes ahead of the others, keeping
+// their order otherwise
+func prioritizeNodes(workItems []imageWorkItem, nodes []*corev1.Node) {
+	priority := map[string]bool{}
+	for _, n := range nodes {
+		priority[n.Name] = true
+	}
+	sort.SliceStable(workItems, func(i, j int) bool {
+		return priority[workItems[i].node.Name] && !priority[workItems[j].node.Name]
+	})
+}
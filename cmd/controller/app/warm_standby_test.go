@@ -0,0 +1,130 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"testing"
+
+	kubefledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	kubefledgedclientsetfake "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func newWarmStandbyTestNode(name string, ready bool, images ...string) *corev1.Node {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"warm-standby": "true"}},
+	}
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	node.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}}
+	if len(images) > 0 {
+		node.Status.Images = []corev1.ContainerImage{{Names: images}}
+	}
+	return node
+}
+
+func TestWarmStandbyNodes(t *testing.T) {
+	tests := []struct {
+		name          string
+		warmStandby   *kubefledgedv1alpha2.WarmStandby
+		expectedNodes []string
+	}{
+		{
+			name:          "#1: All ready nodes selected",
+			warmStandby:   &kubefledgedv1alpha2.WarmStandby{NodeSelector: map[string]string{"warm-standby": "true"}},
+			expectedNodes: []string{"node1", "node2", "node4"},
+		},
+		{
+			name:          "#2: First ready nodes by name",
+			warmStandby:   &kubefledgedv1alpha2.WarmStandby{NodeSelector: map[string]string{"warm-standby": "true"}, Nodes: 2},
+			expectedNodes: []string{"node1", "node2"},
+		},
+		{
+			name: "#3: No warm standby",
+		},
+	}
+	controller, nodeInformer, _ := newTestController(fakeclientset.NewSimpleClientset(), kubefledgedclientsetfake.NewSimpleClientset())
+	for _, n := range []*corev1.Node{
+		newWarmStandbyTestNode("node4", true),
+		newWarmStandbyTestNode("node2", true),
+		newWarmStandbyTestNode("node3", false),
+		newWarmStandbyTestNode("node1", true),
+	} {
+		nodeInformer.Informer().GetIndexer().Add(n)
+	}
+	for _, test := range tests {
+		nodes, err := controller.warmStandbyNodes(test.warmStandby)
+		if err != nil {
+			t.Errorf("Test: %s failed: %v", test.name, err)
+			continue
+		}
+		actual := []string{}
+		for _, n := range nodes {
+			actual = append(actual, n.Name)
+		}
+		if len(actual) != len(test.expectedNodes) {
+			t.Errorf("Test: %s failed: expectedNodes=%v, actualNodes=%v", test.name, test.expectedNodes, actual)
+			continue
+		}
+		for i := range actual {
+			if actual[i] != test.expectedNodes[i] {
+				t.Errorf("Test: %s failed: expectedNodes=%v, actualNodes=%v", test.name, test.expectedNodes, actual)
+				break
+			}
+		}
+	}
+}
+
+func TestMissingImage(t *testing.T) {
+	imageCache := &kubefledgedv1alpha2.ImageCache{
+		Spec: kubefledgedv1alpha2.ImageCacheSpec{
+			CacheSpec: []kubefledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx:1.23", "redis:7"}, Platforms: map[string]string{"redis:7": "linux/arm64"}},
+			},
+		},
+	}
+	tests := []struct {
+		name          string
+		nodes         []*corev1.Node
+		expectedNode  string
+		expectedImage string
+	}{
+		{
+			name:  "#1: Warm standby nodes caught up",
+			nodes: []*corev1.Node{newWarmStandbyTestNode("node1", true, "docker.io/library/nginx:1.23")},
+		},
+		{
+			name: "#2: Warm standby node missing an image",
+			nodes: []*corev1.Node{
+				newWarmStandbyTestNode("node1", true, "docker.io/library/nginx:1.23"),
+				newWarmStandbyTestNode("node2", true, "docker.io/library/redis:7"),
+			},
+			expectedNode:  "node2",
+			expectedImage: "nginx:1.23",
+		},
+	}
+	for _, test := range tests {
+		node, image := missingImage(imageCache, test.nodes)
+		if node != test.expectedNode || image != test.expectedImage {
+			t.Errorf("Test: %s failed: expected=%s/%s, actual=%s/%s", test.name, test.expectedNode, test.expectedImage, node, image)
+		}
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"net/http"
 
 	"github.com/golang/glog"
+	clientset "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned"
 	"github.com/senthilrch/kube-fledged/pkg/webhook"
 
 	admissionv1 "k8s.io/api/admission/v1"
@@ -34,6 +35,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/rest"
 	// TODO: try this library to see if it generates correct json patch
 	// https://github.com/mattbaird/jsonpatch
 )
@@ -244,8 +246,13 @@ func convertAdmissionResponseToV1beta1(r *admissionv1.AdmissionResponse) *admiss
 	}
 }
 
+// imageCacheValidator validates image caches. It enforces the FledgedPolicies of the
+// cluster, and the image cache quota of namespaces when a quota is configured. It is
+// set by StartWebhookServer.
+var imageCacheValidator admitv1Func
+
 func validateImageCache(w http.ResponseWriter, r *http.Request) {
-	serve(w, r, newDelegateToV1AdmitHandler(webhook.ValidateImageCache))
+	serve(w, r, newDelegateToV1AdmitHandler(imageCacheValidator))
 }
 
 func mutateImageCache(w http.ResponseWriter, r *http.Request) {
@@ -253,12 +260,25 @@ func mutateImageCache(w http.ResponseWriter, r *http.Request) {
 }
 
 // StartWebhookServer starts a new wwebhook server for kube-fledged
-func StartWebhookServer(certFile string, keyFile string, port int) error {
+func StartWebhookServer(certFile string, keyFile string, port int, quota webhook.ImageCacheQuota, defaultNodeOS string) error {
 	config := Config{
 		CertFile: certFile,
 		KeyFile:  keyFile,
 	}
 
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		return fmt.Errorf("error building kubeconfig: %v", err)
+	}
+	fledgedClient, err := clientset.NewForConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("error building fledged clientset: %v", err)
+	}
+	imageCacheValidator = webhook.NewImageCacheValidator(quota, defaultNodeOS, fledgedClient)
+	if quota.Enabled() {
+		glog.Infof("Enforcing image cache quota %+v", quota)
+	}
+
 	http.HandleFunc("/validate-image-cache", validateImageCache)
 	http.HandleFunc("/mutate-image-cache", mutateImageCache)
 	http.HandleFunc("/readyz", func(w http.ResponseWriter, req *http.Request) { w.Write([]byte("ok")) })
@@ -267,7 +287,7 @@ func StartWebhookServer(certFile string, keyFile string, port int) error {
 		TLSConfig: configTLS(config),
 	}
 	glog.Infof("Wehook server listening on :%d", port)
-	err := server.ListenAndServeTLS("", "")
+	err = server.ListenAndServeTLS("", "")
 	if err != nil {
 		return err
 	}
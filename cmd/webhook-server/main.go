@@ -20,13 +20,16 @@ import (
 	"flag"
 
 	"github.com/senthilrch/kube-fledged/cmd/webhook-server/app"
+	"github.com/senthilrch/kube-fledged/pkg/webhook"
 )
 
 var (
-	certFile   string
-	keyFile    string
-	port       int
-	initServer bool
+	certFile      string
+	keyFile       string
+	port          int
+	initServer    bool
+	quota         webhook.ImageCacheQuota
+	defaultNodeOS string
 )
 
 func init() {
@@ -34,6 +37,9 @@ func init() {
 	flag.StringVar(&keyFile, "key-file", "", "File containing the default x509 private key matching --cert-file.")
 	flag.IntVar(&port, "port", 443, "Secure port that the webhook server listens on")
 	flag.BoolVar(&initServer, "init-server", false, "True means only init tasks for the server will be performed. Server is not started")
+	flag.IntVar(&quota.MaxImageCaches, "max-image-caches-per-namespace", 0, "Maximum number of image caches a namespace may define. Setting this flag to 0 will disable the limit")
+	flag.StringVar(&defaultNodeOS, "default-node-os", "linux", "Operating system (kubernetes.io/os label value) of the nodes targeted by cache spec entries that do not specify a nodeSelector. Must match --default-node-os of kubefledged-controller")
+	flag.IntVar(&quota.MaxImages, "max-images-per-namespace", 0, "Maximum total number of images and OCI artifacts the image caches of a namespace may list. Setting this flag to 0 will disable the limit")
 }
 
 func main() {
@@ -50,7 +56,7 @@ func main() {
 		}
 		return
 	}
-	if err := app.StartWebhookServer(certFile, keyFile, port); err != nil {
+	if err := app.StartWebhookServer(certFile, keyFile, port, quota, defaultNodeOS); err != nil {
 		panic(err)
 	}
 }
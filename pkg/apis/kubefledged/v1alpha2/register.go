@@ -47,6 +47,14 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&ImageCache{},
 		&ImageCacheList{},
+		&FledgedPolicy{},
+		&FledgedPolicyList{},
+		&ImageCacheRequest{},
+		&ImageCacheRequestList{},
+		&ImageCacheTemplate{},
+		&ImageCacheTemplateList{},
+		&ImageList{},
+		&ImageListList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
@@ -18,7 +18,9 @@ package v1alpha2
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // +genclient
@@ -35,14 +37,342 @@ type ImageCache struct {
 
 // CacheSpecImages specifies the Images to be cached
 type CacheSpecImages struct {
-	Images       []string          `json:"images"`
+	Images []string `json:"images,omitempty"`
+	// Artifacts are OCI artifacts (e.g. wasm modules, ML models) to be pulled into
+	// the artifact store of the nodes
+	Artifacts    []string          `json:"artifacts,omitempty"`
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Platforms maps images of this image list to the platform (os/arch[/variant]
+	// e.g. linux/arm64) to be cached, instead of the platform of the node
+	Platforms map[string]string `json:"platforms,omitempty"`
+	// ImagePullPolicies maps images of this image list to the image pull policy they are
+	// refreshed with, overriding the image pull policy of the image cache, e.g. Always
+	// for mutable tags and IfNotPresent for images pinned by digest
+	ImagePullPolicies map[string]corev1.PullPolicy `json:"imagePullPolicies,omitempty"`
+	// Fallbacks maps images of this image list to ordered fallback references of the
+	// same image, e.g. a registry mirror then the public registry. When the registry of
+	// a reference is unreachable, the image is pulled from the next reference. The image
+	// is cached on the node under the reference it was pulled from
+	Fallbacks map[string][]string `json:"fallbacks,omitempty"`
+	// TargetRefs maps images of this image list to the reference they are tagged as on
+	// the nodes, e.g. to pull mirror.internal/library/nginx:1.23 while workloads keep
+	// using docker.io/library/nginx:1.23. The images are pulled and tagged with the
+	// client of the container runtime, and only the target reference is kept
+	TargetRefs map[string]string `json:"targetRefs,omitempty"`
+	// DisplayTags maps images of this image list referenced by digest only, e.g.
+	// nginx@sha256:..., to a friendly tag shown with the digest in the status and the
+	// metrics of the image cache
+	DisplayTags map[string]string `json:"displayTags,omitempty"`
+	// TrackedRepositories are repositories whose tags matching a version constraint are
+	// added to the resolved images of this image list by the controller
+	TrackedRepositories []TrackedRepository `json:"trackedRepositories,omitempty"`
+	// Catalogs select repositories of registries and their tags by globs. The images
+	// matching a catalog are added to the images of this image list by the controller
+	// when the image cache is created, updated or refreshed
+	Catalogs []RegistryCatalog `json:"catalogs,omitempty"`
+	// Providers are sources of truth of the images of this image list, e.g. a ConfigMap
+	// or a release catalog service. The images they list are added to the images of this
+	// image list by the controller when the image cache is created, updated or refreshed
+	Providers []ImageListProviderSpec `json:"providers,omitempty"`
+	// RefreshInterval overrides the image cache refresh frequency of the controller for
+	// the images of this image list, e.g. 168h for base images and 15m for application
+	// images. Zero disables the refresh of the image list
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
+}
+
+// TrackedRepository is a repository whose tags are polled by the controller. Tags which
+// are semantic versions satisfying the tag constraint are cached.
+type TrackedRepository struct {
+	// Repository is the image repository e.g. nginx or ghcr.io/myorg/myapp
+	Repository string `json:"repository"`
+	// TagConstraint is a semantic version constraint e.g. "1.25.*" or ">= 1.2, < 2"
+	TagConstraint string `json:"tagConstraint"`
+	// MaxTags limits the cached tags to the highest matching versions. Zero means no limit
+	MaxTags int `json:"maxTags,omitempty"`
+	// Prune removes the images of the repository whose tags no longer match from the
+	// image list
+	Prune bool `json:"prune,omitempty"`
+}
+
+// RegistryCatalog selects the images of a registry by repository and tag globs. The
+// repositories are listed using the catalog API of the registry.
+type RegistryCatalog struct {
+	// Repositories is a registry followed by a repository glob e.g.
+	// registry.internal/platform/*
+	Repositories string `json:"repositories"`
+	// Tags is a tag glob e.g. "v1.*"
+	Tags string `json:"tags"`
+	// Prune removes the images of the matching repositories whose tags no longer match,
+	// and the images of repositories no longer matching, from the image list
+	Prune bool `json:"prune,omitempty"`
+}
+
+// ImageListProviderSpec is a source of truth of the images of an image list. Exactly
+// one of ConfigMap, Workloads, Plugin, System, HelmRelease, ImageList and ImageStream
+// must be specified.
+type ImageListProviderSpec struct {
+	// ConfigMap lists the images of a key of a ConfigMap
+	ConfigMap *ConfigMapImageList `json:"configMap,omitempty"`
+	// Workloads lists the images of the containers of pods
+	Workloads *WorkloadImageList `json:"workloads,omitempty"`
+	// Plugin lists the images returned by an external gRPC plugin
+	Plugin *PluginImageList `json:"plugin,omitempty"`
+	// System lists the images of the system components of the cluster
+	System *SystemImageList `json:"system,omitempty"`
+	// HelmRelease lists the images of the manifest of an installed Helm release
+	HelmRelease *HelmReleaseImageList `json:"helmRelease,omitempty"`
+	// ImageList lists the images of an ImageList resource shared by image caches
+	ImageList *ImageListReference `json:"imageList,omitempty"`
+	// ImageStream lists the images of the tags of an OpenShift ImageStream
+	ImageStream *ImageStreamImageList `json:"imageStream,omitempty"`
+	// Prune removes the images previously listed by the provider, which it no longer
+	// lists, from the image list
+	Prune bool `json:"prune,omitempty"`
+}
+
+// ConfigMapImageList lists the images of a key of a ConfigMap in the namespace of the
+// image cache, one image per line. Empty lines and lines starting with # are ignored.
+type ConfigMapImageList struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+// WorkloadImageList lists the images of the containers and init containers of the pods
+// in the namespace of the image cache matching a label selector.
+type WorkloadImageList struct {
+	// Selector selects the pods. All the pods of the namespace are selected if not set
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	// ExcludeInitContainers leaves out the images of the init containers of the pods
+	ExcludeInitContainers bool `json:"excludeInitContainers,omitempty"`
+	// IncludeEphemeralContainers lists the images of the ephemeral containers of the
+	// pods as well, e.g. the debug images added by kubectl debug
+	IncludeEphemeralContainers bool `json:"includeEphemeralContainers,omitempty"`
+}
+
+// ImageListReference lists the images of an ImageList resource. The images follow the
+// updates of the ImageList.
+type ImageListReference struct {
+	// Namespace of the ImageList. Defaults to the namespace of the image cache
+	Namespace string `json:"namespace,omitempty"`
+	// Name of the ImageList
+	Name string `json:"name"`
+}
+
+// ImageStreamImageList lists the images of the tags of an OpenShift ImageStream, pinned
+// to the digests the tags currently point to, e.g.
+// image-registry.openshift-image-registry.svc:5000/team/app@sha256:... The images follow
+// the tags when the image cache is refreshed.
+type ImageStreamImageList struct {
+	// Namespace of the ImageStream. Defaults to the namespace of the image cache
+	Namespace string `json:"namespace,omitempty"`
+	// Name of the ImageStream
+	Name string `json:"name"`
+	// Tags of the ImageStream whose images are listed. All the tags are listed if not set
+	Tags []string `json:"tags,omitempty"`
+}
+
+// HelmReleaseImageList lists the images of the containers of the manifest of the
+// deployed revision of a Helm release, read from the release Secret. The images follow
+// the upgrades and rollbacks of the release.
+type HelmReleaseImageList struct {
+	// Namespace of the release. Defaults to the namespace of the image cache
+	Namespace string `json:"namespace,omitempty"`
+	// Name of the release
+	Name string `json:"name"`
+}
+
+// PluginImageList lists the images returned by an external gRPC plugin, e.g. an
+// internal release catalog service.
+type PluginImageList struct {
+	// Address of the plugin e.g. catalog.tools.svc:9000 or unix:///var/run/plugin.sock
+	Address string `json:"address"`
+	// Parameters are passed to the plugin
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// SystemImageList lists the images of the system components of the cluster: the static
+// pods of the control plane, the DaemonSets (e.g. kube-proxy, CNI and CSI node plugins)
+// and the pause images reported by the nodes. It is used to pre-pull the images of the
+// target version of an upgrade across the nodes before the upgrade window.
+type SystemImageList struct {
+	// Namespaces whose static pods and DaemonSets are inspected. Defaults to kube-system
+	Namespaces []string `json:"namespaces,omitempty"`
+	// KubernetesVersion is the target version of an upgrade e.g. v1.28.2. The images of
+	// kube-apiserver, kube-controller-manager, kube-scheduler and kube-proxy are listed
+	// with this version as tag. The images in use are listed if not specified
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+	// PauseImage is the pause image of the target version e.g. registry.k8s.io/pause:3.9,
+	// listed in addition to the pause images reported by the nodes
+	PauseImage string `json:"pauseImage,omitempty"`
 }
 
 // ImageCacheSpec is the spec for a ImageCache resource
 type ImageCacheSpec struct {
 	CacheSpec        []CacheSpecImages             `json:"cacheSpec"`
 	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// ImagePullPolicy overrides the image pull policy of the controller for the images
+	// of this image cache. Possible values are 'Always' and 'IfNotPresent'
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	// TTL is the time-to-live of the image cache, from its creation. Once expired, the
+	// images of the image cache are purged and the image cache is deleted
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+	// TTLSecondsAfterFinished makes the image cache one-shot: it is not refreshed, and
+	// is deleted this many seconds after its last action finished, whether it succeeded
+	// or failed. Its images are not purged
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+	// UnusedImagePurge purges the images of the image cache that are not used by any
+	// pod for a while. It requires usage tracking to be enabled in the controller
+	UnusedImagePurge *UnusedImagePurge `json:"unusedImagePurge,omitempty"`
+	// WarmStandby keeps spare nodes caught up with all the images of the image cache
+	WarmStandby *WarmStandby `json:"warmStandby,omitempty"`
+	// PurgeUnmatchedNodes purges the images of a cache spec entry from the nodes whose
+	// labels changed and no longer match its node selector. The images are kept on the
+	// nodes if false
+	PurgeUnmatchedNodes bool `json:"purgeUnmatchedNodes,omitempty"`
+	// FailureThreshold is the number (e.g. 10) or percentage (e.g. "5%") of image pulls
+	// of an image cache action that may fail. Once exceeded, the remaining image pulls
+	// are cancelled and the action fails. Image pulls are never cancelled if not set
+	FailureThreshold *intstr.IntOrString `json:"failureThreshold,omitempty"`
+	// MaxBytesPerNode caps the size of the images of the image cache pulled to a node,
+	// e.g. "20Gi". Images beyond the cap are not pulled. Images whose size is not yet
+	// reported by any node are not accounted for
+	MaxBytesPerNode *resource.Quantity `json:"maxBytesPerNode,omitempty"`
+	// ServiceAccountName overrides the service account of the controller for the jobs
+	// pulling and deleting the images of this image cache. The service account must be
+	// in the namespace of the image cache, and be allowed by a FledgedPolicy
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// SecurityProfiles override the seccomp and AppArmor profiles of the controller for
+	// the pods of the jobs pulling and deleting the images of this image cache
+	SecurityProfiles *SecurityProfiles `json:"securityProfiles,omitempty"`
+	// CostBudget is the maximum estimated egress cost (e.g. "25.50") of the image pulls
+	// of an image cache action. Actions beyond the budget wait for approval. It requires
+	// egress cost rates to be configured in the controller
+	CostBudget *resource.Quantity `json:"costBudget,omitempty"`
+	// Hooks are run before the image cache actions start and after they complete. They
+	// require the PullHooks feature gate to be enabled in the controller
+	Hooks *ImageCacheHooks `json:"hooks,omitempty"`
+	// Rollout stages the image pulls of the image cache actions in waves of nodes. The
+	// images are pulled to all the nodes at once if not set
+	Rollout *RolloutStrategy `json:"rollout,omitempty"`
+	// Snapshotter is the containerd snapshotter (e.g. overlayfs, zfs, nydus or stargz) the
+	// images are unpacked into, so that they land in the snapshotter the workloads use.
+	// It overrides the kubefledged.io/snapshotter label of the nodes. It only applies to
+	// the images pulled with the ctr client of containerd
+	Snapshotter string `json:"snapshotter,omitempty"`
+}
+
+// RolloutStrategy stages the image pulls of an image cache action in waves of nodes. A
+// wave starts once the image pulls of the previous wave completed. The rollout halts
+// if an image pull of a wave failed.
+type RolloutStrategy struct {
+	// Type of the rollout. 'Topology' pulls the images to one node of every topology
+	// domain first, e.g. seeding the registry mirrors local to the zones, then to the
+	// remaining nodes domain by domain
+	Type RolloutType `json:"type"`
+	// TopologyKey is the node label whose value is the topology domain of the nodes.
+	// Defaults to 'topology.kubernetes.io/zone'
+	TopologyKey string `json:"topologyKey,omitempty"`
+}
+
+// RolloutType defines how the image pulls of an image cache action are staged
+type RolloutType string
+
+// List of constants for RolloutType
+const (
+	RolloutTypeTopology RolloutType = "Topology"
+)
+
+// SecurityProfiles are the seccomp and AppArmor profiles of the pods of the jobs pulling
+// and deleting images, e.g. to admit them in namespaces enforcing the restricted Pod
+// Security Standard or custom AppArmor policies
+type SecurityProfiles struct {
+	// SeccompProfile is the seccomp profile of the pods
+	SeccompProfile *corev1.SeccompProfile `json:"seccompProfile,omitempty"`
+	// AppArmorProfile is the AppArmor profile of the containers of the pods, set with
+	// the AppArmor annotations: "runtime/default", "unconfined" or "localhost/<profile>"
+	AppArmorProfile string `json:"appArmorProfile,omitempty"`
+}
+
+// ImageCacheHooks are the hooks of the actions of an image cache, e.g. to drain a
+// node-local registry mirror before the image pulls, or to notify a CMDB after them.
+type ImageCacheHooks struct {
+	// PrePull hooks run, in order, before the image pulls or deletes of an action start
+	PrePull []ImageCacheHook `json:"prePull,omitempty"`
+	// PostPull hooks run, in order, once all the image pulls or deletes of an action
+	// completed, whether they succeeded or failed
+	PostPull []ImageCacheHook `json:"postPull,omitempty"`
+}
+
+// ImageCacheHook is a command run in a pod, or an HTTP call. Exactly one of Exec and
+// HTTP must be specified.
+type ImageCacheHook struct {
+	Name string `json:"name"`
+	// Scope is either 'Cache', the hook runs once per action, or 'Node', the hook runs
+	// once for every node targeted by the action. Defaults to 'Cache'
+	Scope HookScope `json:"scope,omitempty"`
+	// Exec runs a command in a pod. The pods of node scoped hooks run on their node
+	Exec *ExecHook `json:"exec,omitempty"`
+	// HTTP posts the hook context to a URL
+	HTTP *HTTPHook `json:"http,omitempty"`
+	// Timeout of the hook. Defaults to 5m
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// FailurePolicy of a pre-pull hook is either 'Fail', a failed hook fails the action
+	// before any image is pulled, or 'Ignore'. Failed post-pull hooks are reported only.
+	// Defaults to 'Fail'
+	FailurePolicy HookFailurePolicy `json:"failurePolicy,omitempty"`
+}
+
+// ExecHook runs a command in a container. The hook context is passed in the
+// environment variables HOOK_PHASE, HOOK_ACTION, HOOK_IMAGECACHE, HOOK_NODE and
+// HOOK_STATUS.
+type ExecHook struct {
+	Image   string   `json:"image"`
+	Command []string `json:"command"`
+}
+
+// HTTPHook posts the hook context as JSON to a URL. The hook fails unless the response
+// status is 2xx.
+type HTTPHook struct {
+	URL string `json:"url"`
+}
+
+// HookScope defines whether a hook runs once per image cache action or once per node
+type HookScope string
+
+// List of constants for HookScope
+const (
+	HookScopeCache HookScope = "Cache"
+	HookScopeNode  HookScope = "Node"
+)
+
+// HookFailurePolicy defines how a failed pre-pull hook is handled
+type HookFailurePolicy string
+
+// List of constants for HookFailurePolicy
+const (
+	HookFailurePolicyFail   HookFailurePolicy = "Fail"
+	HookFailurePolicyIgnore HookFailurePolicy = "Ignore"
+)
+
+// WarmStandby designates "warm" spare nodes of an image cache. Warm standby nodes cache
+// all the images of the image cache, whatever the node selectors of its image lists.
+// They are served before other nodes, and are caught up as soon as they miss an image.
+type WarmStandby struct {
+	// NodeSelector selects the warm standby nodes
+	NodeSelector map[string]string `json:"nodeSelector"`
+	// Nodes is the number of warm standby nodes. Ready nodes are chosen by name. All
+	// the selected nodes are warm standby nodes if 0
+	Nodes int `json:"nodes,omitempty"`
+}
+
+// UnusedImagePurge is a policy purging the unused images of an image cache. Unused
+// images are removed from the image lists of the image cache, and purged from the nodes.
+type UnusedImagePurge struct {
+	// After is the duration after which an image not used by any pod is purged. Images
+	// never used are purged this long after the creation of the image cache
+	After metav1.Duration `json:"after"`
+	// Pinned images are never purged for being unused
+	Pinned []string `json:"pinned,omitempty"`
 }
 
 // ImageCacheStatus is the status for a ImageCache resource
@@ -53,6 +383,231 @@ type ImageCacheStatus struct {
 	Failures       map[string]NodeReasonMessageList `json:"failures,omitempty"`
 	StartTime      *metav1.Time                     `json:"startTime"`
 	CompletionTime *metav1.Time                     `json:"completionTime,omitempty"`
+	Plan           *ImageCachePlan                  `json:"plan,omitempty"`
+	Progress       *ImageCacheProgress              `json:"progress,omitempty"`
+	// Usage is the usage of the images of the image cache by the pods of the cluster.
+	// It is only reported if usage tracking is enabled in the controller
+	Usage []ImageUsage `json:"usage,omitempty"`
+	// Deferred are the nodes that were offline when images were to be pulled to them,
+	// or whose images were to be pulled from registries whose circuit was open. The
+	// pulls are retried once the nodes reconnect and the circuits cooled down. It is
+	// only reported if deferral of pulls to offline nodes or the registry circuit
+	// breaker is enabled in the controller
+	Deferred []DeferredNode `json:"deferred,omitempty"`
+	// Sources are the references the images with fallback references were pulled from
+	// by the last action, for each node
+	Sources map[string][]NodeSource `json:"sources,omitempty"`
+	// GCPressure are the nodes targeted by the image cache whose images exceed the
+	// kubelet image garbage collection low threshold, so that cached images are at
+	// risk of being removed
+	GCPressure []NodeGCPressure `json:"gcPressure,omitempty"`
+	// EntryRefreshTimes are the start times of the last actions that pulled the images of
+	// each image list, in the order of the cache spec. It is only reported if an image
+	// list overrides the refresh interval
+	EntryRefreshTimes []metav1.Time `json:"entryRefreshTimes,omitempty"`
+	// ProvidedImages are the images listed by the pruned providers of each image list at
+	// the last action, in the order of the cache spec. Images no longer listed are pruned
+	ProvidedImages [][]string `json:"providedImages,omitempty"`
+	// ResolvedImages are the images added to each image list by its registry catalogs,
+	// image list providers and tracked repositories, in the order of the cache spec. They are cached along with the
+	// images of the image list
+	ResolvedImages [][]string `json:"resolvedImages,omitempty"`
+	// LayerStats are the bytes of the layers shared by the images of the image cache,
+	// and unique to each image, on the nodes. It is only reported if layer statistics
+	// are enabled in the controller
+	LayerStats []LayerStats `json:"layerStats,omitempty"`
+	// ImageFsUsage is the usage of the image filesystem of the nodes targeted by the
+	// last action, at its start and at its end. It is only reported if recording of the
+	// image filesystem usage is enabled in the controller
+	ImageFsUsage []NodeImageFsUsage `json:"imageFsUsage,omitempty"`
+	// DryRun is the plan of the last dry run of the image cache, requested by annotating
+	// it with kubefledged.io/dry-run-imagecache. A dry run plans a refresh of the image
+	// cache and estimates its image pulls without pulling any image
+	DryRun *ImageCacheDryRun `json:"dryRun,omitempty"`
+	// ControllerVersion is the version of the controller that last successfully
+	// reconciled the image cache
+	ControllerVersion string `json:"controllerVersion,omitempty"`
+	// ObservedSpecHash is the hash of the spec last successfully reconciled. The spec
+	// was changed and not yet acted on if its hash differs
+	ObservedSpecHash string `json:"observedSpecHash,omitempty"`
+	// Rollout is the progress of the staged rollout of the image pulls of the last
+	// action. It is only reported if the image cache specifies a rollout
+	Rollout *ImageCacheRollout `json:"rollout,omitempty"`
+}
+
+// ImageCacheRollout is the progress of a staged rollout
+type ImageCacheRollout struct {
+	// Wave is the number, from 1, of the wave of nodes of the last action
+	Wave int `json:"wave"`
+	// Waves is the number of waves of nodes of the rollout
+	Waves int `json:"waves"`
+}
+
+// ImageCacheDryRun is the plan of an image cache refresh computed without executing it
+type ImageCacheDryRun struct {
+	Time metav1.Time    `json:"time"`
+	Plan ImageCachePlan `json:"plan"`
+}
+
+// NodeImageFsUsage is the usage of the image filesystem of a node before and after an
+// image cache action, as reported by the summary API of kubelet
+type NodeImageFsUsage struct {
+	Node string `json:"node"`
+	// BeforeBytes is the used bytes of the image filesystem at the start of the action
+	BeforeBytes int64 `json:"beforeBytes"`
+	// AfterBytes is the used bytes of the image filesystem at the end of the action
+	AfterBytes int64 `json:"afterBytes,omitempty"`
+	// DeltaBytes is the difference between the used bytes at the end and at the start
+	// of the action. It is negative if the action freed disk space
+	DeltaBytes int64 `json:"deltaBytes,omitempty"`
+	// CapacityBytes is the capacity of the image filesystem
+	CapacityBytes int64 `json:"capacityBytes,omitempty"`
+}
+
+// LayerStats are the layer statistics of the images of an image cache on nodes. Nodes
+// caching the same images for the same platform are reported together. Sizes are the
+// compressed sizes of the layers in the registries.
+type LayerStats struct {
+	Nodes    []string `json:"nodes"`
+	Platform string   `json:"platform"`
+	// TotalBytes is the sum of the sizes of the images
+	TotalBytes int64 `json:"totalBytes"`
+	// DedupBytes is the size of the distinct layers of the images, i.e. the bytes
+	// actually pulled to and stored on each node
+	DedupBytes int64 `json:"dedupBytes"`
+	// SharedBytes is the size of the distinct layers used by more than one image
+	SharedBytes int64             `json:"sharedBytes"`
+	Images      []ImageLayerStats `json:"images"`
+}
+
+// ImageLayerStats are the layer statistics of an image of an image cache
+type ImageLayerStats struct {
+	Image string `json:"image"`
+	// Bytes is the size of the image
+	Bytes int64 `json:"bytes"`
+	// UniqueBytes is the size of the layers of the image not used by the other images
+	// of the image cache, i.e. the bytes the image adds to the nodes
+	UniqueBytes int64 `json:"uniqueBytes"`
+}
+
+// NodeGCPressure is the image storage usage of a node close to the kubelet image
+// garbage collection thresholds
+type NodeGCPressure struct {
+	Node string `json:"node"`
+	// ImageBytes is the size of all the images on the node
+	ImageBytes int64 `json:"imageBytes"`
+	// CachedBytes is the size of the images of the image cache on the node
+	CachedBytes int64 `json:"cachedBytes"`
+	// HeadroomBytes is the size of the images that can be added to the node before the
+	// high threshold is reached. It is negative beyond the high threshold
+	HeadroomBytes int64 `json:"headroomBytes"`
+}
+
+// NodeSource is the reference an image was pulled from to a node
+type NodeSource struct {
+	Node   string `json:"node"`
+	Source string `json:"source"`
+}
+
+// DeferredNode is a node whose image pulls are deferred until it reconnects
+type DeferredNode struct {
+	Node string `json:"node"`
+	// Images are the images and OCI artifacts to be pulled to the node
+	Images []string `json:"images"`
+	// Attempts is the number of times the pulls to the node were deferred
+	Attempts int `json:"attempts"`
+	// NextAttempt is the earliest time the pulls are retried, with exponential backoff
+	NextAttempt *metav1.Time `json:"nextAttempt,omitempty"`
+}
+
+// ImageUsage is the usage of a cached image by the pods of the cluster
+type ImageUsage struct {
+	Image string `json:"image"`
+	// DisplayTag is the display tag of the image, when it is referenced by digest only
+	DisplayTag string `json:"displayTag,omitempty"`
+	// Pods is the number of running or pending pods whose containers use the image
+	Pods int `json:"pods"`
+	// LastUsed is the last time a pod was found using the image
+	LastUsed *metav1.Time `json:"lastUsed,omitempty"`
+}
+
+// ImageCacheProgress is the progress of an image cache action. It is updated
+// periodically while the image cache action is under processing
+type ImageCacheProgress struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	// Pulls is the layer download progress of the image pulls in progress. It is
+	// only reported for pulls performed with the containerd pull strategy
+	Pulls []ImagePullProgress `json:"pulls,omitempty"`
+}
+
+// ImagePullProgress is the layer download progress of an image pull on a node
+type ImagePullProgress struct {
+	Image      string `json:"image"`
+	Node       string `json:"node"`
+	Layers     int    `json:"layers"`
+	LayersDone int    `json:"layersDone"`
+	// BytesPulled and BytesTotal are the bytes downloaded so far and the size of
+	// the layers being downloaded
+	BytesPulled int64 `json:"bytesPulled,omitempty"`
+	BytesTotal  int64 `json:"bytesTotal,omitempty"`
+}
+
+// ImageCachePlan is the effective plan of an image cache action, after merging
+// cache spec entries that list the same image for the same nodes
+type ImageCachePlan struct {
+	WorkItems       int                 `json:"workItems"`
+	MergedWorkItems int                 `json:"mergedWorkItems,omitempty"`
+	Overlaps        []ImageCacheOverlap `json:"overlaps,omitempty"`
+	// CappedWorkItems is the number of image pulls skipped because of maxBytesPerNode
+	CappedWorkItems int `json:"cappedWorkItems,omitempty"`
+	// Nodes is the number of nodes the images are pulled to. It is only reported if
+	// approval of large image cache actions is enabled in the controller
+	Nodes int `json:"nodes,omitempty"`
+	// EstimatedBytes is the size of the pulled images, from the sizes reported by the
+	// nodes holding them. It is only reported if approval of large image cache actions
+	// is enabled in the controller
+	EstimatedBytes int64 `json:"estimatedBytes,omitempty"`
+	// EstimatedCost is the egress cost of the image pulls, from the sizes of the images
+	// in the registries and the cost rates of the registries. It is only reported if
+	// egress cost rates are configured in the controller
+	EstimatedCost string `json:"estimatedCost,omitempty"`
+	// Estimate is the estimated bytes and duration of the image pulls on each node. It
+	// is reported by dry runs, and by all actions if a node bandwidth is configured in
+	// the controller
+	Estimate *ImageCacheEstimate `json:"estimate,omitempty"`
+	// SpecHash is the hash of the spec of the image cache the plan was computed from
+	SpecHash string `json:"specHash,omitempty"`
+}
+
+// ImageCacheEstimate is the estimated bytes and duration of the image pulls of an image
+// cache action, from the sizes of the images in the registries. Images already present
+// on a node are not pulled again unless the image pull policy is Always.
+type ImageCacheEstimate struct {
+	// TotalBytes is the bytes pulled to all the nodes
+	TotalBytes int64 `json:"totalBytes"`
+	// Duration is the estimated wall-clock time of the image pulls, i.e. the duration
+	// on the slowest node. It is only reported if a node bandwidth is configured
+	Duration *metav1.Duration `json:"duration,omitempty"`
+	// Nodes are the estimates of the nodes images are pulled to
+	Nodes []NodeEstimate `json:"nodes,omitempty"`
+}
+
+// NodeEstimate is the estimated bytes and duration of the image pulls on a node
+type NodeEstimate struct {
+	Node  string `json:"node"`
+	Bytes int64  `json:"bytes"`
+	// Duration is the bytes pulled at the node bandwidth configured in the controller
+	Duration *metav1.Duration `json:"duration,omitempty"`
+}
+
+// ImageCacheOverlap is an image listed in more than one cache spec entry,
+// where the node selectors of the entries match common nodes
+type ImageCacheOverlap struct {
+	Image   string `json:"image"`
+	Entries []int  `json:"entries"`
+	Nodes   int    `json:"nodes"`
 }
 
 // NodeReasonMessage has failure reason and message for a node
@@ -60,6 +615,10 @@ type NodeReasonMessage struct {
 	Node    string `json:"node"`
 	Reason  string `json:"reason"`
 	Message string `json:"message"`
+	// Log is an excerpt of the log of the failed container if it did not report a
+	// termination message, or of the Failed events of its pod if it is waiting,
+	// e.g. in ErrImagePull or ImagePullBackOff
+	Log string `json:"log,omitempty"`
 }
 
 // NodeReasonMessageList has list of node reason message
@@ -75,6 +634,232 @@ type ImageCacheList struct {
 	Items []ImageCache `json:"items"`
 }
 
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// FledgedPolicy is a cluster-wide policy for image caches. It sets defaults for image
+// cache actions, and guardrails enforced on the image caches of all namespaces
+type FledgedPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec FledgedPolicySpec `json:"spec"`
+}
+
+// FledgedPolicySpec is the spec for a FledgedPolicy resource
+type FledgedPolicySpec struct {
+	// Defaults are applied to the image caches that do not override them
+	Defaults *FledgedPolicyDefaults `json:"defaults,omitempty"`
+	// AllowedRegistries are the registries (e.g. docker.io) or repository prefixes
+	// (e.g. ghcr.io/myorg) images and artifacts may be cached from. Empty allows all
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
+	// MaxNodesPerCache is the maximum number of nodes an image cache may target.
+	// Zero means no limit
+	MaxNodesPerCache int `json:"maxNodesPerCache,omitempty"`
+	// RestrictedNodeSelectors are sensitive node pools that only the image caches of
+	// some namespaces may target
+	RestrictedNodeSelectors []RestrictedNodeSelector `json:"restrictedNodeSelectors,omitempty"`
+	// NamespaceNodeSelectors restrict the image caches of namespaces to the node pools
+	// assigned to them
+	NamespaceNodeSelectors []NamespaceNodeSelector `json:"namespaceNodeSelectors,omitempty"`
+	// AllowedServiceAccounts are the service accounts that image caches may use for
+	// the jobs pulling and deleting their images. Image caches may not override the
+	// service account if no policy allows any
+	AllowedServiceAccounts []string `json:"allowedServiceAccounts,omitempty"`
+	// CostBudgets are the maximum estimated egress costs of the image cache actions of
+	// namespaces. Actions beyond the budget wait for approval
+	CostBudgets []NamespaceCostBudget `json:"costBudgets,omitempty"`
+	// ProviderNamespaces are the namespaces whose Helm releases, ImageLists and
+	// ImageStreams the image list providers of the image caches of other namespaces may
+	// list. Providers may only list those of the namespace of their image cache otherwise
+	ProviderNamespaces []ProviderNamespace `json:"providerNamespaces,omitempty"`
+}
+
+// ProviderNamespace is a namespace whose Helm releases, ImageLists and ImageStreams the
+// image caches of the listed namespaces may list, or the image caches of all the
+// namespaces if none are listed
+type ProviderNamespace struct {
+	Namespace  string   `json:"namespace"`
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// NamespaceCostBudget is the maximum estimated egress cost of the image pulls of each
+// image cache action in a namespace
+type NamespaceCostBudget struct {
+	Namespace string            `json:"namespace"`
+	Budget    resource.Quantity `json:"budget"`
+}
+
+// FledgedPolicyDefaults are defaults for image cache actions
+type FledgedPolicyDefaults struct {
+	// ImagePullPolicy is the image pull policy of image caches that do not specify one
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	// ImagePullDeadline is the maximum duration allowed for pulling the images of an
+	// image cache, instead of the image pull deadline of the controller
+	ImagePullDeadline *metav1.Duration `json:"imagePullDeadline,omitempty"`
+}
+
+// RestrictedNodeSelector is a node pool, identified by node labels, that only the
+// image caches of the listed namespaces may target
+type RestrictedNodeSelector struct {
+	NodeSelector map[string]string `json:"nodeSelector"`
+	Namespaces   []string          `json:"namespaces,omitempty"`
+}
+
+// NamespaceNodeSelector lists the node pools, identified by node labels, that the image
+// caches of a namespace may target. Every entry of the image caches of the namespace
+// must have a node selector requiring the labels of one of the node pools
+type NamespaceNodeSelector struct {
+	Namespace     string              `json:"namespace"`
+	NodeSelectors []map[string]string `json:"nodeSelectors"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// FledgedPolicyList is a list of FledgedPolicy resources
+type FledgedPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []FledgedPolicy `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImageCacheRequest is a one-shot request to pull images to nodes. Unlike an image
+// cache, it is executed once and never refreshed. It is deleted after a TTL once
+// finished, leaving the images on the nodes.
+type ImageCacheRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImageCacheRequestSpec   `json:"spec"`
+	Status ImageCacheRequestStatus `json:"status,omitempty"`
+}
+
+// ImageCacheRequestSpec is the spec for an ImageCacheRequest resource
+type ImageCacheRequestSpec struct {
+	Images       []string          `json:"images"`
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// TTLSecondsAfterFinished is the number of seconds the request is kept once
+	// finished. Defaults to one hour
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+}
+
+// ImageCacheRequestStatus is the status for an ImageCacheRequest resource
+type ImageCacheRequestStatus struct {
+	Status   ImageCacheActionStatus           `json:"status,omitempty"`
+	Reason   string                           `json:"reason,omitempty"`
+	Message  string                           `json:"message,omitempty"`
+	Failures map[string]NodeReasonMessageList `json:"failures,omitempty"`
+	// ImageCache is the name of the image cache pulling the images of the request. It
+	// is deleted once the request is finished
+	ImageCache     string       `json:"imageCache,omitempty"`
+	StartTime      *metav1.Time `json:"startTime,omitempty"`
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImageCacheRequestList is a list of ImageCacheRequest resources
+type ImageCacheRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ImageCacheRequest `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImageCacheTemplate instantiates and maintains an image cache for every node pool of
+// the cluster. The node pools are the values of a node label, discovered from the nodes.
+type ImageCacheTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImageCacheTemplateSpec   `json:"spec"`
+	Status ImageCacheTemplateStatus `json:"status,omitempty"`
+}
+
+// ImageCacheTemplateSpec is the spec for an ImageCacheTemplate resource
+type ImageCacheTemplateSpec struct {
+	// NodePoolLabelKey is the node label whose values are the node pools, e.g.
+	// 'karpenter.sh/nodepool' or 'cloud.google.com/gke-nodepool'
+	NodePoolLabelKey string `json:"nodePoolLabelKey"`
+	// Template is the spec of the image caches instantiated for the node pools. The node
+	// selectors of its image lists are restricted to the nodes of the pool
+	Template ImageCacheSpec `json:"template"`
+	// Pools are the images cached on the nodes of a node pool only, in addition to the
+	// images of the template, by node pool
+	Pools map[string][]string `json:"pools,omitempty"`
+}
+
+// ImageCacheTemplateStatus is the status for an ImageCacheTemplate resource
+type ImageCacheTemplateStatus struct {
+	// ImageCaches are the names of the image caches instantiated, by node pool
+	ImageCaches map[string]string `json:"imageCaches,omitempty"`
+	// Message reports the node pools whose image cache could not be instantiated
+	Message string `json:"message,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImageCacheTemplateList is a list of ImageCacheTemplate resources
+type ImageCacheTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ImageCacheTemplate `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImageList is a reusable set of images, referenced by the image lists of image caches
+// with an imageList image list provider. Image caches referencing an image list are
+// refreshed when it is updated.
+type ImageList struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ImageListSpec `json:"spec"`
+}
+
+// ImageListSpec is the spec for an ImageList resource
+type ImageListSpec struct {
+	// Images are the images of the image list
+	Images []string `json:"images"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImageListList is a list of ImageList resources
+type ImageListList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ImageList `json:"items"`
+}
+
+// Annotations requesting actions on an image cache
+const (
+	ImageCachePurgeAnnotationKey   = "kubefledged.io/purge-imagecache"
+	ImageCacheRefreshAnnotationKey = "kubefledged.io/refresh-imagecache"
+	ImageCacheCancelAnnotationKey  = "kubefledged.io/cancel-imagecache"
+	// ImageCacheApproveAnnotationKey approves an image cache action pending approval
+	ImageCacheApproveAnnotationKey = "kubefledged.io/approve-imagecache"
+	// ImageCacheDryRunAnnotationKey requests a dry run of the image cache
+	ImageCacheDryRunAnnotationKey = "kubefledged.io/dry-run-imagecache"
+)
+
+// ImageCacheManifestCoverageAnnotationKey is the annotation of the image caches of a
+// cache manifest recording, for each image, the number of nodes holding it out of the
+// nodes targeted when the manifest was exported
+const ImageCacheManifestCoverageAnnotationKey = "kubefledged.io/manifest-coverage"
+
 // ImageCacheActionStatus defines the status of ImageCacheAction
 type ImageCacheActionStatus string
 
@@ -86,6 +871,7 @@ const (
 	ImageCacheActionStatusUnknown            ImageCacheActionStatus = "Unknown"
 	ImageCacheActionStatusAborted            ImageCacheActionStatus = "Aborted"
 	ImageCacheActioneNoImagesPulledOrDeleted ImageCacheActionStatus = "NoImagesPulledOrDeleted"
+	ImageCacheActionStatusPendingApproval    ImageCacheActionStatus = "PendingApproval"
 )
 
 // List of constants for ImageCacheReason
@@ -105,6 +891,21 @@ const (
 	ImageCacheReasonCacheSpecValidationFailed      = "CacheSpecValidationFailed"
 	ImageCacheReasonOldImageCacheNotFound          = "OldImageCacheNotFound"
 	ImageCacheReasonNotSupportedUpdates            = "NotSupportedUpdates"
+	ImageCacheReasonJobStuck                       = "JobStuck"
+	ImageCacheReasonPolicyViolation                = "PolicyViolation"
+	ImageCacheReasonFailureThresholdExceeded       = "FailureThresholdExceeded"
+	ImageCacheReasonImageCacheCancel               = "ImageCacheCancel"
+	ImageCacheReasonPrePullHookFailed              = "PrePullHookFailed"
+	ImageCacheReasonPostPullHookFailed             = "PostPullHookFailed"
+	ImageCacheReasonRegistryCircuitOpen            = "RegistryCircuitOpen"
+	ImageCacheReasonNodeOverloaded                 = "NodeOverloaded"
+	ImageCacheReasonImageDigestMismatch            = "ImageDigestMismatch"
+)
+
+// List of constants for ImageCacheRequestReason
+const (
+	ImageCacheRequestReasonImageCacheCreateFailed = "ImageCacheCreateFailed"
+	ImageCacheRequestReasonImageCacheDeleted      = "ImageCacheDeleted"
 )
 
 // List of constants for ImageCacheMessage
@@ -113,6 +914,7 @@ const (
 	ImageCacheMessageUpdatingCache                  = "Image cache is being updated. Please view the status after some time"
 	ImageCacheMessageRefreshingCache                = "Image cache is being refreshed. Please view the status after some time"
 	ImageCacheMessagePurgeCache                     = "Image cache is being purged. Please view the status after some time"
+	ImageCacheMessagePrePullHooksPending            = "Pre-pull hooks are running. Images will be pulled or deleted once they succeed"
 	ImageCacheMessageDeletingImages                 = "Images in the cache are being deleted. Please view the status after some time"
 	ImageCacheMessageImagesPulledSuccessfully       = "All requested images pulled succesfully to respective nodes"
 	ImageCacheMessageImagesDeletedSuccessfully      = "All cached images succesfully deleted from respective nodes"
@@ -23,7 +23,9 @@ package v1alpha2
 
 import (
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	intstr "k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -34,6 +36,11 @@ func (in *CacheSpecImages) DeepCopyInto(out *CacheSpecImages) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Artifacts != nil {
+		in, out := &in.Artifacts, &out.Artifacts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.NodeSelector != nil {
 		in, out := &in.NodeSelector, &out.NodeSelector
 		*out = make(map[string]string, len(*in))
@@ -41,6 +48,71 @@ func (in *CacheSpecImages) DeepCopyInto(out *CacheSpecImages) {
 			(*out)[key] = val
 		}
 	}
+	if in.Platforms != nil {
+		in, out := &in.Platforms, &out.Platforms
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ImagePullPolicies != nil {
+		in, out := &in.ImagePullPolicies, &out.ImagePullPolicies
+		*out = make(map[string]v1.PullPolicy, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Fallbacks != nil {
+		in, out := &in.Fallbacks, &out.Fallbacks
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.TargetRefs != nil {
+		in, out := &in.TargetRefs, &out.TargetRefs
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DisplayTags != nil {
+		in, out := &in.DisplayTags, &out.DisplayTags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.TrackedRepositories != nil {
+		in, out := &in.TrackedRepositories, &out.TrackedRepositories
+		*out = make([]TrackedRepository, len(*in))
+		copy(*out, *in)
+	}
+	if in.Catalogs != nil {
+		in, out := &in.Catalogs, &out.Catalogs
+		*out = make([]RegistryCatalog, len(*in))
+		copy(*out, *in)
+	}
+	if in.Providers != nil {
+		in, out := &in.Providers, &out.Providers
+		*out = make([]ImageListProviderSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RefreshInterval != nil {
+		in, out := &in.RefreshInterval, &out.RefreshInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 	return
 }
 
@@ -55,27 +127,88 @@ func (in *CacheSpecImages) DeepCopy() *CacheSpecImages {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageCache) DeepCopyInto(out *ImageCache) {
+func (in *ConfigMapImageList) DeepCopyInto(out *ConfigMapImageList) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapImageList.
+func (in *ConfigMapImageList) DeepCopy() *ConfigMapImageList {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapImageList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeferredNode) DeepCopyInto(out *DeferredNode) {
+	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NextAttempt != nil {
+		in, out := &in.NextAttempt, &out.NextAttempt
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeferredNode.
+func (in *DeferredNode) DeepCopy() *DeferredNode {
+	if in == nil {
+		return nil
+	}
+	out := new(DeferredNode)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExecHook) DeepCopyInto(out *ExecHook) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecHook.
+func (in *ExecHook) DeepCopy() *ExecHook {
+	if in == nil {
+		return nil
+	}
+	out := new(ExecHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FledgedPolicy) DeepCopyInto(out *FledgedPolicy) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCache.
-func (in *ImageCache) DeepCopy() *ImageCache {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FledgedPolicy.
+func (in *FledgedPolicy) DeepCopy() *FledgedPolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageCache)
+	out := new(FledgedPolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ImageCache) DeepCopyObject() runtime.Object {
+func (in *FledgedPolicy) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -83,13 +216,34 @@ func (in *ImageCache) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageCacheList) DeepCopyInto(out *ImageCacheList) {
+func (in *FledgedPolicyDefaults) DeepCopyInto(out *FledgedPolicyDefaults) {
+	*out = *in
+	if in.ImagePullDeadline != nil {
+		in, out := &in.ImagePullDeadline, &out.ImagePullDeadline
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FledgedPolicyDefaults.
+func (in *FledgedPolicyDefaults) DeepCopy() *FledgedPolicyDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(FledgedPolicyDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FledgedPolicyList) DeepCopyInto(out *FledgedPolicyList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]ImageCache, len(*in))
+		*out = make([]FledgedPolicy, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -97,18 +251,18 @@ func (in *ImageCacheList) DeepCopyInto(out *ImageCacheList) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheList.
-func (in *ImageCacheList) DeepCopy() *ImageCacheList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FledgedPolicyList.
+func (in *FledgedPolicyList) DeepCopy() *FledgedPolicyList {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageCacheList)
+	out := new(FledgedPolicyList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ImageCacheList) DeepCopyObject() runtime.Object {
+func (in *FledgedPolicyList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -116,104 +270,1433 @@ func (in *ImageCacheList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageCacheSpec) DeepCopyInto(out *ImageCacheSpec) {
+func (in *FledgedPolicySpec) DeepCopyInto(out *FledgedPolicySpec) {
 	*out = *in
-	if in.CacheSpec != nil {
-		in, out := &in.CacheSpec, &out.CacheSpec
-		*out = make([]CacheSpecImages, len(*in))
+	if in.Defaults != nil {
+		in, out := &in.Defaults, &out.Defaults
+		*out = new(FledgedPolicyDefaults)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AllowedRegistries != nil {
+		in, out := &in.AllowedRegistries, &out.AllowedRegistries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RestrictedNodeSelectors != nil {
+		in, out := &in.RestrictedNodeSelectors, &out.RestrictedNodeSelectors
+		*out = make([]RestrictedNodeSelector, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.ImagePullSecrets != nil {
-		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
-		*out = make([]v1.LocalObjectReference, len(*in))
+	if in.NamespaceNodeSelectors != nil {
+		in, out := &in.NamespaceNodeSelectors, &out.NamespaceNodeSelectors
+		*out = make([]NamespaceNodeSelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AllowedServiceAccounts != nil {
+		in, out := &in.AllowedServiceAccounts, &out.AllowedServiceAccounts
+		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.CostBudgets != nil {
+		in, out := &in.CostBudgets, &out.CostBudgets
+		*out = make([]NamespaceCostBudget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ProviderNamespaces != nil {
+		in, out := &in.ProviderNamespaces, &out.ProviderNamespaces
+		*out = make([]ProviderNamespace, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheSpec.
-func (in *ImageCacheSpec) DeepCopy() *ImageCacheSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FledgedPolicySpec.
+func (in *FledgedPolicySpec) DeepCopy() *FledgedPolicySpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageCacheSpec)
+	out := new(FledgedPolicySpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageCacheStatus) DeepCopyInto(out *ImageCacheStatus) {
+func (in *HTTPHook) DeepCopyInto(out *HTTPHook) {
 	*out = *in
-	if in.Failures != nil {
-		in, out := &in.Failures, &out.Failures
-		*out = make(map[string]NodeReasonMessageList, len(*in))
-		for key, val := range *in {
-			var outVal []NodeReasonMessage
-			if val == nil {
-				(*out)[key] = nil
-			} else {
-				in, out := &val, &outVal
-				*out = make(NodeReasonMessageList, len(*in))
-				copy(*out, *in)
-			}
-			(*out)[key] = outVal
-		}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPHook.
+func (in *HTTPHook) DeepCopy() *HTTPHook {
+	if in == nil {
+		return nil
 	}
-	if in.StartTime != nil {
-		in, out := &in.StartTime, &out.StartTime
-		*out = (*in).DeepCopy()
+	out := new(HTTPHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseImageList) DeepCopyInto(out *HelmReleaseImageList) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseImageList.
+func (in *HelmReleaseImageList) DeepCopy() *HelmReleaseImageList {
+	if in == nil {
+		return nil
 	}
-	if in.CompletionTime != nil {
-		in, out := &in.CompletionTime, &out.CompletionTime
-		*out = (*in).DeepCopy()
+	out := new(HelmReleaseImageList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCache) DeepCopyInto(out *ImageCache) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCache.
+func (in *ImageCache) DeepCopy() *ImageCache {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageCache) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
 	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCacheDryRun) DeepCopyInto(out *ImageCacheDryRun) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	in.Plan.DeepCopyInto(&out.Plan)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheStatus.
-func (in *ImageCacheStatus) DeepCopy() *ImageCacheStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheDryRun.
+func (in *ImageCacheDryRun) DeepCopy() *ImageCacheDryRun {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageCacheStatus)
+	out := new(ImageCacheDryRun)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NodeReasonMessage) DeepCopyInto(out *NodeReasonMessage) {
+func (in *ImageCacheEstimate) DeepCopyInto(out *ImageCacheEstimate) {
 	*out = *in
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]NodeEstimate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeReasonMessage.
-func (in *NodeReasonMessage) DeepCopy() *NodeReasonMessage {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheEstimate.
+func (in *ImageCacheEstimate) DeepCopy() *ImageCacheEstimate {
 	if in == nil {
 		return nil
 	}
-	out := new(NodeReasonMessage)
+	out := new(ImageCacheEstimate)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in NodeReasonMessageList) DeepCopyInto(out *NodeReasonMessageList) {
-	{
-		in := &in
-		*out = make(NodeReasonMessageList, len(*in))
-		copy(*out, *in)
-		return
+func (in *ImageCacheHook) DeepCopyInto(out *ImageCacheHook) {
+	*out = *in
+	if in.Exec != nil {
+		in, out := &in.Exec, &out.Exec
+		*out = new(ExecHook)
+		(*in).DeepCopyInto(*out)
 	}
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(HTTPHook)
+		**out = **in
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeReasonMessageList.
-func (in NodeReasonMessageList) DeepCopy() NodeReasonMessageList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheHook.
+func (in *ImageCacheHook) DeepCopy() *ImageCacheHook {
 	if in == nil {
 		return nil
 	}
-	out := new(NodeReasonMessageList)
+	out := new(ImageCacheHook)
 	in.DeepCopyInto(out)
-	return *out
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCacheHooks) DeepCopyInto(out *ImageCacheHooks) {
+	*out = *in
+	if in.PrePull != nil {
+		in, out := &in.PrePull, &out.PrePull
+		*out = make([]ImageCacheHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PostPull != nil {
+		in, out := &in.PostPull, &out.PostPull
+		*out = make([]ImageCacheHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheHooks.
+func (in *ImageCacheHooks) DeepCopy() *ImageCacheHooks {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCacheHooks)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCacheList) DeepCopyInto(out *ImageCacheList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImageCache, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheList.
+func (in *ImageCacheList) DeepCopy() *ImageCacheList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCacheList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageCacheList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCacheOverlap) DeepCopyInto(out *ImageCacheOverlap) {
+	*out = *in
+	if in.Entries != nil {
+		in, out := &in.Entries, &out.Entries
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheOverlap.
+func (in *ImageCacheOverlap) DeepCopy() *ImageCacheOverlap {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCacheOverlap)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCachePlan) DeepCopyInto(out *ImageCachePlan) {
+	*out = *in
+	if in.Overlaps != nil {
+		in, out := &in.Overlaps, &out.Overlaps
+		*out = make([]ImageCacheOverlap, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Estimate != nil {
+		in, out := &in.Estimate, &out.Estimate
+		*out = new(ImageCacheEstimate)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCachePlan.
+func (in *ImageCachePlan) DeepCopy() *ImageCachePlan {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCachePlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCacheProgress) DeepCopyInto(out *ImageCacheProgress) {
+	*out = *in
+	if in.Pulls != nil {
+		in, out := &in.Pulls, &out.Pulls
+		*out = make([]ImagePullProgress, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheProgress.
+func (in *ImageCacheProgress) DeepCopy() *ImageCacheProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCacheProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCacheRequest) DeepCopyInto(out *ImageCacheRequest) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheRequest.
+func (in *ImageCacheRequest) DeepCopy() *ImageCacheRequest {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCacheRequest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageCacheRequest) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCacheRequestList) DeepCopyInto(out *ImageCacheRequestList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImageCacheRequest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheRequestList.
+func (in *ImageCacheRequestList) DeepCopy() *ImageCacheRequestList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCacheRequestList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageCacheRequestList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCacheRequestSpec) DeepCopyInto(out *ImageCacheRequestSpec) {
+	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.TTLSecondsAfterFinished != nil {
+		in, out := &in.TTLSecondsAfterFinished, &out.TTLSecondsAfterFinished
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheRequestSpec.
+func (in *ImageCacheRequestSpec) DeepCopy() *ImageCacheRequestSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCacheRequestSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCacheRequestStatus) DeepCopyInto(out *ImageCacheRequestStatus) {
+	*out = *in
+	if in.Failures != nil {
+		in, out := &in.Failures, &out.Failures
+		*out = make(map[string]NodeReasonMessageList, len(*in))
+		for key, val := range *in {
+			var outVal []NodeReasonMessage
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make(NodeReasonMessageList, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheRequestStatus.
+func (in *ImageCacheRequestStatus) DeepCopy() *ImageCacheRequestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCacheRequestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCacheRollout) DeepCopyInto(out *ImageCacheRollout) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheRollout.
+func (in *ImageCacheRollout) DeepCopy() *ImageCacheRollout {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCacheRollout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCacheSpec) DeepCopyInto(out *ImageCacheSpec) {
+	*out = *in
+	if in.CacheSpec != nil {
+		in, out := &in.CacheSpec, &out.CacheSpec
+		*out = make([]CacheSpecImages, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.TTLSecondsAfterFinished != nil {
+		in, out := &in.TTLSecondsAfterFinished, &out.TTLSecondsAfterFinished
+		*out = new(int32)
+		**out = **in
+	}
+	if in.UnusedImagePurge != nil {
+		in, out := &in.UnusedImagePurge, &out.UnusedImagePurge
+		*out = new(UnusedImagePurge)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WarmStandby != nil {
+		in, out := &in.WarmStandby, &out.WarmStandby
+		*out = new(WarmStandby)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FailureThreshold != nil {
+		in, out := &in.FailureThreshold, &out.FailureThreshold
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MaxBytesPerNode != nil {
+		in, out := &in.MaxBytesPerNode, &out.MaxBytesPerNode
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.SecurityProfiles != nil {
+		in, out := &in.SecurityProfiles, &out.SecurityProfiles
+		*out = new(SecurityProfiles)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CostBudget != nil {
+		in, out := &in.CostBudget, &out.CostBudget
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = new(ImageCacheHooks)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutStrategy)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheSpec.
+func (in *ImageCacheSpec) DeepCopy() *ImageCacheSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCacheSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCacheStatus) DeepCopyInto(out *ImageCacheStatus) {
+	*out = *in
+	if in.Failures != nil {
+		in, out := &in.Failures, &out.Failures
+		*out = make(map[string]NodeReasonMessageList, len(*in))
+		for key, val := range *in {
+			var outVal []NodeReasonMessage
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make(NodeReasonMessageList, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = new(ImageCachePlan)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Progress != nil {
+		in, out := &in.Progress, &out.Progress
+		*out = new(ImageCacheProgress)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Usage != nil {
+		in, out := &in.Usage, &out.Usage
+		*out = make([]ImageUsage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Deferred != nil {
+		in, out := &in.Deferred, &out.Deferred
+		*out = make([]DeferredNode, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = make(map[string][]NodeSource, len(*in))
+		for key, val := range *in {
+			var outVal []NodeSource
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]NodeSource, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.GCPressure != nil {
+		in, out := &in.GCPressure, &out.GCPressure
+		*out = make([]NodeGCPressure, len(*in))
+		copy(*out, *in)
+	}
+	if in.EntryRefreshTimes != nil {
+		in, out := &in.EntryRefreshTimes, &out.EntryRefreshTimes
+		*out = make([]metav1.Time, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ProvidedImages != nil {
+		in, out := &in.ProvidedImages, &out.ProvidedImages
+		*out = make([][]string, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+		}
+	}
+	if in.ResolvedImages != nil {
+		in, out := &in.ResolvedImages, &out.ResolvedImages
+		*out = make([][]string, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+		}
+	}
+	if in.LayerStats != nil {
+		in, out := &in.LayerStats, &out.LayerStats
+		*out = make([]LayerStats, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ImageFsUsage != nil {
+		in, out := &in.ImageFsUsage, &out.ImageFsUsage
+		*out = make([]NodeImageFsUsage, len(*in))
+		copy(*out, *in)
+	}
+	if in.DryRun != nil {
+		in, out := &in.DryRun, &out.DryRun
+		*out = new(ImageCacheDryRun)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(ImageCacheRollout)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheStatus.
+func (in *ImageCacheStatus) DeepCopy() *ImageCacheStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCacheStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCacheTemplate) DeepCopyInto(out *ImageCacheTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheTemplate.
+func (in *ImageCacheTemplate) DeepCopy() *ImageCacheTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCacheTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageCacheTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCacheTemplateList) DeepCopyInto(out *ImageCacheTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImageCacheTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheTemplateList.
+func (in *ImageCacheTemplateList) DeepCopy() *ImageCacheTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCacheTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageCacheTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCacheTemplateSpec) DeepCopyInto(out *ImageCacheTemplateSpec) {
+	*out = *in
+	in.Template.DeepCopyInto(&out.Template)
+	if in.Pools != nil {
+		in, out := &in.Pools, &out.Pools
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheTemplateSpec.
+func (in *ImageCacheTemplateSpec) DeepCopy() *ImageCacheTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCacheTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCacheTemplateStatus) DeepCopyInto(out *ImageCacheTemplateStatus) {
+	*out = *in
+	if in.ImageCaches != nil {
+		in, out := &in.ImageCaches, &out.ImageCaches
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCacheTemplateStatus.
+func (in *ImageCacheTemplateStatus) DeepCopy() *ImageCacheTemplateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCacheTemplateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageLayerStats) DeepCopyInto(out *ImageLayerStats) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageLayerStats.
+func (in *ImageLayerStats) DeepCopy() *ImageLayerStats {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageLayerStats)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageList) DeepCopyInto(out *ImageList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageList.
+func (in *ImageList) DeepCopy() *ImageList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageListList) DeepCopyInto(out *ImageListList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImageList, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageListList.
+func (in *ImageListList) DeepCopy() *ImageListList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageListList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageListList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageListProviderSpec) DeepCopyInto(out *ImageListProviderSpec) {
+	*out = *in
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(ConfigMapImageList)
+		**out = **in
+	}
+	if in.Workloads != nil {
+		in, out := &in.Workloads, &out.Workloads
+		*out = new(WorkloadImageList)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Plugin != nil {
+		in, out := &in.Plugin, &out.Plugin
+		*out = new(PluginImageList)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.System != nil {
+		in, out := &in.System, &out.System
+		*out = new(SystemImageList)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HelmRelease != nil {
+		in, out := &in.HelmRelease, &out.HelmRelease
+		*out = new(HelmReleaseImageList)
+		**out = **in
+	}
+	if in.ImageList != nil {
+		in, out := &in.ImageList, &out.ImageList
+		*out = new(ImageListReference)
+		**out = **in
+	}
+	if in.ImageStream != nil {
+		in, out := &in.ImageStream, &out.ImageStream
+		*out = new(ImageStreamImageList)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageListProviderSpec.
+func (in *ImageListProviderSpec) DeepCopy() *ImageListProviderSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageListProviderSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageListReference) DeepCopyInto(out *ImageListReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageListReference.
+func (in *ImageListReference) DeepCopy() *ImageListReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageListReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageListSpec) DeepCopyInto(out *ImageListSpec) {
+	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageListSpec.
+func (in *ImageListSpec) DeepCopy() *ImageListSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageListSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePullProgress) DeepCopyInto(out *ImagePullProgress) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePullProgress.
+func (in *ImagePullProgress) DeepCopy() *ImagePullProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePullProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageStreamImageList) DeepCopyInto(out *ImageStreamImageList) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageStreamImageList.
+func (in *ImageStreamImageList) DeepCopy() *ImageStreamImageList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageStreamImageList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageUsage) DeepCopyInto(out *ImageUsage) {
+	*out = *in
+	if in.LastUsed != nil {
+		in, out := &in.LastUsed, &out.LastUsed
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageUsage.
+func (in *ImageUsage) DeepCopy() *ImageUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LayerStats) DeepCopyInto(out *LayerStats) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]ImageLayerStats, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LayerStats.
+func (in *LayerStats) DeepCopy() *LayerStats {
+	if in == nil {
+		return nil
+	}
+	out := new(LayerStats)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceCostBudget) DeepCopyInto(out *NamespaceCostBudget) {
+	*out = *in
+	out.Budget = in.Budget.DeepCopy()
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceCostBudget.
+func (in *NamespaceCostBudget) DeepCopy() *NamespaceCostBudget {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceCostBudget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceNodeSelector) DeepCopyInto(out *NamespaceNodeSelector) {
+	*out = *in
+	if in.NodeSelectors != nil {
+		in, out := &in.NodeSelectors, &out.NodeSelectors
+		*out = make([]map[string]string, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = make(map[string]string, len(*in))
+				for key, val := range *in {
+					(*out)[key] = val
+				}
+			}
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceNodeSelector.
+func (in *NamespaceNodeSelector) DeepCopy() *NamespaceNodeSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceNodeSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeEstimate) DeepCopyInto(out *NodeEstimate) {
+	*out = *in
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeEstimate.
+func (in *NodeEstimate) DeepCopy() *NodeEstimate {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeEstimate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeGCPressure) DeepCopyInto(out *NodeGCPressure) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeGCPressure.
+func (in *NodeGCPressure) DeepCopy() *NodeGCPressure {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeGCPressure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeImageFsUsage) DeepCopyInto(out *NodeImageFsUsage) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeImageFsUsage.
+func (in *NodeImageFsUsage) DeepCopy() *NodeImageFsUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeImageFsUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeReasonMessage) DeepCopyInto(out *NodeReasonMessage) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeReasonMessage.
+func (in *NodeReasonMessage) DeepCopy() *NodeReasonMessage {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeReasonMessage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in NodeReasonMessageList) DeepCopyInto(out *NodeReasonMessageList) {
+	{
+		in := &in
+		*out = make(NodeReasonMessageList, len(*in))
+		copy(*out, *in)
+		return
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeReasonMessageList.
+func (in NodeReasonMessageList) DeepCopy() NodeReasonMessageList {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeReasonMessageList)
+	in.DeepCopyInto(out)
+	return *out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeSource) DeepCopyInto(out *NodeSource) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeSource.
+func (in *NodeSource) DeepCopy() *NodeSource {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PluginImageList) DeepCopyInto(out *PluginImageList) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PluginImageList.
+func (in *PluginImageList) DeepCopy() *PluginImageList {
+	if in == nil {
+		return nil
+	}
+	out := new(PluginImageList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderNamespace) DeepCopyInto(out *ProviderNamespace) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderNamespace.
+func (in *ProviderNamespace) DeepCopy() *ProviderNamespace {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderNamespace)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryCatalog) DeepCopyInto(out *RegistryCatalog) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryCatalog.
+func (in *RegistryCatalog) DeepCopy() *RegistryCatalog {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryCatalog)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestrictedNodeSelector) DeepCopyInto(out *RestrictedNodeSelector) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestrictedNodeSelector.
+func (in *RestrictedNodeSelector) DeepCopy() *RestrictedNodeSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(RestrictedNodeSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStrategy) DeepCopyInto(out *RolloutStrategy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStrategy.
+func (in *RolloutStrategy) DeepCopy() *RolloutStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecurityProfiles) DeepCopyInto(out *SecurityProfiles) {
+	*out = *in
+	if in.SeccompProfile != nil {
+		in, out := &in.SeccompProfile, &out.SeccompProfile
+		*out = new(v1.SeccompProfile)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecurityProfiles.
+func (in *SecurityProfiles) DeepCopy() *SecurityProfiles {
+	if in == nil {
+		return nil
+	}
+	out := new(SecurityProfiles)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SystemImageList) DeepCopyInto(out *SystemImageList) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SystemImageList.
+func (in *SystemImageList) DeepCopy() *SystemImageList {
+	if in == nil {
+		return nil
+	}
+	out := new(SystemImageList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrackedRepository) DeepCopyInto(out *TrackedRepository) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrackedRepository.
+func (in *TrackedRepository) DeepCopy() *TrackedRepository {
+	if in == nil {
+		return nil
+	}
+	out := new(TrackedRepository)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UnusedImagePurge) DeepCopyInto(out *UnusedImagePurge) {
+	*out = *in
+	out.After = in.After
+	if in.Pinned != nil {
+		in, out := &in.Pinned, &out.Pinned
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UnusedImagePurge.
+func (in *UnusedImagePurge) DeepCopy() *UnusedImagePurge {
+	if in == nil {
+		return nil
+	}
+	out := new(UnusedImagePurge)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WarmStandby) DeepCopyInto(out *WarmStandby) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WarmStandby.
+func (in *WarmStandby) DeepCopy() *WarmStandby {
+	if in == nil {
+		return nil
+	}
+	out := new(WarmStandby)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadImageList) DeepCopyInto(out *WorkloadImageList) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadImageList.
+func (in *WorkloadImageList) DeepCopy() *WorkloadImageList {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadImageList)
+	in.DeepCopyInto(out)
+	return out
 }
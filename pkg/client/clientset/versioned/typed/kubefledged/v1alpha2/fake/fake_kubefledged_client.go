@@ -28,10 +28,26 @@ type FakeKubefledgedV1alpha2 struct {
 	*testing.Fake
 }
 
+func (c *FakeKubefledgedV1alpha2) FledgedPolicies() v1alpha2.FledgedPolicyInterface {
+	return &FakeFledgedPolicies{c}
+}
+
 func (c *FakeKubefledgedV1alpha2) ImageCaches(namespace string) v1alpha2.ImageCacheInterface {
 	return &FakeImageCaches{c, namespace}
 }
 
+func (c *FakeKubefledgedV1alpha2) ImageCacheRequests(namespace string) v1alpha2.ImageCacheRequestInterface {
+	return &FakeImageCacheRequests{c, namespace}
+}
+
+func (c *FakeKubefledgedV1alpha2) ImageCacheTemplates(namespace string) v1alpha2.ImageCacheTemplateInterface {
+	return &FakeImageCacheTemplates{c, namespace}
+}
+
+func (c *FakeKubefledgedV1alpha2) ImageLists(namespace string) v1alpha2.ImageListInterface {
+	return &FakeImageLists{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeKubefledgedV1alpha2) RESTClient() rest.Interface {
@@ -18,4 +18,12 @@ limitations under the License.
 
 package v1alpha2
 
+type FledgedPolicyExpansion interface{}
+
 type ImageCacheExpansion interface{}
+
+type ImageCacheRequestExpansion interface{}
+
+type ImageCacheTemplateExpansion interface{}
+
+type ImageListExpansion interface{}
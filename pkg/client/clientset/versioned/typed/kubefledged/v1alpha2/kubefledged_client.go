@@ -28,7 +28,11 @@ import (
 
 type KubefledgedV1alpha2Interface interface {
 	RESTClient() rest.Interface
+	FledgedPoliciesGetter
 	ImageCachesGetter
+	ImageCacheRequestsGetter
+	ImageCacheTemplatesGetter
+	ImageListsGetter
 }
 
 // KubefledgedV1alpha2Client is used to interact with features provided by the kubefledged.io group.
@@ -36,10 +40,26 @@ type KubefledgedV1alpha2Client struct {
 	restClient rest.Interface
 }
 
+func (c *KubefledgedV1alpha2Client) FledgedPolicies() FledgedPolicyInterface {
+	return newFledgedPolicies(c)
+}
+
 func (c *KubefledgedV1alpha2Client) ImageCaches(namespace string) ImageCacheInterface {
 	return newImageCaches(c, namespace)
 }
 
+func (c *KubefledgedV1alpha2Client) ImageCacheRequests(namespace string) ImageCacheRequestInterface {
+	return newImageCacheRequests(c, namespace)
+}
+
+func (c *KubefledgedV1alpha2Client) ImageCacheTemplates(namespace string) ImageCacheTemplateInterface {
+	return newImageCacheTemplates(c, namespace)
+}
+
+func (c *KubefledgedV1alpha2Client) ImageLists(namespace string) ImageListInterface {
+	return newImageLists(c, namespace)
+}
+
 // NewForConfig creates a new KubefledgedV1alpha2Client for the given config.
 // NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
 // where httpClient was generated with rest.HTTPClientFor(c).
@@ -53,8 +53,16 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=kubefledged.io, Version=v1alpha2
+	case v1alpha2.SchemeGroupVersion.WithResource("fledgedpolicies"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Kubefledged().V1alpha2().FledgedPolicies().Informer()}, nil
 	case v1alpha2.SchemeGroupVersion.WithResource("imagecaches"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Kubefledged().V1alpha2().ImageCaches().Informer()}, nil
+	case v1alpha2.SchemeGroupVersion.WithResource("imagecacherequests"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Kubefledged().V1alpha2().ImageCacheRequests().Informer()}, nil
+	case v1alpha2.SchemeGroupVersion.WithResource("imagecachetemplates"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Kubefledged().V1alpha2().ImageCacheTemplates().Informer()}, nil
+	case v1alpha2.SchemeGroupVersion.WithResource("imagelists"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Kubefledged().V1alpha2().ImageLists().Informer()}, nil
 
 	}
 
@@ -24,8 +24,16 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// FledgedPolicies returns a FledgedPolicyInformer.
+	FledgedPolicies() FledgedPolicyInformer
 	// ImageCaches returns a ImageCacheInformer.
 	ImageCaches() ImageCacheInformer
+	// ImageCacheRequests returns a ImageCacheRequestInformer.
+	ImageCacheRequests() ImageCacheRequestInformer
+	// ImageCacheTemplates returns a ImageCacheTemplateInformer.
+	ImageCacheTemplates() ImageCacheTemplateInformer
+	// ImageLists returns a ImageListInformer.
+	ImageLists() ImageListInformer
 }
 
 type version struct {
@@ -39,7 +47,27 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// FledgedPolicies returns a FledgedPolicyInformer.
+func (v *version) FledgedPolicies() FledgedPolicyInformer {
+	return &fledgedPolicyInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // ImageCaches returns a ImageCacheInformer.
 func (v *version) ImageCaches() ImageCacheInformer {
 	return &imageCacheInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// ImageCacheRequests returns a ImageCacheRequestInformer.
+func (v *version) ImageCacheRequests() ImageCacheRequestInformer {
+	return &imageCacheRequestInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// ImageCacheTemplates returns a ImageCacheTemplateInformer.
+func (v *version) ImageCacheTemplates() ImageCacheTemplateInformer {
+	return &imageCacheTemplateInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// ImageLists returns a ImageListInformer.
+func (v *version) ImageLists() ImageListInformer {
+	return &imageListInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
@@ -18,6 +18,10 @@ limitations under the License.
 
 package v1alpha2
 
+// FledgedPolicyListerExpansion allows custom methods to be added to
+// FledgedPolicyLister.
+type FledgedPolicyListerExpansion interface{}
+
 // ImageCacheListerExpansion allows custom methods to be added to
 // ImageCacheLister.
 type ImageCacheListerExpansion interface{}
@@ -25,3 +29,27 @@ type ImageCacheListerExpansion interface{}
 // ImageCacheNamespaceListerExpansion allows custom methods to be added to
 // ImageCacheNamespaceLister.
 type ImageCacheNamespaceListerExpansion interface{}
+
+// ImageCacheRequestListerExpansion allows custom methods to be added to
+// ImageCacheRequestLister.
+type ImageCacheRequestListerExpansion interface{}
+
+// ImageCacheRequestNamespaceListerExpansion allows custom methods to be added to
+// ImageCacheRequestNamespaceLister.
+type ImageCacheRequestNamespaceListerExpansion interface{}
+
+// ImageCacheTemplateListerExpansion allows custom methods to be added to
+// ImageCacheTemplateLister.
+type ImageCacheTemplateListerExpansion interface{}
+
+// ImageCacheTemplateNamespaceListerExpansion allows custom methods to be added to
+// ImageCacheTemplateNamespaceLister.
+type ImageCacheTemplateNamespaceListerExpansion interface{}
+
+// ImageListListerExpansion allows custom methods to be added to
+// ImageListLister.
+type ImageListListerExpansion interface{}
+
+// ImageListNamespaceListerExpansion allows custom methods to be added to
+// ImageListNamespaceLister.
+type ImageListNamespaceListerExpansion interface{}
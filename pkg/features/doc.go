@@ -0,0 +1,21 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package features defines the feature gates of kube-fledged. Feature gates guard
+// subsystems by maturity, following the conventions of Kubernetes: alpha features are
+// disabled by default and users opt in to them with the --feature-gates flag, beta
+// features are enabled by default and may be disabled.
+package features
@@ -0,0 +1,65 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/component-base/featuregate"
+)
+
+const (
+	// ContainerdPull enables pulling images with ctr on containerd nodes, selected by
+	// --pull-strategy=containerd
+	ContainerdPull featuregate.Feature = "ContainerdPull"
+	// RegistryWebhook enables the endpoint refreshing image caches on registry push
+	// notifications, served on --registry-webhook-addr
+	RegistryWebhook featuregate.Feature = "RegistryWebhook"
+	// LayerStats enables reporting the layers shared by the images of image caches,
+	// selected by --layer-stats
+	LayerStats featuregate.Feature = "LayerStats"
+	// PullHooks enables the pre-pull and post-pull hooks of image caches
+	PullHooks featuregate.Feature = "PullHooks"
+	// LoadThrottling enables deferring the image pulls to loaded nodes, selected by the
+	// --load-throttle-* flags
+	LoadThrottling featuregate.Feature = "LoadThrottling"
+)
+
+// DefaultMutableFeatureGate is the feature gate of kube-fledged, set by the
+// --feature-gates flag
+var DefaultMutableFeatureGate featuregate.MutableFeatureGate = featuregate.NewFeatureGate()
+
+// DefaultFeatureGate is the read-only view of the feature gate of kube-fledged
+var DefaultFeatureGate featuregate.FeatureGate = DefaultMutableFeatureGate
+
+// defaultFeatureGates are the known feature gates of kube-fledged. New features are
+// added as alpha, disabled by default.
+var defaultFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
+	ContainerdPull:  {Default: false, PreRelease: featuregate.Alpha},
+	RegistryWebhook: {Default: false, PreRelease: featuregate.Alpha},
+	LayerStats:      {Default: false, PreRelease: featuregate.Alpha},
+	PullHooks:       {Default: false, PreRelease: featuregate.Alpha},
+	LoadThrottling:  {Default: false, PreRelease: featuregate.Alpha},
+}
+
+func init() {
+	runtime.Must(DefaultMutableFeatureGate.Add(defaultFeatureGates))
+}
+
+// Enabled returns true if the feature is enabled
+func Enabled(feature featuregate.Feature) bool {
+	return DefaultFeatureGate.Enabled(feature)
+}
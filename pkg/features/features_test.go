@@ -0,0 +1,80 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"testing"
+
+	"k8s.io/component-base/featuregate"
+)
+
+func TestFeatureGates(t *testing.T) {
+	tests := []struct {
+		name            string
+		gates           string
+		expectedEnabled map[featuregate.Feature]bool
+		expectErr       bool
+	}{
+		{
+			name:            "#1: Defaults",
+			gates:           "",
+			expectedEnabled: map[featuregate.Feature]bool{ContainerdPull: false, RegistryWebhook: false, LayerStats: false, PullHooks: false, LoadThrottling: false},
+		},
+		{
+			name:            "#2: Alpha features enabled",
+			gates:           "ContainerdPull=true,LayerStats=true",
+			expectedEnabled: map[featuregate.Feature]bool{ContainerdPull: true, RegistryWebhook: false, LayerStats: true},
+		},
+		{
+			name:            "#3: Alpha feature enabled and disabled",
+			gates:           "PullHooks=true,LayerStats=false",
+			expectedEnabled: map[featuregate.Feature]bool{PullHooks: true, LayerStats: false},
+		},
+		{
+			name:      "#4: Unknown feature",
+			gates:     "Teleport=true",
+			expectErr: true,
+		},
+		{
+			name:      "#5: Invalid value",
+			gates:     "LayerStats=maybe",
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		gate := featuregate.NewFeatureGate()
+		if err := gate.Add(defaultFeatureGates); err != nil {
+			t.Fatalf("Error adding feature gates: %v", err)
+		}
+		err := gate.Set(test.gates)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("Test: %s failed: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test: %s failed: unexpected error: %v", test.name, err)
+			continue
+		}
+		for feature, expected := range test.expectedEnabled {
+			if gate.Enabled(feature) != expected {
+				t.Errorf("Test: %s failed: expected %s enabled=%t", test.name, feature, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,21 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package imagelist provides the sources of truth of the images of image lists, e.g.
+// a ConfigMap, the pods of workloads, the system components of the cluster or an
+// external plugin. The images listed by the providers of an image list are added to it
+// by the controller.
+package imagelist
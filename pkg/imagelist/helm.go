@@ -0,0 +1,175 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagelist
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes"
+)
+
+// helmReleaseKey is the key of the release in the data of a Helm release Secret
+const helmReleaseKey = "release"
+
+// containerListKeys are the fields of the manifests of Kubernetes objects listing
+// containers, e.g. in the pod templates of workloads or in the specs of pods
+var containerListKeys = []string{"initContainers", "containers", "ephemeralContainers"}
+
+// helmRelease is the part of a Helm release stored in a release Secret read by the
+// provider
+type helmRelease struct {
+	Manifest string `json:"manifest"`
+}
+
+// helmReleaseProvider lists the images of the manifest of a Helm release
+type helmReleaseProvider struct {
+	kubeclientset kubernetes.Interface
+	namespace     string
+	name          string
+}
+
+// NewHelmReleaseProvider returns an ImageListProvider listing the images of the
+// containers of the manifest of the deployed revision of a Helm release. The release is
+// read from the release Secret stored by Helm in the namespace of the release.
+func NewHelmReleaseProvider(kubeclientset kubernetes.Interface, namespace, name string) ImageListProvider {
+	return &helmReleaseProvider{kubeclientset: kubeclientset, namespace: namespace, name: name}
+}
+
+func (p *helmReleaseProvider) ListImages() ([]string, error) {
+	secret, err := p.deployedRevision()
+	if err != nil {
+		return nil, err
+	}
+	release, err := decodeHelmRelease(secret.Data[helmReleaseKey])
+	if err != nil {
+		return nil, fmt.Errorf("error decoding Helm release Secret %s/%s: %v", p.namespace, secret.Name, err)
+	}
+	images, err := ManifestImages(release.Manifest)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing manifest of Helm release %s/%s: %v", p.namespace, p.name, err)
+	}
+	return images, nil
+}
+
+// deployedRevision returns the release Secret of the deployed revision of the release.
+// If several revisions are labelled deployed, the latest one is returned.
+func (p *helmReleaseProvider) deployedRevision() (*corev1.Secret, error) {
+	selector := fmt.Sprintf("owner=helm,name=%s,status=deployed", p.name)
+	secrets, err := p.kubeclientset.CoreV1().Secrets(p.namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("error listing Secrets of Helm release %s/%s: %v", p.namespace, p.name, err)
+	}
+	var deployed *corev1.Secret
+	latest := -1
+	for k := range secrets.Items {
+		version, err := strconv.Atoi(secrets.Items[k].Labels["version"])
+		if err != nil {
+			continue
+		}
+		if version > latest {
+			deployed = &secrets.Items[k]
+			latest = version
+		}
+	}
+	if deployed == nil {
+		return nil, fmt.Errorf("no deployed revision of Helm release %s/%s found", p.namespace, p.name)
+	}
+	return deployed, nil
+}
+
+// decodeHelmRelease decodes a release stored by Helm, which is base64 encoded JSON,
+// gzipped unless stored by old versions of Helm
+func decodeHelmRelease(data []byte) (*helmRelease, error) {
+	decoded, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(decoded, []byte{0x1f, 0x8b}) {
+		reader, err := gzip.NewReader(bytes.NewReader(decoded))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		if decoded, err = io.ReadAll(reader); err != nil {
+			return nil, err
+		}
+	}
+	release := &helmRelease{}
+	if err := json.Unmarshal(decoded, release); err != nil {
+		return nil, err
+	}
+	return release, nil
+}
+
+// ManifestImages returns the images of the containers of the Kubernetes objects of a
+// multi-document YAML manifest, sorted and without duplicates. Containers are found
+// wherever the objects list them, so that the images of custom resources embedding pod
+// templates are listed too.
+func ManifestImages(manifest string) ([]string, error) {
+	images := []string{}
+	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(manifest), 4096)
+	for {
+		var object interface{}
+		if err := decoder.Decode(&object); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		images = appendManifestImages(images, object)
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+// appendManifestImages appends the images of the containers listed within a decoded
+// manifest node which are not yet in images
+func appendManifestImages(images []string, node interface{}) []string {
+	switch node := node.(type) {
+	case map[string]interface{}:
+		for _, key := range containerListKeys {
+			containers, _ := node[key].([]interface{})
+			for _, container := range containers {
+				if c, ok := container.(map[string]interface{}); ok {
+					if image, ok := c["image"].(string); ok && image != "" && !containsString(images, image) {
+						images = append(images, image)
+					}
+				}
+			}
+		}
+		for _, value := range node {
+			images = appendManifestImages(images, value)
+		}
+	case []interface{}:
+		for _, value := range node {
+			images = appendManifestImages(images, value)
+		}
+	}
+	return images
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagelist
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+const webManifest = `---
+# Source: web/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: 80
+---
+# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: migrate
+        image: registry.internal/web-migrations:1.4.0
+      containers:
+      - name: web
+        image: registry.internal/web:1.4.0
+      - name: proxy
+        image: envoyproxy/envoy:v1.27.0
+---
+# Source: web/templates/cronjob.yaml
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: web-cleanup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: cleanup
+            image: registry.internal/web:1.4.0
+`
+
+// helmReleaseSecret returns a release Secret of a revision of a Helm release, encoded
+// as stored by Helm
+func helmReleaseSecret(t *testing.T, name string, version int, status, manifest string, compress bool) *corev1.Secret {
+	data, err := json.Marshal(map[string]interface{}{"name": name, "version": version, "manifest": manifest})
+	if err != nil {
+		t.Fatalf("error encoding Helm release: %v", err)
+	}
+	if compress {
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		writer.Write(data)
+		writer.Close()
+		data = buf.Bytes()
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sh.helm.release.v1." + name + ".v" + strconv.Itoa(version),
+			Namespace: "apps",
+			Labels:    map[string]string{"owner": "helm", "name": name, "status": status, "version": strconv.Itoa(version)},
+		},
+		Type: "helm.sh/release.v1",
+		Data: map[string][]byte{helmReleaseKey: []byte(base64.StdEncoding.EncodeToString(data))},
+	}
+}
+
+func TestHelmReleaseProvider(t *testing.T) {
+	upgraded := strings.ReplaceAll(webManifest, "1.4.0", "1.5.0")
+	kubeclientset := fakeclientset.NewSimpleClientset(
+		helmReleaseSecret(t, "web", 1, "superseded", webManifest, true),
+		helmReleaseSecret(t, "web", 2, "deployed", upgraded, true),
+		helmReleaseSecret(t, "legacy", 1, "deployed", webManifest, false),
+		helmReleaseSecret(t, "failed", 1, "failed", webManifest, true),
+	)
+
+	tests := []struct {
+		name                string
+		release             string
+		expectedImages      []string
+		expectedErrorString string
+	}{
+		{
+			name:    "#1: Images of the deployed revision",
+			release: "web",
+			expectedImages: []string{"envoyproxy/envoy:v1.27.0", "registry.internal/web-migrations:1.5.0",
+				"registry.internal/web:1.5.0"},
+		},
+		{
+			name:    "#2: Uncompressed release",
+			release: "legacy",
+			expectedImages: []string{"envoyproxy/envoy:v1.27.0", "registry.internal/web-migrations:1.4.0",
+				"registry.internal/web:1.4.0"},
+		},
+		{
+			name:                "#3: Release without deployed revision",
+			release:             "failed",
+			expectedErrorString: "no deployed revision of Helm release apps/failed found",
+		},
+		{
+			name:                "#4: Release not installed",
+			release:             "db",
+			expectedErrorString: "no deployed revision of Helm release apps/db found",
+		},
+	}
+	for _, test := range tests {
+		images, err := NewHelmReleaseProvider(kubeclientset, "apps", test.release).ListImages()
+		if test.expectedErrorString != "" {
+			if err == nil || !strings.Contains(err.Error(), test.expectedErrorString) {
+				t.Errorf("Test: %s failed: expected error %q, actual %v", test.name, test.expectedErrorString, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test: %s failed: unexpected error: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(images, test.expectedImages) {
+			t.Errorf("Test: %s failed: expected images %v, actual %v", test.name, test.expectedImages, images)
+		}
+	}
+}
+
+func TestManifestImages(t *testing.T) {
+	tests := []struct {
+		name           string
+		manifest       string
+		expectedImages []string
+		expectError    bool
+	}{
+		{
+			name:           "#1: Empty manifest",
+			manifest:       "",
+			expectedImages: []string{},
+		},
+		{
+			name: "#2: Pod template of custom resource",
+			manifest: `apiVersion: monitoring.coreos.com/v1
+kind: Alertmanager
+spec:
+  containers:
+  - name: config-reloader
+    image: quay.io/prometheus-operator/prometheus-config-reloader:v0.68.0
+`,
+			expectedImages: []string{"quay.io/prometheus-operator/prometheus-config-reloader:v0.68.0"},
+		},
+		{
+			name:        "#3: Invalid manifest",
+			manifest:    "kind: [Deployment",
+			expectError: true,
+		},
+	}
+	for _, test := range tests {
+		images, err := ManifestImages(test.manifest)
+		if test.expectError {
+			if err == nil {
+				t.Errorf("Test: %s failed: expected error, actual none", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test: %s failed: unexpected error: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(images, test.expectedImages) {
+			t.Errorf("Test: %s failed: expected images %v, actual %v", test.name, test.expectedImages, images)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagelist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+// imageStream is the part of an OpenShift ImageStream read by the provider
+type imageStream struct {
+	Status struct {
+		Tags []struct {
+			Tag   string `json:"tag"`
+			Items []struct {
+				DockerImageReference string `json:"dockerImageReference"`
+			} `json:"items"`
+		} `json:"tags"`
+	} `json:"status"`
+}
+
+// imageStreamProvider lists the images of the tags of an OpenShift ImageStream
+type imageStreamProvider struct {
+	restClient rest.Interface
+	namespace  string
+	name       string
+	tags       []string
+	timeout    time.Duration
+}
+
+// NewImageStreamProvider returns an ImageListProvider listing the images the tags of an
+// OpenShift ImageStream currently point to, as pull specs pinned to their digest. The
+// ImageStream is read from the image.openshift.io API group using a REST client rooted
+// at the API server, e.g. the REST client of the discovery client. All the tags are
+// listed if no tags are given.
+func NewImageStreamProvider(restClient rest.Interface, namespace, name string, tags []string, timeout time.Duration) ImageListProvider {
+	return &imageStreamProvider{restClient: restClient, namespace: namespace, name: name, tags: tags, timeout: timeout}
+}
+
+func (p *imageStreamProvider) ListImages() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	raw, err := p.restClient.Get().AbsPath("/apis/image.openshift.io/v1/namespaces", p.namespace, "imagestreams", p.name).Do(ctx).Raw()
+	if err != nil {
+		return nil, fmt.Errorf("error getting ImageStream %s/%s: %v", p.namespace, p.name, err)
+	}
+	stream := imageStream{}
+	if err := json.Unmarshal(raw, &stream); err != nil {
+		return nil, fmt.Errorf("error decoding ImageStream %s/%s: %v", p.namespace, p.name, err)
+	}
+	// The first item of the history of a tag is the image the tag currently points to
+	current := map[string]string{}
+	order := []string{}
+	for _, tag := range stream.Status.Tags {
+		if len(tag.Items) == 0 || tag.Items[0].DockerImageReference == "" {
+			continue
+		}
+		current[tag.Tag] = tag.Items[0].DockerImageReference
+		order = append(order, tag.Tag)
+	}
+	if len(p.tags) > 0 {
+		order = p.tags
+	}
+	images := []string{}
+	for _, tag := range order {
+		image, ok := current[tag]
+		if !ok {
+			return nil, fmt.Errorf("tag %s of ImageStream %s/%s not found or not imported", tag, p.namespace, p.name)
+		}
+		if !containsString(images, image) {
+			images = append(images, image)
+		}
+	}
+	return images, nil
+}
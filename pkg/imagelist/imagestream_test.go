@@ -0,0 +1,98 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagelist
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest/fake"
+)
+
+func TestImageStreamProvider(t *testing.T) {
+	stream := `{"kind":"ImageStream","apiVersion":"image.openshift.io/v1","metadata":{"name":"app","namespace":"team"},"status":{"tags":[` +
+		`{"tag":"v2","items":[{"dockerImageReference":"image-registry.openshift-image-registry.svc:5000/team/app@sha256:2222","image":"sha256:2222"},` +
+		`{"dockerImageReference":"image-registry.openshift-image-registry.svc:5000/team/app@sha256:1111","image":"sha256:1111"}]},` +
+		`{"tag":"latest","items":[{"dockerImageReference":"image-registry.openshift-image-registry.svc:5000/team/app@sha256:2222","image":"sha256:2222"}]},` +
+		`{"tag":"v3","items":[]}]}}`
+	tests := []struct {
+		name           string
+		imageStream    string
+		tags           []string
+		status         int
+		expectedImages []string
+		expectErr      bool
+	}{
+		{
+			name:           "#1: Images of all the tags",
+			imageStream:    "app",
+			status:         http.StatusOK,
+			expectedImages: []string{"image-registry.openshift-image-registry.svc:5000/team/app@sha256:2222"},
+		},
+		{
+			name:           "#2: Images of given tags",
+			imageStream:    "app",
+			tags:           []string{"latest"},
+			status:         http.StatusOK,
+			expectedImages: []string{"image-registry.openshift-image-registry.svc:5000/team/app@sha256:2222"},
+		},
+		{
+			name:        "#3: Tag not imported",
+			imageStream: "app",
+			tags:        []string{"v2", "v3"},
+			status:      http.StatusOK,
+			expectErr:   true,
+		},
+		{
+			name:        "#4: ImageStream not found",
+			imageStream: "db",
+			status:      http.StatusNotFound,
+			expectErr:   true,
+		},
+	}
+	for _, test := range tests {
+		restClient := &fake.RESTClient{
+			NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+			GroupVersion:         schema.GroupVersion{Group: "image.openshift.io", Version: "v1"},
+			Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path != "/apis/image.openshift.io/v1/namespaces/team/imagestreams/"+test.imageStream {
+					t.Errorf("Test: %s failed: unexpected path %s", test.name, req.URL.Path)
+				}
+				body := stream
+				if test.status != http.StatusOK {
+					body = `{}`
+				}
+				return &http.Response{StatusCode: test.status, Header: http.Header{"Content-Type": []string{"application/json"}},
+					Body: io.NopCloser(bytes.NewBufferString(body))}, nil
+			}),
+		}
+		images, err := NewImageStreamProvider(restClient, "team", test.imageStream, test.tags, time.Second).ListImages()
+		if (err != nil) != test.expectErr {
+			t.Errorf("Test: %s failed: expectErr=%t, actual error=%v", test.name, test.expectErr, err)
+			continue
+		}
+		if !test.expectErr && !reflect.DeepEqual(images, test.expectedImages) {
+			t.Errorf("Test: %s failed: expected images %v, actual %v", test.name, test.expectedImages, images)
+		}
+	}
+}
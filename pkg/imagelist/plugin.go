@@ -0,0 +1,86 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagelist
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// PluginListImagesMethod is the gRPC method served by image list plugins. Its request
+// and response are google.protobuf.Struct messages, so that plugins need no generated
+// code from kube-fledged: see imagelist.proto.
+const PluginListImagesMethod = "/kubefledged.imagelist.v1.ImageListProvider/ListImages"
+
+// pluginProvider lists the images returned by an external gRPC plugin
+type pluginProvider struct {
+	address    string
+	imageCache string
+	parameters map[string]string
+	timeout    time.Duration
+	dialOpts   []grpc.DialOption
+}
+
+// NewPluginProvider returns an ImageListProvider listing the images returned by the
+// gRPC plugin served at an address, e.g. "catalog.tools.svc:9000" or
+// "unix:///var/run/plugin.sock". The plugin is called with the namespace/name of the
+// image cache and the parameters of the provider.
+func NewPluginProvider(address, imageCache string, parameters map[string]string, timeout time.Duration) ImageListProvider {
+	return &pluginProvider{address: address, imageCache: imageCache, parameters: parameters, timeout: timeout,
+		dialOpts: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}}
+}
+
+func (p *pluginProvider) ListImages() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, p.address, p.dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to image list plugin %s: %v", p.address, err)
+	}
+	defer conn.Close()
+
+	parameters := map[string]interface{}{}
+	for k, v := range p.parameters {
+		parameters[k] = v
+	}
+	request, err := structpb.NewStruct(map[string]interface{}{"imageCache": p.imageCache, "parameters": parameters})
+	if err != nil {
+		return nil, err
+	}
+	response := &structpb.Struct{}
+	if err := conn.Invoke(ctx, PluginListImagesMethod, request, response); err != nil {
+		return nil, fmt.Errorf("error calling image list plugin %s: %v", p.address, err)
+	}
+	list := response.GetFields()["images"].GetListValue()
+	if list == nil {
+		return nil, fmt.Errorf("image list plugin %s returned no images list", p.address)
+	}
+	images := []string{}
+	for _, v := range list.GetValues() {
+		image, ok := v.GetKind().(*structpb.Value_StringValue)
+		if !ok {
+			return nil, fmt.Errorf("image list plugin %s returned a non string image %v", p.address, v)
+		}
+		images = append(images, image.StringValue)
+	}
+	return images, nil
+}
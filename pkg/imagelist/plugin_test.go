@@ -0,0 +1,103 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagelist
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// startFakePlugin serves an image list plugin returning the response for the request
+// it receives
+func startFakePlugin(t *testing.T, respond func(request *structpb.Struct) (*structpb.Struct, error)) *bufconn.Listener {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "kubefledged.imagelist.v1.ImageListProvider",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "ListImages",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				request := &structpb.Struct{}
+				if err := dec(request); err != nil {
+					return nil, err
+				}
+				return respond(request)
+			},
+		}},
+	}, struct{}{})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener
+}
+
+func TestPluginProvider(t *testing.T) {
+	tests := []struct {
+		name           string
+		response       map[string]interface{}
+		expectedImages []string
+		expectErr      bool
+	}{
+		{
+			name:           "#1: Images returned by the plugin",
+			response:       map[string]interface{}{"images": []interface{}{"nginx:1.23", "httpd:2.4"}},
+			expectedImages: []string{"nginx:1.23", "httpd:2.4"},
+		},
+		{
+			name:      "#2: No images list",
+			response:  map[string]interface{}{"tags": []interface{}{"1.23"}},
+			expectErr: true,
+		},
+		{
+			name:      "#3: Non string image",
+			response:  map[string]interface{}{"images": []interface{}{1.23}},
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		var received *structpb.Struct
+		listener := startFakePlugin(t, func(request *structpb.Struct) (*structpb.Struct, error) {
+			received = request
+			return structpb.NewStruct(test.response)
+		})
+		provider := NewPluginProvider("bufnet", "team/release", map[string]string{"release": "1.2"}, time.Second*5).(*pluginProvider)
+		provider.dialOpts = []grpc.DialOption{
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		}
+		images, err := provider.ListImages()
+		if (err != nil) != test.expectErr {
+			t.Errorf("Test: %s failed: expectErr=%t, actual error=%v", test.name, test.expectErr, err)
+			continue
+		}
+		if !test.expectErr && !reflect.DeepEqual(images, test.expectedImages) {
+			t.Errorf("Test: %s failed: expected images %v, actual %v", test.name, test.expectedImages, images)
+		}
+		if received.GetFields()["imageCache"].GetStringValue() != "team/release" ||
+			received.GetFields()["parameters"].GetStructValue().GetFields()["release"].GetStringValue() != "1.2" {
+			t.Errorf("Test: %s failed: unexpected request %v", test.name, received)
+		}
+	}
+}
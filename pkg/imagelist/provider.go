@@ -0,0 +1,162 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagelist
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ImageListProvider lists the images an image list should contain
+type ImageListProvider interface {
+	ListImages() ([]string, error)
+}
+
+// staticProvider lists a fixed list of images
+type staticProvider struct {
+	images []string
+}
+
+// NewStaticProvider returns an ImageListProvider listing a fixed list of images
+func NewStaticProvider(images []string) ImageListProvider {
+	return &staticProvider{images: images}
+}
+
+func (p *staticProvider) ListImages() ([]string, error) {
+	return p.images, nil
+}
+
+// configMapProvider lists the images of a key of a ConfigMap
+type configMapProvider struct {
+	kubeclientset kubernetes.Interface
+	namespace     string
+	name          string
+	key           string
+}
+
+// NewConfigMapProvider returns an ImageListProvider listing the images of a key of a
+// ConfigMap, one image per line. Empty lines and lines starting with # are ignored.
+func NewConfigMapProvider(kubeclientset kubernetes.Interface, namespace, name, key string) ImageListProvider {
+	return &configMapProvider{kubeclientset: kubeclientset, namespace: namespace, name: name, key: key}
+}
+
+func (p *configMapProvider) ListImages() ([]string, error) {
+	configMap, err := p.kubeclientset.CoreV1().ConfigMaps(p.namespace).Get(context.TODO(), p.name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting ConfigMap %s/%s: %v", p.namespace, p.name, err)
+	}
+	data, ok := configMap.Data[p.key]
+	if !ok {
+		return nil, fmt.Errorf("key %s not found in ConfigMap %s/%s", p.key, p.namespace, p.name)
+	}
+	images := []string{}
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || containsString(images, line) {
+			continue
+		}
+		images = append(images, line)
+	}
+	return images, nil
+}
+
+// ContainerSelection selects the containers of a pod whose images are listed, in
+// addition to its regular containers
+type ContainerSelection struct {
+	// InitContainers selects the init containers, whose images are frequently the
+	// actual bottleneck of the start of pods
+	InitContainers bool
+	// EphemeralContainers selects the ephemeral containers, e.g. the debug containers
+	// added by kubectl debug
+	EphemeralContainers bool
+}
+
+// PodSpecImages returns the images of the containers of a pod spec selected by the
+// container selection, without duplicates
+func PodSpecImages(spec corev1.PodSpec, selection ContainerSelection) []string {
+	images := []string{}
+	add := func(image string) {
+		if image != "" && !containsString(images, image) {
+			images = append(images, image)
+		}
+	}
+	if selection.InitContainers {
+		for _, container := range spec.InitContainers {
+			add(container.Image)
+		}
+	}
+	for _, container := range spec.Containers {
+		add(container.Image)
+	}
+	if selection.EphemeralContainers {
+		for _, container := range spec.EphemeralContainers {
+			add(container.Image)
+		}
+	}
+	return images
+}
+
+// workloadProvider lists the images of the containers of pods
+type workloadProvider struct {
+	kubeclientset kubernetes.Interface
+	namespace     string
+	selector      labels.Selector
+	containers    ContainerSelection
+}
+
+// NewWorkloadProvider returns an ImageListProvider listing the images of the containers
+// of the pods of a namespace matching a label selector, and of their init and ephemeral
+// containers if selected
+func NewWorkloadProvider(kubeclientset kubernetes.Interface, namespace string, selector labels.Selector, containers ContainerSelection) ImageListProvider {
+	return &workloadProvider{kubeclientset: kubeclientset, namespace: namespace, selector: selector, containers: containers}
+}
+
+func (p *workloadProvider) ListImages() ([]string, error) {
+	pods, err := p.kubeclientset.CoreV1().Pods(p.namespace).List(context.TODO(),
+		metav1.ListOptions{LabelSelector: p.selector.String(), ResourceVersion: "0"})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pods of namespace %s: %v", p.namespace, err)
+	}
+	images := []string{}
+	for _, pod := range pods.Items {
+		for _, image := range PodSpecImages(pod.Spec, p.containers) {
+			if !containsString(images, image) {
+				images = append(images, image)
+			}
+		}
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagelist
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestConfigMapProvider(t *testing.T) {
+	kubeclientset := fakeclientset.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "release", Namespace: "team"},
+		Data:       map[string]string{"images": "# release 1.2\nnginx:1.23\n\n  httpd:2.4 \nnginx:1.23\n"},
+	})
+	tests := []struct {
+		name           string
+		configMap      string
+		key            string
+		expectedImages []string
+		expectErr      bool
+	}{
+		{
+			name:           "#1: Images of the key",
+			configMap:      "release",
+			key:            "images",
+			expectedImages: []string{"nginx:1.23", "httpd:2.4"},
+		},
+		{
+			name:      "#2: Missing key",
+			configMap: "release",
+			key:       "artifacts",
+			expectErr: true,
+		},
+		{
+			name:      "#3: Missing ConfigMap",
+			configMap: "nightly",
+			key:       "images",
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		images, err := NewConfigMapProvider(kubeclientset, "team", test.configMap, test.key).ListImages()
+		if (err != nil) != test.expectErr {
+			t.Errorf("Test: %s failed: expectErr=%t, actual error=%v", test.name, test.expectErr, err)
+			continue
+		}
+		if !test.expectErr && !reflect.DeepEqual(images, test.expectedImages) {
+			t.Errorf("Test: %s failed: expected images %v, actual %v", test.name, test.expectedImages, images)
+		}
+	}
+}
+
+func TestWorkloadProvider(t *testing.T) {
+	newPod := func(name, namespace, app string, images ...string) *corev1.Pod {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"app": app}}}
+		pod.Spec.InitContainers = []corev1.Container{{Name: "init", Image: images[0]}}
+		for _, image := range images[1:] {
+			pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: image, Image: image})
+		}
+		return pod
+	}
+	kubeclientset := fakeclientset.NewSimpleClientset(
+		newPod("web-1", "team", "web", "busybox:1.36", "nginx:1.23", "envoy:1.24"),
+		newPod("web-2", "team", "web", "busybox:1.36", "nginx:1.23"),
+		newPod("batch-1", "team", "batch", "busybox:1.36", "python:3.11"),
+		newPod("web-1", "other", "web", "busybox:1.36", "httpd:2.4"),
+	)
+	images, err := NewWorkloadProvider(kubeclientset, "team", labels.SelectorFromSet(labels.Set{"app": "web"}),
+		ContainerSelection{InitContainers: true}).ListImages()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"busybox:1.36", "envoy:1.24", "nginx:1.23"}; !reflect.DeepEqual(images, expected) {
+		t.Errorf("expected images %v, actual %v", expected, images)
+	}
+	images, err = NewWorkloadProvider(kubeclientset, "team", labels.SelectorFromSet(labels.Set{"app": "web"}), ContainerSelection{}).ListImages()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"envoy:1.24", "nginx:1.23"}; !reflect.DeepEqual(images, expected) {
+		t.Errorf("expected images without init containers %v, actual %v", expected, images)
+	}
+}
+
+func TestPodSpecImages(t *testing.T) {
+	spec := corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "init", Image: "busybox:1.36"}},
+		Containers:     []corev1.Container{{Name: "app", Image: "nginx:1.23"}, {Name: "sidecar", Image: "busybox:1.36"}},
+		EphemeralContainers: []corev1.EphemeralContainer{
+			{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debugger", Image: "netshoot:v0.11"}},
+		},
+	}
+	tests := []struct {
+		name           string
+		selection      ContainerSelection
+		expectedImages []string
+	}{
+		{
+			name:           "#1: Regular containers only",
+			expectedImages: []string{"nginx:1.23", "busybox:1.36"},
+		},
+		{
+			name:           "#2: Init containers selected",
+			selection:      ContainerSelection{InitContainers: true},
+			expectedImages: []string{"busybox:1.36", "nginx:1.23"},
+		},
+		{
+			name:           "#3: Init and ephemeral containers selected",
+			selection:      ContainerSelection{InitContainers: true, EphemeralContainers: true},
+			expectedImages: []string{"busybox:1.36", "nginx:1.23", "netshoot:v0.11"},
+		},
+	}
+	for _, test := range tests {
+		if images := PodSpecImages(spec, test.selection); !reflect.DeepEqual(images, test.expectedImages) {
+			t.Errorf("Test: %s failed: expected images %v, actual %v", test.name, test.expectedImages, images)
+		}
+	}
+}
+
+func TestStaticProvider(t *testing.T) {
+	images, err := NewStaticProvider([]string{"nginx:1.23"}).ListImages()
+	if err != nil || !reflect.DeepEqual(images, []string{"nginx:1.23"}) {
+		t.Errorf("expected static images, actual %v, error %v", images, err)
+	}
+}
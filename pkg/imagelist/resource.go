@@ -0,0 +1,50 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagelist
+
+import (
+	"fmt"
+
+	listers "github.com/senthilrch/kube-fledged/pkg/client/listers/kubefledged/v1alpha2"
+)
+
+// imageListResourceProvider lists the images of an ImageList resource
+type imageListResourceProvider struct {
+	imageListsLister listers.ImageListLister
+	namespace        string
+	name             string
+}
+
+// NewImageListResourceProvider returns an ImageListProvider listing the images of an
+// ImageList resource, without duplicates. The ImageList is read from the informer cache.
+func NewImageListResourceProvider(imageListsLister listers.ImageListLister, namespace, name string) ImageListProvider {
+	return &imageListResourceProvider{imageListsLister: imageListsLister, namespace: namespace, name: name}
+}
+
+func (p *imageListResourceProvider) ListImages() ([]string, error) {
+	imageList, err := p.imageListsLister.ImageLists(p.namespace).Get(p.name)
+	if err != nil {
+		return nil, fmt.Errorf("error getting ImageList %s/%s: %v", p.namespace, p.name, err)
+	}
+	images := []string{}
+	for _, image := range imageList.Spec.Images {
+		if image != "" && !containsString(images, image) {
+			images = append(images, image)
+		}
+	}
+	return images, nil
+}
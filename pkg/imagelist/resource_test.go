@@ -0,0 +1,78 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagelist
+
+import (
+	"reflect"
+	"testing"
+
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	listers "github.com/senthilrch/kube-fledged/pkg/client/listers/kubefledged/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestImageListResourceProvider(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	indexer.Add(&fledgedv1alpha2.ImageList{
+		ObjectMeta: metav1.ObjectMeta{Name: "base-images", Namespace: "platform"},
+		Spec:       fledgedv1alpha2.ImageListSpec{Images: []string{"nginx:1.23", "", "httpd:2.4", "nginx:1.23"}},
+	})
+	lister := listers.NewImageListLister(indexer)
+	tests := []struct {
+		name           string
+		namespace      string
+		imageList      string
+		expectedImages []string
+		expectErr      bool
+	}{
+		{
+			name:           "#1: Images of the image list",
+			namespace:      "platform",
+			imageList:      "base-images",
+			expectedImages: []string{"nginx:1.23", "httpd:2.4"},
+		},
+		{
+			name:      "#2: Missing image list",
+			namespace: "platform",
+			imageList: "ml-images",
+			expectErr: true,
+		},
+		{
+			name:      "#3: Image list of another namespace",
+			namespace: "team",
+			imageList: "base-images",
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		images, err := NewImageListResourceProvider(lister, test.namespace, test.imageList).ListImages()
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("Test: %s failed: expected error, got none", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test: %s failed: unexpected error: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(images, test.expectedImages) {
+			t.Errorf("Test: %s failed: expectedImages=%v, actualImages=%v", test.name, test.expectedImages, images)
+		}
+	}
+}
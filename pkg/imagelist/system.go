@@ -0,0 +1,137 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagelist
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/docker/distribution/reference"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// mirrorPodAnnotationKey is the annotation of the mirror pods of static pods
+const mirrorPodAnnotationKey = "kubernetes.io/config.mirror"
+
+// kubernetesComponents are the repositories of the system components released with
+// Kubernetes, whose tag is the Kubernetes version
+var kubernetesComponents = []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler", "kube-proxy"}
+
+// systemProvider lists the images of the system components of the cluster
+type systemProvider struct {
+	kubeclientset     kubernetes.Interface
+	namespaces        []string
+	kubernetesVersion string
+	pauseImage        string
+}
+
+// NewSystemProvider returns an ImageListProvider listing the images of the static pods
+// and DaemonSets of namespaces and the pause images reported by the nodes. If a
+// Kubernetes version is given, the images of the Kubernetes components are listed with
+// this version as tag, and the pause image, if given, is listed too.
+func NewSystemProvider(kubeclientset kubernetes.Interface, namespaces []string, kubernetesVersion, pauseImage string) ImageListProvider {
+	return &systemProvider{kubeclientset: kubeclientset, namespaces: namespaces,
+		kubernetesVersion: kubernetesVersion, pauseImage: pauseImage}
+}
+
+func (p *systemProvider) ListImages() ([]string, error) {
+	listed := []string{}
+	for _, namespace := range p.namespaces {
+		pods, err := p.kubeclientset.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{ResourceVersion: "0"})
+		if err != nil {
+			return nil, fmt.Errorf("error listing pods of namespace %s: %v", namespace, err)
+		}
+		for _, pod := range pods.Items {
+			if _, ok := pod.Annotations[mirrorPodAnnotationKey]; ok {
+				listed = appendContainerImages(listed, pod.Spec)
+			}
+		}
+		daemonSets, err := p.kubeclientset.AppsV1().DaemonSets(namespace).List(context.TODO(), metav1.ListOptions{ResourceVersion: "0"})
+		if err != nil {
+			return nil, fmt.Errorf("error listing DaemonSets of namespace %s: %v", namespace, err)
+		}
+		for _, daemonSet := range daemonSets.Items {
+			listed = appendContainerImages(listed, daemonSet.Spec.Template.Spec)
+		}
+	}
+	nodes, err := p.kubeclientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{ResourceVersion: "0"})
+	if err != nil {
+		return nil, fmt.Errorf("error listing nodes: %v", err)
+	}
+	for _, node := range nodes.Items {
+		for _, image := range node.Status.Images {
+			for _, name := range image.Names {
+				if named, err := reference.ParseNormalizedNamed(name); err == nil && isPauseImage(named) {
+					listed = append(listed, name)
+				}
+			}
+		}
+	}
+	if p.pauseImage != "" {
+		listed = append(listed, p.pauseImage)
+	}
+
+	images := []string{}
+	for _, image := range listed {
+		if p.kubernetesVersion != "" {
+			image = withKubernetesVersion(image, p.kubernetesVersion)
+		}
+		if !containsString(images, image) {
+			images = append(images, image)
+		}
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+// appendContainerImages appends the images of the containers and init containers of a
+// pod spec to a list of images
+func appendContainerImages(images []string, spec corev1.PodSpec) []string {
+	for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+		for _, container := range containers {
+			if container.Image != "" {
+				images = append(images, container.Image)
+			}
+		}
+	}
+	return images
+}
+
+// isPauseImage returns true if an image is a tagged pause image. The names of the
+// images reported by the nodes without tag are ignored.
+func isPauseImage(named reference.Named) bool {
+	_, tagged := named.(reference.Tagged)
+	return tagged && path.Base(reference.Path(named)) == "pause"
+}
+
+// withKubernetesVersion returns the image of a Kubernetes component with a version as
+// tag, replacing its tag or digest. Other images are returned unchanged.
+func withKubernetesVersion(image, version string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil || !containsString(kubernetesComponents, path.Base(reference.Path(named))) {
+		return image
+	}
+	tagged, err := reference.WithTag(reference.TrimNamed(named), version)
+	if err != nil {
+		return image
+	}
+	return reference.FamiliarString(tagged)
+}
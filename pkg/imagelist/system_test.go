@@ -0,0 +1,114 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagelist
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSystemProvider(t *testing.T) {
+	staticPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "kube-apiserver-node1", Namespace: "kube-system",
+			Annotations: map[string]string{mirrorPodAnnotationKey: "abc"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "kube-apiserver", Image: "registry.k8s.io/kube-apiserver:v1.27.3"}}},
+	}
+	regularPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "coredns-1", Namespace: "kube-system"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "coredns", Image: "registry.k8s.io/coredns/coredns:v1.10.1"}}},
+	}
+	kubeProxy := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "kube-proxy", Namespace: "kube-system"}}
+	kubeProxy.Spec.Template.Spec.Containers = []corev1.Container{{Name: "kube-proxy", Image: "registry.k8s.io/kube-proxy:v1.27.3"}}
+	cni := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "calico-node", Namespace: "kube-system"}}
+	cni.Spec.Template.Spec.InitContainers = []corev1.Container{{Name: "install-cni", Image: "docker.io/calico/cni:v3.26.1"}}
+	cni.Spec.Template.Spec.Containers = []corev1.Container{{Name: "calico-node", Image: "docker.io/calico/node:v3.26.1"}}
+	csi := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "ebs-csi-node", Namespace: "storage"}}
+	csi.Spec.Template.Spec.Containers = []corev1.Container{{Name: "ebs-plugin", Image: "public.ecr.aws/ebs-csi-driver/aws-ebs-csi-driver:v1.20.0"}}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	node.Status.Images = []corev1.ContainerImage{
+		{Names: []string{"registry.k8s.io/pause@sha256:7031c1b283388d2c2e09b57badb803c05ebed362dc88d84b480cc47f72a21097", "registry.k8s.io/pause:3.9"}},
+		{Names: []string{"nginx:1.23"}},
+	}
+	kubeclientset := fakeclientset.NewSimpleClientset(staticPod, regularPod, kubeProxy, cni, csi, node)
+
+	tests := []struct {
+		name              string
+		namespaces        []string
+		kubernetesVersion string
+		pauseImage        string
+		expectedImages    []string
+	}{
+		{
+			name:       "#1: Images in use",
+			namespaces: []string{"kube-system"},
+			expectedImages: []string{"docker.io/calico/cni:v3.26.1", "docker.io/calico/node:v3.26.1", "registry.k8s.io/kube-apiserver:v1.27.3",
+				"registry.k8s.io/kube-proxy:v1.27.3", "registry.k8s.io/pause:3.9"},
+		},
+		{
+			name:              "#2: Images of the target version",
+			namespaces:        []string{"kube-system", "storage"},
+			kubernetesVersion: "v1.28.2",
+			pauseImage:        "registry.k8s.io/pause:3.9",
+			expectedImages: []string{"docker.io/calico/cni:v3.26.1", "docker.io/calico/node:v3.26.1", "public.ecr.aws/ebs-csi-driver/aws-ebs-csi-driver:v1.20.0",
+				"registry.k8s.io/kube-apiserver:v1.28.2", "registry.k8s.io/kube-proxy:v1.28.2", "registry.k8s.io/pause:3.9"},
+		},
+	}
+	for _, test := range tests {
+		images, err := NewSystemProvider(kubeclientset, test.namespaces, test.kubernetesVersion, test.pauseImage).ListImages()
+		if err != nil {
+			t.Errorf("Test: %s failed: unexpected error: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(images, test.expectedImages) {
+			t.Errorf("Test: %s failed: expected images %v, actual %v", test.name, test.expectedImages, images)
+		}
+	}
+}
+
+func TestWithKubernetesVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		image         string
+		expectedImage string
+	}{
+		{
+			name:          "#1: Tag of Kubernetes component replaced",
+			image:         "registry.k8s.io/kube-scheduler:v1.27.3",
+			expectedImage: "registry.k8s.io/kube-scheduler:v1.28.2",
+		},
+		{
+			name:          "#2: Digest of Kubernetes component replaced",
+			image:         "registry.k8s.io/kube-proxy@sha256:7031c1b283388d2c2e09b57badb803c05ebed362dc88d84b480cc47f72a21097",
+			expectedImage: "registry.k8s.io/kube-proxy:v1.28.2",
+		},
+		{
+			name:          "#3: Other image unchanged",
+			image:         "registry.k8s.io/coredns/coredns:v1.10.1",
+			expectedImage: "registry.k8s.io/coredns/coredns:v1.10.1",
+		},
+	}
+	for _, test := range tests {
+		if image := withKubernetesVersion(test.image, "v1.28.2"); image != test.expectedImage {
+			t.Errorf("Test: %s failed: expected image %s, actual %s", test.name, test.expectedImage, image)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	"github.com/golang/glog"
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apiserver/pkg/storage/names"
+)
+
+// CircuitBreaker configures the circuit breaker of the registries. Once the image pulls
+// from a registry failed consistently, e.g. during an authentication outage or a storm
+// of 429 responses, its circuit opens: no image pull from the registry is dispatched
+// until the cool-down period elapsed, and the pulls not dispatched are deferred.
+type CircuitBreaker struct {
+	// Failures is the number of consecutive failed image pulls from a registry that
+	// opens its circuit. The circuit breaker is disabled if zero
+	Failures int
+	// CoolDown is the period during which the image pulls from a registry whose circuit
+	// opened are not dispatched
+	CoolDown time.Duration
+}
+
+// Validate returns an error if the circuit breaker parameters are inconsistent
+func (b CircuitBreaker) Validate() error {
+	if b.Failures < 0 {
+		return fmt.Errorf("number of failures opening the circuit of a registry must not be negative")
+	}
+	if b.Failures > 0 && b.CoolDown <= 0 {
+		return fmt.Errorf("cool-down period of the circuit of a registry must be positive")
+	}
+	return nil
+}
+
+// registryCircuit is the circuit of a registry. Its failures are not reset when the
+// cool-down period elapses, so that the circuit opens again on the next failure
+type registryCircuit struct {
+	failures  int
+	openUntil time.Time
+}
+
+// imageRegistry returns the registry of an image, or "" if the image reference is
+// invalid
+func imageRegistry(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ""
+	}
+	return reference.Domain(named)
+}
+
+// registryFailure returns true if the failed image pull is a failure of its registry.
+// Failures specific to the image, e.g. an unknown manifest, do not open the circuit.
+func registryFailure(iwres ImageWorkResult) bool {
+	if iwres.Status != ImageWorkResultStatusFailed && iwres.Status != ImageWorkResultStatusUnknown {
+		return false
+	}
+	return iwres.Reason != pullReasonManifestUnknown && iwres.Reason != podReasonInvalidImageName
+}
+
+// recordRegistryOutcome counts the consecutive failed image pulls from the registry of
+// the image of a work result, and opens its circuit once they reach the number of
+// failures of the circuit breaker. A successful image pull resets the count.
+func (m *ImageManager) recordRegistryOutcome(iwres ImageWorkResult) {
+	iwr := iwres.ImageWorkRequest
+	if m.circuitBreaker.Failures == 0 || iwr.WorkType == ImageCachePurge {
+		return
+	}
+	registry := imageRegistry(iwr.PullReference())
+	if registry == "" {
+		return
+	}
+	now := time.Now()
+	m.circuitLock.Lock()
+	circuit, ok := m.circuits[registry]
+	if iwres.Status == ImageWorkResultStatusSucceeded {
+		delete(m.circuits, registry)
+		m.circuitLock.Unlock()
+		if ok && circuit.failures >= m.circuitBreaker.Failures {
+			glog.Infof("Circuit of registry %s closed: image pull of %s succeeded", registry, iwr.PullReference())
+		}
+		return
+	}
+	if !registryFailure(iwres) {
+		m.circuitLock.Unlock()
+		return
+	}
+	if !ok {
+		circuit = &registryCircuit{}
+		m.circuits[registry] = circuit
+	}
+	circuit.failures++
+	// The failures of the pulls dispatched before the circuit opened do not extend
+	// its cool-down period
+	if circuit.failures < m.circuitBreaker.Failures || now.Before(circuit.openUntil) {
+		m.circuitLock.Unlock()
+		return
+	}
+	circuit.openUntil = now.Add(m.circuitBreaker.CoolDown)
+	failures := circuit.failures
+	m.circuitLock.Unlock()
+
+	message := fmt.Sprintf("Circuit of registry %s opened after %d consecutive image pull failures: image pulls from it deferred for %s",
+		registry, failures, m.circuitBreaker.CoolDown)
+	glog.Warning(message)
+	if m.recorder != nil && iwr.Imagecache != nil {
+		m.recorder.Event(iwr.Imagecache, corev1.EventTypeWarning, fledgedv1alpha2.ImageCacheReasonRegistryCircuitOpen, message)
+	}
+}
+
+// RegistryCircuitOpenUntil returns the end of the cool-down period of the circuit of
+// the registry of an image, or the zero time if its circuit is closed
+func (m *ImageManager) RegistryCircuitOpenUntil(image string) time.Time {
+	if m.circuitBreaker.Failures == 0 {
+		return time.Time{}
+	}
+	m.circuitLock.Lock()
+	defer m.circuitLock.Unlock()
+	circuit, ok := m.circuits[imageRegistry(image)]
+	if !ok || !time.Now().Before(circuit.openUntil) {
+		return time.Time{}
+	}
+	return circuit.openUntil
+}
+
+// deferOpenCircuit records the image pull of the work request as deferred if the
+// circuit of its registry is open, and returns true if so
+func (m *ImageManager) deferOpenCircuit(iwr ImageWorkRequest) bool {
+	if iwr.WorkType == ImageCachePurge {
+		return false
+	}
+	openUntil := m.RegistryCircuitOpenUntil(iwr.PullReference())
+	if openUntil.IsZero() {
+		return false
+	}
+	m.lock.Lock()
+	m.imageworkstatus[names.SimpleNameGenerator.GenerateName(fakeJobPrefix)] = circuitDeferredResult(iwr, openUntil)
+	m.lock.Unlock()
+	glog.Infof("Job not created (registry-circuit-open:- %s --> %s, until %s)", iwr.PullReference(),
+		iwr.Node.Labels["kubernetes.io/hostname"], openUntil.Format(time.RFC3339))
+	return true
+}
+
+// circuitDeferredResult returns the result of an image pull deferred because the
+// circuit of its registry is open
+func circuitDeferredResult(iwr ImageWorkRequest, openUntil time.Time) ImageWorkResult {
+	return ImageWorkResult{
+		ImageWorkRequest: iwr,
+		Status:           ImageWorkResultStatusDeferred,
+		Reason:           fledgedv1alpha2.ImageCacheReasonRegistryCircuitOpen,
+		Message: fmt.Sprintf("Circuit of registry %s open until %s", imageRegistry(iwr.PullReference()),
+			openUntil.Format(time.RFC3339)),
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"testing"
+	"time"
+
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCircuitBreakerValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		breaker   CircuitBreaker
+		expectErr bool
+	}{
+		{
+			name: "#1: Circuit breaker disabled",
+		},
+		{
+			name:    "#2: Valid circuit breaker parameters",
+			breaker: CircuitBreaker{Failures: 10, CoolDown: time.Minute},
+		},
+		{
+			name:      "#3: Negative number of failures",
+			breaker:   CircuitBreaker{Failures: -1, CoolDown: time.Minute},
+			expectErr: true,
+		},
+		{
+			name:      "#4: No cool-down period",
+			breaker:   CircuitBreaker{Failures: 10},
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		if err := test.breaker.Validate(); (err != nil) != test.expectErr {
+			t.Errorf("Test: %s failed: expectErr=%t, actual error=%v", test.name, test.expectErr, err)
+		}
+	}
+}
+
+func TestRecordRegistryOutcome(t *testing.T) {
+	imageCache := &fledgedv1alpha2.ImageCache{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace}}
+	result := func(image, status, reason string) ImageWorkResult {
+		return ImageWorkResult{
+			ImageWorkRequest: ImageWorkRequest{Image: image, Node: &node, WorkType: ImageCacheRefresh, Imagecache: imageCache},
+			Status:           status,
+			Reason:           reason,
+		}
+	}
+	imagemanager, _ := newTestImageManager(&fakeclientset.Clientset{}, "IfNotPresent", "sa-kube-fledged", false, "", false, "")
+	imagemanager.circuitBreaker = CircuitBreaker{Failures: 2, CoolDown: time.Hour}
+
+	// Failures specific to an image and failures of other registries are not counted
+	imagemanager.recordRegistryOutcome(result("ghcr.io/foo/bar:v1", ImageWorkResultStatusFailed, pullReasonManifestUnknown))
+	imagemanager.recordRegistryOutcome(result("ghcr.io/foo/bar:v2", ImageWorkResultStatusFailed, "ErrImagePull"))
+	imagemanager.recordRegistryOutcome(result("nginx:1.23", ImageWorkResultStatusFailed, "ErrImagePull"))
+	if openUntil := imagemanager.RegistryCircuitOpenUntil("ghcr.io/foo/baz:v1"); !openUntil.IsZero() {
+		t.Errorf("expected circuit of ghcr.io to be closed, actual open until %s", openUntil)
+	}
+	// A successful pull resets the failures of the registry
+	imagemanager.recordRegistryOutcome(result("ghcr.io/foo/bar:v3", ImageWorkResultStatusSucceeded, ""))
+	imagemanager.recordRegistryOutcome(result("ghcr.io/foo/bar:v2", ImageWorkResultStatusFailed, "ErrImagePull"))
+	if openUntil := imagemanager.RegistryCircuitOpenUntil("ghcr.io/foo/baz:v1"); !openUntil.IsZero() {
+		t.Errorf("expected circuit of ghcr.io to be closed after a successful pull, actual open until %s", openUntil)
+	}
+
+	imagemanager.recordRegistryOutcome(result("ghcr.io/foo/bar:v2", ImageWorkResultStatusUnknown, "ErrImagePull"))
+	if openUntil := imagemanager.RegistryCircuitOpenUntil("ghcr.io/foo/baz:v1"); openUntil.IsZero() {
+		t.Fatalf("expected circuit of ghcr.io to be open")
+	}
+	if openUntil := imagemanager.RegistryCircuitOpenUntil("nginx:1.23"); !openUntil.IsZero() {
+		t.Errorf("expected circuit of docker.io to be closed, actual open until %s", openUntil)
+	}
+
+	// The pulls from the registry are deferred while its circuit is open
+	iwr := ImageWorkRequest{Image: "ghcr.io/foo/baz:v1", Node: &node, WorkType: ImageCacheRefresh, Imagecache: imageCache}
+	if !imagemanager.deferOpenCircuit(iwr) {
+		t.Fatalf("expected pull to be deferred")
+	}
+	deferred := 0
+	for _, iwres := range imagemanager.imageworkstatus {
+		if iwres.Status == ImageWorkResultStatusDeferred && iwres.Reason == fledgedv1alpha2.ImageCacheReasonRegistryCircuitOpen {
+			deferred++
+		}
+	}
+	if deferred != 1 {
+		t.Errorf("expected 1 deferred work result, actual %d", deferred)
+	}
+	iwr.WorkType = ImageCachePurge
+	if imagemanager.deferOpenCircuit(iwr) {
+		t.Errorf("expected purge not to be deferred")
+	}
+
+	// A single failure once the cool-down period elapsed opens the circuit again
+	imagemanager.circuits["ghcr.io"].openUntil = time.Now().Add(-time.Second)
+	if openUntil := imagemanager.RegistryCircuitOpenUntil("ghcr.io/foo/baz:v1"); !openUntil.IsZero() {
+		t.Errorf("expected circuit of ghcr.io to be closed after the cool-down period, actual open until %s", openUntil)
+	}
+	imagemanager.recordRegistryOutcome(result("ghcr.io/foo/bar:v2", ImageWorkResultStatusFailed, "ErrImagePull"))
+	if openUntil := imagemanager.RegistryCircuitOpenUntil("ghcr.io/foo/baz:v1"); openUntil.IsZero() {
+		t.Errorf("expected circuit of ghcr.io to open again")
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"time"
+
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// jobSlotCheckInterval is the delay after which a work request waiting for the number
+// of running jobs to drop below the maximum number of concurrent jobs is processed again
+const jobSlotCheckInterval = time.Second
+
+// SetPullerImages changes the images of the jobs pulling and deleting images. The jobs
+// already created are not affected.
+func (m *ImageManager) SetPullerImages(criClientImage, busyboxImage string) {
+	m.configLock.Lock()
+	defer m.configLock.Unlock()
+	m.criClientImage = criClientImage
+	m.busyboxImage = busyboxImage
+}
+
+// pullerImages returns the images of the jobs pulling and deleting images
+func (m *ImageManager) pullerImages() (criClientImage, busyboxImage string) {
+	m.configLock.RLock()
+	defer m.configLock.RUnlock()
+	return m.criClientImage, m.busyboxImage
+}
+
+// SetMaxConcurrentJobs changes the maximum number of jobs pulling and deleting images
+// that run at the same time. The number of jobs is not limited if zero.
+func (m *ImageManager) SetMaxConcurrentJobs(max int) {
+	m.configLock.Lock()
+	defer m.configLock.Unlock()
+	m.maxConcurrentJobs = max
+}
+
+// deferForJobSlot requeues the work request after jobSlotCheckInterval if the number
+// of running jobs reaches the maximum number of concurrent jobs, and returns true if so.
+// The queue is shut down on stop, dropping the requeued work requests.
+func (m *ImageManager) deferForJobSlot(queue workqueue.RateLimitingInterface, iwr ImageWorkRequest) bool {
+	m.configLock.RLock()
+	max := m.maxConcurrentJobs
+	m.configLock.RUnlock()
+	if max <= 0 || m.runningJobs() < max {
+		m.forgetJobSlotWait(iwr)
+		return false
+	}
+	m.jobSlotLock.Lock()
+	m.jobSlotWaits[iwr] = true
+	m.jobSlotLock.Unlock()
+	queue.AddAfter(iwr, jobSlotCheckInterval)
+	return true
+}
+
+// forgetJobSlotWait forgets that the work request waits for a job slot
+func (m *ImageManager) forgetJobSlotWait(iwr ImageWorkRequest) {
+	m.jobSlotLock.Lock()
+	defer m.jobSlotLock.Unlock()
+	delete(m.jobSlotWaits, iwr)
+}
+
+// waitingForJobSlot returns true if work requests of the image cache wait for a job slot
+func (m *ImageManager) waitingForJobSlot(imageCache *fledgedv1alpha2.ImageCache) bool {
+	m.jobSlotLock.Lock()
+	defer m.jobSlotLock.Unlock()
+	for iwr := range m.jobSlotWaits {
+		if iwr.Imagecache != nil && iwr.Imagecache.Namespace == imageCache.Namespace && iwr.Imagecache.Name == imageCache.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// runningJobs returns the number of running jobs. The jobs of failed image pulls
+// waiting to be retried are not counted.
+func (m *ImageManager) runningJobs() int {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	running := 0
+	for _, iwres := range m.imageworkstatus {
+		if iwres.Status == ImageWorkResultStatusJobCreated && !iwres.retrying {
+			running++
+		}
+	}
+	return running
+}
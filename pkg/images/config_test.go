@@ -0,0 +1,92 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"testing"
+	"time"
+
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestSetPullerImages(t *testing.T) {
+	imagemanager, _ := newTestImageManager(&fakeclientset.Clientset{}, "IfNotPresent", "sa-kube-fledged", false, "", false, "")
+	imagemanager.SetPullerImages("registry.local/kubefledged-cri-client:v1", "registry.local/busybox:1.36")
+	if criClientImage, busyboxImage := imagemanager.pullerImages(); criClientImage != "registry.local/kubefledged-cri-client:v1" ||
+		busyboxImage != "registry.local/busybox:1.36" {
+		t.Errorf("expected puller images to be changed, actual %s, %s", criClientImage, busyboxImage)
+	}
+}
+
+func TestDeferForJobSlot(t *testing.T) {
+	imagemanager, _ := newTestImageManager(&fakeclientset.Clientset{}, "IfNotPresent", "sa-kube-fledged", false, "", false, "")
+	imagemanager.imageworkstatus = map[string]ImageWorkResult{
+		"job1": {Status: ImageWorkResultStatusJobCreated},
+		"job2": {Status: ImageWorkResultStatusJobCreated, retrying: true},
+		"job3": {Status: ImageWorkResultStatusSucceeded},
+	}
+	if running := imagemanager.runningJobs(); running != 1 {
+		t.Errorf("expected 1 running job, actual %d", running)
+	}
+	imageCache := &fledgedv1alpha2.ImageCache{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "kube-fledged"}}
+	iwr := ImageWorkRequest{Image: "nginx:1.23", Node: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}, Imagecache: imageCache}
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+	imagemanager.SetMaxConcurrentJobs(1)
+	start := time.Now()
+	if !imagemanager.deferForJobSlot(queue, iwr) {
+		t.Fatalf("expected work request deferred for a job slot")
+	}
+	if time.Since(start) >= jobSlotCheckInterval {
+		t.Errorf("expected worker not blocked while waiting for a job slot")
+	}
+	if !imagemanager.waitingForJobSlot(imageCache) {
+		t.Errorf("expected image cache waiting for a job slot")
+	}
+	if obj, _ := queue.Get(); obj != iwr {
+		t.Errorf("expected work request requeued, actual %v", obj)
+	} else {
+		queue.Done(obj)
+	}
+	imagemanager.SetMaxConcurrentJobs(2)
+	if imagemanager.deferForJobSlot(queue, iwr) {
+		t.Errorf("expected work request to get a job slot once the maximum was raised")
+	}
+	if imagemanager.waitingForJobSlot(imageCache) {
+		t.Errorf("expected image cache no longer waiting for a job slot")
+	}
+}
+
+func TestEndOfActionWaitsForJobSlot(t *testing.T) {
+	imagemanager, _ := newTestImageManager(&fakeclientset.Clientset{}, "IfNotPresent", "sa-kube-fledged", false, "", false, "")
+	imageCache := &fledgedv1alpha2.ImageCache{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "kube-fledged"}}
+	imagemanager.jobSlotWaits[ImageWorkRequest{Image: "nginx:1.23", Imagecache: imageCache}] = true
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+	queue.Add(ImageWorkRequest{Imagecache: imageCache})
+	imagemanager.processNextWorkItem(queue)
+	if queue.Len() != 0 {
+		t.Errorf("expected request ending the action requeued after a delay, actual %d queued", queue.Len())
+	}
+	if obj, _ := queue.Get(); obj != (ImageWorkRequest{Imagecache: imageCache}) {
+		t.Errorf("expected request ending the action requeued, actual %v", obj)
+	}
+}
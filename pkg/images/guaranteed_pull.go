@@ -0,0 +1,69 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// GuaranteedPulls configures the dispatch of the image pulls to the nodes without
+// requiring schedulable capacity, so that images are cached even on fully packed nodes.
+// The pods of the image pull jobs are bound to their node by the controller instead of
+// being scheduled, and request no resources.
+type GuaranteedPulls struct {
+	// Enabled binds the pods of the image pull jobs to their node
+	Enabled bool
+	// PriorityClassName is the priority class of the pods of the image pull jobs,
+	// instead of the priority class of the jobs, e.g. so that they are not the first
+	// pods evicted by the kubelet under node pressure
+	PriorityClassName string
+}
+
+// Validate returns an error if the guaranteed pulls parameters are inconsistent
+func (g GuaranteedPulls) Validate() error {
+	if !g.Enabled && g.PriorityClassName != "" {
+		return fmt.Errorf("priority class of guaranteed pulls requires guaranteed pulls")
+	}
+	return nil
+}
+
+// bindToNode binds the pods of an image pull job to a node. The scheduler is bypassed,
+// so that the pods neither wait for schedulable capacity nor preempt other pods. All
+// the containers request zero cpu and memory, so that no default requests of a
+// LimitRange of the namespace apply. The kubelet still rejects the pods if the node
+// reached its maximum number of pods.
+func (g GuaranteedPulls) bindToNode(job *batchv1.Job, node *corev1.Node) {
+	podSpec := &job.Spec.Template.Spec
+	podSpec.NodeName = node.Name
+	if g.PriorityClassName != "" {
+		podSpec.PriorityClassName = g.PriorityClassName
+	}
+	zeroRequests := func(containers []corev1.Container) {
+		for i := range containers {
+			containers[i].Resources.Requests = corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("0"),
+				corev1.ResourceMemory: resource.MustParse("0"),
+			}
+		}
+	}
+	zeroRequests(podSpec.InitContainers)
+	zeroRequests(podSpec.Containers)
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"testing"
+
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGuaranteedPullsValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		pulls     GuaranteedPulls
+		expectErr bool
+	}{
+		{
+			name: "#1: Guaranteed pulls disabled",
+		},
+		{
+			name:  "#2: Guaranteed pulls with a priority class",
+			pulls: GuaranteedPulls{Enabled: true, PriorityClassName: "kubefledged-guaranteed-pull"},
+		},
+		{
+			name:      "#3: Priority class without guaranteed pulls",
+			pulls:     GuaranteedPulls{PriorityClassName: "kubefledged-guaranteed-pull"},
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		if err := test.pulls.Validate(); (err != nil) != test.expectErr {
+			t.Errorf("Test: %s failed: expectErr=%t, actual error=%v", test.name, test.expectErr, err)
+		}
+	}
+}
+
+func TestBindToNode(t *testing.T) {
+	imagecache := &fledgedv1alpha2.ImageCache{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "kube-fledged"}}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "bar", Labels: map[string]string{"kubernetes.io/hostname": "bar"}}}
+	tests := []struct {
+		name                      string
+		pulls                     GuaranteedPulls
+		expectedPriorityClassName string
+	}{
+		{
+			name:                      "#1: Priority class of the jobs",
+			pulls:                     GuaranteedPulls{Enabled: true},
+			expectedPriorityClassName: "priority-class-kube-fledged",
+		},
+		{
+			name:                      "#2: Priority class of guaranteed pulls",
+			pulls:                     GuaranteedPulls{Enabled: true, PriorityClassName: "kubefledged-guaranteed-pull"},
+			expectedPriorityClassName: "kubefledged-guaranteed-pull",
+		},
+	}
+	for _, test := range tests {
+		job, err := newImagePullJob(imagecache, "foo:v1", node, "IfNotPresent", "busybox:1.35.0", "", "priority-class-kube-fledged")
+		if err != nil {
+			t.Fatalf("Test: %s failed: unexpected error: %v", test.name, err)
+		}
+		test.pulls.bindToNode(job, node)
+		podSpec := job.Spec.Template.Spec
+		if podSpec.NodeName != node.Name {
+			t.Errorf("Test: %s failed: expected nodeName %q, actual %q", test.name, node.Name, podSpec.NodeName)
+		}
+		if podSpec.PriorityClassName != test.expectedPriorityClassName {
+			t.Errorf("Test: %s failed: expected priorityClassName %q, actual %q", test.name, test.expectedPriorityClassName, podSpec.PriorityClassName)
+		}
+		for _, container := range append(podSpec.InitContainers, podSpec.Containers...) {
+			cpu, memory := container.Resources.Requests[corev1.ResourceCPU], container.Resources.Requests[corev1.ResourceMemory]
+			if len(container.Resources.Requests) != 2 || !cpu.IsZero() || !memory.IsZero() {
+				t.Errorf("Test: %s failed: expected zero requests of container %s, actual %v", test.name, container.Name, container.Resources.Requests)
+			}
+		}
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/docker/distribution/reference"
 	"github.com/golang/glog"
 	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
 	batchv1 "k8s.io/api/batch/v1"
@@ -30,6 +31,14 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+const nodeOSWindows = "windows"
+
+// pullStrategyLabelKey is the label of image pull jobs that records the pull strategy
+const pullStrategyLabelKey = "kubefledged.io/pull-strategy"
+
+// Mount path of the registry credentials of artifact pull jobs
+const artifactRegistryConfigPath = "/etc/kubefledged/registry"
+
 // newImagePullJob constructs a job manifest for pulling an image to a node
 func newImagePullJob(imagecache *fledgedv1alpha2.ImageCache, image string, node *corev1.Node,
 	imagePullPolicy string, busyboxImage string, serviceAccountName string,
@@ -137,12 +146,300 @@ func newImagePullJob(imagecache *fledgedv1alpha2.ImageCache, image string, node
 	if jobPriorityClassName != "" {
 		job.Spec.Template.Spec.PriorityClassName = jobPriorityClassName
 	}
+	if isWindowsNode(node) {
+		// The busybox init container cannot run on Windows nodes. Windows images
+		// ship with cmd.exe, which is used to exit the puller container instead.
+		podSpec := &job.Spec.Template.Spec
+		podSpec.OS = &corev1.PodOS{Name: corev1.Windows}
+		podSpec.InitContainers = nil
+		podSpec.Volumes = nil
+		podSpec.Containers[0].Command = []string{"cmd.exe", "/c", "echo Image pulled successfully!"}
+		podSpec.Containers[0].VolumeMounts = nil
+	}
+	SetRestrictedSecurityContext(&job.Spec.Template.Spec)
+	return job, nil
+}
+
+// newContainerdImagePullJob constructs a job manifest for pulling an image to a node using
+// the ctr client of containerd, which prints the download progress of each layer. The
+// image is pulled into the given containerd namespace, and unpacked into the given
+// snapshotter unless empty. The job is a variant of the image delete job, which mounts
+// the containerd socket.
+func newContainerdImagePullJob(imagecache *fledgedv1alpha2.ImageCache, image string, platform string, node *corev1.Node,
+	containerRuntimeVersion string, criClientImage string, serviceAccountName string,
+	hostNetwork bool, jobPriorityClassName string, criSocketPath string, containerdNamespace string,
+	snapshotter string) (*batchv1.Job, error) {
+	job, err := newImageDeleteJob(imagecache, image, node, containerRuntimeVersion, criClientImage, "",
+		serviceAccountName, hostNetwork, jobPriorityClassName, criSocketPath)
+	if err != nil {
+		return nil, err
+	}
+	job.Labels[pullStrategyLabelKey] = PullStrategyContainerd
+	podSpec := &job.Spec.Template.Spec
+	socketPath := podSpec.Volumes[0].VolumeSource.HostPath.Path
+	podSpec.Containers[0].Name = "imagepuller"
+	pullCommand := "exec /usr/bin/ctr --address " + socketPath + " --namespace " + containerdNamespace + " images pull "
+	if platform != "" {
+		pullCommand += "--platform " + platform + " "
+	}
+	pullCommand += snapshotterArgs(snapshotter)
+	podSpec.Containers[0].Args = []string{"-c", pullCommand + image}
+	podSpec.Containers[0].TerminationMessagePolicy = corev1.TerminationMessageFallbackToLogsOnError
+	return job, nil
+}
+
+// newContainerdImageDeleteJob constructs a job manifest for deleting an image from the
+// given containerd namespace of a node using the ctr client of containerd
+func newContainerdImageDeleteJob(imagecache *fledgedv1alpha2.ImageCache, image string, node *corev1.Node,
+	containerRuntimeVersion string, criClientImage string, serviceAccountName string,
+	hostNetwork bool, jobPriorityClassName string, criSocketPath string, containerdNamespace string) (*batchv1.Job, error) {
+	job, err := newImageDeleteJob(imagecache, image, node, containerRuntimeVersion, criClientImage, "",
+		serviceAccountName, hostNetwork, jobPriorityClassName, criSocketPath)
+	if err != nil {
+		return nil, err
+	}
+	podSpec := &job.Spec.Template.Spec
+	socketPath := podSpec.Volumes[0].VolumeSource.HostPath.Path
+	deleteCommand := "exec /usr/bin/ctr --address " + socketPath + " --namespace " + containerdNamespace + " images rm " + image + " > /dev/termination-log 2>&1"
+	podSpec.Containers[0].Args = []string{"-c", deleteCommand}
+	return job, nil
+}
+
+// newPlatformImagePullJob constructs a job manifest for pulling a specific platform of an
+// image to a node. The kubelet always pulls the platform of the node, so the image is
+// pulled with the client of the container runtime instead. Only containerd and docker
+// support pulling a platform other than the one of the node. On containerd nodes, the
+// image is unpacked into the given snapshotter unless empty.
+func newPlatformImagePullJob(imagecache *fledgedv1alpha2.ImageCache, image string, platform string, node *corev1.Node,
+	containerRuntimeVersion string, criClientImage string, serviceAccountName string,
+	hostNetwork bool, jobPriorityClassName string, criSocketPath string, snapshotter string) (*batchv1.Job, error) {
+	if isWindowsNode(node) {
+		return nil, fmt.Errorf("pulling image %s for platform %s is not supported on windows nodes", image, platform)
+	}
+	if strings.Contains(containerRuntimeVersion, "containerd") {
+		return newContainerdImagePullJob(imagecache, image, platform, node, containerRuntimeVersion, criClientImage,
+			serviceAccountName, hostNetwork, jobPriorityClassName, criSocketPath, DefaultContainerdNamespace, snapshotter)
+	}
+	if !strings.Contains(containerRuntimeVersion, "docker") {
+		return nil, fmt.Errorf("pulling image %s for platform %s is not supported by container runtime %s", image, platform, containerRuntimeVersion)
+	}
+	job, err := newImageDeleteJob(imagecache, image, node, containerRuntimeVersion, criClientImage, "",
+		serviceAccountName, hostNetwork, jobPriorityClassName, criSocketPath)
+	if err != nil {
+		return nil, err
+	}
+	podSpec := &job.Spec.Template.Spec
+	podSpec.Containers[0].Name = "imagepuller"
+	podSpec.Containers[0].Args = []string{"-c", "exec /usr/bin/docker pull --platform " + platform + " " + image + " > /dev/termination-log 2>&1"}
+	return job, nil
+}
+
+// newRetagImagePullJob constructs a job manifest for pulling an image to a node and tagging
+// it as its target reference. The image is pulled and tagged with the client of the
+// container runtime, and only the target reference is kept on the node. Only containerd
+// and docker support tagging images. On containerd nodes, the image is unpacked into the
+// given snapshotter unless empty.
+func newRetagImagePullJob(imagecache *fledgedv1alpha2.ImageCache, image string, targetRef string, platform string,
+	node *corev1.Node, containerRuntimeVersion string, criClientImage string, serviceAccountName string,
+	hostNetwork bool, jobPriorityClassName string, criSocketPath string, containerdNamespace string,
+	snapshotter string) (*batchv1.Job, error) {
+	if isWindowsNode(node) {
+		return nil, fmt.Errorf("tagging image %s as %s is not supported on windows nodes", image, targetRef)
+	}
+	if len(imagecache.Spec.ImagePullSecrets) > 0 {
+		return nil, fmt.Errorf("tagging image %s as %s is not supported for image caches with image pull secrets", image, targetRef)
+	}
+	containerd := strings.Contains(containerRuntimeVersion, "containerd")
+	if !containerd && !strings.Contains(containerRuntimeVersion, "docker") {
+		return nil, fmt.Errorf("tagging image %s as %s is not supported by container runtime %s", image, targetRef, containerRuntimeVersion)
+	}
+	job, err := newImageDeleteJob(imagecache, image, node, containerRuntimeVersion, criClientImage, "",
+		serviceAccountName, hostNetwork, jobPriorityClassName, criSocketPath)
+	if err != nil {
+		return nil, err
+	}
+	podSpec := &job.Spec.Template.Spec
+	podSpec.Containers[0].Name = "imagepuller"
+	pullArgs := ""
+	if platform != "" {
+		pullArgs = "--platform " + platform + " "
+	}
+	pullCommand := "/usr/bin/docker pull " + pullArgs + image
+	tagCommand := "/usr/bin/docker tag " + image + " " + targetRef
+	removeCommand := "/usr/bin/docker image rm " + image
+	if containerd {
+		// ctr does not normalize image references
+		for _, ref := range []*string{&image, &targetRef} {
+			named, err := reference.ParseNormalizedNamed(*ref)
+			if err != nil {
+				return nil, fmt.Errorf("invalid image reference %s: %v", *ref, err)
+			}
+			*ref = reference.TagNameOnly(named).String()
+		}
+		ctr := "/usr/bin/ctr --address " + podSpec.Volumes[0].VolumeSource.HostPath.Path + " --namespace " + containerdNamespace + " images "
+		pullCommand = ctr + "pull " + pullArgs + snapshotterArgs(snapshotter) + image
+		tagCommand = ctr + "tag --force " + image + " " + targetRef
+		removeCommand = ctr + "rm " + image
+	}
+	retagCommand := pullCommand + " > /dev/termination-log 2>&1 && " + tagCommand + " > /dev/termination-log 2>&1 && exec " +
+		removeCommand + " > /dev/termination-log 2>&1"
+	podSpec.Containers[0].Args = []string{"-c", retagCommand}
 	return job, nil
 }
 
+// newArtifactPullJob constructs a job manifest for pulling an OCI artifact into the
+// artifact store of a node. The artifact is pulled with the oras client into a
+// directory of the artifact store named after the artifact reference.
+func newArtifactPullJob(imagecache *fledgedv1alpha2.ImageCache, artifact string, node *corev1.Node,
+	orasImage string, artifactStorePath string, serviceAccountName string,
+	jobPriorityClassName string) (*batchv1.Job, error) {
+	args := []string{"pull", artifact, "--output", artifactStoreDir(artifactStorePath, artifact)}
+	job, err := newArtifactJob(imagecache, node, orasImage, artifactStorePath, serviceAccountName, jobPriorityClassName)
+	if err != nil {
+		return nil, err
+	}
+	podSpec := &job.Spec.Template.Spec
+	if len(imagecache.Spec.ImagePullSecrets) > 0 {
+		// oras reads the registry credentials from a docker config file
+		args = append(args, "--registry-config", artifactRegistryConfigPath+"/config.json")
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: "registry-config",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: imagecache.Spec.ImagePullSecrets[0].Name,
+					Items: []corev1.KeyToPath{
+						{Key: corev1.DockerConfigJsonKey, Path: "config.json"},
+					},
+				},
+			},
+		})
+		podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      "registry-config",
+			MountPath: artifactRegistryConfigPath,
+			ReadOnly:  true,
+		})
+	}
+	podSpec.Containers[0].Name = "artifactpuller"
+	podSpec.Containers[0].Args = args
+	return job, nil
+}
+
+// newArtifactDeleteJob constructs a job manifest to delete an OCI artifact from the
+// artifact store of a node
+func newArtifactDeleteJob(imagecache *fledgedv1alpha2.ImageCache, artifact string, node *corev1.Node,
+	busyboxImage string, artifactStorePath string, serviceAccountName string,
+	jobPriorityClassName string) (*batchv1.Job, error) {
+	job, err := newArtifactJob(imagecache, node, busyboxImage, artifactStorePath, serviceAccountName, jobPriorityClassName)
+	if err != nil {
+		return nil, err
+	}
+	podSpec := &job.Spec.Template.Spec
+	podSpec.Containers[0].Name = "artifactdeleter"
+	podSpec.Containers[0].Command = []string{"rm", "-rf", artifactStoreDir(artifactStorePath, artifact)}
+	return job, nil
+}
+
+// newArtifactJob constructs the job manifest common to artifact pull and delete jobs,
+// with the artifact store of the node mounted at the same path in the container
+func newArtifactJob(imagecache *fledgedv1alpha2.ImageCache, node *corev1.Node, image string,
+	artifactStorePath string, serviceAccountName string, jobPriorityClassName string) (*batchv1.Job, error) {
+	if imagecache == nil {
+		glog.Error("imagecache pointer is nil")
+		return nil, fmt.Errorf("imagecache pointer is nil")
+	}
+	if isWindowsNode(node) {
+		return nil, fmt.Errorf("OCI artifacts cannot be cached on windows node %s", node.Labels["kubernetes.io/hostname"])
+	}
+	hostname := node.Labels["kubernetes.io/hostname"]
+
+	labels := map[string]string{
+		"app":         "kubefledged",
+		"kubefledged": "kubefledged-image-manager",
+		"imagecache":  imagecache.Name,
+		"controller":  controllerAgentName,
+	}
+
+	hostpathtype := corev1.HostPathDirectoryOrCreate
+	backoffLimit := int32(0)
+	activeDeadlineSeconds := int64((time.Hour).Seconds())
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: imagecache.Name + "-",
+			Namespace:    imagecache.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(imagecache, schema.GroupVersionKind{
+					Group:   fledgedv1alpha2.SchemeGroupVersion.Group,
+					Version: fledgedv1alpha2.SchemeGroupVersion.Version,
+					Kind:    "ImageCache",
+				}),
+			},
+			Labels: labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoffLimit,
+			ActiveDeadlineSeconds: &activeDeadlineSeconds,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: imagecache.Namespace,
+					Labels:    labels,
+				},
+				Spec: corev1.PodSpec{
+					NodeSelector: map[string]string{
+						"kubernetes.io/hostname": hostname,
+					},
+					Containers: []corev1.Container{
+						{
+							Image: image,
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "artifact-store",
+									MountPath: artifactStorePath,
+								},
+							},
+							ImagePullPolicy:          corev1.PullIfNotPresent,
+							TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "artifact-store",
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Path: artifactStorePath,
+									Type: &hostpathtype,
+								},
+							},
+						},
+					},
+					RestartPolicy: corev1.RestartPolicyNever,
+					Tolerations: []corev1.Toleration{
+						{
+							Operator: corev1.TolerationOpExists,
+						},
+					},
+				},
+			},
+		},
+	}
+	if serviceAccountName != "" {
+		job.Spec.Template.Spec.ServiceAccountName = serviceAccountName
+	}
+	if jobPriorityClassName != "" {
+		job.Spec.Template.Spec.PriorityClassName = jobPriorityClassName
+	}
+	return job, nil
+}
+
+// artifactStoreDir returns the directory of the artifact store into which an OCI
+// artifact is pulled
+func artifactStoreDir(artifactStorePath string, artifact string) string {
+	return strings.TrimSuffix(artifactStorePath, "/") + "/" + strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(artifact)
+}
+
 // newImageDeleteJob constructs a job manifest to delete an image from a node
 func newImageDeleteJob(imagecache *fledgedv1alpha2.ImageCache, image string, node *corev1.Node,
-	containerRuntimeVersion string, dockerclientimage string, serviceAccountName string,
+	containerRuntimeVersion string, dockerclientimage string, dockerclientwindowsimage string, serviceAccountName string,
 	imageDeleteJobHostNetwork bool, jobPriorityClassName string, criSocketPath string) (*batchv1.Job, error) {
 	hostname := node.Labels["kubernetes.io/hostname"]
 	socketPath := criSocketPath
@@ -250,6 +547,26 @@ func newImageDeleteJob(imagecache *fledgedv1alpha2.ImageCache, image string, nod
 		job.Spec.Template.Spec.Containers[0].VolumeMounts[0].MountPath = socketPath
 		job.Spec.Template.Spec.Volumes[0].VolumeSource.HostPath.Path = socketPath
 	}
+	if isWindowsNode(node) {
+		// Windows runtimes listen on named pipes, which are mounted into the
+		// Windows cri-client container using the same path as on the host.
+		pipePath := `\\.\pipe\containerd-containerd`
+		deleteCommand := "crictl.exe --runtime-endpoint=npipe:////./pipe/containerd-containerd --image-endpoint=npipe:////./pipe/containerd-containerd rmi " + image
+		if strings.Contains(containerRuntimeVersion, "docker") {
+			pipePath = `\\.\pipe\docker_engine`
+			deleteCommand = "docker.exe -H npipe:////./pipe/docker_engine image rm -f " + image
+		}
+		podSpec := &job.Spec.Template.Spec
+		podSpec.OS = &corev1.PodOS{Name: corev1.Windows}
+		podSpec.HostNetwork = false
+		podSpec.Containers[0].Image = dockerclientwindowsimage
+		podSpec.Containers[0].Command = []string{"cmd.exe"}
+		podSpec.Containers[0].Args = []string{"/c", deleteCommand}
+		podSpec.Containers[0].TerminationMessagePolicy = corev1.TerminationMessageFallbackToLogsOnError
+		podSpec.Containers[0].VolumeMounts[0].MountPath = pipePath
+		podSpec.Volumes[0].VolumeSource.HostPath.Path = pipePath
+		podSpec.Volumes[0].VolumeSource.HostPath.Type = nil
+	}
 	if serviceAccountName != "" {
 		job.Spec.Template.Spec.ServiceAccountName = serviceAccountName
 	}
@@ -259,12 +576,31 @@ func newImageDeleteJob(imagecache *fledgedv1alpha2.ImageCache, image string, nod
 	return job, nil
 }
 
+// isWindowsNode returns true if the node runs the Windows operating system
+func isWindowsNode(node *corev1.Node) bool {
+	if nodeOS, ok := node.Labels["kubernetes.io/os"]; ok {
+		return nodeOS == nodeOSWindows
+	}
+	return node.Status.NodeInfo.OperatingSystem == nodeOSWindows
+}
+
+// DigestPinned returns true if the image is referenced by digest. The content of a
+// digest never changes, so such images are only pulled when missing on the nodes.
+func DigestPinned(image string) bool {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return false
+	}
+	_, digested := named.(reference.Digested)
+	return digested
+}
+
 func checkIfImageNeedsToBePulled(imagePullPolicy string, image string, node *corev1.Node) (bool, error) {
 	if imagePullPolicy == string(corev1.PullIfNotPresent) {
 		if !strings.Contains(image, ":") && !strings.Contains(image, "@sha") {
 			return true, nil
 		}
-		if strings.Contains(image, ":latest") {
+		if strings.Contains(image, ":latest") && !DigestPinned(image) {
 			return true, nil
 		}
 		imageAlreadyPresent, err := imageAlreadyPresentInNode(image, node)
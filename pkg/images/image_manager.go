@@ -19,32 +19,83 @@ package images
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/golang/glog"
 	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/features"
+	"github.com/senthilrch/kube-fledged/pkg/nodestats"
+	"github.com/senthilrch/kube-fledged/pkg/registry"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apiserver/pkg/storage/names"
 	kubeinformers "k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 )
 
 const controllerAgentName = "fledged"
 const fakeJobPrefix = "fakejob-"
 
+// Pull strategies
+const (
+	// PullStrategyKubelet pulls images by running a pod using the image, so that
+	// the image is pulled by the kubelet
+	PullStrategyKubelet = "kubelet"
+	// PullStrategyContainerd pulls images on containerd nodes using the ctr client,
+	// which reports the download progress of each layer
+	PullStrategyContainerd = "containerd"
+)
+
+// DefaultContainerdNamespace is the containerd namespace of the images of the kubelet
+const DefaultContainerdNamespace = "k8s.io"
+
+// Limits of the log tail parsed for the layer progress of containerd pulls
+const (
+	pullProgressTailLines  int64 = 100
+	pullProgressLimitBytes int64 = 32768
+)
+
+// OrphanedJobDeleted is used as part of the Event 'reason' when an image pull/delete
+// job without a known work item or image cache is deleted
+const OrphanedJobDeleted = "OrphanedJobDeleted"
+
+// Running jobs without a known work item are deleted periodically, once they are older
+// than the grace period. The grace period covers jobs being created by a worker
+const (
+	orphanedJobsInterval   = time.Minute * 5
+	orphanedJobGracePeriod = time.Minute
+)
+
+// backgroundYieldInterval is the interval at which the background refresh worker checks
+// whether the work requests of the actions triggered by users have been processed
+const backgroundYieldInterval = time.Second
+
+// stuckJobsInterval is the interval at which jobs are checked for stuck pods
+const stuckJobsInterval = time.Second * 30
+
+// Limits of the log excerpt of a failed pod included in the image cache status
+const (
+	podLogTailLines  int64 = 20
+	podLogLimitBytes int64 = 4096
+	podLogTimeout          = time.Second * 10
+)
+
 const (
 	// ImageWorkResultStatusSucceeded means image pull/delete succeeded
 	ImageWorkResultStatusSucceeded = "succeeded"
@@ -56,33 +107,128 @@ const (
 	ImageWorkResultStatusAlreadyPulled = "alreadypulled"
 	//ImageWorkResultStatusUnknown  means status of image pull/delete unknown
 	ImageWorkResultStatusUnknown = "unknown"
+	// ImageWorkResultStatusAborted means image pull/delete was cancelled, on request or
+	// because the failure threshold of the image cache was exceeded
+	ImageWorkResultStatusAborted = "aborted"
+	// ImageWorkResultStatusDeferred means image pull was not dispatched because the
+	// circuit of its registry is open, or its node is overloaded
+	ImageWorkResultStatusDeferred = "deferred"
 )
 
 // ImageManager provides the functionalities for pulling and deleting images
 type ImageManager struct {
 	fledgedNameSpace          string
 	workqueue                 workqueue.RateLimitingInterface
-	imageworkqueue            workqueue.RateLimitingInterface
+	imageworkqueues           ImageWorkQueues
 	kubeclientset             kubernetes.Interface
 	imageworkstatus           map[string]ImageWorkResult
-	kubeInformerFactory       kubeinformers.SharedInformerFactory
 	podsLister                corelisters.PodLister
 	podsSynced                cache.InformerSynced
+	jobsLister                batchlisters.JobLister
+	jobsSynced                cache.InformerSynced
 	imagePullDeadlineDuration time.Duration
 	criClientImage            string
+	criClientWindowsImage     string
 	busyboxImage              string
 	imagePullPolicy           string
 	serviceAccountName        string
 	imageDeleteJobHostNetwork bool
 	jobPriorityClassName      string
+	jobSchedulerName          string
+	jobSecurityProfiles       fledgedv1alpha2.SecurityProfiles
+	guaranteedPulls           GuaranteedPulls
 	canDeleteJob              bool
 	criSocketPath             string
-	lock                      sync.RWMutex
+	statusUpdateInterval      time.Duration
+	statusUpdateBatchSize     int
+	stuckJobThreshold         time.Duration
+	pullRetry                 PullRetry
+	circuitBreaker            CircuitBreaker
+	loadThrottle              LoadThrottle
+	loadReader                nodestats.LoadReader
+	digestResolver            registry.DigestResolver
+	keychains                 *registry.Keychains
+	orasImage                 string
+	artifactStorePath         string
+	pullStrategy              string
+	containerdNamespace       string
+	// recorder relays events of image manager pods to the owning image cache
+	recorder record.EventRecorder
+	lock     sync.RWMutex
+	// progress accumulates the results of image cache actions under processing,
+	// so that status updates can be batched instead of written per result
+	progress     map[string]*imageCacheProgress
+	progressLock sync.Mutex
+	// aborted holds the image caches whose action was aborted, because it was
+	// cancelled or exceeded its failure threshold. It is guarded by lock
+	aborted map[string]abortedAction
+	// circuits holds the circuits of the registries with failed image pulls. It is
+	// guarded by circuitLock
+	circuits    map[string]*registryCircuit
+	circuitLock sync.Mutex
+	// loads holds the last load read from each node. It is guarded by loadLock
+	loads    map[string]cachedNodeLoad
+	loadLock sync.Mutex
+	// resolvedDigests holds the digests resolved for the pull references of the image
+	// cache actions under processing, so that an image pulled to many nodes is resolved
+	// once per action. It is guarded by digestLock
+	resolvedDigests map[string]map[string]string
+	digestLock      sync.Mutex
+	// configLock guards the settings changed at runtime: the images of the jobs and
+	// the maximum number of concurrent jobs
+	configLock        sync.RWMutex
+	maxConcurrentJobs int
+	// jobSlotWaits holds the work requests requeued to wait for a job slot. It is
+	// guarded by jobSlotLock
+	jobSlotWaits map[ImageWorkRequest]bool
+	jobSlotLock  sync.Mutex
+	// workDone is closed and replaced whenever a work item is done, to wake up the
+	// image cache actions waiting for their work items
+	workDone     chan struct{}
+	workDoneLock sync.Mutex
+}
+
+// abortedAction is the reason why an image cache action was aborted
+type abortedAction struct {
+	reason  string
+	message string
+}
+
+// imageCacheProgress counts the finished work items of an image cache action
+type imageCacheProgress struct {
+	completed int
+	failed    int
+	// pending is the number of results not yet flushed to the image cache status
+	pending int
 }
 
 // ImageWorkRequest has image name, node name, work type and imagecache
 type ImageWorkRequest struct {
-	Image                   string
+	Image string
+	// Artifact is true when Image refers to an OCI artifact to be pulled into the
+	// artifact store of the node, rather than a container image
+	Artifact bool
+	// Platform is the platform of the image to be pulled, when it is overridden
+	// in the cache spec
+	Platform string
+	// PullPolicy is the image pull policy of the image, when it is overridden in the
+	// cache spec
+	PullPolicy corev1.PullPolicy
+	// Fallbacks are the ordered fallback references of the image, when they are
+	// specified in the cache spec
+	Fallbacks *[]string
+	// Source is the fallback reference the image is pulled from, once the pulls from
+	// the previous references failed. The image is pulled from Image if empty
+	Source string
+	// TargetRef is the reference the image is tagged as on the node, when it is
+	// specified in the cache spec
+	TargetRef string
+	// PullDeadline overrides the image pull deadline of the image manager for the
+	// image cache action. It is only set on the request ending the action
+	PullDeadline time.Duration
+	// Total is the number of work requests of the image cache action, against which a
+	// failure threshold given as a percentage is resolved
+	Total                   int
 	Node                    *corev1.Node
 	ContainerRuntimeVersion string
 	WorkType                WorkType
@@ -95,6 +241,17 @@ type ImageWorkResult struct {
 	Status           string
 	Reason           string
 	Message          string
+	Log              string
+	// Retries is the number of times the image pull was retried
+	Retries int
+	// retrying is true while the retry of the failed image pull is pending
+	retrying bool
+	// fallback is true while the failed image pull is switching to the next fallback
+	// reference of the image
+	fallback bool
+	// resolvedDigest is the digest the image resolved to in the registry before the
+	// pull, against which the image pulled on the node is verified
+	resolvedDigest string
 }
 
 // WorkType refers to type of work to be done by sync handler
@@ -102,12 +259,14 @@ type WorkType string
 
 // Work types
 const (
-	ImageCacheCreate       WorkType = "create"
-	ImageCacheUpdate       WorkType = "update"
-	ImageCacheDelete       WorkType = "delete"
-	ImageCacheStatusUpdate WorkType = "statusupdate"
-	ImageCacheRefresh      WorkType = "refresh"
-	ImageCachePurge        WorkType = "purge"
+	ImageCacheCreate         WorkType = "create"
+	ImageCacheUpdate         WorkType = "update"
+	ImageCacheDelete         WorkType = "delete"
+	ImageCacheStatusUpdate   WorkType = "statusupdate"
+	ImageCacheRefresh        WorkType = "refresh"
+	ImageCachePurge          WorkType = "purge"
+	ImageCacheProgressUpdate WorkType = "progressupdate"
+	ImageCacheCancel         WorkType = "cancel"
 )
 
 // WorkQueueKey is an item in the sync handler's work queue
@@ -116,52 +275,142 @@ type WorkQueueKey struct {
 	ObjKey        string
 	Status        *map[string]ImageWorkResult
 	OldImageCache *fledgedv1alpha2.ImageCache
+	Progress      *fledgedv1alpha2.ImageCacheProgress
+	// Entries are the indexes of the cache spec entries whose images are refreshed. All
+	// the entries are refreshed if nil
+	Entries *[]int
+	// Nodes are the names of the nodes the action is restricted to, e.g. the nodes whose
+	// labels changed. The action targets all the nodes if nil
+	Nodes *[]string
+	// UnmatchedEntries are the indexes of the cache spec entries whose node selector no
+	// longer matches the nodes of the action. Their images are purged from the nodes
+	UnmatchedEntries *[]int
+	// Background is true for the refreshes started by the controller itself, whose
+	// image work is queued behind the actions triggered by users
+	Background bool
+	// DryRun is true for the dry runs of refreshes, which are planned and estimated
+	// without pulling any image
+	DryRun bool
+	// Wave is the wave, from 2, of the staged rollout continued by the action. Actions
+	// of image caches specifying a rollout start with its first wave if 0
+	Wave int
 }
 
-// NewImageManager returns a new image manager object
-func NewImageManager(
-	workqueue workqueue.RateLimitingInterface,
-	imageworkqueue workqueue.RateLimitingInterface,
-	kubeclientset kubernetes.Interface,
-	namespace string,
-	imagePullDeadlineDuration time.Duration,
-	criClientImage, busyboxImage, imagePullPolicy, serviceAccountName string,
-	imageDeleteJobHostNetwork bool,
-	jobPriorityClassName string,
-	canDeleteJob bool,
-	criSocketPath string) (*ImageManager, coreinformers.PodInformer) {
+// ImageManagerOptions are the settings of the image manager
+type ImageManagerOptions struct {
+	// Namespace is the namespace of kube-fledged
+	Namespace                 string
+	ImagePullDeadlineDuration time.Duration
+	// CRIClientImage, CRIClientWindowsImage and BusyboxImage are the images of the
+	// jobs pulling and deleting images
+	CRIClientImage            string
+	CRIClientWindowsImage     string
+	BusyboxImage              string
+	ImagePullPolicy           string
+	ServiceAccountName        string
+	ImageDeleteJobHostNetwork bool
+	JobPriorityClassName      string
+	JobSchedulerName          string
+	JobSecurityProfiles       fledgedv1alpha2.SecurityProfiles
+	GuaranteedPulls           GuaranteedPulls
+	CanDeleteJob              bool
+	CRISocketPath             string
+	// StatusUpdateInterval and StatusUpdateBatchSize pace the updates of the progress
+	// of image cache actions
+	StatusUpdateInterval  time.Duration
+	StatusUpdateBatchSize int
+	StuckJobThreshold     time.Duration
+	PullRetry             PullRetry
+	CircuitBreaker        CircuitBreaker
+	LoadThrottle          LoadThrottle
+	// LoadReader reads the load of the nodes for the load throttle
+	LoadReader nodestats.LoadReader
+	// DigestResolver resolves the digests of the images pulled. Nil disables the
+	// verification of the digests
+	DigestResolver registry.DigestResolver
+	// Keychains are the credentials of the image pull secrets of the image caches,
+	// used to resolve the digests of the images
+	Keychains           *registry.Keychains
+	ORASImage           string
+	ArtifactStorePath   string
+	PullStrategy        string
+	ContainerdNamespace string
+}
 
+// NewInformerFactory returns the informer factory of the jobs of the image manager and
+// of their pods, selected by their labels
+func NewInformerFactory(kubeclientset kubernetes.Interface) kubeinformers.SharedInformerFactory {
 	appEqKubefledged, _ := labels.NewRequirement("app", selection.Equals, []string{"kubefledged"})
 	kubefledgedEqImagemanager, _ := labels.NewRequirement("kubefledged", selection.Equals, []string{"kubefledged-image-manager"})
 	labelSelector := labels.NewSelector()
 	labelSelector = labelSelector.Add(*appEqKubefledged, *kubefledgedEqImagemanager)
 
-	kubeInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(
+	return kubeinformers.NewSharedInformerFactoryWithOptions(
 		kubeclientset,
 		time.Second*30,
 		kubeinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
 			options.LabelSelector = labelSelector.String()
 		}))
+}
+
+// NewImageManager returns a new image manager object. The pods and jobs are watched
+// with the informers of kubeInformerFactory, which is expected to select the jobs of
+// the image manager and their pods (see NewInformerFactory).
+func NewImageManager(
+	workqueue workqueue.RateLimitingInterface,
+	imageworkqueues ImageWorkQueues,
+	kubeclientset kubernetes.Interface,
+	kubeInformerFactory kubeinformers.SharedInformerFactory,
+	opts ImageManagerOptions,
+	recorder record.EventRecorder) (*ImageManager, coreinformers.PodInformer) {
+
 	podInformer := kubeInformerFactory.Core().V1().Pods()
+	jobInformer := kubeInformerFactory.Batch().V1().Jobs()
 
 	imagemanager := &ImageManager{
-		fledgedNameSpace:          namespace,
+		fledgedNameSpace:          opts.Namespace,
 		workqueue:                 workqueue,
-		imageworkqueue:            imageworkqueue,
+		imageworkqueues:           imageworkqueues,
 		kubeclientset:             kubeclientset,
 		imageworkstatus:           make(map[string]ImageWorkResult),
-		kubeInformerFactory:       kubeInformerFactory,
 		podsLister:                podInformer.Lister(),
 		podsSynced:                podInformer.Informer().HasSynced,
-		imagePullDeadlineDuration: imagePullDeadlineDuration,
-		criClientImage:            criClientImage,
-		busyboxImage:              busyboxImage,
-		imagePullPolicy:           imagePullPolicy,
-		serviceAccountName:        serviceAccountName,
-		imageDeleteJobHostNetwork: imageDeleteJobHostNetwork,
-		jobPriorityClassName:      jobPriorityClassName,
-		canDeleteJob:              canDeleteJob,
-		criSocketPath:             criSocketPath,
+		jobsLister:                jobInformer.Lister(),
+		jobsSynced:                jobInformer.Informer().HasSynced,
+		imagePullDeadlineDuration: opts.ImagePullDeadlineDuration,
+		criClientImage:            opts.CRIClientImage,
+		criClientWindowsImage:     opts.CRIClientWindowsImage,
+		busyboxImage:              opts.BusyboxImage,
+		imagePullPolicy:           opts.ImagePullPolicy,
+		serviceAccountName:        opts.ServiceAccountName,
+		imageDeleteJobHostNetwork: opts.ImageDeleteJobHostNetwork,
+		jobPriorityClassName:      opts.JobPriorityClassName,
+		jobSchedulerName:          opts.JobSchedulerName,
+		jobSecurityProfiles:       opts.JobSecurityProfiles,
+		guaranteedPulls:           opts.GuaranteedPulls,
+		canDeleteJob:              opts.CanDeleteJob,
+		criSocketPath:             opts.CRISocketPath,
+		statusUpdateInterval:      opts.StatusUpdateInterval,
+		statusUpdateBatchSize:     opts.StatusUpdateBatchSize,
+		stuckJobThreshold:         opts.StuckJobThreshold,
+		pullRetry:                 opts.PullRetry,
+		circuitBreaker:            opts.CircuitBreaker,
+		loadThrottle:              opts.LoadThrottle,
+		loadReader:                opts.LoadReader,
+		digestResolver:            opts.DigestResolver,
+		keychains:                 opts.Keychains,
+		orasImage:                 opts.ORASImage,
+		artifactStorePath:         opts.ArtifactStorePath,
+		pullStrategy:              opts.PullStrategy,
+		containerdNamespace:       opts.ContainerdNamespace,
+		progress:                  make(map[string]*imageCacheProgress),
+		resolvedDigests:           make(map[string]map[string]string),
+		jobSlotWaits:              make(map[ImageWorkRequest]bool),
+		aborted:                   make(map[string]abortedAction),
+		circuits:                  make(map[string]*registryCircuit),
+		loads:                     make(map[string]cachedNodeLoad),
+		recorder:                  recorder,
+		workDone:                  make(chan struct{}),
 	}
 	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		//AddFunc: ,
@@ -174,6 +423,11 @@ func NewImageManager(
 				return
 			}
 			glog.V(4).Infof("Pod %s changed status to %s", newPod.Name, newPod.Status.Phase)
+			imagemanager.relayPodEvents(oldPod, newPod)
+			if imagemanager.failTerminalPull(oldPod, newPod) {
+				return
+			}
+			imagemanager.handlePullBackOff(oldPod, newPod)
 			if (newPod.Status.Phase == corev1.PodSucceeded || newPod.Status.Phase == corev1.PodFailed) &&
 				(oldPod.Status.Phase != corev1.PodSucceeded && oldPod.Status.Phase != corev1.PodFailed) {
 				imagemanager.handlePodStatusChange(newPod)
@@ -181,26 +435,40 @@ func NewImageManager(
 		},
 		//DeleteFunc: ,
 	})
+	// The pods of jobs may be deleted before reporting their result, e.g. when their
+	// node is removed, hence the results are also taken from the jobs
+	jobInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old, new interface{}) {
+			newJob := new.(*batchv1.Job)
+			oldJob := old.(*batchv1.Job)
+			if newJob.ResourceVersion == oldJob.ResourceVersion {
+				return
+			}
+			imagemanager.handleJobStatusChange(oldJob, newJob)
+		},
+	})
 	return imagemanager, podInformer
 }
 
 func (m *ImageManager) handlePodStatusChange(pod *corev1.Pod) {
 	glog.V(4).Infof("Pod %s changed status to %s", pod.Name, pod.Status.Phase)
+	job := podJobName(pod)
 	m.lock.RLock()
-	iwres, ok := m.imageworkstatus[pod.Labels["job-name"]]
+	iwres, ok := m.imageworkstatus[job]
 	m.lock.RUnlock()
 	// Corresponding job might have expired and got deleted.
-	// ignore pod status change for such jobs
-	if !ok {
+	// ignore pod status change for such jobs, and for the work items done already,
+	// e.g. aborted or failed because of a terminal image pull failure
+	if !ok || iwres.Status != ImageWorkResultStatusJobCreated || iwres.retrying {
 		return
 	}
 
 	if pod.Status.Phase == corev1.PodSucceeded {
 		iwres.Status = ImageWorkResultStatusSucceeded
 		if iwres.ImageWorkRequest.WorkType == ImageCachePurge {
-			glog.Infof("Job %s succeeded (delete:- %s --> %s, runtime: %s)", pod.Labels["job-name"], iwres.ImageWorkRequest.Image, iwres.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"], iwres.ImageWorkRequest.ContainerRuntimeVersion)
+			glog.Infof("Job %s succeeded (delete:- %s --> %s, runtime: %s)", job, iwres.ImageWorkRequest.Image, iwres.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"], iwres.ImageWorkRequest.ContainerRuntimeVersion)
 		} else {
-			glog.Infof("Job %s succeeded (pull:- %s --> %s, runtime: %s)", pod.Labels["job-name"], iwres.ImageWorkRequest.Image, iwres.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"], iwres.ImageWorkRequest.ContainerRuntimeVersion)
+			glog.Infof("Job %s succeeded (pull:- %s --> %s, runtime: %s)", job, iwres.ImageWorkRequest.Image, iwres.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"], iwres.ImageWorkRequest.ContainerRuntimeVersion)
 		}
 	}
 	if pod.Status.Phase == corev1.PodFailed {
@@ -215,21 +483,585 @@ func (m *ImageManager) handlePodStatusChange(pod *corev1.Pod) {
 			iwres.Message = fledgedv1alpha2.ImageCacheMessageImagePullStatusUnknown
 		}
 		if iwres.ImageWorkRequest.WorkType == ImageCachePurge {
-			glog.Infof("Job %s failed (delete: %s --> %s)", pod.Labels["job-name"], iwres.ImageWorkRequest.Image, iwres.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"])
+			glog.Infof("Job %s failed (delete: %s --> %s)", job, iwres.ImageWorkRequest.Image, iwres.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"])
 		} else {
-			glog.Infof("Job %s failed (pull: %s --> %s)", pod.Labels["job-name"], iwres.ImageWorkRequest.Image, iwres.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"])
+			glog.Infof("Job %s failed (pull: %s --> %s)", job, iwres.ImageWorkRequest.Image, iwres.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"])
+		}
+	}
+	m.recordRegistryOutcome(iwres)
+	if iwres.Status == ImageWorkResultStatusFailed && (m.fallBackPull(job, iwres, pod) || m.retryPull(job, iwres, pod)) {
+		return
+	}
+	// The pull of an image whose digest differs from the digest resolved from the
+	// registry is not retried, since a retry would likely yield the same image
+	m.verifyDigest(&iwres, pod)
+	m.lock.Lock()
+	// the result may have been recorded from the job in the meantime
+	if current, ok := m.imageworkstatus[job]; !ok || current.Status != ImageWorkResultStatusJobCreated || current.retrying {
+		m.lock.Unlock()
+		return
+	}
+	m.imageworkstatus[job] = iwres
+	m.lock.Unlock()
+	m.notifyWorkDone()
+	m.recordProgress(iwres.ImageWorkRequest.Imagecache, iwres.Status == ImageWorkResultStatusFailed)
+	if iwres.Status == ImageWorkResultStatusFailed {
+		m.enforceFailureThreshold(iwres.ImageWorkRequest)
+	}
+}
+
+// attachFailureExcerpts attaches an excerpt of the failure of its pod to each failed
+// image work result of the image cache. A container which terminated without a
+// termination message contributes the last lines of its log, and a waiting container,
+// e.g. in ErrImagePull or ImagePullBackOff, the events of the kubelet reporting the
+// failure. The excerpts are fetched while processing the results rather than in the
+// pod event handler, since fetching them blocks on the API server
+func (m *ImageManager) attachFailureExcerpts(imageCacheName string) {
+	failed := map[string]ImageWorkResult{}
+	m.lock.RLock()
+	for job, iwres := range m.imageworkstatus {
+		if iwres.ImageWorkRequest.Imagecache.Name == imageCacheName && iwres.Status == ImageWorkResultStatusFailed && iwres.Log == "" {
+			failed[job] = iwres
+		}
+	}
+	m.lock.RUnlock()
+	for job, iwres := range failed {
+		pods, err := m.podsLister.Pods(iwres.ImageWorkRequest.Imagecache.Namespace).
+			List(labels.Set(map[string]string{"job-name": job}).AsSelector())
+		if err != nil || len(pods) != 1 {
+			continue
+		}
+		excerpt := m.podFailureExcerpt(pods[0])
+		if excerpt == "" {
+			continue
+		}
+		m.lock.Lock()
+		if current, ok := m.imageworkstatus[job]; ok {
+			current.Log = excerpt
+			m.imageworkstatus[job] = current
+		}
+		m.lock.Unlock()
+	}
+}
+
+// podFailureExcerpt returns an excerpt of the failure of the container of the pod of
+// an image pull/delete job, or an empty excerpt if the container reported the failure
+// in its termination message
+func (m *ImageManager) podFailureExcerpt(pod *corev1.Pod) string {
+	if len(pod.Status.ContainerStatuses) != 1 {
+		return ""
+	}
+	status := pod.Status.ContainerStatuses[0]
+	if status.State.Terminated != nil && status.State.Terminated.Message == "" {
+		return m.podLogExcerpt(pod, status.Name, podLogTailLines, podLogLimitBytes)
+	}
+	if status.State.Waiting != nil {
+		return m.podFailedEventsExcerpt(pod, podLogTailLines, podLogLimitBytes)
+	}
+	return ""
+}
+
+// podFailedEventsExcerpt returns the messages of the last Failed events of the pod,
+// one per line. Errors are logged and an empty excerpt returned
+func (m *ImageManager) podFailedEventsExcerpt(pod *corev1.Pod, tailLines, limitBytes int64) string {
+	ctx, cancel := context.WithTimeout(context.TODO(), podLogTimeout)
+	defer cancel()
+	fieldSelector := fields.Set{
+		"involvedObject.kind":      "Pod",
+		"involvedObject.name":      pod.Name,
+		"involvedObject.namespace": pod.Namespace,
+		"reason":                   "Failed",
+	}.AsSelector().String()
+	eventlist, err := m.kubeclientset.CoreV1().Events(pod.Namespace).
+		List(ctx, metav1.ListOptions{FieldSelector: fieldSelector})
+	if err != nil {
+		glog.Errorf("Error listing events for pod (%s): %v", pod.Name, err)
+		return ""
+	}
+	events := eventlist.Items
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].LastTimestamp.Before(&events[j].LastTimestamp)
+	})
+	if int64(len(events)) > tailLines {
+		events = events[int64(len(events))-tailLines:]
+	}
+	lines := make([]string, 0, len(events))
+	for _, event := range events {
+		lines = append(lines, event.Message)
+	}
+	excerpt := strings.Join(lines, "\n")
+	if int64(len(excerpt)) > limitBytes {
+		excerpt = excerpt[int64(len(excerpt))-limitBytes:]
+	}
+	return excerpt
+}
+
+// podLogExcerpt returns the last lines of the log of a container of the pod. Errors
+// are logged and an empty excerpt returned, since the log is informational only
+func (m *ImageManager) podLogExcerpt(pod *corev1.Pod, container string, tailLines, limitBytes int64) string {
+	ctx, cancel := context.WithTimeout(context.TODO(), podLogTimeout)
+	defer cancel()
+	logs, err := m.kubeclientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container:  container,
+		TailLines:  &tailLines,
+		LimitBytes: &limitBytes,
+	}).DoRaw(ctx)
+	if err != nil {
+		glog.Errorf("Error getting log of pod %s: %v", pod.Name, err)
+		return ""
+	}
+	return strings.TrimSpace(string(logs))
+}
+
+// relayPodEvents records an event on the owning image cache when a pod of an image
+// pull/delete job becomes unschedulable, fails to pull its image or is evicted, so
+// that these problems are visible without inspecting the per-node pods
+func (m *ImageManager) relayPodEvents(oldPod, newPod *corev1.Pod) {
+	if m.recorder == nil {
+		return
+	}
+	m.lock.RLock()
+	iwres, ok := m.imageworkstatus[podJobName(newPod)]
+	m.lock.RUnlock()
+	if !ok || iwres.ImageWorkRequest.Imagecache == nil {
+		return
+	}
+	for _, reason := range podProblems(newPod) {
+		if containsString(podProblems(oldPod), reason) {
+			continue
+		}
+		nodeName := newPod.Spec.NodeName
+		if iwres.ImageWorkRequest.Node != nil {
+			nodeName = iwres.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"]
+		}
+		m.recorder.Eventf(iwres.ImageWorkRequest.Imagecache, corev1.EventTypeWarning, reason,
+			"Pod %s of job %s (image: %s, node: %s): %s", newPod.Name, podJobName(newPod),
+			iwres.ImageWorkRequest.Image, nodeName, podProblemMessage(newPod, reason))
+	}
+}
+
+// Reasons of pod problems relayed to the owning image cache
+const (
+	podReasonFailedScheduling = "FailedScheduling"
+	podReasonErrImagePull     = "ErrImagePull"
+	podReasonImagePullBackOff = "ImagePullBackOff"
+	podReasonInvalidImageName = "InvalidImageName"
+	podReasonEvicted          = "Evicted"
+)
+
+// podProblems returns the reasons of the problems the pod currently has
+func podProblems(pod *corev1.Pod) []string {
+	problems := []string{}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodScheduled && c.Status == corev1.ConditionFalse && c.Reason == corev1.PodReasonUnschedulable {
+			problems = append(problems, podReasonFailedScheduling)
+		}
+	}
+	statuses := append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		if cs.State.Waiting == nil {
+			continue
+		}
+		switch cs.State.Waiting.Reason {
+		case podReasonErrImagePull, podReasonImagePullBackOff, podReasonInvalidImageName:
+			if !containsString(problems, cs.State.Waiting.Reason) {
+				problems = append(problems, cs.State.Waiting.Reason)
+			}
+		}
+	}
+	if pod.Status.Reason == podReasonEvicted {
+		problems = append(problems, podReasonEvicted)
+	}
+	return problems
+}
+
+// podProblemMessage returns the message reported by kubernetes for a pod problem
+func podProblemMessage(pod *corev1.Pod, reason string) string {
+	switch reason {
+	case podReasonFailedScheduling:
+		for _, c := range pod.Status.Conditions {
+			if c.Type == corev1.PodScheduled {
+				return c.Message
+			}
+		}
+	case podReasonEvicted:
+		return pod.Status.Message
+	default:
+		statuses := append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+		statuses = append(statuses, pod.Status.ContainerStatuses...)
+		for _, cs := range statuses {
+			if cs.State.Waiting != nil && cs.State.Waiting.Reason == reason {
+				return cs.State.Waiting.Message
+			}
+		}
+	}
+	return ""
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteOrphanedJobs deletes running image pull/delete jobs that are not tracked as
+// a work item, e.g. jobs left behind by a crash of the controller
+func (m *ImageManager) deleteOrphanedJobs() {
+	// The informer of the jobs only holds the jobs of the image manager
+	jobs, err := m.jobsLister.List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Error listing jobs: %v", err)
+		return
+	}
+	deletePropagation := metav1.DeletePropagationBackground
+	for _, job := range jobs {
+		// Finished jobs are retained as per the job retention policy
+		if job.Status.Succeeded > 0 || job.Status.Failed > 0 {
+			continue
+		}
+		if time.Since(job.CreationTimestamp.Time) < orphanedJobGracePeriod {
+			continue
+		}
+		m.lock.RLock()
+		_, ok := m.imageworkstatus[job.Name]
+		m.lock.RUnlock()
+		if ok {
+			continue
+		}
+		if err := m.kubeclientset.BatchV1().Jobs(job.Namespace).
+			Delete(context.TODO(), job.Name, metav1.DeleteOptions{PropagationPolicy: &deletePropagation}); err != nil {
+			glog.Errorf("Error deleting orphaned job %s: %v", job.Name, err)
+			continue
+		}
+		glog.Infof("Orphaned job %s deleted (imagecache: %s)", job.Name, jobImageCache(job))
+		if m.recorder != nil {
+			m.recorder.Eventf(job, corev1.EventTypeWarning, OrphanedJobDeleted,
+				"Job %s deleted: it is not tracked as a work item of image cache %q", job.Name, jobImageCache(job))
+		}
+	}
+}
+
+// remediateStuckJobs fails the work items whose pod has been stuck in pending for longer
+// than the stuck job threshold, and deletes their jobs. A pod is stuck if it cannot be
+// scheduled, or if its init containers did not complete (e.g. volume mount issues).
+// Pods pulling the image of the main container are not considered stuck, since large
+// images legitimately take time to pull.
+func (m *ImageManager) remediateStuckJobs() {
+	type stuckJob struct {
+		iwres  ImageWorkResult
+		reason string
+	}
+	stuckJobs := map[string]stuckJob{}
+	m.lock.RLock()
+	for job, iwres := range m.imageworkstatus {
+		if iwres.Status != ImageWorkResultStatusJobCreated || iwres.ImageWorkRequest.Imagecache == nil {
+			continue
+		}
+		pods, err := m.podsLister.Pods(iwres.ImageWorkRequest.Imagecache.Namespace).
+			List(labels.Set(map[string]string{"job-name": job}).AsSelector())
+		if err != nil {
+			glog.Errorf("Error listing Pods: %v", err)
+			continue
+		}
+		if len(pods) != 1 {
+			continue
+		}
+		if reason := podStuckReason(pods[0], m.stuckJobThreshold); reason != "" {
+			stuckJobs[job] = stuckJob{iwres: iwres, reason: reason}
+		}
+	}
+	m.lock.RUnlock()
+
+	deletePropagation := metav1.DeletePropagationBackground
+	for job, stuck := range stuckJobs {
+		iwres := stuck.iwres
+		iwres.Status = ImageWorkResultStatusFailed
+		iwres.Reason = fledgedv1alpha2.ImageCacheReasonJobStuck
+		iwres.Message = fmt.Sprintf("Pod of job %s stuck in pending for more than %s: %s", job, m.stuckJobThreshold, stuck.reason)
+		m.lock.Lock()
+		// the pod may have changed status in the meantime
+		if current, ok := m.imageworkstatus[job]; !ok || current.Status != ImageWorkResultStatusJobCreated {
+			m.lock.Unlock()
+			continue
+		}
+		m.imageworkstatus[job] = iwres
+		m.lock.Unlock()
+		m.notifyWorkDone()
+		glog.Warningf("Job %s stuck (image: %s --> %s): %s", job, iwres.ImageWorkRequest.Image, iwres.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"], stuck.reason)
+		if err := m.kubeclientset.BatchV1().Jobs(iwres.ImageWorkRequest.Imagecache.Namespace).
+			Delete(context.TODO(), job, metav1.DeleteOptions{PropagationPolicy: &deletePropagation}); err != nil {
+			glog.Errorf("Error deleting stuck job %s: %v", job, err)
+		}
+		if m.recorder != nil {
+			m.recorder.Event(iwres.ImageWorkRequest.Imagecache, corev1.EventTypeWarning, iwres.Reason, iwres.Message)
+		}
+		m.recordProgress(iwres.ImageWorkRequest.Imagecache, true)
+		m.enforceFailureThreshold(iwres.ImageWorkRequest)
+	}
+}
+
+// podStuckReason returns why the pod is stuck in pending for longer than threshold,
+// or an empty string if it is not stuck
+func podStuckReason(pod *corev1.Pod, threshold time.Duration) string {
+	if pod.Status.Phase != corev1.PodPending || time.Since(pod.CreationTimestamp.Time) < threshold {
+		return ""
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodScheduled && c.Status == corev1.ConditionFalse {
+			return fmt.Sprintf("%s: %s", c.Reason, c.Message)
+		}
+	}
+	if pod.Spec.NodeName == "" {
+		return "pod not scheduled"
+	}
+	for _, cs := range pod.Status.InitContainerStatuses {
+		if cs.State.Terminated == nil {
+			if cs.State.Waiting != nil {
+				return fmt.Sprintf("init container %s waiting: %s", cs.Name, cs.State.Waiting.Reason)
+			}
+			return fmt.Sprintf("init container %s not completed", cs.Name)
+		}
+	}
+	if len(pod.Status.InitContainerStatuses) < len(pod.Spec.InitContainers) {
+		return "init containers not started"
+	}
+	return ""
+}
+
+// recordProgress counts a finished work item of an image cache action. The progress
+// is flushed to the image cache status once the batch size is reached, or by the
+// periodic flush, whichever comes first.
+func (m *ImageManager) recordProgress(imageCache *fledgedv1alpha2.ImageCache, failed bool) {
+	if imageCache == nil {
+		return
+	}
+	objKey, err := cache.MetaNamespaceKeyFunc(imageCache)
+	if err != nil {
+		glog.Errorf("Error from cache.MetaNamespaceKeyFunc(imageCache): %v", err)
+		return
+	}
+	m.progressLock.Lock()
+	defer m.progressLock.Unlock()
+	p, ok := m.progress[objKey]
+	if !ok {
+		p = &imageCacheProgress{}
+		m.progress[objKey] = p
+	}
+	if failed {
+		p.failed++
+	} else {
+		p.completed++
+	}
+	p.pending++
+	if m.statusUpdateBatchSize > 0 && p.pending >= m.statusUpdateBatchSize {
+		m.enqueueProgress(objKey, p, nil)
+	}
+}
+
+// enforceFailureThreshold aborts the image cache action of a failed work request once
+// its failures exceed the failure threshold of the image cache. The jobs of the action
+// still running are deleted, and the work requests not yet dispatched are skipped.
+func (m *ImageManager) enforceFailureThreshold(iwr ImageWorkRequest) {
+	imageCache := iwr.Imagecache
+	if imageCache == nil || imageCache.Spec.FailureThreshold == nil || iwr.WorkType == ImageCachePurge {
+		return
+	}
+	objKey, err := cache.MetaNamespaceKeyFunc(imageCache)
+	if err != nil {
+		glog.Errorf("Error from cache.MetaNamespaceKeyFunc(imageCache): %v", err)
+		return
+	}
+	threshold, err := intstr.GetScaledValueFromIntOrPercent(imageCache.Spec.FailureThreshold, iwr.Total, false)
+	if err != nil {
+		glog.Errorf("Invalid failure threshold of image cache %s: %v", objKey, err)
+		return
+	}
+	m.progressLock.Lock()
+	failed := 0
+	if p, ok := m.progress[objKey]; ok {
+		failed = p.failed
+	}
+	m.progressLock.Unlock()
+	if failed <= threshold {
+		return
+	}
+
+	message := fmt.Sprintf("%d image pulls failed, more than the failure threshold of %s: image pull cancelled",
+		failed, imageCache.Spec.FailureThreshold.String())
+	if m.AbortImageCacheAction(imageCache, fledgedv1alpha2.ImageCacheReasonFailureThresholdExceeded, message) && m.recorder != nil {
+		m.recorder.Event(imageCache, corev1.EventTypeWarning, fledgedv1alpha2.ImageCacheReasonFailureThresholdExceeded, message)
+	}
+}
+
+// AbortImageCacheAction aborts the action under processing of an image cache. Its
+// running jobs are deleted, and its work requests not yet dispatched are skipped. The
+// work items are reported as aborted with the given reason and message. It returns
+// false if the action was already aborted.
+func (m *ImageManager) AbortImageCacheAction(imageCache *fledgedv1alpha2.ImageCache, reason, message string) bool {
+	objKey, err := cache.MetaNamespaceKeyFunc(imageCache)
+	if err != nil {
+		glog.Errorf("Error from cache.MetaNamespaceKeyFunc(imageCache): %v", err)
+		return false
+	}
+	jobs := []string{}
+	m.lock.Lock()
+	if _, ok := m.aborted[objKey]; ok {
+		m.lock.Unlock()
+		return false
+	}
+	m.aborted[objKey] = abortedAction{reason: reason, message: message}
+	for job, iwres := range m.imageworkstatus {
+		if iwres.Status != ImageWorkResultStatusJobCreated || !sameImageCache(iwres.ImageWorkRequest.Imagecache, imageCache) {
+			continue
+		}
+		iwres.Status = ImageWorkResultStatusAborted
+		iwres.Reason = reason
+		iwres.Message = message
+		m.imageworkstatus[job] = iwres
+		jobs = append(jobs, job)
+	}
+	m.lock.Unlock()
+	m.notifyWorkDone()
+
+	glog.Warningf("Image cache %s aborted: %s. %d running jobs deleted", objKey, message, len(jobs))
+	deletePropagation := metav1.DeletePropagationBackground
+	for _, job := range jobs {
+		if err := m.kubeclientset.BatchV1().Jobs(imageCache.Namespace).
+			Delete(context.TODO(), job, metav1.DeleteOptions{PropagationPolicy: &deletePropagation}); err != nil {
+			glog.Errorf("Error deleting job %s: %v", job, err)
 		}
 	}
+	return true
+}
+
+// BeginImageCacheAction must be called before the work requests of a new action of
+// the image cache are queued, so that an abort of a previous action that raced with
+// its completion does not affect the new action
+func (m *ImageManager) BeginImageCacheAction(imageCache *fledgedv1alpha2.ImageCache) {
+	objKey, err := cache.MetaNamespaceKeyFunc(imageCache)
+	if err != nil {
+		return
+	}
 	m.lock.Lock()
-	m.imageworkstatus[pod.Labels["job-name"]] = iwres
+	delete(m.aborted, objKey)
 	m.lock.Unlock()
 }
 
+// sameImageCache returns true if both image caches have the same namespace and name
+func sameImageCache(a, b *fledgedv1alpha2.ImageCache) bool {
+	return a != nil && b != nil && a.Namespace == b.Namespace && a.Name == b.Name
+}
+
+// flushProgress enqueues a status update for every image cache action that has
+// results not yet reflected in its status, or pulls reporting layer progress
+func (m *ImageManager) flushProgress() {
+	pulls := m.collectPullProgress()
+	m.progressLock.Lock()
+	defer m.progressLock.Unlock()
+	for objKey := range pulls {
+		if _, ok := m.progress[objKey]; !ok {
+			m.progress[objKey] = &imageCacheProgress{}
+		}
+	}
+	for objKey, p := range m.progress {
+		if p.pending > 0 || len(pulls[objKey]) > 0 {
+			m.enqueueProgress(objKey, p, pulls[objKey])
+		}
+	}
+}
+
+// enqueueProgress must be called with progressLock held
+func (m *ImageManager) enqueueProgress(objKey string, p *imageCacheProgress, pulls []fledgedv1alpha2.ImagePullProgress) {
+	p.pending = 0
+	m.workqueue.Add(WorkQueueKey{
+		WorkType: ImageCacheProgressUpdate,
+		ObjKey:   objKey,
+		Progress: &fledgedv1alpha2.ImageCacheProgress{Completed: p.completed, Failed: p.failed, Pulls: pulls},
+	})
+}
+
+// useContainerdPull returns true if the image of the work request is pulled using the
+// containerd pull strategy. The ctr client does not use image pull secrets, so pulls
+// of image caches with image pull secrets are left to the kubelet. Images tagged as a
+// target reference are pulled by retag jobs instead.
+func (m *ImageManager) useContainerdPull(iwr ImageWorkRequest) bool {
+	return iwr.WorkType != ImageCachePurge && iwr.TargetRef == "" && m.pulledWithContainerd(iwr)
+}
+
+// pulledWithContainerd returns true if the image of the work request is, or was, pulled
+// using the containerd pull strategy
+func (m *ImageManager) pulledWithContainerd(iwr ImageWorkRequest) bool {
+	return m.pullStrategy == PullStrategyContainerd && features.Enabled(features.ContainerdPull) && !iwr.Artifact &&
+		iwr.Imagecache != nil && len(iwr.Imagecache.Spec.ImagePullSecrets) == 0 &&
+		iwr.Node != nil && !isWindowsNode(iwr.Node) &&
+		strings.Contains(iwr.ContainerRuntimeVersion, "containerd")
+}
+
+// useContainerdDelete returns true if the image of the work request is deleted using
+// the ctr client. The images pulled into a containerd namespace other than the one of
+// the kubelet are not visible to crictl.
+func (m *ImageManager) useContainerdDelete(iwr ImageWorkRequest) bool {
+	return m.containerdNamespace != DefaultContainerdNamespace && m.pulledWithContainerd(iwr)
+}
+
+// collectPullProgress parses the layer progress of the running containerd pulls from
+// the logs of their pods, and returns it by image cache
+func (m *ImageManager) collectPullProgress() map[string][]fledgedv1alpha2.ImagePullProgress {
+	pulls := map[string][]fledgedv1alpha2.ImagePullProgress{}
+	if m.pullStrategy != PullStrategyContainerd {
+		return pulls
+	}
+	inflight := map[string]ImageWorkResult{}
+	m.lock.RLock()
+	for job, iwres := range m.imageworkstatus {
+		if iwres.Status == ImageWorkResultStatusJobCreated && m.useContainerdPull(iwres.ImageWorkRequest) {
+			inflight[job] = iwres
+		}
+	}
+	m.lock.RUnlock()
+
+	for job, iwres := range inflight {
+		pods, err := m.podsLister.Pods(iwres.ImageWorkRequest.Imagecache.Namespace).
+			List(labels.Set(map[string]string{"job-name": job}).AsSelector())
+		if err != nil || len(pods) != 1 || pods[0].Status.Phase != corev1.PodRunning {
+			continue
+		}
+		objKey, err := cache.MetaNamespaceKeyFunc(iwres.ImageWorkRequest.Imagecache)
+		if err != nil {
+			continue
+		}
+		progress := parseCtrPullProgress(m.podLogExcerpt(pods[0], "imagepuller", pullProgressTailLines, pullProgressLimitBytes))
+		progress.Image = iwres.ImageWorkRequest.Image
+		progress.Node = iwres.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"]
+		pulls[objKey] = append(pulls[objKey], progress)
+	}
+	for objKey := range pulls {
+		sort.Slice(pulls[objKey], func(i, j int) bool {
+			a, b := pulls[objKey][i], pulls[objKey][j]
+			if a.Image != b.Image {
+				return a.Image < b.Image
+			}
+			return a.Node < b.Node
+		})
+	}
+	return pulls
+}
+
 func (m *ImageManager) updatePendingImageWorkResults(imageCacheName string) error {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 	for job, iwres := range m.imageworkstatus {
 		if iwres.ImageWorkRequest.Imagecache.Name == imageCacheName {
+			// the failure of the last attempt is reported when a retry is pending
+			if iwres.Status == ImageWorkResultStatusJobCreated && iwres.retrying {
+				iwres.Status = ImageWorkResultStatusFailed
+				m.imageworkstatus[job] = iwres
+				continue
+			}
 			if iwres.Status == ImageWorkResultStatusJobCreated {
 				pods, err := m.podsLister.Pods(iwres.ImageWorkRequest.Imagecache.Namespace).
 					List(labels.Set(map[string]string{"job-name": job}).AsSelector())
@@ -302,23 +1134,13 @@ func (m *ImageManager) updatePendingImageWorkResults(imageCacheName string) erro
 	return nil
 }
 
-func (m *ImageManager) updateImageCacheStatus(imageCache *fledgedv1alpha2.ImageCache, errCh chan<- error) {
-	wait.Poll(time.Second, m.imagePullDeadlineDuration,
-		func() (done bool, err error) {
-			m.lock.RLock()
-			defer m.lock.RUnlock()
-			done, err = true, nil
-			for _, iwres := range m.imageworkstatus {
-				if iwres.ImageWorkRequest.Imagecache.Name == imageCache.Name {
-					if iwres.Status == ImageWorkResultStatusJobCreated {
-						done, err = false, nil
-						return
-					}
-				}
-			}
-			return
-		})
-	glog.V(4).Info("wait.Poll exited successfully")
+func (m *ImageManager) updateImageCacheStatus(imageCache *fledgedv1alpha2.ImageCache, pullDeadline time.Duration, errCh chan<- error) {
+	if pullDeadline <= 0 {
+		pullDeadline = m.imagePullDeadlineDuration
+	}
+	defer m.forgetResolvedDigests(imageCache)
+	m.waitForWork(imageCache.Name, pullDeadline)
+	glog.V(4).Info("m.waitForWork exited successfully")
 	err := m.updatePendingImageWorkResults(imageCache.Name)
 	if err != nil {
 		glog.Errorf("Error from updatePendingImageWorkResults(): %v", err)
@@ -326,6 +1148,7 @@ func (m *ImageManager) updateImageCacheStatus(imageCache *fledgedv1alpha2.ImageC
 		return
 	}
 	glog.V(4).Info("m.updatePendingImageWorkResults exited successfully")
+	m.attachFailureExcerpts(imageCache.Name)
 	//m.lock.Lock()
 	iwstatus := map[string]ImageWorkResult{}
 	//m.lock.Unlock()
@@ -368,6 +1191,12 @@ func (m *ImageManager) updateImageCacheStatus(imageCache *fledgedv1alpha2.ImageC
 		errCh <- err
 		return
 	}
+	m.progressLock.Lock()
+	delete(m.progress, objKey)
+	m.progressLock.Unlock()
+	m.lock.Lock()
+	delete(m.aborted, objKey)
+	m.lock.Unlock()
 	m.workqueue.AddRateLimited(WorkQueueKey{
 		WorkType: ImageCacheStatusUpdate,
 		Status:   &iwstatus,
@@ -381,13 +1210,25 @@ func (m *ImageManager) updateImageCacheStatus(imageCache *fledgedv1alpha2.ImageC
 func (m *ImageManager) Run(stopCh <-chan struct{}) error {
 	defer runtime.HandleCrash()
 	glog.Info("Starting image manager")
-	go m.kubeInformerFactory.Start(stopCh)
 	// Wait for the caches to be synced before starting workers
 	glog.Info("Waiting for informer caches to sync")
-	if ok := cache.WaitForCacheSync(stopCh, m.podsSynced); !ok {
+	if ok := cache.WaitForCacheSync(stopCh, m.podsSynced, m.jobsSynced); !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
-	go wait.Until(m.runWorker, time.Second, stopCh)
+	// A single worker per queue, as the request marking the end of an image cache
+	// action must be processed after the other requests of the action
+	for _, queue := range []workqueue.RateLimitingInterface{m.imageworkqueues.Create, m.imageworkqueues.Refresh, m.imageworkqueues.Purge} {
+		queue := queue
+		go wait.Until(func() { m.runWorker(queue) }, time.Second, stopCh)
+	}
+	go wait.Until(m.runBackgroundWorker, time.Second, stopCh)
+	go wait.Until(m.deleteOrphanedJobs, orphanedJobsInterval, stopCh)
+	if m.stuckJobThreshold > 0 {
+		go wait.Until(m.remediateStuckJobs, stuckJobsInterval, stopCh)
+	}
+	if m.statusUpdateInterval > 0 {
+		go wait.Until(m.flushProgress, m.statusUpdateInterval, stopCh)
+	}
 	glog.Info("Started image manager")
 	<-stopCh
 	glog.Info("Shutting down image manager")
@@ -397,16 +1238,35 @@ func (m *ImageManager) Run(stopCh <-chan struct{}) error {
 // runWorker is a long-running function that will continually call the
 // processNextWorkItem function in order to read and process a message on the
 // workqueue.
-func (m *ImageManager) runWorker() {
-	for m.processNextWorkItem() {
+func (m *ImageManager) runWorker(queue workqueue.RateLimitingInterface) {
+	for m.processNextWorkItem(queue) {
 	}
 }
 
+// runBackgroundWorker processes the work requests of the refreshes started by the
+// controller, yielding to the work requests of the actions triggered by users
+func (m *ImageManager) runBackgroundWorker() {
+	for {
+		for m.userWorkPending() {
+			time.Sleep(backgroundYieldInterval)
+		}
+		if !m.processNextWorkItem(m.imageworkqueues.BackgroundRefresh) {
+			return
+		}
+	}
+}
+
+// userWorkPending returns true if work requests of actions triggered by users are
+// waiting to be processed
+func (m *ImageManager) userWorkPending() bool {
+	return m.imageworkqueues.Create.Len() > 0 || m.imageworkqueues.Refresh.Len() > 0 || m.imageworkqueues.Purge.Len() > 0
+}
+
 // processNextWorkItem will read a single work item off the workqueue and
 // attempt to process it, by calling the syncHandler.
-func (m *ImageManager) processNextWorkItem() bool {
+func (m *ImageManager) processNextWorkItem(queue workqueue.RateLimitingInterface) bool {
 	//glog.Info("processNextWorkItem::Beginning...")
-	obj, shutdown := m.imageworkqueue.Get()
+	obj, shutdown := queue.Get()
 
 	if shutdown {
 		return false
@@ -420,7 +1280,7 @@ func (m *ImageManager) processNextWorkItem() bool {
 		// not call Forget if a transient error occurs, instead the item is
 		// put back on the workqueue and attempted again after a back-off
 		// period.
-		defer m.imageworkqueue.Done(obj)
+		defer queue.Done(obj)
 		var iwr ImageWorkRequest
 		var ok bool
 		// We expect strings to come off the workqueue. These are of the
@@ -432,7 +1292,7 @@ func (m *ImageManager) processNextWorkItem() bool {
 			// As the item in the workqueue is actually invalid, we call
 			// Forget here else we'd go into a loop of attempting to
 			// process a work item that is invalid.
-			m.imageworkqueue.Forget(obj)
+			queue.Forget(obj)
 			runtime.HandleError(fmt.Errorf("unexpected type in workqueue: %#v", obj))
 			return nil
 		}
@@ -440,9 +1300,40 @@ func (m *ImageManager) processNextWorkItem() bool {
 		// When both Image and Node fields are empty it indicates all image pull/delete requests
 		// have been placed in the workqueue by the controller. The controller is waiting for status update
 		if iwr.Image == "" && iwr.Node == nil {
-			m.imageworkqueue.Forget(obj)
+			queue.Forget(obj)
+			// The request marking the end of the action is requeued behind the work
+			// requests of the action waiting for a job slot
+			if m.waitingForJobSlot(iwr.Imagecache) {
+				queue.AddAfter(obj, jobSlotCheckInterval)
+				return nil
+			}
 			errCh := make(chan error)
-			go m.updateImageCacheStatus(iwr.Imagecache, errCh)
+			go m.updateImageCacheStatus(iwr.Imagecache, iwr.PullDeadline, errCh)
+			return nil
+		}
+		// The remaining work requests of an aborted image cache action are not dispatched
+		if m.skipAborted(iwr) {
+			m.forgetJobSlotWait(iwr)
+			queue.Forget(obj)
+			return nil
+		}
+		// The work requests are requeued while the number of running jobs reaches the
+		// maximum number of concurrent jobs, so that the worker is not blocked
+		if m.deferForJobSlot(queue, iwr) {
+			queue.Forget(obj)
+			return nil
+		}
+		// The image pulls from a registry whose circuit is open fall back to the
+		// next fallback reference of the image, or are deferred
+		iwr = m.closedCircuitSource(iwr)
+		if m.deferOpenCircuit(iwr) {
+			queue.Forget(obj)
+			return nil
+		}
+		// The image pulls to nodes whose load exceeds the thresholds of the load
+		// throttle are deferred until the load subsides
+		if m.deferOverloadedNode(iwr) {
+			queue.Forget(obj)
 			return nil
 		}
 		// Run the syncHandler, passing it the namespace/name string of the
@@ -450,6 +1341,7 @@ func (m *ImageManager) processNextWorkItem() bool {
 		var job *batchv1.Job
 		var err error
 		var pull, delete bool
+		var resolvedDigest string
 		if iwr.WorkType == ImageCachePurge {
 			delete = true
 			job, err = m.deleteImage(iwr)
@@ -457,14 +1349,34 @@ func (m *ImageManager) processNextWorkItem() bool {
 				return fmt.Errorf("error deleting image '%s' from node '%s': %s", iwr.Image, iwr.Node.Labels["kubernetes.io/hostname"], err.Error())
 			}
 			glog.Infof("Job %s created (delete:- %s --> %s, runtime: %s)", job.Name, iwr.Image, iwr.Node.Labels["kubernetes.io/hostname"], iwr.ContainerRuntimeVersion)
+		} else if iwr.Artifact || (iwr.Platform != "" && !DigestPinned(iwr.Image)) {
+			// The node status does not report artifacts in the artifact store, nor
+			// the platform of the images, so these are always pulled
+			pull = true
+			job, err = m.pullImage(iwr)
+			if err != nil {
+				return fmt.Errorf("error pulling '%s' to node '%s': %s", iwr.Image, iwr.Node.Labels["kubernetes.io/hostname"], err.Error())
+			}
+			if iwr.Artifact {
+				glog.Infof("Job %s created (pull artifact:- %s --> %s)", job.Name, iwr.Image, iwr.Node.Labels["kubernetes.io/hostname"])
+			} else {
+				glog.Infof("Job %s created (pull:- %s --> %s, platform: %s, runtime: %s)", job.Name, iwr.Image, iwr.Node.Labels["kubernetes.io/hostname"], iwr.Platform, iwr.ContainerRuntimeVersion)
+			}
 		} else {
 			pull = true
-			pull, err = checkIfImageNeedsToBePulled(m.imagePullPolicy, iwr.Image, iwr.Node)
+			pullPolicy := m.imagePullPolicyFor(iwr)
+			// Images referenced by digest are immutable, so they are never pulled again
+			// while present on the node
+			if DigestPinned(iwr.Image) {
+				pullPolicy = string(corev1.PullIfNotPresent)
+			}
+			pull, err = checkIfImageNeedsToBePulled(pullPolicy, iwr.NodeReference(), iwr.Node)
 			if err != nil {
 				glog.Errorf("Error from checkIfImageNeedsToBePulled(): %+v", err)
 				return fmt.Errorf("error from checkIfImageNeedsToBePulled(): %+v", err)
 			}
 			if pull {
+				resolvedDigest = m.resolveDigest(iwr)
 				job, err = m.pullImage(iwr)
 				if err != nil {
 					return fmt.Errorf("error pulling image '%s' to node '%s': %s", iwr.Image, iwr.Node.Labels["kubernetes.io/hostname"], err.Error())
@@ -478,13 +1390,16 @@ func (m *ImageManager) processNextWorkItem() bool {
 		// get queued again until another change happens.
 		m.lock.Lock()
 		if pull || delete {
-			m.imageworkstatus[job.Name] = ImageWorkResult{ImageWorkRequest: iwr, Status: ImageWorkResultStatusJobCreated}
+			m.imageworkstatus[job.Name] = ImageWorkResult{ImageWorkRequest: iwr, Status: ImageWorkResultStatusJobCreated, resolvedDigest: resolvedDigest}
 		} else {
 			// generate a random fake job name
 			m.imageworkstatus[names.SimpleNameGenerator.GenerateName(fakeJobPrefix)] = ImageWorkResult{ImageWorkRequest: iwr, Status: ImageWorkResultStatusAlreadyPulled}
 		}
 		m.lock.Unlock()
-		m.imageworkqueue.Forget(obj)
+		if !pull && !delete {
+			m.recordProgress(iwr.Imagecache, false)
+		}
+		queue.Forget(obj)
 		return nil
 	}(obj)
 
@@ -496,15 +1411,95 @@ func (m *ImageManager) processNextWorkItem() bool {
 	return true
 }
 
+// skipAborted records the work request as aborted if the action of its image cache
+// has been aborted, and returns true if so
+func (m *ImageManager) skipAborted(iwr ImageWorkRequest) bool {
+	if iwr.Imagecache == nil {
+		return false
+	}
+	objKey, err := cache.MetaNamespaceKeyFunc(iwr.Imagecache)
+	if err != nil {
+		return false
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	aborted, ok := m.aborted[objKey]
+	if !ok {
+		return false
+	}
+	m.imageworkstatus[names.SimpleNameGenerator.GenerateName(fakeJobPrefix)] = ImageWorkResult{
+		ImageWorkRequest: iwr,
+		Status:           ImageWorkResultStatusAborted,
+		Reason:           aborted.reason,
+		Message:          aborted.message,
+	}
+	return true
+}
+
+// imagePullPolicyFor returns the image pull policy of the image of a work request, if
+// specified, else the image pull policy of the image cache, if specified, else the image
+// pull policy of the controller
+func (m *ImageManager) imagePullPolicyFor(iwr ImageWorkRequest) string {
+	if iwr.PullPolicy != "" {
+		return string(iwr.PullPolicy)
+	}
+	if iwr.Imagecache != nil && iwr.Imagecache.Spec.ImagePullPolicy != "" {
+		return string(iwr.Imagecache.Spec.ImagePullPolicy)
+	}
+	return m.imagePullPolicy
+}
+
+// serviceAccountNameFor returns the service account of the image cache, if specified,
+// else the service account of the controller
+func (m *ImageManager) serviceAccountNameFor(imageCache *fledgedv1alpha2.ImageCache) string {
+	if imageCache != nil && imageCache.Spec.ServiceAccountName != "" {
+		return imageCache.Spec.ServiceAccountName
+	}
+	return m.serviceAccountName
+}
+
+// setSchedulerName sets the scheduler of the pods of the job, if one is configured
+func (m *ImageManager) setSchedulerName(job *batchv1.Job) {
+	if m.jobSchedulerName != "" {
+		job.Spec.Template.Spec.SchedulerName = m.jobSchedulerName
+	}
+}
+
 // pullImage pulls the image to the node
 func (m *ImageManager) pullImage(iwr ImageWorkRequest) (*batchv1.Job, error) {
+	criClientImage, busyboxImage := m.pullerImages()
+	image := iwr.PullReference()
 	// Construct the Job manifest
-	newjob, err := newImagePullJob(iwr.Imagecache, iwr.Image, iwr.Node, m.imagePullPolicy,
-		m.busyboxImage, m.serviceAccountName, m.jobPriorityClassName)
+	var newjob *batchv1.Job
+	var err error
+	if iwr.Artifact {
+		newjob, err = newArtifactPullJob(iwr.Imagecache, image, iwr.Node, m.orasImage,
+			m.artifactStorePath, m.serviceAccountNameFor(iwr.Imagecache), m.jobPriorityClassName)
+	} else if iwr.TargetRef != "" {
+		newjob, err = newRetagImagePullJob(iwr.Imagecache, image, iwr.TargetRef, iwr.Platform, iwr.Node, iwr.ContainerRuntimeVersion,
+			criClientImage, m.serviceAccountNameFor(iwr.Imagecache), m.imageDeleteJobHostNetwork, m.jobPriorityClassName, m.criSocketPath,
+			m.containerdNamespace, snapshotterFor(iwr))
+	} else if m.useContainerdPull(iwr) {
+		newjob, err = newContainerdImagePullJob(iwr.Imagecache, image, iwr.Platform, iwr.Node, iwr.ContainerRuntimeVersion,
+			criClientImage, m.serviceAccountNameFor(iwr.Imagecache), m.imageDeleteJobHostNetwork, m.jobPriorityClassName, m.criSocketPath,
+			m.containerdNamespace, snapshotterFor(iwr))
+	} else if iwr.Platform != "" {
+		newjob, err = newPlatformImagePullJob(iwr.Imagecache, image, iwr.Platform, iwr.Node, iwr.ContainerRuntimeVersion,
+			criClientImage, m.serviceAccountNameFor(iwr.Imagecache), m.imageDeleteJobHostNetwork, m.jobPriorityClassName, m.criSocketPath,
+			snapshotterFor(iwr))
+	} else {
+		newjob, err = newImagePullJob(iwr.Imagecache, image, iwr.Node, m.imagePullPolicyFor(iwr),
+			busyboxImage, m.serviceAccountNameFor(iwr.Imagecache), m.jobPriorityClassName)
+	}
 	if err != nil {
 		glog.Errorf("Error when constructing job manifest: %v", err)
 		return nil, err
 	}
+	m.setSchedulerName(newjob)
+	if m.guaranteedPulls.Enabled {
+		m.guaranteedPulls.bindToNode(newjob, iwr.Node)
+	}
+	m.setSecurityProfiles(newjob, iwr.Imagecache)
 	// Create a Job to pull the image into the node
 	job, err := m.kubeclientset.BatchV1().Jobs(iwr.Imagecache.Namespace).Create(context.TODO(), newjob, metav1.CreateOptions{})
 	if err != nil {
@@ -516,13 +1511,27 @@ func (m *ImageManager) pullImage(iwr ImageWorkRequest) (*batchv1.Job, error) {
 
 // deleteImage deletes the image from the node
 func (m *ImageManager) deleteImage(iwr ImageWorkRequest) (*batchv1.Job, error) {
+	criClientImage, busyboxImage := m.pullerImages()
 	// Construct the Job manifest
-	newjob, err := newImageDeleteJob(iwr.Imagecache, iwr.Image, iwr.Node, iwr.ContainerRuntimeVersion,
-		m.criClientImage, m.serviceAccountName, m.imageDeleteJobHostNetwork, m.jobPriorityClassName, m.criSocketPath)
+	var newjob *batchv1.Job
+	var err error
+	if iwr.Artifact {
+		newjob, err = newArtifactDeleteJob(iwr.Imagecache, iwr.Image, iwr.Node, busyboxImage,
+			m.artifactStorePath, m.serviceAccountNameFor(iwr.Imagecache), m.jobPriorityClassName)
+	} else if m.useContainerdDelete(iwr) {
+		newjob, err = newContainerdImageDeleteJob(iwr.Imagecache, iwr.NodeReference(), iwr.Node, iwr.ContainerRuntimeVersion,
+			criClientImage, m.serviceAccountNameFor(iwr.Imagecache), m.imageDeleteJobHostNetwork, m.jobPriorityClassName, m.criSocketPath,
+			m.containerdNamespace)
+	} else {
+		newjob, err = newImageDeleteJob(iwr.Imagecache, iwr.NodeReference(), iwr.Node, iwr.ContainerRuntimeVersion,
+			criClientImage, m.criClientWindowsImage, m.serviceAccountNameFor(iwr.Imagecache), m.imageDeleteJobHostNetwork, m.jobPriorityClassName, m.criSocketPath)
+	}
 	if err != nil {
 		glog.Errorf("Error when constructing job manifest: %v", err)
 		return nil, err
 	}
+	m.setSchedulerName(newjob)
+	m.setSecurityProfiles(newjob, iwr.Imagecache)
 	// Create a Job to delete the image from the node
 	job, err := m.kubeclientset.BatchV1().Jobs(iwr.Imagecache.Namespace).Create(context.TODO(), newjob, metav1.CreateOptions{})
 	if err != nil {
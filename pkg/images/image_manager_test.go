@@ -24,15 +24,20 @@ import (
 	"time"
 
 	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/features"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
 	core "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 )
 
@@ -44,11 +49,18 @@ var node = corev1.Node{
 	},
 }
 
+var windowsNode = corev1.Node{
+	ObjectMeta: metav1.ObjectMeta{
+		Labels: map[string]string{"kubernetes.io/hostname": "bar", "kubernetes.io/os": "windows"},
+	},
+}
+
 func newTestImageManager(kubeclientset kubernetes.Interface, imagepullpolicy string,
 	serviceaccountname string, imagedeletejobhostnetwork bool,
 	jobpriorityclassname string, candeletejob bool, criSocketPath string) (*ImageManager, coreinformers.PodInformer) {
 	imagePullDeadlineDuration := time.Millisecond * 10
 	criClientImage := "senthilrch/fledged-docker-client:latest"
+	criClientWindowsImage := "senthilrch/fledged-docker-client-windows:latest"
 	busyboxImage := "senthilrch/busybox:1.35.0"
 	imagePullPolicy := imagepullpolicy
 	serviceAccountName := serviceaccountname
@@ -56,17 +68,89 @@ func newTestImageManager(kubeclientset kubernetes.Interface, imagepullpolicy str
 	jobPriorityClassName := jobpriorityclassname
 	canDeleteJob := candeletejob
 	socketPath := criSocketPath
+	statusUpdateInterval := time.Second * 5
+	statusUpdateBatchSize := 2
+	stuckJobThreshold := time.Minute * 2
+	pullRetry := PullRetry{BaseDelay: time.Second * 10, MaxDelay: time.Minute * 5, Jitter: 0.1}
+	orasImage := "ghcr.io/oras-project/oras:v0.16.0"
+	artifactStorePath := "/var/lib/kubefledged/artifacts"
+	pullStrategy := PullStrategyKubelet
+	containerdNamespace := DefaultContainerdNamespace
 	imagecacheworkqueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ImageCaches")
-	imageworkqueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ImagePullerStatus")
+	imageworkqueues := NewImageWorkQueues()
 
-	imagemanager, podInformer := NewImageManager(imagecacheworkqueue, imageworkqueue, kubeclientset,
-		fledgedNameSpace, imagePullDeadlineDuration, criClientImage, busyboxImage, imagePullPolicy,
-		serviceAccountName, imageDeleteJobHostNetwork, jobPriorityClassName, canDeleteJob, socketPath)
+	imagemanager, podInformer := NewImageManager(imagecacheworkqueue, imageworkqueues, kubeclientset,
+		NewInformerFactory(kubeclientset), ImageManagerOptions{
+			Namespace:                 fledgedNameSpace,
+			ImagePullDeadlineDuration: imagePullDeadlineDuration,
+			CRIClientImage:            criClientImage,
+			CRIClientWindowsImage:     criClientWindowsImage,
+			BusyboxImage:              busyboxImage,
+			ImagePullPolicy:           imagePullPolicy,
+			ServiceAccountName:        serviceAccountName,
+			ImageDeleteJobHostNetwork: imageDeleteJobHostNetwork,
+			JobPriorityClassName:      jobPriorityClassName,
+			CanDeleteJob:              canDeleteJob,
+			CRISocketPath:             socketPath,
+			StatusUpdateInterval:      statusUpdateInterval,
+			StatusUpdateBatchSize:     statusUpdateBatchSize,
+			StuckJobThreshold:         stuckJobThreshold,
+			PullRetry:                 pullRetry,
+			ORASImage:                 orasImage,
+			ArtifactStorePath:         artifactStorePath,
+			PullStrategy:              pullStrategy,
+			ContainerdNamespace:       containerdNamespace,
+		}, record.NewFakeRecorder(10))
 	imagemanager.podsSynced = func() bool { return true }
+	imagemanager.jobsSynced = func() bool { return true }
 
 	return imagemanager, podInformer
 }
 
+func TestNewArtifactJobs(t *testing.T) {
+	imagecache := &fledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "kube-fledged",
+		},
+		Spec: fledgedv1alpha2.ImageCacheSpec{
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "myregistrykey"}},
+		},
+	}
+	storePath := "/var/lib/kubefledged/artifacts"
+	artifactDir := "/var/lib/kubefledged/artifacts/ghcr.io_foo_model_v1"
+
+	pullJob, err := newArtifactPullJob(imagecache, "ghcr.io/foo/model:v1", &node, "oras", storePath, "", "")
+	if err != nil {
+		t.Fatalf("newArtifactPullJob() failed: %v", err)
+	}
+	podSpec := pullJob.Spec.Template.Spec
+	expectedArgs := []string{"pull", "ghcr.io/foo/model:v1", "--output", artifactDir, "--registry-config", "/etc/kubefledged/registry/config.json"}
+	if !reflect.DeepEqual(podSpec.Containers[0].Args, expectedArgs) {
+		t.Errorf("pull job: expected args %v, got %v", expectedArgs, podSpec.Containers[0].Args)
+	}
+	if podSpec.Volumes[0].HostPath == nil || podSpec.Volumes[0].HostPath.Path != storePath {
+		t.Errorf("pull job: expected artifact store %s to be mounted, got %+v", storePath, podSpec.Volumes[0])
+	}
+	if len(podSpec.Volumes) != 2 || podSpec.Volumes[1].Secret.SecretName != "myregistrykey" {
+		t.Errorf("pull job: expected registry credentials to be mounted, got %+v", podSpec.Volumes)
+	}
+
+	deleteJob, err := newArtifactDeleteJob(imagecache, "ghcr.io/foo/model:v1", &node, "busybox", storePath, "", "")
+	if err != nil {
+		t.Fatalf("newArtifactDeleteJob() failed: %v", err)
+	}
+	podSpec = deleteJob.Spec.Template.Spec
+	expectedCommand := []string{"rm", "-rf", artifactDir}
+	if !reflect.DeepEqual(podSpec.Containers[0].Command, expectedCommand) {
+		t.Errorf("delete job: expected command %v, got %v", expectedCommand, podSpec.Containers[0].Command)
+	}
+
+	if _, err := newArtifactPullJob(imagecache, "ghcr.io/foo/model:v1", &windowsNode, "oras", storePath, "", ""); err == nil {
+		t.Errorf("pull job: expected error for windows node")
+	}
+}
+
 func TestPullDeleteImage(t *testing.T) {
 	job := batchv1.Job{}
 	defaultImageCache := fledgedv1alpha2.ImageCache{
@@ -200,6 +284,44 @@ func TestPullDeleteImage(t *testing.T) {
 			expectError:         false,
 			expectedErrorString: "",
 		},
+		{
+			name:   "#10 Successful creation of image pull job (os: windows)",
+			action: "pullimage",
+			iwr: ImageWorkRequest{
+				Image:      "foo",
+				Node:       &windowsNode,
+				WorkType:   ImageCacheCreate,
+				Imagecache: &defaultImageCache,
+			},
+			expectError:         false,
+			expectedErrorString: "",
+		},
+		{
+			name:   "#11 Successful creation of image delete job (os: windows, runtime: containerd)",
+			action: "deleteimage",
+			iwr: ImageWorkRequest{
+				Image:                   "foo",
+				Node:                    &windowsNode,
+				ContainerRuntimeVersion: "containerd://1.6.8",
+				WorkType:                ImageCachePurge,
+				Imagecache:              &defaultImageCache,
+			},
+			expectError:         false,
+			expectedErrorString: "",
+		},
+		{
+			name:   "#12 Successful creation of image delete job (os: windows, runtime: docker)",
+			action: "deleteimage",
+			iwr: ImageWorkRequest{
+				Image:                   "foo",
+				Node:                    &windowsNode,
+				ContainerRuntimeVersion: "docker://20.10.20",
+				WorkType:                ImageCachePurge,
+				Imagecache:              &defaultImageCache,
+			},
+			expectError:         false,
+			expectedErrorString: "",
+		},
 	}
 	for _, test := range tests {
 		fakekubeclientset := &fakeclientset.Clientset{}
@@ -234,6 +356,97 @@ func TestPullDeleteImage(t *testing.T) {
 	}
 }
 
+func TestJobSchedulerName(t *testing.T) {
+	imageCache := &fledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "kube-fledged"},
+	}
+	tests := []struct {
+		name                  string
+		action                string
+		jobSchedulerName      string
+		expectedSchedulerName string
+	}{
+		{
+			name:   "#1: Pull job - default scheduler",
+			action: "pullimage",
+		},
+		{
+			name:                  "#2: Pull job - custom scheduler",
+			action:                "pullimage",
+			jobSchedulerName:      "batch-scheduler",
+			expectedSchedulerName: "batch-scheduler",
+		},
+		{
+			name:                  "#3: Delete job - custom scheduler",
+			action:                "deleteimage",
+			jobSchedulerName:      "batch-scheduler",
+			expectedSchedulerName: "batch-scheduler",
+		},
+	}
+	for _, test := range tests {
+		var created *batchv1.Job
+		fakekubeclientset := &fakeclientset.Clientset{}
+		fakekubeclientset.AddReactor("create", "jobs", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+			created = action.(core.CreateAction).GetObject().(*batchv1.Job)
+			return true, created, nil
+		})
+		imagemanager, _ := newTestImageManager(fakekubeclientset, "IfNotPresent", "sa-kube-fledged", false, "priority-class-kube-fledged", false, "")
+		imagemanager.jobSchedulerName = test.jobSchedulerName
+		iwr := ImageWorkRequest{Image: "foo", Node: &node, WorkType: ImageCacheCreate, Imagecache: imageCache}
+		var err error
+		if test.action == "pullimage" {
+			_, err = imagemanager.pullImage(iwr)
+		} else {
+			iwr.WorkType = ImageCachePurge
+			_, err = imagemanager.deleteImage(iwr)
+		}
+		if err != nil {
+			t.Errorf("Test: %s failed: %v", test.name, err)
+			continue
+		}
+		if actual := created.Spec.Template.Spec.SchedulerName; actual != test.expectedSchedulerName {
+			t.Errorf("Test: %s failed: expectedSchedulerName=%s, actualSchedulerName=%s", test.name, test.expectedSchedulerName, actual)
+		}
+	}
+}
+
+func TestNewImageJobsWindows(t *testing.T) {
+	imagecache := &fledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "kube-fledged",
+		},
+	}
+	pullJob, err := newImagePullJob(imagecache, "foo", &windowsNode, "IfNotPresent", "busybox", "", "")
+	if err != nil {
+		t.Fatalf("newImagePullJob() failed: %v", err)
+	}
+	podSpec := pullJob.Spec.Template.Spec
+	if podSpec.OS == nil || podSpec.OS.Name != corev1.Windows {
+		t.Errorf("pull job: expected pod OS windows, got %+v", podSpec.OS)
+	}
+	if len(podSpec.InitContainers) != 0 || len(podSpec.Volumes) != 0 {
+		t.Errorf("pull job: expected no init containers or volumes on windows, got %d init containers, %d volumes",
+			len(podSpec.InitContainers), len(podSpec.Volumes))
+	}
+
+	deleteJob, err := newImageDeleteJob(imagecache, "foo", &windowsNode, "containerd://1.6.8",
+		"cri-client", "cri-client-windows", "", true, "", "")
+	if err != nil {
+		t.Fatalf("newImageDeleteJob() failed: %v", err)
+	}
+	podSpec = deleteJob.Spec.Template.Spec
+	if podSpec.Containers[0].Image != "cri-client-windows" {
+		t.Errorf("delete job: expected image cri-client-windows, got %s", podSpec.Containers[0].Image)
+	}
+	if podSpec.HostNetwork {
+		t.Errorf("delete job: expected hostNetwork to be disabled on windows")
+	}
+	if podSpec.Volumes[0].HostPath.Path != `\\.\pipe\containerd-containerd` {
+		t.Errorf("delete job: expected containerd named pipe, got %s", podSpec.Volumes[0].HostPath.Path)
+	}
+}
+
 func TestHandlePodStatusChange(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -308,6 +521,27 @@ func TestHandlePodStatusChange(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:     "#5: Create - Pod failed without termination message",
+			worktype: ImageCacheCreate,
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"job-name": "fakejob"},
+				},
+				Status: corev1.PodStatus{
+					Phase: corev1.PodFailed,
+					ContainerStatuses: []corev1.ContainerStatus{
+						{
+							State: corev1.ContainerState{
+								Terminated: &corev1.ContainerStateTerminated{
+									Reason: "Error",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, test := range tests {
 		fakekubeclientset := &fakeclientset.Clientset{}
@@ -331,6 +565,1030 @@ func TestHandlePodStatusChange(t *testing.T) {
 				t.Errorf("Test: %s failed: expectedWorkResult=%s, actualWorkResult=%s", test.name, ImageWorkResultStatusFailed, imagemanager.imageworkstatus[test.pod.Labels["job-name"]].Status)
 			}
 		}
+		// the log excerpt is fetched when the results are processed
+		if imagemanager.imageworkstatus[test.pod.Labels["job-name"]].Log != "" {
+			t.Errorf("Test: %s failed: expectedLog=\"\", actualLog=%q", test.name, imagemanager.imageworkstatus[test.pod.Labels["job-name"]].Log)
+		}
+	}
+}
+
+func TestAttachFailureExcerpts(t *testing.T) {
+	imagecache := &fledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+	}
+	newPod := func(job string, state corev1.ContainerState) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: job + "-pod", Namespace: fledgedNameSpace, Labels: map[string]string{"job-name": job}},
+			Status: corev1.PodStatus{
+				Phase:             corev1.PodFailed,
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "imagepuller", State: state}},
+			},
+		}
+	}
+	failedEvent := func(name, message string, minute int) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: fledgedNameSpace},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "waiting-pod", Namespace: fledgedNameSpace},
+			Reason:         "Failed",
+			Message:        message,
+			LastTimestamp:  metav1.NewTime(time.Date(2022, 1, 1, 0, minute, 0, 0, time.UTC)),
+		}
+	}
+	tests := []struct {
+		name        string
+		job         string
+		status      string
+		pod         *corev1.Pod
+		expectedLog string
+	}{
+		{
+			name:        "#1: Terminated without termination message",
+			job:         "terminated",
+			status:      ImageWorkResultStatusFailed,
+			pod:         newPod("terminated", corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error"}}),
+			expectedLog: "fake logs",
+		},
+		{
+			name:   "#2: Terminated with termination message",
+			job:    "message",
+			status: ImageWorkResultStatusFailed,
+			pod:    newPod("message", corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", Message: "no space left on device"}}),
+		},
+		{
+			name:        "#3: Waiting in ImagePullBackOff",
+			job:         "waiting",
+			status:      ImageWorkResultStatusFailed,
+			pod:         newPod("waiting", corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"}}),
+			expectedLog: "Failed to pull image: manifest unknown\nError: ErrImagePull",
+		},
+		{
+			name:   "#4: Succeeded",
+			job:    "succeeded",
+			status: ImageWorkResultStatusSucceeded,
+			pod:    newPod("succeeded", corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error"}}),
+		},
+		{
+			name:   "#5: Pod deleted",
+			job:    "deleted",
+			status: ImageWorkResultStatusFailed,
+		},
+	}
+	for _, test := range tests {
+		fakekubeclientset := fakeclientset.NewSimpleClientset(
+			failedEvent("second", "Error: ErrImagePull", 2),
+			failedEvent("first", "Failed to pull image: manifest unknown", 1),
+		)
+		imagemanager, podInformer := newTestImageManager(fakekubeclientset, "IfNotPresent", "sa-kube-fledged", false, "", false, "")
+		if test.pod != nil {
+			podInformer.Informer().GetIndexer().Add(test.pod)
+		}
+		imagemanager.imageworkstatus[test.job] = ImageWorkResult{
+			Status:           test.status,
+			ImageWorkRequest: ImageWorkRequest{Imagecache: imagecache, Node: &node},
+		}
+		imagemanager.attachFailureExcerpts(imagecache.Name)
+		if actual := imagemanager.imageworkstatus[test.job].Log; actual != test.expectedLog {
+			t.Errorf("Test: %s failed: expectedLog=%q, actualLog=%q", test.name, test.expectedLog, actual)
+		}
+	}
+}
+
+func TestRelayPodEvents(t *testing.T) {
+	imagecache := &fledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "kube-fledged",
+		},
+	}
+	pendingPod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "fakepod",
+			Labels: map[string]string{"job-name": "fakejob"},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodPending},
+	}
+	unschedulablePod := *pendingPod.DeepCopy()
+	unschedulablePod.Status.Conditions = []corev1.PodCondition{
+		{
+			Type:    corev1.PodScheduled,
+			Status:  corev1.ConditionFalse,
+			Reason:  corev1.PodReasonUnschedulable,
+			Message: "0/1 nodes are available",
+		},
+	}
+	errImagePullPod := *pendingPod.DeepCopy()
+	errImagePullPod.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "ErrImagePull", Message: "not found"},
+			},
+		},
+	}
+	evictedPod := *pendingPod.DeepCopy()
+	evictedPod.Status.Phase = corev1.PodFailed
+	evictedPod.Status.Reason = "Evicted"
+	evictedPod.Status.Message = "The node was low on resource: ephemeral-storage"
+
+	tests := []struct {
+		name          string
+		oldPod        corev1.Pod
+		newPod        corev1.Pod
+		imageCache    *fledgedv1alpha2.ImageCache
+		expectedEvent string
+	}{
+		{
+			name:          "#1: Pod unschedulable",
+			oldPod:        pendingPod,
+			newPod:        unschedulablePod,
+			imageCache:    imagecache,
+			expectedEvent: "Warning FailedScheduling Pod fakepod of job fakejob (image: foo, node: bar): 0/1 nodes are available",
+		},
+		{
+			name:          "#2: Image pull error",
+			oldPod:        pendingPod,
+			newPod:        errImagePullPod,
+			imageCache:    imagecache,
+			expectedEvent: "Warning ErrImagePull Pod fakepod of job fakejob (image: foo, node: bar): not found",
+		},
+		{
+			name:          "#3: Pod evicted",
+			oldPod:        pendingPod,
+			newPod:        evictedPod,
+			imageCache:    imagecache,
+			expectedEvent: "Warning Evicted Pod fakepod of job fakejob (image: foo, node: bar): The node was low on resource: ephemeral-storage",
+		},
+		{
+			name:          "#4: Problem already relayed",
+			oldPod:        errImagePullPod,
+			newPod:        errImagePullPod,
+			imageCache:    imagecache,
+			expectedEvent: "",
+		},
+		{
+			name:          "#5: No image cache - event not relayed",
+			oldPod:        pendingPod,
+			newPod:        errImagePullPod,
+			imageCache:    nil,
+			expectedEvent: "",
+		},
+	}
+	for _, test := range tests {
+		fakekubeclientset := &fakeclientset.Clientset{}
+		imagemanager, _ := newTestImageManager(fakekubeclientset, "IfNotPresent", "sa-kube-fledged", false, "priority-class-kube-fledged", false, "")
+		recorder := record.NewFakeRecorder(10)
+		imagemanager.recorder = recorder
+		imagemanager.imageworkstatus["fakejob"] = ImageWorkResult{
+			Status: ImageWorkResultStatusJobCreated,
+			ImageWorkRequest: ImageWorkRequest{
+				Image:      "foo",
+				WorkType:   ImageCacheCreate,
+				Node:       &node,
+				Imagecache: test.imageCache,
+			},
+		}
+		imagemanager.relayPodEvents(&test.oldPod, &test.newPod)
+		actualEvent := ""
+		select {
+		case actualEvent = <-recorder.Events:
+		default:
+		}
+		if actualEvent != test.expectedEvent {
+			t.Errorf("Test: %s failed: expectedEvent=%q, actualEvent=%q", test.name, test.expectedEvent, actualEvent)
+		}
+	}
+}
+
+func TestDeleteOrphanedJobs(t *testing.T) {
+	oldJob := func(name string, succeeded int32) batchv1.Job {
+		return batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "kube-fledged",
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+				Labels:            map[string]string{"app": "kubefledged", "kubefledged": "kubefledged-image-manager", "imagecache": "foo"},
+			},
+			Status: batchv1.JobStatus{Succeeded: succeeded},
+		}
+	}
+	newJob := oldJob("newjob", 0)
+	newJob.CreationTimestamp = metav1.Now()
+	ownedJob := oldJob("ownedjob", 0)
+	ownedJob.Labels = map[string]string{"app": "kubefledged", "kubefledged": "kubefledged-image-manager"}
+	ownedJob.OwnerReferences = []metav1.OwnerReference{{Kind: "ImageCache", Name: "foo"}}
+	tests := []struct {
+		name            string
+		jobs            []batchv1.Job
+		knownJobs       []string
+		expectedDeletes []string
+	}{
+		{
+			name:            "#1: Orphaned running job deleted",
+			jobs:            []batchv1.Job{oldJob("orphanedjob", 0)},
+			expectedDeletes: []string{"orphanedjob"},
+		},
+		{
+			name:            "#2: Tracked running job not deleted",
+			jobs:            []batchv1.Job{oldJob("trackedjob", 0)},
+			knownJobs:       []string{"trackedjob"},
+			expectedDeletes: []string{},
+		},
+		{
+			name:            "#3: Finished job retained",
+			jobs:            []batchv1.Job{oldJob("finishedjob", 1)},
+			expectedDeletes: []string{},
+		},
+		{
+			name:            "#4: Job within grace period not deleted",
+			jobs:            []batchv1.Job{newJob},
+			expectedDeletes: []string{},
+		},
+		{
+			name:            "#5: Orphaned job owned by an image cache deleted",
+			jobs:            []batchv1.Job{ownedJob},
+			expectedDeletes: []string{"ownedjob"},
+		},
+	}
+	for _, test := range tests {
+		fakekubeclientset := &fakeclientset.Clientset{}
+		actualDeletes := []string{}
+		fakekubeclientset.AddReactor("delete", "jobs", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+			actualDeletes = append(actualDeletes, action.(core.DeleteAction).GetName())
+			return true, nil, nil
+		})
+		imagemanager, _ := newTestImageManager(fakekubeclientset, "IfNotPresent", "sa-kube-fledged", false, "priority-class-kube-fledged", false, "")
+		jobs := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		imagemanager.jobsLister = batchlisters.NewJobLister(jobs)
+		for i := range test.jobs {
+			jobs.Add(&test.jobs[i])
+		}
+		for _, job := range test.knownJobs {
+			imagemanager.imageworkstatus[job] = ImageWorkResult{Status: ImageWorkResultStatusJobCreated}
+		}
+		imagemanager.deleteOrphanedJobs()
+		if !reflect.DeepEqual(actualDeletes, test.expectedDeletes) {
+			t.Errorf("Test: %s failed: expectedDeletes=%v, actualDeletes=%v", test.name, test.expectedDeletes, actualDeletes)
+		}
+	}
+}
+
+func TestRemediateStuckJobs(t *testing.T) {
+	imagecache := &fledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "kube-fledged",
+		},
+	}
+	pendingPod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "fakepod",
+			Namespace:         "kube-fledged",
+			Labels:            map[string]string{"job-name": "fakejob"},
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+		},
+		Spec: corev1.PodSpec{
+			NodeName:       "bar",
+			InitContainers: []corev1.Container{{Name: "busybox"}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodPending},
+	}
+	unschedulablePod := *pendingPod.DeepCopy()
+	unschedulablePod.Spec.NodeName = ""
+	unschedulablePod.Status.Conditions = []corev1.PodCondition{
+		{
+			Type:    corev1.PodScheduled,
+			Status:  corev1.ConditionFalse,
+			Reason:  corev1.PodReasonUnschedulable,
+			Message: "0/1 nodes are available",
+		},
+	}
+	recentUnschedulablePod := *unschedulablePod.DeepCopy()
+	recentUnschedulablePod.CreationTimestamp = metav1.Now()
+	initStuckPod := *pendingPod.DeepCopy()
+	initStuckPod.Status.InitContainerStatuses = []corev1.ContainerStatus{
+		{
+			Name: "busybox",
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "PodInitializing"},
+			},
+		},
+	}
+	pullingPod := *pendingPod.DeepCopy()
+	pullingPod.Status.InitContainerStatuses = []corev1.ContainerStatus{
+		{
+			Name: "busybox",
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{Reason: "Completed"},
+			},
+		},
+	}
+	tests := []struct {
+		name           string
+		pod            corev1.Pod
+		expectedStatus string
+		expectedDelete bool
+	}{
+		{
+			name:           "#1: Unschedulable pod - job stuck",
+			pod:            unschedulablePod,
+			expectedStatus: ImageWorkResultStatusFailed,
+			expectedDelete: true,
+		},
+		{
+			name:           "#2: Init container not completed - job stuck",
+			pod:            initStuckPod,
+			expectedStatus: ImageWorkResultStatusFailed,
+			expectedDelete: true,
+		},
+		{
+			name:           "#3: Unschedulable pod within threshold - job not stuck",
+			pod:            recentUnschedulablePod,
+			expectedStatus: ImageWorkResultStatusJobCreated,
+			expectedDelete: false,
+		},
+		{
+			name:           "#4: Image being pulled - job not stuck",
+			pod:            pullingPod,
+			expectedStatus: ImageWorkResultStatusJobCreated,
+			expectedDelete: false,
+		},
+	}
+	for _, test := range tests {
+		fakekubeclientset := &fakeclientset.Clientset{}
+		actualDelete := false
+		fakekubeclientset.AddReactor("delete", "jobs", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+			actualDelete = true
+			return true, nil, nil
+		})
+		imagemanager, podInformer := newTestImageManager(fakekubeclientset, "IfNotPresent", "sa-kube-fledged", false, "priority-class-kube-fledged", false, "")
+		podInformer.Informer().GetIndexer().Add(&test.pod)
+		imagemanager.imageworkstatus["fakejob"] = ImageWorkResult{
+			Status: ImageWorkResultStatusJobCreated,
+			ImageWorkRequest: ImageWorkRequest{
+				Image:      "foo",
+				WorkType:   ImageCacheCreate,
+				Node:       &node,
+				Imagecache: imagecache,
+			},
+		}
+		imagemanager.remediateStuckJobs()
+		if imagemanager.imageworkstatus["fakejob"].Status != test.expectedStatus {
+			t.Errorf("Test: %s failed: expectedWorkResult=%s, actualWorkResult=%s", test.name, test.expectedStatus, imagemanager.imageworkstatus["fakejob"].Status)
+		}
+		if actualDelete != test.expectedDelete {
+			t.Errorf("Test: %s failed: expectedDelete=%t, actualDelete=%t", test.name, test.expectedDelete, actualDelete)
+		}
+	}
+}
+
+func TestEnforceFailureThreshold(t *testing.T) {
+	intThreshold := intstr.FromInt(2)
+	percentThreshold := intstr.FromString("10%")
+	tests := []struct {
+		name            string
+		threshold       *intstr.IntOrString
+		workType        WorkType
+		failed          int
+		total           int
+		expectedAborted bool
+	}{
+		{
+			name:            "#1: Failures within absolute threshold",
+			threshold:       &intThreshold,
+			workType:        ImageCacheCreate,
+			failed:          2,
+			total:           10,
+			expectedAborted: false,
+		},
+		{
+			name:            "#2: Absolute threshold exceeded",
+			threshold:       &intThreshold,
+			workType:        ImageCacheCreate,
+			failed:          3,
+			total:           10,
+			expectedAborted: true,
+		},
+		{
+			name:            "#3: Percentage threshold exceeded",
+			threshold:       &percentThreshold,
+			workType:        ImageCacheRefresh,
+			failed:          3,
+			total:           20,
+			expectedAborted: true,
+		},
+		{
+			name:            "#4: No threshold",
+			workType:        ImageCacheCreate,
+			failed:          10,
+			total:           10,
+			expectedAborted: false,
+		},
+		{
+			name:            "#5: Purge never aborted",
+			threshold:       &intThreshold,
+			workType:        ImageCachePurge,
+			failed:          3,
+			total:           10,
+			expectedAborted: false,
+		},
+	}
+	for _, test := range tests {
+		imagecache := &fledgedv1alpha2.ImageCache{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "kube-fledged"},
+			Spec:       fledgedv1alpha2.ImageCacheSpec{FailureThreshold: test.threshold},
+		}
+		fakekubeclientset := &fakeclientset.Clientset{}
+		actualDelete := false
+		fakekubeclientset.AddReactor("delete", "jobs", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+			actualDelete = true
+			return true, nil, nil
+		})
+		imagemanager, _ := newTestImageManager(fakekubeclientset, "IfNotPresent", "sa-kube-fledged", false, "priority-class-kube-fledged", false, "")
+		imagemanager.progress["kube-fledged/foo"] = &imageCacheProgress{failed: test.failed}
+		iwr := ImageWorkRequest{Image: "foo", Node: &node, WorkType: test.workType, Total: test.total, Imagecache: imagecache}
+		imagemanager.imageworkstatus["runningjob"] = ImageWorkResult{ImageWorkRequest: iwr, Status: ImageWorkResultStatusJobCreated}
+
+		imagemanager.enforceFailureThreshold(iwr)
+
+		expectedStatus := ImageWorkResultStatusJobCreated
+		if test.expectedAborted {
+			expectedStatus = ImageWorkResultStatusAborted
+		}
+		if actual := imagemanager.imageworkstatus["runningjob"].Status; actual != expectedStatus {
+			t.Errorf("Test: %s failed: expectedWorkResult=%s, actualWorkResult=%s", test.name, expectedStatus, actual)
+		}
+		if actualDelete != test.expectedAborted {
+			t.Errorf("Test: %s failed: expectedDelete=%t, actualDelete=%t", test.name, test.expectedAborted, actualDelete)
+		}
+		if skipped := imagemanager.skipAborted(ImageWorkRequest{Image: "bar", Node: &node, WorkType: test.workType, Imagecache: imagecache}); skipped != test.expectedAborted {
+			t.Errorf("Test: %s failed: expectedSkipped=%t, actualSkipped=%t", test.name, test.expectedAborted, skipped)
+		}
+	}
+}
+
+func TestAbortImageCacheAction(t *testing.T) {
+	imagecache := &fledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "kube-fledged"},
+	}
+	othercache := &fledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "kube-fledged"},
+	}
+	fakekubeclientset := &fakeclientset.Clientset{}
+	deletedJobs := []string{}
+	fakekubeclientset.AddReactor("delete", "jobs", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+		deletedJobs = append(deletedJobs, action.(core.DeleteAction).GetName())
+		return true, nil, nil
+	})
+	imagemanager, _ := newTestImageManager(fakekubeclientset, "IfNotPresent", "sa-kube-fledged", false, "priority-class-kube-fledged", false, "")
+	imagemanager.imageworkstatus["job1"] = ImageWorkResult{
+		ImageWorkRequest: ImageWorkRequest{Image: "foo", Node: &node, WorkType: ImageCacheCreate, Imagecache: imagecache},
+		Status:           ImageWorkResultStatusJobCreated,
+	}
+	imagemanager.imageworkstatus["job2"] = ImageWorkResult{
+		ImageWorkRequest: ImageWorkRequest{Image: "foo", Node: &node, WorkType: ImageCacheCreate, Imagecache: othercache},
+		Status:           ImageWorkResultStatusJobCreated,
+	}
+
+	if !imagemanager.AbortImageCacheAction(imagecache, fledgedv1alpha2.ImageCacheReasonImageCacheCancel, "cancelled") {
+		t.Errorf("expected image cache action to be aborted")
+	}
+	if imagemanager.AbortImageCacheAction(imagecache, fledgedv1alpha2.ImageCacheReasonImageCacheCancel, "cancelled") {
+		t.Errorf("expected image cache action to be aborted only once")
+	}
+	if iwres := imagemanager.imageworkstatus["job1"]; iwres.Status != ImageWorkResultStatusAborted || iwres.Reason != fledgedv1alpha2.ImageCacheReasonImageCacheCancel {
+		t.Errorf("expected job1 to be aborted, got %+v", iwres)
+	}
+	if iwres := imagemanager.imageworkstatus["job2"]; iwres.Status != ImageWorkResultStatusJobCreated {
+		t.Errorf("expected job2 of another image cache to be kept, got %+v", iwres)
+	}
+	if !reflect.DeepEqual(deletedJobs, []string{"job1"}) {
+		t.Errorf("expected job1 to be deleted, got %v", deletedJobs)
+	}
+	if !imagemanager.skipAborted(ImageWorkRequest{Image: "bar", Node: &node, WorkType: ImageCacheCreate, Imagecache: imagecache}) {
+		t.Errorf("expected remaining work request to be skipped")
+	}
+	imagemanager.BeginImageCacheAction(imagecache)
+	if imagemanager.skipAborted(ImageWorkRequest{Image: "bar", Node: &node, WorkType: ImageCacheCreate, Imagecache: imagecache}) {
+		t.Errorf("expected work request of a new action not to be skipped")
+	}
+}
+
+func TestImagePullPolicyFor(t *testing.T) {
+	tests := []struct {
+		name                  string
+		imageCache            *fledgedv1alpha2.ImageCache
+		pullPolicy            corev1.PullPolicy
+		expectedPullPolicy    string
+		expectedPullOnPresent bool
+	}{
+		{
+			name:                  "#1: No image cache - controller pull policy",
+			imageCache:            nil,
+			expectedPullPolicy:    "IfNotPresent",
+			expectedPullOnPresent: false,
+		},
+		{
+			name:                  "#2: Pull policy not specified - controller pull policy",
+			imageCache:            &fledgedv1alpha2.ImageCache{},
+			expectedPullPolicy:    "IfNotPresent",
+			expectedPullOnPresent: false,
+		},
+		{
+			name: "#3: Pull policy Always - image re-pulled",
+			imageCache: &fledgedv1alpha2.ImageCache{
+				Spec: fledgedv1alpha2.ImageCacheSpec{ImagePullPolicy: corev1.PullAlways},
+			},
+			expectedPullPolicy:    "Always",
+			expectedPullOnPresent: true,
+		},
+		{
+			name: "#4: Pull policy of the image overrides the pull policy of the image cache",
+			imageCache: &fledgedv1alpha2.ImageCache{
+				Spec: fledgedv1alpha2.ImageCacheSpec{ImagePullPolicy: corev1.PullAlways},
+			},
+			pullPolicy:            corev1.PullIfNotPresent,
+			expectedPullPolicy:    "IfNotPresent",
+			expectedPullOnPresent: false,
+		},
+	}
+	testnode := node
+	testnode.Status.Images = []corev1.ContainerImage{
+		{
+			Names: []string{"foo:v1"},
+		},
+	}
+	for _, test := range tests {
+		fakekubeclientset := &fakeclientset.Clientset{}
+		imagemanager, _ := newTestImageManager(fakekubeclientset, "IfNotPresent", "sa-kube-fledged", false, "priority-class-kube-fledged", false, "")
+		pullPolicy := imagemanager.imagePullPolicyFor(ImageWorkRequest{Imagecache: test.imageCache, PullPolicy: test.pullPolicy})
+		if pullPolicy != test.expectedPullPolicy {
+			t.Errorf("Test: %s failed: expectedPullPolicy=%s, actualPullPolicy=%s", test.name, test.expectedPullPolicy, pullPolicy)
+		}
+		pull, _ := checkIfImageNeedsToBePulled(pullPolicy, "foo:v1", &testnode)
+		if pull != test.expectedPullOnPresent {
+			t.Errorf("Test: %s failed: expectedPull=%t, actualPull=%t", test.name, test.expectedPullOnPresent, pull)
+		}
+	}
+}
+
+func TestServiceAccountNameFor(t *testing.T) {
+	tests := []struct {
+		name                       string
+		imageCache                 *fledgedv1alpha2.ImageCache
+		expectedServiceAccountName string
+	}{
+		{
+			name:                       "#1: No image cache - controller service account",
+			expectedServiceAccountName: "sa-kube-fledged",
+		},
+		{
+			name:                       "#2: Service account not specified - controller service account",
+			imageCache:                 &fledgedv1alpha2.ImageCache{},
+			expectedServiceAccountName: "sa-kube-fledged",
+		},
+		{
+			name: "#3: Service account of the image cache",
+			imageCache: &fledgedv1alpha2.ImageCache{
+				Spec: fledgedv1alpha2.ImageCacheSpec{ServiceAccountName: "image-puller"},
+			},
+			expectedServiceAccountName: "image-puller",
+		},
+	}
+	for _, test := range tests {
+		imagemanager, _ := newTestImageManager(&fakeclientset.Clientset{}, "IfNotPresent", "sa-kube-fledged", false, "priority-class-kube-fledged", false, "")
+		if actual := imagemanager.serviceAccountNameFor(test.imageCache); actual != test.expectedServiceAccountName {
+			t.Errorf("Test: %s failed: expectedServiceAccountName=%s, actualServiceAccountName=%s", test.name, test.expectedServiceAccountName, actual)
+		}
+	}
+}
+
+func TestRecordProgress(t *testing.T) {
+	imagecache := &fledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "kube-fledged",
+		},
+	}
+	tests := []struct {
+		name             string
+		imageCache       *fledgedv1alpha2.ImageCache
+		results          []bool
+		flush            bool
+		expectedQueueLen int
+		expectedProgress *fledgedv1alpha2.ImageCacheProgress
+	}{
+		{
+			name:             "#1: No image cache - progress not recorded",
+			imageCache:       nil,
+			results:          []bool{false, false},
+			expectedQueueLen: 0,
+		},
+		{
+			name:             "#2: Below batch size - no progress update",
+			imageCache:       imagecache,
+			results:          []bool{false},
+			expectedQueueLen: 0,
+		},
+		{
+			name:             "#3: Batch size reached - progress update enqueued",
+			imageCache:       imagecache,
+			results:          []bool{false, true},
+			expectedQueueLen: 1,
+			expectedProgress: &fledgedv1alpha2.ImageCacheProgress{Completed: 1, Failed: 1},
+		},
+		{
+			name:             "#4: Below batch size - progress update enqueued on flush",
+			imageCache:       imagecache,
+			results:          []bool{true},
+			flush:            true,
+			expectedQueueLen: 1,
+			expectedProgress: &fledgedv1alpha2.ImageCacheProgress{Completed: 0, Failed: 1},
+		},
+		{
+			name:             "#5: Batch size reached - nothing pending on flush",
+			imageCache:       imagecache,
+			results:          []bool{false, false},
+			flush:            true,
+			expectedQueueLen: 1,
+			expectedProgress: &fledgedv1alpha2.ImageCacheProgress{Completed: 2, Failed: 0},
+		},
+	}
+	for _, test := range tests {
+		fakekubeclientset := &fakeclientset.Clientset{}
+		imagemanager, _ := newTestImageManager(fakekubeclientset, "IfNotPresent", "sa-kube-fledged", false, "priority-class-kube-fledged", false, "")
+		for _, failed := range test.results {
+			imagemanager.recordProgress(test.imageCache, failed)
+		}
+		if test.flush {
+			imagemanager.flushProgress()
+		}
+		if imagemanager.workqueue.Len() != test.expectedQueueLen {
+			t.Errorf("Test: %s failed: expectedQueueLen=%d, actualQueueLen=%d", test.name, test.expectedQueueLen, imagemanager.workqueue.Len())
+			continue
+		}
+		if test.expectedQueueLen == 0 {
+			continue
+		}
+		obj, _ := imagemanager.workqueue.Get()
+		wqKey := obj.(WorkQueueKey)
+		if wqKey.WorkType != ImageCacheProgressUpdate || !reflect.DeepEqual(wqKey.Progress, test.expectedProgress) {
+			t.Errorf("Test: %s failed: expectedProgress=%+v, actualWorkQueueKey=%+v", test.name, test.expectedProgress, wqKey)
+		}
+	}
+}
+
+func TestNewContainerdImagePullJob(t *testing.T) {
+	imagecache := &fledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "kube-fledged",
+		},
+	}
+	tests := []struct {
+		name                string
+		containerdNamespace string
+		snapshotter         string
+		expectedArgs        []string
+	}{
+		{
+			name:                "#1: Default containerd namespace",
+			containerdNamespace: DefaultContainerdNamespace,
+			expectedArgs:        []string{"-c", "exec /usr/bin/ctr --address /run/containerd/containerd.sock --namespace k8s.io images pull foo:v1"},
+		},
+		{
+			name:                "#2: Non-default containerd namespace",
+			containerdNamespace: "default",
+			expectedArgs:        []string{"-c", "exec /usr/bin/ctr --address /run/containerd/containerd.sock --namespace default images pull foo:v1"},
+		},
+		{
+			name:                "#3: Snapshotter",
+			containerdNamespace: DefaultContainerdNamespace,
+			snapshotter:         "stargz",
+			expectedArgs:        []string{"-c", "exec /usr/bin/ctr --address /run/containerd/containerd.sock --namespace k8s.io images pull --snapshotter stargz foo:v1"},
+		},
+	}
+	for _, test := range tests {
+		job, err := newContainerdImagePullJob(imagecache, "foo:v1", "", &node, "containerd://1.6.9", "cri-client", "", false, "", "", test.containerdNamespace, test.snapshotter)
+		if err != nil {
+			t.Fatalf("Test: %s failed: newContainerdImagePullJob() failed: %v", test.name, err)
+		}
+		if job.Labels[pullStrategyLabelKey] != PullStrategyContainerd {
+			t.Errorf("Test: %s failed: expected label %s=%s, got %v", test.name, pullStrategyLabelKey, PullStrategyContainerd, job.Labels)
+		}
+		container := job.Spec.Template.Spec.Containers[0]
+		if container.Name != "imagepuller" || !reflect.DeepEqual(container.Args, test.expectedArgs) {
+			t.Errorf("Test: %s failed: expected container imagepuller with args %v, got %s with args %v", test.name, test.expectedArgs, container.Name, container.Args)
+		}
+	}
+}
+
+func TestUseContainerdPull(t *testing.T) {
+	imagecache := &fledgedv1alpha2.ImageCache{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "kube-fledged"}}
+	tests := []struct {
+		name         string
+		pullStrategy string
+		gateDisabled bool
+		expected     bool
+	}{
+		{name: "#1: Containerd pull strategy", pullStrategy: PullStrategyContainerd, expected: true},
+		{name: "#2: ContainerdPull feature gate disabled", pullStrategy: PullStrategyContainerd, gateDisabled: true},
+		{name: "#3: Kubelet pull strategy", pullStrategy: PullStrategyKubelet},
+	}
+	defer features.DefaultMutableFeatureGate.SetFromMap(map[string]bool{string(features.ContainerdPull): false})
+	for _, test := range tests {
+		if err := features.DefaultMutableFeatureGate.SetFromMap(map[string]bool{string(features.ContainerdPull): !test.gateDisabled}); err != nil {
+			t.Fatalf("Test: %s failed: error setting feature gate: %v", test.name, err)
+		}
+		imagemanager, _ := newTestImageManager(&fakeclientset.Clientset{}, "IfNotPresent", "sa-kube-fledged", false, "", false, "")
+		imagemanager.pullStrategy = test.pullStrategy
+		iwr := ImageWorkRequest{Image: "foo:v1", Node: &node, ContainerRuntimeVersion: "containerd://1.6.9",
+			WorkType: ImageCacheCreate, Imagecache: imagecache}
+		if actual := imagemanager.useContainerdPull(iwr); actual != test.expected {
+			t.Errorf("Test: %s failed: expected=%t, actual=%t", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestDeleteImageContainerdNamespace(t *testing.T) {
+	if err := features.DefaultMutableFeatureGate.SetFromMap(map[string]bool{string(features.ContainerdPull): true}); err != nil {
+		t.Fatalf("Error enabling feature gate: %v", err)
+	}
+	defer features.DefaultMutableFeatureGate.SetFromMap(map[string]bool{string(features.ContainerdPull): false})
+	imagecache := &fledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "kube-fledged",
+		},
+	}
+	tests := []struct {
+		name                string
+		pullStrategy        string
+		containerdNamespace string
+		expectedArgs        []string
+	}{
+		{
+			name:                "#1: Default containerd namespace - image deleted with crictl",
+			pullStrategy:        PullStrategyContainerd,
+			containerdNamespace: DefaultContainerdNamespace,
+			expectedArgs: []string{"-c", "exec /usr/bin/crictl --runtime-endpoint=unix:///run/containerd/containerd.sock" +
+				" --image-endpoint=unix:///run/containerd/containerd.sock rmi foo:v1 > /dev/termination-log 2>&1"},
+		},
+		{
+			name:                "#2: Non-default containerd namespace - image deleted with ctr",
+			pullStrategy:        PullStrategyContainerd,
+			containerdNamespace: "default",
+			expectedArgs: []string{"-c", "exec /usr/bin/ctr --address /run/containerd/containerd.sock --namespace default" +
+				" images rm foo:v1 > /dev/termination-log 2>&1"},
+		},
+		{
+			name:                "#3: Kubelet pull strategy - image deleted with crictl",
+			pullStrategy:        PullStrategyKubelet,
+			containerdNamespace: "default",
+			expectedArgs: []string{"-c", "exec /usr/bin/crictl --runtime-endpoint=unix:///run/containerd/containerd.sock" +
+				" --image-endpoint=unix:///run/containerd/containerd.sock rmi foo:v1 > /dev/termination-log 2>&1"},
+		},
+	}
+	for _, test := range tests {
+		fakekubeclientset := &fakeclientset.Clientset{}
+		var job *batchv1.Job
+		fakekubeclientset.AddReactor("create", "jobs", func(action core.Action) (bool, runtime.Object, error) {
+			job = action.(core.CreateAction).GetObject().(*batchv1.Job)
+			return true, job, nil
+		})
+		imagemanager, _ := newTestImageManager(fakekubeclientset, "IfNotPresent", "sa-kube-fledged", false, "", false, "")
+		imagemanager.pullStrategy = test.pullStrategy
+		imagemanager.containerdNamespace = test.containerdNamespace
+		iwr := ImageWorkRequest{Image: "foo:v1", Node: &node, ContainerRuntimeVersion: "containerd://1.6.9",
+			WorkType: ImageCachePurge, Imagecache: imagecache}
+		if _, err := imagemanager.deleteImage(iwr); err != nil {
+			t.Errorf("Test: %s failed: unexpected error: %v", test.name, err)
+			continue
+		}
+		if args := job.Spec.Template.Spec.Containers[0].Args; !reflect.DeepEqual(args, test.expectedArgs) {
+			t.Errorf("Test: %s failed: expectedArgs=%v, actualArgs=%v", test.name, test.expectedArgs, args)
+		}
+	}
+}
+
+func TestNewPlatformImagePullJob(t *testing.T) {
+	imagecache := &fledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "kube-fledged",
+		},
+	}
+	tests := []struct {
+		name                    string
+		node                    *corev1.Node
+		containerRuntimeVersion string
+		expectedArgs            []string
+		expectError             bool
+	}{
+		{
+			name:                    "#1: containerd node",
+			node:                    &node,
+			containerRuntimeVersion: "containerd://1.6.9",
+			expectedArgs:            []string{"-c", "exec /usr/bin/ctr --address /run/containerd/containerd.sock --namespace k8s.io images pull --platform linux/arm64 foo:v1"},
+		},
+		{
+			name:                    "#2: docker node",
+			node:                    &node,
+			containerRuntimeVersion: "docker://20.10.20",
+			expectedArgs:            []string{"-c", "exec /usr/bin/docker pull --platform linux/arm64 foo:v1 > /dev/termination-log 2>&1"},
+		},
+		{
+			name:                    "#3: cri-o node - not supported",
+			node:                    &node,
+			containerRuntimeVersion: "cri-o://1.25.0",
+			expectError:             true,
+		},
+		{
+			name:                    "#4: windows node - not supported",
+			node:                    &windowsNode,
+			containerRuntimeVersion: "containerd://1.6.9",
+			expectError:             true,
+		},
+	}
+	for _, test := range tests {
+		job, err := newPlatformImagePullJob(imagecache, "foo:v1", "linux/arm64", test.node, test.containerRuntimeVersion, "cri-client", "", false, "", "", "")
+		if test.expectError {
+			if err == nil {
+				t.Errorf("Test: %s failed: expected error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test: %s failed: unexpected error: %v", test.name, err)
+			continue
+		}
+		if args := job.Spec.Template.Spec.Containers[0].Args; !reflect.DeepEqual(args, test.expectedArgs) {
+			t.Errorf("Test: %s failed: expectedArgs=%v, actualArgs=%v", test.name, test.expectedArgs, args)
+		}
+	}
+}
+
+func TestNewRetagImagePullJob(t *testing.T) {
+	imagecache := &fledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "kube-fledged",
+		},
+	}
+	tests := []struct {
+		name                    string
+		node                    *corev1.Node
+		containerRuntimeVersion string
+		platform                string
+		snapshotter             string
+		imagePullSecrets        []corev1.LocalObjectReference
+		expectedArgs            []string
+		expectError             bool
+	}{
+		{
+			name:                    "#1: containerd node",
+			node:                    &node,
+			containerRuntimeVersion: "containerd://1.6.9",
+			expectedArgs: []string{"-c", "/usr/bin/ctr --address /run/containerd/containerd.sock --namespace k8s.io images pull mirror.internal/library/foo:v1 > /dev/termination-log 2>&1 && " +
+				"/usr/bin/ctr --address /run/containerd/containerd.sock --namespace k8s.io images tag --force mirror.internal/library/foo:v1 docker.io/library/foo:v1 > /dev/termination-log 2>&1 && " +
+				"exec /usr/bin/ctr --address /run/containerd/containerd.sock --namespace k8s.io images rm mirror.internal/library/foo:v1 > /dev/termination-log 2>&1"},
+		},
+		{
+			name:                    "#2: docker node with platform",
+			node:                    &node,
+			containerRuntimeVersion: "docker://20.10.20",
+			platform:                "linux/arm64",
+			expectedArgs: []string{"-c", "/usr/bin/docker pull --platform linux/arm64 mirror.internal/library/foo:v1 > /dev/termination-log 2>&1 && " +
+				"/usr/bin/docker tag mirror.internal/library/foo:v1 foo:v1 > /dev/termination-log 2>&1 && " +
+				"exec /usr/bin/docker image rm mirror.internal/library/foo:v1 > /dev/termination-log 2>&1"},
+		},
+		{
+			name:                    "#3: cri-o node - not supported",
+			node:                    &node,
+			containerRuntimeVersion: "cri-o://1.25.0",
+			expectError:             true,
+		},
+		{
+			name:                    "#4: windows node - not supported",
+			node:                    &windowsNode,
+			containerRuntimeVersion: "containerd://1.6.9",
+			expectError:             true,
+		},
+		{
+			name:                    "#5: image pull secrets - not supported",
+			node:                    &node,
+			containerRuntimeVersion: "containerd://1.6.9",
+			imagePullSecrets:        []corev1.LocalObjectReference{{Name: "regcred"}},
+			expectError:             true,
+		},
+		{
+			name:                    "#6: containerd node with snapshotter",
+			node:                    &node,
+			containerRuntimeVersion: "containerd://1.6.9",
+			snapshotter:             "nydus",
+			expectedArgs: []string{"-c", "/usr/bin/ctr --address /run/containerd/containerd.sock --namespace k8s.io images pull --snapshotter nydus mirror.internal/library/foo:v1 > /dev/termination-log 2>&1 && " +
+				"/usr/bin/ctr --address /run/containerd/containerd.sock --namespace k8s.io images tag --force mirror.internal/library/foo:v1 docker.io/library/foo:v1 > /dev/termination-log 2>&1 && " +
+				"exec /usr/bin/ctr --address /run/containerd/containerd.sock --namespace k8s.io images rm mirror.internal/library/foo:v1 > /dev/termination-log 2>&1"},
+		},
+	}
+	for _, test := range tests {
+		imagecache.Spec.ImagePullSecrets = test.imagePullSecrets
+		job, err := newRetagImagePullJob(imagecache, "mirror.internal/library/foo:v1", "foo:v1", test.platform, test.node,
+			test.containerRuntimeVersion, "cri-client", "", false, "", "", DefaultContainerdNamespace, test.snapshotter)
+		if test.expectError {
+			if err == nil {
+				t.Errorf("Test: %s failed: expected error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test: %s failed: unexpected error: %v", test.name, err)
+			continue
+		}
+		if args := job.Spec.Template.Spec.Containers[0].Args; !reflect.DeepEqual(args, test.expectedArgs) {
+			t.Errorf("Test: %s failed: expectedArgs=%v, actualArgs=%v", test.name, test.expectedArgs, args)
+		}
+	}
+}
+
+func TestDigestPinned(t *testing.T) {
+	digest := "sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31"
+	tests := []struct {
+		name     string
+		image    string
+		expected bool
+	}{
+		{name: "#1: Digest only", image: "nginx@" + digest, expected: true},
+		{name: "#2: Tag and digest", image: "nginx:1.23@" + digest, expected: true},
+		{name: "#3: Tag only", image: "nginx:1.23", expected: false},
+		{name: "#4: Invalid reference", image: "Nginx@" + digest, expected: false},
+	}
+	for _, test := range tests {
+		if actual := DigestPinned(test.image); actual != test.expected {
+			t.Errorf("Test: %s failed: expected=%t, actual=%t", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestCollectPullProgress(t *testing.T) {
+	if err := features.DefaultMutableFeatureGate.SetFromMap(map[string]bool{string(features.ContainerdPull): true}); err != nil {
+		t.Fatalf("Error enabling feature gate: %v", err)
+	}
+	defer features.DefaultMutableFeatureGate.SetFromMap(map[string]bool{string(features.ContainerdPull): false})
+	imagecache := &fledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "kube-fledged",
+		},
+	}
+	iwr := ImageWorkRequest{
+		Image:                   "foo:v1",
+		Node:                    &node,
+		ContainerRuntimeVersion: "containerd://1.6.9",
+		WorkType:                ImageCacheCreate,
+		Imagecache:              imagecache,
+	}
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "fakejob-pod",
+			Namespace: "kube-fledged",
+			Labels:    map[string]string{"job-name": "fakejob"},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	tests := []struct {
+		name          string
+		pullStrategy  string
+		expectedPulls int
+	}{
+		{
+			name:          "#1: kubelet pull strategy - no pull progress",
+			pullStrategy:  PullStrategyKubelet,
+			expectedPulls: 0,
+		},
+		{
+			name:          "#2: containerd pull strategy - pull progress collected",
+			pullStrategy:  PullStrategyContainerd,
+			expectedPulls: 1,
+		},
+	}
+	for _, test := range tests {
+		fakekubeclientset := &fakeclientset.Clientset{}
+		imagemanager, podInformer := newTestImageManager(fakekubeclientset, "IfNotPresent", "sa-kube-fledged", false, "priority-class-kube-fledged", false, "")
+		imagemanager.pullStrategy = test.pullStrategy
+		podInformer.Informer().GetIndexer().Add(&pod)
+		imagemanager.imageworkstatus["fakejob"] = ImageWorkResult{ImageWorkRequest: iwr, Status: ImageWorkResultStatusJobCreated}
+		imagemanager.flushProgress()
+		if imagemanager.workqueue.Len() != test.expectedPulls {
+			t.Errorf("Test: %s failed: expectedQueueLen=%d, actualQueueLen=%d", test.name, test.expectedPulls, imagemanager.workqueue.Len())
+			continue
+		}
+		if test.expectedPulls == 0 {
+			continue
+		}
+		obj, _ := imagemanager.workqueue.Get()
+		pulls := obj.(WorkQueueKey).Progress.Pulls
+		if len(pulls) != test.expectedPulls || pulls[0].Image != "foo:v1" || pulls[0].Node != node.Labels["kubernetes.io/hostname"] {
+			t.Errorf("Test: %s failed: expectedPulls=%d, actualPulls=%+v", test.name, test.expectedPulls, pulls)
+		}
 	}
 }
 
@@ -632,7 +1890,7 @@ func TestUpdateImageCacheStatus(t *testing.T) {
 		}
 		imagemanager.imageworkstatus = test.imageworkstatus
 		errCh := make(chan error)
-		go imagemanager.updateImageCacheStatus(imageCache, errCh)
+		go imagemanager.updateImageCacheStatus(imageCache, 0, errCh)
 		err := <-errCh
 		if err != nil {
 			t.Logf("err=%s", err.Error())
@@ -910,11 +2168,12 @@ func TestProcessNextWorkItem(t *testing.T) {
 			}
 		}
 		imagemanager.imageworkstatus = test.imageworkstatus
+		imageworkqueue := imagemanager.imageworkqueues.For(test.iwr.WorkType, false)
 		if test.expectedErrorString == "Unexpected type in workqueue" {
-			imagemanager.imageworkqueue.Add(struct{}{})
+			imageworkqueue.Add(struct{}{})
 		}
-		imagemanager.imageworkqueue.Add(test.iwr)
-		imagemanager.processNextWorkItem()
+		imageworkqueue.Add(test.iwr)
+		imagemanager.processNextWorkItem(imageworkqueue)
 		var err error
 		if test.expectError {
 			if err == nil {
@@ -0,0 +1,118 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/google/go-containerregistry/pkg/authn"
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// imagePullerContainerName is the name of the container of the image pull jobs which
+// runs the pulled image
+const imagePullerContainerName = "imagepuller"
+
+// resolveDigest returns the digest the pull reference of the work request resolves to
+// in the registry, against which the image pulled on the node is verified. Empty is
+// returned if the verification of image digests is disabled, or if the image pulled by
+// the job is not reported in the status of its pod, i.e. for the images pulled with a
+// CRI client and the artifacts. Images whose digest can't be resolved aren't verified.
+// The pull reference is resolved once per image cache action, for all the nodes, with
+// the image pull secrets of the image cache.
+func (m *ImageManager) resolveDigest(iwr ImageWorkRequest) string {
+	if m.digestResolver == nil || iwr.Artifact || iwr.TargetRef != "" || iwr.Platform != "" || m.useContainerdPull(iwr) {
+		return ""
+	}
+	image := iwr.PullReference()
+	key := ""
+	var keychain authn.Keychain
+	if iwr.Imagecache != nil {
+		keychain = m.keychains.For(iwr.Imagecache.Namespace, iwr.Imagecache.Spec.ImagePullSecrets)
+		key = iwr.Imagecache.Namespace + "/" + iwr.Imagecache.Name
+		m.digestLock.Lock()
+		d, ok := m.resolvedDigests[key][image]
+		m.digestLock.Unlock()
+		if ok {
+			return d
+		}
+	}
+	d, err := m.digestResolver.ResolveDigest(image, keychain)
+	if err != nil {
+		glog.Warningf("Digest of image %s not verified: %v", image, err)
+		d = ""
+	}
+	if key != "" {
+		m.digestLock.Lock()
+		if m.resolvedDigests[key] == nil {
+			m.resolvedDigests[key] = map[string]string{}
+		}
+		m.resolvedDigests[key][image] = d
+		m.digestLock.Unlock()
+	}
+	return d
+}
+
+// forgetResolvedDigests forgets the digests resolved for the finished action of an image
+// cache: the next action resolves its images again
+func (m *ImageManager) forgetResolvedDigests(imageCache *fledgedv1alpha2.ImageCache) {
+	m.digestLock.Lock()
+	delete(m.resolvedDigests, imageCache.Namespace+"/"+imageCache.Name)
+	m.digestLock.Unlock()
+}
+
+// pulledDigest returns the digest of the image pulled on the node by the pod of an image
+// pull job, as reported by the container runtime in the image ID of the container
+// running it, e.g. docker.io/library/nginx@sha256:... Empty is returned if the runtime
+// reports no repository digest.
+func pulledDigest(pod *corev1.Pod) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != imagePullerContainerName {
+			continue
+		}
+		if i := strings.LastIndex(status.ImageID, "@"); i >= 0 {
+			return status.ImageID[i+1:]
+		}
+	}
+	return ""
+}
+
+// verifyDigest fails the result of a successful image pull if the digest of the image
+// pulled on the node differs from the digest resolved from the registry before the
+// pull, e.g. because of a corrupted mirror or a tag swapped between the resolution and
+// the pull
+func (m *ImageManager) verifyDigest(iwres *ImageWorkResult, pod *corev1.Pod) {
+	if iwres.Status != ImageWorkResultStatusSucceeded || iwres.resolvedDigest == "" {
+		return
+	}
+	pulled := pulledDigest(pod)
+	if pulled == "" || pulled == iwres.resolvedDigest {
+		return
+	}
+	iwres.Status = ImageWorkResultStatusFailed
+	iwres.Reason = fledgedv1alpha2.ImageCacheReasonImageDigestMismatch
+	iwres.Message = fmt.Sprintf("Digest %s of image %s pulled on the node differs from digest %s resolved from the registry",
+		pulled, iwres.ImageWorkRequest.PullReference(), iwres.resolvedDigest)
+	glog.Warningf("%s (node %s)", iwres.Message, iwres.ImageWorkRequest.Node.Name)
+	if m.recorder != nil {
+		m.recorder.Eventf(iwres.ImageWorkRequest.Imagecache, corev1.EventTypeWarning, iwres.Reason, "%s: node %s",
+			iwres.Message, iwres.ImageWorkRequest.Node.Name)
+	}
+}
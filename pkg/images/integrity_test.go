@@ -0,0 +1,217 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/registry"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	resolvedTestDigest = "sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31"
+	swappedTestDigest  = "sha256:7031c1b283388d2c2e09b57badb803c05ebed362dc88d84b480cc47f72a21097"
+)
+
+// fakeDigestResolver resolves images to fixed digests
+type fakeDigestResolver map[string]string
+
+func (r fakeDigestResolver) ResolveDigest(image string, keychain authn.Keychain) (string, error) {
+	if d, ok := r[image]; ok {
+		return d, nil
+	}
+	return "", fmt.Errorf("manifest unknown")
+}
+
+func TestResolveDigest(t *testing.T) {
+	tests := []struct {
+		name           string
+		resolver       fakeDigestResolver
+		iwr            ImageWorkRequest
+		expectedDigest string
+	}{
+		{
+			name:           "#1: Image pulled by kubelet",
+			resolver:       fakeDigestResolver{"nginx:1.23": resolvedTestDigest},
+			iwr:            ImageWorkRequest{Image: "nginx:1.23"},
+			expectedDigest: resolvedTestDigest,
+		},
+		{
+			name:           "#2: Image pulled from fallback reference",
+			resolver:       fakeDigestResolver{"mirror.internal/nginx:1.23": resolvedTestDigest},
+			iwr:            ImageWorkRequest{Image: "nginx:1.23", Source: "mirror.internal/nginx:1.23"},
+			expectedDigest: resolvedTestDigest,
+		},
+		{
+			name:     "#3: Image pulled with CRI client",
+			resolver: fakeDigestResolver{"nginx:1.23": resolvedTestDigest},
+			iwr:      ImageWorkRequest{Image: "nginx:1.23", Platform: "linux/arm64"},
+		},
+		{
+			name:     "#4: Digest not resolved",
+			resolver: fakeDigestResolver{},
+			iwr:      ImageWorkRequest{Image: "nginx:1.23"},
+		},
+		{
+			name: "#5: Verification disabled",
+			iwr:  ImageWorkRequest{Image: "nginx:1.23"},
+		},
+	}
+	for _, test := range tests {
+		imagemanager, _ := newTestImageManager(fakeclientset.NewSimpleClientset(), "IfNotPresent", "", false, "", false, "")
+		if test.resolver != nil {
+			imagemanager.digestResolver = test.resolver
+		}
+		if d := imagemanager.resolveDigest(test.iwr); d != test.expectedDigest {
+			t.Errorf("Test: %s failed: expectedDigest=%q, actualDigest=%q", test.name, test.expectedDigest, d)
+		}
+	}
+}
+
+// countingDigestResolver counts the digests resolved
+type countingDigestResolver struct {
+	fakeDigestResolver
+	resolved int
+}
+
+func (r *countingDigestResolver) ResolveDigest(image string, keychain authn.Keychain) (string, error) {
+	r.resolved++
+	return r.fakeDigestResolver.ResolveDigest(image, keychain)
+}
+
+func TestResolveDigestOncePerAction(t *testing.T) {
+	imageCache := &fledgedv1alpha2.ImageCache{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace}}
+	resolver := &countingDigestResolver{fakeDigestResolver: fakeDigestResolver{"nginx:1.23": resolvedTestDigest}}
+	imagemanager, _ := newTestImageManager(fakeclientset.NewSimpleClientset(), "IfNotPresent", "", false, "", false, "")
+	imagemanager.digestResolver = resolver
+	for _, image := range []string{"nginx:1.23", "nginx:1.23", "redis:7", "redis:7"} {
+		imagemanager.resolveDigest(ImageWorkRequest{Image: image, Imagecache: imageCache})
+	}
+	if resolver.resolved != 2 {
+		t.Errorf("expected each image resolved once per action, actual resolved=%d", resolver.resolved)
+	}
+	imagemanager.forgetResolvedDigests(imageCache)
+	if d := imagemanager.resolveDigest(ImageWorkRequest{Image: "nginx:1.23", Imagecache: imageCache}); d != resolvedTestDigest || resolver.resolved != 3 {
+		t.Errorf("expected image resolved again by the next action, actual digest=%q, resolved=%d", d, resolver.resolved)
+	}
+}
+
+// keychainDigestResolver records the keychain of the last digest resolved
+type keychainDigestResolver struct {
+	keychain authn.Keychain
+}
+
+func (r *keychainDigestResolver) ResolveDigest(image string, keychain authn.Keychain) (string, error) {
+	r.keychain = keychain
+	return resolvedTestDigest, nil
+}
+
+func TestResolveDigestWithImagePullSecrets(t *testing.T) {
+	pullSecrets := []corev1.LocalObjectReference{{Name: "team"}}
+	imageCache := &fledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "team-a"},
+		Spec:       fledgedv1alpha2.ImageCacheSpec{ImagePullSecrets: pullSecrets},
+	}
+	resolver := &keychainDigestResolver{}
+	imagemanager, _ := newTestImageManager(fakeclientset.NewSimpleClientset(), "IfNotPresent", "", false, "", false, "")
+	imagemanager.digestResolver = resolver
+	imagemanager.keychains = registry.NewKeychains(fakeclientset.NewSimpleClientset(), nil, time.Minute)
+	imagemanager.resolveDigest(ImageWorkRequest{Image: "nginx:1.23", Imagecache: imageCache})
+	if resolver.keychain == nil || resolver.keychain != imagemanager.keychains.For("team-a", pullSecrets) {
+		t.Errorf("expected the digest resolved with the keychain of the image pull secrets of the image cache")
+	}
+}
+
+func TestVerifyDigest(t *testing.T) {
+	imageCache := &fledgedv1alpha2.ImageCache{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace}}
+	tests := []struct {
+		name           string
+		resolvedDigest string
+		imageID        string
+		expectedStatus string
+		expectedReason string
+		noRecorder     bool
+	}{
+		{
+			name:           "#1: Digest matches",
+			resolvedDigest: resolvedTestDigest,
+			imageID:        "docker.io/library/nginx@" + resolvedTestDigest,
+			expectedStatus: ImageWorkResultStatusSucceeded,
+		},
+		{
+			name:           "#2: Digest mismatch",
+			resolvedDigest: resolvedTestDigest,
+			imageID:        "docker-pullable://nginx@" + swappedTestDigest,
+			expectedStatus: ImageWorkResultStatusFailed,
+			expectedReason: fledgedv1alpha2.ImageCacheReasonImageDigestMismatch,
+		},
+		{
+			name:           "#3: No repository digest reported",
+			resolvedDigest: resolvedTestDigest,
+			imageID:        swappedTestDigest,
+			expectedStatus: ImageWorkResultStatusSucceeded,
+		},
+		{
+			name:           "#4: Digest not resolved",
+			imageID:        "docker.io/library/nginx@" + swappedTestDigest,
+			expectedStatus: ImageWorkResultStatusSucceeded,
+		},
+		{
+			name:           "#5: Digest mismatch without event recorder",
+			resolvedDigest: resolvedTestDigest,
+			imageID:        "docker.io/library/nginx@" + swappedTestDigest,
+			expectedStatus: ImageWorkResultStatusFailed,
+			expectedReason: fledgedv1alpha2.ImageCacheReasonImageDigestMismatch,
+			noRecorder:     true,
+		},
+	}
+	for _, test := range tests {
+		imagemanager, _ := newTestImageManager(fakeclientset.NewSimpleClientset(), "IfNotPresent", "", false, "", false, "")
+		if test.noRecorder {
+			imagemanager.recorder = nil
+		}
+		imagemanager.imageworkstatus["fakejob"] = ImageWorkResult{
+			Status:         ImageWorkResultStatusJobCreated,
+			resolvedDigest: test.resolvedDigest,
+			ImageWorkRequest: ImageWorkRequest{
+				Image:      "nginx:1.23",
+				WorkType:   ImageCacheCreate,
+				Node:       &node,
+				Imagecache: imageCache,
+			},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"job-name": "fakejob"}},
+			Status: corev1.PodStatus{
+				Phase:             corev1.PodSucceeded,
+				ContainerStatuses: []corev1.ContainerStatus{{Name: imagePullerContainerName, ImageID: test.imageID}},
+			},
+		}
+		imagemanager.handlePodStatusChange(pod)
+		iwres := imagemanager.imageworkstatus["fakejob"]
+		if iwres.Status != test.expectedStatus || iwres.Reason != test.expectedReason {
+			t.Errorf("Test: %s failed: expected %s/%q, actual %s/%q", test.name, test.expectedStatus, test.expectedReason, iwres.Status, iwres.Reason)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"time"
+
+	"github.com/golang/glog"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// podJobName returns the name of the job owning the pod of an image pull/delete job
+func podJobName(pod *corev1.Pod) string {
+	if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "Job" {
+		return owner.Name
+	}
+	return pod.Labels["job-name"]
+}
+
+// jobImageCache returns the name of the image cache owning an image pull/delete job
+func jobImageCache(job *batchv1.Job) string {
+	for _, owner := range job.OwnerReferences {
+		if owner.Kind == "ImageCache" {
+			return owner.Name
+		}
+	}
+	return job.Labels["imagecache"]
+}
+
+// jobFinished returns the condition of a job that completed or failed, if any
+func jobFinished(job *batchv1.Job) *batchv1.JobCondition {
+	for i, c := range job.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+			return &job.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// handleJobStatusChange records the result of a work item once its job completed or
+// failed. The result is taken from the pod of the job if it finished, so that the pod
+// failure is reported and retried. Otherwise, e.g. if the pod was deleted or the job
+// exceeded its deadline, the result is taken from the job condition.
+func (m *ImageManager) handleJobStatusChange(oldJob, newJob *batchv1.Job) {
+	condition := jobFinished(newJob)
+	if condition == nil || jobFinished(oldJob) != nil {
+		return
+	}
+	pods, err := m.podsLister.Pods(newJob.Namespace).List(labels.Set(map[string]string{"job-name": newJob.Name}).AsSelector())
+	if err != nil {
+		glog.Errorf("Error listing Pods: %v", err)
+	}
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			m.handlePodStatusChange(pod)
+			return
+		}
+	}
+	m.lock.Lock()
+	iwres, ok := m.imageworkstatus[newJob.Name]
+	if !ok || iwres.Status != ImageWorkResultStatusJobCreated || iwres.retrying {
+		m.lock.Unlock()
+		return
+	}
+	if condition.Type == batchv1.JobComplete {
+		iwres.Status = ImageWorkResultStatusSucceeded
+	} else {
+		iwres.Status = ImageWorkResultStatusFailed
+		iwres.Reason = condition.Reason
+		iwres.Message = condition.Message
+	}
+	m.imageworkstatus[newJob.Name] = iwres
+	m.lock.Unlock()
+	glog.Infof("Job %s %s (image: %s --> %s, imagecache: %s)", newJob.Name, iwres.Status, iwres.ImageWorkRequest.Image,
+		iwres.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"], jobImageCache(newJob))
+	m.recordRegistryOutcome(iwres)
+	m.notifyWorkDone()
+	m.recordProgress(iwres.ImageWorkRequest.Imagecache, iwres.Status == ImageWorkResultStatusFailed)
+	if iwres.Status == ImageWorkResultStatusFailed {
+		m.enforceFailureThreshold(iwres.ImageWorkRequest)
+	}
+}
+
+// workDoneCh returns a channel closed once the next work item is done
+func (m *ImageManager) workDoneCh() <-chan struct{} {
+	m.workDoneLock.Lock()
+	defer m.workDoneLock.Unlock()
+	return m.workDone
+}
+
+// notifyWorkDone wakes up the image cache actions waiting for their work items
+func (m *ImageManager) notifyWorkDone() {
+	m.workDoneLock.Lock()
+	defer m.workDoneLock.Unlock()
+	close(m.workDone)
+	m.workDone = make(chan struct{})
+}
+
+// pendingWork returns true if a work item of the image cache is in progress
+func (m *ImageManager) pendingWork(imageCacheName string) bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	for _, iwres := range m.imageworkstatus {
+		if iwres.ImageWorkRequest.Imagecache.Name == imageCacheName && iwres.Status == ImageWorkResultStatusJobCreated {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForWork waits until the work items of the image cache are done, or the image pull
+// deadline expired. It is woken up by the informers of the jobs and their pods whenever
+// a work item is done, instead of polling.
+func (m *ImageManager) waitForWork(imageCacheName string, pullDeadline time.Duration) {
+	deadline := time.NewTimer(pullDeadline)
+	defer deadline.Stop()
+	for {
+		// The channel is taken before checking the work items, so that the work items
+		// done in the meantime are not missed
+		done := m.workDoneCh()
+		if !m.pendingWork(imageCacheName) {
+			return
+		}
+		select {
+		case <-done:
+		case <-deadline.C:
+			return
+		}
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"testing"
+	"time"
+
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPodJobName(t *testing.T) {
+	controller := true
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"job-name": "labeljob"}}}
+	if name := podJobName(pod); name != "labeljob" {
+		t.Errorf("expected job name from label, actual %q", name)
+	}
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "Job", Name: "ownerjob", Controller: &controller}}
+	if name := podJobName(pod); name != "ownerjob" {
+		t.Errorf("expected job name from owner, actual %q", name)
+	}
+}
+
+func TestHandleJobStatusChange(t *testing.T) {
+	imageCache := &fledgedv1alpha2.ImageCache{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace}}
+	runningJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "fakejob", Namespace: fledgedNameSpace, ResourceVersion: "1"}}
+	finishedJob := func(conditionType batchv1.JobConditionType, reason, message string) *batchv1.Job {
+		job := runningJob.DeepCopy()
+		job.ResourceVersion = "2"
+		job.Status.Conditions = []batchv1.JobCondition{{Type: conditionType, Status: corev1.ConditionTrue, Reason: reason, Message: message}}
+		return job
+	}
+	failedPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "fakepod", Namespace: fledgedNameSpace, Labels: map[string]string{"job-name": "fakejob"}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodFailed,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", Message: "pull failed"}}},
+			},
+		},
+	}
+	tests := []struct {
+		name            string
+		oldJob          *batchv1.Job
+		newJob          *batchv1.Job
+		pod             *corev1.Pod
+		expectedStatus  string
+		expectedReason  string
+		expectedMessage string
+	}{
+		{
+			name:           "#1: Job completed without a pod",
+			oldJob:         runningJob,
+			newJob:         finishedJob(batchv1.JobComplete, "", ""),
+			expectedStatus: ImageWorkResultStatusSucceeded,
+		},
+		{
+			name:            "#2: Job failed without a pod",
+			oldJob:          runningJob,
+			newJob:          finishedJob(batchv1.JobFailed, "DeadlineExceeded", "Job was active longer than specified deadline"),
+			expectedStatus:  ImageWorkResultStatusFailed,
+			expectedReason:  "DeadlineExceeded",
+			expectedMessage: "Job was active longer than specified deadline",
+		},
+		{
+			name:            "#3: Job failed with a failed pod",
+			oldJob:          runningJob,
+			newJob:          finishedJob(batchv1.JobFailed, "BackoffLimitExceeded", "Job has reached the specified backoff limit"),
+			pod:             failedPod,
+			expectedStatus:  ImageWorkResultStatusFailed,
+			expectedReason:  "Error",
+			expectedMessage: "pull failed",
+		},
+		{
+			name:           "#4: Job finished already",
+			oldJob:         finishedJob(batchv1.JobComplete, "", ""),
+			newJob:         finishedJob(batchv1.JobComplete, "", ""),
+			expectedStatus: ImageWorkResultStatusJobCreated,
+		},
+	}
+	for _, test := range tests {
+		imagemanager, podInformer := newTestImageManager(fakeclientset.NewSimpleClientset(), "IfNotPresent", "sa-kube-fledged", false, "", false, "")
+		if test.pod != nil {
+			podInformer.Informer().GetIndexer().Add(test.pod)
+		}
+		imagemanager.imageworkstatus["fakejob"] = ImageWorkResult{
+			Status:           ImageWorkResultStatusJobCreated,
+			ImageWorkRequest: ImageWorkRequest{Image: "foo:v1", Node: &node, WorkType: ImageCacheCreate, Imagecache: imageCache},
+		}
+		done := imagemanager.workDoneCh()
+		imagemanager.handleJobStatusChange(test.oldJob, test.newJob)
+		iwres := imagemanager.imageworkstatus["fakejob"]
+		if iwres.Status != test.expectedStatus || iwres.Reason != test.expectedReason || iwres.Message != test.expectedMessage {
+			t.Errorf("Test: %s failed: expected status %s, reason %q, message %q, actual result %+v",
+				test.name, test.expectedStatus, test.expectedReason, test.expectedMessage, iwres)
+		}
+		select {
+		case <-done:
+			if test.expectedStatus == ImageWorkResultStatusJobCreated {
+				t.Errorf("Test: %s failed: unexpected notification of work done", test.name)
+			}
+		default:
+			if test.expectedStatus != ImageWorkResultStatusJobCreated {
+				t.Errorf("Test: %s failed: expected notification of work done", test.name)
+			}
+		}
+	}
+}
+
+func TestWaitForWork(t *testing.T) {
+	imageCache := &fledgedv1alpha2.ImageCache{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace}}
+	imagemanager, _ := newTestImageManager(fakeclientset.NewSimpleClientset(), "IfNotPresent", "sa-kube-fledged", false, "", false, "")
+	imagemanager.imageworkstatus["fakejob"] = ImageWorkResult{
+		Status:           ImageWorkResultStatusJobCreated,
+		ImageWorkRequest: ImageWorkRequest{Image: "foo:v1", Node: &node, WorkType: ImageCacheCreate, Imagecache: imageCache},
+	}
+
+	// The deadline expires while the work item is in progress
+	start := time.Now()
+	imagemanager.waitForWork("foo", time.Millisecond*50)
+	if elapsed := time.Since(start); elapsed < time.Millisecond*50 {
+		t.Errorf("expected wait until the deadline, returned after %s", elapsed)
+	}
+
+	// The wait ends once the work item is done
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		imagemanager.lock.Lock()
+		iwres := imagemanager.imageworkstatus["fakejob"]
+		iwres.Status = ImageWorkResultStatusSucceeded
+		imagemanager.imageworkstatus["fakejob"] = iwres
+		imagemanager.lock.Unlock()
+		imagemanager.notifyWorkDone()
+	}()
+	start = time.Now()
+	imagemanager.waitForWork("foo", time.Minute)
+	if elapsed := time.Since(start); elapsed > time.Second*10 {
+		t.Errorf("expected wait to end once the work item is done, returned after %s", elapsed)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/features"
+	"github.com/senthilrch/kube-fledged/pkg/nodestats"
+	"k8s.io/apiserver/pkg/storage/names"
+)
+
+// nodeLoadTTL is the period during which the load read from a node is reused for the
+// image pulls dispatched to it
+const nodeLoadTTL = 15 * time.Second
+
+// LoadThrottle configures the throttling of image pulls on loaded nodes. No image pull
+// is dispatched to a node whose load exceeds one of the thresholds, and the pulls not
+// dispatched are deferred until the load of the node subsides.
+type LoadThrottle struct {
+	// CPUPercent is the CPU usage of a node, in percent of its CPU capacity, beyond
+	// which image pulls to the node are deferred. The threshold is disabled if zero
+	CPUPercent int
+	// NetworkBytesPerSecond is the network traffic of a node beyond which image pulls
+	// to the node are deferred. The threshold is disabled if zero
+	NetworkBytesPerSecond int64
+	// DiskIOBytesPerSecond is the disk IO of a node beyond which image pulls to the
+	// node are deferred. The threshold is disabled if zero
+	DiskIOBytesPerSecond int64
+}
+
+// Enabled returns true if any threshold is set
+func (t LoadThrottle) Enabled() bool {
+	return t.CPUPercent > 0 || t.NetworkBytesPerSecond > 0 || t.DiskIOBytesPerSecond > 0
+}
+
+// Validate returns an error if the thresholds are out of range
+func (t LoadThrottle) Validate() error {
+	if t.CPUPercent < 0 || t.CPUPercent > 100 {
+		return fmt.Errorf("CPU threshold must be between 0 and 100 percent")
+	}
+	if t.NetworkBytesPerSecond < 0 {
+		return fmt.Errorf("network threshold must not be negative")
+	}
+	if t.DiskIOBytesPerSecond < 0 {
+		return fmt.Errorf("disk IO threshold must not be negative")
+	}
+	return nil
+}
+
+// cachedNodeLoad is the load read from a node
+type cachedNodeLoad struct {
+	load   nodestats.NodeLoad
+	readAt time.Time
+}
+
+// nodeLoad returns the load of a node, read from the node unless it was read within
+// nodeLoadTTL
+func (m *ImageManager) nodeLoad(node string) (nodestats.NodeLoad, error) {
+	m.loadLock.Lock()
+	cached, ok := m.loads[node]
+	m.loadLock.Unlock()
+	if ok && time.Since(cached.readAt) < nodeLoadTTL {
+		return cached.load, nil
+	}
+	load, err := m.loadReader.NodeLoad(node)
+	if err != nil {
+		return nodestats.NodeLoad{}, err
+	}
+	m.loadLock.Lock()
+	m.loads[node] = cachedNodeLoad{load: load, readAt: time.Now()}
+	m.loadLock.Unlock()
+	return load, nil
+}
+
+// overloadedNode returns the thresholds exceeded by the load of the node of the work
+// request, or "" if none is. Nodes whose load can not be read are not throttled.
+func (m *ImageManager) overloadedNode(iwr ImageWorkRequest) string {
+	node := iwr.Node.Name
+	load, err := m.nodeLoad(node)
+	if err != nil {
+		glog.Warningf("Error reading load of node %s, image pulls not throttled: %v", node, err)
+		return ""
+	}
+	exceeded := []string{}
+	if m.loadThrottle.CPUPercent > 0 {
+		if capacity := iwr.Node.Status.Capacity.Cpu().MilliValue(); capacity > 0 {
+			percent := float64(load.CPUNanoCores) / float64(capacity*1000000) * 100
+			if percent > float64(m.loadThrottle.CPUPercent) {
+				exceeded = append(exceeded, fmt.Sprintf("CPU %.0f%% > %d%%", percent, m.loadThrottle.CPUPercent))
+			}
+		}
+	}
+	if m.loadThrottle.NetworkBytesPerSecond > 0 && load.NetworkBytesPerSecond > float64(m.loadThrottle.NetworkBytesPerSecond) {
+		exceeded = append(exceeded, fmt.Sprintf("network %.0f B/s > %d B/s", load.NetworkBytesPerSecond, m.loadThrottle.NetworkBytesPerSecond))
+	}
+	if m.loadThrottle.DiskIOBytesPerSecond > 0 && load.DiskIOBytesPerSecond > float64(m.loadThrottle.DiskIOBytesPerSecond) {
+		exceeded = append(exceeded, fmt.Sprintf("disk IO %.0f B/s > %d B/s", load.DiskIOBytesPerSecond, m.loadThrottle.DiskIOBytesPerSecond))
+	}
+	return strings.Join(exceeded, ", ")
+}
+
+// deferOverloadedNode records the image pull of the work request as deferred if the
+// load of its node exceeds a threshold of the load throttle, and returns true if so
+func (m *ImageManager) deferOverloadedNode(iwr ImageWorkRequest) bool {
+	if !m.loadThrottle.Enabled() || !features.Enabled(features.LoadThrottling) || m.loadReader == nil || iwr.WorkType == ImageCachePurge || iwr.Node == nil {
+		return false
+	}
+	exceeded := m.overloadedNode(iwr)
+	if exceeded == "" {
+		return false
+	}
+	m.lock.Lock()
+	m.imageworkstatus[names.SimpleNameGenerator.GenerateName(fakeJobPrefix)] = ImageWorkResult{
+		ImageWorkRequest: iwr,
+		Status:           ImageWorkResultStatusDeferred,
+		Reason:           fledgedv1alpha2.ImageCacheReasonNodeOverloaded,
+		Message:          fmt.Sprintf("Load of node %s exceeds the thresholds: %s", iwr.Node.Labels["kubernetes.io/hostname"], exceeded),
+	}
+	m.lock.Unlock()
+	glog.Infof("Job not created (node-overloaded:- %s --> %s, %s)", iwr.PullReference(), iwr.Node.Labels["kubernetes.io/hostname"], exceeded)
+	return true
+}
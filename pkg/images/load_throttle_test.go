@@ -0,0 +1,161 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+	"testing"
+
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/features"
+	"github.com/senthilrch/kube-fledged/pkg/nodestats"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeLoadReader returns the given load of every node, counting the reads
+type fakeLoadReader struct {
+	load  nodestats.NodeLoad
+	err   error
+	reads int
+}
+
+func (r *fakeLoadReader) NodeLoad(node string) (nodestats.NodeLoad, error) {
+	r.reads++
+	return r.load, r.err
+}
+
+func TestLoadThrottleValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		throttle  LoadThrottle
+		expectErr bool
+	}{
+		{name: "#1: Disabled", throttle: LoadThrottle{}},
+		{name: "#2: All thresholds", throttle: LoadThrottle{CPUPercent: 80, NetworkBytesPerSecond: 1 << 20, DiskIOBytesPerSecond: 1 << 20}},
+		{name: "#3: CPU threshold above 100 percent", throttle: LoadThrottle{CPUPercent: 101}, expectErr: true},
+		{name: "#4: Negative network threshold", throttle: LoadThrottle{NetworkBytesPerSecond: -1}, expectErr: true},
+	}
+	for _, test := range tests {
+		if err := test.throttle.Validate(); (err != nil) != test.expectErr {
+			t.Errorf("Test: %s failed: expectErr=%t, actual error=%v", test.name, test.expectErr, err)
+		}
+	}
+}
+
+func TestDeferOverloadedNode(t *testing.T) {
+	imageCache := &fledgedv1alpha2.ImageCache{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace}}
+	loadedNode := node
+	loadedNode.Name = "bar"
+	loadedNode.Status.Capacity = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")}
+	tests := []struct {
+		name          string
+		throttle      LoadThrottle
+		load          nodestats.NodeLoad
+		err           error
+		workType      WorkType
+		gateDisabled  bool
+		expectedDefer bool
+	}{
+		{
+			name:          "#1: CPU usage above threshold",
+			throttle:      LoadThrottle{CPUPercent: 80},
+			load:          nodestats.NodeLoad{CPUNanoCores: 3600000000},
+			workType:      ImageCacheCreate,
+			expectedDefer: true,
+		},
+		{
+			name:     "#2: CPU usage below threshold",
+			throttle: LoadThrottle{CPUPercent: 80},
+			load:     nodestats.NodeLoad{CPUNanoCores: 2000000000},
+			workType: ImageCacheCreate,
+		},
+		{
+			name:          "#3: Network traffic above threshold",
+			throttle:      LoadThrottle{NetworkBytesPerSecond: 1000},
+			load:          nodestats.NodeLoad{NetworkBytesPerSecond: 5000},
+			workType:      ImageCacheRefresh,
+			expectedDefer: true,
+		},
+		{
+			name:          "#4: Disk IO above threshold",
+			throttle:      LoadThrottle{DiskIOBytesPerSecond: 1000},
+			load:          nodestats.NodeLoad{DiskIOBytesPerSecond: 5000},
+			workType:      ImageCacheCreate,
+			expectedDefer: true,
+		},
+		{
+			name:     "#5: Purges are not throttled",
+			throttle: LoadThrottle{CPUPercent: 80},
+			load:     nodestats.NodeLoad{CPUNanoCores: 3600000000},
+			workType: ImageCachePurge,
+		},
+		{
+			name:     "#6: Load not readable - not throttled",
+			throttle: LoadThrottle{CPUPercent: 80},
+			err:      fmt.Errorf("node unreachable"),
+			workType: ImageCacheCreate,
+		},
+		{
+			name:     "#7: Load throttle disabled",
+			load:     nodestats.NodeLoad{CPUNanoCores: 3600000000},
+			workType: ImageCacheCreate,
+		},
+		{
+			name:         "#8: LoadThrottling feature gate disabled",
+			throttle:     LoadThrottle{CPUPercent: 80},
+			load:         nodestats.NodeLoad{CPUNanoCores: 3600000000},
+			workType:     ImageCacheCreate,
+			gateDisabled: true,
+		},
+	}
+	defer features.DefaultMutableFeatureGate.SetFromMap(map[string]bool{string(features.LoadThrottling): false})
+	for _, test := range tests {
+		if err := features.DefaultMutableFeatureGate.SetFromMap(map[string]bool{string(features.LoadThrottling): !test.gateDisabled}); err != nil {
+			t.Fatalf("Test: %s failed: error setting feature gate: %v", test.name, err)
+		}
+		imagemanager, _ := newTestImageManager(&fakeclientset.Clientset{}, "IfNotPresent", "sa-kube-fledged", false, "", false, "")
+		reader := &fakeLoadReader{load: test.load, err: test.err}
+		imagemanager.loadThrottle = test.throttle
+		imagemanager.loadReader = reader
+		iwr := ImageWorkRequest{Image: "foo", Node: &loadedNode, WorkType: test.workType, Imagecache: imageCache}
+
+		if deferred := imagemanager.deferOverloadedNode(iwr); deferred != test.expectedDefer {
+			t.Errorf("Test: %s failed: expectedDefer=%t, actualDefer=%t", test.name, test.expectedDefer, deferred)
+			continue
+		}
+		results := 0
+		for _, result := range imagemanager.imageworkstatus {
+			results++
+			if result.Status != ImageWorkResultStatusDeferred || result.Reason != fledgedv1alpha2.ImageCacheReasonNodeOverloaded {
+				t.Errorf("Test: %s failed: expected deferred result, actual %+v", test.name, result)
+			}
+		}
+		if expected := map[bool]int{true: 1, false: 0}[test.expectedDefer]; results != expected {
+			t.Errorf("Test: %s failed: expected %d results, actual %d", test.name, expected, results)
+		}
+		// The load of the node is reused for the next image pulls
+		if test.expectedDefer {
+			imagemanager.deferOverloadedNode(iwr)
+			if reader.reads != 1 {
+				t.Errorf("Test: %s failed: expected the load read once, actual %d reads", test.name, reader.reads)
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PullReference returns the reference the image of the work request is pulled from
+func (iwr ImageWorkRequest) PullReference() string {
+	if iwr.Source != "" {
+		return iwr.Source
+	}
+	return iwr.Image
+}
+
+// NodeReference returns the reference the image of the work request is stored as on the
+// node: its target reference if it is tagged as one, else the reference it is pulled from
+func (iwr ImageWorkRequest) NodeReference() string {
+	if iwr.TargetRef != "" {
+		return iwr.TargetRef
+	}
+	return iwr.PullReference()
+}
+
+// nextSource returns the fallback reference following the reference the image of the
+// work request is pulled from, or "" if there is none
+func (iwr ImageWorkRequest) nextSource() string {
+	if iwr.Fallbacks == nil {
+		return ""
+	}
+	fallbacks := *iwr.Fallbacks
+	if iwr.Source == "" {
+		if len(fallbacks) == 0 {
+			return ""
+		}
+		return fallbacks[0]
+	}
+	for i, fallback := range fallbacks {
+		if fallback == iwr.Source && i+1 < len(fallbacks) {
+			return fallbacks[i+1]
+		}
+	}
+	return ""
+}
+
+// closedCircuitSource returns the work request pulling its image from the first of its
+// references, from the one it is pulled from on, whose registry circuit is closed. The
+// work request is returned unchanged if the circuits of all of them are open, so that
+// its pull is deferred.
+func (m *ImageManager) closedCircuitSource(iwr ImageWorkRequest) ImageWorkRequest {
+	if iwr.WorkType == ImageCachePurge {
+		return iwr
+	}
+	candidate := iwr
+	for !m.RegistryCircuitOpenUntil(candidate.PullReference()).IsZero() {
+		source := candidate.nextSource()
+		if source == "" {
+			return iwr
+		}
+		candidate.Source = source
+	}
+	if candidate.Source != iwr.Source {
+		glog.Infof("Circuit of registry of %s open, pulling %s from %s", iwr.PullReference(), iwr.Image, candidate.Source)
+	}
+	return candidate
+}
+
+// fallBackPull switches the failed image pull of a job to the next fallback reference of
+// its image, and returns true if so. Only the failures of the registry of the reference,
+// e.g. an unreachable registry, fall back. The work item remains in progress: its job
+// is replaced with a job pulling the image from the fallback reference.
+func (m *ImageManager) fallBackPull(job string, iwres ImageWorkResult, pod *corev1.Pod) bool {
+	iwr := iwres.ImageWorkRequest
+	next := iwr.nextSource()
+	if iwr.WorkType == ImageCachePurge || next == "" ||
+		iwres.Reason == pullReasonManifestUnknown || iwres.Reason == podReasonInvalidImageName {
+		return false
+	}
+	m.lock.Lock()
+	if current, ok := m.imageworkstatus[job]; !ok || current.Status != ImageWorkResultStatusJobCreated || current.retrying {
+		m.lock.Unlock()
+		return ok && current.retrying
+	}
+	iwres.Status = ImageWorkResultStatusJobCreated
+	iwres.retrying = true
+	iwres.fallback = true
+	iwres.ImageWorkRequest.Source = next
+	m.imageworkstatus[job] = iwres
+	m.lock.Unlock()
+
+	glog.Infof("Job %s failed (pull: %s --> %s): %s, falling back to %s", job, iwr.PullReference(),
+		iwr.Node.Labels["kubernetes.io/hostname"], iwres.Reason, next)
+	// A pending pod would keep pulling the image with the backoff of kubelet
+	if (pod != nil && pod.Status.Phase == corev1.PodPending) || m.canDeleteJob {
+		deletePropagation := metav1.DeletePropagationBackground
+		if err := m.kubeclientset.BatchV1().Jobs(iwr.Imagecache.Namespace).
+			Delete(context.TODO(), job, metav1.DeleteOptions{PropagationPolicy: &deletePropagation}); err != nil {
+			glog.Errorf("Error deleting failed job %s: %v", job, err)
+		}
+	}
+	go m.recreatePullJob(job)
+	return true
+}
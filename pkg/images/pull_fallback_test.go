@@ -0,0 +1,187 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"testing"
+	"time"
+
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+)
+
+func TestNextSource(t *testing.T) {
+	fallbacks := []string{"mirror.internal/nginx:1.23", "nginx:1.23"}
+	tests := []struct {
+		name         string
+		iwr          ImageWorkRequest
+		expectedNext string
+	}{
+		{
+			name:         "#1: No fallback references",
+			iwr:          ImageWorkRequest{Image: "registry.internal/nginx:1.23"},
+			expectedNext: "",
+		},
+		{
+			name:         "#2: Pulled from the image - first fallback reference next",
+			iwr:          ImageWorkRequest{Image: "registry.internal/nginx:1.23", Fallbacks: &fallbacks},
+			expectedNext: "mirror.internal/nginx:1.23",
+		},
+		{
+			name:         "#3: Pulled from the first fallback reference - second next",
+			iwr:          ImageWorkRequest{Image: "registry.internal/nginx:1.23", Fallbacks: &fallbacks, Source: "mirror.internal/nginx:1.23"},
+			expectedNext: "nginx:1.23",
+		},
+		{
+			name:         "#4: Pulled from the last fallback reference",
+			iwr:          ImageWorkRequest{Image: "registry.internal/nginx:1.23", Fallbacks: &fallbacks, Source: "nginx:1.23"},
+			expectedNext: "",
+		},
+	}
+	for _, test := range tests {
+		if next := test.iwr.nextSource(); next != test.expectedNext {
+			t.Errorf("Test: %s failed: expectedNext=%q, actualNext=%q", test.name, test.expectedNext, next)
+		}
+	}
+}
+
+func TestClosedCircuitSource(t *testing.T) {
+	imageCache := &fledgedv1alpha2.ImageCache{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace}}
+	fallbacks := []string{"mirror.internal/nginx:1.23", "nginx:1.23"}
+	iwr := ImageWorkRequest{Image: "registry.internal/nginx:1.23", Fallbacks: &fallbacks, Node: &node,
+		WorkType: ImageCacheRefresh, Imagecache: imageCache}
+	imagemanager, _ := newTestImageManager(&fakeclientset.Clientset{}, "IfNotPresent", "sa-kube-fledged", false, "", false, "")
+	imagemanager.circuitBreaker = CircuitBreaker{Failures: 1, CoolDown: time.Hour}
+
+	if source := imagemanager.closedCircuitSource(iwr).PullReference(); source != iwr.Image {
+		t.Errorf("expected image pulled from %s while its circuit is closed, actual %s", iwr.Image, source)
+	}
+	imagemanager.recordRegistryOutcome(ImageWorkResult{ImageWorkRequest: iwr, Status: ImageWorkResultStatusFailed, Reason: "ErrImagePull"})
+	if source := imagemanager.closedCircuitSource(iwr).PullReference(); source != "mirror.internal/nginx:1.23" {
+		t.Errorf("expected image pulled from mirror.internal/nginx:1.23 while the circuit of its registry is open, actual %s", source)
+	}
+	mirror := iwr
+	mirror.Source = "mirror.internal/nginx:1.23"
+	public := iwr
+	public.Source = "nginx:1.23"
+	imagemanager.recordRegistryOutcome(ImageWorkResult{ImageWorkRequest: mirror, Status: ImageWorkResultStatusFailed, Reason: "ErrImagePull"})
+	imagemanager.recordRegistryOutcome(ImageWorkResult{ImageWorkRequest: public, Status: ImageWorkResultStatusFailed, Reason: "ErrImagePull"})
+	if source := imagemanager.closedCircuitSource(iwr).PullReference(); source != iwr.Image {
+		t.Errorf("expected work request unchanged while all the circuits are open, actual source %s", source)
+	}
+}
+
+func TestFallBackPull(t *testing.T) {
+	imageCache := &fledgedv1alpha2.ImageCache{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace}}
+	fallbacks := []string{"mirror.internal/nginx:1.23"}
+	pendingPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"job-name": "fakejob"}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: podReasonErrImagePull,
+					Message: "dial tcp 10.0.0.1:443: connect: connection refused"}}},
+			},
+		},
+	}
+	tests := []struct {
+		name             string
+		fallbacks        *[]string
+		source           string
+		reason           string
+		expectedFallback bool
+	}{
+		{
+			name:             "#1: Registry unreachable - falls back",
+			fallbacks:        &fallbacks,
+			reason:           podReasonErrImagePull,
+			expectedFallback: true,
+		},
+		{
+			name:      "#2: Unknown manifest - does not fall back",
+			fallbacks: &fallbacks,
+			reason:    pullReasonManifestUnknown,
+		},
+		{
+			name:   "#3: No fallback references - does not fall back",
+			reason: podReasonErrImagePull,
+		},
+		{
+			name:      "#4: Pulled from the last fallback reference - does not fall back",
+			fallbacks: &fallbacks,
+			source:    "mirror.internal/nginx:1.23",
+			reason:    podReasonErrImagePull,
+		},
+	}
+	for _, test := range tests {
+		created := make(chan *batchv1.Job, 1)
+		fakekubeclientset := &fakeclientset.Clientset{}
+		fakekubeclientset.AddReactor("create", "jobs", func(action core.Action) (bool, runtime.Object, error) {
+			job := action.(core.CreateAction).GetObject().(*batchv1.Job)
+			job.Name = "fallbackjob"
+			created <- job
+			return true, job, nil
+		})
+		fakekubeclientset.AddReactor("delete", "jobs", func(action core.Action) (bool, runtime.Object, error) {
+			return true, nil, nil
+		})
+		imagemanager, _ := newTestImageManager(fakekubeclientset, "IfNotPresent", "sa-kube-fledged", false, "", false, "")
+		iwres := ImageWorkResult{
+			Status: ImageWorkResultStatusJobCreated,
+			ImageWorkRequest: ImageWorkRequest{Image: "registry.internal/nginx:1.23", Fallbacks: test.fallbacks, Source: test.source,
+				Node: &node, WorkType: ImageCacheCreate, Imagecache: imageCache},
+		}
+		imagemanager.imageworkstatus["fakejob"] = iwres
+		iwres.Reason = test.reason
+
+		if fallback := imagemanager.fallBackPull("fakejob", iwres, pendingPod); fallback != test.expectedFallback {
+			t.Errorf("Test: %s failed: expectedFallback=%t, actualFallback=%t", test.name, test.expectedFallback, fallback)
+			continue
+		}
+		if !test.expectedFallback {
+			continue
+		}
+		select {
+		case job := <-created:
+			if image := job.Spec.Template.Spec.Containers[0].Image; image != "mirror.internal/nginx:1.23" {
+				t.Errorf("Test: %s failed: expected job pulling mirror.internal/nginx:1.23, actual %s", test.name, image)
+			}
+		case <-time.After(wait.ForeverTestTimeout):
+			t.Errorf("Test: %s failed: fallback job not created", test.name)
+			continue
+		}
+		// The result is replaced once the job is created
+		var result ImageWorkResult
+		var ok bool
+		wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+			imagemanager.lock.RLock()
+			defer imagemanager.lock.RUnlock()
+			result, ok = imagemanager.imageworkstatus["fallbackjob"]
+			return ok, nil
+		})
+		if !ok || result.Status != ImageWorkResultStatusJobCreated || result.retrying || result.Retries != 0 ||
+			result.ImageWorkRequest.PullReference() != "mirror.internal/nginx:1.23" {
+			t.Errorf("Test: %s failed: expected fallback pull in progress, actual result %+v", test.name, result)
+		}
+	}
+}
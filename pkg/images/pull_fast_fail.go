@@ -0,0 +1,117 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"strings"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Reasons of the image pull failures that retrying the pull does not resolve
+const (
+	pullReasonManifestUnknown = "ManifestUnknown"
+	pullReasonUnauthorized    = "Unauthorized"
+)
+
+// terminalPullMessages maps the messages of the terminal image pull failures reported
+// by the container runtimes to their reasons
+var terminalPullMessages = []struct {
+	substring string
+	reason    string
+}{
+	{"manifest unknown", pullReasonManifestUnknown},
+	{"not found", pullReasonManifestUnknown},
+	{"repository does not exist", pullReasonManifestUnknown},
+	{"unauthorized", pullReasonUnauthorized},
+	{"authentication required", pullReasonUnauthorized},
+	{"access denied", pullReasonUnauthorized},
+	{"403 forbidden", pullReasonUnauthorized},
+}
+
+// terminalPullReason returns the reason of a terminal image pull failure of the pod, and
+// its message. Empty is returned if the pod has no terminal image pull failure.
+func terminalPullReason(pod *corev1.Pod) (string, string) {
+	for _, problem := range podProblems(pod) {
+		switch problem {
+		case podReasonInvalidImageName:
+			return podReasonInvalidImageName, podProblemMessage(pod, problem)
+		case podReasonErrImagePull, podReasonImagePullBackOff:
+			message := podProblemMessage(pod, problem)
+			lower := strings.ToLower(message)
+			for _, t := range terminalPullMessages {
+				if strings.Contains(lower, t.substring) {
+					return t.reason, message
+				}
+			}
+		}
+	}
+	return "", ""
+}
+
+// failTerminalPull fails the work item of a job whose pod failed to pull its image for a
+// reason that retrying the pull does not resolve, e.g. an unknown manifest or missing
+// credentials, instead of waiting for the image pull deadline. The job is deleted so that
+// kubelet stops retrying the pull. A registry failure, e.g. missing credentials, falls
+// back to the next fallback reference of the image instead. It returns true if the work
+// item failed or fell back.
+func (m *ImageManager) failTerminalPull(oldPod, newPod *corev1.Pod) bool {
+	reason, message := terminalPullReason(newPod)
+	if reason == "" {
+		return false
+	}
+	if oldReason, _ := terminalPullReason(oldPod); oldReason == reason {
+		return false
+	}
+	job := podJobName(newPod)
+	m.lock.RLock()
+	iwres, ok := m.imageworkstatus[job]
+	m.lock.RUnlock()
+	if !ok || iwres.Status != ImageWorkResultStatusJobCreated || iwres.retrying || iwres.ImageWorkRequest.WorkType == ImageCachePurge {
+		return false
+	}
+	iwres.Status = ImageWorkResultStatusFailed
+	iwres.Reason = reason
+	iwres.Message = message
+	m.recordRegistryOutcome(iwres)
+	if m.fallBackPull(job, iwres, newPod) {
+		return true
+	}
+	m.lock.Lock()
+	// the result may have been recorded from the job in the meantime
+	if current, ok := m.imageworkstatus[job]; !ok || current.Status != ImageWorkResultStatusJobCreated || current.retrying {
+		m.lock.Unlock()
+		return false
+	}
+	m.imageworkstatus[job] = iwres
+	m.lock.Unlock()
+	m.notifyWorkDone()
+
+	glog.Infof("Job %s failed (pull: %s --> %s): %s: %s", job, iwres.ImageWorkRequest.Image,
+		iwres.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"], reason, message)
+	deletePropagation := metav1.DeletePropagationBackground
+	if err := m.kubeclientset.BatchV1().Jobs(newPod.Namespace).
+		Delete(context.TODO(), job, metav1.DeleteOptions{PropagationPolicy: &deletePropagation}); err != nil {
+		glog.Errorf("Error deleting failed job %s: %v", job, err)
+	}
+	m.recordProgress(iwres.ImageWorkRequest.Imagecache, true)
+	m.enforceFailureThreshold(iwres.ImageWorkRequest)
+	return true
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"testing"
+
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+)
+
+func waitingPod(reason, message string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "fakepod", Namespace: fledgedNameSpace, Labels: map[string]string{"job-name": "fakejob"}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason, Message: message}}},
+			},
+		},
+	}
+}
+
+func TestTerminalPullReason(t *testing.T) {
+	tests := []struct {
+		name           string
+		pod            *corev1.Pod
+		expectedReason string
+	}{
+		{
+			name:           "#1: Unknown manifest",
+			pod:            waitingPod(podReasonErrImagePull, `rpc error: code = NotFound desc = failed to pull and unpack image "docker.io/library/foo:v9": failed to resolve reference "docker.io/library/foo:v9": docker.io/library/foo:v9: not found`),
+			expectedReason: pullReasonManifestUnknown,
+		},
+		{
+			name:           "#2: Missing credentials",
+			pod:            waitingPod(podReasonImagePullBackOff, `Back-off pulling image "registry.internal/foo:v1": pull access denied, 401 Unauthorized`),
+			expectedReason: pullReasonUnauthorized,
+		},
+		{
+			name:           "#3: Invalid image name",
+			pod:            waitingPod(podReasonInvalidImageName, `Failed to apply default image tag "Foo": couldn't parse image reference`),
+			expectedReason: podReasonInvalidImageName,
+		},
+		{
+			name:           "#4: Transient image pull failure",
+			pod:            waitingPod(podReasonErrImagePull, `dial tcp: lookup registry.internal: i/o timeout`),
+			expectedReason: "",
+		},
+		{
+			name:           "#5: Image being pulled",
+			pod:            waitingPod("ContainerCreating", ""),
+			expectedReason: "",
+		},
+	}
+	for _, test := range tests {
+		if reason, _ := terminalPullReason(test.pod); reason != test.expectedReason {
+			t.Errorf("Test: %s failed: expected reason %q, actual %q", test.name, test.expectedReason, reason)
+		}
+	}
+}
+
+func TestFailTerminalPull(t *testing.T) {
+	imageCache := &fledgedv1alpha2.ImageCache{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace}}
+	deleted := 0
+	fakekubeclientset := &fakeclientset.Clientset{}
+	fakekubeclientset.AddReactor("delete", "jobs", func(action core.Action) (bool, runtime.Object, error) {
+		deleted++
+		return true, nil, nil
+	})
+	imagemanager, _ := newTestImageManager(fakekubeclientset, "IfNotPresent", "sa-kube-fledged", false, "", true, "")
+	imagemanager.imageworkstatus["fakejob"] = ImageWorkResult{
+		Status:           ImageWorkResultStatusJobCreated,
+		ImageWorkRequest: ImageWorkRequest{Image: "foo:v9", Node: &node, WorkType: ImageCacheCreate, Imagecache: imageCache},
+	}
+	pendingPod := waitingPod("ContainerCreating", "")
+	failedPod := waitingPod(podReasonErrImagePull, "manifest unknown: manifest unknown")
+
+	if !imagemanager.failTerminalPull(pendingPod, failedPod.DeepCopy()) {
+		t.Fatalf("expected work item to fail")
+	}
+	iwres := imagemanager.imageworkstatus["fakejob"]
+	if iwres.Status != ImageWorkResultStatusFailed || iwres.Reason != pullReasonManifestUnknown || iwres.Message != "manifest unknown: manifest unknown" {
+		t.Errorf("expected work item to fail with reason %s, actual result %+v", pullReasonManifestUnknown, iwres)
+	}
+	if deleted != 1 {
+		t.Errorf("expected failed job to be deleted, %d deletions", deleted)
+	}
+	if p := imagemanager.progress["kube-fledged/foo"]; p == nil || p.failed != 1 {
+		t.Errorf("expected failure to be recorded in progress, actual %+v", p)
+	}
+
+	// The back-off of the same failure, and the failure of the pod, are ignored
+	backOffPod := waitingPod(podReasonImagePullBackOff, "manifest unknown: manifest unknown")
+	if imagemanager.failTerminalPull(failedPod, backOffPod) {
+		t.Errorf("expected failed work item to be left unchanged")
+	}
+	failedPod.Status.Phase = corev1.PodFailed
+	imagemanager.handlePodStatusChange(failedPod)
+	if p := imagemanager.progress["kube-fledged/foo"]; p.failed != 1 {
+		t.Errorf("expected failure to be recorded once, actual %+v", p)
+	}
+}
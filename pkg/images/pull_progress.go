@@ -0,0 +1,88 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+)
+
+// ctrLayerLine matches a layer line of the progress printed by 'ctr images pull' e.g.
+// "layer-sha256:3f4ca6...:    downloading    |++++------| 12.0 MiB/30.0 MiB"
+var ctrLayerLine = regexp.MustCompile(`(layer-sha256:[0-9a-f]+):\s+(\w+)\s+\|[^|]*\|\s*(.*)$`)
+
+// ctrLayerBytes matches the bytes downloaded and the size of a layer e.g. "12.0 MiB/30.0 MiB"
+var ctrLayerBytes = regexp.MustCompile(`^([0-9.]+)\s*([KMGT]?i?B)/([0-9.]+)\s*([KMGT]?i?B)`)
+
+var byteUnits = map[string]float64{
+	"B":   1,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+}
+
+// parseCtrPullProgress parses the layer download progress of an image pull from the
+// log of a 'ctr images pull' command. ctr reprints the status of every layer at each
+// refresh, so the last status printed for a layer is its current status.
+func parseCtrPullProgress(log string) fledgedv1alpha2.ImagePullProgress {
+	progress := fledgedv1alpha2.ImagePullProgress{}
+	type layerStatus struct {
+		status string
+		pulled int64
+		total  int64
+	}
+	layers := map[string]layerStatus{}
+	order := []string{}
+	for _, line := range strings.Split(log, "\n") {
+		m := ctrLayerLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		ls := layerStatus{status: m[2]}
+		if b := ctrLayerBytes.FindStringSubmatch(strings.TrimSpace(m[3])); b != nil {
+			ls.pulled = parseBytes(b[1], b[2])
+			ls.total = parseBytes(b[3], b[4])
+		}
+		if _, ok := layers[m[1]]; !ok {
+			order = append(order, m[1])
+		}
+		layers[m[1]] = ls
+	}
+	for _, layer := range order {
+		ls := layers[layer]
+		progress.Layers++
+		if ls.status == "done" || ls.status == "exists" {
+			progress.LayersDone++
+			continue
+		}
+		progress.BytesPulled += ls.pulled
+		progress.BytesTotal += ls.total
+	}
+	return progress
+}
+
+func parseBytes(value, unit string) int64 {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(f * byteUnits[unit])
+}
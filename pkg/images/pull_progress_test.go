@@ -0,0 +1,72 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"reflect"
+	"testing"
+
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+)
+
+func TestParseCtrPullProgress(t *testing.T) {
+	tests := []struct {
+		name             string
+		log              string
+		expectedProgress fledgedv1alpha2.ImagePullProgress
+	}{
+		{
+			name:             "#1: No progress printed",
+			log:              "",
+			expectedProgress: fledgedv1alpha2.ImagePullProgress{},
+		},
+		{
+			name: "#2: Layers downloading",
+			log: `docker.io/library/foo:v1: resolved       |++++++++++++++++++++++++++++++++++++++|
+manifest-sha256:aaaa: done           |++++++++++++++++++++++++++++++++++++++|
+layer-sha256:1111:    done           |++++++++++++++++++++++++++++++++++++++|
+layer-sha256:2222:    downloading    |++++++++++----------------------------| 1.0 MiB/4.0 MiB
+layer-sha256:3333:    waiting        |--------------------------------------|
+elapsed: 1.2 s                       total:  1.0 Mi (853.3 KiB/s)`,
+			expectedProgress: fledgedv1alpha2.ImagePullProgress{
+				Layers:      3,
+				LayersDone:  1,
+				BytesPulled: 1 << 20,
+				BytesTotal:  4 << 20,
+			},
+		},
+		{
+			name: "#3: Last status of a layer is used",
+			log: `layer-sha256:1111:    downloading    |++++----| 1.0 MiB/2.0 MiB
+layer-sha256:2222:    downloading    |+-------| 512.0 KiB/4.0 MiB
+layer-sha256:1111:    done           |++++++++|
+layer-sha256:2222:    downloading    |++------| 1.0 MiB/4.0 MiB`,
+			expectedProgress: fledgedv1alpha2.ImagePullProgress{
+				Layers:      2,
+				LayersDone:  1,
+				BytesPulled: 1 << 20,
+				BytesTotal:  4 << 20,
+			},
+		},
+	}
+	for _, test := range tests {
+		progress := parseCtrPullProgress(test.log)
+		if !reflect.DeepEqual(progress, test.expectedProgress) {
+			t.Errorf("Test: %s failed: expectedProgress=%+v, actualProgress=%+v", test.name, test.expectedProgress, progress)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// PullRetry configures the retries of failed image pulls. Image pulls are retried with
+// exponential backoff, within the image pull deadline of the image cache action.
+type PullRetry struct {
+	// Retries is the maximum number of retries of a failed image pull. Failed image
+	// pulls are not retried if zero
+	Retries int
+	// BaseDelay is the delay before the first retry. It doubles with each retry
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between retries
+	MaxDelay time.Duration
+	// Jitter is the maximum fraction of the delay added to it at random, so that the
+	// retries of the pulls failed at the same time are spread
+	Jitter float64
+}
+
+// Validate returns an error if the retry parameters are inconsistent
+func (r PullRetry) Validate() error {
+	if r.Retries < 0 {
+		return fmt.Errorf("number of pull retries must not be negative")
+	}
+	if r.BaseDelay <= 0 {
+		return fmt.Errorf("pull retry base delay must be positive")
+	}
+	if r.MaxDelay < r.BaseDelay {
+		return fmt.Errorf("pull retry max delay must not be lower than the base delay")
+	}
+	if r.Jitter < 0 {
+		return fmt.Errorf("pull retry jitter must not be negative")
+	}
+	return nil
+}
+
+// Delay returns the delay before a retry, the first retry being 0
+func (r PullRetry) Delay(retry int) time.Duration {
+	delay := r.BaseDelay
+	for i := 0; i < retry && delay < r.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > r.MaxDelay {
+		delay = r.MaxDelay
+	}
+	if r.Jitter > 0 {
+		delay = wait.Jitter(delay, r.Jitter)
+	}
+	return delay
+}
+
+// retryPull schedules the retry of the failed image pull of a job, and returns true if
+// the pull is retried. The work item remains in progress until the retry: its job is
+// replaced with a new job once the delay elapsed.
+func (m *ImageManager) retryPull(job string, iwres ImageWorkResult, pod *corev1.Pod) bool {
+	if iwres.ImageWorkRequest.WorkType == ImageCachePurge || iwres.Retries >= m.pullRetry.Retries {
+		return false
+	}
+	m.lock.Lock()
+	// the pull may be retried already, e.g. when the pod fails after failing to pull
+	if current, ok := m.imageworkstatus[job]; !ok || current.Status != ImageWorkResultStatusJobCreated || current.retrying {
+		m.lock.Unlock()
+		return ok && current.retrying
+	}
+	// the reason of the failure is reported if the pull is not retried before the
+	// image pull deadline
+	iwres.Status = ImageWorkResultStatusJobCreated
+	iwres.retrying = true
+	m.imageworkstatus[job] = iwres
+	m.lock.Unlock()
+
+	delay := m.pullRetry.Delay(iwres.Retries)
+	glog.Infof("Job %s failed (pull: %s --> %s): %s, retrying in %s (retry %d of %d)", job, iwres.ImageWorkRequest.Image,
+		iwres.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"], iwres.Reason, delay, iwres.Retries+1, m.pullRetry.Retries)
+	// A pending pod would keep pulling the image with the backoff of kubelet
+	if pod.Status.Phase == corev1.PodPending || m.canDeleteJob {
+		deletePropagation := metav1.DeletePropagationBackground
+		if err := m.kubeclientset.BatchV1().Jobs(iwres.ImageWorkRequest.Imagecache.Namespace).
+			Delete(context.TODO(), job, metav1.DeleteOptions{PropagationPolicy: &deletePropagation}); err != nil {
+			glog.Errorf("Error deleting failed job %s: %v", job, err)
+		}
+	}
+	time.AfterFunc(delay, func() { m.recreatePullJob(job) })
+	return true
+}
+
+// handlePullBackOff falls back to the next fallback reference of the image, or retries
+// the image pull, of a job whose pod failed to pull its image, instead of leaving the
+// retries to the backoff of kubelet
+func (m *ImageManager) handlePullBackOff(oldPod, newPod *corev1.Pod) {
+	if !containsString(podProblems(newPod), podReasonErrImagePull) || containsString(podProblems(oldPod), podReasonErrImagePull) {
+		return
+	}
+	job := podJobName(newPod)
+	m.lock.RLock()
+	iwres, ok := m.imageworkstatus[job]
+	m.lock.RUnlock()
+	if !ok || iwres.Status != ImageWorkResultStatusJobCreated {
+		return
+	}
+	iwres.Reason = podReasonErrImagePull
+	iwres.Message = podProblemMessage(newPod, podReasonErrImagePull)
+	if m.fallBackPull(job, iwres, newPod) || m.pullRetry.Retries == 0 {
+		return
+	}
+	m.retryPull(job, iwres, newPod)
+}
+
+// recreatePullJob replaces the failed job of an image pull with a new job. The pull is
+// not retried if its image cache action completed or was aborted in the meantime, and
+// is deferred if the circuit of its registry opened in the meantime.
+func (m *ImageManager) recreatePullJob(job string) {
+	m.lock.RLock()
+	iwres, ok := m.imageworkstatus[job]
+	m.lock.RUnlock()
+	if !ok || !iwres.retrying || iwres.Status != ImageWorkResultStatusJobCreated || m.skipAborted(iwres.ImageWorkRequest) {
+		return
+	}
+	if openUntil := m.RegistryCircuitOpenUntil(iwres.ImageWorkRequest.PullReference()); !openUntil.IsZero() {
+		m.lock.Lock()
+		if current, ok := m.imageworkstatus[job]; ok && current.Status == ImageWorkResultStatusJobCreated && current.retrying {
+			m.imageworkstatus[job] = circuitDeferredResult(iwres.ImageWorkRequest, openUntil)
+		}
+		m.lock.Unlock()
+		m.notifyWorkDone()
+		glog.Infof("Job %s not retried (registry-circuit-open:- %s --> %s)", job, iwres.ImageWorkRequest.PullReference(),
+			iwres.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"])
+		return
+	}
+	newJob, err := m.pullImage(iwres.ImageWorkRequest)
+	m.lock.Lock()
+	if current, ok := m.imageworkstatus[job]; !ok || current.Status != ImageWorkResultStatusJobCreated {
+		m.lock.Unlock()
+		if err == nil {
+			deletePropagation := metav1.DeletePropagationBackground
+			if err := m.kubeclientset.BatchV1().Jobs(newJob.Namespace).
+				Delete(context.TODO(), newJob.Name, metav1.DeleteOptions{PropagationPolicy: &deletePropagation}); err != nil {
+				glog.Errorf("Error deleting job %s: %v", newJob.Name, err)
+			}
+		}
+		return
+	}
+	if err != nil {
+		iwres.Status = ImageWorkResultStatusFailed
+		iwres.Message = fmt.Sprintf("%s. Error creating job to retry image pull: %v", iwres.Message, err)
+		m.imageworkstatus[job] = iwres
+		m.lock.Unlock()
+		m.notifyWorkDone()
+		m.recordProgress(iwres.ImageWorkRequest.Imagecache, true)
+		m.enforceFailureThreshold(iwres.ImageWorkRequest)
+		return
+	}
+	delete(m.imageworkstatus, job)
+	fallback := iwres.fallback
+	if !fallback {
+		iwres.Retries++
+	}
+	iwres.retrying = false
+	iwres.fallback = false
+	iwres.Reason = ""
+	iwres.Message = ""
+	m.imageworkstatus[newJob.Name] = iwres
+	m.lock.Unlock()
+	if fallback {
+		glog.Infof("Job %s created (fallback pull:- %s --> %s)", newJob.Name, iwres.ImageWorkRequest.PullReference(),
+			iwres.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"])
+		return
+	}
+	glog.Infof("Job %s created (retry %d of pull:- %s --> %s)", newJob.Name, iwres.Retries, iwres.ImageWorkRequest.PullReference(),
+		iwres.ImageWorkRequest.Node.Labels["kubernetes.io/hostname"])
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"testing"
+	"time"
+
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+)
+
+func TestPullRetryDelay(t *testing.T) {
+	retry := PullRetry{Retries: 10, BaseDelay: time.Second * 10, MaxDelay: time.Minute}
+	tests := []struct {
+		name          string
+		retry         int
+		expectedDelay time.Duration
+	}{
+		{
+			name:          "#1: First retry",
+			retry:         0,
+			expectedDelay: time.Second * 10,
+		},
+		{
+			name:          "#2: Delay doubles with each retry",
+			retry:         2,
+			expectedDelay: time.Second * 40,
+		},
+		{
+			name:          "#3: Delay capped at the max delay",
+			retry:         5,
+			expectedDelay: time.Minute,
+		},
+	}
+	for _, test := range tests {
+		if delay := retry.Delay(test.retry); delay != test.expectedDelay {
+			t.Errorf("Test: %s failed: expected delay %s, actual %s", test.name, test.expectedDelay, delay)
+		}
+	}
+	retry.Jitter = 0.5
+	for i := 0; i < 10; i++ {
+		if delay := retry.Delay(0); delay < time.Second*10 || delay > time.Second*15 {
+			t.Errorf("expected jittered delay between 10s and 15s, actual %s", delay)
+		}
+	}
+}
+
+func TestPullRetryValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		retry     PullRetry
+		expectErr bool
+	}{
+		{
+			name:  "#1: Valid retry parameters",
+			retry: PullRetry{Retries: 3, BaseDelay: time.Second, MaxDelay: time.Minute, Jitter: 0.1},
+		},
+		{
+			name:      "#2: Max delay lower than base delay",
+			retry:     PullRetry{Retries: 3, BaseDelay: time.Minute, MaxDelay: time.Second},
+			expectErr: true,
+		},
+		{
+			name:      "#3: Negative jitter",
+			retry:     PullRetry{Retries: 3, BaseDelay: time.Second, MaxDelay: time.Minute, Jitter: -1},
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		if err := test.retry.Validate(); (err != nil) != test.expectErr {
+			t.Errorf("Test: %s failed: expectErr=%t, actual error=%v", test.name, test.expectErr, err)
+		}
+	}
+}
+
+func TestRetryPull(t *testing.T) {
+	imageCache := &fledgedv1alpha2.ImageCache{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace}}
+	failedPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"job-name": "fakejob"}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodFailed,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", Message: "pull failed"}}},
+			},
+		},
+	}
+	fakekubeclientset := &fakeclientset.Clientset{}
+	fakekubeclientset.AddReactor("create", "jobs", func(action core.Action) (bool, runtime.Object, error) {
+		job := action.(core.CreateAction).GetObject().(*batchv1.Job)
+		job.Name = "retryjob"
+		return true, job, nil
+	})
+	fakekubeclientset.AddReactor("delete", "jobs", func(action core.Action) (bool, runtime.Object, error) {
+		return true, nil, nil
+	})
+	imagemanager, _ := newTestImageManager(fakekubeclientset, "IfNotPresent", "sa-kube-fledged", false, "", true, "")
+	imagemanager.pullRetry = PullRetry{Retries: 1, BaseDelay: time.Hour, MaxDelay: time.Hour}
+	imagemanager.imageworkstatus["fakejob"] = ImageWorkResult{
+		Status:           ImageWorkResultStatusJobCreated,
+		ImageWorkRequest: ImageWorkRequest{Image: "foo:v1", Node: &node, WorkType: ImageCacheCreate, Imagecache: imageCache},
+	}
+
+	// The first failure is retried: the work item remains in progress
+	imagemanager.handlePodStatusChange(failedPod)
+	iwres := imagemanager.imageworkstatus["fakejob"]
+	if iwres.Status != ImageWorkResultStatusJobCreated || !iwres.retrying || iwres.Reason != "Error" {
+		t.Fatalf("expected pull to be retried, actual result %+v", iwres)
+	}
+	// A second failure of the same job does not retry again
+	if !imagemanager.retryPull("fakejob", iwres, failedPod) {
+		t.Errorf("expected pending retry to be reported")
+	}
+
+	imagemanager.recreatePullJob("fakejob")
+	if _, ok := imagemanager.imageworkstatus["fakejob"]; ok {
+		t.Errorf("expected failed job to be replaced")
+	}
+	iwres, ok := imagemanager.imageworkstatus["retryjob"]
+	if !ok || iwres.Retries != 1 || iwres.retrying || iwres.Status != ImageWorkResultStatusJobCreated {
+		t.Fatalf("expected retry job to be in progress, actual result %+v", iwres)
+	}
+
+	// The retries are exhausted
+	retryPod := failedPod.DeepCopy()
+	retryPod.Labels["job-name"] = "retryjob"
+	imagemanager.handlePodStatusChange(retryPod)
+	if iwres := imagemanager.imageworkstatus["retryjob"]; iwres.Status != ImageWorkResultStatusFailed {
+		t.Errorf("expected pull to fail once retries are exhausted, actual result %+v", iwres)
+	}
+}
+
+func TestUpdatePendingRetriedImageWorkResults(t *testing.T) {
+	imageCache := &fledgedv1alpha2.ImageCache{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace}}
+	imagemanager, _ := newTestImageManager(&fakeclientset.Clientset{}, "IfNotPresent", "sa-kube-fledged", false, "", false, "")
+	imagemanager.imageworkstatus["fakejob"] = ImageWorkResult{
+		Status:           ImageWorkResultStatusJobCreated,
+		Reason:           podReasonErrImagePull,
+		retrying:         true,
+		ImageWorkRequest: ImageWorkRequest{Image: "foo:v1", Node: &node, WorkType: ImageCacheCreate, Imagecache: imageCache},
+	}
+	if err := imagemanager.updatePendingImageWorkResults("foo"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if iwres := imagemanager.imageworkstatus["fakejob"]; iwres.Status != ImageWorkResultStatusFailed || iwres.Reason != podReasonErrImagePull {
+		t.Errorf("expected failure of last attempt to be reported, actual result %+v", iwres)
+	}
+}
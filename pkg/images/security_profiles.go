@@ -0,0 +1,125 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+	"strings"
+
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Values of the AppArmor profiles of containers
+const (
+	appArmorProfileRuntimeDefault  = "runtime/default"
+	appArmorProfileUnconfined      = "unconfined"
+	appArmorProfileLocalhostPrefix = "localhost/"
+)
+
+// Prefix of the key of the annotation setting the AppArmor profile of a container
+const appArmorAnnotationKeyPrefix = "container.apparmor.security.beta.kubernetes.io/"
+
+// restrictedRunAsUser is the user (nobody) the containers of the image pull jobs of the
+// kubelet pull strategy run as, whatever the user of their image
+const restrictedRunAsUser = int64(65534)
+
+// ParseSeccompProfile parses a seccomp profile given as RuntimeDefault, Unconfined or
+// Localhost/<profile>, the profile being relative to the seccomp profile root of kubelet
+func ParseSeccompProfile(s string) (*corev1.SeccompProfile, error) {
+	profileType, localhostProfile, hasProfile := strings.Cut(s, "/")
+	profile := &corev1.SeccompProfile{Type: corev1.SeccompProfileType(profileType)}
+	if hasProfile {
+		profile.LocalhostProfile = &localhostProfile
+	}
+	if err := ValidateSecurityProfiles(&fledgedv1alpha2.SecurityProfiles{SeccompProfile: profile}); err != nil {
+		return nil, fmt.Errorf("invalid seccomp profile %q: expected RuntimeDefault, Unconfined or Localhost/<profile>", s)
+	}
+	return profile, nil
+}
+
+// securityProfilesFor returns the security profiles of the image cache, each profile
+// not specified by the image cache being the one of the controller
+func (m *ImageManager) securityProfilesFor(imageCache *fledgedv1alpha2.ImageCache) fledgedv1alpha2.SecurityProfiles {
+	profiles := m.jobSecurityProfiles
+	if imageCache == nil || imageCache.Spec.SecurityProfiles == nil {
+		return profiles
+	}
+	if imageCache.Spec.SecurityProfiles.SeccompProfile != nil {
+		profiles.SeccompProfile = imageCache.Spec.SecurityProfiles.SeccompProfile
+	}
+	if imageCache.Spec.SecurityProfiles.AppArmorProfile != "" {
+		profiles.AppArmorProfile = imageCache.Spec.SecurityProfiles.AppArmorProfile
+	}
+	return profiles
+}
+
+// setSecurityProfiles sets the seccomp and AppArmor profiles of the pods of the job, if
+// the image cache or the controller specify them. The AppArmor profile is set for all
+// the containers of the pods, including the init containers.
+func (m *ImageManager) setSecurityProfiles(job *batchv1.Job, imageCache *fledgedv1alpha2.ImageCache) {
+	profiles := m.securityProfilesFor(imageCache)
+	podSpec := &job.Spec.Template.Spec
+	if profiles.SeccompProfile != nil {
+		if podSpec.SecurityContext == nil {
+			podSpec.SecurityContext = &corev1.PodSecurityContext{}
+		}
+		podSpec.SecurityContext.SeccompProfile = profiles.SeccompProfile.DeepCopy()
+	}
+	// AppArmor is not supported on windows nodes
+	if profiles.AppArmorProfile == "" || (podSpec.OS != nil && podSpec.OS.Name == corev1.Windows) {
+		return
+	}
+	if job.Spec.Template.Annotations == nil {
+		job.Spec.Template.Annotations = map[string]string{}
+	}
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for _, container := range containers {
+			job.Spec.Template.Annotations[appArmorAnnotationKeyPrefix+container.Name] = profiles.AppArmorProfile
+		}
+	}
+}
+
+// SetRestrictedSecurityContext makes the pods of an image pull job of the kubelet pull
+// strategy, or of another job not needing access to the node, satisfy the "restricted" Pod Security Standard: the containers run as a
+// non-root user, without privilege escalation and capabilities, with the seccomp
+// profile of the container runtime. The pull strategies and the jobs needing the CRI
+// socket or the artifact store of the node mount host paths and run as root, which the
+// "baseline" and "restricted" standards do not allow. Windows pods are left as is.
+func SetRestrictedSecurityContext(podSpec *corev1.PodSpec) {
+	if podSpec.OS != nil && podSpec.OS.Name == corev1.Windows {
+		return
+	}
+	runAsNonRoot := true
+	runAsUser := restrictedRunAsUser
+	allowPrivilegeEscalation := false
+	podSpec.SecurityContext = &corev1.PodSecurityContext{
+		RunAsNonRoot:   &runAsNonRoot,
+		RunAsUser:      &runAsUser,
+		RunAsGroup:     &runAsUser,
+		SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for i := range containers {
+			containers[i].SecurityContext = &corev1.SecurityContext{
+				AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+				Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+			}
+		}
+	}
+}
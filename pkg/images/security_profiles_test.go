@@ -0,0 +1,184 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"reflect"
+	"testing"
+
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+)
+
+func TestParseSeccompProfile(t *testing.T) {
+	localhostProfile := "profiles/puller.json"
+	tests := []struct {
+		name            string
+		profile         string
+		expectedProfile *corev1.SeccompProfile
+		expectErr       bool
+	}{
+		{
+			name:            "#1: Runtime default profile",
+			profile:         "RuntimeDefault",
+			expectedProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		},
+		{
+			name:            "#2: Localhost profile",
+			profile:         "Localhost/profiles/puller.json",
+			expectedProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeLocalhost, LocalhostProfile: &localhostProfile},
+		},
+		{
+			name:      "#3: Localhost profile without profile",
+			profile:   "Localhost",
+			expectErr: true,
+		},
+		{
+			name:      "#4: Unsupported profile",
+			profile:   "docker/default",
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		profile, err := ParseSeccompProfile(test.profile)
+		if (err != nil) != test.expectErr {
+			t.Errorf("Test: %s failed: expectErr=%t, actual error=%v", test.name, test.expectErr, err)
+			continue
+		}
+		if !reflect.DeepEqual(profile, test.expectedProfile) {
+			t.Errorf("Test: %s failed: expected profile %+v, actual %+v", test.name, test.expectedProfile, profile)
+		}
+	}
+}
+
+func TestSetSecurityProfiles(t *testing.T) {
+	runtimeDefault := &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}
+	unconfined := &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined}
+	tests := []struct {
+		name                string
+		action              string
+		defaults            fledgedv1alpha2.SecurityProfiles
+		override            *fledgedv1alpha2.SecurityProfiles
+		expectedSeccomp     *corev1.SeccompProfile
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:            "#1: Pull job - no profiles, restricted by default",
+			action:          "pullimage",
+			expectedSeccomp: runtimeDefault,
+		},
+		{
+			name:            "#2: Pull job - profiles of the controller",
+			action:          "pullimage",
+			defaults:        fledgedv1alpha2.SecurityProfiles{SeccompProfile: runtimeDefault, AppArmorProfile: "runtime/default"},
+			expectedSeccomp: runtimeDefault,
+			expectedAnnotations: map[string]string{
+				appArmorAnnotationKeyPrefix + "busybox":     "runtime/default",
+				appArmorAnnotationKeyPrefix + "imagepuller": "runtime/default",
+			},
+		},
+		{
+			name:            "#3: Pull job - profiles overridden by the image cache",
+			action:          "pullimage",
+			defaults:        fledgedv1alpha2.SecurityProfiles{SeccompProfile: runtimeDefault, AppArmorProfile: "runtime/default"},
+			override:        &fledgedv1alpha2.SecurityProfiles{AppArmorProfile: "localhost/kubefledged-puller"},
+			expectedSeccomp: runtimeDefault,
+			expectedAnnotations: map[string]string{
+				appArmorAnnotationKeyPrefix + "busybox":     "localhost/kubefledged-puller",
+				appArmorAnnotationKeyPrefix + "imagepuller": "localhost/kubefledged-puller",
+			},
+		},
+		{
+			name:            "#4: Delete job - seccomp profile of the image cache",
+			action:          "deleteimage",
+			override:        &fledgedv1alpha2.SecurityProfiles{SeccompProfile: unconfined},
+			expectedSeccomp: unconfined,
+		},
+	}
+	for _, test := range tests {
+		imageCache := &fledgedv1alpha2.ImageCache{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace},
+			Spec:       fledgedv1alpha2.ImageCacheSpec{SecurityProfiles: test.override},
+		}
+		var created *batchv1.Job
+		fakekubeclientset := &fakeclientset.Clientset{}
+		fakekubeclientset.AddReactor("create", "jobs", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+			created = action.(core.CreateAction).GetObject().(*batchv1.Job)
+			return true, created, nil
+		})
+		imagemanager, _ := newTestImageManager(fakekubeclientset, "IfNotPresent", "sa-kube-fledged", false, "", false, "")
+		imagemanager.jobSecurityProfiles = test.defaults
+		iwr := ImageWorkRequest{Image: "foo", Node: &node, WorkType: ImageCacheCreate, Imagecache: imageCache}
+		var err error
+		if test.action == "pullimage" {
+			_, err = imagemanager.pullImage(iwr)
+		} else {
+			iwr.WorkType = ImageCachePurge
+			_, err = imagemanager.deleteImage(iwr)
+		}
+		if err != nil {
+			t.Errorf("Test: %s failed: %v", test.name, err)
+			continue
+		}
+		var seccomp *corev1.SeccompProfile
+		if securityContext := created.Spec.Template.Spec.SecurityContext; securityContext != nil {
+			seccomp = securityContext.SeccompProfile
+		}
+		if !reflect.DeepEqual(seccomp, test.expectedSeccomp) {
+			t.Errorf("Test: %s failed: expected seccomp profile %+v, actual %+v", test.name, test.expectedSeccomp, seccomp)
+		}
+		if !reflect.DeepEqual(created.Spec.Template.Annotations, test.expectedAnnotations) {
+			t.Errorf("Test: %s failed: expected annotations %v, actual %v", test.name, test.expectedAnnotations, created.Spec.Template.Annotations)
+		}
+	}
+}
+
+func TestSetRestrictedSecurityContext(t *testing.T) {
+	imagecache := &fledgedv1alpha2.ImageCache{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: fledgedNameSpace}}
+	job, err := newImagePullJob(imagecache, "foo", &node, "IfNotPresent", "busybox", "", "")
+	if err != nil {
+		t.Fatalf("newImagePullJob() failed: %v", err)
+	}
+	podSpec := job.Spec.Template.Spec
+	securityContext := podSpec.SecurityContext
+	if securityContext == nil || securityContext.RunAsNonRoot == nil || !*securityContext.RunAsNonRoot ||
+		securityContext.RunAsUser == nil || *securityContext.RunAsUser == 0 ||
+		!reflect.DeepEqual(securityContext.SeccompProfile, &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}) {
+		t.Errorf("expected restricted pod security context, actual %+v", securityContext)
+	}
+	for _, container := range append(podSpec.InitContainers, podSpec.Containers...) {
+		if container.SecurityContext == nil || container.SecurityContext.AllowPrivilegeEscalation == nil ||
+			*container.SecurityContext.AllowPrivilegeEscalation || container.SecurityContext.Capabilities == nil ||
+			!reflect.DeepEqual(container.SecurityContext.Capabilities.Drop, []corev1.Capability{"ALL"}) {
+			t.Errorf("expected restricted security context of container %s, actual %+v", container.Name, container.SecurityContext)
+		}
+	}
+
+	// Linux security settings are not allowed in windows pods
+	job, err = newImagePullJob(imagecache, "foo", &windowsNode, "IfNotPresent", "busybox", "", "")
+	if err != nil {
+		t.Fatalf("newImagePullJob() failed: %v", err)
+	}
+	if securityContext := job.Spec.Template.Spec.SecurityContext; securityContext != nil {
+		t.Errorf("expected no pod security context on windows, actual %+v", securityContext)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"regexp"
+
+	"github.com/golang/glog"
+)
+
+// SnapshotterLabelKey is the label of the nodes naming the containerd snapshotter the
+// images are unpacked into on the node, e.g. the snapshotter of the CRI plugin of
+// containerd when it is not the default one
+const SnapshotterLabelKey = "kubefledged.io/snapshotter"
+
+// snapshotterPattern matches the names of containerd snapshotters e.g. overlayfs, zfs,
+// nydus or stargz
+var snapshotterPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9._-]*[a-z0-9])?$`)
+
+// ValidSnapshotter returns true if the snapshotter is a valid name of a containerd
+// snapshotter
+func ValidSnapshotter(snapshotter string) bool {
+	return snapshotterPattern.MatchString(snapshotter)
+}
+
+// snapshotterFor returns the containerd snapshotter the image of the work request is
+// unpacked into: the snapshotter of its image cache, else the snapshotter named by the
+// label of its node. Empty means the default snapshotter of containerd.
+func snapshotterFor(iwr ImageWorkRequest) string {
+	if iwr.Imagecache != nil && iwr.Imagecache.Spec.Snapshotter != "" {
+		return iwr.Imagecache.Spec.Snapshotter
+	}
+	if iwr.Node == nil {
+		return ""
+	}
+	snapshotter := iwr.Node.Labels[SnapshotterLabelKey]
+	if snapshotter != "" && !ValidSnapshotter(snapshotter) {
+		glog.Warningf("Invalid snapshotter %q of node %s ignored", snapshotter, iwr.Node.Name)
+		return ""
+	}
+	return snapshotter
+}
+
+// snapshotterArgs returns the arguments of the ctr client selecting the snapshotter the
+// images are unpacked into, if any
+func snapshotterArgs(snapshotter string) string {
+	if snapshotter == "" {
+		return ""
+	}
+	return "--snapshotter " + snapshotter + " "
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"testing"
+
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSnapshotterFor(t *testing.T) {
+	labelledNode := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "gpu-1",
+		Labels: map[string]string{SnapshotterLabelKey: "nydus"}}}
+	invalidNode := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "gpu-2",
+		Labels: map[string]string{SnapshotterLabelKey: "-nydus"}}}
+	tests := []struct {
+		name                string
+		snapshotter         string
+		node                *corev1.Node
+		expectedSnapshotter string
+	}{
+		{
+			name: "#1: Default snapshotter",
+			node: &node,
+		},
+		{
+			name:                "#2: Snapshotter of the node",
+			node:                labelledNode,
+			expectedSnapshotter: "nydus",
+		},
+		{
+			name:                "#3: Snapshotter of the image cache overrides the node",
+			snapshotter:         "stargz",
+			node:                labelledNode,
+			expectedSnapshotter: "stargz",
+		},
+		{
+			name: "#4: Invalid snapshotter of the node ignored",
+			node: invalidNode,
+		},
+	}
+	for _, test := range tests {
+		iwr := ImageWorkRequest{
+			Node:       test.node,
+			Imagecache: &fledgedv1alpha2.ImageCache{Spec: fledgedv1alpha2.ImageCacheSpec{Snapshotter: test.snapshotter}},
+		}
+		if snapshotter := snapshotterFor(iwr); snapshotter != test.expectedSnapshotter {
+			t.Errorf("Test: %s failed: expectedSnapshotter=%q, actualSnapshotter=%q", test.name, test.expectedSnapshotter, snapshotter)
+		}
+	}
+}
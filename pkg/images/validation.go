@@ -0,0 +1,522 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/docker/distribution/reference"
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/registry"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ValidateImageLists validates the image lists of a cache spec. Each image list must
+// have at least one image, OCI artifact or tracked repository, every image and artifact
+// must be a valid reference and an image or artifact must not be listed twice within an
+// image list. Platforms, image pull policies and fallback references may only be
+// specified for images of the image list.
+func ValidateImageLists(cacheSpec []fledgedv1alpha2.CacheSpecImages) error {
+	for _, i := range cacheSpec {
+		if len(i.Images) == 0 && len(i.Artifacts) == 0 && len(i.TrackedRepositories) == 0 && len(i.Catalogs) == 0 && len(i.Providers) == 0 {
+			return fmt.Errorf("No images specified within image list")
+		}
+		for _, t := range i.TrackedRepositories {
+			if err := ValidateTrackedRepository(t); err != nil {
+				return err
+			}
+		}
+		for _, c := range i.Catalogs {
+			if err := ValidateRegistryCatalog(c); err != nil {
+				return err
+			}
+		}
+		for _, p := range i.Providers {
+			if err := ValidateImageListProvider(p); err != nil {
+				return err
+			}
+		}
+		if i.RefreshInterval != nil && i.RefreshInterval.Duration < 0 {
+			return fmt.Errorf("Negative refresh interval within image list: %s", i.RefreshInterval.Duration)
+		}
+		for m := range i.Images {
+			if err := ValidateImageReference(i.Images[m]); err != nil {
+				return err
+			}
+			for p := 0; p < m; p++ {
+				if i.Images[p] == i.Images[m] {
+					return fmt.Errorf("Duplicate image names within image list: %s", i.Images[m])
+				}
+			}
+		}
+		for m := range i.Artifacts {
+			if err := ValidateImageReference(i.Artifacts[m]); err != nil {
+				return err
+			}
+			for p := 0; p < m; p++ {
+				if i.Artifacts[p] == i.Artifacts[m] {
+					return fmt.Errorf("Duplicate artifact names within image list: %s", i.Artifacts[m])
+				}
+			}
+		}
+		for image, platform := range i.Platforms {
+			if !containsString(i.Images, image) {
+				return fmt.Errorf("Platform specified for image %s, which is not in the image list", image)
+			}
+			if err := ValidatePlatform(platform); err != nil {
+				return err
+			}
+		}
+		for image, pullPolicy := range i.ImagePullPolicies {
+			if !containsString(i.Images, image) {
+				return fmt.Errorf("Image pull policy specified for image %s, which is not in the image list", image)
+			}
+			if err := ValidateImagePullPolicy(pullPolicy); err != nil {
+				return err
+			}
+		}
+		for image, fallbacks := range i.Fallbacks {
+			if !containsString(i.Images, image) {
+				return fmt.Errorf("Fallback references specified for image %s, which is not in the image list", image)
+			}
+			for m, fallback := range fallbacks {
+				if err := ValidateImageReference(fallback); err != nil {
+					return err
+				}
+				if fallback == image || containsString(fallbacks[:m], fallback) {
+					return fmt.Errorf("Duplicate fallback reference %s of image %s", fallback, image)
+				}
+			}
+		}
+		for image, targetRef := range i.TargetRefs {
+			if !containsString(i.Images, image) {
+				return fmt.Errorf("Target reference specified for image %s, which is not in the image list", image)
+			}
+			if err := ValidateImageReference(targetRef); err != nil {
+				return err
+			}
+			if targetRef == image {
+				return fmt.Errorf("Target reference of image %s is the image itself", image)
+			}
+		}
+		for image, displayTag := range i.DisplayTags {
+			if !containsString(i.Images, image) {
+				return fmt.Errorf("Display tag specified for image %s, which is not in the image list", image)
+			}
+			if err := ValidateDisplayTag(image, displayTag); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateTrackedRepository checks that the repository of a tracked repository is a
+// valid repository name without a tag or digest, and that its tag constraint is a valid
+// semantic version constraint.
+func ValidateTrackedRepository(t fledgedv1alpha2.TrackedRepository) error {
+	named, err := reference.ParseNormalizedNamed(t.Repository)
+	if err != nil {
+		return fmt.Errorf("Invalid tracked repository %q: %v", t.Repository, err)
+	}
+	if !reference.IsNameOnly(named) {
+		return fmt.Errorf("Invalid tracked repository %q: tags and digests are not allowed", t.Repository)
+	}
+	if _, err := semver.NewConstraint(t.TagConstraint); err != nil {
+		return fmt.Errorf("Invalid tag constraint %q of tracked repository %s: %v", t.TagConstraint, t.Repository, err)
+	}
+	if t.MaxTags < 0 {
+		return fmt.Errorf("Invalid maxTags %d of tracked repository %s: must not be negative", t.MaxTags, t.Repository)
+	}
+	return nil
+}
+
+// ValidateRegistryCatalog checks that a registry catalog has a registry followed by a
+// valid repository glob, and a valid tag glob.
+func ValidateRegistryCatalog(c fledgedv1alpha2.RegistryCatalog) error {
+	if _, _, err := registry.SplitRepositoryGlob(c.Repositories); err != nil {
+		return fmt.Errorf("Invalid catalog: %v", err)
+	}
+	if _, err := path.Match(c.Tags, ""); err != nil || c.Tags == "" {
+		return fmt.Errorf("Invalid tag glob %q of catalog %s", c.Tags, c.Repositories)
+	}
+	return nil
+}
+
+// ValidateImageListProvider checks that an image list provider is either a ConfigMap,
+// workloads, a plugin, the system components or a Helm release, and that it is fully
+// specified.
+func ValidateImageListProvider(p fledgedv1alpha2.ImageListProviderSpec) error {
+	kinds := 0
+	if p.ConfigMap != nil {
+		kinds++
+		if p.ConfigMap.Name == "" || p.ConfigMap.Key == "" {
+			return fmt.Errorf("Invalid ConfigMap image list provider: name and key must not be empty")
+		}
+	}
+	if p.Workloads != nil {
+		kinds++
+		if _, err := metav1.LabelSelectorAsSelector(p.Workloads.Selector); err != nil {
+			return fmt.Errorf("Invalid selector of workloads image list provider: %v", err)
+		}
+	}
+	if p.Plugin != nil {
+		kinds++
+		if p.Plugin.Address == "" {
+			return fmt.Errorf("Invalid plugin image list provider: address must not be empty")
+		}
+	}
+	if p.System != nil {
+		kinds++
+		if err := validateSystemImageList(p.System); err != nil {
+			return err
+		}
+	}
+	if p.HelmRelease != nil {
+		kinds++
+		if p.HelmRelease.Name == "" {
+			return fmt.Errorf("Invalid Helm release image list provider: name must not be empty")
+		}
+	}
+	if p.ImageList != nil {
+		kinds++
+		if p.ImageList.Name == "" {
+			return fmt.Errorf("Invalid ImageList image list provider: name must not be empty")
+		}
+	}
+	if p.ImageStream != nil {
+		kinds++
+		if p.ImageStream.Name == "" {
+			return fmt.Errorf("Invalid ImageStream image list provider: name must not be empty")
+		}
+		for _, tag := range p.ImageStream.Tags {
+			if tag == "" {
+				return fmt.Errorf("Invalid ImageStream image list provider: tags must not be empty")
+			}
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("Invalid image list provider: exactly one of configMap, workloads, plugin, system, helmRelease, imageList and imageStream must be specified")
+	}
+	return nil
+}
+
+// kubernetesVersionPattern matches a Kubernetes release version e.g. v1.28.2
+var kubernetesVersionPattern = regexp.MustCompile(`^v[0-9]+\.[0-9]+\.[0-9]+([-+][0-9A-Za-z.-]+)?$`)
+
+// validateSystemImageList checks the namespaces, the Kubernetes version and the pause
+// image of a system image list provider
+func validateSystemImageList(s *fledgedv1alpha2.SystemImageList) error {
+	for _, namespace := range s.Namespaces {
+		if namespace == "" {
+			return fmt.Errorf("Invalid system image list provider: namespaces must not be empty")
+		}
+	}
+	if s.KubernetesVersion != "" && !kubernetesVersionPattern.MatchString(s.KubernetesVersion) {
+		return fmt.Errorf("Invalid Kubernetes version %q of system image list provider: expected e.g. v1.28.2", s.KubernetesVersion)
+	}
+	if s.PauseImage != "" {
+		if err := ValidateImageReference(s.PauseImage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// platformPattern matches a platform of the form os/arch[/variant] e.g. linux/arm64/v8
+var platformPattern = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9_]+(/[a-z0-9]+)?$`)
+
+// ValidatePlatform checks that a platform overriding the platform of the nodes for an
+// image is of the form os/arch[/variant].
+func ValidatePlatform(platform string) error {
+	if !platformPattern.MatchString(platform) {
+		return fmt.Errorf("Invalid platform %q: expected os/arch[/variant] e.g. linux/arm64", platform)
+	}
+	return nil
+}
+
+// ValidateImageReference parses an image with the distribution reference parser, and
+// returns an error naming the image if it is not a valid image reference.
+func ValidateImageReference(image string) error {
+	if _, err := reference.ParseNormalizedNamed(image); err != nil {
+		return fmt.Errorf("Invalid image reference %q: %v", image, err)
+	}
+	return nil
+}
+
+// ValidateDisplayTag checks that an image with a display tag is referenced by digest
+// only, and that the display tag is a valid tag.
+func ValidateDisplayTag(image string, displayTag string) error {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return fmt.Errorf("Invalid image reference %q: %v", image, err)
+	}
+	if _, tagged := named.(reference.Tagged); tagged || !DigestPinned(image) {
+		return fmt.Errorf("Display tag specified for image %s, which is not referenced by digest only", image)
+	}
+	if _, err := reference.WithTag(reference.TrimNamed(named), displayTag); err != nil {
+		return fmt.Errorf("Invalid display tag %q of image %s: %v", displayTag, image, err)
+	}
+	return nil
+}
+
+// ValidateTTL checks that the time-to-live of an image cache, if specified, is positive.
+func ValidateTTL(ttl *metav1.Duration) error {
+	if ttl != nil && ttl.Duration <= 0 {
+		return fmt.Errorf("Invalid ttl %s: must be positive", ttl.Duration)
+	}
+	return nil
+}
+
+// ValidateUnusedImagePurge checks that the unused image purge policy of an image cache,
+// if specified, has a positive duration.
+func ValidateUnusedImagePurge(purge *fledgedv1alpha2.UnusedImagePurge) error {
+	if purge != nil && purge.After.Duration <= 0 {
+		return fmt.Errorf("Invalid unusedImagePurge.after %s: must be positive", purge.After.Duration)
+	}
+	return nil
+}
+
+// ValidateWarmStandby checks that the warm standby nodes of an image cache, if
+// specified, are selected by a node selector. An empty node selector would make every
+// node of the cluster a warm standby node.
+func ValidateWarmStandby(warmStandby *fledgedv1alpha2.WarmStandby) error {
+	if warmStandby == nil {
+		return nil
+	}
+	if len(warmStandby.NodeSelector) == 0 {
+		return fmt.Errorf("Invalid warmStandby: nodeSelector must not be empty")
+	}
+	if warmStandby.Nodes < 0 {
+		return fmt.Errorf("Invalid warmStandby.nodes %d: must not be negative", warmStandby.Nodes)
+	}
+	return nil
+}
+
+// ValidateRollout checks that the rollout of an image cache, if specified, is of a
+// supported type and that its topology key is a valid label key.
+func ValidateRollout(rollout *fledgedv1alpha2.RolloutStrategy) error {
+	if rollout == nil {
+		return nil
+	}
+	if rollout.Type != fledgedv1alpha2.RolloutTypeTopology {
+		return fmt.Errorf("Unsupported rollout type %q: supported value is %q", rollout.Type, fledgedv1alpha2.RolloutTypeTopology)
+	}
+	if rollout.TopologyKey != "" {
+		if errs := validation.IsQualifiedName(rollout.TopologyKey); len(errs) > 0 {
+			return fmt.Errorf("Invalid rollout.topologyKey %q: %s", rollout.TopologyKey, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// ValidateSnapshotter checks that the snapshotter of an image cache, if specified, is a
+// valid name of a containerd snapshotter.
+func ValidateSnapshotter(snapshotter string) error {
+	if snapshotter != "" && !ValidSnapshotter(snapshotter) {
+		return fmt.Errorf("Invalid snapshotter %q: expected e.g. overlayfs, zfs, nydus or stargz", snapshotter)
+	}
+	return nil
+}
+
+// ValidateFailureThreshold checks that the failure threshold of an image cache, if
+// specified, is a non-negative number or a percentage between 0% and 100%.
+func ValidateFailureThreshold(threshold *intstr.IntOrString) error {
+	if threshold == nil {
+		return nil
+	}
+	if threshold.Type == intstr.String {
+		percent, err := strconv.Atoi(strings.TrimSuffix(threshold.StrVal, "%"))
+		if err != nil || !strings.HasSuffix(threshold.StrVal, "%") || percent < 0 || percent > 100 {
+			return fmt.Errorf("Invalid failureThreshold %q: must be a number or a percentage between 0%% and 100%%", threshold.StrVal)
+		}
+		return nil
+	}
+	if threshold.IntVal < 0 {
+		return fmt.Errorf("Invalid failureThreshold %d: must not be negative", threshold.IntVal)
+	}
+	return nil
+}
+
+// ValidateMaxBytesPerNode checks that the cap on the size of the images of an image
+// cache on a node, if specified, is positive.
+func ValidateMaxBytesPerNode(maxBytes *resource.Quantity) error {
+	if maxBytes != nil && maxBytes.Sign() <= 0 {
+		return fmt.Errorf("Invalid maxBytesPerNode %s: must be positive", maxBytes.String())
+	}
+	return nil
+}
+
+// ValidateHooks checks that the hooks of an image cache, if specified, are named
+// uniquely within their phase and are either a command run in a pod or an HTTP call.
+func ValidateHooks(hooks *fledgedv1alpha2.ImageCacheHooks) error {
+	if hooks == nil {
+		return nil
+	}
+	for phase, list := range map[string][]fledgedv1alpha2.ImageCacheHook{"prePull": hooks.PrePull, "postPull": hooks.PostPull} {
+		names := map[string]bool{}
+		for _, hook := range list {
+			if hook.Name == "" {
+				return fmt.Errorf("Invalid %s hook: name must not be empty", phase)
+			}
+			if names[hook.Name] {
+				return fmt.Errorf("Duplicate %s hook name: %s", phase, hook.Name)
+			}
+			names[hook.Name] = true
+			if err := validateHook(hook); err != nil {
+				return fmt.Errorf("Invalid %s hook %s: %v", phase, hook.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateHook checks that a hook is either a command run in a pod or an HTTP call
+func validateHook(hook fledgedv1alpha2.ImageCacheHook) error {
+	switch hook.Scope {
+	case "", fledgedv1alpha2.HookScopeCache, fledgedv1alpha2.HookScopeNode:
+	default:
+		return fmt.Errorf("unsupported scope %q", hook.Scope)
+	}
+	switch hook.FailurePolicy {
+	case "", fledgedv1alpha2.HookFailurePolicyFail, fledgedv1alpha2.HookFailurePolicyIgnore:
+	default:
+		return fmt.Errorf("unsupported failurePolicy %q", hook.FailurePolicy)
+	}
+	if hook.Timeout != nil && hook.Timeout.Duration <= 0 {
+		return fmt.Errorf("timeout %s must be positive", hook.Timeout.Duration)
+	}
+	if (hook.Exec == nil) == (hook.HTTP == nil) {
+		return fmt.Errorf("exactly one of exec and http must be specified")
+	}
+	if hook.Exec != nil {
+		if _, err := reference.ParseNormalizedNamed(hook.Exec.Image); err != nil {
+			return fmt.Errorf("invalid image %q: %v", hook.Exec.Image, err)
+		}
+		if len(hook.Exec.Command) == 0 {
+			return fmt.Errorf("command must not be empty")
+		}
+	}
+	if hook.HTTP != nil {
+		u, err := url.Parse(hook.HTTP.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid url %q: expected an http or https URL", hook.HTTP.URL)
+		}
+	}
+	return nil
+}
+
+// ValidateImagePullPolicy checks that the image pull policy of an image cache, if
+// specified, is one supported for pulling images into the cache.
+func ValidateImagePullPolicy(imagePullPolicy corev1.PullPolicy) error {
+	switch imagePullPolicy {
+	case "", corev1.PullAlways, corev1.PullIfNotPresent:
+		return nil
+	}
+	return fmt.Errorf("Unsupported image pull policy %q: supported values are %q and %q", imagePullPolicy, corev1.PullAlways, corev1.PullIfNotPresent)
+}
+
+// ValidateNoDuplicateImages checks that an image is not listed in more than one image
+// list of a cache spec, when the effective node selectors of those image lists may
+// match the same nodes. Image lists without node selector select the nodes running
+// defaultNodeOS, or all the nodes if defaultNodeOS is empty.
+func ValidateNoDuplicateImages(cacheSpec []fledgedv1alpha2.CacheSpecImages, defaultNodeOS string) error {
+	for k := range cacheSpec {
+		for j := 0; j < k; j++ {
+			if !NodeSelectorsOverlap(EffectiveNodeSelector(cacheSpec[j].NodeSelector, defaultNodeOS),
+				EffectiveNodeSelector(cacheSpec[k].NodeSelector, defaultNodeOS)) {
+				continue
+			}
+			for _, image := range cacheSpec[k].Images {
+				for _, other := range cacheSpec[j].Images {
+					if image == other {
+						return fmt.Errorf("Duplicate image names within image lists %d and %d targeting the same nodes: %s", j, k, image)
+					}
+				}
+			}
+			for _, artifact := range cacheSpec[k].Artifacts {
+				for _, other := range cacheSpec[j].Artifacts {
+					if artifact == other {
+						return fmt.Errorf("Duplicate artifact names within image lists %d and %d targeting the same nodes: %s", j, k, artifact)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// EffectiveNodeSelector returns the node selector of an image list, which selects the
+// nodes running defaultNodeOS if the image list has none
+func EffectiveNodeSelector(nodeSelector map[string]string, defaultNodeOS string) map[string]string {
+	if len(nodeSelector) == 0 && defaultNodeOS != "" {
+		return map[string]string{corev1.LabelOSStable: defaultNodeOS}
+	}
+	return nodeSelector
+}
+
+// NodeSelectorsOverlap returns true if a node can match both node selectors, i.e.
+// the node selectors do not require different values for the same label.
+func NodeSelectorsOverlap(a, b map[string]string) bool {
+	for key, value := range a {
+		if other, ok := b[key]; ok && other != value {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateSecurityProfiles checks that the seccomp and AppArmor profiles of the pods of
+// the jobs, if specified, are supported by kubelet.
+func ValidateSecurityProfiles(profiles *fledgedv1alpha2.SecurityProfiles) error {
+	if profiles == nil {
+		return nil
+	}
+	if seccomp := profiles.SeccompProfile; seccomp != nil {
+		switch seccomp.Type {
+		case corev1.SeccompProfileTypeRuntimeDefault, corev1.SeccompProfileTypeUnconfined:
+			if seccomp.LocalhostProfile != nil {
+				return fmt.Errorf("Invalid seccomp profile: localhostProfile may only be set for type %s", corev1.SeccompProfileTypeLocalhost)
+			}
+		case corev1.SeccompProfileTypeLocalhost:
+			if seccomp.LocalhostProfile == nil || *seccomp.LocalhostProfile == "" {
+				return fmt.Errorf("Invalid seccomp profile: localhostProfile is required for type %s", corev1.SeccompProfileTypeLocalhost)
+			}
+		default:
+			return fmt.Errorf("Unsupported seccomp profile type %q: supported values are %q, %q and %q", seccomp.Type,
+				corev1.SeccompProfileTypeRuntimeDefault, corev1.SeccompProfileTypeUnconfined, corev1.SeccompProfileTypeLocalhost)
+		}
+	}
+	switch apparmor := profiles.AppArmorProfile; {
+	case apparmor == "", apparmor == appArmorProfileRuntimeDefault, apparmor == appArmorProfileUnconfined:
+	case strings.HasPrefix(apparmor, appArmorProfileLocalhostPrefix) && len(apparmor) > len(appArmorProfileLocalhostPrefix):
+	default:
+		return fmt.Errorf("Unsupported AppArmor profile %q: supported values are %q, %q and %q", apparmor,
+			appArmorProfileRuntimeDefault, appArmorProfileUnconfined, appArmorProfileLocalhostPrefix+"<profile>")
+	}
+	return nil
+}
@@ -0,0 +1,800 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestValidateCacheSpec(t *testing.T) {
+	tests := []struct {
+		name                string
+		cacheSpec           []fledgedv1alpha2.CacheSpecImages
+		expectedErrorString string
+	}{
+		{
+			name: "#1: Valid cache spec",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo", "bar"}},
+			},
+		},
+		{
+			name: "#2: Empty image list",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{}},
+			},
+			expectedErrorString: "No images specified within image list",
+		},
+		{
+			name: "#3: Duplicate image within image list",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo", "bar", "foo"}},
+			},
+			expectedErrorString: "Duplicate image names within image list: foo",
+		},
+		{
+			name: "#4: Duplicate image across image lists targeting the same nodes",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}},
+				{Images: []string{"bar", "foo"}, NodeSelector: map[string]string{"pool": "foo"}},
+			},
+			expectedErrorString: "Duplicate image names within image lists 0 and 1 targeting the same nodes: foo",
+		},
+		{
+			name: "#5: Same image across image lists targeting different nodes",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}, NodeSelector: map[string]string{"pool": "foo"}},
+				{Images: []string{"foo"}, NodeSelector: map[string]string{"pool": "bar"}},
+			},
+		},
+		{
+			name: "#6: Valid image references",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{
+					"nginx",
+					"docker.io/library/nginx:1.23.1",
+					"localhost:5000/foo/bar:v1",
+					"quay.io/foo/bar@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+				}},
+			},
+		},
+		{
+			name: "#7: Empty tag",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx:"}},
+			},
+			expectedErrorString: "Invalid image reference \"nginx:\"",
+		},
+		{
+			name: "#8: Invalid characters",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"Nginx:latest"}},
+			},
+			expectedErrorString: "Invalid image reference \"Nginx:latest\"",
+		},
+		{
+			name: "#9: Bad digest",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx@sha256:1234"}},
+			},
+			expectedErrorString: "Invalid image reference \"nginx@sha256:1234\"",
+		},
+		{
+			name: "#10: Image list with artifacts only",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Artifacts: []string{"ghcr.io/foo/model:v1"}},
+			},
+		},
+		{
+			name: "#11: Duplicate artifact within image list",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}, Artifacts: []string{"ghcr.io/foo/model:v1", "ghcr.io/foo/model:v1"}},
+			},
+			expectedErrorString: "Duplicate artifact names within image list: ghcr.io/foo/model:v1",
+		},
+		{
+			name: "#12: Invalid artifact reference",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Artifacts: []string{"ghcr.io/foo/model:"}},
+			},
+			expectedErrorString: "Invalid image reference \"ghcr.io/foo/model:\"",
+		},
+		{
+			name: "#13: Platform specified for image",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo", "bar"}, Platforms: map[string]string{"foo": "linux/arm64/v8"}},
+			},
+		},
+		{
+			name: "#14: Platform specified for image not in image list",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}, Platforms: map[string]string{"bar": "linux/arm64"}},
+			},
+			expectedErrorString: "Platform specified for image bar, which is not in the image list",
+		},
+		{
+			name: "#15: Invalid platform",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}, Platforms: map[string]string{"foo": "arm64"}},
+			},
+			expectedErrorString: "Invalid platform \"arm64\"",
+		},
+		{
+			name: "#16: Image list with only a tracked repository",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{TrackedRepositories: []fledgedv1alpha2.TrackedRepository{{Repository: "nginx", TagConstraint: "1.25.*"}}},
+			},
+		},
+		{
+			name: "#17: Tracked repository with tag",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{TrackedRepositories: []fledgedv1alpha2.TrackedRepository{{Repository: "nginx:1.25", TagConstraint: "1.25.*"}}},
+			},
+			expectedErrorString: "Invalid tracked repository \"nginx:1.25\": tags and digests are not allowed",
+		},
+		{
+			name: "#18: Invalid tag constraint",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{TrackedRepositories: []fledgedv1alpha2.TrackedRepository{{Repository: "nginx", TagConstraint: "latest"}}},
+			},
+			expectedErrorString: "Invalid tag constraint \"latest\" of tracked repository nginx",
+		},
+		{
+			name: "#19: Image list with only a catalog",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Catalogs: []fledgedv1alpha2.RegistryCatalog{{Repositories: "registry.internal/platform/*", Tags: "v1.*"}}},
+			},
+		},
+		{
+			name: "#20: Catalog without repository glob",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Catalogs: []fledgedv1alpha2.RegistryCatalog{{Repositories: "registry.internal", Tags: "v1.*"}}},
+			},
+			expectedErrorString: "Invalid catalog: invalid repositories \"registry.internal\"",
+		},
+		{
+			name: "#21: Catalog with invalid tag glob",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Catalogs: []fledgedv1alpha2.RegistryCatalog{{Repositories: "registry.internal/platform/*", Tags: "v1.["}}},
+			},
+			expectedErrorString: "Invalid tag glob \"v1.[\" of catalog registry.internal/platform/*",
+		},
+		{
+			name: "#22: Image list overriding the refresh interval",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}, RefreshInterval: &metav1.Duration{Duration: 15 * time.Minute}},
+			},
+		},
+		{
+			name: "#23: Negative refresh interval",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}, RefreshInterval: &metav1.Duration{Duration: -time.Minute}},
+			},
+			expectedErrorString: "Negative refresh interval within image list: -1m0s",
+		},
+		{
+			name: "#24: Image list with only providers",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Providers: []fledgedv1alpha2.ImageListProviderSpec{
+					{ConfigMap: &fledgedv1alpha2.ConfigMapImageList{Name: "release", Key: "images"}, Prune: true},
+					{Workloads: &fledgedv1alpha2.WorkloadImageList{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}}},
+					{Plugin: &fledgedv1alpha2.PluginImageList{Address: "catalog.tools.svc:9000"}},
+				}},
+			},
+		},
+		{
+			name: "#25: Provider of several kinds",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Providers: []fledgedv1alpha2.ImageListProviderSpec{{
+					ConfigMap: &fledgedv1alpha2.ConfigMapImageList{Name: "release", Key: "images"},
+					Plugin:    &fledgedv1alpha2.PluginImageList{Address: "catalog.tools.svc:9000"},
+				}}},
+			},
+			expectedErrorString: "Invalid image list provider: exactly one of configMap, workloads, plugin, system, helmRelease, imageList and imageStream must be specified",
+		},
+		{
+			name: "#26: ConfigMap provider without key",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Providers: []fledgedv1alpha2.ImageListProviderSpec{{ConfigMap: &fledgedv1alpha2.ConfigMapImageList{Name: "release"}}}},
+			},
+			expectedErrorString: "Invalid ConfigMap image list provider: name and key must not be empty",
+		},
+		{
+			name: "#27: Workloads provider with invalid selector",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Providers: []fledgedv1alpha2.ImageListProviderSpec{{Workloads: &fledgedv1alpha2.WorkloadImageList{
+					Selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Near"}}},
+				}}}},
+			},
+			expectedErrorString: "Invalid selector of workloads image list provider",
+		},
+		{
+			name: "#28: System provider of the target version of an upgrade",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Providers: []fledgedv1alpha2.ImageListProviderSpec{{System: &fledgedv1alpha2.SystemImageList{
+					KubernetesVersion: "v1.28.2", PauseImage: "registry.k8s.io/pause:3.9",
+				}}}},
+			},
+		},
+		{
+			name: "#29: System provider with invalid Kubernetes version",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Providers: []fledgedv1alpha2.ImageListProviderSpec{{System: &fledgedv1alpha2.SystemImageList{KubernetesVersion: "1.28"}}}},
+			},
+			expectedErrorString: "Invalid Kubernetes version \"1.28\" of system image list provider",
+		},
+		{
+			name: "#30: Image pull policy specified for image",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo:v1", "bar@sha256:3ab0a6d8b2bce2bb8b29c8e1fb9fc1b8ea1d1b1c4c3c35e7dd96ab4b68ac9a1b"},
+					ImagePullPolicies: map[string]corev1.PullPolicy{"foo:v1": corev1.PullAlways}},
+			},
+		},
+		{
+			name: "#31: Image pull policy specified for image not in image list",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}, ImagePullPolicies: map[string]corev1.PullPolicy{"bar": corev1.PullAlways}},
+			},
+			expectedErrorString: "Image pull policy specified for image bar, which is not in the image list",
+		},
+		{
+			name: "#32: Unsupported image pull policy",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}, ImagePullPolicies: map[string]corev1.PullPolicy{"foo": corev1.PullNever}},
+			},
+			expectedErrorString: "Unsupported image pull policy \"Never\"",
+		},
+		{
+			name: "#33: Fallback references specified for image",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"registry.internal/nginx:1.23"},
+					Fallbacks: map[string][]string{"registry.internal/nginx:1.23": {"mirror.internal/nginx:1.23", "nginx:1.23"}}},
+			},
+		},
+		{
+			name: "#34: Fallback references specified for image not in image list",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}, Fallbacks: map[string][]string{"bar": {"mirror.internal/bar"}}},
+			},
+			expectedErrorString: "Fallback references specified for image bar, which is not in the image list",
+		},
+		{
+			name: "#35: Duplicate fallback reference",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}, Fallbacks: map[string][]string{"foo": {"mirror.internal/foo", "mirror.internal/foo"}}},
+			},
+			expectedErrorString: "Duplicate fallback reference mirror.internal/foo of image foo",
+		},
+		{
+			name: "#36: Target reference specified for image",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"mirror.internal/library/nginx:1.23"},
+					TargetRefs: map[string]string{"mirror.internal/library/nginx:1.23": "docker.io/library/nginx:1.23"}},
+			},
+		},
+		{
+			name: "#37: Target reference specified for image not in image list",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}, TargetRefs: map[string]string{"bar": "docker.io/library/bar"}},
+			},
+			expectedErrorString: "Target reference specified for image bar, which is not in the image list",
+		},
+		{
+			name: "#38: Target reference is the image itself",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}, TargetRefs: map[string]string{"foo": "foo"}},
+			},
+			expectedErrorString: "Target reference of image foo is the image itself",
+		},
+		{
+			name: "#39: Display tag specified for image referenced by digest only",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31"},
+					DisplayTags: map[string]string{"nginx@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31": "1.23"}},
+			},
+		},
+		{
+			name: "#40: Display tag specified for image referenced by tag",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx:1.23"}, DisplayTags: map[string]string{"nginx:1.23": "1.23"}},
+			},
+			expectedErrorString: "Display tag specified for image nginx:1.23, which is not referenced by digest only",
+		},
+		{
+			name: "#41: Invalid display tag",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"nginx@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31"},
+					DisplayTags: map[string]string{"nginx@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31": "-1.23"}},
+			},
+			expectedErrorString: "Invalid display tag \"-1.23\"",
+		},
+		{
+			name: "#42: Display tag specified for image not in image list",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}, DisplayTags: map[string]string{"bar": "1.0"}},
+			},
+			expectedErrorString: "Display tag specified for image bar, which is not in the image list",
+		},
+		{
+			name: "#43: Helm release provider",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Providers: []fledgedv1alpha2.ImageListProviderSpec{{HelmRelease: &fledgedv1alpha2.HelmReleaseImageList{Namespace: "monitoring", Name: "prometheus"}, Prune: true}}},
+			},
+		},
+		{
+			name: "#44: Helm release provider without name",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Providers: []fledgedv1alpha2.ImageListProviderSpec{{HelmRelease: &fledgedv1alpha2.HelmReleaseImageList{Namespace: "monitoring"}}}},
+			},
+			expectedErrorString: "Invalid Helm release image list provider: name must not be empty",
+		},
+		{
+			name: "#45: ImageList provider",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Providers: []fledgedv1alpha2.ImageListProviderSpec{{ImageList: &fledgedv1alpha2.ImageListReference{Namespace: "platform", Name: "base-images"}, Prune: true}}},
+			},
+		},
+		{
+			name: "#46: ImageList provider without name",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Providers: []fledgedv1alpha2.ImageListProviderSpec{{ImageList: &fledgedv1alpha2.ImageListReference{Namespace: "platform"}}}},
+			},
+			expectedErrorString: "Invalid ImageList image list provider: name must not be empty",
+		},
+		{
+			name: "#47: Helm release and ImageList providers in one provider",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Providers: []fledgedv1alpha2.ImageListProviderSpec{{HelmRelease: &fledgedv1alpha2.HelmReleaseImageList{Name: "prometheus"},
+					ImageList: &fledgedv1alpha2.ImageListReference{Name: "base-images"}}}},
+			},
+			expectedErrorString: "Invalid image list provider: exactly one of configMap, workloads, plugin, system, helmRelease, imageList and imageStream must be specified",
+		},
+		{
+			name: "#48: ImageStream provider",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Providers: []fledgedv1alpha2.ImageListProviderSpec{{ImageStream: &fledgedv1alpha2.ImageStreamImageList{Name: "app", Tags: []string{"latest", "v2"}}, Prune: true}}},
+			},
+		},
+		{
+			name: "#49: ImageStream provider without name",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Providers: []fledgedv1alpha2.ImageListProviderSpec{{ImageStream: &fledgedv1alpha2.ImageStreamImageList{Tags: []string{"latest"}}}}},
+			},
+			expectedErrorString: "Invalid ImageStream image list provider: name must not be empty",
+		},
+		{
+			name: "#50: ImageStream provider with an empty tag",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Providers: []fledgedv1alpha2.ImageListProviderSpec{{ImageStream: &fledgedv1alpha2.ImageStreamImageList{Name: "app", Tags: []string{""}}}}},
+			},
+			expectedErrorString: "Invalid ImageStream image list provider: tags must not be empty",
+		},
+		{
+			name: "#51: Same image for the default node OS and for windows nodes",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}},
+				{Images: []string{"foo"}, NodeSelector: map[string]string{"kubernetes.io/os": "windows"}},
+			},
+		},
+		{
+			name: "#52: Same image for the default node OS and for its nodes",
+			cacheSpec: []fledgedv1alpha2.CacheSpecImages{
+				{Images: []string{"foo"}},
+				{Images: []string{"foo"}, NodeSelector: map[string]string{"kubernetes.io/os": "linux", "pool": "foo"}},
+			},
+			expectedErrorString: "Duplicate image names within image lists 0 and 1 targeting the same nodes: foo",
+		},
+	}
+	for _, test := range tests {
+		err := ValidateImageLists(test.cacheSpec)
+		if err == nil {
+			err = ValidateNoDuplicateImages(test.cacheSpec, "linux")
+		}
+		if test.expectedErrorString == "" {
+			if err != nil {
+				t.Errorf("Test: %s failed. expectedError=nil, actualError=%s", test.name, err.Error())
+			}
+		} else if err == nil || !strings.HasPrefix(err.Error(), test.expectedErrorString) {
+			t.Errorf("Test: %s failed: expectedError=%s, actualError=%v", test.name, test.expectedErrorString, err)
+		}
+	}
+}
+
+func TestValidateNoDuplicateImagesWithoutDefaultNodeOS(t *testing.T) {
+	cacheSpec := []fledgedv1alpha2.CacheSpecImages{
+		{Images: []string{"foo"}},
+		{Images: []string{"foo"}, NodeSelector: map[string]string{"kubernetes.io/os": "windows"}},
+	}
+	if err := ValidateNoDuplicateImages(cacheSpec, ""); err == nil {
+		t.Errorf("expected an image list without node selector to overlap all the image lists without a default node OS")
+	}
+}
+
+func TestValidateImagePullPolicy(t *testing.T) {
+	tests := []struct {
+		name                string
+		imagePullPolicy     corev1.PullPolicy
+		expectedErrorString string
+	}{
+		{
+			name:            "#1: Not specified",
+			imagePullPolicy: "",
+		},
+		{
+			name:            "#2: Always",
+			imagePullPolicy: corev1.PullAlways,
+		},
+		{
+			name:            "#3: IfNotPresent",
+			imagePullPolicy: corev1.PullIfNotPresent,
+		},
+		{
+			name:                "#4: Never",
+			imagePullPolicy:     corev1.PullNever,
+			expectedErrorString: "Unsupported image pull policy \"Never\"",
+		},
+	}
+	for _, test := range tests {
+		err := ValidateImagePullPolicy(test.imagePullPolicy)
+		if test.expectedErrorString == "" {
+			if err != nil {
+				t.Errorf("Test: %s failed. expectedError=nil, actualError=%s", test.name, err.Error())
+			}
+		} else if err == nil || !strings.HasPrefix(err.Error(), test.expectedErrorString) {
+			t.Errorf("Test: %s failed: expectedError=%s, actualError=%v", test.name, test.expectedErrorString, err)
+		}
+	}
+}
+
+func TestValidateTTL(t *testing.T) {
+	tests := []struct {
+		name                string
+		ttl                 *metav1.Duration
+		expectedErrorString string
+	}{
+		{
+			name: "#1: Not specified",
+		},
+		{
+			name: "#2: Positive ttl",
+			ttl:  &metav1.Duration{Duration: time.Hour},
+		},
+		{
+			name:                "#3: Zero ttl",
+			ttl:                 &metav1.Duration{},
+			expectedErrorString: "Invalid ttl 0s: must be positive",
+		},
+	}
+	for _, test := range tests {
+		err := ValidateTTL(test.ttl)
+		if test.expectedErrorString == "" {
+			if err != nil {
+				t.Errorf("Test: %s failed. expectedError=nil, actualError=%s", test.name, err.Error())
+			}
+		} else if err == nil || !strings.HasPrefix(err.Error(), test.expectedErrorString) {
+			t.Errorf("Test: %s failed: expectedError=%s, actualError=%v", test.name, test.expectedErrorString, err)
+		}
+	}
+}
+
+func TestValidateWarmStandby(t *testing.T) {
+	tests := []struct {
+		name                string
+		warmStandby         *fledgedv1alpha2.WarmStandby
+		expectedErrorString string
+	}{
+		{
+			name: "#1: Not specified",
+		},
+		{
+			name:        "#2: Valid warm standby",
+			warmStandby: &fledgedv1alpha2.WarmStandby{NodeSelector: map[string]string{"pool": "spare"}, Nodes: 2},
+		},
+		{
+			name:                "#3: Empty node selector",
+			warmStandby:         &fledgedv1alpha2.WarmStandby{Nodes: 2},
+			expectedErrorString: "Invalid warmStandby: nodeSelector must not be empty",
+		},
+		{
+			name:                "#4: Negative number of nodes",
+			warmStandby:         &fledgedv1alpha2.WarmStandby{NodeSelector: map[string]string{"pool": "spare"}, Nodes: -1},
+			expectedErrorString: "Invalid warmStandby.nodes -1: must not be negative",
+		},
+	}
+	for _, test := range tests {
+		err := ValidateWarmStandby(test.warmStandby)
+		if test.expectedErrorString == "" {
+			if err != nil {
+				t.Errorf("Test: %s failed. expectedError=nil, actualError=%s", test.name, err.Error())
+			}
+		} else if err == nil || !strings.HasPrefix(err.Error(), test.expectedErrorString) {
+			t.Errorf("Test: %s failed: expectedError=%s, actualError=%v", test.name, test.expectedErrorString, err)
+		}
+	}
+}
+
+func TestValidateRollout(t *testing.T) {
+	tests := []struct {
+		name                string
+		rollout             *fledgedv1alpha2.RolloutStrategy
+		expectedErrorString string
+	}{
+		{
+			name: "#1: Not specified",
+		},
+		{
+			name:    "#2: Topology rollout with the default topology key",
+			rollout: &fledgedv1alpha2.RolloutStrategy{Type: fledgedv1alpha2.RolloutTypeTopology},
+		},
+		{
+			name:    "#3: Topology rollout with a topology key",
+			rollout: &fledgedv1alpha2.RolloutStrategy{Type: fledgedv1alpha2.RolloutTypeTopology, TopologyKey: "topology.kubernetes.io/region"},
+		},
+		{
+			name:                "#4: Unsupported type",
+			rollout:             &fledgedv1alpha2.RolloutStrategy{Type: "Canary"},
+			expectedErrorString: "Unsupported rollout type \"Canary\"",
+		},
+		{
+			name:                "#5: Invalid topology key",
+			rollout:             &fledgedv1alpha2.RolloutStrategy{Type: fledgedv1alpha2.RolloutTypeTopology, TopologyKey: "zone/"},
+			expectedErrorString: "Invalid rollout.topologyKey \"zone/\"",
+		},
+	}
+	for _, test := range tests {
+		err := ValidateRollout(test.rollout)
+		if test.expectedErrorString == "" {
+			if err != nil {
+				t.Errorf("Test: %s failed. expectedError=nil, actualError=%s", test.name, err.Error())
+			}
+		} else if err == nil || !strings.HasPrefix(err.Error(), test.expectedErrorString) {
+			t.Errorf("Test: %s failed: expectedError=%s, actualError=%v", test.name, test.expectedErrorString, err)
+		}
+	}
+}
+
+func TestValidateSnapshotter(t *testing.T) {
+	tests := []struct {
+		name                string
+		snapshotter         string
+		expectedErrorString string
+	}{
+		{
+			name: "#1: Not specified",
+		},
+		{
+			name:        "#2: Stargz snapshotter",
+			snapshotter: "stargz",
+		},
+		{
+			name:                "#3: Invalid snapshotter",
+			snapshotter:         "overlayfs; reboot",
+			expectedErrorString: "Invalid snapshotter \"overlayfs; reboot\"",
+		},
+	}
+	for _, test := range tests {
+		err := ValidateSnapshotter(test.snapshotter)
+		if test.expectedErrorString == "" {
+			if err != nil {
+				t.Errorf("Test: %s failed. expectedError=nil, actualError=%s", test.name, err.Error())
+			}
+		} else if err == nil || !strings.HasPrefix(err.Error(), test.expectedErrorString) {
+			t.Errorf("Test: %s failed: expectedError=%s, actualError=%v", test.name, test.expectedErrorString, err)
+		}
+	}
+}
+
+func TestValidateFailureThreshold(t *testing.T) {
+	threshold := func(v intstr.IntOrString) *intstr.IntOrString { return &v }
+	tests := []struct {
+		name                string
+		threshold           *intstr.IntOrString
+		expectedErrorString string
+	}{
+		{
+			name: "#1: Not specified",
+		},
+		{
+			name:      "#2: Number",
+			threshold: threshold(intstr.FromInt(10)),
+		},
+		{
+			name:      "#3: Percentage",
+			threshold: threshold(intstr.FromString("5%")),
+		},
+		{
+			name:                "#4: Negative number",
+			threshold:           threshold(intstr.FromInt(-1)),
+			expectedErrorString: "Invalid failureThreshold -1: must not be negative",
+		},
+		{
+			name:                "#5: Percentage above 100%",
+			threshold:           threshold(intstr.FromString("150%")),
+			expectedErrorString: "Invalid failureThreshold \"150%\"",
+		},
+		{
+			name:                "#6: Not a percentage",
+			threshold:           threshold(intstr.FromString("ten")),
+			expectedErrorString: "Invalid failureThreshold \"ten\"",
+		},
+	}
+	for _, test := range tests {
+		err := ValidateFailureThreshold(test.threshold)
+		if test.expectedErrorString == "" {
+			if err != nil {
+				t.Errorf("Test: %s failed. expectedError=nil, actualError=%s", test.name, err.Error())
+			}
+		} else if err == nil || !strings.HasPrefix(err.Error(), test.expectedErrorString) {
+			t.Errorf("Test: %s failed: expectedError=%s, actualError=%v", test.name, test.expectedErrorString, err)
+		}
+	}
+}
+
+func TestValidateMaxBytesPerNode(t *testing.T) {
+	quantity := func(v string) *resource.Quantity { q := resource.MustParse(v); return &q }
+	tests := []struct {
+		name                string
+		maxBytes            *resource.Quantity
+		expectedErrorString string
+	}{
+		{
+			name: "#1: Not specified",
+		},
+		{
+			name:     "#2: Positive",
+			maxBytes: quantity("20Gi"),
+		},
+		{
+			name:                "#3: Zero",
+			maxBytes:            quantity("0"),
+			expectedErrorString: "Invalid maxBytesPerNode 0: must be positive",
+		},
+	}
+	for _, test := range tests {
+		err := ValidateMaxBytesPerNode(test.maxBytes)
+		if test.expectedErrorString == "" {
+			if err != nil {
+				t.Errorf("Test: %s failed. expectedError=nil, actualError=%s", test.name, err.Error())
+			}
+		} else if err == nil || !strings.HasPrefix(err.Error(), test.expectedErrorString) {
+			t.Errorf("Test: %s failed: expectedError=%s, actualError=%v", test.name, test.expectedErrorString, err)
+		}
+	}
+}
+
+func TestValidateHooks(t *testing.T) {
+	execHook := &fledgedv1alpha2.ExecHook{Image: "busybox:1.36", Command: []string{"sh", "-c", "true"}}
+	httpHook := &fledgedv1alpha2.HTTPHook{URL: "https://cmdb.local/notify"}
+	tests := []struct {
+		name                string
+		hooks               *fledgedv1alpha2.ImageCacheHooks
+		expectedErrorString string
+	}{
+		{
+			name: "#1: Not specified",
+		},
+		{
+			name: "#2: Valid hooks",
+			hooks: &fledgedv1alpha2.ImageCacheHooks{
+				PrePull:  []fledgedv1alpha2.ImageCacheHook{{Name: "drain", Scope: fledgedv1alpha2.HookScopeNode, Exec: execHook}},
+				PostPull: []fledgedv1alpha2.ImageCacheHook{{Name: "drain", HTTP: httpHook, Timeout: &metav1.Duration{Duration: time.Second}}},
+			},
+		},
+		{
+			name: "#3: Duplicate hook names",
+			hooks: &fledgedv1alpha2.ImageCacheHooks{
+				PrePull: []fledgedv1alpha2.ImageCacheHook{{Name: "drain", Exec: execHook}, {Name: "drain", HTTP: httpHook}},
+			},
+			expectedErrorString: "Duplicate prePull hook name: drain",
+		},
+		{
+			name: "#4: Both exec and http",
+			hooks: &fledgedv1alpha2.ImageCacheHooks{
+				PostPull: []fledgedv1alpha2.ImageCacheHook{{Name: "notify", Exec: execHook, HTTP: httpHook}},
+			},
+			expectedErrorString: "Invalid postPull hook notify: exactly one of exec and http must be specified",
+		},
+		{
+			name: "#5: Invalid url",
+			hooks: &fledgedv1alpha2.ImageCacheHooks{
+				PostPull: []fledgedv1alpha2.ImageCacheHook{{Name: "notify", HTTP: &fledgedv1alpha2.HTTPHook{URL: "cmdb.local"}}},
+			},
+			expectedErrorString: "Invalid postPull hook notify: invalid url",
+		},
+		{
+			name: "#6: Unsupported scope",
+			hooks: &fledgedv1alpha2.ImageCacheHooks{
+				PrePull: []fledgedv1alpha2.ImageCacheHook{{Name: "drain", Scope: "Zone", Exec: execHook}},
+			},
+			expectedErrorString: "Invalid prePull hook drain: unsupported scope",
+		},
+		{
+			name: "#7: Empty command",
+			hooks: &fledgedv1alpha2.ImageCacheHooks{
+				PrePull: []fledgedv1alpha2.ImageCacheHook{{Name: "drain", Exec: &fledgedv1alpha2.ExecHook{Image: "busybox:1.36"}}},
+			},
+			expectedErrorString: "Invalid prePull hook drain: command must not be empty",
+		},
+	}
+	for _, test := range tests {
+		err := ValidateHooks(test.hooks)
+		if test.expectedErrorString == "" {
+			if err != nil {
+				t.Errorf("Test: %s failed. expectedError=nil, actualError=%s", test.name, err.Error())
+			}
+		} else if err == nil || !strings.HasPrefix(err.Error(), test.expectedErrorString) {
+			t.Errorf("Test: %s failed: expectedError=%s, actualError=%v", test.name, test.expectedErrorString, err)
+		}
+	}
+}
+
+func TestValidateSecurityProfiles(t *testing.T) {
+	localhostProfile := "profiles/puller.json"
+	tests := []struct {
+		name                string
+		profiles            *fledgedv1alpha2.SecurityProfiles
+		expectedErrorString string
+	}{
+		{
+			name: "#1: Not specified",
+		},
+		{
+			name: "#2: Valid profiles",
+			profiles: &fledgedv1alpha2.SecurityProfiles{
+				SeccompProfile:  &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeLocalhost, LocalhostProfile: &localhostProfile},
+				AppArmorProfile: "localhost/kubefledged-puller",
+			},
+		},
+		{
+			name: "#3: Localhost seccomp profile without profile",
+			profiles: &fledgedv1alpha2.SecurityProfiles{
+				SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeLocalhost},
+			},
+			expectedErrorString: "Invalid seccomp profile: localhostProfile is required",
+		},
+		{
+			name: "#4: Unsupported seccomp profile type",
+			profiles: &fledgedv1alpha2.SecurityProfiles{
+				SeccompProfile: &corev1.SeccompProfile{Type: "Default"},
+			},
+			expectedErrorString: "Unsupported seccomp profile type \"Default\"",
+		},
+		{
+			name:                "#5: Unsupported AppArmor profile",
+			profiles:            &fledgedv1alpha2.SecurityProfiles{AppArmorProfile: "localhost/"},
+			expectedErrorString: "Unsupported AppArmor profile \"localhost/\"",
+		},
+	}
+	for _, test := range tests {
+		err := ValidateSecurityProfiles(test.profiles)
+		if test.expectedErrorString == "" {
+			if err != nil {
+				t.Errorf("Test: %s failed. expectedError=nil, actualError=%s", test.name, err.Error())
+			}
+		} else if err == nil || !strings.HasPrefix(err.Error(), test.expectedErrorString) {
+			t.Errorf("Test: %s failed: expectedError=%s, actualError=%v", test.name, test.expectedErrorString, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"k8s.io/client-go/util/workqueue"
+)
+
+// ImageWorkQueues holds the image work requests of image cache actions. Each type of
+// action has its own queue, rate limiter and worker, so that a large purge does not
+// starve the creation of image caches and vice versa. The refreshes started by the
+// controller itself are held in a queue of their own, so that the actions triggered
+// by users never wait behind them.
+type ImageWorkQueues struct {
+	// Create holds the work requests of create and update actions
+	Create workqueue.RateLimitingInterface
+	// Refresh holds the work requests of refresh actions triggered by users
+	Refresh workqueue.RateLimitingInterface
+	// BackgroundRefresh holds the work requests of the refresh actions started by the
+	// controller, e.g. the periodic refresh
+	BackgroundRefresh workqueue.RateLimitingInterface
+	// Purge holds the work requests of purge actions
+	Purge workqueue.RateLimitingInterface
+}
+
+// NewImageWorkQueues returns the image work queues, each with its own rate limiter
+func NewImageWorkQueues() ImageWorkQueues {
+	return ImageWorkQueues{
+		Create:            workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ImagePullerStatusCreate"),
+		Refresh:           workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ImagePullerStatusRefresh"),
+		BackgroundRefresh: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ImagePullerStatusBackgroundRefresh"),
+		Purge:             workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ImagePullerStatusPurge"),
+	}
+}
+
+// For returns the queue of the work requests of an image cache action. All the work
+// requests of an action, including the image deletions of an update, must be placed
+// in the queue of the action: the request marking the end of the action is processed
+// only after them. Background is true for the refreshes started by the controller.
+func (q ImageWorkQueues) For(workType WorkType, background bool) workqueue.RateLimitingInterface {
+	switch workType {
+	case ImageCacheRefresh:
+		if background {
+			return q.BackgroundRefresh
+		}
+		return q.Refresh
+	case ImageCachePurge:
+		return q.Purge
+	default:
+		return q.Create
+	}
+}
+
+// All returns all the image work queues
+func (q ImageWorkQueues) All() []workqueue.RateLimitingInterface {
+	return []workqueue.RateLimitingInterface{q.Create, q.Refresh, q.BackgroundRefresh, q.Purge}
+}
+
+// ShutDown shuts down all the image work queues
+func (q ImageWorkQueues) ShutDown() {
+	for _, queue := range q.All() {
+		queue.ShutDown()
+	}
+}
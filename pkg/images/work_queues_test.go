@@ -0,0 +1,97 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"testing"
+
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestImageWorkQueuesFor(t *testing.T) {
+	queues := NewImageWorkQueues()
+	defer queues.ShutDown()
+	tests := []struct {
+		name          string
+		workType      WorkType
+		background    bool
+		expectedQueue workqueue.RateLimitingInterface
+	}{
+		{
+			name:          "#1: Create action",
+			workType:      ImageCacheCreate,
+			expectedQueue: queues.Create,
+		},
+		{
+			name:          "#2: Update action",
+			workType:      ImageCacheUpdate,
+			expectedQueue: queues.Create,
+		},
+		{
+			name:          "#3: Refresh action",
+			workType:      ImageCacheRefresh,
+			expectedQueue: queues.Refresh,
+		},
+		{
+			name:          "#4: Background refresh action",
+			workType:      ImageCacheRefresh,
+			background:    true,
+			expectedQueue: queues.BackgroundRefresh,
+		},
+		{
+			name:          "#5: Purge action",
+			workType:      ImageCachePurge,
+			expectedQueue: queues.Purge,
+		},
+	}
+	for _, test := range tests {
+		if queue := queues.For(test.workType, test.background); queue != test.expectedQueue {
+			t.Errorf("Test: %s failed: work requests placed in the wrong queue", test.name)
+		}
+	}
+}
+
+func TestImageWorkQueuesIndependent(t *testing.T) {
+	queues := NewImageWorkQueues()
+	defer queues.ShutDown()
+	for i := 0; i < 100; i++ {
+		queues.For(ImageCachePurge, false).Add(ImageWorkRequest{Image: "foo", WorkType: ImageCachePurge, Total: i})
+	}
+	queues.For(ImageCacheCreate, false).Add(ImageWorkRequest{Image: "bar", WorkType: ImageCacheCreate})
+	if queues.Create.Len() != 1 || queues.Purge.Len() != 100 || queues.Refresh.Len() != 0 {
+		t.Errorf("expected queue lengths create=1, refresh=0, purge=100, actual create=%d, refresh=%d, purge=%d",
+			queues.Create.Len(), queues.Refresh.Len(), queues.Purge.Len())
+	}
+	obj, _ := queues.Create.Get()
+	if iwr := obj.(ImageWorkRequest); iwr.Image != "bar" {
+		t.Errorf("expected create request to be processed ahead of the purge requests, got %+v", iwr)
+	}
+}
+
+func TestUserWorkPending(t *testing.T) {
+	imagemanager, _ := newTestImageManager(&fakeclientset.Clientset{}, "IfNotPresent", "sa-kube-fledged", false, "", false, "")
+	defer imagemanager.imageworkqueues.ShutDown()
+	imagemanager.imageworkqueues.BackgroundRefresh.Add(ImageWorkRequest{Image: "foo", WorkType: ImageCacheRefresh})
+	if imagemanager.userWorkPending() {
+		t.Errorf("expected background refresh requests not to hold back the background worker")
+	}
+	imagemanager.imageworkqueues.Refresh.Add(ImageWorkRequest{Image: "bar", WorkType: ImageCacheRefresh})
+	if !imagemanager.userWorkPending() {
+		t.Errorf("expected user triggered refresh requests to hold back the background worker")
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodestats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+// ImageFsReader reads the usage of the image filesystem of nodes
+type ImageFsReader interface {
+	// ImageFsUsage returns the used and the capacity bytes of the image filesystem of
+	// a node
+	ImageFsUsage(node string) (ImageFsUsage, error)
+}
+
+// ImageFsUsage is the usage of the image filesystem of a node
+type ImageFsUsage struct {
+	UsedBytes     int64
+	CapacityBytes int64
+}
+
+// summary is the part of the summary API response of kubelet holding the statistics of
+// the image filesystem
+type summary struct {
+	Node struct {
+		Runtime *struct {
+			ImageFs *struct {
+				UsedBytes     *uint64 `json:"usedBytes"`
+				CapacityBytes *uint64 `json:"capacityBytes"`
+			} `json:"imageFs"`
+		} `json:"runtime"`
+	} `json:"node"`
+}
+
+// summaryReader reads the image filesystem usage from the summary API of kubelet
+type summaryReader struct {
+	restClient rest.Interface
+	timeout    time.Duration
+}
+
+// NewImageFsReader returns an ImageFsReader reading the summary API of kubelet through
+// the node proxy of the API server, using the REST client of the core API group
+func NewImageFsReader(restClient rest.Interface, timeout time.Duration) ImageFsReader {
+	return &summaryReader{restClient: restClient, timeout: timeout}
+}
+
+func (r *summaryReader) ImageFsUsage(node string) (ImageFsUsage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	raw, err := r.restClient.Get().Resource("nodes").Name(node).SubResource("proxy").Suffix("stats/summary").Do(ctx).Raw()
+	if err != nil {
+		return ImageFsUsage{}, fmt.Errorf("error getting stats summary of node %s: %v", node, err)
+	}
+	s := summary{}
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return ImageFsUsage{}, fmt.Errorf("error decoding stats summary of node %s: %v", node, err)
+	}
+	if s.Node.Runtime == nil || s.Node.Runtime.ImageFs == nil || s.Node.Runtime.ImageFs.UsedBytes == nil {
+		return ImageFsUsage{}, fmt.Errorf("image filesystem usage not reported by node %s", node)
+	}
+	usage := ImageFsUsage{UsedBytes: int64(*s.Node.Runtime.ImageFs.UsedBytes)}
+	if s.Node.Runtime.ImageFs.CapacityBytes != nil {
+		usage.CapacityBytes = int64(*s.Node.Runtime.ImageFs.CapacityBytes)
+	}
+	return usage, nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodestats
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest/fake"
+)
+
+func TestImageFsUsage(t *testing.T) {
+	tests := []struct {
+		name          string
+		node          string
+		status        int
+		body          string
+		expectedUsage ImageFsUsage
+		expectErr     bool
+	}{
+		{
+			name:          "#1: Image filesystem usage reported",
+			node:          "node1",
+			status:        http.StatusOK,
+			body:          `{"node":{"nodeName":"node1","runtime":{"imageFs":{"usedBytes":1000,"capacityBytes":5000,"availableBytes":4000}}}}`,
+			expectedUsage: ImageFsUsage{UsedBytes: 1000, CapacityBytes: 5000},
+		},
+		{
+			name:      "#2: Image filesystem usage not reported",
+			node:      "node1",
+			status:    http.StatusOK,
+			body:      `{"node":{"nodeName":"node1"}}`,
+			expectErr: true,
+		},
+		{
+			name:      "#3: Node not found",
+			node:      "node2",
+			status:    http.StatusNotFound,
+			body:      `{}`,
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		restClient := &fake.RESTClient{
+			NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+			GroupVersion:         corev1.SchemeGroupVersion,
+			VersionedAPIPath:     "/api/v1",
+			Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path != "/api/v1/nodes/"+test.node+"/proxy/stats/summary" {
+					t.Errorf("Test: %s failed: unexpected path %s", test.name, req.URL.Path)
+				}
+				return &http.Response{StatusCode: test.status, Header: http.Header{"Content-Type": []string{"application/json"}},
+					Body: io.NopCloser(bytes.NewBufferString(test.body))}, nil
+			}),
+		}
+		usage, err := NewImageFsReader(restClient, time.Second).ImageFsUsage(test.node)
+		if (err != nil) != test.expectErr {
+			t.Errorf("Test: %s failed: expectErr=%t, actual error=%v", test.name, test.expectErr, err)
+			continue
+		}
+		if usage != test.expectedUsage {
+			t.Errorf("Test: %s failed: expected usage %+v, actual %+v", test.name, test.expectedUsage, usage)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodestats
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// loadSampleInterval is the interval between the two samples of the counters of a
+	// node taken when no recent sample of the node is known
+	loadSampleInterval = time.Second
+	// loadSampleMaxAge is the age beyond which the last sample of a node is too old to
+	// compute its rates from
+	loadSampleMaxAge = 5 * time.Minute
+)
+
+// LoadReader reads the load of nodes
+type LoadReader interface {
+	// NodeLoad returns the current load of a node
+	NodeLoad(node string) (NodeLoad, error)
+}
+
+// NodeLoad is the load of a node
+type NodeLoad struct {
+	// CPUNanoCores is the CPU usage of the node
+	CPUNanoCores uint64
+	// NetworkBytesPerSecond is the rate of the bytes received and transmitted by the
+	// network interfaces of the node
+	NetworkBytesPerSecond float64
+	// DiskIOBytesPerSecond is the rate of the bytes read from and written to the disks
+	// of the node. It is only read if requested from the load reader
+	DiskIOBytesPerSecond float64
+}
+
+// loadSummary is the part of the summary API response of kubelet holding the CPU and
+// network statistics of the node
+type loadSummary struct {
+	Node struct {
+		CPU *struct {
+			UsageNanoCores *uint64 `json:"usageNanoCores"`
+		} `json:"cpu"`
+		Network *struct {
+			RxBytes    *uint64 `json:"rxBytes"`
+			TxBytes    *uint64 `json:"txBytes"`
+			Interfaces []struct {
+				RxBytes *uint64 `json:"rxBytes"`
+				TxBytes *uint64 `json:"txBytes"`
+			} `json:"interfaces"`
+		} `json:"network"`
+	} `json:"node"`
+}
+
+// loadSample holds the cumulative counters of a node at a point in time
+type loadSample struct {
+	time         time.Time
+	cpuNanoCores uint64
+	networkBytes uint64
+	diskIOBytes  float64
+}
+
+// loadReader reads the CPU usage and the network counters of the nodes from the
+// summary API of kubelet, and their disk IO counters from the cAdvisor metrics of
+// kubelet. Rates are computed from the last sample of each node.
+type loadReader struct {
+	restClient rest.Interface
+	timeout    time.Duration
+	diskIO     bool
+	now        func() time.Time
+	sleep      func(time.Duration)
+	lock       sync.Mutex
+	samples    map[string]loadSample
+}
+
+// NewLoadReader returns a LoadReader reading the summary API of kubelet through the node
+// proxy of the API server, using the REST client of the core API group. The disk IO of
+// the nodes is only read if diskIO is true, as the cAdvisor metrics of kubelet are large.
+func NewLoadReader(restClient rest.Interface, timeout time.Duration, diskIO bool) LoadReader {
+	return &loadReader{restClient: restClient, timeout: timeout, diskIO: diskIO, now: time.Now, sleep: time.Sleep,
+		samples: map[string]loadSample{}}
+}
+
+func (r *loadReader) NodeLoad(node string) (NodeLoad, error) {
+	r.lock.Lock()
+	previous, ok := r.samples[node]
+	r.lock.Unlock()
+	if !ok || r.now().Sub(previous.time) > loadSampleMaxAge {
+		sample, err := r.sample(node)
+		if err != nil {
+			return NodeLoad{}, err
+		}
+		previous = sample
+		r.sleep(loadSampleInterval)
+	}
+	current, err := r.sample(node)
+	if err != nil {
+		return NodeLoad{}, err
+	}
+	r.lock.Lock()
+	r.samples[node] = current
+	r.lock.Unlock()
+
+	load := NodeLoad{CPUNanoCores: current.cpuNanoCores}
+	elapsed := current.time.Sub(previous.time).Seconds()
+	if elapsed <= 0 {
+		return load, nil
+	}
+	// Counters reset when the node restarts
+	if current.networkBytes >= previous.networkBytes {
+		load.NetworkBytesPerSecond = float64(current.networkBytes-previous.networkBytes) / elapsed
+	}
+	if current.diskIOBytes >= previous.diskIOBytes {
+		load.DiskIOBytesPerSecond = (current.diskIOBytes - previous.diskIOBytes) / elapsed
+	}
+	return load, nil
+}
+
+// sample reads the counters of a node
+func (r *loadReader) sample(node string) (loadSample, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	raw, err := r.restClient.Get().Resource("nodes").Name(node).SubResource("proxy").Suffix("stats/summary").Do(ctx).Raw()
+	if err != nil {
+		return loadSample{}, fmt.Errorf("error getting stats summary of node %s: %v", node, err)
+	}
+	s := loadSummary{}
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return loadSample{}, fmt.Errorf("error decoding stats summary of node %s: %v", node, err)
+	}
+	if s.Node.CPU == nil || s.Node.CPU.UsageNanoCores == nil {
+		return loadSample{}, fmt.Errorf("CPU usage not reported by node %s", node)
+	}
+	sample := loadSample{time: r.now(), cpuNanoCores: *s.Node.CPU.UsageNanoCores}
+	if network := s.Node.Network; network != nil {
+		if len(network.Interfaces) == 0 {
+			sample.networkBytes = uint64Value(network.RxBytes) + uint64Value(network.TxBytes)
+		}
+		for _, i := range network.Interfaces {
+			sample.networkBytes += uint64Value(i.RxBytes) + uint64Value(i.TxBytes)
+		}
+	}
+	if !r.diskIO {
+		return sample, nil
+	}
+	raw, err = r.restClient.Get().Resource("nodes").Name(node).SubResource("proxy").Suffix("metrics/cadvisor").Do(ctx).Raw()
+	if err != nil {
+		return loadSample{}, fmt.Errorf("error getting cadvisor metrics of node %s: %v", node, err)
+	}
+	families, err := (&expfmt.TextParser{}).TextToMetricFamilies(bytes.NewReader(raw))
+	if err != nil {
+		return loadSample{}, fmt.Errorf("error decoding cadvisor metrics of node %s: %v", node, err)
+	}
+	// The counters of the root cgroup account for the disk IO of the whole node
+	for _, name := range []string{"container_fs_reads_bytes_total", "container_fs_writes_bytes_total"} {
+		family, ok := families[name]
+		if !ok {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "id" && l.GetValue() == "/" {
+					sample.diskIOBytes += m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return sample, nil
+}
+
+func uint64Value(v *uint64) uint64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
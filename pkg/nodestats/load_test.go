@@ -0,0 +1,104 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodestats
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest/fake"
+)
+
+func TestNodeLoad(t *testing.T) {
+	summaries := []string{
+		`{"node":{"cpu":{"usageNanoCores":500000000},"network":{"interfaces":[{"rxBytes":1000,"txBytes":1000},{"rxBytes":0,"txBytes":0}]}}}`,
+		`{"node":{"cpu":{"usageNanoCores":1500000000},"network":{"interfaces":[{"rxBytes":3000,"txBytes":2000},{"rxBytes":500,"txBytes":500}]}}}`,
+		`{"node":{"cpu":{"usageNanoCores":1000000000},"network":{"interfaces":[{"rxBytes":4000,"txBytes":2000},{"rxBytes":1000,"txBytes":1000}]}}}`,
+	}
+	cadvisor := []float64{1000, 11000, 21000}
+	sample := 0
+	diskSample := 0
+	restClient := &fake.RESTClient{
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		GroupVersion:         corev1.SchemeGroupVersion,
+		VersionedAPIPath:     "/api/v1",
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			body := ""
+			switch req.URL.Path {
+			case "/api/v1/nodes/node1/proxy/stats/summary":
+				body = summaries[sample]
+				sample++
+			case "/api/v1/nodes/node1/proxy/metrics/cadvisor":
+				body = fmt.Sprintf("# TYPE container_fs_reads_bytes_total counter\n"+
+					"container_fs_reads_bytes_total{device=\"/dev/sda\",id=\"/\"} %v\n"+
+					"container_fs_reads_bytes_total{device=\"/dev/sda\",id=\"/kubepods\"} 99999\n"+
+					"# TYPE container_fs_writes_bytes_total counter\n"+
+					"container_fs_writes_bytes_total{device=\"/dev/sda\",id=\"/\"} %v\n", cadvisor[diskSample], cadvisor[diskSample])
+				diskSample++
+			default:
+				t.Errorf("unexpected path %s", req.URL.Path)
+			}
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": []string{"text/plain"}},
+				Body: io.NopCloser(bytes.NewBufferString(body))}, nil
+		}),
+	}
+	now := time.Now()
+	reader := NewLoadReader(restClient, time.Second, true).(*loadReader)
+	reader.now = func() time.Time { return now }
+	reader.sleep = func(d time.Duration) { now = now.Add(d) }
+
+	// The first load of a node is sampled twice
+	load, err := reader.NodeLoad("node1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := NodeLoad{CPUNanoCores: 1500000000, NetworkBytesPerSecond: 4000, DiskIOBytesPerSecond: 20000}
+	if load != expected {
+		t.Errorf("expected load %+v, actual %+v", expected, load)
+	}
+	// The next loads are computed from the last sample
+	now = now.Add(10 * time.Second)
+	load, err = reader.NodeLoad("node1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = NodeLoad{CPUNanoCores: 1000000000, NetworkBytesPerSecond: 200, DiskIOBytesPerSecond: 2000}
+	if load != expected {
+		t.Errorf("expected load %+v, actual %+v", expected, load)
+	}
+}
+
+func TestNodeLoadNotReported(t *testing.T) {
+	restClient := &fake.RESTClient{
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		GroupVersion:         corev1.SchemeGroupVersion,
+		VersionedAPIPath:     "/api/v1",
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": []string{"application/json"}},
+				Body: io.NopCloser(bytes.NewBufferString(`{"node":{"nodeName":"node1"}}`))}, nil
+		}),
+	}
+	if _, err := NewLoadReader(restClient, time.Second, false).NodeLoad("node1"); err == nil {
+		t.Errorf("expected error when the CPU usage is not reported")
+	}
+}
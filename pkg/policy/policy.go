@@ -0,0 +1,250 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ValidateImageCache checks that the images, artifacts, node selectors and service
+// account of an image cache are allowed by the policies.
+func ValidateImageCache(imageCache *fledgedv1alpha2.ImageCache, policies []*fledgedv1alpha2.FledgedPolicy) error {
+	if sa := imageCache.Spec.ServiceAccountName; sa != "" && !serviceAccountAllowed(sa, policies) {
+		return fmt.Errorf("Service account %s is not allowed by any policy", sa)
+	}
+	for _, cacheSpec := range imageCache.Spec.CacheSpec {
+		for _, provider := range cacheSpec.Providers {
+			if err := ValidateProvider(imageCache.Namespace, provider, policies); err != nil {
+				return err
+			}
+		}
+	}
+	for _, p := range sortPolicies(policies) {
+		for _, cacheSpec := range imageCache.Spec.CacheSpec {
+			for _, image := range append(append([]string{}, cacheSpec.Images...), cacheSpec.Artifacts...) {
+				if !registryAllowed(image, p.Spec.AllowedRegistries) {
+					return fmt.Errorf("Image %s is not from a registry allowed by policy %s: allowed registries are %v",
+						image, p.Name, p.Spec.AllowedRegistries)
+				}
+			}
+			for _, r := range p.Spec.RestrictedNodeSelectors {
+				if targetsNodePool(cacheSpec.NodeSelector, r.NodeSelector) && !containsString(r.Namespaces, imageCache.Namespace) {
+					return fmt.Errorf("Namespace %s is not allowed by policy %s to target nodes with labels %v",
+						imageCache.Namespace, p.Name, r.NodeSelector)
+				}
+			}
+			for _, n := range p.Spec.NamespaceNodeSelectors {
+				if n.Namespace == imageCache.Namespace && !targetsAnyNodePool(cacheSpec.NodeSelector, n.NodeSelectors) {
+					return fmt.Errorf("Node selector %v of namespace %s does not select the nodes allowed by policy %s: allowed node selectors are %v",
+						cacheSpec.NodeSelector, imageCache.Namespace, p.Name, n.NodeSelectors)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// NodeAllowed returns false if the node belongs to a restricted node pool which the
+// image caches of the namespace may not target, or if it is outside the node pools the
+// namespace is restricted to. It is used to exclude such nodes from image cache entries
+// that do not explicitly select the node pool.
+func NodeAllowed(namespace string, node *corev1.Node, policies []*fledgedv1alpha2.FledgedPolicy) bool {
+	for _, p := range policies {
+		for _, r := range p.Spec.RestrictedNodeSelectors {
+			if labels.SelectorFromSet(r.NodeSelector).Matches(labels.Set(node.Labels)) && !containsString(r.Namespaces, namespace) {
+				return false
+			}
+		}
+		for _, n := range p.Spec.NamespaceNodeSelectors {
+			if n.Namespace == namespace && !inAnyNodePool(node, n.NodeSelectors) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// MaxNodesPerCache returns the lowest limit of nodes per image cache set by the
+// policies, or zero if there is no limit.
+func MaxNodesPerCache(policies []*fledgedv1alpha2.FledgedPolicy) int {
+	max := 0
+	for _, p := range policies {
+		if p.Spec.MaxNodesPerCache > 0 && (max == 0 || p.Spec.MaxNodesPerCache < max) {
+			max = p.Spec.MaxNodesPerCache
+		}
+	}
+	return max
+}
+
+// CostBudget returns the lowest cost budget set by the policies for the image cache
+// actions of the namespace, or nil if there is no budget.
+func CostBudget(namespace string, policies []*fledgedv1alpha2.FledgedPolicy) *resource.Quantity {
+	var budget *resource.Quantity
+	for _, p := range policies {
+		for i := range p.Spec.CostBudgets {
+			b := p.Spec.CostBudgets[i]
+			if b.Namespace == namespace && (budget == nil || b.Budget.Cmp(*budget) < 0) {
+				budget = &b.Budget
+			}
+		}
+	}
+	return budget
+}
+
+// ImagePullPolicy returns the default image pull policy set by the policies, or an
+// empty string if none is set.
+func ImagePullPolicy(policies []*fledgedv1alpha2.FledgedPolicy) corev1.PullPolicy {
+	for _, p := range sortPolicies(policies) {
+		if p.Spec.Defaults != nil && p.Spec.Defaults.ImagePullPolicy != "" {
+			return p.Spec.Defaults.ImagePullPolicy
+		}
+	}
+	return ""
+}
+
+// ImagePullDeadline returns the default image pull deadline set by the policies, or
+// zero if none is set.
+func ImagePullDeadline(policies []*fledgedv1alpha2.FledgedPolicy) time.Duration {
+	for _, p := range sortPolicies(policies) {
+		if p.Spec.Defaults != nil && p.Spec.Defaults.ImagePullDeadline != nil && p.Spec.Defaults.ImagePullDeadline.Duration > 0 {
+			return p.Spec.Defaults.ImagePullDeadline.Duration
+		}
+	}
+	return 0
+}
+
+// ValidateProvider checks that the image list provider of an image cache of a namespace
+// lists the images of the Helm releases, ImageLists and ImageStreams of its own
+// namespace, or of a namespace shared with it by a policy.
+func ValidateProvider(namespace string, provider fledgedv1alpha2.ImageListProviderSpec, policies []*fledgedv1alpha2.FledgedPolicy) error {
+	providerNamespace := providerNamespace(provider)
+	if providerNamespace == "" || providerNamespace == namespace {
+		return nil
+	}
+	for _, p := range policies {
+		for _, n := range p.Spec.ProviderNamespaces {
+			if n.Namespace == providerNamespace && (len(n.Namespaces) == 0 || containsString(n.Namespaces, namespace)) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("Namespace %s is not allowed by any policy to list the images of namespace %s", namespace, providerNamespace)
+}
+
+// providerNamespace returns the namespace of the Helm release, ImageList or ImageStream
+// of an image list provider, or an empty string for the other providers
+func providerNamespace(provider fledgedv1alpha2.ImageListProviderSpec) string {
+	switch {
+	case provider.HelmRelease != nil:
+		return provider.HelmRelease.Namespace
+	case provider.ImageList != nil:
+		return provider.ImageList.Namespace
+	case provider.ImageStream != nil:
+		return provider.ImageStream.Namespace
+	}
+	return ""
+}
+
+// serviceAccountAllowed returns true if a policy allows image caches to use the
+// service account
+func serviceAccountAllowed(serviceAccountName string, policies []*fledgedv1alpha2.FledgedPolicy) bool {
+	for _, p := range policies {
+		if containsString(p.Spec.AllowedServiceAccounts, serviceAccountName) {
+			return true
+		}
+	}
+	return false
+}
+
+// registryAllowed returns true if the image is from one of the allowed registries or
+// repository prefixes, or if no registries are listed.
+func registryAllowed(image string, allowedRegistries []string) bool {
+	if len(allowedRegistries) == 0 {
+		return true
+	}
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return false
+	}
+	name := named.Name()
+	for _, allowed := range allowedRegistries {
+		allowed = strings.TrimSuffix(allowed, "/")
+		if name == allowed || strings.HasPrefix(name, allowed+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// targetsNodePool returns true if the node selector of an image cache entry selects
+// nodes of the node pool, i.e. it requires all the labels of the node pool
+func targetsNodePool(nodeSelector map[string]string, nodePool map[string]string) bool {
+	if len(nodePool) == 0 {
+		return false
+	}
+	for key, value := range nodePool {
+		if nodeSelector[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// targetsAnyNodePool returns true if the node selector of an image cache entry selects
+// nodes of one of the node pools
+func targetsAnyNodePool(nodeSelector map[string]string, nodePools []map[string]string) bool {
+	for _, nodePool := range nodePools {
+		if targetsNodePool(nodeSelector, nodePool) {
+			return true
+		}
+	}
+	return false
+}
+
+// inAnyNodePool returns true if the node has the labels of one of the node pools
+func inAnyNodePool(node *corev1.Node, nodePools []map[string]string) bool {
+	for _, nodePool := range nodePools {
+		if labels.SelectorFromSet(nodePool).Matches(labels.Set(node.Labels)) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortPolicies(policies []*fledgedv1alpha2.FledgedPolicy) []*fledgedv1alpha2.FledgedPolicy {
+	sorted := append([]*fledgedv1alpha2.FledgedPolicy{}, policies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,297 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var gpuPolicy = &fledgedv1alpha2.FledgedPolicy{
+	ObjectMeta: metav1.ObjectMeta{Name: "gpu"},
+	Spec: fledgedv1alpha2.FledgedPolicySpec{
+		RestrictedNodeSelectors: []fledgedv1alpha2.RestrictedNodeSelector{
+			{NodeSelector: map[string]string{"node-pool": "gpu"}, Namespaces: []string{"ml-team"}},
+		},
+	},
+}
+
+var registryPolicy = &fledgedv1alpha2.FledgedPolicy{
+	ObjectMeta: metav1.ObjectMeta{Name: "registries"},
+	Spec: fledgedv1alpha2.FledgedPolicySpec{
+		AllowedRegistries: []string{"docker.io/library", "ghcr.io/"},
+	},
+}
+
+var teamPolicy = &fledgedv1alpha2.FledgedPolicy{
+	ObjectMeta: metav1.ObjectMeta{Name: "teams"},
+	Spec: fledgedv1alpha2.FledgedPolicySpec{
+		NamespaceNodeSelectors: []fledgedv1alpha2.NamespaceNodeSelector{
+			{Namespace: "team-a", NodeSelectors: []map[string]string{{"node-pool": "team-a"}, {"node-pool": "shared"}}},
+		},
+	},
+}
+
+var serviceAccountPolicy = &fledgedv1alpha2.FledgedPolicy{
+	ObjectMeta: metav1.ObjectMeta{Name: "service-accounts"},
+	Spec: fledgedv1alpha2.FledgedPolicySpec{
+		AllowedServiceAccounts: []string{"image-puller"},
+	},
+}
+
+var providerNamespacePolicy = &fledgedv1alpha2.FledgedPolicy{
+	ObjectMeta: metav1.ObjectMeta{Name: "provider-namespaces"},
+	Spec: fledgedv1alpha2.FledgedPolicySpec{
+		ProviderNamespaces: []fledgedv1alpha2.ProviderNamespace{
+			{Namespace: "platform"},
+			{Namespace: "monitoring", Namespaces: []string{"team-a"}},
+		},
+	},
+}
+
+func newPolicyTestImageCache(namespace string, nodeSelector map[string]string, images ...string) *fledgedv1alpha2.ImageCache {
+	return &fledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: namespace},
+		Spec: fledgedv1alpha2.ImageCacheSpec{
+			CacheSpec: []fledgedv1alpha2.CacheSpecImages{{Images: images, NodeSelector: nodeSelector}},
+		},
+	}
+}
+
+func TestValidateImageCache(t *testing.T) {
+	withServiceAccount := func(imageCache *fledgedv1alpha2.ImageCache, serviceAccountName string) *fledgedv1alpha2.ImageCache {
+		imageCache.Spec.ServiceAccountName = serviceAccountName
+		return imageCache
+	}
+	withProvider := func(imageCache *fledgedv1alpha2.ImageCache, provider fledgedv1alpha2.ImageListProviderSpec) *fledgedv1alpha2.ImageCache {
+		imageCache.Spec.CacheSpec[0].Providers = []fledgedv1alpha2.ImageListProviderSpec{provider}
+		return imageCache
+	}
+	tests := []struct {
+		name                string
+		imageCache          *fledgedv1alpha2.ImageCache
+		policies            []*fledgedv1alpha2.FledgedPolicy
+		expectedErrorString string
+	}{
+		{
+			name:       "#1: No policies",
+			imageCache: newPolicyTestImageCache("team-a", map[string]string{"node-pool": "gpu"}, "quay.io/foo:v1"),
+		},
+		{
+			name:       "#2: Images from allowed registries",
+			imageCache: newPolicyTestImageCache("team-a", nil, "nginx:1.23", "ghcr.io/foo/bar:v1"),
+			policies:   []*fledgedv1alpha2.FledgedPolicy{registryPolicy},
+		},
+		{
+			name:                "#3: Image from a registry that is not allowed",
+			imageCache:          newPolicyTestImageCache("team-a", nil, "nginx:1.23", "quay.io/foo:v1"),
+			policies:            []*fledgedv1alpha2.FledgedPolicy{registryPolicy},
+			expectedErrorString: "Image quay.io/foo:v1 is not from a registry allowed by policy registries",
+		},
+		{
+			name:                "#4: Repository prefix is not matched partially",
+			imageCache:          newPolicyTestImageCache("team-a", nil, "docker.io/libraryx/foo:v1"),
+			policies:            []*fledgedv1alpha2.FledgedPolicy{registryPolicy},
+			expectedErrorString: "is not from a registry allowed",
+		},
+		{
+			name:                "#5: Namespace not allowed to target restricted node pool",
+			imageCache:          newPolicyTestImageCache("team-a", map[string]string{"node-pool": "gpu", "zone": "a"}, "nginx:1.23"),
+			policies:            []*fledgedv1alpha2.FledgedPolicy{gpuPolicy},
+			expectedErrorString: "Namespace team-a is not allowed by policy gpu to target nodes",
+		},
+		{
+			name:       "#6: Namespace allowed to target restricted node pool",
+			imageCache: newPolicyTestImageCache("ml-team", map[string]string{"node-pool": "gpu"}, "nginx:1.23"),
+			policies:   []*fledgedv1alpha2.FledgedPolicy{gpuPolicy},
+		},
+		{
+			name:       "#7: Node selector not targeting restricted node pool",
+			imageCache: newPolicyTestImageCache("team-a", map[string]string{"node-pool": "cpu"}, "nginx:1.23"),
+			policies:   []*fledgedv1alpha2.FledgedPolicy{gpuPolicy},
+		},
+		{
+			name:       "#8: Namespace targets node pool assigned to it",
+			imageCache: newPolicyTestImageCache("team-a", map[string]string{"node-pool": "shared", "zone": "a"}, "nginx:1.23"),
+			policies:   []*fledgedv1alpha2.FledgedPolicy{teamPolicy},
+		},
+		{
+			name:                "#9: Namespace targets node pool not assigned to it",
+			imageCache:          newPolicyTestImageCache("team-a", map[string]string{"node-pool": "team-b"}, "nginx:1.23"),
+			policies:            []*fledgedv1alpha2.FledgedPolicy{teamPolicy},
+			expectedErrorString: "does not select the nodes allowed by policy teams",
+		},
+		{
+			name:                "#10: Namespace restricted to node pools targets all nodes",
+			imageCache:          newPolicyTestImageCache("team-a", nil, "nginx:1.23"),
+			policies:            []*fledgedv1alpha2.FledgedPolicy{teamPolicy},
+			expectedErrorString: "does not select the nodes allowed by policy teams",
+		},
+		{
+			name:       "#11: Namespace without node pools targets all nodes",
+			imageCache: newPolicyTestImageCache("team-b", nil, "nginx:1.23"),
+			policies:   []*fledgedv1alpha2.FledgedPolicy{teamPolicy},
+		},
+		{
+			name:       "#12: Service account allowed",
+			imageCache: withServiceAccount(newPolicyTestImageCache("team-b", nil, "nginx:1.23"), "image-puller"),
+			policies:   []*fledgedv1alpha2.FledgedPolicy{teamPolicy, serviceAccountPolicy},
+		},
+		{
+			name:                "#13: Service account not allowed",
+			imageCache:          withServiceAccount(newPolicyTestImageCache("team-a", nil, "nginx:1.23"), "kubefledged-controller"),
+			policies:            []*fledgedv1alpha2.FledgedPolicy{serviceAccountPolicy},
+			expectedErrorString: "Service account kubefledged-controller is not allowed by any policy",
+		},
+		{
+			name:                "#14: Service account without policies",
+			imageCache:          withServiceAccount(newPolicyTestImageCache("team-a", nil, "nginx:1.23"), "image-puller"),
+			expectedErrorString: "Service account image-puller is not allowed by any policy",
+		},
+		{
+			name: "#15: Helm release of the namespace of the image cache",
+			imageCache: withProvider(newPolicyTestImageCache("team-a", nil),
+				fledgedv1alpha2.ImageListProviderSpec{HelmRelease: &fledgedv1alpha2.HelmReleaseImageList{Namespace: "team-a", Name: "web"}}),
+		},
+		{
+			name: "#16: Helm release of another namespace without policies",
+			imageCache: withProvider(newPolicyTestImageCache("team-a", nil),
+				fledgedv1alpha2.ImageListProviderSpec{HelmRelease: &fledgedv1alpha2.HelmReleaseImageList{Namespace: "monitoring", Name: "prometheus"}}),
+			expectedErrorString: "Namespace team-a is not allowed by any policy to list the images of namespace monitoring",
+		},
+		{
+			name: "#17: Helm release of a namespace shared with the namespace",
+			imageCache: withProvider(newPolicyTestImageCache("team-a", nil),
+				fledgedv1alpha2.ImageListProviderSpec{HelmRelease: &fledgedv1alpha2.HelmReleaseImageList{Namespace: "monitoring", Name: "prometheus"}}),
+			policies: []*fledgedv1alpha2.FledgedPolicy{providerNamespacePolicy},
+		},
+		{
+			name: "#18: ImageStream of a namespace shared with other namespaces",
+			imageCache: withProvider(newPolicyTestImageCache("team-b", nil),
+				fledgedv1alpha2.ImageListProviderSpec{ImageStream: &fledgedv1alpha2.ImageStreamImageList{Namespace: "monitoring", Name: "prometheus"}}),
+			policies:            []*fledgedv1alpha2.FledgedPolicy{providerNamespacePolicy},
+			expectedErrorString: "Namespace team-b is not allowed by any policy to list the images of namespace monitoring",
+		},
+		{
+			name: "#19: ImageList of a namespace shared with all namespaces",
+			imageCache: withProvider(newPolicyTestImageCache("team-b", nil),
+				fledgedv1alpha2.ImageListProviderSpec{ImageList: &fledgedv1alpha2.ImageListReference{Namespace: "platform", Name: "base-images"}}),
+			policies: []*fledgedv1alpha2.FledgedPolicy{providerNamespacePolicy},
+		},
+	}
+	for _, test := range tests {
+		err := ValidateImageCache(test.imageCache, test.policies)
+		if test.expectedErrorString == "" {
+			if err != nil {
+				t.Errorf("Test: %s failed: expectedError=nil, actualError=%s", test.name, err.Error())
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.expectedErrorString) {
+			t.Errorf("Test: %s failed: expectedError=%s, actualError=%v", test.name, test.expectedErrorString, err)
+		}
+	}
+}
+
+func TestNodeAllowed(t *testing.T) {
+	gpuNode := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "gpu1", Labels: map[string]string{"node-pool": "gpu"}}}
+	cpuNode := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "cpu1", Labels: map[string]string{"node-pool": "cpu"}}}
+	teamNode := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "team-a1", Labels: map[string]string{"node-pool": "team-a"}}}
+	tests := []struct {
+		name      string
+		namespace string
+		node      *corev1.Node
+		expected  bool
+	}{
+		{name: "#1: Unrestricted node", namespace: "team-b", node: cpuNode, expected: true},
+		{name: "#2: Restricted node, namespace not allowed", namespace: "team-b", node: gpuNode, expected: false},
+		{name: "#3: Restricted node, namespace allowed", namespace: "ml-team", node: gpuNode, expected: true},
+		{name: "#4: Node in node pool of namespace", namespace: "team-a", node: teamNode, expected: true},
+		{name: "#5: Node outside node pools of namespace", namespace: "team-a", node: cpuNode, expected: false},
+	}
+	for _, test := range tests {
+		if actual := NodeAllowed(test.namespace, test.node, []*fledgedv1alpha2.FledgedPolicy{gpuPolicy, teamPolicy}); actual != test.expected {
+			t.Errorf("Test: %s failed: expected=%t, actual=%t", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestPolicyDefaults(t *testing.T) {
+	policies := []*fledgedv1alpha2.FledgedPolicy{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "b"},
+			Spec: fledgedv1alpha2.FledgedPolicySpec{
+				Defaults: &fledgedv1alpha2.FledgedPolicyDefaults{
+					ImagePullPolicy:   corev1.PullAlways,
+					ImagePullDeadline: &metav1.Duration{Duration: 10 * time.Minute},
+				},
+				MaxNodesPerCache: 10,
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "a"},
+			Spec: fledgedv1alpha2.FledgedPolicySpec{
+				Defaults:         &fledgedv1alpha2.FledgedPolicyDefaults{ImagePullPolicy: corev1.PullIfNotPresent},
+				MaxNodesPerCache: 20,
+			},
+		},
+	}
+	if actual := ImagePullPolicy(policies); actual != corev1.PullIfNotPresent {
+		t.Errorf("expected image pull policy %s, got %s", corev1.PullIfNotPresent, actual)
+	}
+	if actual := ImagePullDeadline(policies); actual != 10*time.Minute {
+		t.Errorf("expected image pull deadline 10m, got %s", actual)
+	}
+	if actual := MaxNodesPerCache(policies); actual != 10 {
+		t.Errorf("expected max nodes per cache 10, got %d", actual)
+	}
+	if ImagePullPolicy(nil) != "" || ImagePullDeadline(nil) != 0 || MaxNodesPerCache(nil) != 0 {
+		t.Errorf("expected no defaults without policies")
+	}
+}
+
+func TestCostBudget(t *testing.T) {
+	policies := []*fledgedv1alpha2.FledgedPolicy{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "a"},
+			Spec: fledgedv1alpha2.FledgedPolicySpec{
+				CostBudgets: []fledgedv1alpha2.NamespaceCostBudget{
+					{Namespace: "team-a", Budget: resource.MustParse("50")},
+					{Namespace: "team-b", Budget: resource.MustParse("10")},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "b"},
+			Spec: fledgedv1alpha2.FledgedPolicySpec{
+				CostBudgets: []fledgedv1alpha2.NamespaceCostBudget{{Namespace: "team-a", Budget: resource.MustParse("25.5")}},
+			},
+		},
+	}
+	if actual := CostBudget("team-a", policies); actual == nil || actual.Cmp(resource.MustParse("25.5")) != 0 {
+		t.Errorf("expected cost budget 25.5 for team-a, got %v", actual)
+	}
+	if actual := CostBudget("team-c", policies); actual != nil {
+		t.Errorf("expected no cost budget for team-c, got %v", actual)
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+// CatalogLister lists the repositories of registries
+type CatalogLister interface {
+	// ListRepositories lists the repositories of a registry with the credentials of the
+	// keychain, or else of the keychain of the lister if nil
+	ListRepositories(registry string, keychain authn.Keychain) ([]string, error)
+}
+
+// NewCatalogLister returns a CatalogLister listing repositories using the catalog API of
+// the registries (distribution spec), with the credentials of the keychain. Some public
+// registries (e.g. Docker Hub) do not serve the catalog API.
+func NewCatalogLister(timeout time.Duration, keychain authn.Keychain) CatalogLister {
+	return &registryClient{client: &http.Client{Timeout: timeout}, scheme: "https", keychain: keychain}
+}
+
+// catalog is the response of the catalog API
+type catalog struct {
+	Repositories []string `json:"repositories"`
+}
+
+// ListRepositories returns all the repositories of a registry e.g. "registry.internal"
+func (l *registryClient) ListRepositories(registry string, keychain authn.Keychain) ([]string, error) {
+	repositories := []string{}
+	client, err := l.clientFor(registry, "registry:catalog:*", keychain)
+	if err != nil {
+		return nil, fmt.Errorf("error listing repositories of %s: %v", registry, err)
+	}
+	err = getPages(client, fmt.Sprintf("%s://%s/v2/_catalog", l.scheme, registry), func(body io.Reader) error {
+		c := catalog{}
+		if err := json.NewDecoder(body).Decode(&c); err != nil {
+			return err
+		}
+		repositories = append(repositories, c.Repositories...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing repositories of %s: %v", registry, err)
+	}
+	return repositories, nil
+}
+
+// SplitRepositoryGlob splits a registry followed by a repository glob, e.g.
+// "registry.internal/platform/*", into the registry and the repository glob
+func SplitRepositoryGlob(repositories string) (string, string, error) {
+	i := strings.Index(repositories, "/")
+	if i <= 0 || i == len(repositories)-1 {
+		return "", "", fmt.Errorf("invalid repositories %q: expected a registry followed by a repository glob e.g. registry.internal/platform/*", repositories)
+	}
+	glob := repositories[i+1:]
+	if _, err := path.Match(glob, ""); err != nil {
+		return "", "", fmt.Errorf("invalid repository glob %q: %v", glob, err)
+	}
+	return repositories[:i], glob, nil
+}
+
+// MatchGlob returns the names matching the glob, e.g. "platform/*" or "v1.*", in the
+// order of names. A "*" does not match a "/", hence "platform/*" does not match the
+// repositories nested below platform/foo.
+func MatchGlob(names []string, glob string) ([]string, error) {
+	matched := []string{}
+	for _, name := range names {
+		ok, err := path.Match(glob, name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %v", glob, err)
+		}
+		if ok {
+			matched = append(matched, name)
+		}
+	}
+	return matched, nil
+}
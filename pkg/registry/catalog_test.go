@@ -0,0 +1,119 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListRepositories(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/token":
+			if r.URL.Query().Get("scope") != "registry:catalog:*" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"token":"secret"}`)
+		case "/v2/_catalog":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="registry:catalog:*"`, srv.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("last") == "" {
+				w.Header().Set("Link", `</v2/_catalog?last=platform/api&n=2>; rel="next"`)
+				fmt.Fprint(w, `{"repositories":["apps/web","platform/api"]}`)
+				return
+			}
+			fmt.Fprint(w, `{"repositories":["platform/worker"]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	lister := &registryClient{client: srv.Client(), scheme: "https"}
+	host := strings.TrimPrefix(srv.URL, "https://")
+	repositories, err := lister.ListRepositories(host, nil)
+	if err != nil {
+		t.Fatalf("ListRepositories() failed: %v", err)
+	}
+	if expected := []string{"apps/web", "platform/api", "platform/worker"}; !reflect.DeepEqual(repositories, expected) {
+		t.Errorf("expected repositories %v, got %v", expected, repositories)
+	}
+}
+
+func TestSplitRepositoryGlob(t *testing.T) {
+	tests := []struct {
+		name                string
+		repositories        string
+		expectedRegistry    string
+		expectedGlob        string
+		expectedErrorString string
+	}{
+		{name: "#1: Registry and glob", repositories: "registry.internal/platform/*", expectedRegistry: "registry.internal", expectedGlob: "platform/*"},
+		{name: "#2: Registry with port", repositories: "localhost:5000/*", expectedRegistry: "localhost:5000", expectedGlob: "*"},
+		{name: "#3: No glob", repositories: "registry.internal/", expectedErrorString: "expected a registry followed by a repository glob"},
+		{name: "#4: No registry", repositories: "platform", expectedErrorString: "expected a registry followed by a repository glob"},
+		{name: "#5: Invalid glob", repositories: "registry.internal/platform/[", expectedErrorString: "invalid repository glob"},
+	}
+	for _, test := range tests {
+		registry, glob, err := SplitRepositoryGlob(test.repositories)
+		if test.expectedErrorString != "" {
+			if err == nil || !strings.Contains(err.Error(), test.expectedErrorString) {
+				t.Errorf("Test: %s failed: expectedError=%s, actualError=%v", test.name, test.expectedErrorString, err)
+			}
+			continue
+		}
+		if err != nil || registry != test.expectedRegistry || glob != test.expectedGlob {
+			t.Errorf("Test: %s failed: expected=%s %s, actual=%s %s (%v)", test.name, test.expectedRegistry, test.expectedGlob, registry, glob, err)
+		}
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	names := []string{"platform/api", "platform/worker", "platform/tools/cli", "apps/web"}
+	tests := []struct {
+		name     string
+		glob     string
+		expected []string
+	}{
+		{name: "#1: Repositories below a path", glob: "platform/*", expected: []string{"platform/api", "platform/worker"}},
+		{name: "#2: Nested repositories", glob: "platform/*/*", expected: []string{"platform/tools/cli"}},
+		{name: "#3: No match", glob: "infra/*", expected: []string{}},
+	}
+	for _, test := range tests {
+		matched, err := MatchGlob(names, test.glob)
+		if err != nil {
+			t.Errorf("Test: %s failed: unexpected error %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(matched, test.expected) {
+			t.Errorf("Test: %s failed: expected=%v, actual=%v", test.name, test.expected, matched)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/opencontainers/go-digest"
+)
+
+// DigestResolver resolves the tags of images to digests in the registries
+type DigestResolver interface {
+	// ResolveDigest returns the digest of the manifest, or image index, an image e.g.
+	// "nginx:1.23" refers to, with the credentials of the keychain, or else of the
+	// keychain of the resolver if nil. The digest of an image referenced by digest is
+	// returned as is.
+	ResolveDigest(image string, keychain authn.Keychain) (string, error)
+}
+
+// NewDigestResolver returns a DigestResolver reading the image manifests from the
+// registries, with the credentials of the keychain
+func NewDigestResolver(timeout time.Duration, keychain authn.Keychain) DigestResolver {
+	return &registryClient{client: &http.Client{Timeout: timeout}, scheme: "https", keychain: keychain}
+}
+
+// ResolveDigest returns the digest of the manifest of an image, as reported by the
+// registry or computed from the manifest if the registry does not report it
+func (l *registryClient) ResolveDigest(image string, keychain authn.Keychain) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("invalid image %q: %v", image, err)
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		return digested.Digest().String(), nil
+	}
+	named = reference.TagNameOnly(named)
+	client, err := l.clientFor(registryHost(named), "repository:"+reference.Path(named)+":pull", keychain)
+	if err != nil {
+		return "", fmt.Errorf("error resolving digest of %s: %v", image, err)
+	}
+	u := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", l.scheme, registryHost(named), reference.Path(named), named.(reference.Tagged).Tag())
+	resp, err := get(client, u, manifestMediaTypes...)
+	if err != nil {
+		return "", fmt.Errorf("error resolving digest of %s: %v", image, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error resolving digest of %s: registry returned %s", image, resp.Status)
+	}
+	if d, err := digest.Parse(resp.Header.Get("Docker-Content-Digest")); err == nil {
+		return d.String(), nil
+	}
+	d, err := digest.FromReader(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error resolving digest of %s: %v", image, err)
+	}
+	return d.String(), nil
+}
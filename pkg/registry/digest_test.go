@@ -0,0 +1,91 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+)
+
+func TestResolveDigest(t *testing.T) {
+	unreported := `{"config":{"digest":"sha256:config","size":10},"layers":[]}`
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/":
+			// anonymous access
+		case "/v2/foo/bar/manifests/v1":
+			w.Header().Set("Docker-Content-Digest", "sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31")
+			fmt.Fprint(w, `{"manifests":[]}`)
+		case "/v2/foo/unreported/manifests/v1":
+			fmt.Fprint(w, unreported)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	resolver := &registryClient{client: srv.Client(), scheme: "https"}
+	host := strings.TrimPrefix(srv.URL, "https://")
+	tests := []struct {
+		name                string
+		image               string
+		expectedDigest      string
+		expectedErrorString string
+	}{
+		{
+			name:           "#1: Digest reported by the registry",
+			image:          host + "/foo/bar:v1",
+			expectedDigest: "sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31",
+		},
+		{
+			name:           "#2: Digest computed from the manifest",
+			image:          host + "/foo/unreported:v1",
+			expectedDigest: digest.FromString(unreported).String(),
+		},
+		{
+			name:           "#3: Image referenced by digest",
+			image:          host + "/foo/pinned@sha256:7031c1b283388d2c2e09b57badb803c05ebed362dc88d84b480cc47f72a21097",
+			expectedDigest: "sha256:7031c1b283388d2c2e09b57badb803c05ebed362dc88d84b480cc47f72a21097",
+		},
+		{
+			name:                "#4: Missing image",
+			image:               host + "/foo/missing:v1",
+			expectedErrorString: "error resolving digest of " + host + "/foo/missing:v1",
+		},
+	}
+	for _, test := range tests {
+		d, err := resolver.ResolveDigest(test.image, nil)
+		if test.expectedErrorString != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), test.expectedErrorString) {
+				t.Errorf("Test: %s failed: expectedError=%s, actualError=%v", test.name, test.expectedErrorString, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test: %s failed: %v", test.name, err)
+			continue
+		}
+		if d != test.expectedDigest {
+			t.Errorf("Test: %s failed: expectedDigest=%s, actualDigest=%s", test.name, test.expectedDigest, d)
+		}
+	}
+}
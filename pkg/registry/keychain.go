@@ -0,0 +1,221 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/go-containerregistry/pkg/authn"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// dockerHubRegistry is the name of the registry of Docker Hub used by the keychains
+const dockerHubRegistry = "index.docker.io"
+
+// NewKeychain returns the keychain of the registry operations of the controller. The
+// credentials of a registry are looked up, in order, in the image pull secrets of the
+// service account and the pull secrets of the namespace, then in the docker config file
+// of the controller ($DOCKER_CONFIG/config.json), e.g. the credentials of the nodes
+// mounted from /var/lib/kubelet/config.json. The credential helpers of the docker
+// config file (e.g. docker-credential-ecr-login) provide the credentials of cloud
+// registries. Registries without credentials are accessed anonymously. The secrets are
+// read again once older than refresh.
+func NewKeychain(kubeclientset kubernetes.Interface, namespace string, serviceAccountName string,
+	pullSecrets []string, refresh time.Duration) authn.Keychain {
+	return authn.NewMultiKeychain(&pullSecretKeychain{
+		kubeclientset:      kubeclientset,
+		namespace:          namespace,
+		serviceAccountName: serviceAccountName,
+		pullSecrets:        pullSecrets,
+		refresh:            refresh,
+	}, authn.DefaultKeychain)
+}
+
+// Keychains returns the keychains of the registry operations for the images of image
+// caches. The credentials of a registry are looked up in the image pull secrets of the
+// image cache, read from its namespace, then in the keychain of the controller.
+type Keychains struct {
+	kubeclientset kubernetes.Interface
+	keychain      authn.Keychain
+	refresh       time.Duration
+
+	lock      sync.Mutex
+	keychains map[string]authn.Keychain
+}
+
+// NewKeychains returns the keychains of image caches, falling back to the keychain of
+// the controller. The secrets are read again once older than refresh.
+func NewKeychains(kubeclientset kubernetes.Interface, keychain authn.Keychain, refresh time.Duration) *Keychains {
+	return &Keychains{kubeclientset: kubeclientset, keychain: keychain, refresh: refresh, keychains: map[string]authn.Keychain{}}
+}
+
+// For returns the keychain of the image pull secrets of a namespace, built once per
+// namespace and image pull secrets. The keychain of the controller is returned if there
+// are no image pull secrets. A nil Keychains returns a nil keychain.
+func (k *Keychains) For(namespace string, pullSecrets []corev1.LocalObjectReference) authn.Keychain {
+	if k == nil {
+		return nil
+	}
+	if len(pullSecrets) == 0 {
+		return k.keychain
+	}
+	names := make([]string, 0, len(pullSecrets))
+	for _, secret := range pullSecrets {
+		names = append(names, secret.Name)
+	}
+	key := namespace + "/" + strings.Join(names, ",")
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if keychain, ok := k.keychains[key]; ok {
+		return keychain
+	}
+	var keychain authn.Keychain = &pullSecretKeychain{
+		kubeclientset: k.kubeclientset,
+		namespace:     namespace,
+		pullSecrets:   names,
+		refresh:       k.refresh,
+	}
+	if k.keychain != nil {
+		keychain = authn.NewMultiKeychain(keychain, k.keychain)
+	}
+	k.keychains[key] = keychain
+	return keychain
+}
+
+// pullSecretKeychain resolves the credentials of registries from image pull secrets of
+// type kubernetes.io/dockerconfigjson or kubernetes.io/dockercfg
+type pullSecretKeychain struct {
+	kubeclientset      kubernetes.Interface
+	namespace          string
+	serviceAccountName string
+	pullSecrets        []string
+	refresh            time.Duration
+
+	lock   sync.Mutex
+	auths  map[string]authn.AuthConfig
+	loaded time.Time
+}
+
+// dockerConfigJSON is the content of a kubernetes.io/dockerconfigjson secret
+type dockerConfigJSON struct {
+	Auths map[string]authn.AuthConfig `json:"auths"`
+}
+
+// Resolve returns the credentials of the registry of a resource, or anonymous if no
+// pull secret has credentials for the registry
+func (k *pullSecretKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	auths := k.credentials()
+	registry := target.RegistryStr()
+	if auth, ok := auths[registry]; ok {
+		return authn.FromConfig(auth), nil
+	}
+	// registries given as globs e.g. *.azurecr.io
+	for pattern, auth := range auths {
+		if matched, _ := path.Match(pattern, registry); matched {
+			return authn.FromConfig(auth), nil
+		}
+	}
+	return authn.Anonymous, nil
+}
+
+// credentials returns the credentials of the pull secrets by registry, reading the
+// secrets again if they are older than the refresh interval
+func (k *pullSecretKeychain) credentials() map[string]authn.AuthConfig {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if k.auths != nil && time.Since(k.loaded) < k.refresh {
+		return k.auths
+	}
+	names := append([]string{}, k.pullSecrets...)
+	if k.serviceAccountName != "" {
+		serviceAccount, err := k.kubeclientset.CoreV1().ServiceAccounts(k.namespace).Get(context.TODO(), k.serviceAccountName, metav1.GetOptions{})
+		if err != nil {
+			glog.Warningf("Error getting service account %s/%s for its image pull secrets: %v", k.namespace, k.serviceAccountName, err)
+		} else {
+			for _, secret := range serviceAccount.ImagePullSecrets {
+				names = append(names, secret.Name)
+			}
+		}
+	}
+	k.auths = map[string]authn.AuthConfig{}
+	for _, name := range names {
+		secret, err := k.kubeclientset.CoreV1().Secrets(k.namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			glog.Warningf("Error getting image pull secret %s/%s: %v", k.namespace, name, err)
+			continue
+		}
+		auths, err := parsePullSecret(secret)
+		if err != nil {
+			glog.Warningf("Error parsing image pull secret %s/%s: %v", k.namespace, name, err)
+			continue
+		}
+		for registry, auth := range auths {
+			// the first pull secret with credentials for a registry wins
+			if _, ok := k.auths[registry]; !ok {
+				k.auths[registry] = auth
+			}
+		}
+	}
+	k.loaded = time.Now()
+	return k.auths
+}
+
+// parsePullSecret returns the credentials of an image pull secret by registry
+func parsePullSecret(secret *corev1.Secret) (map[string]authn.AuthConfig, error) {
+	auths := map[string]authn.AuthConfig{}
+	switch secret.Type {
+	case corev1.SecretTypeDockerConfigJson:
+		config := dockerConfigJSON{}
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+			return nil, err
+		}
+		auths = config.Auths
+	case corev1.SecretTypeDockercfg:
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &auths); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+	normalized := map[string]authn.AuthConfig{}
+	for key, auth := range auths {
+		normalized[registryOfConfigKey(key)] = auth
+	}
+	return normalized, nil
+}
+
+// registryOfConfigKey returns the registry of a key of a docker config file, which may
+// be a URL e.g. https://index.docker.io/v1/ or a registry followed by a path
+func registryOfConfigKey(key string) string {
+	registry := strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+	if i := strings.Index(registry, "/"); i >= 0 {
+		registry = registry[:i]
+	}
+	switch registry {
+	case "docker.io", "registry-1.docker.io":
+		return dockerHubRegistry
+	}
+	return registry
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func newPullSecret(name string, secretType corev1.SecretType, key, data string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-fledged"},
+		Type:       secretType,
+		Data:       map[string][]byte{key: []byte(data)},
+	}
+}
+
+func TestPullSecretKeychain(t *testing.T) {
+	kubeclientset := fakeclientset.NewSimpleClientset(
+		&corev1.ServiceAccount{
+			ObjectMeta:       metav1.ObjectMeta{Name: "sa-kube-fledged", Namespace: "kube-fledged"},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "acr"}, {Name: "missing"}},
+		},
+		newPullSecret("hub", corev1.SecretTypeDockerConfigJson, corev1.DockerConfigJsonKey,
+			`{"auths":{"https://index.docker.io/v1/":{"auth":"aHViOnNlY3JldA=="},"registry.internal:5000":{"username":"internal","password":"secret"}}}`),
+		newPullSecret("acr", corev1.SecretTypeDockercfg, corev1.DockerConfigKey,
+			`{"*.azurecr.io":{"username":"acr","password":"secret"}}`),
+	)
+	keychain := &pullSecretKeychain{
+		kubeclientset:      kubeclientset,
+		namespace:          "kube-fledged",
+		serviceAccountName: "sa-kube-fledged",
+		pullSecrets:        []string{"hub"},
+		refresh:            time.Minute,
+	}
+	tests := []struct {
+		name           string
+		registry       string
+		expectedConfig *authn.AuthConfig
+	}{
+		{
+			name:           "#1: Docker Hub credentials of a docker config URL",
+			registry:       "docker.io",
+			expectedConfig: &authn.AuthConfig{Username: "hub", Password: "secret", Auth: "aHViOnNlY3JldA=="},
+		},
+		{
+			name:           "#2: Credentials of a registry with port",
+			registry:       "registry.internal:5000",
+			expectedConfig: &authn.AuthConfig{Username: "internal", Password: "secret", Auth: "aW50ZXJuYWw6c2VjcmV0"},
+		},
+		{
+			name:           "#3: Credentials of a registry glob in a pull secret of the service account",
+			registry:       "team.azurecr.io",
+			expectedConfig: &authn.AuthConfig{Username: "acr", Password: "secret", Auth: "YWNyOnNlY3JldA=="},
+		},
+		{
+			name:     "#4: Registry without credentials",
+			registry: "ghcr.io",
+		},
+	}
+	for _, test := range tests {
+		registry, err := name.NewRegistry(test.registry)
+		if err != nil {
+			t.Fatalf("Test: %s failed: %v", test.name, err)
+		}
+		auth, err := keychain.Resolve(registry)
+		if err != nil {
+			t.Errorf("Test: %s failed: %v", test.name, err)
+			continue
+		}
+		if test.expectedConfig == nil {
+			if auth != authn.Anonymous {
+				t.Errorf("Test: %s failed: expected anonymous access, actual %+v", test.name, auth)
+			}
+			continue
+		}
+		config, err := auth.Authorization()
+		if err != nil || !reflect.DeepEqual(config, test.expectedConfig) {
+			t.Errorf("Test: %s failed: expected credentials %+v, actual %+v (error %v)", test.name, test.expectedConfig, config, err)
+		}
+	}
+	// The secrets are read once per refresh interval
+	gets := 0
+	for _, action := range kubeclientset.Actions() {
+		if action.GetVerb() == "get" && action.GetResource().Resource == "secrets" {
+			gets++
+		}
+	}
+	if gets != 3 {
+		t.Errorf("expected 3 gets of secrets, actual %d", gets)
+	}
+}
+
+func TestRegistryClientCredentials(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/token":
+			if user, password, ok := r.BasicAuth(); !ok || user != "internal" || password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"token":"private"}`)
+		case "/v2/private/app/tags/list":
+			if r.Header.Get("Authorization") != "Bearer private" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"name":"private/app","tags":["v1"]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+	teamSecret := newPullSecret("team", corev1.SecretTypeDockerConfigJson, corev1.DockerConfigJsonKey,
+		fmt.Sprintf(`{"auths":{"%s":{"username":"internal","password":"secret"}}}`, host))
+	teamSecret.Namespace = "team-a"
+	kubeclientset := fakeclientset.NewSimpleClientset(newPullSecret("internal", corev1.SecretTypeDockerConfigJson, corev1.DockerConfigJsonKey,
+		fmt.Sprintf(`{"auths":{"%s":{"username":"internal","password":"secret"}}}`, host)), teamSecret)
+
+	anonymous := &registryClient{client: srv.Client(), scheme: "https"}
+	if _, err := anonymous.ListTags(host+"/private/app", nil); err == nil {
+		t.Errorf("expected anonymous access to be denied")
+	}
+	lister := &registryClient{client: srv.Client(), scheme: "https",
+		keychain: NewKeychain(kubeclientset, "kube-fledged", "", []string{"internal"}, time.Minute)}
+	tags, err := lister.ListTags(host+"/private/app", nil)
+	if err != nil || !reflect.DeepEqual(tags, []string{"v1"}) {
+		t.Errorf("expected tags of private repository, actual %v (error %v)", tags, err)
+	}
+
+	// The image pull secrets of an image cache are read from its namespace
+	keychains := NewKeychains(kubeclientset, nil, time.Minute)
+	if _, err := anonymous.ListTags(host+"/private/app", keychains.For("team-b", []corev1.LocalObjectReference{{Name: "team"}})); err == nil {
+		t.Errorf("expected access with the pull secret of another namespace to be denied")
+	}
+	tags, err = anonymous.ListTags(host+"/private/app", keychains.For("team-a", []corev1.LocalObjectReference{{Name: "team"}}))
+	if err != nil || !reflect.DeepEqual(tags, []string{"v1"}) {
+		t.Errorf("expected tags of private repository with the pull secret of the image cache, actual %v (error %v)", tags, err)
+	}
+	if keychains.For("team-a", nil) != nil {
+		t.Errorf("expected the keychain of the controller for an image cache without pull secrets")
+	}
+}
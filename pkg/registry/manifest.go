@@ -0,0 +1,181 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+// ImageSizer returns the size of images in the registries
+type ImageSizer interface {
+	// ImageSize returns the size of the config and the (compressed) layers of an image
+	// for the platform (os/arch[/variant] e.g. linux/arm64), i.e. the bytes downloaded
+	// from the registry when pulling the image to a node not holding any of its layers.
+	// The credentials of the keychain are used, or else of the keychain of the sizer if
+	// nil.
+	ImageSize(image string, platform string, keychain authn.Keychain) (int64, error)
+}
+
+// LayerLister returns the layers of images in the registries
+type LayerLister interface {
+	// ImageLayers returns the config and the (compressed) layers of an image for the
+	// platform (os/arch[/variant] e.g. linux/arm64). The credentials of the keychain
+	// are used, or else of the keychain of the lister if nil.
+	ImageLayers(image string, platform string, keychain authn.Keychain) ([]Layer, error)
+}
+
+// Layer is a blob of an image: its config or one of its layers
+type Layer struct {
+	Digest string
+	Size   int64
+}
+
+// NewLayerLister returns a LayerLister reading the image manifests from the registries,
+// with the credentials of the keychain
+func NewLayerLister(timeout time.Duration, keychain authn.Keychain) LayerLister {
+	return &registryClient{client: &http.Client{Timeout: timeout}, scheme: "https", keychain: keychain}
+}
+
+// NewImageSizer returns an ImageSizer reading the image manifests from the registries,
+// with the credentials of the keychain
+func NewImageSizer(timeout time.Duration, keychain authn.Keychain) ImageSizer {
+	return &registryClient{client: &http.Client{Timeout: timeout}, scheme: "https", keychain: keychain}
+}
+
+// manifestMediaTypes are the media types of the image manifests and image indexes
+// (manifest lists) accepted from the registries
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// descriptor describes a blob or a manifest
+type descriptor struct {
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
+// manifest is an image manifest or an image index
+type manifest struct {
+	Config    descriptor   `json:"config"`
+	Layers    []descriptor `json:"layers"`
+	Manifests []struct {
+		descriptor
+		Platform struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+			Variant      string `json:"variant"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+// ImageSize returns the size of an image e.g. "nginx:1.23" or "ghcr.io/foo/bar@sha256:..."
+func (l *registryClient) ImageSize(image string, platform string, keychain authn.Keychain) (int64, error) {
+	m, err := l.imageManifest(image, platform, keychain)
+	if err != nil {
+		return 0, err
+	}
+	size := m.Config.Size
+	for _, layer := range m.Layers {
+		size += layer.Size
+	}
+	return size, nil
+}
+
+// ImageLayers returns the config and the layers of an image e.g. "nginx:1.23"
+func (l *registryClient) ImageLayers(image string, platform string, keychain authn.Keychain) ([]Layer, error) {
+	m, err := l.imageManifest(image, platform, keychain)
+	if err != nil {
+		return nil, err
+	}
+	layers := []Layer{{Digest: m.Config.Digest, Size: m.Config.Size}}
+	for _, layer := range m.Layers {
+		layers = append(layers, Layer{Digest: layer.Digest, Size: layer.Size})
+	}
+	return layers, nil
+}
+
+// imageManifest returns the image manifest of an image for the platform, resolving the
+// image index if the image has one
+func (l *registryClient) imageManifest(image string, platform string, keychain authn.Keychain) (*manifest, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image %q: %v", image, err)
+	}
+	named = reference.TagNameOnly(named)
+	ref := ""
+	if digested, ok := named.(reference.Digested); ok {
+		ref = digested.Digest().String()
+	} else if tagged, ok := named.(reference.Tagged); ok {
+		ref = tagged.Tag()
+	}
+	client, err := l.clientFor(registryHost(named), "repository:"+reference.Path(named)+":pull", keychain)
+	if err != nil {
+		return nil, fmt.Errorf("error getting manifest of %s: %v", image, err)
+	}
+	base := fmt.Sprintf("%s://%s/v2/%s/manifests/", l.scheme, registryHost(named), reference.Path(named))
+	m, err := getManifest(client, base+ref)
+	if err != nil {
+		return nil, fmt.Errorf("error getting manifest of %s: %v", image, err)
+	}
+	if len(m.Manifests) > 0 {
+		digest := ""
+		for _, d := range m.Manifests {
+			p := d.Platform.OS + "/" + d.Platform.Architecture
+			if platform == p || (d.Platform.Variant != "" && platform == p+"/"+d.Platform.Variant) {
+				digest = d.Digest
+				break
+			}
+		}
+		if digest == "" {
+			return nil, fmt.Errorf("image %s has no manifest for platform %s", image, platform)
+		}
+		if m, err = getManifest(client, base+digest); err != nil {
+			return nil, fmt.Errorf("error getting manifest of %s for platform %s: %v", image, platform, err)
+		}
+	}
+	return m, nil
+}
+
+// getManifest gets a manifest
+func getManifest(client *http.Client, u string) (*manifest, error) {
+	resp, err := get(client, u, manifestMediaTypes...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry returned %s", resp.Status)
+	}
+	if mediaType := resp.Header.Get("Content-Type"); strings.Contains(mediaType, "manifest.v1+prettyjws") {
+		return nil, fmt.Errorf("unsupported manifest media type %s", mediaType)
+	}
+	m := &manifest{}
+	if err := json.NewDecoder(resp.Body).Decode(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
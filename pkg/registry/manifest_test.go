@@ -0,0 +1,147 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImageSize(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/token":
+			fmt.Fprint(w, `{"token":"secret"}`)
+		case "/v2/foo/bar/manifests/v1", "/v2/foo/bar/manifests/sha256:amd64", "/v2/foo/bar/manifests/sha256:armv7":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:foo/bar:pull"`, srv.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			switch strings.TrimPrefix(r.URL.Path, "/v2/foo/bar/manifests/") {
+			case "v1":
+				fmt.Fprint(w, `{"manifests":[
+					{"digest":"sha256:amd64","size":500,"platform":{"os":"linux","architecture":"amd64"}},
+					{"digest":"sha256:armv7","size":500,"platform":{"os":"linux","architecture":"arm","variant":"v7"}}]}`)
+			case "sha256:amd64":
+				fmt.Fprint(w, `{"config":{"size":10},"layers":[{"size":100},{"size":200}]}`)
+			case "sha256:armv7":
+				fmt.Fprint(w, `{"config":{"size":10},"layers":[{"size":50}]}`)
+			}
+		case "/v2/foo/single/manifests/v1":
+			fmt.Fprint(w, `{"config":{"size":5},"layers":[{"size":1000}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	sizer := &registryClient{client: srv.Client(), scheme: "https"}
+	host := strings.TrimPrefix(srv.URL, "https://")
+	tests := []struct {
+		name                string
+		image               string
+		platform            string
+		expectedSize        int64
+		expectedErrorString string
+	}{
+		{
+			name:         "#1: Image index",
+			image:        host + "/foo/bar:v1",
+			platform:     "linux/amd64",
+			expectedSize: 310,
+		},
+		{
+			name:         "#2: Image index with platform variant",
+			image:        host + "/foo/bar:v1",
+			platform:     "linux/arm/v7",
+			expectedSize: 60,
+		},
+		{
+			name:                "#3: Platform not in image index",
+			image:               host + "/foo/bar:v1",
+			platform:            "windows/amd64",
+			expectedErrorString: "image " + host + "/foo/bar:v1 has no manifest for platform windows/amd64",
+		},
+		{
+			name:         "#4: Single platform image",
+			image:        host + "/foo/single:v1",
+			platform:     "linux/arm64",
+			expectedSize: 1005,
+		},
+		{
+			name:                "#5: Missing image",
+			image:               host + "/foo/missing:v1",
+			platform:            "linux/amd64",
+			expectedErrorString: "error getting manifest of " + host + "/foo/missing:v1",
+		},
+	}
+	for _, test := range tests {
+		size, err := sizer.ImageSize(test.image, test.platform, nil)
+		if test.expectedErrorString != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), test.expectedErrorString) {
+				t.Errorf("Test: %s failed: expectedError=%s, actualError=%v", test.name, test.expectedErrorString, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test: %s failed: %v", test.name, err)
+			continue
+		}
+		if size != test.expectedSize {
+			t.Errorf("Test: %s failed: expectedSize=%d, actualSize=%d", test.name, test.expectedSize, size)
+		}
+	}
+}
+
+func TestImageLayers(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/":
+			// anonymous access
+		case "/v2/foo/bar/manifests/v1":
+			fmt.Fprint(w, `{"manifests":[{"digest":"sha256:amd64","size":500,"platform":{"os":"linux","architecture":"amd64"}}]}`)
+		case "/v2/foo/bar/manifests/sha256:amd64":
+			fmt.Fprint(w, `{"config":{"digest":"sha256:config","size":10},"layers":[{"digest":"sha256:base","size":100},{"digest":"sha256:app","size":200}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	lister := &registryClient{client: srv.Client(), scheme: "https"}
+	host := strings.TrimPrefix(srv.URL, "https://")
+	layers, err := lister.ImageLayers(host+"/foo/bar:v1", "linux/amd64", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []Layer{{Digest: "sha256:config", Size: 10}, {Digest: "sha256:base", Size: 100}, {Digest: "sha256:app", Size: 200}}
+	if !reflect.DeepEqual(layers, expected) {
+		t.Errorf("expected layers %+v, actual %+v", expected, layers)
+	}
+	if _, err := lister.ImageLayers(host+"/foo/missing:v1", "linux/amd64", nil); err == nil {
+		t.Errorf("expected an error for a missing image")
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/docker/distribution/reference"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// TagLister lists the tags of image repositories
+type TagLister interface {
+	// ListTags lists the tags of a repository with the credentials of the keychain, or
+	// else of the keychain of the lister if nil
+	ListTags(repository string, keychain authn.Keychain) ([]string, error)
+}
+
+// NewTagLister returns a TagLister listing tags using the tags API of the registries
+// (distribution spec), with the credentials of the keychain
+func NewTagLister(timeout time.Duration, keychain authn.Keychain) TagLister {
+	return &registryClient{client: &http.Client{Timeout: timeout}, scheme: "https", keychain: keychain}
+}
+
+// registryClient accesses the registries using the distribution spec API, with the
+// credentials of its keychain
+type registryClient struct {
+	client   *http.Client
+	scheme   string
+	keychain authn.Keychain
+}
+
+// tagList is the response of the tags API
+type tagList struct {
+	Tags []string `json:"tags"`
+}
+
+var linkPattern = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="next"`)
+
+// ListTags returns all the tags of a repository e.g. "nginx" or "ghcr.io/foo/bar"
+func (l *registryClient) ListTags(repository string, keychain authn.Keychain) ([]string, error) {
+	named, err := reference.ParseNormalizedNamed(repository)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repository %q: %v", repository, err)
+	}
+	tags := []string{}
+	client, err := l.clientFor(registryHost(named), "repository:"+reference.Path(named)+":pull", keychain)
+	if err != nil {
+		return nil, fmt.Errorf("error listing tags of %s: %v", repository, err)
+	}
+	first := fmt.Sprintf("%s://%s/v2/%s/tags/list", l.scheme, registryHost(named), reference.Path(named))
+	err = getPages(client, first, func(body io.Reader) error {
+		list := tagList{}
+		if err := json.NewDecoder(body).Decode(&list); err != nil {
+			return err
+		}
+		tags = append(tags, list.Tags...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing tags of %s: %v", repository, err)
+	}
+	return tags, nil
+}
+
+// clientFor returns an http client accessing a registry with the credentials of the
+// keychain, or else of the keychain of the client if nil, for a scope, e.g.
+// repository:foo/bar:pull. The client performs the token exchange requested by the
+// registry, if any.
+func (l *registryClient) clientFor(registry string, scope string, keychain authn.Keychain) (*http.Client, error) {
+	reg, err := name.NewRegistry(registry)
+	if err != nil {
+		return nil, err
+	}
+	if keychain == nil {
+		keychain = l.keychain
+	}
+	auth := authn.Anonymous
+	if keychain != nil {
+		if auth, err = keychain.Resolve(reg); err != nil {
+			return nil, fmt.Errorf("error resolving credentials of %s: %v", registry, err)
+		}
+	}
+	base := l.client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	ctx := context.Background()
+	if l.client.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.client.Timeout)
+		defer cancel()
+	}
+	t, err := transport.NewWithContext(ctx, reg, auth, base, []string{scope})
+	if err != nil {
+		return nil, fmt.Errorf("error authenticating: %v", err)
+	}
+	return &http.Client{Transport: t, Timeout: l.client.Timeout}, nil
+}
+
+// getPages gets the pages of a paginated API response, following the Link headers
+// (distribution spec), and passes the body of every page to decode
+func getPages(client *http.Client, next string, decode func(body io.Reader) error) error {
+	for next != "" {
+		resp, err := get(client, next)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("registry returned %s", resp.Status)
+		}
+		err = decode(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("error decoding response: %v", err)
+		}
+		next = ""
+		if m := linkPattern.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+			u, err := resp.Request.URL.Parse(m[1])
+			if err != nil {
+				return err
+			}
+			next = u.String()
+		}
+	}
+	return nil
+}
+
+// registryHost returns the host serving the API of the registry of a reference
+func registryHost(named reference.Named) string {
+	host := reference.Domain(named)
+	if host == "docker.io" {
+		host = dockerHubRegistry
+	}
+	return host
+}
+
+func get(client *http.Client, u string, accept ...string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range accept {
+		req.Header.Add("Accept", a)
+	}
+	return client.Do(req)
+}
+
+// MatchTags returns the tags that are semantic versions satisfying the constraint
+// (e.g. "1.25.*" or ">= 1.2, < 2"), highest version first. Tags which are not semantic
+// versions are ignored. If maxTags is greater than zero, at most maxTags tags are
+// returned.
+func MatchTags(tags []string, constraint string, maxTags int) ([]string, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag constraint %q: %v", constraint, err)
+	}
+	versions := map[*semver.Version]string{}
+	matched := []*semver.Version{}
+	for _, tag := range tags {
+		v, err := semver.NewVersion(tag)
+		if err != nil || !c.Check(v) {
+			continue
+		}
+		versions[v] = tag
+		matched = append(matched, v)
+	}
+	sort.Sort(sort.Reverse(semver.Collection(matched)))
+	if maxTags > 0 && len(matched) > maxTags {
+		matched = matched[:maxTags]
+	}
+	result := []string{}
+	for _, v := range matched {
+		result = append(result, versions[v])
+	}
+	return result, nil
+}
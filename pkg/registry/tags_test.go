@@ -0,0 +1,103 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListTags(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/token":
+			if r.URL.Query().Get("scope") != "repository:foo/bar:pull" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"token":"secret"}`)
+		case "/v2/foo/bar/tags/list":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:foo/bar:pull"`, srv.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("last") == "" {
+				w.Header().Set("Link", `</v2/foo/bar/tags/list?last=v1.0.1>; rel="next"`)
+				fmt.Fprint(w, `{"name":"foo/bar","tags":["v1.0.0","v1.0.1"]}`)
+				return
+			}
+			fmt.Fprint(w, `{"name":"foo/bar","tags":["v1.1.0"]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	lister := &registryClient{client: srv.Client(), scheme: "https"}
+	host := strings.TrimPrefix(srv.URL, "https://")
+	tags, err := lister.ListTags(host+"/foo/bar", nil)
+	if err != nil {
+		t.Fatalf("ListTags() failed: %v", err)
+	}
+	if expected := []string{"v1.0.0", "v1.0.1", "v1.1.0"}; !reflect.DeepEqual(tags, expected) {
+		t.Errorf("expected tags %v, got %v", expected, tags)
+	}
+	if _, err := lister.ListTags(host+"/foo/missing", nil); err == nil {
+		t.Errorf("expected error listing tags of missing repository")
+	}
+}
+
+func TestMatchTags(t *testing.T) {
+	tags := []string{"latest", "1.24.0", "1.25.1", "1.25.0", "v1.25.3", "1.25.2-alpine", "1.26.0"}
+	tests := []struct {
+		name                string
+		constraint          string
+		maxTags             int
+		expected            []string
+		expectedErrorString string
+	}{
+		{name: "#1: Wildcard constraint", constraint: "1.25.*", expected: []string{"v1.25.3", "1.25.1", "1.25.0"}},
+		{name: "#2: Range constraint with limit", constraint: ">= 1.24, < 1.26", maxTags: 2, expected: []string{"v1.25.3", "1.25.1"}},
+		{name: "#3: No match", constraint: "2.x", expected: []string{}},
+		{name: "#4: Invalid constraint", constraint: "latest", expectedErrorString: "invalid tag constraint \"latest\""},
+	}
+	for _, test := range tests {
+		matched, err := MatchTags(tags, test.constraint, test.maxTags)
+		if test.expectedErrorString != "" {
+			if err == nil || !strings.Contains(err.Error(), test.expectedErrorString) {
+				t.Errorf("Test: %s failed: expectedError=%s, actualError=%v", test.name, test.expectedErrorString, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test: %s failed: expectedError=nil, actualError=%s", test.name, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(matched, test.expected) {
+			t.Errorf("Test: %s failed: expected=%v, actual=%v", test.name, test.expected, matched)
+		}
+	}
+}
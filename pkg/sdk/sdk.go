@@ -0,0 +1,254 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	clientset "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// Client manages the image caches of a namespace
+type Client struct {
+	clientset clientset.Interface
+	namespace string
+}
+
+// NewClient returns a new Client managing the image caches of the namespace
+func NewClient(clientset clientset.Interface, namespace string) *Client {
+	return &Client{
+		clientset: clientset,
+		namespace: namespace,
+	}
+}
+
+// Finished returns true if the last action of the image cache has completed
+func Finished(imageCache *v1alpha2.ImageCache) bool {
+	return imageCache.Status.Status != "" &&
+		imageCache.Status.Status != v1alpha2.ImageCacheActionStatusProcessing
+}
+
+// actionError returns an error if the finished action of the image cache did not succeed
+func actionError(imageCache *v1alpha2.ImageCache) error {
+	switch imageCache.Status.Status {
+	case v1alpha2.ImageCacheActionStatusSucceeded, v1alpha2.ImageCacheActioneNoImagesPulledOrDeleted:
+		return nil
+	}
+	return fmt.Errorf("image cache %s/%s: %s: %s", imageCache.Namespace, imageCache.Name,
+		imageCache.Status.Status, imageCache.Status.Message)
+}
+
+// CreateCacheAndWait creates an image cache of the images on the nodes matching the node
+// selector, and waits for the images to be pulled. The name of the image cache is
+// generated. An error is returned if the images were not pulled within the timeout.
+func (c *Client) CreateCacheAndWait(ctx context.Context, images []string, nodeSelector map[string]string, timeout time.Duration) (*v1alpha2.ImageCache, error) {
+	imageCache := &v1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "imagecache-",
+			Namespace:    c.namespace,
+		},
+		Spec: v1alpha2.ImageCacheSpec{
+			CacheSpec: []v1alpha2.CacheSpecImages{
+				{
+					Images:       images,
+					NodeSelector: nodeSelector,
+				},
+			},
+		},
+	}
+	return c.CreateAndWait(ctx, imageCache, timeout)
+}
+
+// CreateAndWait creates the image cache and waits for its images to be pulled
+func (c *Client) CreateAndWait(ctx context.Context, imageCache *v1alpha2.ImageCache, timeout time.Duration) (*v1alpha2.ImageCache, error) {
+	created, err := c.clientset.KubefledgedV1alpha2().ImageCaches(c.namespace).Create(ctx, imageCache, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return c.WaitForCompletion(ctx, created.Name, timeout)
+}
+
+// WaitForCompletion waits for the running action of the image cache to complete. It
+// returns an error if the action did not succeed or did not complete within the timeout.
+func (c *Client) WaitForCompletion(ctx context.Context, name string, timeout time.Duration) (*v1alpha2.ImageCache, error) {
+	return c.waitFor(ctx, name, timeout, Finished)
+}
+
+// RefreshAndWait refreshes the image cache and waits for the refresh to complete
+func (c *Client) RefreshAndWait(ctx context.Context, name string, timeout time.Duration) (*v1alpha2.ImageCache, error) {
+	return c.annotateAndWait(ctx, name, v1alpha2.ImageCacheRefreshAnnotationKey, timeout)
+}
+
+// PurgeAndWait purges the images of the image cache from the nodes and waits for the
+// purge to complete
+func (c *Client) PurgeAndWait(ctx context.Context, name string, timeout time.Duration) (*v1alpha2.ImageCache, error) {
+	return c.annotateAndWait(ctx, name, v1alpha2.ImageCachePurgeAnnotationKey, timeout)
+}
+
+// annotateAndWait requests an action on the image cache by setting the annotation, and
+// waits for an action started after the request to complete
+func (c *Client) annotateAndWait(ctx context.Context, name, annotation string, timeout time.Duration) (*v1alpha2.ImageCache, error) {
+	imageCache, err := c.clientset.KubefledgedV1alpha2().ImageCaches(c.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	previousStart := imageCache.Status.StartTime
+	imageCacheCopy := imageCache.DeepCopy()
+	if imageCacheCopy.Annotations == nil {
+		imageCacheCopy.Annotations = map[string]string{}
+	}
+	imageCacheCopy.Annotations[annotation] = ""
+	if _, err := c.clientset.KubefledgedV1alpha2().ImageCaches(c.namespace).Update(ctx, imageCacheCopy, metav1.UpdateOptions{}); err != nil {
+		return nil, err
+	}
+	return c.waitFor(ctx, name, timeout, func(imageCache *v1alpha2.ImageCache) bool {
+		started := imageCache.Status.StartTime
+		if started == nil || (previousStart != nil && !started.After(previousStart.Time)) {
+			return false
+		}
+		return Finished(imageCache)
+	})
+}
+
+// waitFor waits until done returns true for the image cache, and returns an error if
+// the finished action did not succeed
+func (c *Client) waitFor(ctx context.Context, name string, timeout time.Duration, done func(*v1alpha2.ImageCache) bool) (*v1alpha2.ImageCache, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var last *v1alpha2.ImageCache
+	err := c.WatchProgress(ctx, name, func(imageCache *v1alpha2.ImageCache) bool {
+		last = imageCache
+		return done(imageCache)
+	})
+	if err != nil {
+		if err == context.DeadlineExceeded && last != nil {
+			return last, fmt.Errorf("timed out waiting for image cache %s/%s: status %q", c.namespace, name, last.Status.Status)
+		}
+		return last, err
+	}
+	return last, actionError(last)
+}
+
+// watchRetryBackoff is the backoff between re-reading the image cache after watch errors
+var watchRetryBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    10,
+	Cap:      30 * time.Second,
+}
+
+// WatchProgress calls fn with the image cache and with every update of the image cache,
+// until fn returns true or the context is done. An error is returned if the image cache
+// is deleted.
+func (c *Client) WatchProgress(ctx context.Context, name string, fn func(*v1alpha2.ImageCache) bool) error {
+	imageCaches := c.clientset.KubefledgedV1alpha2().ImageCaches(c.namespace)
+	backoff := watchRetryBackoff
+	resourceVersion := ""
+	for {
+		if resourceVersion == "" {
+			// The image cache is read (again) when there is no resource version to watch
+			// from, e.g. after it expired (410 Gone)
+			imageCache, err := imageCaches.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if fn(imageCache) {
+				return nil
+			}
+			resourceVersion = imageCache.ResourceVersion
+		}
+		w, err := imageCaches.Watch(ctx, metav1.ListOptions{
+			FieldSelector:   "metadata.name=" + name,
+			ResourceVersion: resourceVersion,
+		})
+		if err == nil {
+			var done bool
+			done, err = watchEvents(ctx, w, name, &resourceVersion, fn)
+			w.Stop()
+			if done {
+				return nil
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil {
+			backoff = watchRetryBackoff
+			continue
+		}
+		if !apierrors.IsResourceExpired(err) && !apierrors.IsGone(err) && !isWatchError(err) {
+			return err
+		}
+		glog.V(4).Infof("Re-reading image cache %s/%s after watch error: %v", c.namespace, name, err)
+		resourceVersion = ""
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff.Step()):
+		}
+	}
+}
+
+// watchError is an error event received on the watch of an image cache
+type watchError struct {
+	error
+}
+
+func isWatchError(err error) bool {
+	_, ok := err.(watchError)
+	return ok
+}
+
+// watchEvents passes the updates of the image cache to fn, until fn returns true, the
+// watch is closed, an error event is received or the context is done
+func watchEvents(ctx context.Context, w watch.Interface, name string, resourceVersion *string, fn func(*v1alpha2.ImageCache) bool) (bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return false, nil
+			}
+			if event.Type == watch.Error {
+				return false, watchError{apierrors.FromObject(event.Object)}
+			}
+			imageCache, ok := event.Object.(*v1alpha2.ImageCache)
+			if !ok || imageCache.Name != name {
+				continue
+			}
+			switch event.Type {
+			case watch.Deleted:
+				return false, fmt.Errorf("image cache %s/%s was deleted", imageCache.Namespace, name)
+			case watch.Added, watch.Modified:
+				*resourceVersion = imageCache.ResourceVersion
+				if fn(imageCache) {
+					return true, nil
+				}
+			}
+		}
+	}
+}
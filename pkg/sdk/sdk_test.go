@@ -0,0 +1,212 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned/fake"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	core "k8s.io/client-go/testing"
+)
+
+const testNamespace = "kube-fledged"
+
+// newTestClientset returns a fake clientset which names the image caches created with a
+// generated name, and signals the watches it starts
+func newTestClientset(objects ...runtime.Object) (*fake.Clientset, chan struct{}) {
+	clientset := fake.NewSimpleClientset(objects...)
+	clientset.PrependReactor("create", "imagecaches", func(action core.Action) (bool, runtime.Object, error) {
+		imageCache := action.(core.CreateAction).GetObject().(*v1alpha2.ImageCache)
+		if imageCache.Name == "" {
+			imageCache.Name = imageCache.GenerateName + "test"
+		}
+		return false, nil, nil
+	})
+	watching := make(chan struct{}, 10)
+	clientset.PrependWatchReactor("imagecaches", func(action core.Action) (bool, watch.Interface, error) {
+		w, err := clientset.Tracker().Watch(action.GetResource(), action.GetNamespace())
+		watching <- struct{}{}
+		return true, w, err
+	})
+	return clientset, watching
+}
+
+// setStatus updates the status of the image cache once it is watched
+func setStatus(t *testing.T, clientset *fake.Clientset, watching chan struct{}, name string, statuses ...v1alpha2.ImageCacheStatus) {
+	<-watching
+	for _, status := range statuses {
+		imageCache, err := clientset.KubefledgedV1alpha2().ImageCaches(testNamespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Errorf("Error getting image cache: %v", err)
+			return
+		}
+		imageCache.Status = status
+		if _, err := clientset.KubefledgedV1alpha2().ImageCaches(testNamespace).Update(context.TODO(), imageCache, metav1.UpdateOptions{}); err != nil {
+			t.Errorf("Error updating image cache: %v", err)
+			return
+		}
+	}
+}
+
+func TestCreateCacheAndWait(t *testing.T) {
+	tests := []struct {
+		name        string
+		statuses    []v1alpha2.ImageCacheActionStatus
+		delete      bool
+		expectError bool
+	}{
+		{
+			name:     "#1: Images pulled",
+			statuses: []v1alpha2.ImageCacheActionStatus{v1alpha2.ImageCacheActionStatusProcessing, v1alpha2.ImageCacheActionStatusSucceeded},
+		},
+		{
+			name:        "#2: Image pull failed",
+			statuses:    []v1alpha2.ImageCacheActionStatus{v1alpha2.ImageCacheActionStatusProcessing, v1alpha2.ImageCacheActionStatusFailed},
+			expectError: true,
+		},
+		{
+			name:        "#3: Timed out",
+			statuses:    []v1alpha2.ImageCacheActionStatus{v1alpha2.ImageCacheActionStatusProcessing},
+			expectError: true,
+		},
+		{
+			name:        "#4: Image cache deleted",
+			delete:      true,
+			expectError: true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		clientset, watching := newTestClientset()
+		go func() {
+			if test.delete {
+				<-watching
+				clientset.KubefledgedV1alpha2().ImageCaches(testNamespace).Delete(context.TODO(), "imagecache-test", metav1.DeleteOptions{})
+				return
+			}
+			statuses := []v1alpha2.ImageCacheStatus{}
+			for _, status := range test.statuses {
+				statuses = append(statuses, v1alpha2.ImageCacheStatus{Status: status})
+			}
+			setStatus(t, clientset, watching, "imagecache-test", statuses...)
+		}()
+		client := NewClient(clientset, testNamespace)
+		imageCache, err := client.CreateCacheAndWait(context.TODO(), []string{"nginx:1.23"}, map[string]string{"tier": "web"}, time.Second)
+		if test.expectError {
+			if err == nil {
+				t.Errorf("Test: %s failed: expected error, actual nil", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test: %s failed: unexpected error: %v", test.name, err)
+			continue
+		}
+		if imageCache.Name != "imagecache-test" || imageCache.Spec.CacheSpec[0].NodeSelector["tier"] != "web" {
+			t.Errorf("Test: %s failed: unexpected image cache %+v", test.name, imageCache)
+		}
+	}
+}
+
+func TestRefreshAndWait(t *testing.T) {
+	previousStart := metav1.NewTime(time.Now().Add(-time.Hour))
+	start := metav1.NewTime(time.Now())
+	imageCache := &v1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: testNamespace,
+		},
+		Status: v1alpha2.ImageCacheStatus{
+			Status:    v1alpha2.ImageCacheActionStatusSucceeded,
+			StartTime: &previousStart,
+		},
+	}
+	clientset, watching := newTestClientset(imageCache)
+	go setStatus(t, clientset, watching, "foo",
+		v1alpha2.ImageCacheStatus{Status: v1alpha2.ImageCacheActionStatusSucceeded, StartTime: &previousStart},
+		v1alpha2.ImageCacheStatus{Status: v1alpha2.ImageCacheActionStatusProcessing, StartTime: &start},
+		v1alpha2.ImageCacheStatus{Status: v1alpha2.ImageCacheActionStatusSucceeded, StartTime: &start})
+	client := NewClient(clientset, testNamespace)
+	actual, err := client.RefreshAndWait(context.TODO(), "foo", time.Second)
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if _, ok := actual.Annotations[v1alpha2.ImageCacheRefreshAnnotationKey]; !ok {
+		t.Errorf("Test failed: expected the refresh annotation to be set")
+	}
+	if !actual.Status.StartTime.Equal(&start) {
+		t.Errorf("Test failed: expected the status of the refresh, actual=%+v", actual.Status)
+	}
+}
+
+func TestWatchProgressAfterWatchError(t *testing.T) {
+	defer func(backoff wait.Backoff) { watchRetryBackoff = backoff }(watchRetryBackoff)
+	watchRetryBackoff = wait.Backoff{Duration: time.Millisecond, Steps: 1}
+	imageCache := &v1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: testNamespace,
+		},
+		Status: v1alpha2.ImageCacheStatus{Status: v1alpha2.ImageCacheActionStatusProcessing},
+	}
+	clientset, watching := newTestClientset(imageCache)
+	expired := watch.NewFake()
+	clientset.PrependWatchReactor("imagecaches", func(action core.Action) (bool, watch.Interface, error) {
+		if expired == nil {
+			return false, nil, nil
+		}
+		w := expired
+		expired = nil
+		go func() {
+			// The image cache is updated while the watch fails with an expired resource version
+			watching <- struct{}{}
+			setStatus(t, clientset, watching, "foo", v1alpha2.ImageCacheStatus{Status: v1alpha2.ImageCacheActionStatusSucceeded})
+		}()
+		go w.Error(&apierrors.NewResourceExpired("too old resource version").ErrStatus)
+		return true, w, nil
+	})
+	gets := 0
+	clientset.PrependReactor("get", "imagecaches", func(action core.Action) (bool, runtime.Object, error) {
+		gets++
+		return false, nil, nil
+	})
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+	client := NewClient(clientset, testNamespace)
+	var last *v1alpha2.ImageCache
+	err := client.WatchProgress(ctx, "foo", func(imageCache *v1alpha2.ImageCache) bool {
+		last = imageCache
+		return imageCache.Status.Status == v1alpha2.ImageCacheActionStatusSucceeded
+	})
+	if err != nil {
+		t.Fatalf("Test failed: unexpected error: %v", err)
+	}
+	if last.Status.Status != v1alpha2.ImageCacheActionStatusSucceeded {
+		t.Errorf("Test failed: expected the updated image cache, actual=%+v", last.Status)
+	}
+	if gets < 2 {
+		t.Errorf("Test failed: expected the image cache to be read again after the watch error, actual gets=%d", gets)
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package version holds the version of the kube-fledged binaries, set at build time.
+package version
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Version is the release version of kube-fledged. It is set at build time with
+// -ldflags "-X github.com/senthilrch/kube-fledged/pkg/version.Version=<version>", and
+// is "devel" for binaries built otherwise.
+var Version = "devel"
+
+// Commit is the git commit the binary was built from, and BuildDate the time it was
+// built at in RFC 3339 format. They are set at build time like Version, and are
+// "unknown" for binaries built otherwise.
+var (
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// Info is the build information of a binary
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+}
+
+// Get returns the build information of the running binary
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+}
+
+// String returns the build information in a form suitable for logs
+func (i Info) String() string {
+	return fmt.Sprintf("%s (commit %s, built %s, %s %s)", i.Version, i.Commit, i.BuildDate, i.GoVersion, i.Platform)
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package version
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	Version, Commit, BuildDate = "v0.10.0", "3f2a1c9", "2026-10-15T09:30:00Z"
+	defer func() { Version, Commit, BuildDate = "devel", "unknown", "unknown" }()
+
+	info := Get()
+	expected := Info{Version: "v0.10.0", Commit: "3f2a1c9", BuildDate: "2026-10-15T09:30:00Z",
+		GoVersion: runtime.Version(), Platform: runtime.GOOS + "/" + runtime.GOARCH}
+	if info != expected {
+		t.Errorf("expected build info %+v, actual %+v", expected, info)
+	}
+	expectedString := "v0.10.0 (commit 3f2a1c9, built 2026-10-15T09:30:00Z, " + runtime.Version() + " " + runtime.GOOS + "/" + runtime.GOARCH + ")"
+	if s := info.String(); s != expectedString {
+		t.Errorf("expected %q, actual %q", expectedString, s)
+	}
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/golang/glog"
 	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	"github.com/senthilrch/kube-fledged/pkg/images"
 	v1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -69,8 +70,9 @@ func MutateImageCache(ar v1.AdmissionReview) *v1.AdmissionResponse {
 }
 */
 
-// ValidateImageCache validates image cache resource
-func ValidateImageCache(ar v1.AdmissionReview) *v1.AdmissionResponse {
+// ValidateImageCache validates image cache resource. Image lists without node selector
+// select the nodes running defaultNodeOS, or all the nodes if defaultNodeOS is empty.
+func ValidateImageCache(ar v1.AdmissionReview, defaultNodeOS string) *v1.AdmissionResponse {
 	glog.V(4).Info("admitting image cache")
 	var raw, oldraw []byte
 	var imageCache, oldImageCache fledgedv1alpha2.ImageCache
@@ -101,38 +103,64 @@ func ValidateImageCache(ar v1.AdmissionReview) *v1.AdmissionResponse {
 	cacheSpec := imageCache.Spec.CacheSpec
 	glog.V(4).Infof("cacheSpec: %+v", cacheSpec)
 
-	for _, i := range cacheSpec {
-		if len(i.Images) == 0 {
-			glog.Error("No images specified within image list")
-			return toV1AdmissionResponse(fmt.Errorf("No images specified within image list"))
-		}
+	if err := images.ValidateImageLists(cacheSpec); err != nil {
+		glog.Error(err)
+		return toV1AdmissionResponse(err)
+	}
 
-		for m := range i.Images {
-			for p := 0; p < m; p++ {
-				if i.Images[p] == i.Images[m] {
-					glog.Errorf("Duplicate image names within image list: %s", i.Images[m])
-					return toV1AdmissionResponse(fmt.Errorf("Duplicate image names within image list: %s", i.Images[m]))
-				}
-			}
-		}
-		/*
-			if len(i.NodeSelector) > 0 {
-				if nodes, err = c.nodesLister.List(labels.Set(i.NodeSelector).AsSelector()); err != nil {
-					glog.Errorf("Error listing nodes using nodeselector %+v: %v", i.NodeSelector, err)
-					return err
-				}
-			} else {
-				if nodes, err = c.nodesLister.List(labels.Everything()); err != nil {
-					glog.Errorf("Error listing nodes using nodeselector labels.Everything(): %v", err)
-					return err
-				}
-			}
-			glog.V(4).Infof("No. of nodes in %+v is %d", i.NodeSelector, len(nodes))
-			if len(nodes) == 0 {
-				glog.Errorf("NodeSelector %s did not match any nodes.", labels.Set(i.NodeSelector).String())
-				return fmt.Errorf("NodeSelector %s did not match any nodes", labels.Set(i.NodeSelector).String())
-			}
-		*/
+	if err := images.ValidateNoDuplicateImages(cacheSpec, defaultNodeOS); err != nil {
+		glog.Error(err)
+		return toV1AdmissionResponse(err)
+	}
+
+	if err := images.ValidateImagePullPolicy(imageCache.Spec.ImagePullPolicy); err != nil {
+		glog.Error(err)
+		return toV1AdmissionResponse(err)
+	}
+
+	if err := images.ValidateTTL(imageCache.Spec.TTL); err != nil {
+		glog.Error(err)
+		return toV1AdmissionResponse(err)
+	}
+
+	if err := images.ValidateUnusedImagePurge(imageCache.Spec.UnusedImagePurge); err != nil {
+		glog.Error(err)
+		return toV1AdmissionResponse(err)
+	}
+
+	if err := images.ValidateWarmStandby(imageCache.Spec.WarmStandby); err != nil {
+		glog.Error(err)
+		return toV1AdmissionResponse(err)
+	}
+
+	if err := images.ValidateRollout(imageCache.Spec.Rollout); err != nil {
+		glog.Error(err)
+		return toV1AdmissionResponse(err)
+	}
+
+	if err := images.ValidateSnapshotter(imageCache.Spec.Snapshotter); err != nil {
+		glog.Error(err)
+		return toV1AdmissionResponse(err)
+	}
+
+	if err := images.ValidateFailureThreshold(imageCache.Spec.FailureThreshold); err != nil {
+		glog.Error(err)
+		return toV1AdmissionResponse(err)
+	}
+
+	if err := images.ValidateMaxBytesPerNode(imageCache.Spec.MaxBytesPerNode); err != nil {
+		glog.Error(err)
+		return toV1AdmissionResponse(err)
+	}
+
+	if err := images.ValidateHooks(imageCache.Spec.Hooks); err != nil {
+		glog.Error(err)
+		return toV1AdmissionResponse(err)
+	}
+
+	if err := images.ValidateSecurityProfiles(imageCache.Spec.SecurityProfiles); err != nil {
+		glog.Error(err)
+		return toV1AdmissionResponse(err)
 	}
 
 	if ar.Request.Operation == v1.Update {
@@ -0,0 +1,144 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/golang/glog"
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	clientset "github.com/senthilrch/kube-fledged/pkg/client/clientset/versioned"
+	"github.com/senthilrch/kube-fledged/pkg/policy"
+	v1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ImageCacheQuota limits the number of image caches, and the total number of images
+// and OCI artifacts listed in the image caches, that a namespace may define. A zero
+// limit means no limit.
+type ImageCacheQuota struct {
+	MaxImageCaches int
+	MaxImages      int
+}
+
+// Enabled returns true if the quota sets any limit
+func (q ImageCacheQuota) Enabled() bool {
+	return q.MaxImageCaches > 0 || q.MaxImages > 0
+}
+
+// NewImageCacheValidator returns an admission function validating image caches with
+// ValidateImageCache, which additionally enforces the FledgedPolicies of the cluster and
+// the quota on the namespace of the image cache. The policies and the image caches of
+// the namespace are listed using kubefledgedclientset.
+func NewImageCacheValidator(quota ImageCacheQuota, defaultNodeOS string, kubefledgedclientset clientset.Interface) func(v1.AdmissionReview) *v1.AdmissionResponse {
+	return func(ar v1.AdmissionReview) *v1.AdmissionResponse {
+		reviewResponse := ValidateImageCache(ar, defaultNodeOS)
+		if !reviewResponse.Allowed {
+			return reviewResponse
+		}
+		var imageCache, oldImageCache fledgedv1alpha2.ImageCache
+		if err := json.Unmarshal(ar.Request.Object.Raw, &imageCache); err != nil {
+			glog.Error(err)
+			return toV1AdmissionResponse(err)
+		}
+		namespace := ar.Request.Namespace
+		if namespace == "" {
+			namespace = imageCache.Namespace
+		}
+		imageCache.Namespace = namespace
+		if ar.Request.Operation == v1.Update {
+			if err := json.Unmarshal(ar.Request.OldObject.Raw, &oldImageCache); err != nil {
+				glog.Error(err)
+				return toV1AdmissionResponse(err)
+			}
+			// Status updates of image caches are never rejected
+			if reflect.DeepEqual(imageCache.Spec, oldImageCache.Spec) {
+				return reviewResponse
+			}
+		}
+		if err := validateImageCachePolicies(&imageCache, kubefledgedclientset); err != nil {
+			glog.Error(err)
+			return toV1AdmissionResponse(err)
+		}
+		// Updates that do not add images never exceed the quota
+		if !quota.Enabled() || (ar.Request.Operation == v1.Update && countImages(&imageCache) <= countImages(&oldImageCache)) {
+			return reviewResponse
+		}
+		existing, err := kubefledgedclientset.KubefledgedV1alpha2().ImageCaches(namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			glog.Errorf("Error listing image caches in namespace %s: %v", namespace, err)
+			return toV1AdmissionResponse(err)
+		}
+		if err := ValidateImageCacheQuota(&imageCache, existing.Items, quota); err != nil {
+			glog.Error(err)
+			return toV1AdmissionResponse(err)
+		}
+		return reviewResponse
+	}
+}
+
+// validateImageCachePolicies checks an image cache against the FledgedPolicies of the
+// cluster. Image caches are not checked if the FledgedPolicy CRD is not installed.
+func validateImageCachePolicies(imageCache *fledgedv1alpha2.ImageCache, kubefledgedclientset clientset.Interface) error {
+	list, err := kubefledgedclientset.KubefledgedV1alpha2().FledgedPolicies().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("Error listing fledged policies: %v", err)
+	}
+	policies := []*fledgedv1alpha2.FledgedPolicy{}
+	for i := range list.Items {
+		policies = append(policies, &list.Items[i])
+	}
+	return policy.ValidateImageCache(imageCache, policies)
+}
+
+// ValidateImageCacheQuota checks that the namespace of an image cache stays within the
+// quota, once the image cache is created or updated. existing are the image caches of
+// the namespace, which may include a previous version of the image cache.
+func ValidateImageCacheQuota(imageCache *fledgedv1alpha2.ImageCache, existing []fledgedv1alpha2.ImageCache, quota ImageCacheQuota) error {
+	imageCaches := 1
+	images := countImages(imageCache)
+	for i := range existing {
+		if existing[i].Name == imageCache.Name {
+			continue
+		}
+		imageCaches++
+		images += countImages(&existing[i])
+	}
+	if quota.MaxImageCaches > 0 && imageCaches > quota.MaxImageCaches {
+		return fmt.Errorf("Namespace %s exceeds its quota of %d image caches", imageCache.Namespace, quota.MaxImageCaches)
+	}
+	if quota.MaxImages > 0 && images > quota.MaxImages {
+		return fmt.Errorf("Namespace %s exceeds its quota of %d images: image caches of the namespace list %d images", imageCache.Namespace, quota.MaxImages, images)
+	}
+	return nil
+}
+
+// countImages returns the number of images and OCI artifacts listed in an image cache
+func countImages(imageCache *fledgedv1alpha2.ImageCache) int {
+	count := 0
+	for _, cacheSpec := range imageCache.Spec.CacheSpec {
+		count += len(cacheSpec.Images) + len(cacheSpec.Artifacts)
+	}
+	return count
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2018 The kube-fledged authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"strings"
+	"testing"
+
+	fledgedv1alpha2 "github.com/senthilrch/kube-fledged/pkg/apis/kubefledged/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newQuotaTestImageCache(name string, images ...string) fledgedv1alpha2.ImageCache {
+	return fledgedv1alpha2.ImageCache{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a"},
+		Spec: fledgedv1alpha2.ImageCacheSpec{
+			CacheSpec: []fledgedv1alpha2.CacheSpecImages{{Images: images}},
+		},
+	}
+}
+
+func TestValidateImageCacheQuota(t *testing.T) {
+	tests := []struct {
+		name                string
+		imageCache          fledgedv1alpha2.ImageCache
+		existing            []fledgedv1alpha2.ImageCache
+		quota               ImageCacheQuota
+		expectedErrorString string
+	}{
+		{
+			name:       "#1: No quota",
+			imageCache: newQuotaTestImageCache("foo", "a", "b", "c"),
+			existing:   []fledgedv1alpha2.ImageCache{newQuotaTestImageCache("bar", "d")},
+		},
+		{
+			name:                "#2: Image cache quota exceeded",
+			imageCache:          newQuotaTestImageCache("foo", "a"),
+			existing:            []fledgedv1alpha2.ImageCache{newQuotaTestImageCache("bar", "b")},
+			quota:               ImageCacheQuota{MaxImageCaches: 1},
+			expectedErrorString: "Namespace team-a exceeds its quota of 1 image caches",
+		},
+		{
+			name:                "#3: Image quota exceeded",
+			imageCache:          newQuotaTestImageCache("foo", "a", "b"),
+			existing:            []fledgedv1alpha2.ImageCache{newQuotaTestImageCache("bar", "c")},
+			quota:               ImageCacheQuota{MaxImages: 2},
+			expectedErrorString: "Namespace team-a exceeds its quota of 2 images",
+		},
+		{
+			name:       "#4: Update - previous version of the image cache is not counted",
+			imageCache: newQuotaTestImageCache("foo", "a", "b"),
+			existing:   []fledgedv1alpha2.ImageCache{newQuotaTestImageCache("foo", "a")},
+			quota:      ImageCacheQuota{MaxImageCaches: 1, MaxImages: 2},
+		},
+	}
+	for _, test := range tests {
+		err := ValidateImageCacheQuota(&test.imageCache, test.existing, test.quota)
+		if test.expectedErrorString == "" {
+			if err != nil {
+				t.Errorf("Test: %s failed: expectedError=nil, actualError=%s", test.name, err.Error())
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.expectedErrorString) {
+			t.Errorf("Test: %s failed: expectedError=%s, actualError=%v", test.name, test.expectedErrorString, err)
+		}
+	}
+}